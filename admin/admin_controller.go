@@ -1,17 +1,27 @@
 package admin
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"go_backend_project/config"
+	"go_backend_project/middleware"
 	"go_backend_project/models"
 	"go_backend_project/services"
 	"go_backend_project/services/backtesting"
 	"go_backend_project/services/datafetcher"
+	"go_backend_project/services/pipeline"
 	"go_backend_project/services/signals"
 	"go_backend_project/services/trading"
+	"go_backend_project/services/xlsx"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
@@ -67,20 +77,21 @@ func (ac *AdminController) Dashboard(c *gin.Context) {
 	}
 
 	// Get statistics
+	db := ac.readDB()
 	var stockCount int64
-	ac.db.Model(&models.Stock{}).Count(&stockCount)
+	db.Model(&models.Stock{}).Count(&stockCount)
 
 	var strategyCount int64
-	ac.db.Model(&models.TradingStrategy{}).Count(&strategyCount)
+	db.Model(&models.TradingStrategy{}).Count(&strategyCount)
 
 	var backtestCount int64
-	ac.db.Model(&models.Backtest{}).Count(&backtestCount)
+	db.Model(&models.Backtest{}).Count(&backtestCount)
 
 	var tradeCount int64
-	ac.db.Model(&models.Trade{}).Count(&tradeCount)
+	db.Model(&models.Trade{}).Count(&tradeCount)
 
 	var userCount int64
-	ac.db.Model(&models.User{}).Count(&userCount)
+	db.Model(&models.User{}).Count(&userCount)
 
 	// Check if trading bot is running (handle nil gracefully)
 	botRunning := false
@@ -123,6 +134,12 @@ func (ac *AdminController) requireDatabaseAvailable(c *gin.Context) bool {
 	return true
 }
 
+// readDB returns the connection for read-heavy list queries: the read replica when configured,
+// otherwise the primary. Results may lag writes by the replication delay.
+func (ac *AdminController) readDB() *gorm.DB {
+	return config.ReadDB(ac.db)
+}
+
 // StocksPage shows stocks management page
 func (ac *AdminController) StocksPage(c *gin.Context) {
 	adminUser := ac.getAdminUser(c)
@@ -133,9 +150,10 @@ func (ac *AdminController) StocksPage(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	var total int64
-	ac.db.Model(&models.Stock{}).Count(&total)
+	db := ac.readDB()
+	db.Model(&models.Stock{}).Count(&total)
 
-	ac.db.Limit(limit).Offset(offset).Find(&stocks)
+	db.Limit(limit).Offset(offset).Find(&stocks)
 
 	c.HTML(http.StatusOK, "stocks.html", gin.H{
 		"stocks":    stocks,
@@ -166,7 +184,7 @@ func (ac *AdminController) BacktestsPage(c *gin.Context) {
 	adminUser := ac.getAdminUser(c)
 
 	var backtests []models.Backtest
-	ac.db.Preload("Strategy").Order("created_at DESC").Limit(50).Find(&backtests)
+	ac.readDB().Preload("Strategy").Order("created_at DESC").Limit(50).Find(&backtests)
 
 	c.HTML(http.StatusOK, "backtests.html", gin.H{
 		"backtests": backtests,
@@ -176,7 +194,605 @@ func (ac *AdminController) BacktestsPage(c *gin.Context) {
 	})
 }
 
-// TradingBotPage shows trading bot control page
+// CompareBacktests handles GET /admin/api/backtests/compare?ids=1,2,3 - returns aligned metrics and equity curves
+func (ac *AdminController) CompareBacktests(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backtest ID: " + part})
+			return
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+
+	if len(ids) < 2 || len(ids) > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide between 2 and 10 backtest IDs"})
+		return
+	}
+
+	comparison, err := ac.backtestEngine.CompareBacktests(ids)
+	if errors.Is(err, backtesting.ErrBacktestNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// ExportBacktest handles GET /admin/api/backtests/:id/export?format=csv|xlsx - downloads the trade
+// log and the daily equity of a backtest, streamed as they are read
+func (ac *AdminController) ExportBacktest(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if ac.backtestEngine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Backtest engine not initialized"})
+		return
+	}
+
+	format := c.DefaultQuery("format", backtesting.ExportCSV)
+	contentType := "text/csv"
+	switch format {
+	case backtesting.ExportCSV:
+	case backtesting.ExportXLSX:
+		contentType = xlsx.ContentType
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+
+	var backtest models.Backtest
+	if err := ac.readDB().First(&backtest, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backtest not found"})
+		return
+	}
+
+	filename := fmt.Sprintf("backtest_%d.%s", backtest.ID, format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+	if err := ac.backtestEngine.Export(&backtest, format, c.Writer); err != nil {
+		log.Printf("Failed to export backtest %d: %v", backtest.ID, err)
+	}
+}
+
+// GetRuleBacktestConfig handles GET /admin/api/rule-backtests/config - returns nightly rule backtest settings
+func (ac *AdminController) GetRuleBacktestConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, struct {
+		signals.RuleBacktestConfig
+		LastRun *time.Time `json:"last_run"`
+	}{signals.LoadRuleBacktestConfig(ac.db), signals.LastRuleBacktestRun(ac.db)})
+}
+
+// UpdateRuleBacktestConfig handles PUT /admin/api/rule-backtests/config - updates degradation thresholds
+func (ac *AdminController) UpdateRuleBacktestConfig(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	cfg := signals.LoadRuleBacktestConfig(ac.db)
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cfg.WindowMonths < 1 || cfg.WindowMonths > 24 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window_months must be between 1 and 24"})
+		return
+	}
+	if cfg.StepDays < 1 || cfg.MaxHoldingDays < 1 || cfg.MinTrades < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_days and max_holding_days must be positive"})
+		return
+	}
+	if cfg.MinWinRate < 0 || cfg.MinWinRate > 100 || cfg.MaxWinRateDrop < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid win rate thresholds"})
+		return
+	}
+
+	if err := signals.SaveRuleBacktestConfig(ac.db, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule backtest config updated", "config": cfg})
+}
+
+// GetPipeline handles GET /admin/api/pipeline - returns the daily pipeline settings, the run in
+// progress and the stages of the last run
+func (ac *AdminController) GetPipeline(c *gin.Context) {
+	if pipeline.GlobalDataPipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data pipeline not initialized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"config":      pipeline.GlobalDataPipeline.GetConfig(),
+		"current_run": pipeline.GlobalDataPipeline.CurrentRun(),
+	})
+}
+
+// UpdatePipelineConfig handles PUT /admin/api/pipeline/config - updates the schedule and validation threshold
+func (ac *AdminController) UpdatePipelineConfig(c *gin.Context) {
+	if pipeline.GlobalDataPipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data pipeline not initialized"})
+		return
+	}
+
+	cfg := pipeline.GlobalDataPipeline.GetConfig()
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := pipeline.GlobalDataPipeline.UpdateConfig(cfg.Enabled, cfg.ScheduleTime, cfg.MaxInvalidPercent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pipeline config updated", "config": pipeline.GlobalDataPipeline.GetConfig()})
+}
+
+// RunPipeline handles POST /admin/api/pipeline/run - starts a pipeline run now as a background job
+func (ac *AdminController) RunPipeline(c *gin.Context) {
+	if pipeline.GlobalDataPipeline == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data pipeline not initialized"})
+		return
+	}
+
+	job, err := pipeline.GlobalDataPipeline.Submit(pipeline.TriggerManual, c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Pipeline run started",
+		"job_id":     job.ID,
+		"status_url": "/admin/api/jobs/" + job.ID,
+		"job":        job,
+	})
+}
+
+// GetCompositeWeights handles GET /admin/api/signals/composite-weights - returns composite strategy weights
+func (ac *AdminController) GetCompositeWeights(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"config":   signals.GetCompositeWeightConfig(),
+		"defaults": signals.DefaultCompositeWeightConfig(),
+	})
+}
+
+// UpdateCompositeWeights handles PUT /admin/api/signals/composite-weights - validates and applies new weights
+func (ac *AdminController) UpdateCompositeWeights(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var cfg signals.CompositeWeightConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := signals.SaveCompositeWeights(ac.db, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Composite weights updated", "config": signals.GetCompositeWeightConfig()})
+}
+
+// GetIndicatorProfiles handles GET /admin/api/indicators/profiles - returns indicator calculation profiles
+func (ac *AdminController) GetIndicatorProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"config":   services.GetIndicatorProfileConfig(),
+		"defaults": services.DefaultIndicatorProfileConfig(),
+	})
+}
+
+// UpdateIndicatorProfiles handles PUT /admin/api/indicators/profiles - validates and applies indicator
+// profiles; values change on the next indicator calculation
+func (ac *AdminController) UpdateIndicatorProfiles(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var cfg services.IndicatorProfileConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SaveIndicatorProfiles(ac.db, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Indicator profiles updated", "config": services.GetIndicatorProfileConfig()})
+}
+
+// RunRuleBacktests handles POST /admin/api/rule-backtests/run - starts the nightly rule backtest immediately
+func (ac *AdminController) RunRuleBacktests(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if signals.GlobalConditionEvaluator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Condition evaluator not initialized"})
+		return
+	}
+
+	go func() {
+		if err := signals.GlobalConditionEvaluator.RunNightlyRuleBacktests(); err != nil {
+			log.Printf("Manual rule backtest failed: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Rule backtest started"})
+}
+
+// GetRuleBacktestRuns handles GET /admin/api/rule-backtests/runs?rule_id=&limit= - returns stored backtest history
+func (ac *AdminController) GetRuleBacktestRuns(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	query := ac.readDB().Model(&models.SignalRuleBacktestRun{})
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		id, err := strconv.ParseUint(ruleID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+			return
+		}
+		query = query.Where("rule_id = ?", id)
+	}
+	if c.Query("degraded") == "true" {
+		query = query.Where("degraded = ?", true)
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	var runs []models.SignalRuleBacktestRun
+	if err := query.Order("created_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "count": len(runs)})
+}
+
+// GetSignalHistory handles GET /admin/api/signal-history?rule_id=&stock=&state=active - returns emitted signals
+func (ac *AdminController) GetSignalHistory(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	query := ac.readDB().Model(&models.SignalHistory{})
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	if stock := c.Query("stock"); stock != "" {
+		query = query.Where("stock_symbol = ?", strings.ToUpper(stock))
+	}
+	if state := c.Query("state"); state != "" {
+		if !signals.IsSignalState(state) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state"})
+			return
+		}
+		query = query.Where("state = ?", state)
+	}
+
+	var history []models.SignalHistory
+	if err := query.Order("emitted_at DESC").Limit(limit).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"history": history, "count": len(history)}
+	if signals.GlobalConditionEvaluator != nil {
+		if counts, err := signals.GlobalConditionEvaluator.SignalStateCounts(); err == nil {
+			response["state_counts"] = counts
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EmitRuleSignals handles POST /admin/api/signal-history/emit - runs deduplicated rule signal emission now
+func (ac *AdminController) EmitRuleSignals(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if signals.GlobalConditionEvaluator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Condition evaluator not initialized"})
+		return
+	}
+
+	emitted, err := signals.GlobalConditionEvaluator.RunRuleSignalEmission()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]gin.H, 0, len(emitted))
+	for _, sig := range emitted {
+		result := gin.H{
+			"stock_code":  sig.StockCode,
+			"signal_type": sig.SignalType,
+			"score":       sig.Score,
+			"price":       sig.Price,
+		}
+		if sig.Rule != nil {
+			result["rule_id"] = sig.Rule.ID
+			result["rule_name"] = sig.Rule.Name
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Emitted %d new signals", len(emitted)), "signals": results})
+}
+
+// GetSignalChangelog handles GET /admin/api/signal-history/changes?date=2024-06-28 - returns new,
+// dropped, upgraded and downgraded signals versus the previous trading day (default: today)
+func (ac *AdminController) GetSignalChangelog(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if signals.GlobalConditionEvaluator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Condition evaluator not initialized"})
+		return
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.ParseInLocation(services.PriceDateFormat, value, services.MarketCalendar().Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	changelog, err := signals.GlobalConditionEvaluator.SignalChangelog(date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, changelog)
+}
+
+// GetSyncHistory handles GET /admin/api/sync/history - lists sync runs filtered by type, status and
+// date range (from/to as YYYY-MM-DD, inclusive) with per-type success metrics
+func (ac *AdminController) GetSyncHistory(c *gin.Context) {
+	if services.GlobalSyncHistory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sync history not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	filter := services.SyncHistoryFilter{
+		SyncType: c.Query("type"),
+		Status:   c.Query("status"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation(services.PriceDateFormat, from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation(services.PriceDateFormat, to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	history, total, err := services.GlobalSyncHistory.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := services.GlobalSyncHistory.Stats(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history":        history,
+		"total":          total,
+		"page":           page,
+		"page_size":      pageSize,
+		"stats":          stats,
+		"retention_days": services.GlobalSyncHistory.RetentionDays(),
+	})
+}
+
+// GetNotifications handles GET /admin/api/notifications?unread=true - returns admin notifications
+func (ac *AdminController) GetNotifications(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	db := ac.readDB()
+	query := db.Model(&models.AdminNotification{})
+	if c.Query("unread") == "true" {
+		query = query.Where("is_read = ?", false)
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var unread int64
+	db.Model(&models.AdminNotification{}).Where("is_read = ?", false).Count(&unread)
+
+	var notifications []models.AdminNotification
+	if err := query.Order("created_at DESC").Limit(100).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread": unread})
+}
+
+// MarkNotificationRead handles POST /admin/api/notifications/:id/read - marks a notification as read
+func (ac *AdminController) MarkNotificationRead(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	now := time.Now()
+	result := ac.db.Model(&models.AdminNotification{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"is_read": true, "read_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// GetOrderBookSpreads handles GET /admin/api/orderbook/spreads?code=VNM&days=30 - returns end-of-day spread history
+func (ac *AdminController) GetOrderBookSpreads(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 365 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days).Format(services.PriceDateFormat)
+
+	query := ac.readDB().Model(&models.OrderBookSnapshot{}).Where("date >= ?", since)
+	if code := strings.ToUpper(c.Query("code")); code != "" {
+		query = query.Where("code = ?", code)
+	}
+
+	var snapshots []models.OrderBookSnapshot
+	if err := query.Order("date DESC, code ASC").Limit(5000).Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Average the daily mean spread per code
+	type spreadSummary struct {
+		Code         string  `json:"code"`
+		Days         int     `json:"days"`
+		AvgSpreadPct float64 `json:"avg_spread_pct"`
+		MaxSpreadPct float64 `json:"max_spread_pct"`
+	}
+	byCode := make(map[string]*spreadSummary)
+	for _, snap := range snapshots {
+		summary, ok := byCode[snap.Code]
+		if !ok {
+			summary = &spreadSummary{Code: snap.Code}
+			byCode[snap.Code] = summary
+		}
+		summary.Days++
+		summary.AvgSpreadPct += snap.AvgSpreadPct
+		if snap.MaxSpreadPct > summary.MaxSpreadPct {
+			summary.MaxSpreadPct = snap.MaxSpreadPct
+		}
+	}
+	summaries := make([]*spreadSummary, 0, len(byCode))
+	for _, summary := range byCode {
+		summary.AvgSpreadPct /= float64(summary.Days)
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].AvgSpreadPct < summaries[j].AvgSpreadPct
+	})
+
+	var today []services.DepthDayStats
+	if services.GlobalRealtimeService != nil {
+		today = services.GlobalRealtimeService.GetDepthDayStats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots": snapshots,
+		"summary":   summaries,
+		"today":     today,
+	})
+}
+
+// GetMarketHolidays handles GET /admin/api/market/holidays - returns the trading calendar holidays
+// and the last year they cover
+func (ac *AdminController) GetMarketHolidays(c *gin.Context) {
+	calendar := services.MarketCalendar()
+	c.JSON(http.StatusOK, gin.H{"holidays": calendar.Holidays(), "last_year": calendar.LastHolidayYear()})
+}
+
+// UpdateMarketHolidays handles PUT /admin/api/market/holidays - replaces the holiday list
+func (ac *AdminController) UpdateMarketHolidays(c *gin.Context) {
+	var req struct {
+		Holidays []services.MarketHoliday `json:"holidays" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.MarketCalendar().SetHolidays(req.Holidays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Market holidays updated", "count": len(req.Holidays)})
+}
+
 func (ac *AdminController) TradingBotPage(c *gin.Context) {
 	adminUser := ac.getAdminUser(c)
 
@@ -284,7 +900,7 @@ func (ac *AdminController) CreateStrategyAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Strategy created", "id": strategy.ID})
 }
 
-// RunBacktestAction runs a backtest
+// RunBacktestAction starts a backtest job; poll GET /admin/api/jobs/:id for its result
 func (ac *AdminController) RunBacktestAction(c *gin.Context) {
 	if !ac.requireDatabaseAvailable(c) {
 		return
@@ -298,6 +914,12 @@ func (ac *AdminController) RunBacktestAction(c *gin.Context) {
 	startDate, _ := time.Parse("2006-01-02", c.PostForm("start_date"))
 	endDate, _ := time.Parse("2006-01-02", c.PostForm("end_date"))
 	initialCapital, _ := strconv.ParseFloat(c.PostForm("initial_capital"), 64)
+	slippageBps, _ := strconv.ParseFloat(c.PostForm("slippage_bps"), 64)
+	impactBps, _ := strconv.ParseFloat(c.PostForm("impact_bps"), 64)
+	maxParticipation, _ := strconv.ParseFloat(c.PostForm("max_participation"), 64)
+	targetVolatility, _ := strconv.ParseFloat(c.PostForm("target_volatility"), 64)
+	kellyCap, _ := strconv.ParseFloat(c.PostForm("kelly_cap"), 64)
+	maxPositions, _ := strconv.Atoi(c.PostForm("max_positions"))
 
 	symbols := c.PostFormArray("symbols[]")
 	if len(symbols) == 0 {
@@ -312,19 +934,43 @@ func (ac *AdminController) RunBacktestAction(c *gin.Context) {
 		Commission:     decimal.NewFromFloat(0.0015),
 		Symbols:        symbols,
 		RiskPerTrade:   decimal.NewFromFloat(0.02),
+		Mode:           c.PostForm("mode"),
+		MaxPositions:   maxPositions,
+		Sizing: backtesting.SizingConfig{
+			Method:           c.PostForm("sizing"),
+			TargetVolatility: decimal.NewFromFloat(targetVolatility),
+			KellyCap:         decimal.NewFromFloat(kellyCap),
+		},
+		Execution: backtesting.ExecutionConfig{
+			SlippageModel:    c.PostForm("slippage_model"),
+			SlippageBps:      decimal.NewFromFloat(slippageBps),
+			ImpactBps:        decimal.NewFromFloat(impactBps),
+			MaxParticipation: decimal.NewFromFloat(maxParticipation),
+			IgnoreSettlement: c.PostForm("ignore_settlement") == "true",
+		},
 	}
-
-	backtest, err := ac.backtestEngine.RunBacktest(config)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "Backtest completed",
-		"backtest_id":  backtest.ID,
-		"total_return": backtest.TotalReturn,
-		"win_rate":     backtest.WinRate,
+	// Backtests replay every trading day and can take minutes, so they run as a job
+	engine := ac.backtestEngine
+	submitJob(c, services.JobTypeBacktest, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		backtest, err := engine.RunBacktestContext(ctx, config, func(day time.Time, fraction float64) {
+			progress(fraction*100, day.Format("2006-01-02"), "Replaying trading days")
+		})
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"backtest_id":  backtest.ID,
+			"total_return":     backtest.TotalReturn,
+			"win_rate":         backtest.WinRate,
+			"benchmark_return": backtest.BenchmarkReturn,
+			"alpha":            backtest.Alpha,
+			"beta":             backtest.Beta,
+		}, nil
 	})
 }
 
@@ -388,9 +1034,10 @@ func (ac *AdminController) UsersPage(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	var total int64
-	ac.db.Model(&models.User{}).Count(&total)
+	db := ac.readDB()
+	db.Model(&models.User{}).Count(&total)
 
-	ac.db.Limit(limit).Offset(offset).Order("created_at DESC").Find(&users)
+	db.Limit(limit).Offset(offset).Order("created_at DESC").Find(&users)
 
 	c.HTML(http.StatusOK, "users.html", gin.H{
 		"users":     users,
@@ -423,14 +1070,15 @@ func (ac *AdminController) APIOverviewPage(c *gin.Context) {
 	adminUser := ac.getAdminUser(c)
 
 	// Get API statistics
+	db := ac.readDB()
 	var userCount int64
-	ac.db.Model(&models.User{}).Count(&userCount)
+	db.Model(&models.User{}).Count(&userCount)
 
 	var stockCount int64
-	ac.db.Model(&models.Stock{}).Count(&stockCount)
+	db.Model(&models.Stock{}).Count(&stockCount)
 
 	var subscriptionCount int64
-	ac.db.Model(&models.Subscription{}).Where("status = ?", "active").Count(&subscriptionCount)
+	db.Model(&models.Subscription{}).Where("status = ?", "active").Count(&subscriptionCount)
 
 	c.HTML(http.StatusOK, "api_overview.html", gin.H{
 		"userCount":         userCount,
@@ -513,10 +1161,12 @@ func (ac *AdminController) UpdateUserStatusAction(c *gin.Context) {
 
 	isActive := c.PostForm("is_active") == "true"
 
+	before := ac.localUserBefore(userID)
 	if err := ac.db.Model(&models.User{}).Where("id = ?", userID).Update("is_active", isActive).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
 		return
 	}
+	ac.publishLocalUserChange(c, before, func(after *models.User) { after.IsActive = isActive })
 
 	c.JSON(http.StatusOK, gin.H{"message": "User status updated"})
 }
@@ -552,10 +1202,12 @@ func (ac *AdminController) UpdateUserRoleAction(c *gin.Context) {
 		return
 	}
 
+	before := ac.localUserBefore(userID)
 	if err := ac.db.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
 		return
 	}
+	ac.publishLocalUserChange(c, before, func(after *models.User) { after.Role = role })
 
 	c.JSON(http.StatusOK, gin.H{"message": "User role updated"})
 }
@@ -634,6 +1286,18 @@ func (ac *AdminController) SignalConditionsPage(c *gin.Context) {
 	var rules []models.SignalRule
 	ac.db.Order("priority DESC, name ASC").Find(&rules)
 
+	// 3M performance per rule so underperformers can be spotted and retired
+	rulePerformance := make(map[uint]*signals.WindowPerformance)
+	if signals.GlobalConditionEvaluator != nil {
+		if entries, err := signals.GlobalConditionEvaluator.GetPerformanceLeaderboard("rule", "3M", 0); err == nil {
+			for _, entry := range entries {
+				if perf := entry.Windows["3M"]; perf != nil {
+					rulePerformance[entry.SourceID] = perf
+				}
+			}
+		}
+	}
+
 	// Get indicator types for dropdown
 	indicatorTypes := []map[string]string{
 		{"value": "RSI", "label": "RSI (14)", "category": "Oscillators"},
@@ -654,6 +1318,20 @@ func (ac *AdminController) SignalConditionsPage(c *gin.Context) {
 		{"value": "PRICE", "label": "Price", "category": "Price"},
 		{"value": "PRICE_CHANGE", "label": "Price Change %", "category": "Price"},
 		{"value": "TRADING_VALUE", "label": "Trading Value (Ty)", "category": "Volume"},
+		{"value": "OBV_TREND", "label": "OBV Trend (20D)", "category": "Volume"},
+		{"value": "AD_TREND", "label": "A/D Trend (20D)", "category": "Volume"},
+		{"value": "PCT_FROM_52W_HIGH", "label": "% From 52W High", "category": "Price"},
+		{"value": "PCT_FROM_52W_LOW", "label": "% From 52W Low", "category": "Price"},
+		{"value": "GAP_PERCENT", "label": "Opening Gap %", "category": "Price"},
+		{"value": "PCT_FROM_SUPPORT", "label": "% Above Nearest Support", "category": "Price"},
+		{"value": "PCT_FROM_RESISTANCE", "label": "% Below Nearest Resistance", "category": "Price"},
+		{"value": "ICHIMOKU_TENKAN", "label": "Ichimoku Tenkan (9)", "category": "Ichimoku"},
+		{"value": "ICHIMOKU_KIJUN", "label": "Ichimoku Kijun (26)", "category": "Ichimoku"},
+		{"value": "ICHIMOKU_SENKOU_A", "label": "Ichimoku Senkou A", "category": "Ichimoku"},
+		{"value": "ICHIMOKU_SENKOU_B", "label": "Ichimoku Senkou B", "category": "Ichimoku"},
+		{"value": "ICHIMOKU_CHIKOU", "label": "Ichimoku Chikou", "category": "Ichimoku"},
+		{"value": "PRICE_ABOVE_CLOUD", "label": "Price Above Cloud (1/0)", "category": "Ichimoku"},
+		{"value": "TK_CROSS_BULL", "label": "Tenkan/Kijun Bull Cross (1/0)", "category": "Ichimoku"},
 	}
 
 	operators := []map[string]string{
@@ -669,14 +1347,15 @@ func (ac *AdminController) SignalConditionsPage(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "signal_conditions.html", gin.H{
-		"adminUser":      adminUser,
-		"page":           "signal_conditions",
-		"title":          "Signal Conditions",
-		"groups":         groups,
-		"templates":      templates,
-		"rules":          rules,
-		"indicatorTypes": indicatorTypes,
-		"operators":      operators,
+		"adminUser":       adminUser,
+		"page":            "signal_conditions",
+		"title":           "Signal Conditions",
+		"groups":          groups,
+		"templates":       templates,
+		"rules":           rules,
+		"rulePerformance": rulePerformance,
+		"indicatorTypes":  indicatorTypes,
+		"operators":       operators,
 	})
 }
 
@@ -694,31 +1373,164 @@ func (ac *AdminController) CreateConditionGroupAction(c *gin.Context) {
 		return
 	}
 
-	adminUser := ac.getAdminUser(c)
-	createdBy := uint(0)
-	if adminUser != nil {
-		createdBy = adminUser.ID
+	adminUser := ac.getAdminUser(c)
+	createdBy := uint(0)
+	if adminUser != nil {
+		createdBy = adminUser.ID
+	}
+
+	group := &models.SignalConditionGroup{
+		Name:        request.Name,
+		Description: request.Description,
+		SignalType:  request.SignalType,
+		Priority:    request.Priority,
+		IsActive:    true,
+		CreatedBy:   createdBy,
+	}
+
+	if err := ac.db.Create(group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create condition group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Condition group created", "id": group.ID})
+}
+
+// UpdateConditionGroupAction updates a condition group
+func (ac *AdminController) UpdateConditionGroupAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		SignalType  string `json:"signal_type"`
+		Priority    int    `json:"priority"`
+		IsActive    bool   `json:"is_active"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":        request.Name,
+		"description": request.Description,
+		"signal_type": request.SignalType,
+		"priority":    request.Priority,
+		"is_active":   request.IsActive,
+	}
+
+	if err := ac.db.Model(&models.SignalConditionGroup{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update condition group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Condition group updated"})
+}
+
+// DeleteConditionGroupAction moves a condition group and its conditions to the trash
+func (ac *AdminController) DeleteConditionGroupAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	// Rules reference groups by ID inside their ConditionGroups JSON, so check
+	// for dependents before the group disappears from under them
+	mode := c.Query("mode") // "", "cascade" or "detach"
+	if mode != "" && mode != "cascade" && mode != "detach" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode, expected cascade or detach"})
+		return
+	}
+
+	dependents, err := ac.rulesReferencingGroup(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rule references"})
+		return
+	}
+
+	if mode == "" {
+		var active []gin.H
+		for _, rule := range dependents {
+			if rule.IsActive {
+				active = append(active, gin.H{"id": rule.ID, "name": rule.Name})
+			}
+		}
+		if len(active) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Condition group is used by active signal rules",
+				"rules": active,
+				"hint":  "Retry with ?mode=detach to remove the group from these rules, or ?mode=cascade to delete them too",
+			})
+			return
+		}
 	}
 
-	group := &models.SignalConditionGroup{
-		Name:        request.Name,
-		Description: request.Description,
-		SignalType:  request.SignalType,
-		Priority:    request.Priority,
-		IsActive:    true,
-		CreatedBy:   createdBy,
-	}
+	// Stamp the group and its conditions with the same deleted_at so a restore
+	// brings back exactly the conditions removed with the group
+	now := time.Now()
+	err = ac.db.Transaction(func(tx *gorm.DB) error {
+		for i := range dependents {
+			rule := &dependents[i]
+			switch mode {
+			case "cascade":
+				if err := tx.Model(&models.SignalRule{}).Where("id = ?", rule.ID).Update("deleted_at", now).Error; err != nil {
+					return err
+				}
+			case "detach":
+				refs, err := rule.GroupRefs()
+				if err != nil {
+					return err
+				}
+				kept := make([]models.RuleGroupRef, 0, len(refs))
+				for _, ref := range refs {
+					if ref.GroupID != uint(id) {
+						kept = append(kept, ref)
+					}
+				}
+				if err := rule.SetGroupRefs(kept); err != nil {
+					return err
+				}
+				if err := tx.Model(&models.SignalRule{}).Where("id = ?", rule.ID).Update("condition_groups", rule.ConditionGroups).Error; err != nil {
+					return err
+				}
+			}
+		}
 
-	if err := ac.db.Create(group).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create condition group"})
+		if err := tx.Model(&models.SignalCondition{}).Where("group_id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.SignalConditionGroup{}).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Condition group not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete condition group"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Condition group created", "id": group.ID})
+	c.JSON(http.StatusOK, gin.H{"message": "Condition group moved to trash", "affected_rules": len(dependents), "mode": mode})
 }
 
-// UpdateConditionGroupAction updates a condition group
-func (ac *AdminController) UpdateConditionGroupAction(c *gin.Context) {
+// GetConditionGroupDependentsAction returns the rules that reference a condition group
+func (ac *AdminController) GetConditionGroupDependentsAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -726,37 +1538,83 @@ func (ac *AdminController) UpdateConditionGroupAction(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		SignalType  string `json:"signal_type"`
-		Priority    int    `json:"priority"`
-		IsActive    bool   `json:"is_active"`
+	rules, err := ac.rulesReferencingGroup(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rule references"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "count": len(rules)})
+}
+
+// GetOrphanedReferencesAction lists rules whose ConditionGroups reference missing or deleted groups
+func (ac *AdminController) GetOrphanedReferencesAction(c *gin.Context) {
+	var groups []models.SignalConditionGroup
+	if err := ac.db.Select("id").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load condition groups"})
 		return
 	}
-
-	updates := map[string]interface{}{
-		"name":        request.Name,
-		"description": request.Description,
-		"signal_type": request.SignalType,
-		"priority":    request.Priority,
-		"is_active":   request.IsActive,
+	existing := make(map[uint]bool, len(groups))
+	for _, g := range groups {
+		existing[g.ID] = true
 	}
 
-	if err := ac.db.Model(&models.SignalConditionGroup{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update condition group"})
+	var rules []models.SignalRule
+	if err := ac.db.Order("id ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signal rules"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Condition group updated"})
+	var orphans []gin.H
+	for _, rule := range rules {
+		refs, err := rule.GroupRefs()
+		if err != nil {
+			orphans = append(orphans, gin.H{
+				"rule_id":   rule.ID,
+				"rule_name": rule.Name,
+				"is_active": rule.IsActive,
+				"error":     "Invalid condition_groups JSON",
+			})
+			continue
+		}
+
+		var missing []uint
+		for _, ref := range refs {
+			if !existing[ref.GroupID] {
+				missing = append(missing, ref.GroupID)
+			}
+		}
+		if len(missing) > 0 {
+			orphans = append(orphans, gin.H{
+				"rule_id":        rule.ID,
+				"rule_name":      rule.Name,
+				"is_active":      rule.IsActive,
+				"missing_groups": missing,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orphans": orphans, "count": len(orphans)})
 }
 
-// DeleteConditionGroupAction deletes a condition group
-func (ac *AdminController) DeleteConditionGroupAction(c *gin.Context) {
+// rulesReferencingGroup returns non-deleted rules whose ConditionGroups include groupID
+func (ac *AdminController) rulesReferencingGroup(groupID uint) ([]models.SignalRule, error) {
+	var rules []models.SignalRule
+	if err := ac.db.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	var dependents []models.SignalRule
+	for _, rule := range rules {
+		if rule.ReferencesGroup(groupID) {
+			dependents = append(dependents, rule)
+		}
+	}
+	return dependents, nil
+}
+
+// RestoreConditionGroupAction restores a condition group and the conditions deleted with it
+func (ac *AdminController) RestoreConditionGroupAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -764,15 +1622,26 @@ func (ac *AdminController) DeleteConditionGroupAction(c *gin.Context) {
 		return
 	}
 
-	// Delete conditions first
-	ac.db.Where("group_id = ?", id).Delete(&models.SignalCondition{})
+	var group models.SignalConditionGroup
+	if err := ac.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Condition group not found in trash"})
+		return
+	}
 
-	if err := ac.db.Delete(&models.SignalConditionGroup{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete condition group"})
+	err = ac.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.SignalCondition{}).
+			Where("group_id = ? AND deleted_at = ?", id, group.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.SignalConditionGroup{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore condition group"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Condition group deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Condition group restored"})
 }
 
 // AddConditionAction adds a condition to a group
@@ -877,7 +1746,7 @@ func (ac *AdminController) UpdateConditionAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Condition updated"})
 }
 
-// DeleteConditionAction deletes a condition
+// DeleteConditionAction moves a condition to the trash
 func (ac *AdminController) DeleteConditionAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -891,7 +1760,29 @@ func (ac *AdminController) DeleteConditionAction(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Condition deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Condition moved to trash"})
+}
+
+// RestoreConditionAction restores a deleted condition whose group is not in the trash
+func (ac *AdminController) RestoreConditionAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var condition models.SignalCondition
+	if err := ac.db.Unscoped().First(&condition, id).Error; err == nil {
+		var trashedGroups int64
+		ac.db.Unscoped().Model(&models.SignalConditionGroup{}).
+			Where("id = ? AND deleted_at IS NOT NULL", condition.GroupID).Count(&trashedGroups)
+		if trashedGroups > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The condition's group is in the trash; restore the group first"})
+			return
+		}
+	}
+	ac.restoreFromTrash(c, &models.SignalCondition{}, "Condition")
 }
 
 // CreateSignalRuleAction creates a new signal rule
@@ -905,6 +1796,8 @@ func (ac *AdminController) CreateSignalRuleAction(c *gin.Context) {
 		TargetPercent   float64 `json:"target_percent"`
 		StopLossPercent float64 `json:"stop_loss_percent"`
 		Priority        int     `json:"priority"`
+		CooldownHours   int     `json:"cooldown_hours"`
+		ExpiryDays      int     `json:"expiry_days"`
 		GroupIDs        []uint  `json:"group_ids"`
 	}
 
@@ -922,6 +1815,12 @@ func (ac *AdminController) CreateSignalRuleAction(c *gin.Context) {
 	if request.StopLossPercent == 0 {
 		request.StopLossPercent = 5
 	}
+	if request.CooldownHours <= 0 {
+		request.CooldownHours = signals.DefaultSignalCooldownHours
+	}
+	if request.ExpiryDays <= 0 {
+		request.ExpiryDays = signals.DefaultSignalExpiryDays
+	}
 
 	// Build condition groups JSON
 	var groupConfigs []map[string]interface{}
@@ -950,6 +1849,8 @@ func (ac *AdminController) CreateSignalRuleAction(c *gin.Context) {
 		StopLossPercent: decimal.NewFromFloat(request.StopLossPercent),
 		Priority:        request.Priority,
 		ConditionGroups: string(groupsJSON),
+		CooldownHours:   request.CooldownHours,
+		ExpiryDays:      request.ExpiryDays,
 		IsActive:        true,
 		CreatedBy:       createdBy,
 	}
@@ -980,6 +1881,8 @@ func (ac *AdminController) UpdateSignalRuleAction(c *gin.Context) {
 		TargetPercent   float64 `json:"target_percent"`
 		StopLossPercent float64 `json:"stop_loss_percent"`
 		Priority        int     `json:"priority"`
+		CooldownHours   int     `json:"cooldown_hours"`
+		ExpiryDays      int     `json:"expiry_days"`
 		IsActive        bool    `json:"is_active"`
 		GroupIDs        []uint  `json:"group_ids"`
 	}
@@ -1017,6 +1920,12 @@ func (ac *AdminController) UpdateSignalRuleAction(c *gin.Context) {
 	if len(groupsJSON) > 0 {
 		updates["condition_groups"] = string(groupsJSON)
 	}
+	if request.CooldownHours > 0 {
+		updates["cooldown_hours"] = request.CooldownHours
+	}
+	if request.ExpiryDays > 0 {
+		updates["expiry_days"] = request.ExpiryDays
+	}
 
 	if err := ac.db.Model(&models.SignalRule{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update signal rule"})
@@ -1026,7 +1935,7 @@ func (ac *AdminController) UpdateSignalRuleAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Signal rule updated"})
 }
 
-// DeleteSignalRuleAction deletes a signal rule
+// DeleteSignalRuleAction moves a signal rule to the trash
 func (ac *AdminController) DeleteSignalRuleAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -1040,10 +1949,53 @@ func (ac *AdminController) DeleteSignalRuleAction(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Signal rule deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Signal rule moved to trash"})
+}
+
+// RetireSignalRuleAction deactivates a signal rule without deleting its history
+func (ac *AdminController) RetireSignalRuleAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	result := ac.db.Model(&models.SignalRule{}).Where("id = ?", id).Update("is_active", false)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retire signal rule"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Signal rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signal rule retired"})
+}
+
+// RestoreSignalRuleAction restores a deleted signal rule
+func (ac *AdminController) RestoreSignalRuleAction(c *gin.Context) {
+	ac.restoreFromTrash(c, &models.SignalRule{}, "Signal rule")
+}
+
+// indicatorsAsOf recomputes indicators as of the request's ?as_of=YYYY-MM-DD date. It returns
+// nil indicators when as_of is not set, and responds 400 and reports false when it is invalid.
+func indicatorsAsOf(c *gin.Context) (map[string]*services.ExtendedStockIndicators, string, bool) {
+	asOf := c.Query("as_of")
+	if asOf == "" {
+		return nil, "", true
+	}
+	indicators, tradingDate, err := services.IndicatorsAsOf(asOf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return indicators, tradingDate, true
 }
 
-// TestSignalRuleAction tests a signal rule against current stock data
+// TestSignalRuleAction tests a signal rule against current stock data, or against indicators
+// recomputed from price history up to ?as_of=YYYY-MM-DD
 func (ac *AdminController) TestSignalRuleAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -1063,7 +2015,16 @@ func (ac *AdminController) TestSignalRuleAction(c *gin.Context) {
 		return
 	}
 
-	results, err := signals.GlobalConditionEvaluator.ScreenStocksWithRule(uint(id), minTradingVal, limit)
+	asOfIndicators, asOf, ok := indicatorsAsOf(c)
+	if !ok {
+		return
+	}
+	var results []*signals.RuleSignal
+	if asOfIndicators != nil {
+		results, err = signals.GlobalConditionEvaluator.ScreenIndicatorsWithRule(uint(id), asOfIndicators, minTradingVal, limit)
+	} else {
+		results, err = signals.GlobalConditionEvaluator.ScreenStocksWithRule(uint(id), minTradingVal, limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1089,10 +2050,11 @@ func (ac *AdminController) TestSignalRuleAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"signals": signalsOut,
 		"count":   len(signalsOut),
+		"as_of":   asOf,
 	})
 }
 
-// TestTemplateAction tests a signal template against current stock data
+// TestTemplateAction tests a signal template against current stock data, or as of ?as_of=YYYY-MM-DD
 func (ac *AdminController) TestTemplateAction(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -1112,7 +2074,16 @@ func (ac *AdminController) TestTemplateAction(c *gin.Context) {
 		return
 	}
 
-	results, err := signals.GlobalConditionEvaluator.ScreenStocksWithTemplate(uint(id), minTradingVal, limit)
+	asOfIndicators, asOf, ok := indicatorsAsOf(c)
+	if !ok {
+		return
+	}
+	var results []*signals.RuleSignal
+	if asOfIndicators != nil {
+		results, err = signals.GlobalConditionEvaluator.ScreenIndicatorsWithTemplate(uint(id), asOfIndicators, minTradingVal, limit)
+	} else {
+		results, err = signals.GlobalConditionEvaluator.ScreenStocksWithTemplate(uint(id), minTradingVal, limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1137,6 +2108,7 @@ func (ac *AdminController) TestTemplateAction(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"signals": signalsOut,
 		"count":   len(signalsOut),
+		"as_of":   asOf,
 	})
 }
 
@@ -1163,11 +2135,58 @@ func (ac *AdminController) GetRuleStatisticsAction(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// TestStockWithConditionsAction tests a specific stock against a condition group or rule
+// SimulateSignalRuleAction replays a rule over the stored price history of one stock and lists
+// every day it would have fired with the 5, 10 and 20-day returns that followed
+func (ac *AdminController) SimulateSignalRuleAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	stockCode := strings.ToUpper(c.Query("stock"))
+	if stockCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock code is required"})
+		return
+	}
+	from, to := c.Query("from"), c.Query("to")
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse(services.PriceDateFormat, date); date != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date " + date + ", expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	if signals.GlobalConditionEvaluator == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Condition evaluator not initialized"})
+		return
+	}
+
+	var rule models.SignalRule
+	if err := ac.db.First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	simulation, err := signals.GlobalConditionEvaluator.SimulateRule(c.Request.Context(), &rule, stockCode, from, to)
+	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+// TestStockWithConditionsAction tests a specific stock against a condition group or rule, on
+// current indicators or as of ?as_of=YYYY-MM-DD. With ?explain=true it also returns the trace
+// of every condition: values, threshold, weight and the logic step that decided the outcome.
 func (ac *AdminController) TestStockWithConditionsAction(c *gin.Context) {
 	stockCode := c.Query("stock")
 	groupIDStr := c.Query("group_id")
 	ruleIDStr := c.Query("rule_id")
+	explain := c.Query("explain") == "true"
 
 	if stockCode == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock code is required"})
@@ -1175,11 +2194,25 @@ func (ac *AdminController) TestStockWithConditionsAction(c *gin.Context) {
 	}
 
 	// Get stock indicators
-	indicators, err := services.GlobalIndicatorService.GetStockIndicators(stockCode)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Stock indicators not found"})
+	asOfIndicators, asOf, ok := indicatorsAsOf(c)
+	if !ok {
 		return
 	}
+	var indicators *services.ExtendedStockIndicators
+	if asOfIndicators != nil {
+		indicators = asOfIndicators[strings.ToUpper(stockCode)]
+		if indicators == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stock did not trade on " + asOf})
+			return
+		}
+	} else {
+		var err error
+		indicators, err = services.GlobalIndicatorService.GetStockIndicators(stockCode)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stock indicators not found"})
+			return
+		}
+	}
 
 	if signals.GlobalConditionEvaluator == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Condition evaluator not initialized"})
@@ -1188,6 +2221,7 @@ func (ac *AdminController) TestStockWithConditionsAction(c *gin.Context) {
 
 	result := gin.H{
 		"stock": stockCode,
+		"as_of": asOf,
 		"price": indicators.CurrentPrice,
 		"indicators": map[string]interface{}{
 			"rsi":              indicators.RSI,
@@ -1222,6 +2256,9 @@ func (ac *AdminController) TestStockWithConditionsAction(c *gin.Context) {
 				"max_score":   groupResult.MaxScore,
 				"conditions":  groupResult.Results,
 			}
+			if explain {
+				result["group_explain"] = signals.GlobalConditionEvaluator.ExplainConditionGroup(&group, indicators)
+			}
 		}
 	}
 
@@ -1245,6 +2282,11 @@ func (ac *AdminController) TestStockWithConditionsAction(c *gin.Context) {
 				result["rule_signal"] = nil
 				result["rule_message"] = "No signal triggered"
 			}
+			if explain {
+				if trace, err := signals.GlobalConditionEvaluator.ExplainRule(&rule, indicators); err == nil {
+					result["rule_explain"] = trace
+				}
+			}
 		}
 	}
 
@@ -1316,3 +2358,152 @@ func (ac *AdminController) CreateTemplateFromGroupAction(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Template created", "id": template.ID})
 }
+
+// DeleteTemplateAction moves a custom signal template to the trash
+func (ac *AdminController) DeleteTemplateAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var template models.SignalTemplate
+	if err := ac.db.First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	if template.IsBuiltIn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in templates cannot be deleted"})
+		return
+	}
+
+	if err := ac.db.Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template moved to trash"})
+}
+
+// FeatureTemplateAction marks or unmarks a public template as featured in the marketplace
+func (ac *AdminController) FeatureTemplateAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var request struct {
+		Featured bool `json:"featured"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{"is_featured": request.Featured}
+	if request.Featured {
+		// Featuring implies the template is visible in the marketplace
+		updates["visibility"] = models.TemplateVisibilityPublic
+	}
+
+	result := ac.db.Model(&models.SignalTemplate{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template updated"})
+}
+
+// UnpublishTemplateAction removes a community template from the public marketplace
+func (ac *AdminController) UnpublishTemplateAction(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	result := ac.db.Model(&models.SignalTemplate{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"visibility":  models.TemplateVisibilityPrivate,
+		"is_featured": false,
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish template"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template unpublished"})
+}
+
+// RestoreTemplateAction restores a deleted signal template
+func (ac *AdminController) RestoreTemplateAction(c *gin.Context) {
+	ac.restoreFromTrash(c, &models.SignalTemplate{}, "Template")
+}
+
+// GetTrashAction returns soft-deleted groups, conditions, rules and templates
+func (ac *AdminController) GetTrashAction(c *gin.Context) {
+	var groups []models.SignalConditionGroup
+	ac.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&groups)
+
+	// Conditions deleted together with their group are restored with the group,
+	// so only list the ones deleted on their own
+	var conditions []models.SignalCondition
+	ac.db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Where("group_id NOT IN (?)", ac.db.Unscoped().Model(&models.SignalConditionGroup{}).Select("id").Where("deleted_at IS NOT NULL")).
+		Order("deleted_at DESC").Find(&conditions)
+
+	var rules []models.SignalRule
+	ac.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&rules)
+
+	var templates []models.SignalTemplate
+	ac.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&templates)
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":         groups,
+		"conditions":     conditions,
+		"rules":          rules,
+		"templates":      templates,
+		"retention_days": models.SignalTrashRetentionDays,
+	})
+}
+
+// restoreFromTrash clears deleted_at on a soft-deleted record identified by :id
+func (ac *AdminController) restoreFromTrash(c *gin.Context, model interface{}, label string) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	result := ac.db.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if result.Error != nil && strings.Contains(result.Error.Error(), "duplicate key") {
+		// Names are only unique outside the trash, so the name may have been reused meanwhile
+		c.JSON(http.StatusConflict, gin.H{"error": "A live " + strings.ToLower(label) + " now uses this name; rename that " + strings.ToLower(label) + " before restoring this one"})
+		return
+	}
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore " + strings.ToLower(label)})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": label + " not found in trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": label + " restored"})
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsPage renders the analytics dashboard
+func (ac *AdminController) AnalyticsPage(c *gin.Context) {
+	adminUser := ac.getAdminUser(c)
+
+	c.HTML(http.StatusOK, "analytics.html", gin.H{
+		"adminUser": adminUser,
+		"page":      "analytics",
+		"title":     "Analytics",
+	})
+}
+
+// GetAnalytics handles GET /admin/api/analytics - daily active users and requests, top endpoints,
+// most viewed stocks and most triggered rules between ?from= and ?to= (YYYY-MM-DD, last 30 days
+// by default), top ?limit= entries; ?format=csv downloads the report
+func (ac *AdminController) GetAnalytics(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	from, to, err := services.ParseAnalyticsRange(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultAnalyticsTopN)))
+
+	report, err := services.GetAnalyticsReport(ac.readDB(), from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("analytics_%s_%s.csv", report.From, report.To)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"section", "date", "key", "label", "count", "users", "errors", "avg_ms"})
+	for _, d := range report.Days {
+		w.Write([]string{"daily", d.Date, "", "", strconv.FormatInt(d.Requests, 10),
+			strconv.FormatInt(d.ActiveUsers, 10), strconv.FormatInt(d.Errors, 10), fmt.Sprint(d.AvgMs)})
+	}
+	for _, section := range []struct {
+		name  string
+		ranks []services.AnalyticsRank
+	}{
+		{"endpoint", report.TopEndpoints},
+		{"stock", report.TopStocks},
+		{"rule", report.TopRules},
+	} {
+		for _, r := range section.ranks {
+			w.Write([]string{section.name, "", r.Key, r.Label, strconv.FormatInt(r.Count, 10),
+				strconv.FormatInt(r.UserDays, 10), strconv.FormatInt(r.Errors, 10), fmt.Sprint(r.AvgMs)})
+		}
+	}
+	w.Flush()
+}
+
+// RollupAnalytics handles POST /admin/api/analytics/rollup - recomputes the stored analytics of
+// ?date= (YYYY-MM-DD) from the usage logs still kept, e.g. after a failed nightly rollup
+func (ac *AdminController) RollupAnalytics(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	day, err := time.Parse(services.AnalyticsDateFormat, c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	// Noon of the day in market time, so the market day is the one requested
+	at := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, services.MarketCalendar().Location())
+	rows, err := services.RollupAnalyticsDay(ac.db, at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll up analytics"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"date": day.Format(services.AnalyticsDateFormat), "rows": rows})
+}
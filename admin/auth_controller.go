@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"go_backend_project/middleware"
 	"go_backend_project/models"
 	"go_backend_project/services"
 
@@ -201,7 +202,7 @@ func (ac *AuthController) createSessionAndRedirect(c *gin.Context, admin *models
 	session := models.AdminSession{
 		AdminUserID: admin.ID,
 		Token:       token,
-		IPAddress:   c.ClientIP(),
+		IPAddress:   middleware.ClientIP(c),
 		UserAgent:   c.Request.UserAgent(),
 		ExpiresAt:   time.Now().Add(24 * time.Hour), // 24 hour session
 	}
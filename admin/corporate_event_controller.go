@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncCorporateEvents handles POST /admin/api/events/sync - fetches the corporate events of
+// {"codes": [...]} (all listed stocks when empty) from VNDirect as a background job
+func (ac *AdminController) SyncCorporateEvents(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Codes []string `json:"codes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes := make([]string, 0, len(req.Codes))
+	for _, code := range req.Codes {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	db := ac.db
+	submitJob(c, services.JobTypeEventSync, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		if len(codes) == 0 {
+			all, err := services.EventSyncCodes()
+			if err != nil {
+				return nil, err
+			}
+			codes = all
+		}
+		return services.SyncCorporateEvents(ctx, db, codes, progress)
+	})
+}
+
+// GetCorporateEvents handles GET /admin/api/events?code=&type=&days=30 - lists upcoming
+// corporate events
+func (ac *AdminController) GetCorporateEvents(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 365 {
+		days = 30
+	}
+	var codes []string
+	if code := strings.ToUpper(strings.TrimSpace(c.Query("code"))); code != "" {
+		codes = []string{code}
+	}
+
+	events, err := services.UpcomingCorporateEvents(ac.readDB(), codes, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if eventType := c.Query("type"); eventType != "" {
+		filtered := make([]models.CorporateEvent, 0, len(events))
+		for _, e := range events {
+			if e.Type == eventType {
+				filtered = append(filtered, e)
+			}
+		}
+		events = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": len(events)})
+}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCustomStrategies handles GET /admin/api/custom-strategies - lists user-defined strategies
+func (ac *AdminController) GetCustomStrategies(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var defs []models.CustomStrategy
+	if err := ac.db.Order("name ASC").Find(&defs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"strategies": defs,
+		"fields":     signals.ExpressionFieldNames(),
+		"functions":  []string{"abs(x)", "min(a, b)", "max(a, b)", "clamp(x, lo, hi)", "pct(a, b)", "if(cond, a, b)"},
+	})
+}
+
+// CreateCustomStrategy handles POST /admin/api/custom-strategies - validates, stores and registers a strategy
+func (ac *AdminController) CreateCustomStrategy(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	def := signals.DefaultCustomStrategy()
+	if err := c.ShouldBindJSON(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	def.ID = 0
+	if _, err := signals.NewExpressionStrategy(def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	ac.db.Model(&models.CustomStrategy{}).Where("name = ?", def.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Strategy name already exists"})
+		return
+	}
+
+	if adminUser := ac.getAdminUser(c); adminUser != nil {
+		def.CreatedBy = adminUser.ID
+	}
+	if err := ac.db.Create(&def).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := signals.ApplyCustomStrategy(def, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Custom strategy created", "strategy": def})
+}
+
+// UpdateCustomStrategy handles PUT /admin/api/custom-strategies/:id - updates and re-registers a strategy
+func (ac *AdminController) UpdateCustomStrategy(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var existing models.CustomStrategy
+	if err := ac.db.First(&existing, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategy not found"})
+		return
+	}
+
+	def := existing
+	if err := c.ShouldBindJSON(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	def.ID, def.CreatedBy, def.CreatedAt = existing.ID, existing.CreatedBy, existing.CreatedAt
+	if _, err := signals.NewExpressionStrategy(def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if def.Name != existing.Name {
+		var count int64
+		ac.db.Model(&models.CustomStrategy{}).Where("name = ? AND id <> ?", def.Name, def.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Strategy name already exists"})
+			return
+		}
+	}
+
+	if err := ac.db.Save(&def).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := signals.ApplyCustomStrategy(def, existing.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom strategy updated", "strategy": def})
+}
+
+// DeleteCustomStrategy handles DELETE /admin/api/custom-strategies/:id - removes and unregisters a strategy
+func (ac *AdminController) DeleteCustomStrategy(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var def models.CustomStrategy
+	if err := ac.db.First(&def, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategy not found"})
+		return
+	}
+	if err := ac.db.Delete(&def).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if signals.GlobalSignalService != nil {
+		signals.GlobalSignalService.UnregisterStrategy(def.Name)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom strategy deleted"})
+}
+
+// ValidateCustomStrategy handles POST /admin/api/custom-strategies/validate - checks a definition
+// and optionally previews its signal for a stock code without saving
+func (ac *AdminController) ValidateCustomStrategy(c *gin.Context) {
+	var req struct {
+		models.CustomStrategy
+		Code string `json:"code"`
+	}
+	req.CustomStrategy = signals.DefaultCustomStrategy()
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = "preview"
+	}
+
+	strategy, err := signals.NewExpressionStrategy(req.CustomStrategy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"valid": false, "error": err.Error()})
+		return
+	}
+	if req.Code == "" {
+		c.JSON(http.StatusOK, gin.H{"valid": true})
+		return
+	}
+
+	ind, err := services.GlobalIndicatorService.GetStockIndicators(req.Code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"valid": true, "error": "Stock indicators not found: " + err.Error()})
+		return
+	}
+	signal, _ := strategy.Evaluate(ind)
+	signal.Code = req.Code
+
+	c.JSON(http.StatusOK, gin.H{"valid": true, "signal": signal})
+}
+
+// GetStrategyPlugins handles GET /admin/api/strategy-plugins - lists third-party strategy plugins and their health
+func (ac *AdminController) GetStrategyPlugins(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"plugins": signals.GetStrategyPlugins()})
+}
+
+// CheckStrategyPlugin handles POST /admin/api/strategy-plugins/:name/health - runs a plugin health check,
+// re-enabling the plugin when it passes
+func (ac *AdminController) CheckStrategyPlugin(c *gin.Context) {
+	info, err := signals.CheckStrategyPlugin(c.Param("name"))
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		if info.Name == "" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "plugin": info})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Plugin healthy", "plugin": info})
+}
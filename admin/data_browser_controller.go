@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataBrowserPage shows the read-only SQL data browser
+func (ac *AdminController) DataBrowserPage(c *gin.Context) {
+	adminUser := ac.getAdminUser(c)
+
+	c.HTML(http.StatusOK, "data_browser.html", gin.H{
+		"adminUser": adminUser,
+		"page":      "data_browser",
+		"title":     "Data Browser",
+	})
+}
+
+// GetDataBrowserTables handles GET /admin/api/data-browser/tables - lists the tables that can be queried
+func (ac *AdminController) GetDataBrowserTables(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	tables, err := services.DataBrowserTables(ac.readDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tables":        tables,
+		"max_page_size": services.DataBrowserMaxPageSize,
+		"max_export":    services.DataBrowserMaxExportRows,
+	})
+}
+
+// RunDataBrowserQuery handles POST /admin/api/data-browser/query - runs a read-only SELECT against
+// whitelisted tables and returns one page, or up to the export limit as CSV with ?format=csv
+func (ac *AdminController) RunDataBrowserQuery(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Query    string `json:"query" binding:"required"`
+		Page     int    `json:"page"`
+		PageSize int    `json:"page_size"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export := c.Query("format") == "csv"
+	if export {
+		req.Page, req.PageSize = 1, services.DataBrowserMaxExportRows
+	} else if req.PageSize < 1 || req.PageSize > services.DataBrowserMaxPageSize {
+		req.PageSize = 100
+	}
+
+	result, err := services.RunReadOnlyQuery(ac.readDB(), req.Query, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !export {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	filename := fmt.Sprintf("data_browser_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(result.Columns)
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, v := range row {
+			switch v := v.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format(time.RFC3339)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDebugCapture handles GET /admin/api/debug-capture - returns the request capture settings
+func (ac *AdminController) GetDebugCapture(c *gin.Context) {
+	cfg := services.CurrentDebugCapture()
+	c.JSON(http.StatusOK, gin.H{
+		"config":      cfg,
+		"active":      cfg.Active(time.Now()),
+		"captured":    middleware.DebugCaptures.Len(),
+		"buffer_size": middleware.DebugCaptureBufferSize,
+	})
+}
+
+// UpdateDebugCapture handles PUT /admin/api/debug-capture - samples requests to some routes with
+// their bodies, e.g. {"enabled": true, "sample_pct": 10, "routes": ["/api/v1/signals"],
+// "until": "<RFC3339>"}; without until capture stops after an hour
+func (ac *AdminController) UpdateDebugCapture(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var cfg services.DebugCaptureConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg.UpdatedBy = c.GetString("admin_username")
+
+	if err := services.SaveDebugCapture(ac.db, cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Debug capture updated", "config": services.CurrentDebugCapture()})
+}
+
+// GetCapturedRequests handles GET /admin/api/debug-capture/requests - returns the requests
+// captured by this instance, newest first (?route=/api/v1/signals&limit=50)
+func (ac *AdminController) GetCapturedRequests(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	requests := middleware.DebugCaptures.List(c.Query("route"), limit)
+	c.JSON(http.StatusOK, gin.H{"requests": requests, "count": len(requests)})
+}
+
+// ClearCapturedRequests handles DELETE /admin/api/debug-capture/requests - empties the capture
+// buffer of this instance
+func (ac *AdminController) ClearCapturedRequests(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "Captured requests cleared", "cleared": middleware.DebugCaptures.Clear()})
+}
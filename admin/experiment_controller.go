@@ -0,0 +1,167 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentsPage renders the A/B experiments page
+func (ac *AdminController) ExperimentsPage(c *gin.Context) {
+	adminUser := ac.getAdminUser(c)
+
+	c.HTML(http.StatusOK, "experiments.html", gin.H{
+		"adminUser": adminUser,
+		"page":      "experiments",
+		"title":     "Experiments",
+	})
+}
+
+// GetExperiments handles GET /admin/api/experiments - lists the experiments, newest first
+func (ac *AdminController) GetExperiments(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var experiments []models.Experiment
+	if err := ac.db.Order("created_at DESC").Find(&experiments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch experiments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": experiments})
+}
+
+// CreateExperiment handles POST /admin/api/experiments - creates a draft experiment
+// ({"key": "signal_badge", "route_prefix": "/api/v1/signals/", "variants": [{"key": "confidence"},
+// {"key": "strength"}], "traffic_pct": 50})
+func (ac *AdminController) CreateExperiment(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var input services.ExperimentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exp, err := services.CreateExperiment(ac.db, input, c.GetString("admin_username"))
+	if errors.Is(err, services.ErrInvalidExperiment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create experiment"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": exp})
+}
+
+// UpdateExperiment handles PUT /admin/api/experiments/:id - changes a draft experiment; once
+// started only the name, description and a larger traffic share are accepted
+func (ac *AdminController) UpdateExperiment(c *gin.Context) {
+	exp, ok := ac.loadExperiment(c)
+	if !ok {
+		return
+	}
+
+	var input services.ExperimentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.UpdateExperiment(ac.db, exp, input)
+	if errors.Is(err, services.ErrInvalidExperiment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update experiment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": exp})
+}
+
+// StartExperiment handles POST /admin/api/experiments/:id/start - starts or resumes an experiment
+func (ac *AdminController) StartExperiment(c *gin.Context) {
+	ac.setExperimentStatus(c, models.ExperimentStatusRunning)
+}
+
+// StopExperiment handles POST /admin/api/experiments/:id/stop - stops assigning variants; the
+// exposures and events are kept for the results
+func (ac *AdminController) StopExperiment(c *gin.Context) {
+	ac.setExperimentStatus(c, models.ExperimentStatusStopped)
+}
+
+func (ac *AdminController) setExperimentStatus(c *gin.Context, status string) {
+	exp, ok := ac.loadExperiment(c)
+	if !ok {
+		return
+	}
+
+	err := services.SetExperimentStatus(ac.db, exp, status)
+	if errors.Is(err, services.ErrInvalidExperiment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update experiment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": exp})
+}
+
+// GetExperimentResults handles GET /admin/api/experiments/:id/results - users, exposures, API
+// requests per user, returning users and event conversions of each variant
+func (ac *AdminController) GetExperimentResults(c *gin.Context) {
+	exp, ok := ac.loadExperiment(c)
+	if !ok {
+		return
+	}
+
+	results, err := services.GetExperimentResults(ac.readDB(), exp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute results"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// DeleteExperiment handles DELETE /admin/api/experiments/:id - removes an experiment with its
+// exposures and events
+func (ac *AdminController) DeleteExperiment(c *gin.Context) {
+	exp, ok := ac.loadExperiment(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteExperiment(ac.db, exp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete experiment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Experiment deleted"})
+}
+
+// loadExperiment loads the experiment in the id parameter
+func (ac *AdminController) loadExperiment(c *gin.Context) (*models.Experiment, bool) {
+	if !ac.requireDatabaseAvailable(c) {
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var exp models.Experiment
+	if err := ac.db.First(&exp, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return nil, false
+	}
+	return &exp, true
+}
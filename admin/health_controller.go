@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIncidents handles GET /admin/api/incidents - lists health incidents, newest first,
+// optionally only those with a ?status= (open, resolved) or of a ?check=
+func (ac *AdminController) GetIncidents(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := ac.db.Model(&models.Incident{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if check := c.Query("check"); check != "" {
+		query = query.Where("check_name = ?", check)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var incidents []models.Incident
+	if err := query.Order("opened_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&incidents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch incidents"})
+		return
+	}
+
+	response := gin.H{
+		"data": incidents,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+		"alert_channels": services.GlobalOperatorAlerts.Channels(),
+	}
+	if services.GlobalHealthMonitor != nil {
+		response["thresholds"] = services.GlobalHealthMonitor.Thresholds()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RunHealthCheck handles POST /admin/api/health/check - runs the health checks now instead of
+// waiting for the scheduler, opening or resolving incidents
+func (ac *AdminController) RunHealthCheck(c *gin.Context) {
+	if services.GlobalHealthMonitor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Health monitor not available"})
+		return
+	}
+
+	readings, err := services.GlobalHealthMonitor.Check(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Health check failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": readings})
+}
+
+// SendTestAlert handles POST /admin/api/health/test-alert - sends a test alert on every
+// configured channel to verify the Telegram and SMTP settings
+func (ac *AdminController) SendTestAlert(c *gin.Context) {
+	channels := services.GlobalOperatorAlerts.Channels()
+	if len(channels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No alert channel configured"})
+		return
+	}
+
+	err := services.GlobalOperatorAlerts.Send("[TEST] Operator alert",
+		"Test alert sent by "+c.GetString("admin_username")+" from the admin panel")
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "channels": channels})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test alert sent", "channels": channels})
+}
+
+// RunSelfTest handles POST /admin/api/selftest - checks the connectivity and credentials of the
+// external dependencies and returns a pass/fail report; nothing is sent to the alert channels
+func (ac *AdminController) RunSelfTest(c *gin.Context) {
+	report := services.RunSelfTest(c.Request.Context(), ac.db)
+	c.JSON(http.StatusOK, report)
+}
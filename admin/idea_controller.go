@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIdeas handles GET /admin/api/ideas - lists trade ideas for moderation, most reported first
+// with ?reported=true, optionally only those with a status
+func (ac *AdminController) GetIdeas(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := ac.db.Model(&models.Idea{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if code := c.Query("stock"); code != "" {
+		query = query.Where("stock_code = ?", code)
+	}
+	if c.Query("reported") == "true" {
+		query = query.Where("report_count > 0")
+	}
+
+	var total int64
+	query.Count(&total)
+	if c.Query("reported") == "true" {
+		query = query.Order("report_count DESC")
+	}
+
+	var ideas []models.Idea
+	if err := query.Order("created_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&ideas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ideas"})
+		return
+	}
+
+	// The report count and moderator are hidden from the public feeds
+	type moderatedIdea struct {
+		models.Idea
+		Reports     int    `json:"reports"`
+		ModeratedBy string `json:"moderated_by,omitempty"`
+	}
+	data := make([]moderatedIdea, len(ideas))
+	for i, idea := range ideas {
+		data[i] = moderatedIdea{Idea: idea, Reports: idea.ReportCount, ModeratedBy: idea.ModeratedBy}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetIdeaReports handles GET /admin/api/ideas/:id/reports - lists the reports of an idea
+func (ac *AdminController) GetIdeaReports(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var reports []models.IdeaReport
+	if err := ac.db.Where("idea_id = ?", id).Order("created_at DESC").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reports})
+}
+
+// ModerateIdea handles POST /admin/api/ideas/:id/moderate - hides an idea from the feeds or
+// publishes it again ({"status": "hidden", "reason": "..."}); the author is notified
+func (ac *AdminController) ModerateIdea(c *gin.Context) {
+	idea, ok := ac.loadIdea(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"` // published, hidden
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.ModerateIdea(ac.db, idea, req.Status, req.Reason, c.GetString("admin_username"))
+	if errors.Is(err, services.ErrInvalidIdea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": idea})
+}
+
+// DeleteIdea handles DELETE /admin/api/ideas/:id - removes an idea with its likes, follows and reports
+func (ac *AdminController) DeleteIdea(c *gin.Context) {
+	idea, ok := ac.loadIdea(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteIdea(ac.db, idea); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Idea deleted"})
+}
+
+// loadIdea loads the idea in the id parameter whatever its status
+func (ac *AdminController) loadIdea(c *gin.Context) (*models.Idea, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var idea models.Idea
+	if err := ac.db.First(&idea, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Idea not found"})
+		return nil, false
+	}
+	return &idea, true
+}
@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImpersonateUser handles POST /admin/api/users/:id/impersonate - mints a short-lived token that
+// acts as the user on the public API, for support debugging. Body: {"reason", "write",
+// "ttl_minutes"}; tokens are read-only unless a superadmin asks for write access, and every
+// request made with them is audit-logged.
+func (ac *AdminController) ImpersonateUser(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Reason     string `json:"reason" binding:"required"`
+		Write      bool   `json:"write"`
+		TTLMinutes int    `json:"ttl_minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Write && c.GetString("admin_role") != "superadmin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only superadmins can impersonate with write access"})
+		return
+	}
+	if req.TTLMinutes < 0 || time.Duration(req.TTLMinutes)*time.Minute > middleware.MaxImpersonationTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ttl_minutes must be between 1 and %d", int(middleware.MaxImpersonationTTL.Minutes()))})
+		return
+	}
+
+	userID := c.Param("id")
+	email, err := ac.impersonationTarget(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin := c.GetString("admin_username")
+	token, claims, err := middleware.MintImpersonationToken(userID, email, admin, req.Write, time.Duration(req.TTLMinutes)*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mint token: " + err.Error()})
+		return
+	}
+
+	session := &models.ImpersonationSession{
+		TokenID:       claims.ID,
+		AdminUsername: admin,
+		TargetUserID:  userID,
+		TargetEmail:   email,
+		Reason:        req.Reason,
+		ReadOnly:      !req.Write,
+		IPAddress:     middleware.ClientIP(c),
+		ExpiresAt:     claims.ExpiresAt.Time,
+	}
+	// The token is only handed out once its session is on record
+	if err := ac.db.Create(session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record impersonation session: " + err.Error()})
+		return
+	}
+
+	services.NotifyAdmins(ac.db, models.NotificationLevelWarning, "impersonation", "User impersonation started",
+		fmt.Sprintf("%s is impersonating %s until %s: %s", admin, userID, session.ExpiresAt.Format(time.RFC3339), req.Reason), session)
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": session.ExpiresAt,
+		"session":    session,
+	})
+}
+
+// GetImpersonationSessions handles GET /admin/api/impersonation?admin=&user= - lists impersonation
+// sessions, newest first
+func (ac *AdminController) GetImpersonationSessions(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	query := ac.readDB().Model(&models.ImpersonationSession{})
+	if admin := c.Query("admin"); admin != "" {
+		query = query.Where("admin_username = ?", admin)
+	}
+	if user := c.Query("user"); user != "" {
+		query = query.Where("target_user_id = ?", user)
+	}
+
+	var sessions []models.ImpersonationSession
+	if err := query.Order("created_at DESC").Limit(100).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "total": len(sessions)})
+}
+
+// GetImpersonationRequests handles GET /admin/api/impersonation/:id/requests - returns the audit
+// log of the requests made in an impersonation session
+func (ac *AdminController) GetImpersonationRequests(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	db := ac.readDB()
+	var session models.ImpersonationSession
+	if err := db.First(&session, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var requests []models.ImpersonationRequest
+	if err := db.Where("token_id = ?", session.TokenID).Order("created_at").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"session": session, "requests": requests})
+}
+
+// impersonationTarget returns the email of the Supabase user to impersonate, falling back to the
+// local user table when Supabase is not configured
+func (ac *AdminController) impersonationTarget(userID string) (string, error) {
+	if client, err := services.NewSupabaseDBClient(); err == nil {
+		profile, err := client.GetProfileByID(userID)
+		if err != nil {
+			return "", fmt.Errorf("user %s not found: %v", userID, err)
+		}
+		return profile.Email, nil
+	}
+
+	var user models.User
+	if err := ac.db.Where("supabase_user_id = ?", userID).First(&user).Error; err != nil {
+		return "", fmt.Errorf("user %s not found", userID)
+	}
+	return user.Email, nil
+}
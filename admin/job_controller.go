@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pacing of the job event stream
+const (
+	jobEventInterval  = 500 * time.Millisecond
+	jobEventHeartbeat = 15 * time.Second
+)
+
+// JobController reports on and cancels the background jobs started by heavy admin actions
+type JobController struct{}
+
+// NewJobController creates a new job controller
+func NewJobController() *JobController {
+	return &JobController{}
+}
+
+// ListJobs handles GET /admin/api/jobs?type=backtest&limit=20 - returns recent jobs, newest first
+func (ctrl *JobController) ListJobs(c *gin.Context) {
+	if services.GlobalJobManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job manager not initialized"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	jobs := services.GlobalJobManager.List(c.Query("type"), limit)
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "count": len(jobs)})
+}
+
+// GetJob handles GET /admin/api/jobs/:id - returns the status, progress and result of a job
+func (ctrl *JobController) GetJob(c *gin.Context) {
+	if services.GlobalJobManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job manager not initialized"})
+		return
+	}
+
+	job, err := services.GlobalJobManager.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// JobEvents handles GET /admin/api/jobs/:id/events - streams the job as Server-Sent Events.
+// A "progress" event carries the job (progress, current_item, eta_seconds) whenever it changes, at
+// most twice a second and at least every 15 seconds; a final "done" event ends the stream.
+func (ctrl *JobController) JobEvents(c *gin.Context) {
+	if services.GlobalJobManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job manager not initialized"})
+		return
+	}
+
+	id := c.Param("id")
+	job, changed, err := services.GlobalJobManager.Watch(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	// Streams outlive the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	ctx := c.Request.Context()
+	heartbeat := time.NewTicker(jobEventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		if job.Finished() {
+			c.SSEvent("done", job)
+			c.Writer.Flush()
+			return
+		}
+		c.SSEvent("progress", job)
+		c.Writer.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+		case <-changed:
+			// Coalesce bursts of updates, e.g. one per stock
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobEventInterval):
+			}
+		}
+
+		job, changed, err = services.GlobalJobManager.Watch(id)
+		if err != nil {
+			return // forgotten while streaming
+		}
+	}
+}
+
+// CancelJob handles POST /admin/api/jobs/:id/cancel - cancels a queued or running job
+func (ctrl *JobController) CancelJob(c *gin.Context) {
+	if services.GlobalJobManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job manager not initialized"})
+		return
+	}
+
+	job, err := services.GlobalJobManager.Cancel(c.Param("id"))
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "job": job})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job cancellation requested", "job": job})
+}
+
+// submitJob starts a background job for an admin action and responds 202 with its ID
+func submitJob(c *gin.Context, jobType string, fn services.JobFunc) {
+	if services.GlobalJobManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job manager not initialized"})
+		return
+	}
+
+	job, err := services.GlobalJobManager.Submit(jobType, c.GetString("admin_username"), fn)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Job started",
+		"job_id":     job.ID,
+		"status_url": "/admin/api/jobs/" + job.ID,
+		"job":        job,
+	})
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"net/http"
+
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMaintenance handles GET /admin/api/maintenance - returns the maintenance mode and the
+// subsystem kill switches
+func (ac *AdminController) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"config":     services.CurrentMaintenance(),
+		"subsystems": services.Subsystems,
+	})
+}
+
+// UpdateMaintenance handles PUT /admin/api/maintenance - switches maintenance mode and the
+// subsystem kill switches, e.g. {"enabled": true, "message": "...", "until": "<RFC3339>",
+// "disabled": {"realtime": true}}
+func (ac *AdminController) UpdateMaintenance(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var cfg services.MaintenanceConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg.UpdatedBy = c.GetString("admin_username")
+
+	if err := services.SaveMaintenance(ac.db, cfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance settings updated", "config": services.CurrentMaintenance()})
+}
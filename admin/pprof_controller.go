@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"net/http/pprof"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PprofEnabled reports whether the profiler routes are registered: outside production, or in
+// production when PPROF_ENABLED=true
+func PprofEnabled() bool {
+	if os.Getenv("PPROF_ENABLED") == "true" {
+		return true
+	}
+	env := os.Getenv("ENVIRONMENT")
+	return env != "" && env != "production"
+}
+
+// Pprof handles GET /admin/api/debug/pprof/*profile - serves the net/http/pprof profiles: download
+// .../debug/pprof/profile?seconds=30 (CPU) or .../debug/pprof/heap (memory) with the admin session
+// and open the file with go tool pprof. The index lists the available profiles.
+func (ac *AdminController) Pprof(c *gin.Context) {
+	switch name := strings.TrimPrefix(c.Param("profile"), "/"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
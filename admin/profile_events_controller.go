@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfileEvents handles GET /admin/api/profile-events - lists the profile event targets and the
+// latest deliveries, newest first
+func (ac *AdminController) GetProfileEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    services.GlobalProfileEvents != nil,
+		"targets":    services.GlobalProfileEvents.Targets(),
+		"deliveries": services.GlobalProfileEvents.Deliveries(),
+	})
+}
+
+// SendTestProfileEvent handles POST /admin/api/profile-events/test - queues a profile.test event
+// to every target, to check the CRM and billing endpoints receive and verify events
+func (ac *AdminController) SendTestProfileEvent(c *gin.Context) {
+	if services.GlobalProfileEvents == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profile events are not configured (set PROFILE_WEBHOOK_URLS or PROFILE_EVENTS_PUBSUB_TOPIC)"})
+		return
+	}
+	services.GlobalProfileEvents.Publish(services.ProfileEvent{
+		Type:       services.ProfileEventTest,
+		Changes:    map[string]services.ProfileFieldChange{},
+		Actor:      c.GetString("admin_username"),
+		OccurredAt: time.Now().UTC(),
+	})
+	c.JSON(http.StatusAccepted, gin.H{"message": "Test event queued", "targets": services.GlobalProfileEvents.Targets()})
+}
+
+// profileBefore returns the Supabase profile about to be changed when profile events are on,
+// nil otherwise
+func (ctrl *UserManagementController) profileBefore(userID string) *services.UserProfile {
+	if services.GlobalProfileEvents == nil {
+		return nil
+	}
+	profile, err := ctrl.supabaseClient.GetProfileByID(userID)
+	if err != nil {
+		log.Printf("Warning: profile events skipped for user %s: %v", userID, err)
+		return nil
+	}
+	return profile
+}
+
+// publishProfileChanges emits the events for what an admin changed in a profile since before. The
+// profile is read again when the update didn't return it.
+func (ctrl *UserManagementController) publishProfileChanges(c *gin.Context, before, after *services.UserProfile) {
+	if before == nil {
+		return
+	}
+	if after == nil {
+		var err error
+		if after, err = ctrl.supabaseClient.GetProfileByID(before.ID); err != nil {
+			log.Printf("Warning: profile events skipped for user %s: %v", before.ID, err)
+			return
+		}
+	}
+	services.GlobalProfileEvents.Publish(services.ProfileChangeEvents(before.ID, after.Email,
+		c.GetString("admin_username"), services.ProfileStateOf(before), services.ProfileStateOf(after))...)
+}
+
+// localUserBefore returns the local user about to be changed when profile events are on, nil
+// otherwise
+func (ac *AdminController) localUserBefore(userID string) *models.User {
+	if services.GlobalProfileEvents == nil {
+		return nil
+	}
+	var user models.User
+	if err := ac.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		log.Printf("Warning: profile events skipped for user %s: %v", userID, err)
+		return nil
+	}
+	return &user
+}
+
+// publishLocalUserChange emits the events for an admin change of a local user's role or status
+func (ac *AdminController) publishLocalUserChange(c *gin.Context, before *models.User, change func(after *models.User)) {
+	if before == nil {
+		return
+	}
+	after := *before
+	change(&after)
+	services.GlobalProfileEvents.Publish(services.ProfileChangeEvents(before.SupabaseUserID, before.Email,
+		c.GetString("admin_username"), localProfileState(before), localProfileState(&after))...)
+}
+
+func localProfileState(user *models.User) services.ProfileState {
+	return services.ProfileState{Role: user.Role, IsActive: user.IsActive}
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go_backend_project/services"
+	"go_backend_project/services/reports"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReportTemplate handles GET /admin/api/reports/template - returns the weekly report layout,
+// with the built-in default for reverting edits
+func (ac *AdminController) GetReportTemplate(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	cfg, err := reports.LoadTemplate(ac.readDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template":   cfg,
+		"is_default": cfg.Source == reports.DefaultTemplate,
+		"default":    reports.DefaultTemplate,
+	})
+}
+
+// UpdateReportTemplate handles PUT /admin/api/reports/template - saves a new weekly report layout
+// ({"source": "<html/template>"}) once it renders the sample data; an empty source restores the
+// default
+func (ac *AdminController) UpdateReportTemplate(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Source string `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Source == "" {
+		req.Source = reports.DefaultTemplate
+	}
+
+	cfg, err := reports.SaveTemplate(ac.db, req.Source, c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report template updated", "template": cfg})
+}
+
+// PreviewReport handles POST /admin/api/reports/preview?user= - renders a layout ({"source"}, or
+// the stored one when empty) with sample data, or with a user's real data for the last week
+func (ac *AdminController) PreviewReport(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Source string `json:"source"`
+	}
+	c.ShouldBindJSON(&req)
+	if req.Source == "" {
+		cfg, err := reports.LoadTemplate(ac.readDB())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		req.Source = cfg.Source
+	}
+
+	data := reports.SampleData()
+	if userID := c.Query("user"); userID != "" {
+		var err error
+		if data, err = reports.Build(ac.readDB(), userID, reports.WeekEnd(time.Now())); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	html, err := reports.Render(req.Source, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
+
+// RunWeeklyReports handles POST /admin/api/reports/run - generates the last week's reports of all
+// users now as a background job, replacing reports already generated for that week
+func (ac *AdminController) RunWeeklyReports(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	db := ac.db
+	submitJob(c, services.JobTypeWeeklyReports, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		return reports.GenerateWeekly(ctx, db, reports.WeekEnd(time.Now()), progress)
+	})
+}
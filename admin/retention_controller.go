@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetention handles GET /admin/api/retention - lists the retention policy of every dataset
+// and the report of the latest pruning run
+func (ac *AdminController) GetRetention(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"policies":    services.RetentionPolicies(),
+		"last_report": services.LastRetentionReport(),
+	})
+}
+
+// PruneRetention handles POST /admin/api/retention/prune - removes the data past its retention
+// period as a background job; {"dry_run": true} only reports what would be removed
+func (ac *AdminController) PruneRetention(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := ac.db
+	submitJob(c, services.JobTypeRetention, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		return services.RunRetention(ctx, db, req.DryRun, progress)
+	})
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"go_backend_project/services"
+	"go_backend_project/services/seed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeedDemoData handles POST /admin/api/seed/demo - seeds the demo stocks, price history, rules,
+// templates, users and portfolios from the embedded fixture in a background job. Rows that
+// already exist are kept, so it can be run again. Production instances refuse unless
+// SEED_DEMO_ENABLED=true.
+func (ac *AdminController) SeedDemoData(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if !seed.Enabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Demo seeding is disabled in production (set SEED_DEMO_ENABLED=true to allow it)"})
+		return
+	}
+
+	db := ac.db
+	submitJob(c, services.JobTypeSeedDemo, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		return seed.Demo(ctx, db)
+	})
+}
@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSignalSnapshot handles GET /admin/api/signals/snapshot - describes the signal snapshot the
+// public endpoints serve from
+func (ac *AdminController) GetSignalSnapshot(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"snapshot": signals.GetSnapshotStatus(), "file": signals.SnapshotFile})
+}
+
+// PublishSignalSnapshot handles POST /admin/api/signals/snapshot - rebuilds and publishes the
+// signal snapshot as a background job, e.g. after editing a strategy
+func (ac *AdminController) PublishSignalSnapshot(c *gin.Context) {
+	if signals.GlobalSignalService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Signal service not available"})
+		return
+	}
+
+	submitJob(c, services.JobTypeSignalSnapshot, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		snapshot, err := signals.GlobalSignalService.BuildSnapshot(ctx, func(done, total int, strategy string) {
+			if total > 0 {
+				progress(float64(done)/float64(total)*100, strategy, fmt.Sprintf("%d/%d strategies", done, total))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := signals.PublishSnapshot(snapshot); err != nil {
+			return nil, err
+		}
+		return signals.GetSnapshotStatus(), nil
+	})
+}
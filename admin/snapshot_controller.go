@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/snapshot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadSnapshot handles GET /admin/api/snapshot - streams a snapshot of the application state
+// (stock, price, rule and config tables plus the data directory) as a .tar.gz download
+func (ac *AdminController) DownloadSnapshot(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	filename := fmt.Sprintf("cpls_snapshot_%s.tar.gz", time.Now().Format("20060102_150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "application/gzip")
+
+	// The status is sent with the first bytes, so a failure can only cut the download short,
+	// which restores detect from the missing manifest
+	if _, err := snapshot.Write(c.Request.Context(), ac.db, c.Writer, c.GetString("admin_username")); err != nil {
+		log.Printf("Snapshot download failed: %v", err)
+	}
+}
+
+// PushSnapshot handles POST /admin/api/snapshot/push - writes a snapshot to Google Cloud Storage
+// as a background job; bucket defaults to SNAPSHOT_GCS_BUCKET and object to a timestamped name
+func (ac *AdminController) PushSnapshot(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	var req struct {
+		Bucket string `json:"bucket"`
+		Object string `json:"object"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Bucket == "" {
+		req.Bucket = snapshot.DefaultBucket()
+	}
+	if req.Object == "" {
+		req.Object = snapshot.DefaultObjectName(time.Now())
+	}
+	if req.Bucket == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket is required (or set SNAPSHOT_GCS_BUCKET)"})
+		return
+	}
+
+	db := ac.db
+	createdBy := c.GetString("admin_username")
+	submitJob(c, services.JobTypeSnapshotPush, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		progress(0, req.Object, "Uploading snapshot to gs://"+req.Bucket)
+		manifest, err := snapshot.Push(ctx, db, req.Bucket, req.Object, createdBy)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"bucket":   req.Bucket,
+			"object":   req.Object,
+			"manifest": manifest,
+		}, nil
+	})
+}
+
+// RestoreSnapshot handles POST /admin/api/snapshot/restore?confirm=true - replaces the application
+// state with a snapshot uploaded as the multipart field "file", or read from {"bucket", "object"}
+// in Google Cloud Storage. Only instances with SNAPSHOT_RESTORE_ENABLED=true accept restores.
+func (ac *AdminController) RestoreSnapshot(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+	if !snapshot.RestoreEnabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Restore is disabled on this instance (set SNAPSHOT_RESTORE_ENABLED=true on staging)"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Restoring replaces stocks, prices, rules, config and the data directory; pass confirm=true"})
+		return
+	}
+
+	var source io.ReadCloser
+	origin := ""
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		source, origin = f, file.Filename
+	} else {
+		var req struct {
+			Bucket string `json:"bucket"`
+			Object string `json:"object" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Upload the snapshot as \"file\" or pass {\"bucket\", \"object\"}: " + err.Error()})
+			return
+		}
+		if req.Bucket == "" {
+			req.Bucket = snapshot.DefaultBucket()
+		}
+		source, err = snapshot.Pull(c.Request.Context(), req.Bucket, req.Object)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		origin = "gs://" + req.Bucket + "/" + req.Object
+	}
+	defer source.Close()
+
+	result, err := snapshot.Restore(c.Request.Context(), ac.db, source)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Restore failed: " + err.Error()})
+		return
+	}
+
+	services.NotifyAdmins(ac.db, models.NotificationLevelWarning, "snapshot", "Snapshot restored",
+		fmt.Sprintf("%s restored the snapshot of %s from %s", c.GetString("admin_username"),
+			result.Source.CreatedAt.Format(time.RFC3339), origin), result)
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Snapshot restored",
+		"restore":          result,
+		"restart_required": true,
+	})
+}
@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	"go_backend_project/middleware"
 	"go_backend_project/services"
 
 	"github.com/gin-gonic/gin"
@@ -298,20 +300,101 @@ func (ctrl *StockController) UpdatePriceConfig(c *gin.Context) {
 	})
 }
 
-// StartPriceSync handles POST /admin/api/prices/sync - starts price sync for all stocks
+// StartPriceSync handles POST /admin/api/prices/sync - starts a job syncing prices for all stocks
 func (ctrl *StockController) StartPriceSync(c *gin.Context) {
 	if services.GlobalPriceService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Price service not initialized"})
 		return
 	}
+	if services.GlobalPriceService.IsRunning() {
+		c.JSON(http.StatusConflict, gin.H{"error": "sync already in progress"})
+		return
+	}
 
-	if err := services.GlobalPriceService.StartFullSync(); err != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	submitJob(c, services.JobTypePriceSync, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		result, err := services.GlobalPriceService.RunFullSync(ctx, func(p services.PriceSyncProgress) {
+			if p.TotalStocks == 0 {
+				return
+			}
+			progress(float64(p.ProcessedStocks)/float64(p.TotalStocks)*100, p.CurrentStock,
+				fmt.Sprintf("Processed %d/%d stocks, %d failed", p.ProcessedStocks, p.TotalStocks, p.FailedCount))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+}
+
+// SyncPriceBatch handles POST /admin/api/prices/sync-batch - starts a job refreshing prices for a
+// list of codes or a whole floor, e.g. {"codes": ["VNM", "FPT"]} or {"floor": "HOSE"}. The batch
+// keeps its own progress and can run alongside a full sync.
+func (ctrl *StockController) SyncPriceBatch(c *gin.Context) {
+	if services.GlobalPriceService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Price service not initialized"})
 		return
 	}
 
+	var req services.BatchSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := services.ResolveBatchCodes(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submitJob(c, services.JobTypePriceBatch, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		result, err := services.GlobalPriceService.SyncBatch(ctx, codes, func(p services.BatchSyncProgress) {
+			progress(float64(p.ProcessedStocks)/float64(p.TotalStocks)*100, p.CurrentStock,
+				fmt.Sprintf("Processed %d/%d stocks, %d failed", p.ProcessedStocks, p.TotalStocks, p.FailedCount))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+}
+
+// RetryFailedPrices handles POST /admin/api/prices/retry-failed - re-syncs only the stocks that failed
+// in the last sync, with lower concurrency
+func (ctrl *StockController) RetryFailedPrices(c *gin.Context) {
+	if services.GlobalPriceService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Price service not initialized"})
+		return
+	}
+
+	codes := services.GlobalPriceService.GetFailedStocks()
+	count, err := services.GlobalPriceService.RetryFailedStocks()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "failed_stocks": codes})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Retry of failed stocks started",
+		"count":         count,
+		"failed_stocks": codes,
+		"workers":       services.RetryWorkerCount,
+	})
+}
+
+// GetStockLifecycle handles GET /admin/api/stocks/lifecycle - lists delisted stocks and newly
+// listed stocks waiting for their first price sync
+func (ctrl *StockController) GetStockLifecycle(c *gin.Context) {
+	if services.GlobalStockLifecycle == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Stock lifecycle not initialized"})
+		return
+	}
+
+	state := services.GlobalStockLifecycle.GetState()
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Price sync started",
+		"inactive":         state.Inactive,
+		"inactive_count":   len(state.Inactive),
+		"pending_listings": state.PendingListings,
+		"updated_at":       state.UpdatedAt,
 	})
 }
 
@@ -355,6 +438,28 @@ func (ctrl *StockController) GetPriceSyncStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetPriceCache handles GET /admin/api/prices/cache - returns the size and hit rate of the parsed
+// price file cache
+func (ctrl *StockController) GetPriceCache(c *gin.Context) {
+	if services.GlobalPriceService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Price service not initialized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GlobalPriceService.CacheStats())
+}
+
+// PurgePriceCache handles POST /admin/api/prices/cache/purge - empties the parsed price file cache
+func (ctrl *StockController) PurgePriceCache(c *gin.Context) {
+	if services.GlobalPriceService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Price service not initialized"})
+		return
+	}
+
+	purged := services.GlobalPriceService.PurgeCache()
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Purged %d cached price files", purged), "purged": purged})
+}
+
 // SyncSingleStockPrice handles POST /admin/api/prices/:code - syncs price for single stock
 func (ctrl *StockController) SyncSingleStockPrice(c *gin.Context) {
 	if services.GlobalPriceService == nil {
@@ -396,20 +501,22 @@ func (ctrl *StockController) GetStockPrice(c *gin.Context) {
 
 // ==================== Indicator Endpoints ====================
 
-// CalculateAllIndicators handles POST /admin/api/indicators/calculate - calculates all indicators
+// CalculateAllIndicators handles POST /admin/api/indicators/calculate - starts a job calculating all indicators
 func (ctrl *StockController) CalculateAllIndicators(c *gin.Context) {
 	if services.GlobalIndicatorService == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Indicator service not initialized"})
 		return
 	}
 
-	if err := services.GlobalIndicatorService.CalculateAndSaveAllIndicators(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Indicators calculated successfully",
+	submitJob(c, services.JobTypeCalculateIndicators, func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+		err := services.GlobalIndicatorService.CalculateAndSaveAllIndicatorsContext(ctx, func(done, total int, code string) {
+			// Saving the results takes the last few percent
+			progress(float64(done)/float64(total)*95, code, fmt.Sprintf("Calculated %d/%d stocks", done, total))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"message": "Indicators calculated successfully"}, nil
 	})
 }
 
@@ -459,8 +566,11 @@ func (ctrl *StockController) FilterStocks(c *gin.Context) {
 		return
 	}
 
-	results, err := services.GlobalIndicatorService.FilterStocks(filter)
+	results, err := services.GlobalIndicatorService.FilterStocksContext(c.Request.Context(), filter)
 	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -715,6 +825,37 @@ func (ctrl *StockController) GetRealtimeStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// GetRealtimeConfig returns WebSocket keepalive, queue and tier limit settings
+func (ctrl *StockController) GetRealtimeConfig(c *gin.Context) {
+	if services.GlobalRealtimeService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime service not initialized"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.GlobalRealtimeService.GetConfig())
+}
+
+// UpdateRealtimeConfig updates WebSocket settings; changes apply to new connections
+func (ctrl *StockController) UpdateRealtimeConfig(c *gin.Context) {
+	if services.GlobalRealtimeService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime service not initialized"})
+		return
+	}
+
+	cfg := services.GlobalRealtimeService.GetConfig()
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalRealtimeService.UpdateConfig(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Realtime config updated", "config": cfg})
+}
+
 // ==================== MongoDB Operations ====================
 
 // GetMongoDBStatus returns MongoDB Atlas connection status and statistics
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"go_backend_project/middleware"
 	"go_backend_project/models"
 	"go_backend_project/services"
 
@@ -193,7 +194,7 @@ func (ac *SupabaseAuthController) Login(c *gin.Context) {
 		Email:     user.Email,
 		FullName:  user.FullName,
 		Role:      user.Role,
-		IPAddress: c.ClientIP(),
+		IPAddress: middleware.ClientIP(c),
 		UserAgent: c.Request.UserAgent(),
 		ExpiresAt: time.Now().Add(SessionDuration),
 	}
@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetErasureRequests handles GET /admin/api/erasure-requests?status=pending - lists user data
+// erasure requests, newest first; status defaults to pending
+func (ac *AdminController) GetErasureRequests(c *gin.Context) {
+	if !ac.requireDatabaseAvailable(c) {
+		return
+	}
+
+	query := ac.readDB().Model(&models.UserErasureRequest{})
+	if status := c.DefaultQuery("status", models.ErasureStatusPending); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.UserErasureRequest
+	if err := query.Order("created_at DESC").Limit(200).Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": requests, "total": len(requests)})
+}
+
+// ExportErasureRequestData handles GET /admin/api/erasure-requests/:id/export - downloads the
+// data bundle of the user behind an open request; required before the request can be approved
+func (ac *AdminController) ExportErasureRequestData(c *gin.Context) {
+	request, ok := ac.loadOpenErasureRequest(c)
+	if !ok {
+		return
+	}
+
+	supabase, _ := services.NewSupabaseDBClient()
+	bundle, err := services.ExportUserData(ac.db, supabase, request.SupabaseUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data: " + err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if err := ac.db.Model(request).Updates(map[string]interface{}{
+		"exported_at": now,
+		"exported_by": c.GetString("admin_username"),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("user_data_%d_%s.json", request.ID, now.Format("20060102_150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ApproveErasureRequest handles POST /admin/api/erasure-requests/:id/approve - erases the user's
+// data and records the deletion receipt. Failed requests can be approved again to retry the
+// Supabase cleanup.
+func (ac *AdminController) ApproveErasureRequest(c *gin.Context) {
+	request, ok := ac.loadOpenErasureRequest(c)
+	if !ok {
+		return
+	}
+	if request.ExportedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export the user's data before approving the erasure"})
+		return
+	}
+
+	supabase, _ := services.NewSupabaseDBClient()
+	admin := c.GetString("admin_username")
+	receipt, err := services.ExecuteUserErasure(ac.db, supabase, request, admin)
+	if err != nil {
+		if receipt != nil {
+			services.NotifyAdmins(ac.db, models.NotificationLevelCritical, "privacy", "User data erasure incomplete",
+				fmt.Sprintf("Erasure request %d: %v", request.ID, err), receipt)
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "receipt": receipt})
+		return
+	}
+
+	services.NotifyAdmins(ac.db, models.NotificationLevelInfo, "privacy", "User data erased",
+		fmt.Sprintf("%s completed erasure request %d", admin, request.ID), receipt)
+	c.JSON(http.StatusOK, gin.H{"message": "User data erased", "receipt": receipt})
+}
+
+// RejectErasureRequest handles POST /admin/api/erasure-requests/:id/reject - closes a pending
+// request without erasing anything; {"note"} records why
+func (ac *AdminController) RejectErasureRequest(c *gin.Context) {
+	request, ok := ac.loadOpenErasureRequest(c)
+	if !ok {
+		return
+	}
+	if request.Status != models.ErasureStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending requests can be rejected"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if err := ac.db.Model(request).Updates(map[string]interface{}{
+		"status":      models.ErasureStatusRejected,
+		"reviewed_by": c.GetString("admin_username"),
+		"reviewed_at": now,
+		"review_note": req.Note,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Erasure request rejected"})
+}
+
+// loadOpenErasureRequest loads the pending or failed erasure request named by :id
+func (ac *AdminController) loadOpenErasureRequest(c *gin.Context) (*models.UserErasureRequest, bool) {
+	if !ac.requireDatabaseAvailable(c) {
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var request models.UserErasureRequest
+	if err := ac.db.First(&request, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Erasure request not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	if request.Status != models.ErasureStatusPending && request.Status != models.ErasureStatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Erasure request is " + request.Status})
+		return nil, false
+	}
+	return &request, true
+}
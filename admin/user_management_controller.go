@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"go_backend_project/middleware"
 	"go_backend_project/services"
 
 	"github.com/gin-gonic/gin"
@@ -30,8 +31,24 @@ func (ctrl *UserManagementController) ListUsers(c *gin.Context) {
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := c.DefaultQuery("sort_order", "desc")
 
-	result, err := ctrl.supabaseClient.GetProfiles(page, pageSize, search, sortBy, sortOrder)
+	client := ctrl.supabaseClient.WithContext(c.Request.Context())
+	var result *services.ProfilesListResponse
+	var err error
+	if cursor := c.Query("cursor"); cursor != "" {
+		// Cursor pages stay stable while users sign up between requests
+		var pageReq services.PageRequest
+		pageReq, err = services.ParsePageRequest("", c.Query("page_size"), cursor, sortBy+"."+sortOrder,
+			services.DefaultPageSize, services.MaxPageSize)
+		if err == nil {
+			result, err = client.GetProfilesPage(pageReq, search, sortBy, sortOrder)
+		}
+	} else {
+		result, err = client.GetProfiles(page, pageSize, search, sortBy, sortOrder)
+	}
 	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
 		c.HTML(http.StatusOK, "users_management.html", gin.H{
 			"Title":     "User Management",
 			"AdminUser": c.GetString("admin_username"),
@@ -41,7 +58,7 @@ func (ctrl *UserManagementController) ListUsers(c *gin.Context) {
 	}
 
 	// Get stats
-	stats, _ := ctrl.supabaseClient.GetProfileStats()
+	stats, _ := client.GetProfileStats()
 
 	c.HTML(http.StatusOK, "users_management.html", gin.H{
 		"Title":      "User Management",
@@ -51,6 +68,7 @@ func (ctrl *UserManagementController) ListUsers(c *gin.Context) {
 		"Page":       result.Page,
 		"PageSize":   result.PageSize,
 		"TotalPages": result.TotalPages,
+		"NextCursor": result.NextCursor,
 		"Search":     search,
 		"SortBy":     sortBy,
 		"SortOrder":  sortOrder,
@@ -143,11 +161,17 @@ func (ctrl *UserManagementController) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	var before *services.UserProfile
+	if input.Membership != "" || input.MembershipExpiresAt != "" || input.Role != "" ||
+		input.IsActive != nil || input.IsBanned != nil || input.BanReason != "" {
+		before = ctrl.profileBefore(userID)
+	}
 	profile, err := ctrl.supabaseClient.UpdateProfile(userID, &input)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctrl.publishProfileChanges(c, before, profile)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User updated successfully",
@@ -183,10 +207,12 @@ func (ctrl *UserManagementController) BanUser(c *gin.Context) {
 	}
 	c.ShouldBindJSON(&input)
 
+	before := ctrl.profileBefore(userID)
 	if err := ctrl.supabaseClient.BanUser(userID, input.Reason); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctrl.publishProfileChanges(c, before, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "User banned successfully"})
 }
@@ -195,10 +221,12 @@ func (ctrl *UserManagementController) BanUser(c *gin.Context) {
 func (ctrl *UserManagementController) UnbanUser(c *gin.Context) {
 	userID := c.Param("id")
 
+	before := ctrl.profileBefore(userID)
 	if err := ctrl.supabaseClient.UnbanUser(userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctrl.publishProfileChanges(c, before, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "User unbanned successfully"})
 }
@@ -231,10 +259,12 @@ func (ctrl *UserManagementController) UpdateSubscription(c *gin.Context) {
 		endDate = &t
 	}
 
+	before := ctrl.profileBefore(userID)
 	if err := ctrl.supabaseClient.UpdateSubscription(userID, input.Plan, endDate); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctrl.publishProfileChanges(c, before, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription updated successfully"})
 }
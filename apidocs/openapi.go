@@ -0,0 +1,244 @@
+// Package apidocs serves the OpenAPI contract of the public API. Paths, methods and path
+// parameters come from the routes registered on the router; summaries, query parameters and
+// request examples are generated from the handler doc comments by cmd/apidocgen.
+package apidocs
+
+//go:generate go run ../cmd/apidocgen -dir ../controllers -out operations_gen.go
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix is the route prefix documented in the spec
+const APIPrefix = "/api/v1"
+
+// SpecPath is where the OpenAPI document is served
+const SpecPath = APIPrefix + "/openapi.json"
+
+// handlerDoc is the documentation extracted from a handler comment
+type handlerDoc struct {
+	Summary     string
+	Description string
+	Query       []queryParam
+	Body        string // JSON request body example
+}
+
+type queryParam struct {
+	Name    string
+	Example string // "gainers|losers" lists the accepted values
+}
+
+var pathParam = regexp.MustCompile(`[:*](\w+)`)
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// SpecHandler serves the OpenAPI document, built from the router's routes on the first request
+// so that routes registered after startup are included
+func SpecHandler(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		specOnce.Do(func() {
+			specJSON, _ = json.MarshalIndent(BuildSpec(router.Routes()), "", "  ")
+		})
+		c.Data(http.StatusOK, "application/json; charset=utf-8", specJSON)
+	}
+}
+
+// BuildSpec assembles an OpenAPI 3 document for the routes under APIPrefix
+func BuildSpec(routes gin.RoutesInfo) map[string]interface{} {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	paths := map[string]map[string]interface{}{}
+	tagSet := map[string]bool{}
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, APIPrefix+"/") || r.Path == SpecPath {
+			continue
+		}
+		path := pathParam.ReplaceAllString(r.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		tag := tagFor(r.Path)
+		tagSet[tag] = true
+		paths[path][strings.ToLower(r.Method)] = operation(r, tag)
+	}
+
+	tags := make([]map[string]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, map[string]string{"name": tag})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i]["name"] < tags[j]["name"] })
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "CPLS API",
+			"version":     "v1",
+			"description": "Stock data, indicators and trading signals for the Vietnamese market. Send a Supabase access token as a Bearer token for user endpoints.",
+		},
+		"servers": []map[string]string{{"url": "/"}},
+		"tags":    tags,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []map[string][]string{{"bearerAuth": {}}, {}},
+	}
+}
+
+func operation(r gin.RouteInfo, tag string) map[string]interface{} {
+	name := handlerName(r.Handler)
+	doc, documented := handlerDocs[name]
+	if doc.Summary == "" {
+		doc.Summary = summaryFromName(name)
+	}
+
+	op := map[string]interface{}{
+		"tags":        []string{tag},
+		"summary":     doc.Summary,
+		"operationId": operationID(r.Method, r.Path),
+		"responses": map[string]interface{}{
+			"200": map[string]string{"description": "Success"},
+			"400": map[string]string{"description": "Invalid request"},
+		},
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if !documented {
+		op["x-undocumented"] = true
+	}
+
+	var params []map[string]interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+		})
+	}
+	for _, q := range doc.Query {
+		params = append(params, map[string]interface{}{"name": q.Name, "in": "query", "schema": querySchema(q.Example)})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		content := map[string]interface{}{"schema": map[string]string{"type": "object"}}
+		var example interface{}
+		if doc.Body != "" && json.Unmarshal([]byte(doc.Body), &example) == nil {
+			content["example"] = example
+		}
+		op["requestBody"] = map[string]interface{}{"content": map[string]interface{}{"application/json": content}}
+	}
+	return op
+}
+
+// querySchema infers a parameter schema from its example value
+func querySchema(example string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "string"}
+	switch {
+	case example == "":
+	case strings.Contains(example, "|"):
+		schema["enum"] = strings.Split(example, "|")
+	case example == "true" || example == "false":
+		schema["type"] = "boolean"
+		schema["example"] = example == "true"
+	default:
+		schema["example"] = example
+		if n, err := strconv.Atoi(example); err == nil {
+			schema["type"] = "integer"
+			schema["example"] = n
+		} else if f, err := strconv.ParseFloat(example, 64); err == nil {
+			schema["type"] = "number"
+			schema["example"] = f
+		}
+	}
+	return schema
+}
+
+// handlerName turns "go_backend_project/controllers.(*MarketController).GetMovers-fm" into
+// "controllers.(*MarketController).GetMovers", the key used by the generated docs
+func handlerName(handler string) string {
+	if i := strings.LastIndex(handler, "/"); i >= 0 {
+		handler = handler[i+1:]
+	}
+	return strings.TrimSuffix(handler, "-fm")
+}
+
+// summaryFromName splits the method name of an undocumented handler: GetMovers -> "Get movers"
+func summaryFromName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if strings.HasPrefix(name, "func") {
+		return ""
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteRune(' ')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tagFor groups operations by the first path segment after the prefix
+func tagFor(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, APIPrefix+"/"), "/")
+	return segment
+}
+
+func operationID(method, path string) string {
+	parts := []string{strings.ToLower(method)}
+	for _, s := range strings.Split(strings.TrimPrefix(path, APIPrefix+"/"), "/") {
+		s = strings.TrimLeft(s, ":*")
+		if s != "" {
+			parts = append(parts, strings.ReplaceAll(s, "-", "_"))
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// SwaggerUI serves a Swagger UI page for the spec. The UI assets are loaded from the unpkg CDN.
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>CPLS API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      SwaggerUIBundle({ url: "` + SpecPath + `", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
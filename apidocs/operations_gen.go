@@ -0,0 +1,557 @@
+// Code generated by apidocgen from the controller doc comments. DO NOT EDIT.
+
+package apidocs
+
+var handlerDocs = map[string]handlerDoc{
+	"controllers.(*AccountController).CancelErasureRequest": {
+		Summary: "Withdraws the current user's pending erasure request",
+	},
+	"controllers.(*AccountController).GetErasureRequest": {
+		Summary: "Returns the current user's latest data erasure request",
+	},
+	"controllers.(*AccountController).RequestErasure": {
+		Summary: "Asks for the current user's data to be erased; an admin reviews the request, exports the user's data and then erases it",
+		Body:    "{\"reason\": \"...\"}",
+	},
+	"controllers.(*ExperimentController).GetAssignments": {
+		Summary: "Returns the current user's variants of the running experiments, so clients can render before calling the routes the experiments cover",
+	},
+	"controllers.(*ExperimentController).RecordEvent": {
+		Summary: "Records an engagement event, e.g. \"signal_click\", of the current user in an experiment",
+	},
+	"controllers.(*IdeaController).CreateIdea": {
+		Summary: "Posts a trade idea on a stock at its current price, optionally linked to a signal rule or template, with an optional target, stop loss and horizon its outcome is judged by",
+	},
+	"controllers.(*IdeaController).DeleteIdea": {
+		Summary: "Removes the current user's idea",
+	},
+	"controllers.(*IdeaController).FollowIdea": {
+		Summary: "Follows an idea to be notified when it closes",
+	},
+	"controllers.(*IdeaController).FollowedIdeas": {
+		Summary: "Returns the ideas the current user follows",
+	},
+	"controllers.(*IdeaController).GetIdea": {
+		Summary: "Returns a published idea, or a hidden one to its author",
+	},
+	"controllers.(*IdeaController).LatestIdeas": {
+		Summary: "Returns published ideas, newest first",
+		Query:   []queryParam{{"stock", "FPT"}, {"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*IdeaController).LikeIdea": {
+		Summary: "Likes an idea",
+	},
+	"controllers.(*IdeaController).MyIdeas": {
+		Summary: "Returns the current user's ideas, including hidden ones",
+	},
+	"controllers.(*IdeaController).ReportIdea": {
+		Summary: "Reports an idea to the moderators; enough reports hide it until it is reviewed",
+	},
+	"controllers.(*IdeaController).StockIdeas": {
+		Summary: "Returns the ideas on a stock, newest first, with how past ideas on it turned out",
+	},
+	"controllers.(*IdeaController).TrendingIdeas": {
+		Summary: "Returns the most liked and followed ideas of the last week, weighted towards newer ones",
+		Query:   []queryParam{{"stock", "FPT"}, {"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*IdeaController).UnfollowIdea": {
+		Summary: "Stops following an idea",
+	},
+	"controllers.(*IdeaController).UnlikeIdea": {
+		Summary: "Removes the current user's like of an idea",
+	},
+	"controllers.(*IdeaController).UpdateIdea": {
+		Summary: "Changes the title and body of the current user's idea; prices can't change",
+	},
+	"controllers.(*MarketController).GetCalendar": {
+		Summary: "Returns the current session and upcoming trading sessions",
+		Query:   []queryParam{{"days", "10"}},
+	},
+	"controllers.(*MarketController).GetEventCalendar": {
+		Summary: "Returns the upcoming corporate events of the given codes, or of the current user's watchlist when no codes are given",
+		Query:   []queryParam{{"days", "30"}, {"codes", "VNM,FPT"}},
+	},
+	"controllers.(*MarketController).GetHeatmap": {
+		Summary: "Returns the sector-grouped market map with market cap weights, daily change and RS",
+	},
+	"controllers.(*MarketController).GetMarketStats": {
+		Summary: "Returns the distribution of key indicators across all stocks (RSI and RS quantiles, RS decile boundaries, breadth of MACD and moving averages); with code, also where that stock sits within them",
+		Query:   []queryParam{{"code", "VNM"}},
+	},
+	"controllers.(*MarketController).GetMovers": {
+		Summary: "Returns top gainers, losers or most traded stocks with liquidity and exchange filters, in the paginated envelope of the public signal API",
+		Query:   []queryParam{{"type", "gainers|losers|volume"}, {"exchange", "HOSE"}, {"min_trading_val", "5"}, {"instrument_type", "stock"}, {"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*MarketController).GetRegime": {
+		Summary: "Returns the detected market regime and the composite strategy weights applied to it",
+		Query:   []queryParam{{"refresh", "true"}},
+	},
+	"controllers.(*MarketController).GetStatus": {
+		Summary: "Returns the session each exchange is in and the time to its next session, so clients can poll quickly while the market is open and back off while it is closed",
+	},
+	"controllers.(*NotificationController).GetPreferences": {
+		Summary: "Returns the current user's push settings",
+	},
+	"controllers.(*NotificationController).GetUnreadCount": {
+		Summary: "Returns the current user's unread notifications, in total and per category",
+	},
+	"controllers.(*NotificationController).ListNotifications": {
+		Summary: "Returns the current user's notifications, newest first, with the unread count",
+		Query:   []queryParam{{"unread", "true"}, {"category", "signal_alert"}, {"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*NotificationController).MarkAllRead": {
+		Summary: "Marks all of the current user's unread notifications as read, optionally only those of one category",
+		Query:   []queryParam{{"category", "price_alert"}},
+	},
+	"controllers.(*NotificationController).MarkRead": {
+		Summary: "Marks one of the current user's notifications as read",
+	},
+	"controllers.(*NotificationController).RegisterDevice": {
+		Summary: "Registers an FCM registration token of the current user's device; a token registered before by another account moves to the current user",
+		Body:    "{\"token\": \"...\", \"platform\": \"android\", \"app_version\": \"1.4.0\"}",
+	},
+	"controllers.(*NotificationController).UnregisterDevice": {
+		Summary: "Removes a registration token of the current user, e.g. on logout",
+		Body:    "{\"token\": \"...\"}",
+	},
+	"controllers.(*NotificationController).UpdatePreferences": {
+		Summary: "Updates the current user's push settings; quiet hours are HH:MM in the given IANA timezone (market time when empty), and empty start and end turn them off",
+		Body:    "{\"push_enabled\": true, \"quiet_hours_start\": \"22:00\", \"quiet_hours_end\": \"07:00\", \"timezone\": \"Asia/Ho_Chi_Minh\"}",
+	},
+	"controllers.(*PublicSignalController).GetAccumulationScreener": {
+		Summary: "Returns stocks under institutional accumulation: a rising A/D line confirmed by OBV, with strong relative strength",
+		Query:   []queryParam{{"min_ad_trend", "20"}, {"min_rs", "70"}, {"min_trading_val", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetAllIndicators": {
+		Summary: "Returns paginated indicators for all stocks, by page or by cursor",
+		Query:   []queryParam{{"page", "1"}, {"page_size", "50"}, {"cursor", ""}, {"sort_by", "rs_avg"}, {"units", "raw"}},
+	},
+	"controllers.(*PublicSignalController).GetBatchSignals": {
+		Summary: "Returns signals with key indicators for up to 100 codes in one request",
+		Body:    "{\"codes\": [\"VNM\", \"FPT\"], \"strategy\": \"composite\"}",
+	},
+	"controllers.(*PublicSignalController).GetBreakoutStocks": {
+		Summary: "Returns stocks with volume breakout",
+		Query:   []queryParam{{"min_vol_ratio", "2"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetBuySignals": {
+		Summary: "Returns top buy signals",
+		Query:   []queryParam{{"min_strength", "70"}, {"min_trading_val", "10"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetETFScreener": {
+		Summary: "Returns ETFs with their trend, momentum and composite signal",
+		Query:   []queryParam{{"sort_by", "rs_avg"}, {"min_trading_val", "0"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetFiftyTwoWeekScreener": {
+		Summary: "Returns stocks closing within a few percent of, or breaking, their 52-week high (side=high) or low (side=low) on elevated volume",
+		Query:   []queryParam{{"side", "high"}, {"within", "2"}, {"min_vol_ratio", "1.5"}, {"min_trading_val", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetGapScreener": {
+		Summary:     "Returns stocks whose open gapped at least min_gap % away from the previous close",
+		Description: "While realtime polling runs, today's open and reference price come from the realtime feed; otherwise the gap of the latest daily bar is used.",
+		Query:       []queryParam{{"direction", "up|down|both"}, {"min_gap", "2"}, {"min_vol_ratio", "0"}, {"min_trading_val", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetLeaderboard": {
+		Summary: "Returns rule/template performance over 1M/3M/6M windows",
+		Query:   []queryParam{{"type", "rule|template"}, {"window", "3M"}, {"min_signals", "5"}},
+	},
+	"controllers.(*PublicSignalController).GetMomentumStocks": {
+		Summary: "Returns stocks with high momentum",
+		Query:   []queryParam{{"min_rs", "80"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetOversoldStocks": {
+		Summary: "Returns oversold stocks (RSI < 30)",
+		Query:   []queryParam{{"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetSellSignals": {
+		Summary: "Returns top sell signals",
+		Query:   []queryParam{{"min_strength", "30"}, {"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetSignalHistory": {
+		Summary: "Returns persisted rule signals with their lifecycle state, newest first",
+		Query:   []queryParam{{"state", "active"}, {"code", "VNM"}, {"page", "1"}, {"page_size", "20"}, {"cursor", ""}},
+	},
+	"controllers.(*PublicSignalController).GetSignalStats": {
+		Summary: "Returns signal statistics",
+	},
+	"controllers.(*PublicSignalController).GetSignals": {
+		Summary:     "Returns paginated signals with filtering",
+		Description: "Pass next_cursor from the meta as cursor for stable paging while signals change.",
+		Query:       []queryParam{{"page", "1"}, {"page_size", "20"}, {"cursor", ""}, {"strategy", "composite"}, {"signal_type", "BUY"}, {"min_strength", "60"}, {"type", "stock"}, {"units", "raw"}},
+	},
+	"controllers.(*PublicSignalController).GetStockConsensus": {
+		Summary: "Returns the verdict of every strategy and active rule for a stock",
+	},
+	"controllers.(*PublicSignalController).GetStockIndicators": {
+		Summary:     "Returns all indicators for a stock with its RS ranks",
+		Description: "Stocks listed after the last full calculation are computed on demand, with rs_rank_estimated set on their ranks.",
+	},
+	"controllers.(*PublicSignalController).GetStockSignal": {
+		Summary: "Returns signal for a specific stock",
+		Query:   []queryParam{{"strategy", "composite"}},
+	},
+	"controllers.(*PublicSignalController).GetStrategies": {
+		Summary: "Returns available strategies",
+	},
+	"controllers.(*PublicSignalController).GetStrategySignals": {
+		Summary: "Returns signals for a specific strategy",
+		Query:   []queryParam{{"limit", "20"}},
+	},
+	"controllers.(*PublicSignalController).GetTopSignals": {
+		Summary: "Returns top buy and sell signals",
+		Query:   []queryParam{{"limit", "10"}},
+	},
+	"controllers.(*PublicSignalController).RankByCompositeScore": {
+		Summary: "Ranks the universe by a user-defined composite score: weighted indicator fields normalized across the universe (rank percentile by default, or zscore / none), or a raw score expression, optionally restricted by a filter expression",
+		Query:   []queryParam{{"type", "stock"}, {"min_trading_val", "1"}, {"limit", "50"}},
+		Body:    "{\"terms\": [{\"field\": \"rs_avg\", \"weight\": 0.6}, {\"field\": \"vol_ratio\", \"weight\": 0.4}], \"filter\": \"price > ma50\"}",
+	},
+	"controllers.(*RealtimeController).HandleWebSocket": {
+		Summary: "Upgrades to a WebSocket whose subscription limit follows the user's membership tier",
+		Query:   []queryParam{{"token", ""}},
+	},
+	"controllers.(*ReportController).GetReport": {
+		Summary: "Returns one of the current user's reports as an HTML page, or as a PDF download when one was rendered",
+		Query:   []queryParam{{"format", "pdf"}},
+	},
+	"controllers.(*ReportController).ListReports": {
+		Summary: "Returns the current user's reports, newest first, without their content",
+		Query:   []queryParam{{"limit", "20"}},
+	},
+	"controllers.(*ScreenerController).GetBullishStocks": {
+		Summary: "Returns stocks with bullish indicators",
+	},
+	"controllers.(*ScreenerController).GetMostActive": {
+		Summary: "Returns most actively traded stocks",
+	},
+	"controllers.(*ScreenerController).GetOverboughtStocks": {
+		Summary: "Returns overbought stocks (RSI > 70)",
+	},
+	"controllers.(*ScreenerController).GetOversoldStocks": {
+		Summary: "Returns oversold stocks (RSI < 30)",
+	},
+	"controllers.(*ScreenerController).GetPresets": {
+		Summary: "Returns predefined screener configurations",
+	},
+	"controllers.(*ScreenerController).GetPriceLimits": {
+		Summary: "Returns today's stocks at their ceiling or floor, or approaching the ceiling, from the realtime price store",
+		Query:   []queryParam{{"status", "hit_ceiling,hit_floor,approaching_ceiling"}},
+	},
+	"controllers.(*ScreenerController).GetTopGainers": {
+		Summary: "Returns top gaining stocks",
+	},
+	"controllers.(*ScreenerController).GetTopLosers": {
+		Summary: "Returns top losing stocks",
+	},
+	"controllers.(*ScreenerController).GetVolumeSpike": {
+		Summary: "Returns stocks with volume spikes",
+	},
+	"controllers.(*ScreenerController).RunPreset": {
+		Summary: "Runs a predefined screener",
+	},
+	"controllers.(*ScreenerController).Screen": {
+		Summary: "Applies filters and returns matching stocks; a tag limits the screen to the stocks the signed-in user tagged with it",
+	},
+	"controllers.(*SharedWatchlistController).AcceptInvitation": {
+		Summary: "Makes the current user a member of the invited list",
+	},
+	"controllers.(*SharedWatchlistController).AddItem": {
+		Summary: "Adds a stock to a shared watchlist, or updates its note; owners and editors only",
+	},
+	"controllers.(*SharedWatchlistController).CreateWatchlist": {
+		Summary: "Creates a shared watchlist owned by the current user, who needs a premium membership",
+	},
+	"controllers.(*SharedWatchlistController).DeclineInvitation": {
+		Summary: "Turns down an invitation to the current user's email",
+	},
+	"controllers.(*SharedWatchlistController).DeleteWatchlist": {
+		Summary: "Deletes a shared watchlist for everyone; owner only",
+	},
+	"controllers.(*SharedWatchlistController).GetWatchlist": {
+		Summary: "Returns a shared watchlist with its stocks, members and the caller's role",
+	},
+	"controllers.(*SharedWatchlistController).InviteMember": {
+		Summary:     "Invites a user by email as a viewer or editor; owner only",
+		Description: "Registered users are notified right away, others find the invitation after signing up with that email.",
+	},
+	"controllers.(*SharedWatchlistController).ListInvitations": {
+		Summary: "Returns the pending shared watchlist invitations to the current user's email",
+	},
+	"controllers.(*SharedWatchlistController).ListWatchlists": {
+		Summary: "Returns the shared watchlists the current user owns or is a member of",
+	},
+	"controllers.(*SharedWatchlistController).RemoveItem": {
+		Summary: "Removes a stock from a shared watchlist; owners and editors only",
+		Query:   []queryParam{{"version", "3"}},
+	},
+	"controllers.(*SharedWatchlistController).RemoveMember": {
+		Summary: "Removes a member or withdraws an invitation; the owner can remove anyone and a member can remove themselves to leave the list",
+	},
+	"controllers.(*SharedWatchlistController).RenameWatchlist": {
+		Summary: "Renames a shared watchlist; owners and editors only",
+	},
+	"controllers.(*SharedWatchlistController).UpdateMember": {
+		Summary: "Changes a member's role; owner only",
+	},
+	"controllers.(*SignalController).GetAllSignals": {
+		Summary: "Generates signals for all stocks with filtering",
+	},
+	"controllers.(*SignalController).GetBuySignals": {
+		Summary: "Returns all buy signals",
+	},
+	"controllers.(*SignalController).GetSellSignals": {
+		Summary: "Returns all sell signals",
+	},
+	"controllers.(*SignalController).GetSignal": {
+		Summary: "Generates a trading signal for a specific stock",
+	},
+	"controllers.(*SignalController).GetStrategies": {
+		Summary: "Returns all available trading strategies",
+	},
+	"controllers.(*SignalController).GetTopSignals": {
+		Summary: "Returns top trading opportunities across all signal types",
+	},
+	"controllers.(*SignalSubscriptionController).CreateSubscription": {
+		Summary: "Subscribes the current user to an active rule they can see (admin-owned or their own) or a template that is public or theirs",
+	},
+	"controllers.(*SignalSubscriptionController).DeleteSubscription": {
+		Summary: "Removes a subscription and its notification history",
+	},
+	"controllers.(*SignalSubscriptionController).GetNotifications": {
+		Summary: "Returns the alerts sent for a subscription, newest first",
+		Query:   []queryParam{{"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*SignalSubscriptionController).ListSubscriptions": {
+		Summary: "Returns the current user's signal subscriptions",
+	},
+	"controllers.(*SignalSubscriptionController).UpdateSubscription": {
+		Summary: "Changes the channel, frequency caps, stock filter or status of a subscription",
+	},
+	"controllers.(*StockController).CalculateIndicators": {
+		Summary: "Calculates and saves technical indicators",
+	},
+	"controllers.(*StockController).FetchHistoricalData": {
+		Summary: "Fetches historical data for a stock",
+	},
+	"controllers.(*StockController).GetIntraday": {
+		Summary: "Returns the intraday VWAP series and volume profile of a stock",
+		Query:   []queryParam{{"date", "YYYY-MM-DD"}},
+	},
+	"controllers.(*StockController).GetMarketIndices": {
+		Summary: "Returns market indices data",
+	},
+	"controllers.(*StockController).GetMostActive": {
+		Summary: "Returns most actively traded stocks",
+	},
+	"controllers.(*StockController).GetRealtimeQuote": {
+		Summary: "Returns real-time quote for a stock",
+	},
+	"controllers.(*StockController).GetStock": {
+		Summary: "Returns a single stock by ID or symbol",
+	},
+	"controllers.(*StockController).GetStockEvents": {
+		Summary: "Returns the upcoming and past year's corporate events of a stock with its trailing 12-month dividend yield",
+		Query:   []queryParam{{"days", "90"}},
+	},
+	"controllers.(*StockController).GetStockLevels": {
+		Summary: "Returns the classic and Fibonacci pivots calculated from the latest session and the support/resistance clusters found in the recent price history",
+		Query:   []queryParam{{"lookback", "120"}, {"units", "raw"}},
+	},
+	"controllers.(*StockController).GetStockOverview": {
+		Summary: "Returns company info, latest price, indicators, composite signal, recent signals, news and the caller's note for a stock in one response",
+		Query:   []queryParam{{"units", "display"}},
+	},
+	"controllers.(*StockController).GetStockPeers": {
+		Summary: "Compares a stock with same-sector stocks of similar market cap and ranks it among them on relative strength, momentum and valuation",
+		Query:   []queryParam{{"limit", "8"}},
+	},
+	"controllers.(*StockController).GetStockPrice": {
+		Summary: "Returns price data for a stock",
+		Query:   []queryParam{{"start_date", "2024-01-02"}, {"end_date", "2024-06-28"}, {"units", "thousands"}},
+	},
+	"controllers.(*StockController).GetStocks": {
+		Summary: "Returns list of all stocks ordered by symbol, by page or by cursor",
+		Query:   []queryParam{{"page", "1"}, {"limit", "50"}, {"cursor", ""}, {"exchange", "HOSE"}, {"industry", ""}, {"type", "stock"}},
+	},
+	"controllers.(*StockController).GetTechnicalIndicators": {
+		Summary: "Returns technical indicators for a stock",
+	},
+	"controllers.(*StockController).GetTopGainers": {
+		Summary: "Returns top gaining stocks",
+	},
+	"controllers.(*StockController).GetTopLosers": {
+		Summary: "Returns top losing stocks",
+	},
+	"controllers.(*StockController).SearchStocks": {
+		Summary: "Returns stocks ranked by code and company name match for typeahead",
+		Query:   []queryParam{{"q", "vinamilk"}, {"limit", "10"}},
+	},
+	"controllers.(*StockNoteController).DeleteNote": {
+		Summary: "Removes the current user's note and tags on a stock",
+	},
+	"controllers.(*StockNoteController).GetNote": {
+		Summary: "Returns the current user's note and tags on a stock",
+	},
+	"controllers.(*StockNoteController).ListNotes": {
+		Summary: "Returns the current user's stock notes, newest first, optionally only those with a tag",
+		Query:   []queryParam{{"tag", "avoid"}},
+	},
+	"controllers.(*StockNoteController).ListTags": {
+		Summary: "Returns the current user's tags with how many stocks carry each",
+	},
+	"controllers.(*StockNoteController).SaveNote": {
+		Summary: "Creates or replaces the current user's note and tags on a stock",
+	},
+	"controllers.(*StrategistController).AddPaperTrade": {
+		Summary: "Adds a strategist signal from the notification center to the current user's paper-trading bot at the current price; the quantity defaults to one board lot",
+	},
+	"controllers.(*StrategistController).ClosePaperTrade": {
+		Summary: "Closes one of the current user's open paper trades at the current price",
+	},
+	"controllers.(*StrategistController).FollowStrategist": {
+		Summary: "Makes the current user receive the signals of an author's strategies",
+	},
+	"controllers.(*StrategistController).FollowedStrategists": {
+		Summary: "Returns the strategists the current user follows with their performance",
+	},
+	"controllers.(*StrategistController).GetPaperTrades": {
+		Summary: "Returns the current user's paper trades, open ones marked to the current price, with the totals of their paper-trading bot",
+		Query:   []queryParam{{"status", "open"}},
+	},
+	"controllers.(*StrategistController).GetStrategist": {
+		Summary: "Returns a template author's signal performance and whether the caller follows them",
+	},
+	"controllers.(*StrategistController).UnfollowStrategist": {
+		Summary: "Stops the current user receiving an author's signals",
+	},
+	"controllers.(*SubscriptionController).CancelSubscription": {
+		Summary: "Cancels user's subscription",
+	},
+	"controllers.(*SubscriptionController).CreatePlan": {
+		Summary: "Creates a new subscription plan (admin only)",
+	},
+	"controllers.(*SubscriptionController).GetPaymentHistory": {
+		Summary: "Returns user's payment history",
+	},
+	"controllers.(*SubscriptionController).GetPlan": {
+		Summary: "Returns a single plan by ID",
+	},
+	"controllers.(*SubscriptionController).GetPlans": {
+		Summary: "Returns all available subscription plans",
+	},
+	"controllers.(*SubscriptionController).GetUserSubscription": {
+		Summary: "Returns user's current subscription",
+	},
+	"controllers.(*SubscriptionController).Subscribe": {
+		Summary: "Subscribes user to a plan",
+	},
+	"controllers.(*TemplateController).BacktestTemplate": {
+		Summary:     "Replays a template on one stock and returns the summary and the trade markers for charting",
+		Description: "The period defaults to the last year and is at most 2 years.",
+		Query:       []queryParam{{"code", "VNM"}, {"from", "2024-01-01"}, {"to", "2025-12-31"}, {"max_holding_days", "20"}},
+	},
+	"controllers.(*TemplateController).BrowseTemplates": {
+		Summary: "Returns public templates",
+		Query:   []queryParam{{"category", "momentum"}, {"featured", "true"}, {"sort", "popular|rating|newest"}, {"page", "1"}, {"limit", "20"}},
+	},
+	"controllers.(*TemplateController).CloneTemplate": {
+		Summary: "Copies a template into a condition group and rule owned by the current user",
+	},
+	"controllers.(*TemplateController).GetMyTemplates": {
+		Summary: "Returns templates authored by the current user",
+	},
+	"controllers.(*TemplateController).GetTemplate": {
+		Summary: "Returns a single template visible to the caller",
+	},
+	"controllers.(*TemplateController).PublishTemplate": {
+		Summary: "Creates a template owned by the current user",
+	},
+	"controllers.(*TemplateController).RateTemplate": {
+		Summary: "Records or updates the current user's rating of a public template",
+	},
+	"controllers.(*TemplateController).UpdateVisibility": {
+		Summary: "Lets the author publish or unpublish a template",
+	},
+	"controllers.(*TradingController).CreateStrategy": {
+		Summary: "Creates a new trading strategy",
+	},
+	"controllers.(*TradingController).DeleteStrategy": {
+		Summary: "Deletes a trading strategy",
+	},
+	"controllers.(*TradingController).ExecuteManualTrade": {
+		Summary: "Executes a manual trade",
+	},
+	"controllers.(*TradingController).GetBacktest": {
+		Summary: "Returns a single backtest with details",
+	},
+	"controllers.(*TradingController).GetBacktests": {
+		Summary: "Returns all backtests",
+	},
+	"controllers.(*TradingController).GetPortfolio": {
+		Summary: "Returns user's portfolio",
+	},
+	"controllers.(*TradingController).GetSignals": {
+		Summary: "Returns trading signals",
+	},
+	"controllers.(*TradingController).GetStrategies": {
+		Summary: "Returns all trading strategies",
+	},
+	"controllers.(*TradingController).GetTrades": {
+		Summary: "Returns trade history",
+	},
+	"controllers.(*TradingController).GetTradingBotStatus": {
+		Summary: "Returns the status of the trading bot",
+	},
+	"controllers.(*TradingController).RunBacktest": {
+		Summary: "Runs a backtest for a strategy",
+	},
+	"controllers.(*TradingController).StartTradingBot": {
+		Summary: "Starts the automated trading bot",
+	},
+	"controllers.(*TradingController).StopTradingBot": {
+		Summary: "Stops the trading bot",
+	},
+	"controllers.(*TradingController).UpdateStrategy": {
+		Summary: "Updates a trading strategy",
+	},
+	"controllers.(*UserController).AddToWatchlist": {
+		Summary: "Adds a stock to user's watchlist",
+	},
+	"controllers.(*UserController).CreateUser": {
+		Summary: "Creates a new user (called after Supabase auth)",
+	},
+	"controllers.(*UserController).CreateUserAlert": {
+		Summary: "Creates a price alert for user",
+	},
+	"controllers.(*UserController).DeleteUser": {
+		Summary: "Soft deletes a user (deactivates)",
+	},
+	"controllers.(*UserController).DeleteUserAlert": {
+		Summary: "Deletes a user alert",
+	},
+	"controllers.(*UserController).GetUser": {
+		Summary: "Returns a single user by ID or Supabase ID",
+	},
+	"controllers.(*UserController).GetUserAlerts": {
+		Summary: "Returns user's price alerts",
+	},
+	"controllers.(*UserController).GetUserWatchlist": {
+		Summary: "Returns user's stock watchlist, optionally only the stocks the user tagged",
+		Query:   []queryParam{{"tag", "avoid"}},
+	},
+	"controllers.(*UserController).GetUsers": {
+		Summary: "Returns list of all users with pagination",
+	},
+	"controllers.(*UserController).RemoveFromWatchlist": {
+		Summary: "Removes a stock from user's watchlist",
+	},
+	"controllers.(*UserController).SyncFromSupabase": {
+		Summary: "Syncs user data from Supabase auth",
+	},
+	"controllers.(*UserController).UpdateLastLogin": {
+		Summary: "Updates the last login timestamp",
+	},
+	"controllers.(*UserController).UpdateUser": {
+		Summary: "Updates user information",
+	},
+}
@@ -0,0 +1,195 @@
+// Command apidocgen extracts the API documentation of the HTTP handlers from their doc comments
+// and writes it as Go source for the apidocs package, which merges it with the registered routes
+// into the OpenAPI spec. Run it through go generate after changing a handler comment:
+//
+//	go generate ./apidocs
+//
+// A handler comment starts with a sentence naming the handler and contains a route line such as
+// "GET /api/v1/market/movers?type=gainers&limit=20", either on its own or as
+// "X handles GET /api/v1/... - description". Query parameters and their examples are taken from
+// the route line; a JSON object after a POST or PUT route is kept as the request body example.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var routeLine = regexp.MustCompile(`^(?:\w+ handles )?(GET|POST|PUT|PATCH|DELETE) (/\S*)\s*(.*)$`)
+
+type queryParam struct {
+	name, example string
+}
+
+type handlerDoc struct {
+	key         string
+	summary     string
+	description string
+	query       []queryParam
+	body        string
+}
+
+func main() {
+	dir := flag.String("dir", "../controllers", "package directory to scan")
+	pkg := flag.String("pkg", "controllers", "package name used in handler names")
+	out := flag.String("out", "operations_gen.go", "output file")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, *dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatalf("parse %s: %v", *dir, err)
+	}
+
+	var docs []handlerDoc
+	for _, p := range pkgs {
+		for _, file := range p.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Doc == nil {
+					continue
+				}
+				if doc, ok := parseHandlerDoc(*pkg, fn); ok {
+					docs = append(docs, doc)
+				}
+			}
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].key < docs[j].key })
+
+	src, err := render(docs)
+	if err != nil {
+		log.Fatalf("format: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("write %s: %v", *out, err)
+	}
+	log.Printf("apidocgen: %d handlers written to %s", len(docs), *out)
+}
+
+// parseHandlerDoc returns the documentation of a function whose comment has a route line
+func parseHandlerDoc(pkg string, fn *ast.FuncDecl) (handlerDoc, bool) {
+	doc := handlerDoc{key: pkg + "." + fn.Name.Name}
+	if fn.Recv != nil && len(fn.Recv.List) == 1 {
+		switch t := fn.Recv.List[0].Type.(type) {
+		case *ast.StarExpr:
+			if id, ok := t.X.(*ast.Ident); ok {
+				doc.key = fmt.Sprintf("%s.(*%s).%s", pkg, id.Name, fn.Name.Name)
+			}
+		case *ast.Ident:
+			doc.key = fmt.Sprintf("%s.%s.%s", pkg, t.Name, fn.Name.Name)
+		}
+	}
+
+	found := false
+	var text []string
+	for _, line := range strings.Split(strings.TrimSpace(fn.Doc.Text()), "\n") {
+		line = strings.TrimSpace(line)
+		m := routeLine.FindStringSubmatch(line)
+		if m == nil || found {
+			text = append(text, line)
+			continue
+		}
+		found = true
+		if i := strings.Index(m[2], "?"); i >= 0 {
+			doc.query = parseQuery(m[2][i+1:])
+		}
+		rest := strings.TrimSpace(m[3])
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			doc.body = rest
+		case strings.HasPrefix(rest, "- "):
+			text = append(text, strings.TrimPrefix(rest, "- "))
+		}
+	}
+	if !found {
+		return doc, false
+	}
+
+	// The first sentence names the handler: "GetMovers returns ..." becomes "Returns ..."
+	paragraph := strings.TrimSpace(strings.Join(strings.Fields(strings.Join(text, " ")), " "))
+	paragraph = strings.TrimPrefix(paragraph, fn.Name.Name+" ")
+	if paragraph != "" {
+		paragraph = strings.ToUpper(paragraph[:1]) + paragraph[1:]
+	}
+	doc.summary, doc.description = paragraph, ""
+	if i := firstSentenceEnd(paragraph); i >= 0 {
+		doc.summary, doc.description = paragraph[:i], strings.TrimSpace(paragraph[i+2:])
+	}
+	doc.summary = strings.TrimSuffix(doc.summary, ".")
+	return doc, true
+}
+
+// abbreviations end in a period without ending the sentence
+var abbreviations = map[string]bool{"e.g": true, "i.e": true, "etc": true, "vs": true, "cf": true}
+
+// firstSentenceEnd returns the index of the period that ends the first sentence, or -1 when the
+// paragraph is a single sentence. Periods after abbreviations such as "e.g." are skipped.
+func firstSentenceEnd(paragraph string) int {
+	for offset := 0; ; {
+		i := strings.Index(paragraph[offset:], ". ")
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		word := paragraph[strings.LastIndex(paragraph[:i], " ")+1 : i]
+		if !abbreviations[strings.ToLower(strings.TrimLeft(word, "(\"'"))] {
+			return i
+		}
+		offset = i + 2
+	}
+}
+
+// parseQuery splits "type=gainers&limit=20" into parameters, keeping the first occurrence
+func parseQuery(query string) []queryParam {
+	var params []queryParam
+	seen := map[string]bool{}
+	for _, part := range strings.Split(query, "&") {
+		name, example, _ := strings.Cut(part, "=")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		params = append(params, queryParam{name: name, example: example})
+	}
+	return params
+}
+
+func render(docs []handlerDoc) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by apidocgen from the controller doc comments. DO NOT EDIT.\n\n")
+	b.WriteString("package apidocs\n\n")
+	b.WriteString("var handlerDocs = map[string]handlerDoc{\n")
+	for _, d := range docs {
+		fmt.Fprintf(&b, "%q: {\n", d.key)
+		fmt.Fprintf(&b, "Summary: %q,\n", d.summary)
+		if d.description != "" {
+			fmt.Fprintf(&b, "Description: %q,\n", d.description)
+		}
+		if len(d.query) > 0 {
+			b.WriteString("Query: []queryParam{")
+			for _, q := range d.query {
+				fmt.Fprintf(&b, "{%q, %q}, ", q.name, q.example)
+			}
+			b.WriteString("},\n")
+		}
+		if d.body != "" {
+			fmt.Fprintf(&b, "Body: %q,\n", d.body)
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
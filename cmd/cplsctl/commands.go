@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"gorm.io/gorm"
+)
+
+// syncPollInterval is how often sync-prices reports progress of a full sync
+const syncPollInterval = 5 * time.Second
+
+type priceSyncRow struct {
+	Code      string `json:"code"`
+	DataCount int    `json:"data_count"`
+	LastDate  string `json:"last_date,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func runSyncPrices(args []string) error {
+	fs, format := newFlagSet("sync-prices")
+	codes := fs.String("codes", "", "comma-separated codes to sync; all stocks when empty")
+	workers := fs.Int("workers", 0, "worker count for a full sync (default: configured)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := setup(false); err != nil {
+		return err
+	}
+	svc := services.GlobalPriceService
+
+	if list := splitCodes(*codes); len(list) > 0 {
+		rows := make([]priceSyncRow, 0, len(list))
+		failed := 0
+		for _, code := range list {
+			row := priceSyncRow{Code: code}
+			file, err := svc.SyncSingleStock(code)
+			if err != nil {
+				row.Error = err.Error()
+				failed++
+			} else {
+				row.DataCount = file.DataCount
+				if len(file.Prices) > 0 {
+					row.LastDate = file.Prices[0].Date
+				}
+			}
+			rows = append(rows, row)
+		}
+		if err := output(*format, rows, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "CODE\tPRICES\tLAST DATE\tERROR")
+			for _, r := range rows {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", r.Code, r.DataCount, r.LastDate, r.Error)
+			}
+		}); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d stocks failed", failed, len(list))
+		}
+		return nil
+	}
+
+	if *workers > 0 {
+		svc.SetWorkerCount(*workers)
+	}
+	if err := svc.StartFullSync(); err != nil {
+		return err
+	}
+	for svc.IsRunning() {
+		time.Sleep(syncPollInterval)
+		p := svc.GetProgress()
+		log.Printf("Price sync: %d/%d processed, %d failed, elapsed %s", p.ProcessedStocks, p.TotalStocks, p.FailedCount, p.ElapsedTime)
+	}
+
+	progress := svc.GetProgress()
+	return output(*format, progress, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Status:\t%s\n", progress.Status)
+		fmt.Fprintf(w, "Stocks:\t%d\n", progress.TotalStocks)
+		fmt.Fprintf(w, "Success:\t%d\n", progress.SuccessCount)
+		fmt.Fprintf(w, "Failed:\t%d\n", progress.FailedCount)
+		fmt.Fprintf(w, "Elapsed:\t%s\n", progress.ElapsedTime)
+		if len(progress.FailedStocks) > 0 {
+			fmt.Fprintf(w, "Failed stocks:\t%v\n", progress.FailedStocks)
+		}
+	})
+}
+
+type indicatorRunResult struct {
+	Count     int    `json:"count"`
+	Profile   string `json:"profile"`
+	RSMethod  string `json:"rs_method"`
+	UpdatedAt string `json:"updated_at"`
+	Duration  string `json:"duration"`
+}
+
+func runCalcIndicators(args []string) error {
+	fs, format := newFlagSet("calc-indicators")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := setup(false); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := services.GlobalIndicatorService.CalculateAndSaveAllIndicators(); err != nil {
+		return err
+	}
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return err
+	}
+
+	result := indicatorRunResult{
+		Count:     summary.Count,
+		Profile:   summary.Profile,
+		RSMethod:  summary.RSMethod,
+		UpdatedAt: summary.UpdatedAt,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+	}
+	return output(*format, result, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Stocks:\t%d\n", result.Count)
+		fmt.Fprintf(w, "Profile:\t%s\n", result.Profile)
+		fmt.Fprintf(w, "RS method:\t%s\n", result.RSMethod)
+		fmt.Fprintf(w, "Updated at:\t%s\n", result.UpdatedAt)
+		fmt.Fprintf(w, "Duration:\t%s\n", result.Duration)
+	})
+}
+
+type ruleSignalRow struct {
+	RuleID      uint    `json:"rule_id"`
+	Rule        string  `json:"rule"`
+	Code        string  `json:"code"`
+	SignalType  string  `json:"signal_type"`
+	Score       int     `json:"score"`
+	Confidence  float64 `json:"confidence"`
+	Price       float64 `json:"price"`
+	TargetPrice float64 `json:"target_price"`
+	StopLoss    float64 `json:"stop_loss"`
+}
+
+func runEvalRules(args []string) error {
+	fs, format := newFlagSet("eval-rules")
+	asOf := fs.String("as-of", "", "evaluate on indicators recomputed as of this date (YYYY-MM-DD); latest saved indicators when empty")
+	ruleIDs := fs.String("rules", "", "comma-separated rule IDs; all active rules when empty")
+	codes := fs.String("codes", "", "comma-separated codes to evaluate; all stocks when empty")
+	types := fs.String("type", "", "instrument types to evaluate, e.g. stock,etf")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	typeFilter, err := services.ParseInstrumentTypes(*types)
+	if err != nil {
+		return err
+	}
+	if *asOf != "" {
+		if _, err := time.Parse(services.PriceDateFormat, *asOf); err != nil {
+			return fmt.Errorf("invalid -as-of date %q", *asOf)
+		}
+	}
+
+	db, err := setup(true)
+	if err != nil {
+		return err
+	}
+	rules, err := loadRules(db, *ruleIDs)
+	if err != nil {
+		return err
+	}
+
+	var indicators map[string]*services.ExtendedStockIndicators
+	if *asOf != "" {
+		universe, err := services.LoadPriceUniverse()
+		if err != nil {
+			return err
+		}
+		indicators = services.CalculateIndicatorsAsOf(universe, *asOf)
+	} else {
+		summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+		if err != nil {
+			return err
+		}
+		indicators = summary.Stocks
+	}
+
+	only := make(map[string]bool)
+	for _, code := range splitCodes(*codes) {
+		only[code] = true
+	}
+
+	evaluator := signals.GlobalConditionEvaluator
+	rows := []ruleSignalRow{}
+	for i := range rules {
+		rule := &rules[i]
+		refs, groups, err := evaluator.LoadRuleGroups(rule)
+		if err != nil {
+			log.Printf("Skipping rule %d: %v", rule.ID, err)
+			continue
+		}
+		for code, ind := range indicators {
+			if len(only) > 0 && !only[code] {
+				continue
+			}
+			if !services.MatchesInstrumentType(code, ind.Type, typeFilter) {
+				continue
+			}
+			signal := evaluator.EvaluateRuleWithGroups(rule, refs, groups, ind)
+			if signal == nil {
+				continue
+			}
+			rows = append(rows, ruleSignalRow{
+				RuleID:      rule.ID,
+				Rule:        rule.Name,
+				Code:        code,
+				SignalType:  signal.SignalType,
+				Score:       signal.Score,
+				Confidence:  signal.Confidence,
+				Price:       signal.Price,
+				TargetPrice: signal.TargetPrice,
+				StopLoss:    signal.StopLoss,
+			})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].RuleID != rows[j].RuleID {
+			return rows[i].RuleID < rows[j].RuleID
+		}
+		if rows[i].Score != rows[j].Score {
+			return rows[i].Score > rows[j].Score
+		}
+		return rows[i].Code < rows[j].Code
+	})
+
+	return output(*format, rows, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "RULE\tCODE\tSIGNAL\tSCORE\tCONFIDENCE\tPRICE\tTARGET\tSTOP")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%d %s\t%s\t%s\t%d\t%.1f\t%.2f\t%.2f\t%.2f\n",
+				r.RuleID, r.Rule, r.Code, r.SignalType, r.Score, r.Confidence, r.Price, r.TargetPrice, r.StopLoss)
+		}
+	})
+}
+
+func runBacktest(args []string) error {
+	fs, format := newFlagSet("backtest")
+	from := fs.String("from", "", "first signal date (YYYY-MM-DD); defaults to the configured window before -to")
+	to := fs.String("to", "", "last signal date (YYYY-MM-DD); defaults to today")
+	ruleIDs := fs.String("rules", "", "comma-separated rule IDs; all active rules when empty")
+	step := fs.Int("step", 0, "evaluate rules every N trading days; defaults to the configured step_days")
+	hold := fs.Int("hold", 0, "exit at close after this many trading days; defaults to the configured max_holding_days")
+	trades := fs.Bool("trades", false, "list individual trades in table output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := setup(true)
+	if err != nil {
+		return err
+	}
+	cfg := signals.LoadRuleBacktestConfig(db)
+	if *step > 0 {
+		cfg.StepDays = *step
+	}
+	if *hold > 0 {
+		cfg.MaxHoldingDays = *hold
+	}
+
+	end := time.Now()
+	if *to != "" {
+		t, err := time.Parse(services.PriceDateFormat, *to)
+		if err != nil {
+			return fmt.Errorf("invalid -to date %q", *to)
+		}
+		end = t
+	}
+	start := end.AddDate(0, -cfg.WindowMonths, 0)
+	if *from != "" {
+		t, err := time.Parse(services.PriceDateFormat, *from)
+		if err != nil {
+			return fmt.Errorf("invalid -from date %q", *from)
+		}
+		start = t
+	}
+	if start.After(end) {
+		return errors.New("-from must not be after -to")
+	}
+
+	rules, err := loadRules(db, *ruleIDs)
+	if err != nil {
+		return err
+	}
+	universe, err := services.LoadPriceUniverse()
+	if err != nil {
+		return err
+	}
+
+	results, err := signals.GlobalConditionEvaluator.BacktestRules(rules, universe, start, end, cfg)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[uint]string, len(rules))
+	for _, r := range rules {
+		names[r.ID] = r.Name
+	}
+	ids := make([]uint, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	ordered := make([]*signals.RuleBacktestResult, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, results[id])
+	}
+
+	return output(*format, ordered, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "RULE\tTRADES\tWIN RATE\tAVG RETURN\tTOTAL RETURN\tMAX LOSS\tAVG HOLD")
+		for _, r := range ordered {
+			fmt.Fprintf(w, "%d %s\t%d\t%.1f%%\t%.2f%%\t%.2f%%\t%.2f%%\t%.1f\n",
+				r.RuleID, names[r.RuleID], r.TotalTrades, r.WinRate, r.AvgReturn, r.TotalReturn, r.MaxLoss, r.AvgHoldDays)
+		}
+		if !*trades {
+			return
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "RULE\tCODE\tSIGNAL DATE\tEXIT DATE\tENTRY\tEXIT\tRETURN\tDAYS\tREASON")
+		for _, r := range ordered {
+			for _, t := range r.Trades {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.2f\t%.2f\t%.2f%%\t%d\t%s\n",
+					r.RuleID, t.Code, t.SignalDate, t.ExitDate, t.EntryPrice, t.ExitPrice, t.ReturnPct, t.HoldingDays, t.ExitReason)
+			}
+		}
+	})
+}
+
+// loadRules loads the rules with the given comma-separated IDs, or all active rules
+func loadRules(db *gorm.DB, ids string) ([]models.SignalRule, error) {
+	var rules []models.SignalRule
+	query := db.Order("priority DESC, id")
+	if ids == "" {
+		query = query.Where("is_active = ?", true)
+	} else {
+		var list []uint
+		for _, part := range splitCodes(ids) {
+			id, err := strconv.ParseUint(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule ID %q", part)
+			}
+			list = append(list, uint(id))
+		}
+		query = query.Where("id IN ?", list)
+	}
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("no matching rules")
+	}
+	return rules, nil
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go_backend_project/config"
+	"go_backend_project/services"
+	"go_backend_project/services/seed"
+)
+
+// Fixture universe seeded for the e2e flows, and the sessions of history each stock gets: a year,
+// so the backtest's moving averages are warmed up over its six-month window
+const (
+	e2eDefaultCodes = "VNM,FPT,HPG,VCB,MWG"
+	e2eHistoryDays  = 260
+)
+
+// e2eStep is one flow; steps run in order and share the run state
+type e2eStep struct {
+	name string
+	run  func(r *e2eRun) error
+}
+
+type e2eStepResult struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type e2eReport struct {
+	BaseURL  string          `json:"base_url"`
+	Passed   bool            `json:"passed"`
+	Steps    []e2eStepResult `json:"steps"`
+	Duration string          `json:"duration"`
+}
+
+// e2eRun holds the client, its admin session cookie and what earlier steps created
+type e2eRun struct {
+	baseURL       string
+	client        *http.Client
+	adminUser     string
+	adminPassword string
+	codes         []string
+	tag           string // makes the names of created rows unique per run
+
+	ruleID     uint
+	strategyID uint
+}
+
+var e2eSteps = []e2eStep{
+	{"health", e2eHealth},
+	{"admin/login-rejected", e2eAdminLoginRejected},
+	{"admin/login", e2eAdminLogin},
+	{"rules/create", e2eRuleCreate},
+	{"rules/update", e2eRuleUpdate},
+	{"rules/stats", e2eRuleStats},
+	{"rules/delete", e2eRuleDelete},
+	{"rules/restore", e2eRuleRestore},
+	{"screener/screen", e2eScreener},
+	{"backtest/run", e2eBacktest},
+}
+
+func runE2E(args []string) error {
+	fs, format := newFlagSet("e2e")
+	baseURL := fs.String("base-url", "http://localhost:8080", "URL of the running server")
+	codes := fs.String("codes", e2eDefaultCodes, "comma-separated fixture stocks to seed and query")
+	seed := fs.Bool("seed", true, "seed the fixture stocks and prices into the database of DB_* / DATABASE_URL")
+	wait := fs.Duration("wait", 2*time.Minute, "how long to wait for the server and its database")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	jar, _ := cookiejar.New(nil)
+	r := &e2eRun{
+		baseURL: strings.TrimRight(*baseURL, "/"),
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 2 * time.Minute,
+			// Login answers with a redirect whose cookie and target the flows check
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		adminUser:     envOr("ADMIN_DEFAULT_USERNAME", "datvt8x"),
+		adminPassword: envOr("ADMIN_DEFAULT_PASSWORD", "@abcd4321"),
+		codes:         splitCodes(*codes),
+		tag:           time.Now().UTC().Format("20060102-150405"),
+	}
+	if len(r.codes) == 0 {
+		return errors.New("no fixture codes")
+	}
+
+	// The server migrates before it registers the API, so a healthy database endpoint means the
+	// tables exist
+	if err := r.waitReady(*wait); err != nil {
+		return err
+	}
+	if *seed {
+		if err := seedE2EFixtures(r.codes); err != nil {
+			return fmt.Errorf("failed to seed fixtures: %w", err)
+		}
+	}
+
+	start := time.Now()
+	report := &e2eReport{BaseURL: r.baseURL, Passed: true}
+	for _, step := range e2eSteps {
+		stepStart := time.Now()
+		err := step.run(r)
+		result := e2eStepResult{Name: step.name, Passed: err == nil, Duration: time.Since(stepStart).Round(time.Millisecond).String()}
+		if err != nil {
+			result.Error = err.Error()
+			report.Passed = false
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	if err := output(*format, report, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "STEP\tRESULT\tDURATION")
+		for _, s := range report.Steps {
+			result := "ok"
+			if !s.Passed {
+				result = "FAIL"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, result, s.Duration)
+		}
+		fmt.Fprintf(w, "Duration:\t%s\n", report.Duration)
+		for _, s := range report.Steps {
+			if !s.Passed {
+				fmt.Fprintf(w, "FAIL\t%s: %s\n", s.Name, s.Error)
+			}
+		}
+	}); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return errors.New("e2e flows failed")
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// waitReady polls the database health endpoint until it answers ok
+func (r *e2eRun) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, body, err := r.request(http.MethodGet, "/api/v1/health/db", nil)
+		if err == nil && status == http.StatusOK && body["status"] == "ok" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("status %d", status)
+			}
+			return fmt.Errorf("server at %s not ready after %s: %v", r.baseURL, timeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// seedE2EFixtures stores the fixture stocks and their last e2eHistoryDays sessions, priced in VND,
+// from the same generator as FAKE_PROVIDERS. Stocks that already have prices are left as they are.
+func seedE2EFixtures(codes []string) error {
+	if _, err := config.LoadConfig(); err != nil {
+		return err
+	}
+	db, err := config.InitDB()
+	if err != nil {
+		return err
+	}
+	_, err = seed.Stocks(db, codes, e2eHistoryDays, nil)
+	return err
+}
+
+// request sends a JSON body, or a form for url.Values, and decodes a JSON object response when
+// there is one
+func (r *e2eRun) request(method, path string, body interface{}) (int, map[string]interface{}, error) {
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case url.Values:
+		reader, contentType = strings.NewReader(b.Encode()), "application/x-www-form-urlencoded"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	req, err := http.NewRequest(method, r.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]interface{}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		json.NewDecoder(resp.Body).Decode(&decoded)
+	}
+	return resp.StatusCode, decoded, nil
+}
+
+// expect sends a request and fails unless it answers with status
+func (r *e2eRun) expect(status int, method, path string, body interface{}) (map[string]interface{}, error) {
+	got, decoded, err := r.request(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if got != status {
+		return decoded, fmt.Errorf("%s %s: status %d, expected %d (%v)", method, path, got, status, decoded["error"])
+	}
+	return decoded, nil
+}
+
+// idOf reads a numeric ID from a response field
+func idOf(body map[string]interface{}, field string) (uint, error) {
+	id, ok := body[field].(float64)
+	if !ok || id <= 0 {
+		return 0, fmt.Errorf("response without %s: %v", field, body)
+	}
+	return uint(id), nil
+}
+
+func e2eHealth(r *e2eRun) error {
+	body, err := r.expect(http.StatusOK, http.MethodGet, "/health", nil)
+	if err != nil {
+		return err
+	}
+	if body["status"] != "ok" {
+		return fmt.Errorf("health status %v", body["status"])
+	}
+	_, err = r.expect(http.StatusOK, http.MethodGet, "/ready", nil)
+	return err
+}
+
+func e2eAdminLoginRejected(r *e2eRun) error {
+	form := url.Values{"username": {r.adminUser}, "password": {r.adminPassword + "-wrong"}}
+	status, _, err := r.request(http.MethodPost, "/admin/login", form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusUnauthorized {
+		return fmt.Errorf("wrong password answered %d, expected 401", status)
+	}
+	return nil
+}
+
+func e2eAdminLogin(r *e2eRun) error {
+	form := url.Values{"username": {r.adminUser}, "password": {r.adminPassword}}
+	req, err := http.NewRequest(http.MethodPost, r.baseURL+"/admin/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound || !strings.HasSuffix(resp.Header.Get("Location"), "/admin/dashboard") {
+		return fmt.Errorf("login answered %d to %q, expected a redirect to the dashboard", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "admin_session" && cookie.Value != "" {
+			// The dashboard must now open without a redirect to the login page
+			_, err := r.expect(http.StatusOK, http.MethodGet, "/admin/dashboard", nil)
+			return err
+		}
+	}
+	return errors.New("login set no admin_session cookie")
+}
+
+func e2eRuleCreate(r *e2eRun) error {
+	body, err := r.expect(http.StatusOK, http.MethodPost, "/admin/signal-conditions/rules", map[string]interface{}{
+		"name":        "e2e rule " + r.tag,
+		"description": "Created by cplsctl e2e",
+		"signal_type": "BUY",
+		"min_score":   60,
+	})
+	if err != nil {
+		return err
+	}
+	r.ruleID, err = idOf(body, "id")
+	return err
+}
+
+func (r *e2eRun) rulePath(suffix string) (string, error) {
+	if r.ruleID == 0 {
+		return "", errors.New("no rule created")
+	}
+	return fmt.Sprintf("/admin/signal-conditions/rules/%d%s", r.ruleID, suffix), nil
+}
+
+func e2eRuleUpdate(r *e2eRun) error {
+	path, err := r.rulePath("")
+	if err != nil {
+		return err
+	}
+	_, err = r.expect(http.StatusOK, http.MethodPut, path, map[string]interface{}{
+		"name":              "e2e rule " + r.tag + " (updated)",
+		"signal_type":       "BUY",
+		"min_score":         70,
+		"target_percent":    12,
+		"stop_loss_percent": 6,
+		"is_active":         true,
+	})
+	return err
+}
+
+func e2eRuleStats(r *e2eRun) error {
+	path, err := r.rulePath("/stats")
+	if err != nil {
+		return err
+	}
+	_, err = r.expect(http.StatusOK, http.MethodGet, path, nil)
+	return err
+}
+
+func e2eRuleDelete(r *e2eRun) error {
+	path, err := r.rulePath("")
+	if err != nil {
+		return err
+	}
+	_, err = r.expect(http.StatusOK, http.MethodDelete, path, nil)
+	return err
+}
+
+// e2eRuleRestore brings the rule back from the trash, then deletes it again so runs do not pile
+// up active rules
+func e2eRuleRestore(r *e2eRun) error {
+	path, err := r.rulePath("/restore")
+	if err != nil {
+		return err
+	}
+	if _, err := r.expect(http.StatusOK, http.MethodPost, path, nil); err != nil {
+		return err
+	}
+	return e2eRuleDelete(r)
+}
+
+func e2eScreener(r *e2eRun) error {
+	body, err := r.expect(http.StatusOK, http.MethodPost, "/api/v1/screener/screen", map[string]interface{}{
+		"symbols": r.codes,
+		"limit":   len(r.codes),
+	})
+	if err != nil {
+		return err
+	}
+	rows, _ := body["data"].([]interface{})
+	if len(rows) != len(r.codes) {
+		return fmt.Errorf("screener returned %d of %d fixture stocks", len(rows), len(r.codes))
+	}
+	for _, row := range rows {
+		result, _ := row.(map[string]interface{})
+		stock, _ := result["stock"].(map[string]interface{})
+		latest, _ := result["latest_price"].(map[string]interface{})
+		// Decimals are encoded as strings
+		if closePrice, _ := latest["close"].(string); closePrice == "" || closePrice == "0" {
+			return fmt.Errorf("%v: no latest price", stock["symbol"])
+		}
+	}
+	return nil
+}
+
+// e2eBacktest runs an SMA crossover over the last six months of the fixture prices
+func e2eBacktest(r *e2eRun) error {
+	body, err := r.expect(http.StatusCreated, http.MethodPost, "/api/v1/strategies", map[string]interface{}{
+		"name":        "e2e sma crossover " + r.tag,
+		"description": "Created by cplsctl e2e",
+		"type":        "sma_crossover",
+		"parameters":  `{"short_period": 10, "long_period": 30}`,
+		"is_active":   true,
+	})
+	if err != nil {
+		return err
+	}
+	strategy, _ := body["data"].(map[string]interface{})
+	if r.strategyID, err = idOf(strategy, "id"); err != nil {
+		return err
+	}
+
+	end := time.Now()
+	body, err = r.expect(http.StatusOK, http.MethodPost, "/api/v1/backtests", map[string]interface{}{
+		"strategy_id":       r.strategyID,
+		"start_date":        end.AddDate(0, -6, 0).Format(services.PriceDateFormat),
+		"end_date":          end.Format(services.PriceDateFormat),
+		"initial_capital":   100_000_000,
+		"symbols":           r.codes,
+		"commission":        0.0015,
+		"risk_per_trade":    0.02,
+		"slippage_model":    "volume",
+		"slippage_bps":      10,
+		"impact_bps":        50,
+		"max_participation": 0.1,
+	})
+	if err != nil {
+		return err
+	}
+	backtest, _ := body["data"].(map[string]interface{})
+	id, err := idOf(backtest, "id")
+	if err != nil {
+		return err
+	}
+	if backtest["completed_at"] == nil {
+		return fmt.Errorf("backtest %d did not complete", id)
+	}
+	_, err = r.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/api/v1/backtests/%d", id), nil)
+	return err
+}
@@ -0,0 +1,170 @@
+// Command cplsctl runs data jobs from a terminal or cron without starting the HTTP server.
+//
+// Run it from the repository root so the data/ directory resolves like it does for the server:
+//
+//	go run ./cmd/cplsctl sync-prices [-codes VNM,FPT]
+//	go run ./cmd/cplsctl calc-indicators
+//	go run ./cmd/cplsctl eval-rules [-as-of 2024-06-28] [-rules 1,2] [-codes VNM] [-type stock]
+//	go run ./cmd/cplsctl backtest -from 2024-01-01 -to 2024-06-30 [-rules 1,2] [-step 5] [-hold 20]
+//	go run ./cmd/cplsctl e2e [-base-url http://localhost:8080] [-codes VNM,FPT] [-seed=false]
+//	go run ./cmd/cplsctl selftest
+//	go run ./cmd/cplsctl seed-demo
+//
+// Every command accepts -format table|json. Rule commands read rules from the database configured
+// by the same environment variables as the server; price and indicator commands use the database
+// only when it is reachable (indicator profiles, sync history, stock lifecycle).
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"go_backend_project/config"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"gorm.io/gorm"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"sync-prices", "fetch daily prices for all stocks, or only -codes", runSyncPrices},
+	{"calc-indicators", "recalculate indicators from local prices and save them", runCalcIndicators},
+	{"eval-rules", "evaluate signal rules on current or -as-of indicators", runEvalRules},
+	{"backtest", "replay signal rules between -from and -to", runBacktest},
+	{"e2e", "seed fixture stocks and run admin, rule, screener and backtest flows against a server", runE2E},
+	{"selftest", "check connectivity and credentials of Postgres, Supabase, MongoDB, providers and alert channels", runSelfTest},
+	{"seed-demo", "seed demo stocks, prices, rules, templates, users and portfolios, keeping existing rows", runSeedDemo},
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(os.Args[2:])
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if name != "help" && name != "-h" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	}
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: cplsctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'cplsctl <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates a command flag set with the shared -format flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	return fs, format
+}
+
+// output writes v as indented JSON, or calls table with a tab-aligned writer
+func output(format string, v interface{}, table func(w *tabwriter.Writer)) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	default:
+		return fmt.Errorf("invalid format %q (use table or json)", format)
+	}
+}
+
+// setup initializes the services the commands share. With requireDB the command fails when the
+// database is unreachable; otherwise it continues on local data only.
+func setup(requireDB bool) (*gorm.DB, error) {
+	if _, err := config.LoadConfig(); err != nil {
+		log.Printf("Warning: Config load issue: %v", err)
+	}
+	if err := services.InitFakeProviders(); err != nil {
+		return nil, fmt.Errorf("failed to enable fake providers: %w", err)
+	}
+
+	db, err := config.InitDB()
+	if err != nil {
+		if requireDB {
+			return nil, fmt.Errorf("database required: %w", err)
+		}
+		log.Printf("Continuing without database: %v", err)
+		db = nil
+	}
+
+	if err := services.InitTradingCalendar(); err != nil {
+		log.Printf("Warning: Failed to initialize trading calendar: %v", err)
+	}
+	if err := services.InitPriceService(); err != nil {
+		return nil, fmt.Errorf("failed to initialize price service: %w", err)
+	}
+	if err := services.InitIndicatorService(); err != nil {
+		return nil, fmt.Errorf("failed to initialize indicator service: %w", err)
+	}
+	if err := services.InitStockLifecycle(db); err != nil {
+		log.Printf("Warning: Failed to initialize stock lifecycle: %v", err)
+	}
+
+	if db != nil {
+		if err := services.InitSyncHistory(db); err != nil {
+			log.Printf("Warning: Failed to initialize sync history: %v", err)
+		}
+		if err := services.LoadIndicatorProfiles(db); err != nil {
+			log.Printf("Warning: Failed to load indicator profiles, using defaults: %v", err)
+		}
+		if err := signals.InitConditionEvaluator(db); err != nil {
+			return nil, fmt.Errorf("failed to initialize condition evaluator: %w", err)
+		}
+	}
+	return db, nil
+}
+
+// splitCodes parses a comma-separated list of stock codes
+func splitCodes(value string) []string {
+	var codes []string
+	for _, part := range strings.Split(value, ",") {
+		if code := strings.ToUpper(strings.TrimSpace(part)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
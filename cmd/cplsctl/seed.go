@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"go_backend_project/services/seed"
+)
+
+func runSeedDemo(args []string) error {
+	fs, format := newFlagSet("seed-demo")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !seed.Enabled() {
+		return errors.New("demo seeding is disabled in production (set SEED_DEMO_ENABLED=true to allow it)")
+	}
+
+	db, err := setup(true)
+	if err != nil {
+		return err
+	}
+	result, err := seed.Demo(context.Background(), db)
+	if err != nil {
+		return err
+	}
+
+	return output(*format, result, func(w *tabwriter.Writer) {
+		kinds := make([]string, 0, len(result.Created)+len(result.Existing))
+		for kind := range result.Created {
+			kinds = append(kinds, kind)
+		}
+		for kind := range result.Existing {
+			if _, ok := result.Created[kind]; !ok {
+				kinds = append(kinds, kind)
+			}
+		}
+		sort.Strings(kinds)
+		fmt.Fprintln(w, "KIND\tCREATED\tEXISTING")
+		for _, kind := range kinds {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", kind, result.Created[kind], result.Existing[kind])
+		}
+		fmt.Fprintf(w, "Duration:\t%s\n", result.Duration)
+	})
+}
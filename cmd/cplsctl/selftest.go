@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"text/tabwriter"
+
+	"go_backend_project/config"
+	"go_backend_project/services"
+)
+
+func runSelfTest(args []string) error {
+	fs, format := newFlagSet("selftest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Initialize the dependencies like the server does, leaving failures to the report
+	if _, err := config.LoadConfig(); err != nil {
+		log.Printf("Warning: Config load issue: %v", err)
+	}
+	if err := services.InitFakeProviders(); err != nil {
+		return fmt.Errorf("failed to enable fake providers: %w", err)
+	}
+	db, err := config.InitDB()
+	if err != nil {
+		log.Printf("Database unreachable: %v", err)
+		db = nil
+	}
+	if err := services.InitPriceService(); err != nil {
+		return fmt.Errorf("failed to initialize price service: %w", err)
+	}
+	if err := services.InitMongoDBClient(); err != nil {
+		log.Printf("MongoDB unreachable: %v", err)
+	}
+	if err := services.InitOperatorAlerts(); err != nil {
+		log.Printf("Operator alerts misconfigured: %v", err)
+	}
+	if err := services.InitPushService(); err != nil {
+		log.Printf("Push notifications misconfigured: %v", err)
+	}
+
+	report := services.RunSelfTest(context.Background(), db)
+	if err := output(*format, report, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "CHECK\tRESULT\tDURATION\tDETAIL")
+		for _, check := range report.Checks {
+			detail := check.Detail
+			if check.Error != "" {
+				detail = check.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%dms\t%s\n", check.Name, check.Status, check.DurationMs, detail)
+		}
+		fmt.Fprintf(w, "Passed:\t%t\t(%d pass, %d fail, %d skipped)\n", report.Passed, report.Passes, report.Failures, report.Skipped)
+	}); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return errors.New("self-test failed")
+	}
+	return nil
+}
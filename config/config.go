@@ -1,6 +1,7 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"net/url"
@@ -22,13 +23,38 @@ type Config struct {
 	DBUser      string
 	DBPassword  string
 	DBName      string
+	DBSSLMode   string // require by default; disable for a local Postgres without TLS
 	JWTSecret   string
 	Environment string
+
+	// Connection pool and query limits, shared by the primary and the replica
+	DBMaxOpenConns     int
+	DBMaxIdleConns     int
+	DBConnMaxLifetime  time.Duration
+	DBConnMaxIdleTime  time.Duration
+	DBStatementTimeout time.Duration // 0 leaves the server default
+
+	// Optional read replica DSN (postgres://...) for read-heavy admin queries
+	DBReplicaURL string
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is believed when resolving the client IP;
+	// empty uses the peer address
+	TrustedProxies []string
+}
+
+// DefaultTrustedProxies are the private and link-local ranges the Cloud Run front end and
+// internal load balancers connect from
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16",
+	"::1/128", "fc00::/7", "fe80::/10",
 }
 
 var AppConfig *Config
 var DB *gorm.DB
 
+// ReplicaDB is the read replica connection, nil when none is configured or it is unreachable
+var ReplicaDB *gorm.DB
+
 func LoadConfig() (*Config, error) {
 	port := strings.TrimSpace(getEnv("PORT", "8080"))
 	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
@@ -43,8 +69,18 @@ func LoadConfig() (*Config, error) {
 		DBUser:      getEnv("DB_USER", "postgres"),
 		DBPassword:  getEnv("DB_PASSWORD", ""),
 		DBName:      getEnv("DB_NAME", "postgres"),
+		DBSSLMode:   getEnv("DB_SSLMODE", "require"),
 		JWTSecret:   getEnv("JWT_SECRET", "default-secret"),
 		Environment: getEnv("ENVIRONMENT", "production"),
+
+		// Cloud Run scales to zero, so the defaults keep the pool small
+		DBMaxOpenConns:     getEnvInt("DB_MAX_OPEN_CONNS", 5),
+		DBMaxIdleConns:     getEnvInt("DB_MAX_IDLE_CONNS", 2),
+		DBConnMaxLifetime:  getEnvDuration("DB_CONN_MAX_LIFETIME", 10*time.Minute),
+		DBConnMaxIdleTime:  getEnvDuration("DB_CONN_MAX_IDLE_TIME", 3*time.Minute),
+		DBStatementTimeout: getEnvDuration("DB_STATEMENT_TIMEOUT", 0),
+		DBReplicaURL:       os.Getenv("DB_REPLICA_URL"),
+		TrustedProxies:     getEnvList("TRUSTED_PROXIES", DefaultTrustedProxies),
 	}
 
 	// Try to parse DATABASE_URL if DB_HOST is not set
@@ -75,6 +111,9 @@ func LoadConfig() (*Config, error) {
 
 	log.Printf("Config: PORT=%s, DB_HOST=%s, DB_USER=%s, DB_NAME=%s, ENV=%s",
 		config.Port, maskStr(config.DBHost), config.DBUser, config.DBName, config.Environment)
+	log.Printf("Config: DB pool max_open=%d max_idle=%d, statement_timeout=%s, read replica=%t",
+		config.DBMaxOpenConns, config.DBMaxIdleConns, config.DBStatementTimeout, config.DBReplicaURL != "")
+	log.Printf("Config: trusted proxies %v", config.TrustedProxies)
 
 	// Log Supabase connection info for debugging
 	if strings.Contains(config.DBHost, "supabase.co") {
@@ -108,6 +147,9 @@ func parseDBURL(dbURL string, config *Config) error {
 	if len(u.Path) > 1 {
 		config.DBName = u.Path[1:] // Remove leading /
 	}
+	if sslMode := u.Query().Get("sslmode"); sslMode != "" {
+		config.DBSSLMode = sslMode
+	}
 
 	log.Printf("Parsed DATABASE_URL: host=%s, port=%s, user=%s, db=%s",
 		maskStr(config.DBHost), config.DBPort, config.DBUser, config.DBName)
@@ -148,7 +190,7 @@ func InitDB() (*gorm.DB, error) {
 
 	// Build DSN with appropriate settings for Supabase
 	// Use direct connection for transactions, pooler for session mode
-	sslMode := "require"
+	sslMode := AppConfig.DBSSLMode
 	connectTimeout := 10
 
 	// For Supabase pooler (pgbouncer), we need different settings
@@ -167,6 +209,15 @@ func InitDB() (*gorm.DB, error) {
 		connectTimeout,
 	)
 
+	// pgbouncer rejects startup parameters, so the timeout only applies to direct connections
+	if AppConfig.DBStatementTimeout > 0 {
+		if isPooler {
+			log.Println("DB_STATEMENT_TIMEOUT ignored: the connection pooler does not accept startup parameters")
+		} else {
+			dsn += fmt.Sprintf("&statement_timeout=%d", AppConfig.DBStatementTimeout.Milliseconds())
+		}
+	}
+
 	log.Printf("Connecting to database at %s...", maskStr(AppConfig.DBHost))
 
 	gormConfig := &gorm.Config{
@@ -194,12 +245,7 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("db.DB() failed: %w", err)
 	}
 
-	// Optimized connection pool settings for cloud environments (Cloud Run + Supabase)
-	// Cloud Run scales to zero, so we need smaller pool settings
-	sqlDB.SetMaxIdleConns(2)                   // Fewer idle connections for serverless
-	sqlDB.SetMaxOpenConns(5)                   // Limit concurrent connections for pooler
-	sqlDB.SetConnMaxLifetime(10 * time.Minute) // Shorter lifetime for cloud environments
-	sqlDB.SetConnMaxIdleTime(3 * time.Minute)  // Close idle connections quickly
+	configurePool(sqlDB)
 
 	if err := sqlDB.Ping(); err != nil {
 		log.Printf("ERROR: Database ping failed: %v", err)
@@ -208,9 +254,70 @@ func InitDB() (*gorm.DB, error) {
 
 	log.Println("Database connected successfully!")
 	DB = db
+
+	// A missing replica degrades to reading from the primary, it does not stop startup
+	if AppConfig.DBReplicaURL != "" {
+		if replica, err := openReplica(gormConfig); err != nil {
+			log.Printf("Warning: read replica unavailable, reads use the primary: %v", err)
+		} else {
+			ReplicaDB = replica
+			log.Println("Read replica connected")
+		}
+	}
 	return db, nil
 }
 
+// openReplica connects to DB_REPLICA_URL with the primary's pool and timeout settings
+func openReplica(gormConfig *gorm.Config) (*gorm.DB, error) {
+	u, err := url.Parse(AppConfig.DBReplicaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_REPLICA_URL: %w", err)
+	}
+	q := u.Query()
+	if q.Get("sslmode") == "" {
+		q.Set("sslmode", "require")
+	}
+	if q.Get("connect_timeout") == "" {
+		q.Set("connect_timeout", "10")
+	}
+	if AppConfig.DBStatementTimeout > 0 && !strings.Contains(u.Host, "pooler.supabase.com") {
+		q.Set("statement_timeout", strconv.FormatInt(AppConfig.DBStatementTimeout.Milliseconds(), 10))
+	}
+	u.RawQuery = q.Encode()
+
+	replica, err := gorm.Open(postgres.Open(u.String()), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB)
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("ping failed: %w", err)
+	}
+	return replica, nil
+}
+
+// configurePool applies the configured connection pool limits
+func configurePool(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(AppConfig.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(AppConfig.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(AppConfig.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(AppConfig.DBConnMaxIdleTime)
+}
+
+// ReadDB returns the read replica for read-heavy queries that tolerate replication lag, or db
+// when no replica is connected
+func ReadDB(db *gorm.DB) *gorm.DB {
+	if ReplicaDB != nil {
+		return ReplicaDB
+	}
+	return db
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -218,6 +325,51 @@ func getEnv(key, def string) string {
 	return def
 }
 
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("Warning: Invalid %s value '%s', using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// getEnvList reads a comma-separated list; "none" gives an empty list
+func getEnvList(key string, def []string) []string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	if strings.EqualFold(v, "none") {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvDuration reads a duration such as "30s" or "5m"
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Warning: Invalid %s value '%s', using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
 func maskStr(s string) string {
 	if len(s) < 8 {
 		return "***"
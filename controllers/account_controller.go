@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AccountController lets users manage their own account, including requesting erasure of their data
+type AccountController struct {
+	db *gorm.DB
+}
+
+// NewAccountController creates a new account controller
+func NewAccountController(db *gorm.DB) *AccountController {
+	return &AccountController{db: db}
+}
+
+// RegisterAccountRoutes registers account routes
+func (ac *AccountController) RegisterAccountRoutes(api *gin.RouterGroup) {
+	account := api.Group("/account")
+	{
+		account.GET("/erasure", ac.GetErasureRequest)
+		account.POST("/erasure", ac.RequestErasure)
+		account.DELETE("/erasure", ac.CancelErasureRequest)
+	}
+}
+
+// GetErasureRequest returns the current user's latest data erasure request
+// GET /api/v1/account/erasure
+func (ac *AccountController) GetErasureRequest(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request models.UserErasureRequest
+	if err := ac.db.Where("subject_hash = ?", services.ErasureSubjectHash(userID)).
+		Order("created_at DESC").First(&request).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No erasure request"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch erasure request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": request})
+}
+
+// RequestErasure asks for the current user's data to be erased; an admin reviews the request,
+// exports the user's data and then erases it
+// POST /api/v1/account/erasure {"reason": "..."}
+func (ac *AccountController) RequestErasure(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	email, _ := middleware.GetSupabaseEmailFromContext(c)
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	_ = c.ShouldBindJSON(&body)
+
+	request, created, err := services.RequestUserErasure(ac.db, userID, email, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create erasure request"})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{"message": "An erasure request is already open", "data": request})
+		return
+	}
+
+	log.Printf("User data erasure requested (request %d)", request.ID)
+	services.NotifyAdmins(ac.db, models.NotificationLevelWarning, "privacy", "User data erasure requested",
+		"A user asked for their data to be erased; export their data and approve or reject the request",
+		gin.H{"request_id": request.ID})
+	c.JSON(http.StatusCreated, gin.H{"message": "Erasure request received", "data": request})
+}
+
+// CancelErasureRequest withdraws the current user's pending erasure request
+// DELETE /api/v1/account/erasure
+func (ac *AccountController) CancelErasureRequest(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	result := ac.db.Model(&models.UserErasureRequest{}).
+		Where("supabase_user_id = ? AND status = ?", userID, models.ErasureStatusPending).
+		Update("status", models.ErasureStatusCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel erasure request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending erasure request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Erasure request cancelled"})
+}
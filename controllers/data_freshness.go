@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dataFreshnessKey is the context key holding the freshness of the request's indicator data
+const dataFreshnessKey = "data_freshness"
+
+// DataFreshnessGuard attaches the freshness of the indicator data to signal and indicator
+// responses: as X-Data-As-Of and X-Data-Stale headers, and in the body through the handlers.
+// Clients that would rather fail than act on old data pass ?strict=true and get a 409 instead.
+func DataFreshnessGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		freshness := services.IndicatorFreshness(time.Now())
+		c.Set(dataFreshnessKey, &freshness)
+		c.Header("X-Data-As-Of", freshness.DataAsOf)
+		c.Header("X-Data-Stale", strconv.FormatBool(freshness.IsStale))
+
+		if freshness.IsStale && c.Query("strict") == "true" {
+			c.AbortWithStatusJSON(http.StatusConflict, SignalResponse{
+				Success:   false,
+				Error:     "Indicator data is stale, the daily data pipeline has not refreshed it",
+				Freshness: &freshness,
+				Timestamp: time.Now().Format(time.RFC3339),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// LiveSignalsGuard lets admins pass ?live=true to have signals evaluated on demand instead of
+// served from the snapshot published by the data pipeline
+func LiveSignalsGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("live") != "true" {
+			c.Next()
+			return
+		}
+		if !middleware.IsAdminRequest(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, SignalResponse{
+				Success:   false,
+				Error:     "live=true is reserved for admins",
+				Timestamp: time.Now().Format(time.RFC3339),
+			})
+			return
+		}
+		c.Request = c.Request.WithContext(signals.WithLiveSignals(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// dataFreshness returns the freshness set by DataFreshnessGuard, or nil on unguarded routes
+func dataFreshness(c *gin.Context) *services.DataFreshness {
+	if v, ok := c.Get(dataFreshnessKey); ok {
+		return v.(*services.DataFreshness)
+	}
+	return nil
+}
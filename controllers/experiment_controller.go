@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExperimentController exposes the caller's A/B experiment variants and records their
+// engagement events
+type ExperimentController struct {
+	db *gorm.DB
+}
+
+// NewExperimentController creates a new experiment controller
+func NewExperimentController(db *gorm.DB) *ExperimentController {
+	return &ExperimentController{db: db}
+}
+
+// RegisterExperimentRoutes registers experiment routes
+func (ec *ExperimentController) RegisterExperimentRoutes(api *gin.RouterGroup) {
+	experiments := api.Group("/experiments")
+	{
+		experiments.GET("", ec.GetAssignments)
+		experiments.POST("/:key/events", ec.RecordEvent)
+	}
+}
+
+// GetAssignments returns the current user's variants of the running experiments, so clients
+// can render before calling the routes the experiments cover
+// GET /api/v1/experiments
+func (ec *ExperimentController) GetAssignments(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": services.ListExperimentAssignments(ec.db, userID)})
+}
+
+// RecordEvent records an engagement event, e.g. "signal_click", of the current user in an
+// experiment
+// POST /api/v1/experiments/:key/events
+func (ec *ExperimentController) RecordEvent(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req struct {
+		Event string `json:"event" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event is required"})
+		return
+	}
+
+	event, err := services.RecordExperimentEvent(ec.db, c.Param("key"), userID, req.Event)
+	switch {
+	case errors.Is(err, services.ErrExperimentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return
+	case errors.Is(err, services.ErrInvalidExperiment), errors.Is(err, services.ErrNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record event"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": event})
+}
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IdeaController serves the community trade ideas: users post an idea on a stock, others like
+// and follow it, and its outcome is tracked on the stock's prices after it was posted
+type IdeaController struct {
+	db *gorm.DB
+}
+
+// NewIdeaController creates a new idea controller
+func NewIdeaController(db *gorm.DB) *IdeaController {
+	return &IdeaController{db: db}
+}
+
+// RegisterIdeaRoutes registers trade idea routes
+func (ic *IdeaController) RegisterIdeaRoutes(api *gin.RouterGroup) {
+	ideas := api.Group("/ideas")
+	{
+		ideas.GET("", ic.LatestIdeas)
+		ideas.GET("/trending", ic.TrendingIdeas)
+		ideas.GET("/stock/:code", ic.StockIdeas)
+		ideas.GET("/mine", ic.MyIdeas)
+		ideas.GET("/following", ic.FollowedIdeas)
+		ideas.POST("", ic.CreateIdea)
+		ideas.GET("/:id", ic.GetIdea)
+		ideas.PUT("/:id", ic.UpdateIdea)
+		ideas.DELETE("/:id", ic.DeleteIdea)
+		ideas.POST("/:id/like", ic.LikeIdea)
+		ideas.DELETE("/:id/like", ic.UnlikeIdea)
+		ideas.POST("/:id/follow", ic.FollowIdea)
+		ideas.DELETE("/:id/follow", ic.UnfollowIdea)
+		ideas.POST("/:id/report", ic.ReportIdea)
+	}
+}
+
+// respondFeed writes a page of a feed with the caller's likes and follows marked
+func (ic *IdeaController) respondFeed(c *gin.Context, q services.IdeaFeedQuery, extra gin.H) {
+	q.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	q.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	ideas, total, err := services.ListIdeas(ic.db, q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ideas"})
+		return
+	}
+	viewerID, _ := middleware.GetSupabaseUserFromContext(c)
+	services.FillIdeaViewerState(ic.db, viewerID, ideas)
+
+	response := gin.H{
+		"data": ideas,
+		"pagination": gin.H{
+			"page":  q.Page,
+			"limit": q.Limit,
+			"total": total,
+		},
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// LatestIdeas returns published ideas, newest first
+// GET /api/v1/ideas?stock=FPT&page=1&limit=20
+func (ic *IdeaController) LatestIdeas(c *gin.Context) {
+	ic.respondFeed(c, services.IdeaFeedQuery{StockCode: c.Query("stock")}, nil)
+}
+
+// TrendingIdeas returns the most liked and followed ideas of the last week, weighted towards newer ones
+// GET /api/v1/ideas/trending?stock=FPT&page=1&limit=20
+func (ic *IdeaController) TrendingIdeas(c *gin.Context) {
+	ic.respondFeed(c, services.IdeaFeedQuery{StockCode: c.Query("stock"), Trending: true}, nil)
+}
+
+// StockIdeas returns the ideas on a stock, newest first, with how past ideas on it turned out
+// GET /api/v1/ideas/stock/:code
+func (ic *IdeaController) StockIdeas(c *gin.Context) {
+	code := strings.ToUpper(c.Param("code"))
+	stats, err := services.IdeaStatsForStock(ic.db, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch idea outcomes"})
+		return
+	}
+	ic.respondFeed(c, services.IdeaFeedQuery{StockCode: code}, gin.H{"outcomes": stats})
+}
+
+// MyIdeas returns the current user's ideas, including hidden ones
+// GET /api/v1/ideas/mine
+func (ic *IdeaController) MyIdeas(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	ideas := []models.Idea{}
+	if err := ic.db.Where("author_id = ?", userID).Order("created_at DESC").Find(&ideas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ideas"})
+		return
+	}
+	services.FillIdeaViewerState(ic.db, userID, ideas)
+	c.JSON(http.StatusOK, gin.H{"data": ideas})
+}
+
+// FollowedIdeas returns the ideas the current user follows
+// GET /api/v1/ideas/following
+func (ic *IdeaController) FollowedIdeas(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	ideas, err := services.ListFollowedIdeas(ic.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ideas"})
+		return
+	}
+	services.FillIdeaViewerState(ic.db, userID, ideas)
+	c.JSON(http.StatusOK, gin.H{"data": ideas})
+}
+
+// CreateIdea posts a trade idea on a stock at its current price, optionally linked to a signal
+// rule or template, with an optional target, stop loss and horizon its outcome is judged by
+// POST /api/v1/ideas
+func (ic *IdeaController) CreateIdea(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request services.IdeaInput
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	email, _ := middleware.GetSupabaseEmailFromContext(c)
+	authorName := shortUserID(userID)
+	if at := strings.Index(email, "@"); at > 0 {
+		authorName = email[:at]
+	}
+
+	idea, err := services.CreateIdea(ic.db, userID, authorName, request)
+	if errors.Is(err, services.ErrInvalidIdea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post idea"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": idea})
+}
+
+// GetIdea returns a published idea, or a hidden one to its author
+// GET /api/v1/ideas/:id
+func (ic *IdeaController) GetIdea(c *gin.Context) {
+	idea, viewerID, ok := ic.loadIdea(c)
+	if !ok {
+		return
+	}
+	ideas := []models.Idea{*idea}
+	services.FillIdeaViewerState(ic.db, viewerID, ideas)
+	c.JSON(http.StatusOK, gin.H{"data": ideas[0]})
+}
+
+// UpdateIdea changes the title and body of the current user's idea; prices can't change
+// PUT /api/v1/ideas/:id
+func (ic *IdeaController) UpdateIdea(c *gin.Context) {
+	idea, ok := ic.loadOwnIdea(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Title string `json:"title" binding:"required"`
+		Body  string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.UpdateIdeaText(ic.db, idea, request.Title, request.Body)
+	if errors.Is(err, services.ErrInvalidIdea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": idea})
+}
+
+// DeleteIdea removes the current user's idea
+// DELETE /api/v1/ideas/:id
+func (ic *IdeaController) DeleteIdea(c *gin.Context) {
+	idea, ok := ic.loadOwnIdea(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteIdea(ic.db, idea); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Idea deleted"})
+}
+
+// LikeIdea likes an idea
+// POST /api/v1/ideas/:id/like
+func (ic *IdeaController) LikeIdea(c *gin.Context) {
+	ic.setEngagement(c, services.LikeIdea, true)
+}
+
+// UnlikeIdea removes the current user's like of an idea
+// DELETE /api/v1/ideas/:id/like
+func (ic *IdeaController) UnlikeIdea(c *gin.Context) {
+	ic.setEngagement(c, services.LikeIdea, false)
+}
+
+// FollowIdea follows an idea to be notified when it closes
+// POST /api/v1/ideas/:id/follow
+func (ic *IdeaController) FollowIdea(c *gin.Context) {
+	ic.setEngagement(c, services.FollowIdea, true)
+}
+
+// UnfollowIdea stops following an idea
+// DELETE /api/v1/ideas/:id/follow
+func (ic *IdeaController) UnfollowIdea(c *gin.Context) {
+	ic.setEngagement(c, services.FollowIdea, false)
+}
+
+// setEngagement applies a like or follow change of the current user and returns the new counts
+func (ic *IdeaController) setEngagement(c *gin.Context, apply func(*gorm.DB, *models.Idea, string, bool) error, on bool) {
+	idea, userID, ok := ic.loadIdea(c)
+	if !ok {
+		return
+	}
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	if err := apply(ic.db, idea, userID, on); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"like_count": idea.LikeCount, "follow_count": idea.FollowCount})
+}
+
+// ReportIdea reports an idea to the moderators; enough reports hide it until it is reviewed
+// POST /api/v1/ideas/:id/report
+func (ic *IdeaController) ReportIdea(c *gin.Context) {
+	idea, userID, ok := ic.loadIdea(c)
+	if !ok {
+		return
+	}
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.ReportIdea(ic.db, idea, userID, request.Reason)
+	if errors.Is(err, services.ErrInvalidIdea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report idea"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Idea reported"})
+}
+
+// loadIdea loads :id if the caller can see it, with the caller's user ID when signed in
+func (ic *IdeaController) loadIdea(c *gin.Context) (*models.Idea, string, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, "", false
+	}
+
+	viewerID, _ := middleware.GetSupabaseUserFromContext(c)
+	idea, err := services.GetIdea(ic.db, uint(id), viewerID)
+	if errors.Is(err, services.ErrIdeaNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Idea not found"})
+		return nil, "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch idea"})
+		return nil, "", false
+	}
+	return idea, viewerID, true
+}
+
+// loadOwnIdea loads :id if the caller wrote it
+func (ic *IdeaController) loadOwnIdea(c *gin.Context) (*models.Idea, bool) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var idea models.Idea
+	if err := ic.db.Where("id = ? AND author_id = ?", id, userID).First(&idea).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Idea not found"})
+		return nil, false
+	}
+	return &idea, true
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MarketController handles market-wide information such as the trading calendar
+type MarketController struct {
+	db *gorm.DB
+}
+
+// NewMarketController creates a new market controller
+func NewMarketController(db *gorm.DB) *MarketController {
+	return &MarketController{db: db}
+}
+
+// GetCalendar returns the current session and upcoming trading sessions
+// GET /api/v1/market/calendar?days=10
+func (mc *MarketController) GetCalendar(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "10"))
+	if days < 1 || days > 60 {
+		days = 10
+	}
+
+	cal := services.MarketCalendar()
+	now := time.Now().In(cal.Location())
+
+	// Holidays in the window covered by the upcoming sessions
+	upcoming := cal.UpcomingSessions(now, days)
+	today := now.Format(services.PriceDateFormat)
+	last := upcoming[len(upcoming)-1].Date
+	holidays := make([]services.MarketHoliday, 0)
+	for _, h := range cal.Holidays() {
+		if h.Date >= today && h.Date <= last {
+			holidays = append(holidays, h)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"timezone":         cal.Location().String(),
+			"now":              now,
+			"is_trading_day":   cal.IsTradingDay(now),
+			"is_market_open":   cal.IsMarketOpen(now),
+			"current_session":  cal.SessionAt(now),
+			"today":            cal.Day(now),
+			"next_trading_day": cal.NextTradingDay(now).Format(services.PriceDateFormat),
+			"upcoming":         upcoming,
+			"holidays":         holidays,
+		},
+	})
+}
+
+// GetStatus returns the session each exchange is in and the time to its next session, so clients
+// can poll quickly while the market is open and back off while it is closed
+// GET /api/v1/market/status
+func (mc *MarketController) GetStatus(c *gin.Context) {
+	cal := services.MarketCalendar()
+	now := time.Now().In(cal.Location())
+
+	exchanges := make([]services.ExchangeStatus, 0, len(services.Exchanges))
+	anyOpen := false
+	for _, exchange := range services.Exchanges {
+		status := cal.ExchangeStatusAt(exchange, now)
+		anyOpen = anyOpen || status.IsOpen
+		exchanges = append(exchanges, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"timezone":       cal.Location().String(),
+			"server_time":    now,
+			"is_trading_day": cal.IsTradingDay(now),
+			"is_open":        anyOpen,
+			"exchanges":      exchanges,
+		},
+	})
+}
+
+// GetEventCalendar returns the upcoming corporate events of the given codes, or of the current
+// user's watchlist when no codes are given
+// GET /api/v1/market/events?days=30&codes=VNM,FPT
+func (mc *MarketController) GetEventCalendar(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 180 {
+		days = 30
+	}
+
+	var codes []string
+	for _, code := range strings.Split(c.Query("codes"), ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		userID, err := middleware.GetSupabaseUserFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required for the watchlist calendar (or pass codes)"})
+			return
+		}
+		if codes, err = services.WatchlistCodes(mc.db, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load watchlist"})
+			return
+		}
+		if len(codes) == 0 {
+			c.JSON(http.StatusOK, gin.H{"data": []interface{}{}, "codes": codes, "total": 0})
+			return
+		}
+	}
+
+	events, err := services.UpcomingCorporateEvents(mc.db, codes, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": events, "codes": codes, "total": len(events)})
+}
+
+// GetRegime returns the detected market regime and the composite strategy weights applied to it
+// GET /api/v1/market/regime?refresh=true
+func (mc *MarketController) GetRegime(c *gin.Context) {
+	var regime *services.MarketRegime
+	var err error
+	if c.Query("refresh") == "true" {
+		regime, err = services.GlobalMarketRegime.Refresh()
+	} else {
+		regime, err = services.GlobalMarketRegime.Current()
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Market regime unavailable: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"regime":            regime,
+			"composite_weights": signals.CompositeWeights(regime.Regime),
+		},
+	})
+}
+
+// GetHeatmap returns the sector-grouped market map with market cap weights, daily change and RS
+// GET /api/v1/market/heatmap
+func (mc *MarketController) GetHeatmap(c *gin.Context) {
+	heatmap, err := services.GlobalMarketHeatmap.Current(mc.db)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Market heatmap unavailable: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": heatmap})
+}
+
+// GetMarketStats returns the distribution of key indicators across all stocks (RSI and RS
+// quantiles, RS decile boundaries, breadth of MACD and moving averages); with code, also where
+// that stock sits within them
+// GET /api/v1/market/stats?code=VNM
+func (mc *MarketController) GetMarketStats(c *gin.Context) {
+	stats, err := services.GlobalMarketStats.Current()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Market statistics unavailable: " + err.Error()})
+		return
+	}
+
+	response := gin.H{"data": stats}
+	if code := strings.ToUpper(c.Query("code")); code != "" {
+		ind, err := services.GlobalIndicatorService.GetRankedStockIndicators(code)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stock indicators not found: " + code})
+			return
+		}
+		response["position"] = stats.Position(ind)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMovers returns top gainers, losers or most traded stocks with liquidity and exchange filters,
+// in the paginated envelope of the public signal API
+// GET /api/v1/market/movers?type=gainers|losers|volume&exchange=HOSE&min_trading_val=5&instrument_type=stock&page=1&limit=20
+func (mc *MarketController) GetMovers(c *gin.Context) {
+	moversType, err := services.ParseMoversType(c.Query("type"))
+	if err != nil {
+		mc.errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	instrumentTypes, err := services.ParseInstrumentTypes(c.DefaultQuery("instrument_type", services.InstrumentTypeStock))
+	if err != nil {
+		mc.errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	minTradingVal := 0.0
+	if value := c.Query("min_trading_val"); value != "" {
+		minTradingVal, err = strconv.ParseFloat(value, 64)
+		if err != nil || minTradingVal < 0 {
+			mc.errorResponse(c, http.StatusBadRequest, "min_trading_val must be a non-negative number (billion VND)")
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", c.DefaultQuery("page_size", "20")))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	movers, updatedAt, err := services.MarketMovers(services.MarketMoverFilter{
+		Type:            moversType,
+		Exchange:        strings.ToUpper(c.Query("exchange")),
+		MinTradingVal:   minTradingVal,
+		InstrumentTypes: instrumentTypes,
+	})
+	if err != nil {
+		mc.errorResponse(c, http.StatusServiceUnavailable, "Market movers unavailable: "+err.Error())
+		return
+	}
+
+	total := len(movers)
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, SignalResponse{
+		Success: true,
+		Data:    movers[start:end],
+		Meta: &MetaInfo{
+			Total:      total,
+			Page:       page,
+			PageSize:   limit,
+			TotalPages: (total + limit - 1) / limit,
+			UpdatedAt:  updatedAt,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+func (mc *MarketController) errorResponse(c *gin.Context, status int, message string) {
+	c.JSON(status, SignalResponse{
+		Success:   false,
+		Error:     message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
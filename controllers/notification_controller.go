@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationController serves the user's in-app inbox, fed by price alerts, signal
+// subscriptions and membership events, and the devices and settings for push delivery
+type NotificationController struct {
+	db *gorm.DB
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController(db *gorm.DB) *NotificationController {
+	return &NotificationController{db: db}
+}
+
+// RegisterNotificationRoutes registers notification routes
+func (nc *NotificationController) RegisterNotificationRoutes(api *gin.RouterGroup) {
+	notifications := api.Group("/notifications")
+	{
+		notifications.GET("", nc.ListNotifications)
+		notifications.GET("/unread-count", nc.GetUnreadCount)
+		notifications.POST("/read-all", nc.MarkAllRead)
+		notifications.POST("/:id/read", nc.MarkRead)
+		notifications.POST("/devices", nc.RegisterDevice)
+		notifications.DELETE("/devices", nc.UnregisterDevice)
+		notifications.GET("/preferences", nc.GetPreferences)
+		notifications.PUT("/preferences", nc.UpdatePreferences)
+	}
+}
+
+// ListNotifications returns the current user's notifications, newest first, with the unread count
+// GET /api/v1/notifications?unread=true&category=signal_alert&page=1&limit=20
+func (nc *NotificationController) ListNotifications(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := nc.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	var total int64
+	query.Count(&total)
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	var unread int64
+	nc.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   notifications,
+		"unread": unread,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// GetUnreadCount returns the current user's unread notifications, in total and per category
+// GET /api/v1/notifications/unread-count
+func (nc *NotificationController) GetUnreadCount(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var rows []struct {
+		Category string
+		Count    int64
+	}
+	if err := nc.db.Model(&models.Notification{}).
+		Select("category, COUNT(*) as count").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Group("category").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	var unread int64
+	byCategory := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		byCategory[row.Category] = row.Count
+		unread += row.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread, "by_category": byCategory})
+}
+
+// MarkRead marks one of the current user's notifications as read
+// POST /api/v1/notifications/:id/read
+func (nc *NotificationController) MarkRead(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var notification models.Notification
+	if err := nc.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := nc.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": notification})
+}
+
+// MarkAllRead marks all of the current user's unread notifications as read, optionally only
+// those of one category
+// POST /api/v1/notifications/read-all?category=price_alert
+func (nc *NotificationController) MarkAllRead(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	query := nc.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// RegisterDevice registers an FCM registration token of the current user's device; a token
+// registered before by another account moves to the current user
+// POST /api/v1/notifications/devices {"token": "...", "platform": "android", "app_version": "1.4.0"}
+func (nc *NotificationController) RegisterDevice(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Token      string `json:"token" binding:"required"`
+		Platform   string `json:"platform" binding:"required"`
+		AppVersion string `json:"app_version"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch request.Platform {
+	case models.DevicePlatformAndroid, models.DevicePlatformIOS, models.DevicePlatformWeb:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "platform must be android, ios or web"})
+		return
+	}
+	if len(request.Token) > 512 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is too long"})
+		return
+	}
+
+	device := models.DeviceToken{
+		UserID:     userID,
+		Token:      request.Token,
+		Platform:   request.Platform,
+		AppVersion: request.AppVersion,
+		LastSeenAt: time.Now(),
+	}
+	if err := nc.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "app_version", "last_seen_at", "updated_at"}),
+	}).Create(&device).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device registered", "data": device})
+}
+
+// UnregisterDevice removes a registration token of the current user, e.g. on logout
+// DELETE /api/v1/notifications/devices {"token": "..."}
+func (nc *NotificationController) UnregisterDevice(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := nc.db.Where("user_id = ? AND token = ?", userID, request.Token).Delete(&models.DeviceToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unregister device"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device unregistered"})
+}
+
+// GetPreferences returns the current user's push settings
+// GET /api/v1/notifications/preferences
+func (nc *NotificationController) GetPreferences(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	pref, err := nc.loadPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+
+	var devices int64
+	nc.db.Model(&models.DeviceToken{}).Where("user_id = ?", userID).Count(&devices)
+
+	c.JSON(http.StatusOK, gin.H{"data": pref, "devices": devices})
+}
+
+// UpdatePreferences updates the current user's push settings; quiet hours are HH:MM in the
+// given IANA timezone (market time when empty), and empty start and end turn them off
+// PUT /api/v1/notifications/preferences {"push_enabled": true, "quiet_hours_start": "22:00", "quiet_hours_end": "07:00", "timezone": "Asia/Ho_Chi_Minh"}
+func (nc *NotificationController) UpdatePreferences(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		PushEnabled     *bool   `json:"push_enabled"`
+		QuietHoursStart *string `json:"quiet_hours_start"`
+		QuietHoursEnd   *string `json:"quiet_hours_end"`
+		Timezone        *string `json:"timezone"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pref, err := nc.loadPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+	if request.PushEnabled != nil {
+		pref.PushEnabled = *request.PushEnabled
+	}
+	if request.QuietHoursStart != nil {
+		pref.QuietHoursStart = *request.QuietHoursStart
+	}
+	if request.QuietHoursEnd != nil {
+		pref.QuietHoursEnd = *request.QuietHoursEnd
+	}
+	if request.Timezone != nil {
+		pref.Timezone = *request.Timezone
+	}
+
+	if (pref.QuietHoursStart == "") != (pref.QuietHoursEnd == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quiet_hours_start and quiet_hours_end must be set together"})
+		return
+	}
+	if pref.QuietHoursStart != "" && (!services.ValidClock(pref.QuietHoursStart) || !services.ValidClock(pref.QuietHoursEnd)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quiet hours must be HH:MM"})
+		return
+	}
+	if pref.Timezone != "" {
+		if _, err := time.LoadLocation(pref.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone " + pref.Timezone})
+			return
+		}
+	}
+
+	if err := nc.db.Save(pref).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pref})
+}
+
+// loadPreferences returns the user's push settings, or the defaults when they have none yet
+func (nc *NotificationController) loadPreferences(userID string) (*models.NotificationPreference, error) {
+	pref := &models.NotificationPreference{UserID: userID, PushEnabled: true}
+	err := nc.db.Where("user_id = ?", userID).First(pref).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return pref, nil
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"math"
 	"net/http"
 	"sort"
@@ -8,12 +9,18 @@ import (
 	"strings"
 	"time"
 
+	"go_backend_project/middleware"
+	"go_backend_project/models"
 	"go_backend_project/services"
 	"go_backend_project/services/signals"
+	"go_backend_project/services/units"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxIndicatorPageSize lets clients load the indicators of the whole market in one page
+const maxIndicatorPageSize = 2000
+
 // PublicSignalController handles optimized public signal API endpoints
 type PublicSignalController struct{}
 
@@ -24,19 +31,22 @@ func NewPublicSignalController() *PublicSignalController {
 
 // SignalResponse represents a standardized signal API response
 type SignalResponse struct {
-	Success   bool        `json:"success"`
-	Data      interface{} `json:"data"`
-	Meta      *MetaInfo   `json:"meta,omitempty"`
-	Error     string      `json:"error,omitempty"`
-	Timestamp string      `json:"timestamp"`
+	Success     bool                    `json:"success"`
+	Data        interface{}             `json:"data"`
+	Meta        *MetaInfo               `json:"meta,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	Freshness   *services.DataFreshness `json:"freshness,omitempty"`   // age of the indicator data
+	Experiments map[string]string       `json:"experiments,omitempty"` // caller's variants by experiment key
+	Timestamp   string                  `json:"timestamp"`
 }
 
 // MetaInfo contains pagination and metadata
 type MetaInfo struct {
 	Total       int    `json:"total"`
-	Page        int    `json:"page"`
+	Page        int    `json:"page"` // 0 when the page was requested by cursor
 	PageSize    int    `json:"page_size"`
 	TotalPages  int    `json:"total_pages"`
+	NextCursor  string `json:"next_cursor,omitempty"` // pass as ?cursor= for the next page
 	Strategy    string `json:"strategy,omitempty"`
 	UpdatedAt   string `json:"updated_at,omitempty"`
 	CacheExpiry string `json:"cache_expiry,omitempty"`
@@ -48,10 +58,12 @@ type StockSignalSummary struct {
 	SignalType  string   `json:"signal_type"`
 	Strength    int      `json:"strength"`
 	Confidence  float64  `json:"confidence"`
-	Price       float64  `json:"price"`
+	Price       float64  `json:"price" unit:"price"`
 	PriceChange float64  `json:"price_change"`
-	TargetPrice float64  `json:"target_price"`
-	StopLoss    float64  `json:"stop_loss"`
+	TargetPrice float64  `json:"target_price" unit:"price"`
+	StopLoss    float64  `json:"stop_loss" unit:"price"`
+	TickSize    float64  `json:"tick_size" unit:"price"`
+	OnTick      bool     `json:"on_tick"`
 	RSAvg       float64  `json:"rs_avg"`
 	RSI         float64  `json:"rsi"`
 	MACD        float64  `json:"macd"`
@@ -74,13 +86,18 @@ type SignalStats struct {
 
 // RegisterPublicSignalRoutes registers optimized public signal routes
 func (ctrl *PublicSignalController) RegisterPublicSignalRoutes(api *gin.RouterGroup) {
-	signalRoutes := api.Group("/signals")
+	signalRoutes := api.Group("/signals", DataFreshnessGuard(), LiveSignalsGuard(), UnitsOption())
 	{
 		// Core signal endpoints
 		signalRoutes.GET("", ctrl.GetSignals)
 		signalRoutes.GET("/stock/:code", ctrl.GetStockSignal)
+		signalRoutes.GET("/stock/:code/consensus", ctrl.GetStockConsensus)
+		signalRoutes.POST("/batch", ctrl.GetBatchSignals)
 		signalRoutes.GET("/top", ctrl.GetTopSignals)
 		signalRoutes.GET("/stats", ctrl.GetSignalStats)
+		signalRoutes.GET("/leaderboard", ctrl.GetLeaderboard)
+		signalRoutes.GET("/history", ctrl.GetSignalHistory)
+		signalRoutes.POST("/rank", ctrl.RankByCompositeScore)
 
 		// Strategy endpoints
 		signalRoutes.GET("/strategies", ctrl.GetStrategies)
@@ -92,6 +109,10 @@ func (ctrl *PublicSignalController) RegisterPublicSignalRoutes(api *gin.RouterGr
 		signalRoutes.GET("/screener/momentum", ctrl.GetMomentumStocks)
 		signalRoutes.GET("/screener/oversold", ctrl.GetOversoldStocks)
 		signalRoutes.GET("/screener/breakout", ctrl.GetBreakoutStocks)
+		signalRoutes.GET("/screener/etf", ctrl.GetETFScreener)
+		signalRoutes.GET("/screener/52w", ctrl.GetFiftyTwoWeekScreener)
+		signalRoutes.GET("/screener/gaps", ctrl.GetGapScreener)
+		signalRoutes.GET("/screener/accumulation", ctrl.GetAccumulationScreener)
 
 		// Indicator-based endpoints
 		signalRoutes.GET("/indicators/:code", ctrl.GetStockIndicators)
@@ -99,36 +120,37 @@ func (ctrl *PublicSignalController) RegisterPublicSignalRoutes(api *gin.RouterGr
 	}
 }
 
-// GetSignals returns paginated signals with filtering
-// GET /api/v1/signals?page=1&page_size=20&strategy=composite&signal_type=BUY&min_strength=60
+// GetSignals returns paginated signals with filtering. Pass next_cursor from the meta as cursor
+// for stable paging while signals change.
+// GET /api/v1/signals?page=1&page_size=20&cursor=&strategy=composite&signal_type=BUY&min_strength=60&type=stock&units=raw
 func (ctrl *PublicSignalController) GetSignals(c *gin.Context) {
 	if signals.GlobalSignalService == nil {
 		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Signal service not available")
 		return
 	}
 
-	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-
 	// Parse filters
 	strategy := c.DefaultQuery("strategy", "composite")
+	pageReq, ok := ctrl.pageRequest(c, "strength:"+strategy, services.DefaultPageSize, services.MaxPageSize)
+	if !ok {
+		return
+	}
 	signalType := c.Query("signal_type")
 	minStrength, _ := strconv.Atoi(c.DefaultQuery("min_strength", "0"))
 	minConfidence, _ := strconv.ParseFloat(c.DefaultQuery("min_confidence", "0"), 64)
 	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "1"), 64)
 
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
+
 	// Build filter
 	filter := &signals.SignalFilter{
-		MinStrength:   minStrength,
-		MinConfidence: minConfidence,
-		MinTradingVal: minTradingVal,
+		MinStrength:     minStrength,
+		MinConfidence:   minConfidence,
+		MinTradingVal:   minTradingVal,
+		InstrumentTypes: types,
 	}
 
 	if signalType != "" {
@@ -136,9 +158,12 @@ func (ctrl *PublicSignalController) GetSignals(c *gin.Context) {
 	}
 
 	// Generate all signals
-	allSignals, err := signals.GlobalSignalService.GenerateAllSignals(strategy, filter)
+	allSignals, err := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), strategy, filter)
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
@@ -149,33 +174,25 @@ func (ctrl *PublicSignalController) GetSignals(c *gin.Context) {
 		filtered = append(filtered, summary)
 	}
 
-	// Sort by strength descending
+	// Sort by strength descending, then code so pages and cursors are stable
 	sort.Slice(filtered, func(i, j int) bool {
-		return filtered[i].Strength > filtered[j].Strength
+		if filtered[i].Strength != filtered[j].Strength {
+			return filtered[i].Strength > filtered[j].Strength
+		}
+		return filtered[i].Code < filtered[j].Code
 	})
 
-	// Paginate
-	total := len(filtered)
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	paginated := filtered[start:end]
+	paginated, page := services.PaginateSlice(filtered, pageReq,
+		func(s StockSignalSummary, cur *services.PageCursor) bool {
+			v := int(cur.Float())
+			return s.Strength < v || s.Strength == v && s.Code > cur.ID
+		},
+		func(s StockSignalSummary) (string, string) { return strconv.Itoa(s.Strength), s.Code })
 
-	ctrl.successResponse(c, paginated, &MetaInfo{
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-		Strategy:   strategy,
-		UpdatedAt:  time.Now().Format(time.RFC3339),
-	})
+	meta := pageMeta(page)
+	meta.Strategy = strategy
+	meta.UpdatedAt = time.Now().Format(time.RFC3339)
+	ctrl.successResponse(c, paginated, meta)
 }
 
 // GetStockSignal returns signal for a specific stock
@@ -212,6 +229,78 @@ func (ctrl *PublicSignalController) GetStockSignal(c *gin.Context) {
 	ctrl.successResponse(c, response, nil)
 }
 
+// GetStockConsensus returns the verdict of every strategy and active rule for a stock
+// GET /api/v1/signals/stock/VNM/consensus
+func (ctrl *PublicSignalController) GetStockConsensus(c *gin.Context) {
+	if signals.GlobalSignalService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Signal service not available")
+		return
+	}
+
+	code := strings.ToUpper(c.Param("code"))
+	consensus, err := signals.BuildConsensus(code)
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusNotFound, "Stock not found: "+code)
+		return
+	}
+
+	ctrl.successResponse(c, consensus, nil)
+}
+
+// GetBatchSignals returns signals with key indicators for up to 100 codes in one request
+// POST /api/v1/signals/batch {"codes": ["VNM", "FPT"], "strategy": "composite"}
+func (ctrl *PublicSignalController) GetBatchSignals(c *gin.Context) {
+	if signals.GlobalSignalService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Signal service not available")
+		return
+	}
+
+	var req struct {
+		Codes    []string `json:"codes" binding:"required"`
+		Strategy string   `json:"strategy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ctrl.errorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+	if req.Strategy == "" {
+		req.Strategy = "composite"
+	}
+
+	// Normalize and de-duplicate codes, keeping request order
+	codes := make([]string, 0, len(req.Codes))
+	seen := make(map[string]bool, len(req.Codes))
+	for _, code := range req.Codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		ctrl.errorResponse(c, http.StatusBadRequest, "codes must not be empty")
+		return
+	}
+	if len(codes) > signals.MaxBatchSignalCodes {
+		ctrl.errorResponse(c, http.StatusBadRequest, "At most "+strconv.Itoa(signals.MaxBatchSignalCodes)+" codes allowed")
+		return
+	}
+
+	results, err := signals.GlobalSignalService.GenerateBatchSignals(codes, req.Strategy)
+	if err != nil {
+		if !ctrl.notReadyResponse(c, err) {
+			ctrl.errorResponse(c, http.StatusInternalServerError, "Failed to generate signals: "+err.Error())
+		}
+		return
+	}
+
+	ctrl.successResponse(c, results, &MetaInfo{
+		Total:    len(results),
+		Strategy: req.Strategy,
+	})
+}
+
 // GetTopSignals returns top buy and sell signals
 // GET /api/v1/signals/top?limit=10
 func (ctrl *PublicSignalController) GetTopSignals(c *gin.Context) {
@@ -225,22 +314,31 @@ func (ctrl *PublicSignalController) GetTopSignals(c *gin.Context) {
 		limit = 10
 	}
 	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "5"), 64)
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	// Get buy signals
-	buySignals, _ := signals.GlobalSignalService.GetBuySignals(50, limit*2)
+	buySignals, err := signals.GlobalSignalService.GetBuySignals(50, limit*2)
+	if ctrl.notReadyResponse(c, err) {
+		return
+	}
 	// Get sell signals
 	sellSignals, _ := signals.GlobalSignalService.GetSellSignals(50, limit*2)
 
 	var topBuy, topSell []StockSignalSummary
 
 	for _, sig := range buySignals {
-		if sig.Indicators != nil && sig.Indicators.AvgTradingVal >= minTradingVal {
+		if sig.Indicators != nil && sig.Indicators.AvgTradingVal >= minTradingVal &&
+			services.MatchesInstrumentType(sig.Code, "", types) {
 			topBuy = append(topBuy, ctrl.convertToSummary(sig))
 		}
 	}
 
 	for _, sig := range sellSignals {
-		if sig.Indicators != nil && sig.Indicators.AvgTradingVal >= minTradingVal {
+		if sig.Indicators != nil && sig.Indicators.AvgTradingVal >= minTradingVal &&
+			services.MatchesInstrumentType(sig.Code, "", types) {
 			topSell = append(topSell, ctrl.convertToSummary(sig))
 		}
 	}
@@ -270,9 +368,12 @@ func (ctrl *PublicSignalController) GetSignalStats(c *gin.Context) {
 		return
 	}
 
-	allSignals, err := signals.GlobalSignalService.GenerateAllSignals("composite", nil)
+	allSignals, err := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), "composite", nil)
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
@@ -313,17 +414,25 @@ func (ctrl *PublicSignalController) GetStrategies(c *gin.Context) {
 		strategyList = signals.GlobalSignalService.GetStrategies()
 	}
 
+	// Active composite weights follow the current market regime
+	regime := services.GlobalMarketRegime.CurrentName()
+	weights := signals.CompositeWeights(regime)
+	weightConfig := signals.GetCompositeWeightConfig()
+
 	strategies := []gin.H{
 		{
 			"name":        "composite",
 			"description": "Combines all strategies with weighted scoring",
 			"weights": gin.H{
-				"momentum":        0.30,
-				"trend_following": 0.35,
-				"mean_reversion":  0.15,
-				"breakout":        0.20,
+				"momentum":        weights.Momentum,
+				"trend_following": weights.Trend,
+				"mean_reversion":  weights.MeanReversion,
+				"breakout":        weights.Breakout,
 			},
-			"recommended": true,
+			"market_regime":   regime,
+			"default_weights": weightConfig.Default,
+			"regime_weights":  weightConfig.Regimes,
+			"recommended":     true,
 		},
 		{
 			"name":        "momentum",
@@ -347,6 +456,21 @@ func (ctrl *PublicSignalController) GetStrategies(c *gin.Context) {
 		},
 	}
 
+	// User-defined expression strategies registered through the admin API
+	for _, name := range strategyList {
+		strategy, _ := signals.GlobalSignalService.GetStrategy(name)
+		if custom, ok := strategy.(*signals.ExpressionStrategy); ok {
+			def := custom.Definition()
+			strategies = append(strategies, gin.H{
+				"name":              name,
+				"description":       custom.Description(),
+				"score_expression":  def.ScoreExpression,
+				"filter_expression": def.FilterExpression,
+				"custom":            true,
+			})
+		}
+	}
+
 	ctrl.successResponse(c, gin.H{
 		"strategies": strategies,
 		"available":  strategyList,
@@ -371,8 +495,11 @@ func (ctrl *PublicSignalController) GetStrategySignals(c *gin.Context) {
 		Limit: limit,
 	}
 
-	allSignals, err := signals.GlobalSignalService.GenerateAllSignals(strategyName, filter)
+	allSignals, err := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), strategyName, filter)
 	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
 		ctrl.errorResponse(c, http.StatusBadRequest, "Invalid strategy: "+strategyName)
 		return
 	}
@@ -410,15 +537,22 @@ func (ctrl *PublicSignalController) GetMomentumStocks(c *gin.Context) {
 
 	minRS, _ := strconv.ParseFloat(c.DefaultQuery("min_rs", "80"), 64)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
 	var results []gin.H
 	for code, ind := range summary.Stocks {
+		if !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
 		if ind.RSAvg >= minRS {
 			results = append(results, gin.H{
 				"code":         code,
@@ -427,7 +561,7 @@ func (ctrl *PublicSignalController) GetMomentumStocks(c *gin.Context) {
 				"rs_1m":        ind.RS1MRank,
 				"rs_3m":        ind.RS3MRank,
 				"rs_1y":        ind.RS1YRank,
-				"price":        ind.CurrentPrice,
+				"price":        units.Price(ind.CurrentPrice),
 				"price_change": ind.PriceChange,
 				"volume_ratio": ind.VolRatio,
 			})
@@ -459,21 +593,28 @@ func (ctrl *PublicSignalController) GetOversoldStocks(c *gin.Context) {
 
 	maxRSI, _ := strconv.ParseFloat(c.DefaultQuery("max_rsi", "30"), 64)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
 	var results []gin.H
 	for code, ind := range summary.Stocks {
+		if !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
 		if ind.RSI <= maxRSI && ind.RSI > 0 {
 			results = append(results, gin.H{
 				"code":          code,
 				"rsi":           ind.RSI,
-				"price":         ind.CurrentPrice,
-				"ma50":          ind.MA50,
+				"price":         units.Price(ind.CurrentPrice),
+				"ma50":          units.Price(ind.MA50),
 				"price_vs_ma50": (ind.CurrentPrice - ind.MA50) / ind.MA50 * 100,
 				"rs_avg":        ind.RSAvg,
 			})
@@ -505,21 +646,28 @@ func (ctrl *PublicSignalController) GetBreakoutStocks(c *gin.Context) {
 
 	minVolRatio, _ := strconv.ParseFloat(c.DefaultQuery("min_vol_ratio", "2"), 64)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
 	var results []gin.H
 	for code, ind := range summary.Stocks {
+		if !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
 		if ind.VolRatio >= minVolRatio && ind.RS3DRank >= 70 {
 			results = append(results, gin.H{
 				"code":         code,
 				"vol_ratio":    ind.VolRatio,
 				"rs_3d":        ind.RS3DRank,
-				"price":        ind.CurrentPrice,
+				"price":        units.Price(ind.CurrentPrice),
 				"price_change": ind.PriceChange,
 				"macd_hist":    ind.MACDHist,
 				"above_ma10":   ind.CurrentPrice > ind.MA10,
@@ -543,7 +691,396 @@ func (ctrl *PublicSignalController) GetBreakoutStocks(c *gin.Context) {
 	})
 }
 
-// GetStockIndicators returns all indicators for a stock
+// GetFiftyTwoWeekScreener returns stocks closing within a few percent of, or breaking, their
+// 52-week high (side=high) or low (side=low) on elevated volume
+// GET /api/v1/signals/screener/52w?side=high&within=2&min_vol_ratio=1.5&min_trading_val=1&limit=20
+func (ctrl *PublicSignalController) GetFiftyTwoWeekScreener(c *gin.Context) {
+	if services.GlobalIndicatorService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
+		return
+	}
+
+	side := c.DefaultQuery("side", "high")
+	if side != "high" && side != "low" {
+		ctrl.errorResponse(c, http.StatusBadRequest, "side must be high or low")
+		return
+	}
+	within, _ := strconv.ParseFloat(c.DefaultQuery("within", "2"), 64)
+	minVolRatio, _ := strconv.ParseFloat(c.DefaultQuery("min_vol_ratio", "1.5"), 64)
+	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "1"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		ctrl.summaryErrorResponse(c, err)
+		return
+	}
+
+	var results []gin.H
+	for code, ind := range summary.Stocks {
+		if ind == nil || ind.High52W == 0 || !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
+		if ind.VolRatio < minVolRatio || ind.AvgTradingVal < minTradingVal {
+			continue
+		}
+
+		distance, breakout := -ind.PctFrom52WHigh, ind.New52WHigh
+		if side == "low" {
+			distance, breakout = ind.PctFrom52WLow, ind.New52WLow
+		}
+		if !breakout && distance > within {
+			continue
+		}
+
+		results = append(results, gin.H{
+			"code":              code,
+			"price":             units.Price(ind.CurrentPrice),
+			"price_change":      ind.PriceChange,
+			"high_52w":          units.Price(ind.High52W),
+			"low_52w":           units.Price(ind.Low52W),
+			"pct_from_52w_high": ind.PctFrom52WHigh,
+			"pct_from_52w_low":  ind.PctFrom52WLow,
+			"breakout":          breakout,
+			"distance":          distance,
+			"vol_ratio":         ind.VolRatio,
+			"rs_avg":            ind.RSAvg,
+			"avg_trading_val":   units.Value(ind.AvgTradingVal),
+		})
+	}
+
+	// Breakouts first, then closest to the extreme, then highest volume ratio
+	sort.Slice(results, func(i, j int) bool {
+		bi, bj := results[i]["breakout"].(bool), results[j]["breakout"].(bool)
+		if bi != bj {
+			return bi
+		}
+		di, dj := results[i]["distance"].(float64), results[j]["distance"].(float64)
+		if di != dj {
+			return di < dj
+		}
+		return results[i]["vol_ratio"].(float64) > results[j]["vol_ratio"].(float64)
+	})
+
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	ctrl.successResponse(c, results, &MetaInfo{
+		Total:     total,
+		UpdatedAt: summary.UpdatedAt,
+	})
+}
+
+// GetGapScreener returns stocks whose open gapped at least min_gap % away from the previous close.
+// While realtime polling runs, today's open and reference price come from the realtime feed;
+// otherwise the gap of the latest daily bar is used.
+// GET /api/v1/signals/screener/gaps?direction=up|down|both&min_gap=2&min_vol_ratio=0&min_trading_val=1&limit=20
+func (ctrl *PublicSignalController) GetGapScreener(c *gin.Context) {
+	if services.GlobalIndicatorService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
+		return
+	}
+
+	direction := c.DefaultQuery("direction", "both")
+	if direction != "up" && direction != "down" && direction != "both" {
+		ctrl.errorResponse(c, http.StatusBadRequest, "direction must be up, down or both")
+		return
+	}
+	minGap, _ := strconv.ParseFloat(c.DefaultQuery("min_gap", "2"), 64)
+	minVolRatio, _ := strconv.ParseFloat(c.DefaultQuery("min_vol_ratio", "0"), 64)
+	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "1"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		ctrl.summaryErrorResponse(c, err)
+		return
+	}
+	realtime := services.GlobalRealtimeService != nil && services.GlobalRealtimeService.IsPolling()
+
+	var results []gin.H
+	for code, ind := range summary.Stocks {
+		if ind == nil || ind.AvgTradingVal < minTradingVal || !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
+
+		gap, volRatio, price, source := ind.GapPercent, ind.VolRatio, ind.CurrentPrice, "daily"
+		if realtime {
+			if p := services.GlobalRealtimeService.LatestPrice(code); p != nil && p.Open > 0 && p.RefPrice > 0 {
+				gap = math.Round((p.Open-p.RefPrice)/p.RefPrice*10000) / 100
+				price, source = p.Price, "realtime"
+				volRatio = 0
+				if ind.AvgVol > 0 {
+					volRatio = math.Round(p.Volume/ind.AvgVol*100) / 100
+				}
+			}
+		}
+
+		switch {
+		case direction == "up" && gap < minGap,
+			direction == "down" && gap > -minGap,
+			direction == "both" && math.Abs(gap) < minGap:
+			continue
+		}
+		if volRatio < minVolRatio {
+			continue
+		}
+
+		gapDirection := "up"
+		if gap < 0 {
+			gapDirection = "down"
+		}
+		results = append(results, gin.H{
+			"code":            code,
+			"gap_percent":     gap,
+			"direction":       gapDirection,
+			"price":           units.Price(price),
+			"price_change":    ind.PriceChange,
+			"vol_ratio":       volRatio,
+			"avg_trading_val": units.Value(ind.AvgTradingVal),
+			"rs_avg":          ind.RSAvg,
+			"source":          source,
+		})
+	}
+
+	// Largest gaps first
+	sort.Slice(results, func(i, j int) bool {
+		return math.Abs(results[i]["gap_percent"].(float64)) > math.Abs(results[j]["gap_percent"].(float64))
+	})
+
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	ctrl.successResponse(c, results, &MetaInfo{
+		Total:     total,
+		UpdatedAt: summary.UpdatedAt,
+	})
+}
+
+// GetAccumulationScreener returns stocks under institutional accumulation: a rising A/D line
+// confirmed by OBV, with strong relative strength
+// GET /api/v1/signals/screener/accumulation?min_ad_trend=20&min_rs=70&min_trading_val=1&limit=20
+func (ctrl *PublicSignalController) GetAccumulationScreener(c *gin.Context) {
+	if services.GlobalIndicatorService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
+		return
+	}
+
+	minADTrend, _ := strconv.ParseFloat(c.DefaultQuery("min_ad_trend", "20"), 64)
+	minRS, _ := strconv.ParseFloat(c.DefaultQuery("min_rs", "70"), 64)
+	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "1"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		ctrl.summaryErrorResponse(c, err)
+		return
+	}
+
+	var results []gin.H
+	for code, ind := range summary.Stocks {
+		if ind == nil || !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
+		if ind.ADTrend < minADTrend || ind.OBVTrend <= 0 || ind.RSAvg < minRS || ind.AvgTradingVal < minTradingVal {
+			continue
+		}
+		results = append(results, gin.H{
+			"code":            code,
+			"ad_trend":        ind.ADTrend,
+			"obv_trend":       ind.OBVTrend,
+			"rs_avg":          ind.RSAvg,
+			"rs_1m":           ind.RS1MRank,
+			"price":           units.Price(ind.CurrentPrice),
+			"price_change":    ind.PriceChange,
+			"vol_ratio":       ind.VolRatio,
+			"avg_trading_val": units.Value(ind.AvgTradingVal),
+			"above_ma50":      ind.CurrentPrice > ind.MA50,
+			"score":           math.Round((ind.ADTrend+ind.RSAvg)/2*100) / 100,
+		})
+	}
+
+	// Strongest combination of accumulation and relative strength first
+	sort.Slice(results, func(i, j int) bool {
+		return results[i]["score"].(float64) > results[j]["score"].(float64)
+	})
+
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	ctrl.successResponse(c, results, &MetaInfo{
+		Total:     total,
+		UpdatedAt: summary.UpdatedAt,
+	})
+}
+
+// RankByCompositeScore ranks the universe by a user-defined composite score: weighted indicator
+// fields normalized across the universe (rank percentile by default, or zscore / none), or
+// a raw score expression, optionally restricted by a filter expression
+// POST /api/v1/signals/rank?type=stock&min_trading_val=1&limit=50 {"terms": [{"field": "rs_avg", "weight": 0.6}, {"field": "vol_ratio", "weight": 0.4}], "filter": "price > ma50"}
+func (ctrl *PublicSignalController) RankByCompositeScore(c *gin.Context) {
+	if services.GlobalIndicatorService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
+		return
+	}
+
+	var spec signals.CompositeScoreSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		ctrl.errorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+	score, err := signals.NewCompositeScore(spec)
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "0"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > services.MaxPageSize {
+		limit = 50
+	}
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		ctrl.summaryErrorResponse(c, err)
+		return
+	}
+
+	universe := make(map[string]*services.ExtendedStockIndicators, len(summary.Stocks))
+	for code, ind := range summary.Stocks {
+		if ind == nil || !services.MatchesInstrumentType(code, ind.Type, types) || ind.AvgTradingVal < minTradingVal {
+			continue
+		}
+		universe[code] = ind
+	}
+
+	results := score.Rank(universe)
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	ctrl.successResponse(c, gin.H{
+		"score":   score.Spec(),
+		"results": results,
+	}, &MetaInfo{
+		Total:     total,
+		UpdatedAt: summary.UpdatedAt,
+	})
+}
+
+// GetETFScreener returns ETFs with their trend, momentum and composite signal
+// GET /api/v1/signals/screener/etf?sort_by=rs_avg&min_trading_val=0&limit=20
+func (ctrl *PublicSignalController) GetETFScreener(c *gin.Context) {
+	if services.GlobalIndicatorService == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort_by", "rs_avg")
+	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "0"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		ctrl.summaryErrorResponse(c, err)
+		return
+	}
+
+	strategy := signals.Strategy(&signals.CompositeStrategy{})
+	if signals.GlobalSignalService != nil {
+		if s, ok := signals.GlobalSignalService.GetStrategy("composite"); ok {
+			strategy = s
+		}
+	}
+
+	etfTypes := []string{services.InstrumentTypeETF}
+	var results []gin.H
+	for code, ind := range summary.Stocks {
+		if ind == nil || !services.MatchesInstrumentType(code, ind.Type, etfTypes) || ind.AvgTradingVal < minTradingVal {
+			continue
+		}
+		result := gin.H{
+			"code":            code,
+			"price":           units.Price(ind.CurrentPrice),
+			"price_change":    ind.PriceChange,
+			"avg_trading_val": units.Value(ind.AvgTradingVal),
+			"rs_avg":          ind.RSAvg,
+			"rs_1m":           ind.RS1MRank,
+			"rs_3m":           ind.RS3MRank,
+			"rsi":             ind.RSI,
+			"macd_hist":       ind.MACDHist,
+			"above_ma50":      ind.MA50 > 0 && ind.CurrentPrice > ind.MA50,
+			"above_ma200":     ind.MA200 > 0 && ind.CurrentPrice > ind.MA200,
+		}
+		if sig, err := strategy.Evaluate(ind); err == nil {
+			result["signal"] = sig.Signal
+			result["strength"] = sig.Strength
+		}
+		results = append(results, result)
+	}
+
+	sortKey := map[string]string{
+		"rs_avg":       "rs_avg",
+		"trading_val":  "avg_trading_val",
+		"price_change": "price_change",
+		"rsi":          "rsi",
+	}[sortBy]
+	if sortKey == "" {
+		sortKey = "rs_avg"
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i][sortKey].(float64) > results[j][sortKey].(float64)
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	ctrl.successResponse(c, results, &MetaInfo{
+		Total:     len(results),
+		Strategy:  strategy.Name(),
+		UpdatedAt: summary.UpdatedAt,
+	})
+}
+
+// GetStockIndicators returns all indicators for a stock with its RS ranks. Stocks listed after the
+// last full calculation are computed on demand, with rs_rank_estimated set on their ranks.
 // GET /api/v1/signals/indicators/VNM
 func (ctrl *PublicSignalController) GetStockIndicators(c *gin.Context) {
 	if services.GlobalIndicatorService == nil {
@@ -553,7 +1090,7 @@ func (ctrl *PublicSignalController) GetStockIndicators(c *gin.Context) {
 
 	code := strings.ToUpper(c.Param("code"))
 
-	ind, err := services.GlobalIndicatorService.GetStockIndicators(code)
+	ind, err := services.GlobalIndicatorService.GetRankedStockIndicators(code)
 	if err != nil {
 		ctrl.errorResponse(c, http.StatusNotFound, "Stock not found: "+code)
 		return
@@ -562,21 +1099,27 @@ func (ctrl *PublicSignalController) GetStockIndicators(c *gin.Context) {
 	ctrl.successResponse(c, ind, nil)
 }
 
-// GetAllIndicators returns paginated indicators for all stocks
-// GET /api/v1/signals/indicators?page=1&page_size=50&sort_by=rs_avg
+// GetAllIndicators returns paginated indicators for all stocks, by page or by cursor
+// GET /api/v1/signals/indicators?page=1&page_size=50&cursor=&sort_by=rs_avg&units=raw
 func (ctrl *PublicSignalController) GetAllIndicators(c *gin.Context) {
 	if services.GlobalIndicatorService == nil {
 		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Indicator service not available")
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
 	sortBy := c.DefaultQuery("sort_by", "rs_avg")
+	pageReq, ok := ctrl.pageRequest(c, sortBy, 50, maxIndicatorPageSize)
+	if !ok {
+		return
+	}
+	types, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
 	if err != nil {
-		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+		ctrl.summaryErrorResponse(c, err)
 		return
 	}
 
@@ -588,46 +1131,46 @@ func (ctrl *PublicSignalController) GetAllIndicators(c *gin.Context) {
 
 	var results []stockInd
 	for code, ind := range summary.Stocks {
+		if ind == nil || !services.MatchesInstrumentType(code, ind.Type, types) {
+			continue
+		}
 		results = append(results, stockInd{Code: code, Indicators: ind})
 	}
 
-	// Sort
-	sort.Slice(results, func(i, j int) bool {
+	// Sort descending by the chosen indicator, then by code so pages and cursors are stable
+	sortValue := func(ind *services.ExtendedStockIndicators) float64 {
 		switch sortBy {
-		case "rs_avg":
-			return results[i].Indicators.RSAvg > results[j].Indicators.RSAvg
 		case "rs_1y":
-			return results[i].Indicators.RS1YRank > results[j].Indicators.RS1YRank
+			return ind.RS1YRank
 		case "rsi":
-			return results[i].Indicators.RSI > results[j].Indicators.RSI
+			return ind.RSI
 		case "price":
-			return results[i].Indicators.CurrentPrice > results[j].Indicators.CurrentPrice
+			return ind.CurrentPrice
 		case "volume":
-			return results[i].Indicators.AvgVol > results[j].Indicators.AvgVol
+			return ind.AvgVol
 		default:
-			return results[i].Indicators.RSAvg > results[j].Indicators.RSAvg
+			return ind.RSAvg
 		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if a, b := sortValue(results[i].Indicators), sortValue(results[j].Indicators); a != b {
+			return a > b
+		}
+		return results[i].Code < results[j].Code
 	})
 
-	// Paginate
-	total := len(results)
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-	start := (page - 1) * pageSize
-	end := start + pageSize
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	ctrl.successResponse(c, results[start:end], &MetaInfo{
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-		UpdatedAt:  summary.UpdatedAt,
-	})
+	paginated, page := services.PaginateSlice(results, pageReq,
+		func(r stockInd, cur *services.PageCursor) bool {
+			v, key := sortValue(r.Indicators), cur.Float()
+			return v < key || v == key && r.Code > cur.ID
+		},
+		func(r stockInd) (string, string) {
+			return services.FormatCursorFloat(sortValue(r.Indicators)), r.Code
+		})
+
+	meta := pageMeta(page)
+	meta.UpdatedAt = summary.UpdatedAt
+	ctrl.successResponse(c, paginated, meta)
 }
 
 // Helper methods
@@ -641,15 +1184,23 @@ func (ctrl *PublicSignalController) getFilteredSignals(c *gin.Context, types []s
 	minStrength, _ := strconv.Atoi(c.DefaultQuery("min_strength", "0"))
 	minTradingVal, _ := strconv.ParseFloat(c.DefaultQuery("min_trading_val", "1"), 64)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	instrumentTypes, ok := ctrl.instrumentTypes(c)
+	if !ok {
+		return
+	}
 
 	filter := &signals.SignalFilter{
-		MinStrength:   minStrength,
-		MinTradingVal: minTradingVal,
-		SignalTypes:   types,
-		Limit:         limit * 2, // Get more to filter
+		MinStrength:     minStrength,
+		MinTradingVal:   minTradingVal,
+		SignalTypes:     types,
+		Limit:           limit * 2, // Get more to filter
+		InstrumentTypes: instrumentTypes,
 	}
 
-	allSignals, _ := signals.GlobalSignalService.GenerateAllSignals("composite", filter)
+	allSignals, err := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), "composite", filter)
+	if middleware.AbortIfRequestDone(c) || ctrl.notReadyResponse(c, err) {
+		return
+	}
 
 	var results []StockSignalSummary
 	for _, sig := range allSignals {
@@ -674,6 +1225,16 @@ func (ctrl *PublicSignalController) getFilteredSignals(c *gin.Context, types []s
 	})
 }
 
+// instrumentTypes parses the ?type= filter (e.g. type=stock,etf), writing a 400 response when invalid
+func (ctrl *PublicSignalController) instrumentTypes(c *gin.Context) ([]string, bool) {
+	types, err := services.ParseInstrumentTypes(c.Query("type"))
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return types, true
+}
+
 func (ctrl *PublicSignalController) convertToSummary(sig *signals.TradingSignal) StockSignalSummary {
 	summary := StockSignalSummary{
 		Code:        sig.Code,
@@ -683,6 +1244,8 @@ func (ctrl *PublicSignalController) convertToSummary(sig *signals.TradingSignal)
 		Price:       sig.Price,
 		TargetPrice: sig.TargetPrice,
 		StopLoss:    sig.StopLoss,
+		TickSize:    sig.TickSize,
+		OnTick:      sig.OnTick,
 		Reasons:     sig.Reasons,
 		Strategy:    sig.Strategy,
 	}
@@ -697,12 +1260,109 @@ func (ctrl *PublicSignalController) convertToSummary(sig *signals.TradingSignal)
 	return summary
 }
 
+// GetLeaderboard returns rule/template performance over 1M/3M/6M windows
+// GET /api/v1/signals/leaderboard?type=rule|template&window=3M&min_signals=5
+func (ctrl *PublicSignalController) GetLeaderboard(c *gin.Context) {
+	if signals.GlobalConditionEvaluator == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Condition evaluator not available")
+		return
+	}
+
+	sourceType := c.Query("type")
+	if sourceType != "" && sourceType != "rule" && sourceType != "template" {
+		ctrl.errorResponse(c, http.StatusBadRequest, "type must be rule or template")
+		return
+	}
+
+	window := strings.ToUpper(c.DefaultQuery("window", "3M"))
+	validWindow := false
+	for _, w := range signals.LeaderboardWindows {
+		if w.Key == window {
+			validWindow = true
+			break
+		}
+	}
+	if !validWindow {
+		ctrl.errorResponse(c, http.StatusBadRequest, "window must be 1M, 3M or 6M")
+		return
+	}
+
+	minSignals, _ := strconv.Atoi(c.DefaultQuery("min_signals", "0"))
+
+	entries, err := signals.GlobalConditionEvaluator.GetPerformanceLeaderboard(sourceType, window, minSignals)
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusInternalServerError, "Failed to compute leaderboard")
+		return
+	}
+
+	ctrl.successResponse(c, entries, &MetaInfo{
+		Total:    len(entries),
+		Page:     1,
+		PageSize: len(entries),
+	})
+}
+
+// GetSignalHistory returns persisted rule signals with their lifecycle state, newest first
+// GET /api/v1/signals/history?state=active&code=VNM&page=1&page_size=20&cursor=
+func (ctrl *PublicSignalController) GetSignalHistory(c *gin.Context) {
+	if signals.GlobalConditionEvaluator == nil {
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Condition evaluator not available")
+		return
+	}
+
+	state := c.Query("state")
+	if state != "" && !signals.IsSignalState(state) {
+		ctrl.errorResponse(c, http.StatusBadRequest, "state must be one of "+strings.Join(models.SignalStates, ", "))
+		return
+	}
+
+	pageReq, ok := ctrl.pageRequest(c, signals.SignalHistorySort, services.DefaultPageSize, services.MaxPageSize)
+	if !ok {
+		return
+	}
+
+	history, page, err := signals.GlobalConditionEvaluator.ListSignalHistoryPage(signals.SignalHistoryFilter{
+		State:      state,
+		StockCode:  strings.ToUpper(c.Query("code")),
+		PublicOnly: true,
+	}, pageReq)
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusInternalServerError, "Failed to load signal history")
+		return
+	}
+
+	ctrl.successResponse(c, history, pageMeta(page))
+}
+
+// pageRequest parses page, page_size and cursor for an ordering, responding 400 on a bad cursor
+func (ctrl *PublicSignalController) pageRequest(c *gin.Context, sortName string, defaultSize, maxSize int) (services.PageRequest, bool) {
+	req, err := services.ParsePageRequest(c.Query("page"), c.Query("page_size"), c.Query("cursor"), sortName, defaultSize, maxSize)
+	if err != nil {
+		ctrl.errorResponse(c, http.StatusBadRequest, err.Error())
+		return req, false
+	}
+	return req, true
+}
+
+// pageMeta converts a page result into response metadata
+func pageMeta(page services.PageResult) *MetaInfo {
+	return &MetaInfo{
+		Total:      page.Total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages,
+		NextCursor: page.NextCursor,
+	}
+}
+
 func (ctrl *PublicSignalController) successResponse(c *gin.Context, data interface{}, meta *MetaInfo) {
 	c.JSON(http.StatusOK, SignalResponse{
-		Success:   true,
-		Data:      data,
-		Meta:      meta,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Success:     true,
+		Data:        withUnits(c, data),
+		Meta:        meta,
+		Freshness:   dataFreshness(c),
+		Experiments: middleware.ExperimentVariants(c),
+		Timestamp:   time.Now().Format(time.RFC3339),
 	})
 }
 
@@ -713,3 +1373,33 @@ func (ctrl *PublicSignalController) errorResponse(c *gin.Context, status int, me
 		Timestamp: time.Now().Format(time.RFC3339),
 	})
 }
+
+// summaryErrorResponse answers a failed signal or indicator lookup: 503 while the indicator
+// summary is missing or signal generation is switched off, 500 for anything else
+func (ctrl *PublicSignalController) summaryErrorResponse(c *gin.Context, err error) {
+	switch {
+	case ctrl.notReadyResponse(c, err):
+	case errors.Is(err, services.ErrSubsystemDisabled):
+		ctrl.errorResponse(c, http.StatusServiceUnavailable, "Signal generation is temporarily disabled")
+	default:
+		ctrl.errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// notReadyResponse writes a 503 with Retry-After and the state of the background recovery when
+// err is services.ErrIndicatorsNotReady, and reports whether it did
+func (ctrl *PublicSignalController) notReadyResponse(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrIndicatorsNotReady) || services.GlobalIndicatorService == nil {
+		return false
+	}
+	notReady := services.GlobalIndicatorService.RecoverSummary()
+	c.Header("Retry-After", strconv.Itoa(notReady.RetryAfterSeconds))
+	c.JSON(http.StatusServiceUnavailable, SignalResponse{
+		Success:   false,
+		Data:      notReady,
+		Error:     "Indicator data is not ready yet",
+		Freshness: dataFreshness(c),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return true
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RealtimeController serves the user-facing realtime price WebSocket
+type RealtimeController struct {
+	supabaseClient *services.SupabaseDBClient
+}
+
+// NewRealtimeController creates a new realtime controller; membership lookups
+// fall back to the free tier when Supabase is not configured
+func NewRealtimeController() *RealtimeController {
+	client, _ := services.NewSupabaseDBClient()
+	return &RealtimeController{supabaseClient: client}
+}
+
+// RegisterRealtimeRoutes registers realtime routes
+func (rc *RealtimeController) RegisterRealtimeRoutes(api *gin.RouterGroup) {
+	api.GET("/realtime/ws", rc.HandleWebSocket)
+}
+
+// HandleWebSocket handles GET /api/v1/realtime/ws?token= - upgrades to a WebSocket
+// whose subscription limit follows the user's membership tier
+func (rc *RealtimeController) HandleWebSocket(c *gin.Context) {
+	if services.GlobalRealtimeService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Realtime service not initialized"})
+		return
+	}
+
+	services.GlobalRealtimeService.HandleWebSocketForTier(c.Writer, c.Request, rc.resolveTier(c))
+}
+
+// resolveTier returns the active membership of the caller, identified by the
+// Authorization header or, for browsers, the token query parameter
+func (rc *RealtimeController) resolveTier(c *gin.Context) string {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		token := c.Query("token")
+		if token == "" {
+			return services.RealtimeTierFree
+		}
+		claims, err := middleware.ParseSupabaseToken(token)
+		if err != nil {
+			return services.RealtimeTierFree
+		}
+		userID = claims.Subject
+	}
+
+	return rc.supabaseClient.ActiveMembership(userID)
+}
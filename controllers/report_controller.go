@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportController serves the user's generated performance reports
+type ReportController struct {
+	db *gorm.DB
+}
+
+// NewReportController creates a new report controller
+func NewReportController(db *gorm.DB) *ReportController {
+	return &ReportController{db: db}
+}
+
+// RegisterReportRoutes registers report routes
+func (rc *ReportController) RegisterReportRoutes(api *gin.RouterGroup) {
+	reports := api.Group("/reports")
+	{
+		reports.GET("", rc.ListReports)
+		reports.GET("/:id", rc.GetReport)
+	}
+}
+
+// ListReports returns the current user's reports, newest first, without their content
+// GET /api/v1/reports?limit=20
+func (rc *ReportController) ListReports(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var reports []models.UserReport
+	if err := rc.db.Select("id, user_id, kind, period_start, period_end, has_pdf, created_at").
+		Where("user_id = ?", userID).Order("period_end DESC").Limit(limit).
+		Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": reports, "total": len(reports)})
+}
+
+// GetReport returns one of the current user's reports as an HTML page, or as a PDF download when
+// one was rendered
+// GET /api/v1/reports/:id?format=pdf
+func (rc *ReportController) GetReport(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var report models.UserReport
+	if err := rc.db.Where("id = ? AND user_id = ?", id, userID).First(&report).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+
+	if c.Query("format") == "pdf" {
+		if !report.HasPDF {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No PDF was rendered for this report"})
+			return
+		}
+		filename := fmt.Sprintf("report-%s.pdf", report.PeriodEnd.Format("2006-01-02"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, "application/pdf", report.PDF)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(report.HTML))
+}
@@ -3,7 +3,10 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
+	"go_backend_project/middleware"
+	"go_backend_project/services"
 	"go_backend_project/services/screener"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -23,15 +26,36 @@ func NewScreenerController(db *gorm.DB) *ScreenerController {
 	}
 }
 
-// Screen applies filters and returns matching stocks
+// Screen applies filters and returns matching stocks; a tag limits the screen to the stocks the
+// signed-in user tagged with it
 // POST /api/v1/screener/screen
 func (sc *ScreenerController) Screen(c *gin.Context) {
-	var filter screener.ScreenerFilter
+	var request struct {
+		screener.ScreenerFilter
+		Tag string `json:"tag"`
+	}
 
-	if err := c.ShouldBindJSON(&filter); err != nil {
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	filter := request.ScreenerFilter
+	if request.Tag != "" {
+		userID, err := middleware.GetSupabaseUserFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required to filter by tag"})
+			return
+		}
+		codes, err := services.StockCodesWithTag(sc.db, userID, request.Tag)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+			return
+		}
+		if codes == nil {
+			codes = []string{}
+		}
+		filter.Symbols = intersectSymbols(filter.Symbols, codes)
+	}
 
 	results, total, err := sc.screener.Screen(&filter)
 	if err != nil {
@@ -285,3 +309,52 @@ func (sc *ScreenerController) GetVolumeSpike(c *gin.Context) {
 		"total": total,
 	})
 }
+
+// GetPriceLimits returns today's stocks at their ceiling or floor, or approaching the ceiling,
+// from the realtime price store
+// GET /api/v1/screener/price-limits?status=hit_ceiling,hit_floor,approaching_ceiling
+func (sc *ScreenerController) GetPriceLimits(c *gin.Context) {
+	var statuses []string
+	for _, status := range strings.Split(c.Query("status"), ",") {
+		switch status = strings.TrimSpace(status); status {
+		case "":
+		case services.LimitHitCeiling, services.LimitHitFloor, services.LimitApproachingCeiling:
+			statuses = append(statuses, status)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be hit_ceiling, hit_floor or approaching_ceiling"})
+			return
+		}
+	}
+
+	stocks := services.PriceLimitStocks(statuses)
+	counts := map[string]int{services.LimitHitCeiling: 0, services.LimitHitFloor: 0, services.LimitApproachingCeiling: 0}
+	for _, stock := range stocks {
+		counts[stock.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       stocks,
+		"total":      len(stocks),
+		"counts":     counts,
+		"is_polling": services.GlobalRealtimeService != nil && services.GlobalRealtimeService.IsPolling(),
+	})
+}
+
+// intersectSymbols returns the tagged codes that are also in symbols, or all of them when no
+// symbols were requested
+func intersectSymbols(symbols, tagged []string) []string {
+	if symbols == nil {
+		return tagged
+	}
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[strings.ToUpper(symbol)] = true
+	}
+	result := []string{}
+	for _, code := range tagged {
+		if wanted[code] {
+			result = append(result, code)
+		}
+	}
+	return result
+}
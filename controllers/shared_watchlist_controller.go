@@ -0,0 +1,420 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SharedWatchlistController serves watchlists a premium user shares with other users as viewers or
+// editors. Edits carry the version of the list they were made against and are rejected with 409
+// when someone else changed it first.
+type SharedWatchlistController struct {
+	db             *gorm.DB
+	supabaseClient *services.SupabaseDBClient
+}
+
+// NewSharedWatchlistController creates a new shared watchlist controller; without Supabase nobody
+// has a premium membership, so lists can't be created
+func NewSharedWatchlistController(db *gorm.DB) *SharedWatchlistController {
+	client, _ := services.NewSupabaseDBClient()
+	return &SharedWatchlistController{db: db, supabaseClient: client}
+}
+
+// RegisterSharedWatchlistRoutes registers shared watchlist routes
+func (sc *SharedWatchlistController) RegisterSharedWatchlistRoutes(api *gin.RouterGroup) {
+	shared := api.Group("/shared-watchlists")
+	{
+		shared.GET("", sc.ListWatchlists)
+		shared.POST("", sc.CreateWatchlist)
+		shared.GET("/invitations", sc.ListInvitations)
+		shared.POST("/invitations/:id/accept", sc.AcceptInvitation)
+		shared.POST("/invitations/:id/decline", sc.DeclineInvitation)
+		shared.GET("/:id", sc.GetWatchlist)
+		shared.PUT("/:id", sc.RenameWatchlist)
+		shared.DELETE("/:id", sc.DeleteWatchlist)
+		shared.POST("/:id/items", sc.AddItem)
+		shared.DELETE("/:id/items/:code", sc.RemoveItem)
+		shared.POST("/:id/members", sc.InviteMember)
+		shared.PUT("/:id/members/:member_id", sc.UpdateMember)
+		shared.DELETE("/:id/members/:member_id", sc.RemoveMember)
+	}
+}
+
+// respondError maps a shared watchlist error to its status; a conflict returns the current list
+// so the client can reapply the edit on top of it
+func (sc *SharedWatchlistController) respondError(c *gin.Context, err error, list *models.SharedWatchlist, userID, message string) {
+	switch {
+	case errors.Is(err, services.ErrSharedWatchlistConflict) && list != nil:
+		current, loadErr := services.GetSharedWatchlist(sc.db, list.ID, userID)
+		if loadErr != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "version": current.Version, "data": current})
+	case errors.Is(err, services.ErrSharedWatchlistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist not found"})
+	case errors.Is(err, services.ErrSharedWatchlistForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSharedWatchlist):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// loadWatchlist returns the list in the id parameter if the caller is its owner or an active member
+func (sc *SharedWatchlistController) loadWatchlist(c *gin.Context) (*models.SharedWatchlist, string, bool) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, "", false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, "", false
+	}
+
+	list, err := services.GetSharedWatchlist(sc.db, uint(id), userID)
+	if err != nil {
+		sc.respondError(c, err, nil, userID, "Failed to fetch watchlist")
+		return nil, "", false
+	}
+	return list, userID, true
+}
+
+// ListWatchlists returns the shared watchlists the current user owns or is a member of
+// GET /api/v1/shared-watchlists
+func (sc *SharedWatchlistController) ListWatchlists(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	lists, err := services.ListSharedWatchlists(sc.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlists"})
+		return
+	}
+	if lists == nil {
+		lists = []models.SharedWatchlist{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": lists,
+		"limits": gin.H{
+			"max_lists":   services.MaxSharedWatchlistsPerOwner,
+			"max_members": services.MaxSharedWatchlistMembers,
+			"max_items":   services.MaxSharedWatchlistItems,
+		},
+	})
+}
+
+// CreateWatchlist creates a shared watchlist owned by the current user, who needs a premium membership
+// POST /api/v1/shared-watchlists
+func (sc *SharedWatchlistController) CreateWatchlist(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.SharedWatchlistOwnerTiers[sc.supabaseClient.ActiveMembership(userID)] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Sharing watchlists requires a premium membership"})
+		return
+	}
+
+	list, err := services.CreateSharedWatchlist(sc.db, userID, request.Name)
+	if err != nil {
+		sc.respondError(c, err, nil, userID, "Failed to create watchlist")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": list})
+}
+
+// GetWatchlist returns a shared watchlist with its stocks, members and the caller's role
+// GET /api/v1/shared-watchlists/:id
+func (sc *SharedWatchlistController) GetWatchlist(c *gin.Context) {
+	list, _, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// RenameWatchlist renames a shared watchlist; owners and editors only
+// PUT /api/v1/shared-watchlists/:id
+func (sc *SharedWatchlistController) RenameWatchlist(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Name    string `json:"name" binding:"required"`
+		Version int    `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.RenameSharedWatchlist(sc.db, list, userID, request.Name, request.Version); err != nil {
+		sc.respondError(c, err, list, userID, "Failed to rename watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// DeleteWatchlist deletes a shared watchlist for everyone; owner only
+// DELETE /api/v1/shared-watchlists/:id
+func (sc *SharedWatchlistController) DeleteWatchlist(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteSharedWatchlist(sc.db, list, userID); err != nil {
+		sc.respondError(c, err, list, userID, "Failed to delete watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist deleted"})
+}
+
+// AddItem adds a stock to a shared watchlist, or updates its note; owners and editors only
+// POST /api/v1/shared-watchlists/:id/items
+func (sc *SharedWatchlistController) AddItem(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		StockCode string `json:"stock_code" binding:"required"`
+		Note      string `json:"note"`
+		Version   int    `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := services.AddSharedWatchlistItem(sc.db, list, userID, request.StockCode, request.Note, request.Version)
+	if err != nil {
+		sc.respondError(c, err, list, userID, "Failed to add stock")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": item, "version": list.Version})
+}
+
+// RemoveItem removes a stock from a shared watchlist; owners and editors only
+// DELETE /api/v1/shared-watchlists/:id/items/:code?version=3
+func (sc *SharedWatchlistController) RemoveItem(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(c.Query("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version is required"})
+		return
+	}
+
+	removed, err := services.RemoveSharedWatchlistItem(sc.db, list, userID, c.Param("code"), version)
+	if err != nil {
+		sc.respondError(c, err, list, userID, "Failed to remove stock")
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not on watchlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Stock removed", "version": list.Version})
+}
+
+// InviteMember invites a user by email as a viewer or editor; owner only. Registered users are
+// notified right away, others find the invitation after signing up with that email.
+// POST /api/v1/shared-watchlists/:id/members
+func (sc *SharedWatchlistController) InviteMember(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email" binding:"required"`
+		Role  string `json:"role" binding:"required"` // viewer, editor
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inviteeID := ""
+	if sc.supabaseClient != nil {
+		if profile, err := sc.supabaseClient.GetProfileByEmail(request.Email); err == nil && profile != nil {
+			inviteeID = profile.ID
+		}
+	}
+
+	member, err := services.InviteSharedWatchlistMember(sc.db, list, userID, request.Email, request.Role, inviteeID)
+	if err != nil {
+		sc.respondError(c, err, list, userID, "Failed to invite member")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": member})
+}
+
+// UpdateMember changes a member's role; owner only
+// PUT /api/v1/shared-watchlists/:id/members/:member_id
+func (sc *SharedWatchlistController) UpdateMember(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("member_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member ID"})
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required"` // viewer, editor
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := services.UpdateSharedWatchlistMemberRole(sc.db, list, uint(memberID), request.Role)
+	if err != nil {
+		sc.respondError(c, err, list, userID, "Failed to update member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": member})
+}
+
+// RemoveMember removes a member or withdraws an invitation; the owner can remove anyone and a
+// member can remove themselves to leave the list
+// DELETE /api/v1/shared-watchlists/:id/members/:member_id
+func (sc *SharedWatchlistController) RemoveMember(c *gin.Context) {
+	list, userID, ok := sc.loadWatchlist(c)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("member_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member ID"})
+		return
+	}
+
+	if err := services.RemoveSharedWatchlistMember(sc.db, list, userID, uint(memberID)); err != nil {
+		sc.respondError(c, err, list, userID, "Failed to remove member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// invitee returns the caller's user ID and email, which invitations are addressed to
+func (sc *SharedWatchlistController) invitee(c *gin.Context) (string, string, bool) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return "", "", false
+	}
+	email, err := middleware.GetSupabaseEmailFromContext(c)
+	if err != nil || email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your account has no email"})
+		return "", "", false
+	}
+	return userID, email, true
+}
+
+// invitationID parses the invitation ID parameter
+func invitationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListInvitations returns the pending shared watchlist invitations to the current user's email
+// GET /api/v1/shared-watchlists/invitations
+func (sc *SharedWatchlistController) ListInvitations(c *gin.Context) {
+	_, email, ok := sc.invitee(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := services.ListSharedWatchlistInvitations(sc.db, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+// AcceptInvitation makes the current user a member of the invited list
+// POST /api/v1/shared-watchlists/invitations/:id/accept
+func (sc *SharedWatchlistController) AcceptInvitation(c *gin.Context) {
+	userID, email, ok := sc.invitee(c)
+	if !ok {
+		return
+	}
+	id, ok := invitationID(c)
+	if !ok {
+		return
+	}
+
+	member, err := services.AcceptSharedWatchlistInvitation(sc.db, id, userID, email)
+	if errors.Is(err, services.ErrSharedWatchlistNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if err != nil {
+		sc.respondError(c, err, nil, userID, "Failed to accept invitation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": member})
+}
+
+// DeclineInvitation turns down an invitation to the current user's email
+// POST /api/v1/shared-watchlists/invitations/:id/decline
+func (sc *SharedWatchlistController) DeclineInvitation(c *gin.Context) {
+	userID, email, ok := sc.invitee(c)
+	if !ok {
+		return
+	}
+	id, ok := invitationID(c)
+	if !ok {
+		return
+	}
+
+	err := services.DeclineSharedWatchlistInvitation(sc.db, id, email)
+	if errors.Is(err, services.ErrSharedWatchlistNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if err != nil {
+		sc.respondError(c, err, nil, userID, "Failed to decline invitation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"go_backend_project/middleware"
 	"go_backend_project/services/signals"
 
 	"github.com/gin-gonic/gin"
@@ -81,16 +82,20 @@ func (ctrl *SignalController) GetAllSignals(c *gin.Context) {
 		filter.SignalTypes = []signals.SignalType{signals.SignalType(signalType)}
 	}
 
-	signalList, err := signals.GlobalSignalService.GenerateAllSignals(strategy, filter)
+	signalList, err := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), strategy, filter)
 	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"count":    len(signalList),
-		"signals":  signalList,
-		"strategy": strategy,
+		"count":     len(signalList),
+		"signals":   signalList,
+		"strategy":  strategy,
+		"freshness": dataFreshness(c),
 		"filter": gin.H{
 			"min_strength":    minStrength,
 			"min_confidence":  minConfidence,
@@ -119,8 +124,9 @@ func (ctrl *SignalController) GetBuySignals(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"count":   len(signalList),
-		"signals": signalList,
+		"count":     len(signalList),
+		"signals":   signalList,
+		"freshness": dataFreshness(c),
 		"filter": gin.H{
 			"min_strength": minStrength,
 			"limit":        limit,
@@ -146,8 +152,9 @@ func (ctrl *SignalController) GetSellSignals(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"count":   len(signalList),
-		"signals": signalList,
+		"count":     len(signalList),
+		"signals":   signalList,
+		"freshness": dataFreshness(c),
 		"filter": gin.H{
 			"min_strength": minStrength,
 			"limit":        limit,
@@ -172,7 +179,7 @@ func (ctrl *SignalController) GetTopSignals(c *gin.Context) {
 		MinTradingVal: 1.0,
 		Limit:         limit,
 	}
-	buySignals, _ := signals.GlobalSignalService.GenerateAllSignals("composite", buyFilter)
+	buySignals, _ := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), "composite", buyFilter)
 
 	// Get strong sell signals
 	sellFilter := &signals.SignalFilter{
@@ -181,13 +188,17 @@ func (ctrl *SignalController) GetTopSignals(c *gin.Context) {
 		MinTradingVal: 1.0,
 		Limit:         limit,
 	}
-	sellSignals, _ := signals.GlobalSignalService.GenerateAllSignals("composite", sellFilter)
+	sellSignals, _ := signals.GlobalSignalService.GenerateAllSignalsContext(c.Request.Context(), "composite", sellFilter)
+	if middleware.AbortIfRequestDone(c) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"top_buy_signals":  buySignals,
 		"top_sell_signals": sellSignals,
 		"buy_count":        len(buySignals),
 		"sell_count":       len(sellSignals),
+		"freshness":        dataFreshness(c),
 	})
 }
 
@@ -195,7 +206,7 @@ func (ctrl *SignalController) GetTopSignals(c *gin.Context) {
 func RegisterSignalRoutes(router *gin.RouterGroup) {
 	ctrl := NewSignalController()
 
-	signalGroup := router.Group("/signals")
+	signalGroup := router.Group("/signals", DataFreshnessGuard())
 	{
 		signalGroup.GET("/strategies", ctrl.GetStrategies)
 		signalGroup.GET("/buy", ctrl.GetBuySignals)
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SignalSubscriptionController lets users subscribe to a signal rule or template and be alerted
+// when a new stock starts matching it
+type SignalSubscriptionController struct {
+	db *gorm.DB
+}
+
+// NewSignalSubscriptionController creates a new signal subscription controller
+func NewSignalSubscriptionController(db *gorm.DB) *SignalSubscriptionController {
+	return &SignalSubscriptionController{db: db}
+}
+
+// RegisterSignalSubscriptionRoutes registers signal subscription routes
+func (sc *SignalSubscriptionController) RegisterSignalSubscriptionRoutes(api *gin.RouterGroup) {
+	subscriptions := api.Group("/subscriptions/signals")
+	{
+		subscriptions.GET("", sc.ListSubscriptions)
+		subscriptions.POST("", sc.CreateSubscription)
+		subscriptions.PUT("/:id", sc.UpdateSubscription)
+		subscriptions.DELETE("/:id", sc.DeleteSubscription)
+		subscriptions.GET("/:id/notifications", sc.GetNotifications)
+	}
+}
+
+// signalSubscriptionRequest holds the user-editable subscription settings
+type signalSubscriptionRequest struct {
+	Name        *string `json:"name"`
+	StockSymbol *string `json:"stock_symbol"`
+	Channel     *string `json:"channel"` // in_app, webhook
+	WebhookURL  *string `json:"webhook_url"`
+	Cooldown    *int    `json:"cooldown"`    // minutes
+	MaxPerDay   *int    `json:"max_per_day"` // 0 = unlimited
+	IsActive    *bool   `json:"is_active"`
+}
+
+// apply copies the set fields onto the alert and validates the result
+func (r *signalSubscriptionRequest) apply(alert *models.SignalAlert) string {
+	if r.Name != nil {
+		alert.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.StockSymbol != nil {
+		alert.StockSymbol = strings.ToUpper(strings.TrimSpace(*r.StockSymbol))
+	}
+	if r.Channel != nil {
+		alert.AlertType = *r.Channel
+	}
+	if r.WebhookURL != nil {
+		alert.WebhookURL = strings.TrimSpace(*r.WebhookURL)
+	}
+	if r.Cooldown != nil {
+		alert.Cooldown = *r.Cooldown
+	}
+	if r.MaxPerDay != nil {
+		alert.MaxPerDay = *r.MaxPerDay
+	}
+	if r.IsActive != nil {
+		alert.IsActive = *r.IsActive
+	}
+
+	switch alert.AlertType {
+	case models.SignalAlertTypeInApp:
+	case models.SignalAlertTypeWebhook:
+		u, err := url.Parse(alert.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "webhook_url must be an http(s) URL for webhook subscriptions"
+		}
+	default:
+		return "channel must be in_app or webhook"
+	}
+	if alert.Name == "" {
+		return "name is required"
+	}
+	if alert.Cooldown < 0 || alert.Cooldown > 7*24*60 {
+		return "cooldown must be between 0 and 10080 minutes"
+	}
+	if alert.MaxPerDay < 0 || alert.MaxPerDay > 100 {
+		return "max_per_day must be between 0 and 100"
+	}
+	return ""
+}
+
+// ListSubscriptions returns the current user's signal subscriptions
+// GET /api/v1/subscriptions/signals
+func (sc *SignalSubscriptionController) ListSubscriptions(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var alerts []models.SignalAlert
+	if err := sc.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": alerts})
+}
+
+// CreateSubscription subscribes the current user to an active rule they can see (admin-owned or
+// their own) or a template that is public or theirs
+// POST /api/v1/subscriptions/signals
+func (sc *SignalSubscriptionController) CreateSubscription(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		RuleID     uint `json:"rule_id"`
+		TemplateID uint `json:"template_id"`
+		signalSubscriptionRequest
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (request.RuleID == 0) == (request.TemplateID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of rule_id or template_id is required"})
+		return
+	}
+
+	var count int64
+	sc.db.Model(&models.SignalAlert{}).Where("user_id = ?", userID).Count(&count)
+	if count >= signals.MaxSubscriptionsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subscription limit reached"})
+		return
+	}
+
+	alert := &models.SignalAlert{
+		RuleID:     request.RuleID,
+		TemplateID: request.TemplateID,
+		UserID:     userID,
+		AlertType:  models.SignalAlertTypeInApp,
+		IsActive:   true,
+		Cooldown:   signals.DefaultSubscriptionCooldown,
+		MaxPerDay:  signals.DefaultSubscriptionMaxPerDay,
+	}
+
+	if request.RuleID > 0 {
+		var rule models.SignalRule
+		if err := sc.db.Where("id = ? AND is_active = ? AND (owner_user_id = '' OR owner_user_id IS NULL OR owner_user_id = ?)",
+			request.RuleID, true, userID).First(&rule).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return
+		}
+		alert.Name = rule.Name
+	} else {
+		var template models.SignalTemplate
+		if err := sc.db.Where("id = ? AND (visibility = ? OR author_id = ?)",
+			request.TemplateID, models.TemplateVisibilityPublic, userID).First(&template).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
+		alert.Name = template.Name
+	}
+
+	if msg := request.apply(alert); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// Zero cooldown and inactive are zero values that Create would replace with the column defaults
+	cooldown, active := alert.Cooldown, alert.IsActive
+	if err := sc.db.Create(alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
+		return
+	}
+	if cooldown == 0 || !active {
+		alert.Cooldown, alert.IsActive = cooldown, active
+		sc.db.Model(alert).Select("cooldown", "is_active").Updates(alert)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": alert})
+}
+
+// UpdateSubscription changes the channel, frequency caps, stock filter or status of a subscription
+// PUT /api/v1/subscriptions/signals/:id
+func (sc *SignalSubscriptionController) UpdateSubscription(c *gin.Context) {
+	alert, ok := sc.loadOwnSubscription(c)
+	if !ok {
+		return
+	}
+
+	var request signalSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := request.apply(alert); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := sc.db.Model(alert).Select("name", "stock_symbol", "alert_type", "webhook_url", "cooldown", "max_per_day", "is_active").
+		Updates(alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": alert})
+}
+
+// DeleteSubscription removes a subscription and its notification history
+// DELETE /api/v1/subscriptions/signals/:id
+func (sc *SignalSubscriptionController) DeleteSubscription(c *gin.Context) {
+	alert, ok := sc.loadOwnSubscription(c)
+	if !ok {
+		return
+	}
+
+	err := sc.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("alert_id = ?", alert.ID).Delete(&models.SignalAlertHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(alert).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted"})
+}
+
+// GetNotifications returns the alerts sent for a subscription, newest first
+// GET /api/v1/subscriptions/signals/:id/notifications?page=1&limit=20
+func (sc *SignalSubscriptionController) GetNotifications(c *gin.Context) {
+	alert, ok := sc.loadOwnSubscription(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := sc.db.Model(&models.SignalAlertHistory{}).Where("alert_id = ?", alert.ID)
+	var total int64
+	query.Count(&total)
+
+	var history []models.SignalAlertHistory
+	if err := query.Order("created_at DESC").Limit(limit).Offset((page - 1) * limit).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  history,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// loadOwnSubscription loads :id if it belongs to the current user
+func (sc *SignalSubscriptionController) loadOwnSubscription(c *gin.Context) (*models.SignalAlert, bool) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var alert models.SignalAlert
+	if err := sc.db.Where("id = ? AND user_id = ?", id, userID).First(&alert).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return nil, false
+	}
+	return &alert, true
+}
@@ -3,9 +3,11 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"go_backend_project/services/analysis"
 	"go_backend_project/services/datafetcher"
 	"github.com/gin-gonic/gin"
@@ -28,17 +30,22 @@ func NewStockController(db *gorm.DB) *StockController {
 	}
 }
 
-// GetStocks returns list of all stocks
-// GET /api/stocks
+// maxStockPageSize lets clients load the whole stock list in one page
+const maxStockPageSize = 2000
+
+// GetStocks returns list of all stocks ordered by symbol, by page or by cursor
+// GET /api/v1/stocks?page=1&limit=50&cursor=&exchange=HOSE&industry=&type=stock
 func (sc *StockController) GetStocks(c *gin.Context) {
 	var stocks []models.Stock
 
 	// Parse query parameters
 	exchange := c.Query("exchange")
 	industry := c.Query("industry")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset := (page - 1) * limit
+	pageReq, err := services.ParsePageRequest(c.Query("page"), c.DefaultQuery("limit", c.Query("page_size")), c.Query("cursor"), "symbol", 50, maxStockPageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	query := sc.db.Model(&models.Stock{})
 
@@ -48,21 +55,43 @@ func (sc *StockController) GetStocks(c *gin.Context) {
 	if industry != "" {
 		query = query.Where("industry = ?", industry)
 	}
+	// ?type=stock,etf filters by instrument type, which is classified in the synced stock list
+	if types, err := services.ParseInstrumentTypes(c.Query("type")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if len(types) > 0 {
+		query = query.Where("symbol IN ?", services.InstrumentCodes(types))
+	}
 
 	var total int64
 	query.Count(&total)
 
-	if err := query.Limit(limit).Offset(offset).Find(&stocks).Error; err != nil {
+	// Symbols are unique, so the symbol alone is the cursor position; one extra row tells
+	// whether another page follows
+	if pageReq.Cursor != nil {
+		query = query.Where("symbol > ?", pageReq.Cursor.Value)
+	} else {
+		query = query.Offset(pageReq.Offset())
+	}
+	if err := query.Order("symbol").Limit(pageReq.PageSize + 1).Find(&stocks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stocks"})
 		return
 	}
 
+	var nextCursor string
+	if len(stocks) > pageReq.PageSize {
+		stocks = stocks[:pageReq.PageSize]
+		last := stocks[len(stocks)-1]
+		nextCursor = pageReq.NewCursor(last.Symbol, strconv.FormatUint(uint64(last.ID), 10))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": stocks,
 		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
+			"page":        pageReq.Page,
+			"limit":       pageReq.PageSize,
+			"total":       total,
+			"next_cursor": nextCursor,
 		},
 	})
 }
@@ -70,7 +99,8 @@ func (sc *StockController) GetStocks(c *gin.Context) {
 // GetStock returns a single stock by ID or symbol
 // GET /api/stocks/:id
 func (sc *StockController) GetStock(c *gin.Context) {
-	id := c.Param("id")
+	// Registered as /:symbol to share the wildcard with the /:symbol/... routes
+	id := c.Param("symbol")
 
 	var stock models.Stock
 
@@ -87,8 +117,101 @@ func (sc *StockController) GetStock(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": stock})
 }
 
+// GetIntraday returns the intraday VWAP series and volume profile of a stock
+// GET /api/v1/stocks/:symbol/intraday?date=YYYY-MM-DD
+func (sc *StockController) GetIntraday(c *gin.Context) {
+	if services.GlobalTickStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Intraday tick storage not available"})
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("symbol"))
+	date := c.DefaultQuery("date", time.Now().Format(services.PriceDateFormat))
+
+	summary, err := services.GlobalTickStore.GetIntraday(symbol, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// GetStockLevels returns the classic and Fibonacci pivots calculated from the latest session and
+// the support/resistance clusters found in the recent price history
+// GET /api/v1/stocks/:symbol/levels?lookback=120&units=raw
+func (sc *StockController) GetStockLevels(c *gin.Context) {
+	lookback, err := strconv.Atoi(c.DefaultQuery("lookback", strconv.Itoa(services.LevelsLookback)))
+	if err != nil || lookback < 20 || lookback > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lookback must be between 20 and 500 sessions"})
+		return
+	}
+
+	levels, err := services.LoadStockLevels(c.Param("symbol"), lookback)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": withUnits(c, levels)})
+}
+
+// GetStockEvents returns the upcoming and past year's corporate events of a stock with its
+// trailing 12-month dividend yield
+// GET /api/v1/stocks/:symbol/events?days=90
+func (sc *StockController) GetStockEvents(c *gin.Context) {
+	code := strings.ToUpper(c.Param("symbol"))
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "90"))
+	if days < 1 || days > 365 {
+		days = 90
+	}
+
+	upcoming, err := services.UpcomingCorporateEvents(sc.db, []string{code}, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+	recent, err := services.RecentCorporateEvents(sc.db, code, 365)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+	yield, err := services.TrailingDividendYield(sc.db, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute dividend yield"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"code":           code,
+			"upcoming":       upcoming,
+			"recent":         recent,
+			"dividend_yield": yield,
+		},
+	})
+}
+
+// GetStockPeers compares a stock with same-sector stocks of similar market cap and ranks it
+// among them on relative strength, momentum and valuation
+// GET /api/v1/stocks/:symbol/peers?limit=8
+func (sc *StockController) GetStockPeers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultPeerCount)))
+	if limit < 1 || limit > services.MaxPeerCount {
+		limit = services.DefaultPeerCount
+	}
+
+	comparison, err := services.ComparePeers(sc.db, c.Param("symbol"), limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comparison})
+}
+
 // GetStockPrice returns price data for a stock
-// GET /api/stocks/:symbol/prices
+// GET /api/stocks/:symbol/prices?start_date=2024-01-02&end_date=2024-06-28&units=thousands
 func (sc *StockController) GetStockPrice(c *gin.Context) {
 	symbol := c.Param("symbol")
 
@@ -113,7 +236,7 @@ func (sc *StockController) GetStockPrice(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": prices,
+		"data": withUnits(c, prices),
 		"stock": stock,
 	})
 }
@@ -242,26 +365,33 @@ func (sc *StockController) GetMarketIndices(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": indices, "date": date})
 }
 
-// SearchStocks searches for stocks by symbol or name
-// GET /api/stocks/search
+// SearchStocks returns stocks ranked by code and company name match for typeahead
+// GET /api/v1/stocks/search?q=vinamilk&limit=10
 func (sc *StockController) SearchStocks(c *gin.Context) {
 	query := c.Query("q")
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query required"})
 		return
 	}
 
-	var stocks []models.Stock
-	err := sc.db.Where("symbol ILIKE ? OR name ILIKE ?", "%"+query+"%", "%"+query+"%").
-		Limit(20).
-		Find(&stocks).Error
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	types, err := services.ParseInstrumentTypes(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	results, err := services.GlobalStockSearch.Search(query, limit, types)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": stocks})
+	c.JSON(http.StatusOK, gin.H{"data": results, "query": query})
 }
 
 // GetTopGainers returns top gaining stocks
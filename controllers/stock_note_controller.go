@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StockNoteController serves the user's private notes and tags on stocks
+type StockNoteController struct {
+	db *gorm.DB
+}
+
+// NewStockNoteController creates a new stock note controller
+func NewStockNoteController(db *gorm.DB) *StockNoteController {
+	return &StockNoteController{db: db}
+}
+
+// RegisterStockNoteRoutes registers stock note routes
+func (nc *StockNoteController) RegisterStockNoteRoutes(api *gin.RouterGroup) {
+	notes := api.Group("/notes")
+	{
+		notes.GET("", nc.ListNotes)
+		notes.GET("/tags", nc.ListTags)
+		notes.GET("/:code", nc.GetNote)
+		notes.PUT("/:code", nc.SaveNote)
+		notes.DELETE("/:code", nc.DeleteNote)
+	}
+}
+
+// ListNotes returns the current user's stock notes, newest first, optionally only those with a tag
+// GET /api/v1/notes?tag=avoid
+func (nc *StockNoteController) ListNotes(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	notes, err := services.ListStockNotes(nc.db, userID, c.Query("tag"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
+		return
+	}
+	if notes == nil {
+		notes = []models.StockNote{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": notes,
+		"limits": gin.H{
+			"max_notes":         services.MaxStockNotesPerUser,
+			"max_note_length":   services.MaxStockNoteLength,
+			"max_tags_per_note": services.MaxTagsPerStockNote,
+			"max_tags":          services.MaxStockTagsPerUser,
+		},
+	})
+}
+
+// ListTags returns the current user's tags with how many stocks carry each
+// GET /api/v1/notes/tags
+func (nc *StockNoteController) ListTags(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	tags, err := services.UserStockTags(nc.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// GetNote returns the current user's note and tags on a stock
+// GET /api/v1/notes/:code
+func (nc *StockNoteController) GetNote(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	note, err := services.GetStockNote(nc.db, userID, c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note"})
+		return
+	}
+	if note == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": note})
+}
+
+// SaveNote creates or replaces the current user's note and tags on a stock
+// PUT /api/v1/notes/:code
+func (nc *StockNoteController) SaveNote(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Note string   `json:"note"`
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
+	if code == "" || len(code) > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock code"})
+		return
+	}
+
+	note, err := services.SaveStockNote(nc.db, userID, code, request.Note, request.Tags)
+	if errors.Is(err, services.ErrInvalidStockNote) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": note})
+}
+
+// DeleteNote removes the current user's note and tags on a stock
+// DELETE /api/v1/notes/:code
+func (nc *StockNoteController) DeleteNote(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	deleted, err := services.DeleteStockNote(nc.db, userID, c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted"})
+}
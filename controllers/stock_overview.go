@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+)
+
+// overviewRecentSignals is the number of persisted signals included in a stock overview
+const overviewRecentSignals = 10
+
+// StockOverviewPrice is the latest daily bar of a stock
+type StockOverviewPrice struct {
+	Date      string  `json:"date"`
+	Open      float64 `json:"open" unit:"price"`
+	High      float64 `json:"high" unit:"price"`
+	Low       float64 `json:"low" unit:"price"`
+	Close     float64 `json:"close" unit:"price"`
+	Change    float64 `json:"change" unit:"price"`
+	PctChange float64 `json:"pct_change"`
+	Volume    float64 `json:"volume"`
+	Value     float64 `json:"value" unit:"value_vnd"`
+}
+
+// StockOverview aggregates everything the stock detail screen needs. Sections that fail to load
+// are left empty and their error is reported in Errors instead of failing the whole response.
+type StockOverview struct {
+	Code          string                            `json:"code"`
+	Profile       *services.VNDirectStock           `json:"profile"`
+	Price         *StockOverviewPrice               `json:"price"`
+	Indicators    *services.ExtendedStockIndicators `json:"indicators"`
+	Signal        *signals.TradingSignal            `json:"signal"`
+	RecentSignals []models.SignalHistory            `json:"recent_signals"`
+	News          []interface{}                     `json:"news"`
+	Note          *models.StockNote                 `json:"note,omitempty"` // the caller's note, when signed in
+	Errors        map[string]string                 `json:"errors,omitempty"`
+	GeneratedAt   string                            `json:"generated_at"`
+}
+
+// GetStockOverview returns company info, latest price, indicators, composite signal,
+// recent signals, news and the caller's note for a stock in one response
+// GET /api/v1/stocks/:symbol/overview?units=display
+func (sc *StockController) GetStockOverview(c *gin.Context) {
+	code := strings.ToUpper(c.Param("symbol"))
+
+	overview := &StockOverview{
+		Code:          code,
+		RecentSignals: []models.SignalHistory{},
+		News:          []interface{}{},
+		// No news source is integrated yet; the section is kept so clients can rely on the shape
+		Errors: map[string]string{"news": "news feed not available"},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	section := func(name string, load func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := load(); err != nil {
+				mu.Lock()
+				overview.Errors[name] = err.Error()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	section("profile", func() error {
+		stocks, err := services.LoadStocksWithFallback()
+		if err != nil {
+			return err
+		}
+		for i := range stocks {
+			if strings.ToUpper(stocks[i].Code) == code {
+				overview.Profile = &stocks[i]
+				return nil
+			}
+		}
+		return fmt.Errorf("stock not found in stock list")
+	})
+
+	section("price", func() error {
+		if services.GlobalPriceService == nil {
+			return fmt.Errorf("price service not available")
+		}
+		file, err := services.GlobalPriceService.LoadStockPrice(code)
+		if err != nil {
+			return err
+		}
+		if len(file.Prices) == 0 {
+			return fmt.Errorf("no price data")
+		}
+		p := file.Prices[0] // newest first
+		overview.Price = &StockOverviewPrice{
+			Date:      p.Date,
+			Open:      p.Open,
+			High:      p.High,
+			Low:       p.Low,
+			Close:     p.Close,
+			Change:    p.Change,
+			PctChange: p.PctChange,
+			Volume:    p.NmVolume,
+			Value:     p.NmValue,
+		}
+		return nil
+	})
+
+	section("indicators", func() error {
+		ind, err := signals.LoadStockIndicators(code)
+		if err != nil {
+			return err
+		}
+		overview.Indicators = ind
+
+		var strategy signals.Strategy = &signals.CompositeStrategy{}
+		if signals.GlobalSignalService != nil {
+			if s, ok := signals.GlobalSignalService.GetStrategy("composite"); ok {
+				strategy = s
+			}
+		}
+		signal, err := strategy.Evaluate(ind)
+		if err != nil {
+			mu.Lock()
+			overview.Errors["signal"] = err.Error()
+			mu.Unlock()
+			return nil
+		}
+		signal.Code = code
+		overview.Signal = signal
+		return nil
+	})
+
+	section("recent_signals", func() error {
+		if signals.GlobalConditionEvaluator == nil {
+			return fmt.Errorf("condition evaluator not available")
+		}
+		history, _, err := signals.GlobalConditionEvaluator.ListSignalHistory(signals.SignalHistoryFilter{
+			StockCode:  code,
+			PublicOnly: true,
+			Page:       1,
+			PageSize:   overviewRecentSignals,
+		})
+		if err != nil {
+			return err
+		}
+		overview.RecentSignals = history
+		return nil
+	})
+
+	if userID, err := middleware.GetSupabaseUserFromContext(c); err == nil && sc.db != nil {
+		section("note", func() error {
+			note, err := services.GetStockNote(sc.db, userID, code)
+			if err != nil {
+				return err
+			}
+			overview.Note = note
+			return nil
+		})
+	}
+
+	wg.Wait()
+
+	if overview.Profile == nil && overview.Price == nil && overview.Indicators == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found", "errors": overview.Errors})
+		return
+	}
+
+	overview.GeneratedAt = time.Now().Format(time.RFC3339)
+	c.JSON(http.StatusOK, gin.H{"data": withUnits(c, overview)})
+}
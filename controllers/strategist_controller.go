@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StrategistController lets users follow the authors of public templates, receive the signals
+// of their strategies in the notification center and copy them into a paper-trading bot
+type StrategistController struct {
+	db *gorm.DB
+}
+
+// NewStrategistController creates a new strategist controller
+func NewStrategistController(db *gorm.DB) *StrategistController {
+	return &StrategistController{db: db}
+}
+
+// RegisterStrategistRoutes registers strategist follow and paper trading routes
+func (sc *StrategistController) RegisterStrategistRoutes(api *gin.RouterGroup) {
+	strategists := api.Group("/strategists")
+	{
+		strategists.GET("/following", sc.FollowedStrategists)
+		strategists.GET("/:author_id", sc.GetStrategist)
+		strategists.POST("/:author_id/follow", sc.FollowStrategist)
+		strategists.DELETE("/:author_id/follow", sc.UnfollowStrategist)
+	}
+
+	paper := api.Group("/paper-trades")
+	{
+		paper.GET("", sc.GetPaperTrades)
+		paper.POST("", sc.AddPaperTrade)
+		paper.POST("/:id/close", sc.ClosePaperTrade)
+	}
+}
+
+// FollowedStrategists returns the strategists the current user follows with their performance
+// GET /api/v1/strategists/following
+func (sc *StrategistController) FollowedStrategists(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	list, err := services.ListFollowedStrategists(sc.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strategists"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetStrategist returns a template author's signal performance and whether the caller follows them
+// GET /api/v1/strategists/:author_id
+func (sc *StrategistController) GetStrategist(c *gin.Context) {
+	stats, err := services.GetStrategistStats(sc.db, c.Param("author_id"))
+	if errors.Is(err, services.ErrStrategistNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategist not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strategist"})
+		return
+	}
+
+	following := false
+	if viewerID, _ := middleware.GetSupabaseUserFromContext(c); viewerID != "" {
+		var count int64
+		sc.db.Model(&models.StrategistFollow{}).Where("user_id = ? AND author_id = ?", viewerID, stats.AuthorID).Count(&count)
+		following = count > 0
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stats, "following": following})
+}
+
+// FollowStrategist makes the current user receive the signals of an author's strategies
+// POST /api/v1/strategists/:author_id/follow
+func (sc *StrategistController) FollowStrategist(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	follow, err := services.FollowStrategist(sc.db, userID, c.Param("author_id"))
+	if errors.Is(err, services.ErrStrategistNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strategist not found"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStrategistFollow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow strategist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": follow})
+}
+
+// UnfollowStrategist stops the current user receiving an author's signals
+// DELETE /api/v1/strategists/:author_id/follow
+func (sc *StrategistController) UnfollowStrategist(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := services.UnfollowStrategist(sc.db, userID, c.Param("author_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow strategist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Strategist unfollowed"})
+}
+
+// GetPaperTrades returns the current user's paper trades, open ones marked to the current price,
+// with the totals of their paper-trading bot
+// GET /api/v1/paper-trades?status=open
+func (sc *StrategistController) GetPaperTrades(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	trades, summary, err := services.ListPaperTrades(sc.db, userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper trades"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": trades, "summary": summary})
+}
+
+// AddPaperTrade adds a strategist signal from the notification center to the current user's
+// paper-trading bot at the current price; the quantity defaults to one board lot
+// POST /api/v1/paper-trades
+func (sc *StrategistController) AddPaperTrade(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		SignalID uint  `json:"signal_id" binding:"required"`
+		Quantity int64 `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trade, err := services.AddSignalToPaperBot(sc.db, userID, request.SignalID, request.Quantity)
+	if errors.Is(err, services.ErrPaperTradeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Signal not found"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidPaperTrade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add paper trade"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": trade})
+}
+
+// ClosePaperTrade closes one of the current user's open paper trades at the current price
+// POST /api/v1/paper-trades/:id/close
+func (sc *StrategistController) ClosePaperTrade(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	trade, err := services.GetPaperTrade(sc.db, userID, uint(id))
+	if errors.Is(err, services.ErrPaperTradeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper trade not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper trade"})
+		return
+	}
+
+	err = services.ClosePaperTrade(sc.db, trade, 0, "manual")
+	if errors.Is(err, services.ErrInvalidPaperTrade) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close paper trade"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": trade})
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -180,6 +182,11 @@ func (sc *SubscriptionController) Subscribe(c *gin.Context) {
 		return
 	}
 
+	services.NotifyLocalUser(sc.db, request.UserID, models.NotificationCategoryMembership,
+		fmt.Sprintf("Welcome to %s", plan.Name),
+		fmt.Sprintf("Your %s membership is active until %s", plan.Name, endDate.Format("2006-01-02")),
+		gin.H{"subscription_id": subscription.ID, "plan_id": plan.ID, "status": "active"})
+
 	c.JSON(http.StatusCreated, gin.H{"data": subscription})
 }
 
@@ -215,6 +222,11 @@ func (sc *SubscriptionController) CancelSubscription(c *gin.Context) {
 		return
 	}
 
+	services.NotifyLocalUser(sc.db, request.UserID, models.NotificationCategoryMembership,
+		"Membership cancelled",
+		fmt.Sprintf("Your membership stays active until %s and will not renew", subscription.EndDate.Format("2006-01-02")),
+		gin.H{"subscription_id": subscription.ID, "plan_id": subscription.PlanID, "status": "cancelled"})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription cancelled", "valid_until": subscription.EndDate})
 }
 
@@ -0,0 +1,484 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/models"
+	"go_backend_project/services/signals"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// maxClonedRulesPerUser caps the rules a user can own through template clones
+const maxClonedRulesPerUser = 20
+
+// TemplateController handles the community signal template marketplace
+type TemplateController struct {
+	db *gorm.DB
+}
+
+// NewTemplateController creates a new template controller
+func NewTemplateController(db *gorm.DB) *TemplateController {
+	return &TemplateController{db: db}
+}
+
+// RegisterTemplateRoutes registers marketplace routes
+func (tc *TemplateController) RegisterTemplateRoutes(api *gin.RouterGroup) {
+	templates := api.Group("/templates")
+	{
+		templates.GET("", tc.BrowseTemplates)
+		templates.GET("/mine", tc.GetMyTemplates)
+		templates.GET("/:id", tc.GetTemplate)
+		templates.POST("", tc.PublishTemplate)
+		templates.PUT("/:id/visibility", tc.UpdateVisibility)
+		templates.POST("/:id/clone", tc.CloneTemplate)
+		templates.POST("/:id/rate", tc.RateTemplate)
+		templates.GET("/:id/backtest", tc.BacktestTemplate)
+	}
+}
+
+// BrowseTemplates returns public templates
+// GET /api/v1/templates?category=momentum&featured=true&sort=popular|rating|newest&page=1&limit=20
+func (tc *TemplateController) BrowseTemplates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := tc.db.Model(&models.SignalTemplate{}).Where("visibility = ?", models.TemplateVisibilityPublic)
+
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if c.Query("featured") == "true" {
+		query = query.Where("is_featured = ?", true)
+	}
+	if search := c.Query("search"); search != "" {
+		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	switch c.DefaultQuery("sort", "popular") {
+	case "rating":
+		query = query.Order("rating_avg DESC, rating_count DESC")
+	case "newest":
+		query = query.Order("created_at DESC")
+	default:
+		query = query.Order("is_featured DESC, popularity DESC, clone_count DESC")
+	}
+
+	var templates []models.SignalTemplate
+	if err := query.Limit(limit).Offset((page - 1) * limit).Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": templates,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetMyTemplates returns templates authored by the current user
+// GET /api/v1/templates/mine
+func (tc *TemplateController) GetMyTemplates(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var templates []models.SignalTemplate
+	if err := tc.db.Where("author_id = ?", userID).Order("created_at DESC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+// GetTemplate returns a single template visible to the caller
+// GET /api/v1/templates/:id
+func (tc *TemplateController) GetTemplate(c *gin.Context) {
+	template, ok := tc.loadVisibleTemplate(c)
+	if !ok {
+		return
+	}
+
+	tc.db.Model(template).UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+
+	c.JSON(http.StatusOK, gin.H{"data": template})
+}
+
+// PublishTemplate creates a template owned by the current user
+// POST /api/v1/templates
+func (tc *TemplateController) PublishTemplate(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Name        string                  `json:"name" binding:"required"`
+		Description string                  `json:"description"`
+		Category    string                  `json:"category"`
+		Visibility  string                  `json:"visibility"`
+		Conditions  []signals.ConditionJSON `json:"conditions" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Visibility == "" {
+		request.Visibility = models.TemplateVisibilityPrivate
+	}
+	if request.Visibility != models.TemplateVisibilityPrivate && request.Visibility != models.TemplateVisibilityPublic {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visibility must be private or public"})
+		return
+	}
+	if request.Category == "" {
+		request.Category = "custom"
+	}
+
+	conditionsJSON, _ := json.Marshal(request.Conditions)
+	email, _ := middleware.GetSupabaseEmailFromContext(c)
+
+	template := &models.SignalTemplate{
+		Name:        request.Name,
+		Description: request.Description,
+		Category:    request.Category,
+		Conditions:  string(conditionsJSON),
+		AuthorID:    userID,
+		AuthorName:  email,
+		Visibility:  request.Visibility,
+	}
+
+	if err := tc.db.Create(template).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create template, the name may already be taken"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": template})
+}
+
+// UpdateVisibility lets the author publish or unpublish a template
+// PUT /api/v1/templates/:id/visibility
+func (tc *TemplateController) UpdateVisibility(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Visibility string `json:"visibility" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Visibility != models.TemplateVisibilityPrivate && request.Visibility != models.TemplateVisibilityPublic {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visibility must be private or public"})
+		return
+	}
+
+	result := tc.db.Model(&models.SignalTemplate{}).
+		Where("id = ? AND author_id = ?", c.Param("id"), userID).
+		Update("visibility", request.Visibility)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template visibility updated"})
+}
+
+// CloneTemplate copies a template into a condition group and rule owned by the current user
+// POST /api/v1/templates/:id/clone
+func (tc *TemplateController) CloneTemplate(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	template, ok := tc.loadVisibleTemplate(c)
+	if !ok {
+		return
+	}
+
+	var owned int64
+	tc.db.Model(&models.SignalRule{}).Where("owner_user_id = ?", userID).Count(&owned)
+	if owned >= maxClonedRulesPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Clone limit reached: at most %d rules per user, delete one first", maxClonedRulesPerUser)})
+		return
+	}
+
+	var request struct {
+		Name       string `json:"name"`
+		SignalType string `json:"signal_type"`
+	}
+	_ = c.ShouldBindJSON(&request)
+
+	if request.SignalType == "" {
+		request.SignalType = "BUY"
+	}
+	if request.Name == "" {
+		request.Name = template.Name
+	}
+	// Group and rule names are globally unique, so suffix with owner and time
+	uniqueName := fmt.Sprintf("%s [%s-%d]", request.Name, shortUserID(userID), time.Now().Unix())
+
+	var conditions []signals.ConditionJSON
+	if err := json.Unmarshal([]byte(template.Conditions), &conditions); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Template conditions are invalid"})
+		return
+	}
+
+	var rule models.SignalRule
+	err = tc.db.Transaction(func(tx *gorm.DB) error {
+		group := models.SignalConditionGroup{
+			Name:        uniqueName,
+			Description: template.Description,
+			SignalType:  request.SignalType,
+			IsActive:    true,
+			OwnerUserID: userID,
+		}
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+
+		for i, cond := range conditions {
+			weight := cond.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			condition := models.SignalCondition{
+				GroupID:          group.ID,
+				Indicator:        models.IndicatorType(cond.Indicator),
+				Operator:         models.ConditionOperator(cond.Operator),
+				Value:            decimal.NewFromFloat(cond.Value),
+				Value2:           decimal.NewFromFloat(cond.Value2),
+				CompareIndicator: models.IndicatorType(cond.CompareIndicator),
+				LogicalOperator:  models.LogicalAnd,
+				Weight:           weight,
+				IsRequired:       cond.Required,
+				OrderIndex:       i,
+			}
+			if err := tx.Create(&condition).Error; err != nil {
+				return err
+			}
+		}
+
+		rule = models.SignalRule{
+			Name:             uniqueName,
+			Description:      template.Description,
+			SignalType:       request.SignalType,
+			StrategyType:     template.Category,
+			MinScore:         60,
+			TargetPercent:    decimal.NewFromInt(10),
+			StopLossPercent:  decimal.NewFromInt(5),
+			IsActive:         true,
+			OwnerUserID:      userID,
+			SourceTemplateID: &template.ID,
+		}
+		if err := rule.SetGroupRefs([]models.RuleGroupRef{{GroupID: group.ID, Logic: "AND", Required: true}}); err != nil {
+			return err
+		}
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.SignalTemplate{}).Where("id = ?", template.ID).UpdateColumns(map[string]interface{}{
+			"clone_count": gorm.Expr("clone_count + 1"),
+			"popularity":  gorm.Expr("popularity + 1"),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Template cloned", "data": rule})
+}
+
+// RateTemplate records or updates the current user's rating of a public template
+// POST /api/v1/templates/:id/rate
+func (tc *TemplateController) RateTemplate(c *gin.Context) {
+	userID, err := middleware.GetSupabaseUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var request struct {
+		Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, ok := tc.loadVisibleTemplate(c)
+	if !ok {
+		return
+	}
+	if template.AuthorID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot rate your own template"})
+		return
+	}
+
+	err = tc.db.Transaction(func(tx *gorm.DB) error {
+		var rating models.SignalTemplateRating
+		err := tx.Where("template_id = ? AND user_id = ?", template.ID, userID).First(&rating).Error
+		if err == gorm.ErrRecordNotFound {
+			rating = models.SignalTemplateRating{TemplateID: template.ID, UserID: userID}
+		} else if err != nil {
+			return err
+		}
+		rating.Rating = request.Rating
+		rating.Comment = request.Comment
+		if err := tx.Save(&rating).Error; err != nil {
+			return err
+		}
+
+		// Recompute aggregates from the ratings table so repeat votes don't drift
+		var agg struct {
+			Sum   int
+			Count int
+		}
+		if err := tx.Model(&models.SignalTemplateRating{}).
+			Select("COALESCE(SUM(rating), 0) as sum, COUNT(*) as count").
+			Where("template_id = ?", template.ID).
+			Scan(&agg).Error; err != nil {
+			return err
+		}
+
+		avg := decimal.Zero
+		if agg.Count > 0 {
+			avg = decimal.NewFromInt(int64(agg.Sum)).Div(decimal.NewFromInt(int64(agg.Count))).Round(2)
+		}
+		template.RatingSum = agg.Sum
+		template.RatingCount = agg.Count
+		template.RatingAvg = avg
+
+		return tx.Model(&models.SignalTemplate{}).Where("id = ?", template.ID).UpdateColumns(map[string]interface{}{
+			"rating_sum":   agg.Sum,
+			"rating_count": agg.Count,
+			"rating_avg":   avg,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rate template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Rating saved",
+		"rating_avg":   template.RatingAvg,
+		"rating_count": template.RatingCount,
+	})
+}
+
+// BacktestTemplate replays a template on one stock and returns the summary and the trade markers
+// for charting. The period defaults to the last year and is at most 2 years.
+// GET /api/v1/templates/:id/backtest?code=VNM&from=2024-01-01&to=2025-12-31&max_holding_days=20
+func (tc *TemplateController) BacktestTemplate(c *gin.Context) {
+	if signals.GlobalConditionEvaluator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Condition evaluator not available"})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	from := c.Query("from")
+	if from == "" {
+		if end, err := time.Parse("2006-01-02", to); err == nil {
+			from = end.AddDate(-1, 0, 0).Format("2006-01-02")
+		}
+	}
+	maxHoldingDays, _ := strconv.Atoi(c.Query("max_holding_days"))
+	if maxHoldingDays < 0 || maxHoldingDays > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_holding_days must be between 1 and 60"})
+		return
+	}
+
+	template, ok := tc.loadVisibleTemplate(c)
+	if !ok {
+		return
+	}
+
+	result, err := signals.GlobalConditionEvaluator.BacktestTemplate(c.Request.Context(), template, code, from, to, maxHoldingDays)
+	if err != nil {
+		if middleware.AbortIfRequestDone(c) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// loadVisibleTemplate loads :id if it is public or authored by the caller
+func (tc *TemplateController) loadVisibleTemplate(c *gin.Context) (*models.SignalTemplate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	var template models.SignalTemplate
+	if err := tc.db.First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+
+	if template.Visibility != models.TemplateVisibilityPublic {
+		userID, _ := middleware.GetSupabaseUserFromContext(c)
+		if userID == "" || userID != template.AuthorID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return nil, false
+		}
+	}
+
+	return &template, true
+}
+
+// shortUserID returns the first 8 characters of a user ID for display names
+func shortUserID(userID string) string {
+	if len(userID) > 8 {
+		return userID[:8]
+	}
+	return userID
+}
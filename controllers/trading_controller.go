@@ -108,6 +108,21 @@ func (tc *TradingController) RunBacktest(c *gin.Context) {
 		Symbols        []string `json:"symbols" binding:"required"`
 		Commission     float64  `json:"commission"`
 		RiskPerTrade   float64  `json:"risk_per_trade"`
+		Mode             string  `json:"mode"` // portfolio ranks entries by signal strength
+		MaxPositions     int     `json:"max_positions"`
+		// Position sizing; see backtesting.SizingConfig
+		Sizing           string  `json:"sizing"` // fixed_fractional, volatility or kelly
+		TargetVolatility float64 `json:"target_volatility"`
+		ATRPeriod        int     `json:"atr_period"`
+		KellyFraction    float64 `json:"kelly_fraction"`
+		KellyCap         float64 `json:"kelly_cap"`
+		KellyMinTrades   int     `json:"kelly_min_trades"`
+		// Order matching; see backtesting.ExecutionConfig
+		SlippageModel    string  `json:"slippage_model"` // "", fixed_bps or volume
+		SlippageBps      float64 `json:"slippage_bps"`
+		ImpactBps        float64 `json:"impact_bps"`
+		MaxParticipation float64 `json:"max_participation"` // share of the day's volume, e.g. 0.1
+		IgnoreSettlement bool    `json:"ignore_settlement"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -135,6 +150,27 @@ func (tc *TradingController) RunBacktest(c *gin.Context) {
 		Commission:     decimal.NewFromFloat(request.Commission),
 		Symbols:        request.Symbols,
 		RiskPerTrade:   decimal.NewFromFloat(request.RiskPerTrade),
+		Mode:           request.Mode,
+		MaxPositions:   request.MaxPositions,
+		Sizing: backtesting.SizingConfig{
+			Method:           request.Sizing,
+			TargetVolatility: decimal.NewFromFloat(request.TargetVolatility),
+			ATRPeriod:        request.ATRPeriod,
+			KellyFraction:    decimal.NewFromFloat(request.KellyFraction),
+			KellyCap:         decimal.NewFromFloat(request.KellyCap),
+			KellyMinTrades:   request.KellyMinTrades,
+		},
+		Execution: backtesting.ExecutionConfig{
+			SlippageModel:    request.SlippageModel,
+			SlippageBps:      decimal.NewFromFloat(request.SlippageBps),
+			ImpactBps:        decimal.NewFromFloat(request.ImpactBps),
+			MaxParticipation: decimal.NewFromFloat(request.MaxParticipation),
+			IgnoreSettlement: request.IgnoreSettlement,
+		},
+	}
+	if err := config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	backtest, err := tc.backtestEngine.RunBacktest(config)
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+
+	"go_backend_project/services/units"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unitsKey is the context key holding the ?units= mode of the request
+const unitsKey = "units"
+
+// UnitsOption lets clients choose how prices and values are reported with ?units=raw (VND),
+// thousands (prices in thousands of VND, values in tỷ) or display (formatted strings). Without
+// it responses keep the units their sources store, so existing clients see no change.
+func UnitsOption() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mode, err := units.ParseMode(c.Query("units"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(unitsKey, mode)
+		c.Next()
+	}
+}
+
+// withUnits reports the amounts of data in the mode chosen by UnitsOption
+func withUnits(c *gin.Context, data interface{}) interface{} {
+	mode, _ := c.Get(unitsKey)
+	m, _ := mode.(units.Mode)
+	return units.Apply(data, m)
+}
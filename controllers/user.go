@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -183,13 +184,28 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deactivated successfully"})
 }
 
-// GetUserWatchlist returns user's stock watchlist
-// GET /api/v1/users/:id/watchlist
+// GetUserWatchlist returns user's stock watchlist, optionally only the stocks the user tagged
+// GET /api/v1/users/:id/watchlist?tag=avoid
 func (uc *UserController) GetUserWatchlist(c *gin.Context) {
 	userID := c.Param("id")
 
+	query := uc.db.Where("user_id = ?", userID)
+	if tag := c.Query("tag"); tag != "" {
+		var user models.User
+		if err := uc.db.Select("id", "supabase_user_id").First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		codes, err := services.StockCodesWithTag(uc.db, user.SupabaseUserID, tag)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+			return
+		}
+		query = query.Where("stock_id IN (?)", uc.db.Model(&models.Stock{}).Select("id").Where("symbol IN ?", codes))
+	}
+
 	var watchlist []models.Watchlist
-	if err := uc.db.Where("user_id = ?", userID).Preload("Stock").Find(&watchlist).Error; err != nil {
+	if err := query.Preload("Stock").Find(&watchlist).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist"})
 		return
 	}
@@ -279,7 +295,7 @@ func (uc *UserController) CreateUserAlert(c *gin.Context) {
 	var request struct {
 		StockID     uint    `json:"stock_id" binding:"required"`
 		AlertType   string  `json:"alert_type" binding:"required"`
-		TargetValue float64 `json:"target_value" binding:"required"`
+		TargetValue float64 `json:"target_value"`
 		NotifyEmail bool    `json:"notify_email"`
 		NotifyPush  bool    `json:"notify_push"`
 	}
@@ -297,6 +313,10 @@ func (uc *UserController) CreateUserAlert(c *gin.Context) {
 		})
 		return
 	}
+	if request.TargetValue == 0 && !models.IsLimitUserAlertType(request.AlertType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_value is required for " + request.AlertType + " alerts"})
+		return
+	}
 
 	alert := models.UserAlert{
 		UserID:      uint(userID),
@@ -16,12 +16,17 @@ import (
 
 	"go_backend_project/admin/templates"
 	"go_backend_project/config"
+	"go_backend_project/middleware"
 	"go_backend_project/models"
 	"go_backend_project/routes"
 	"go_backend_project/scheduler"
 	"go_backend_project/services"
+	"go_backend_project/services/pipeline"
+	"go_backend_project/services/seed"
+	"go_backend_project/services/signals"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // dbInitialized tracks whether database has been successfully initialized
@@ -41,6 +46,11 @@ func main() {
 		log.Printf("Warning: Config load issue: %v", err)
 	}
 
+	// Swap VNDirect, SSI and Supabase for synthetic data before any client is created
+	if err := services.InitFakeProviders(); err != nil {
+		log.Printf("Warning: Failed to enable fake providers: %v", err)
+	}
+
 	// Set Gin mode based on environment
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -48,11 +58,16 @@ func main() {
 
 	// Create Gin router
 	router := gin.New()
+	if err := middleware.ConfigureClientIP(router, cfg.TrustedProxies); err != nil {
+		log.Printf("Warning: %v, using the peer address as client IP", err)
+	}
 
 	// Add middlewares
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
 	router.Use(requestLogger())
+	// Cancel request work just before the server's write timeout would drop the response anyway
+	router.Use(middleware.RequestTimeout(55 * time.Second))
 
 	// Load HTML templates from embedded filesystem
 	if err := loadTemplates(router); err != nil {
@@ -118,7 +133,16 @@ func main() {
 		}
 
 		// Initialize global services
-		initializeGlobalServices()
+		initializeGlobalServices(db)
+
+		// Demo instances seed their sample data on every start; existing rows are kept
+		if seed.OnStartup() {
+			if result, err := seed.Demo(context.Background(), db); err != nil {
+				log.Printf("Warning: Could not seed demo data: %v", err)
+			} else {
+				log.Printf("Demo data seeded: created %v, already present %v", result.Created, result.Existing)
+			}
+		}
 
 		// Mark database as ready
 		dbInitMutex.Lock()
@@ -173,11 +197,106 @@ func runMigrations() error {
 		return err
 	}
 
+	// Migrate admin notification models
+	if err := models.MigrateAdminNotificationModels(db); err != nil {
+		return err
+	}
+
+	// Migrate order book snapshot models
+	if err := models.MigrateOrderBookModels(db); err != nil {
+		return err
+	}
+
+	// Migrate intraday tick models
+	if err := models.MigrateIntradayTickModels(db); err != nil {
+		return err
+	}
+
+	// Migrate system config storage
+	if err := models.MigrateSystemConfigModels(db); err != nil {
+		return err
+	}
+
+	// Migrate user-defined strategy models
+	if err := models.MigrateCustomStrategyModels(db); err != nil {
+		return err
+	}
+
+	// Migrate sync history
+	if err := models.MigrateSyncHistoryModels(db); err != nil {
+		return err
+	}
+
+	// Migrate user data erasure requests
+	if err := models.MigrateUserErasureModels(db); err != nil {
+		return err
+	}
+
+	// Migrate the impersonation audit log
+	if err := models.MigrateImpersonationModels(db); err != nil {
+		return err
+	}
+
+	// Migrate the user notification inbox and push devices
+	if err := models.MigrateNotificationModels(db); err != nil {
+		return err
+	}
+
+	// Migrate scheduled user reports
+	if err := models.MigrateUserReportModels(db); err != nil {
+		return err
+	}
+
+	// Migrate corporate events (dividends, AGMs, rights issues)
+	if err := models.MigrateCorporateEventModels(db); err != nil {
+		return err
+	}
+
+	// Migrate user stock notes and tags
+	if err := models.MigrateStockNoteModels(db); err != nil {
+		return err
+	}
+
+	// Migrate shared watchlists
+	if err := models.MigrateSharedWatchlistModels(db); err != nil {
+		return err
+	}
+
+	// Migrate trade ideas
+	if err := models.MigrateIdeaModels(db); err != nil {
+		return err
+	}
+
+	// Migrate strategist follows and paper trades
+	if err := models.MigrateStrategistModels(db); err != nil {
+		return err
+	}
+
+	// Migrate API usage logs and daily analytics
+	if err := models.MigrateAnalyticsModels(db); err != nil {
+		return err
+	}
+
+	// Migrate experiments
+	if err := models.MigrateExperimentModels(db); err != nil {
+		return err
+	}
+
+	// Migrate health incidents
+	if err := models.MigrateIncidentModels(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // initializeGlobalServices initializes global service instances
-func initializeGlobalServices() {
+func initializeGlobalServices(db *gorm.DB) {
+	// Initialize the market calendar used by schedulers and backtests
+	if err := services.InitTradingCalendar(); err != nil {
+		log.Printf("Warning: Failed to initialize trading calendar: %v", err)
+	}
+
 	// Initialize price service first (indicator service depends on it)
 	if err := services.InitPriceService(); err != nil {
 		log.Printf("Warning: Failed to initialize price service: %v", err)
@@ -188,11 +307,83 @@ func initializeGlobalServices() {
 		log.Printf("Warning: Failed to initialize indicator service: %v", err)
 	}
 
+	// Background jobs for heavy admin actions
+	services.InitJobManager(services.DefaultJobWorkers)
+
+	// Initialize realtime price streaming (polling starts on demand)
+	if err := services.InitRealtimePriceService(); err != nil {
+		log.Printf("Warning: Failed to initialize realtime price service: %v", err)
+	}
+
+	// Initialize intraday tick storage used by the realtime poller
+	if err := services.InitTickStore(db); err != nil {
+		log.Printf("Warning: Failed to initialize tick store: %v", err)
+	}
+
+	// Initialize delisted/newly listed stock tracking used by stock list syncs
+	if err := services.InitStockLifecycle(db); err != nil {
+		log.Printf("Warning: Failed to initialize stock lifecycle: %v", err)
+	}
+
+	// Initialize sync history recording for price, stock list and indicator syncs
+	if err := services.InitSyncHistory(db); err != nil {
+		log.Printf("Warning: Failed to initialize sync history: %v", err)
+	}
+
+	// Initialize FCM push delivery of user notifications if configured
+	if err := services.InitPushService(); err != nil {
+		log.Printf("Warning: Failed to initialize push notifications: %v", err)
+	}
+
+	// Initialize Telegram and email alerts to operators, and the health monitor raising them
+	if err := services.InitOperatorAlerts(); err != nil {
+		log.Printf("Warning: Failed to initialize operator alerts: %v", err)
+	}
+	if err := services.InitHealthMonitor(db); err != nil {
+		log.Printf("Warning: Failed to initialize health monitor: %v", err)
+	}
+
+	// Initialize profile change events to the CRM and billing webhooks and Pub/Sub if configured
+	if err := services.InitProfileEvents(); err != nil {
+		log.Printf("Warning: Failed to initialize profile events: %v", err)
+	}
+
 	// Initialize MongoDB client if configured
 	if err := services.InitMongoDBClient(); err != nil {
 		log.Printf("MongoDB not configured or failed to connect: %v", err)
 	}
 
+	// Initialize signal generation and rule evaluation
+	if err := signals.InitSignalService(); err != nil {
+		log.Printf("Warning: Failed to initialize signal service: %v", err)
+	}
+	if err := signals.InitConditionEvaluator(db); err != nil {
+		log.Printf("Warning: Failed to initialize condition evaluator: %v", err)
+	}
+	if err := services.LoadIndicatorProfiles(db); err != nil {
+		log.Printf("Warning: Failed to load indicator profiles, using defaults: %v", err)
+	}
+	if err := signals.LoadCompositeWeights(db); err != nil {
+		log.Printf("Warning: Failed to load composite strategy weights, using defaults: %v", err)
+	}
+	if err := services.LoadMaintenance(db); err != nil {
+		log.Printf("Warning: Failed to load maintenance mode and kill switches: %v", err)
+	}
+	if err := services.LoadDebugCapture(db); err != nil {
+		log.Printf("Warning: Failed to load debug capture settings: %v", err)
+	}
+	if err := signals.LoadCustomStrategies(db); err != nil {
+		log.Printf("Warning: Failed to load custom strategies: %v", err)
+	}
+	if err := signals.LoadStrategyPlugins(); err != nil {
+		log.Printf("Warning: Failed to load strategy plugins: %v", err)
+	}
+
+	// Initialize the daily data pipeline (scheduled by the background scheduler)
+	if err := pipeline.InitDataPipeline(db); err != nil {
+		log.Printf("Warning: Failed to initialize data pipeline: %v", err)
+	}
+
 	log.Println("Global services initialized")
 }
 
@@ -412,7 +603,7 @@ func requestLogger() gin.HandlerFunc {
 
 		// Only log errors or slow requests in production
 		if c.Writer.Status() >= 400 || duration > 1*time.Second {
-			log.Printf("%s %s %d %v", c.Request.Method, path, c.Writer.Status(), duration)
+			log.Printf("%s %s %d %v %s", c.Request.Method, path, c.Writer.Status(), duration, middleware.ClientIP(c))
 		}
 	}
 }
@@ -431,6 +622,14 @@ func gracefulShutdown(server *http.Server, jobScheduler *scheduler.Scheduler) {
 		jobScheduler.Stop()
 	}
 
+	// Stop realtime polling and close WebSocket clients
+	if services.GlobalRealtimeService != nil {
+		services.GlobalRealtimeService.Shutdown()
+	}
+
+	// Write the API usage logs still buffered
+	services.GlobalAPIUsage.Close()
+
 	// Create context with timeout for shutdown
 	// Cloud Run gives 10 seconds for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIUsageRecorder, when set, is called after each request of a group using APIUsage with the
+// response status and how long it took
+var APIUsageRecorder func(c *gin.Context, status int, duration time.Duration)
+
+// APIUsage reports the requests of matched routes to APIUsageRecorder. Unmatched paths are
+// skipped so that scans for unknown URLs don't fill the usage logs.
+func APIUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if APIUsageRecorder != nil && c.FullPath() != "" {
+			APIUsageRecorder(c, c.Writer.Status(), time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+const clientIPKey = "client_ip"
+
+// ConfigureClientIP makes the router resolve client IPs from X-Forwarded-For, walking it from
+// the right past the trusted proxies so entries a client prepends are ignored. X-Real-IP is not
+// consulted since any client can set it. Invalid proxies leave no proxy trusted and are returned.
+func ConfigureClientIP(router *gin.Engine, trustedProxies []string) error {
+	router.ForwardedByClientIP = true
+	router.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		_ = router.SetTrustedProxies(nil)
+		return fmt.Errorf("invalid trusted proxies %v: %w", trustedProxies, err)
+	}
+	return nil
+}
+
+// ClientIP returns the client IP of a request as resolved by ConfigureClientIP, with IPv4-mapped
+// IPv6 addresses in their IPv4 form, so rate limits, audit logs and request logs key callers
+// alike. It is resolved once per request.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	ip := c.ClientIP()
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	if ip == "" {
+		ip = "unknown"
+	}
+	c.Set(clientIPKey, ip)
+	return ip
+}
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CapturedRequest is a sampled request and its response, secrets redacted
+type CapturedRequest struct {
+	Time           time.Time         `json:"time"`
+	Method         string            `json:"method"`
+	Path           string            `json:"path"`
+	Route          string            `json:"route"`
+	Query          string            `json:"query,omitempty"`
+	Status         int               `json:"status"`
+	DurationMs     int64             `json:"duration_ms"`
+	ClientIP       string            `json:"client_ip"`
+	UserID         string            `json:"user_id,omitempty"`
+	RequestHeaders map[string]string `json:"request_headers"`
+	RequestBody    string            `json:"request_body,omitempty"`
+	ResponseBody   string            `json:"response_body,omitempty"`
+	Truncated      bool              `json:"truncated"` // a body was longer than the capture limit
+}
+
+// DebugCaptureSampler, when set, decides whether a request is captured: it returns how many bytes
+// of each body to keep, or 0 to leave the request alone
+var DebugCaptureSampler func(c *gin.Context) (maxBodyBytes int)
+
+// DebugCaptureBufferSize is how many captured requests an instance keeps
+const DebugCaptureBufferSize = 200
+
+// DebugCaptures holds the requests captured by this instance
+var DebugCaptures = NewCaptureBuffer(DebugCaptureBufferSize)
+
+// CaptureBuffer is a ring of captured requests, the newest replacing the oldest once full
+type CaptureBuffer struct {
+	mu      sync.Mutex
+	entries []CapturedRequest
+	next    int // slot of the next capture once the ring is full
+	size    int
+}
+
+// NewCaptureBuffer creates a ring keeping the last size captures
+func NewCaptureBuffer(size int) *CaptureBuffer {
+	return &CaptureBuffer{entries: make([]CapturedRequest, 0, size), size: size}
+}
+
+// Add stores a capture, dropping the oldest one when the ring is full
+func (b *CaptureBuffer) Add(entry CapturedRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) < b.size {
+		b.entries = append(b.entries, entry)
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % b.size
+}
+
+// List returns the captures newest first, only those of routes starting with route when it is
+// set, at most limit of them when it is positive
+func (b *CaptureBuffer) List(route string, limit int) []CapturedRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(b.entries)
+	out := make([]CapturedRequest, 0, n)
+	for i := 1; i <= n; i++ {
+		// Walk back from the last written slot; while the ring fills up next stays 0
+		entry := b.entries[(b.next-i+n)%n]
+		if route != "" && !strings.HasPrefix(entry.Route, route) {
+			continue
+		}
+		out = append(out, entry)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// Len returns the number of captures held
+func (b *CaptureBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Clear empties the ring and returns how many captures it held
+func (b *CaptureBuffer) Clear() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.entries)
+	b.entries, b.next = make([]CapturedRequest, 0, b.size), 0
+	return n
+}
+
+// redacted replaces secret values in captured requests
+const redacted = "[REDACTED]"
+
+// sensitiveKeyParts mark header, query and JSON keys whose values are never captured
+var sensitiveKeyParts = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey",
+	"api-key", "otp", "pin", "cvv", "card_number", "session", "signature",
+}
+
+// jwtPattern finds bearer tokens left in free text
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// sensitiveJSONPattern finds the values of sensitive keys in JSON that doesn't parse, e.g. cut at
+// the capture limit
+var sensitiveJSONPattern = regexp.MustCompile(`(?i)("[^"]*(?:` + strings.Join(sensitiveKeyParts, "|") +
+	`)[^"]*"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+// DebugCapture records the requests picked by DebugCaptureSampler with their bodies, redacted, in
+// DebugCaptures. Unmatched paths are skipped like in APIUsage.
+func DebugCapture() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if DebugCaptureSampler == nil || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		limit := DebugCaptureSampler(c)
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		var requestBody []byte
+		truncated := false
+		if c.Request.Body != nil {
+			// Read one byte past the limit to tell a truncated body, then hand the handler the
+			// whole body again
+			read, _ := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(read), c.Request.Body), c.Request.Body}
+			if len(read) > limit {
+				read, truncated = read[:limit], true
+			}
+			requestBody = read
+		}
+
+		writer := &limitedCaptureWriter{ResponseWriter: c.Writer, limit: limit}
+		c.Writer = writer
+		start := time.Now()
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		userID, _ := GetSupabaseUserFromContext(c)
+		DebugCaptures.Add(CapturedRequest{
+			Time:           start,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Route:          c.FullPath(),
+			Query:          redactQuery(c.Request.URL.RawQuery),
+			Status:         c.Writer.Status(),
+			DurationMs:     time.Since(start).Milliseconds(),
+			ClientIP:       ClientIP(c),
+			UserID:         userID,
+			RequestHeaders: redactHeaders(c),
+			RequestBody:    RedactBody(requestBody, c.ContentType()),
+			ResponseBody:   RedactBody(writer.body.Bytes(), writer.Header().Get("Content-Type")),
+			Truncated:      truncated || writer.truncated,
+		})
+	}
+}
+
+// RedactBody renders a captured body as text with secrets replaced: values of sensitive keys in
+// JSON and form bodies, and tokens anywhere. Binary bodies are reduced to their size.
+func RedactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			if out, err := json.Marshal(redactJSON(value)); err == nil {
+				return string(out)
+			}
+		}
+		text := sensitiveJSONPattern.ReplaceAllString(string(body), `$1"`+redacted+`"`)
+		return jwtPattern.ReplaceAllString(text, redacted)
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		return redactQuery(string(body))
+	case contentType != "" && !strings.HasPrefix(contentType, "text/") && !strings.Contains(contentType, "xml"):
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+	return jwtPattern.ReplaceAllString(string(body), redacted)
+}
+
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSensitiveKey(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	case string:
+		return jwtPattern.ReplaceAllString(v, redacted)
+	}
+	return value
+}
+
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return jwtPattern.ReplaceAllString(raw, redacted)
+	}
+	for key, items := range values {
+		for i := range items {
+			if isSensitiveKey(key) {
+				items[i] = redacted
+			} else {
+				items[i] = jwtPattern.ReplaceAllString(items[i], redacted)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+func redactHeaders(c *gin.Context) map[string]string {
+	headers := make(map[string]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		if isSensitiveKey(name) {
+			headers[name] = redacted
+			continue
+		}
+		headers[name] = jwtPattern.ReplaceAllString(strings.Join(values, ", "), redacted)
+	}
+	return headers
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCloser reads a replayed body and closes the original one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedCaptureWriter copies up to limit bytes of the body written to the client
+type limitedCaptureWriter struct {
+	gin.ResponseWriter
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *limitedCaptureWriter) capture(data []byte) {
+	room := w.limit - w.body.Len()
+	if len(data) > room {
+		data, w.truncated = data[:max(room, 0)], true
+	}
+	w.body.Write(data)
+}
+
+func (w *limitedCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *limitedCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderExperiments lists the caller's experiment variants as key=variant pairs
+const HeaderExperiments = "X-Experiments"
+
+const contextExperiments = "experiments"
+
+// ExperimentAssigner, when set, returns the caller's variants of the running experiments that
+// cover the request, and logs the exposures
+var ExperimentAssigner func(c *gin.Context) map[string]string
+
+// Experiments stores the caller's experiment variants in the context, where handlers read them
+// with ExperimentVariants, and in the X-Experiments header. It runs before CachedRoutes, whose
+// caches vary by ExperimentCacheKey where responses carry the variants.
+func Experiments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ExperimentAssigner != nil {
+			if variants := ExperimentAssigner(c); len(variants) > 0 {
+				c.Set(contextExperiments, variants)
+				c.Header(HeaderExperiments, ExperimentCacheKey(c))
+			}
+		}
+		c.Next()
+	}
+}
+
+// ExperimentVariants returns the caller's variants by experiment key, or nil
+func ExperimentVariants(c *gin.Context) map[string]string {
+	variants, _ := c.Get(contextExperiments)
+	v, _ := variants.(map[string]string)
+	return v
+}
+
+// ExperimentCacheKey returns the caller's variants as sorted key=variant pairs
+func ExperimentCacheKey(c *gin.Context) string {
+	variants := ExperimentVariants(c)
+	pairs := make([]string, 0, len(variants))
+	for key, variant := range variants {
+		pairs = append(pairs, key+"="+variant)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
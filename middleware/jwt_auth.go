@@ -26,6 +26,9 @@ type SupabaseClaims struct {
 	AMR           []AMREntry             `json:"amr"`
 	SessionID     string                 `json:"session_id"`
 	IsAnonymous   bool                   `json:"is_anonymous"`
+	// Set on impersonation tokens minted for support staff
+	ImpersonatedBy     string `json:"impersonated_by,omitempty"`
+	ImpersonationWrite bool   `json:"impersonation_write,omitempty"`
 }
 
 // AMREntry represents an authentication method reference
@@ -89,6 +92,11 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		c.Set("user_role", claims.Role)
 		c.Set("claims", claims)
 
+		if claims.ImpersonatedBy != "" {
+			handleImpersonation(c, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -125,6 +133,11 @@ func OptionalJWTAuthMiddleware() gin.HandlerFunc {
 		c.Set("user_role", claims.Role)
 		c.Set("claims", claims)
 
+		if claims.ImpersonatedBy != "" {
+			handleImpersonation(c, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -153,8 +166,7 @@ func AdminRoleMiddleware() gin.HandlerFunc {
 		}
 
 		// Check for admin role in app_metadata
-		role, _ := supabaseClaims.AppMetadata["role"].(string)
-		if role != "admin" && role != "superadmin" && supabaseClaims.Role != "service_role" {
+		if !hasAdminRole(supabaseClaims) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "forbidden",
 				"message": "Admin privileges required",
@@ -167,6 +179,23 @@ func AdminRoleMiddleware() gin.HandlerFunc {
 	}
 }
 
+// IsAdminRequest reports whether the request carries the token of a Supabase admin, on routes
+// behind the JWT middlewares
+func IsAdminRequest(c *gin.Context) bool {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return false
+	}
+	supabaseClaims, ok := claims.(*SupabaseClaims)
+	return ok && hasAdminRole(supabaseClaims)
+}
+
+// hasAdminRole checks for the admin role in app_metadata, or the service role
+func hasAdminRole(claims *SupabaseClaims) bool {
+	role, _ := claims.AppMetadata["role"].(string)
+	return role == "admin" || role == "superadmin" || claims.Role == "service_role"
+}
+
 // validateSupabaseToken validates a Supabase JWT token
 func validateSupabaseToken(tokenString string) (*SupabaseClaims, error) {
 	jwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
@@ -180,6 +209,9 @@ func validateSupabaseToken(tokenString string) (*SupabaseClaims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		if claims, ok := token.Claims.(*SupabaseClaims); ok && claims.Issuer == ImpersonationIssuer {
+			return impersonationKey(jwtSecret), nil
+		}
 		return []byte(jwtSecret), nil
 	})
 
@@ -197,9 +229,20 @@ func validateSupabaseToken(tokenString string) (*SupabaseClaims, error) {
 		return nil, errors.New("token has expired")
 	}
 
+	// Impersonation tokens must carry the impersonating admin, and only they may
+	if (claims.Issuer == ImpersonationIssuer) != (claims.ImpersonatedBy != "") {
+		return nil, errors.New("invalid impersonation token")
+	}
+
 	return claims, nil
 }
 
+// ParseSupabaseToken validates a Supabase JWT passed outside the Authorization header,
+// e.g. as a query parameter on WebSocket upgrades
+func ParseSupabaseToken(tokenString string) (*SupabaseClaims, error) {
+	return validateSupabaseToken(tokenString)
+}
+
 // VerifySupabaseUser verifies a user's token with Supabase Auth API
 func VerifySupabaseUser(token string) (*SupabaseUser, error) {
 	supabaseURL := os.Getenv("SUPABASE_URL")
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceState, when set, reports whether the API is in maintenance, the message for the
+// clients and how long until it is expected to end (zero when unknown)
+var MaintenanceState func() (enabled bool, message string, retryAfter time.Duration)
+
+// maintenanceRetryAfter is announced when the end of the maintenance is unknown
+const maintenanceRetryAfter = 5 * time.Minute
+
+// Maintenance answers 503 with the maintenance message while MaintenanceState reports
+// maintenance. Health endpoints, whose path contains /health, keep answering so the platform
+// doesn't restart the instance, and Supabase admins pass through to check the API. It runs after
+// the JWT middlewares.
+func Maintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if MaintenanceState == nil || strings.Contains(c.Request.URL.Path, "/health") {
+			c.Next()
+			return
+		}
+		enabled, message, retryAfter := MaintenanceState()
+		if !enabled || IsAdminRequest(c) {
+			c.Next()
+			return
+		}
+
+		if retryAfter <= 0 {
+			retryAfter = maintenanceRetryAfter
+		}
+		seconds := int(retryAfter.Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"success":     false,
+			"error":       message,
+			"maintenance": true,
+			"retry_after": seconds,
+			"timestamp":   time.Now().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest is logged for requests whose client disconnected before the response
+const StatusClientClosedRequest = 499
+
+// RequestTimeout bounds the request context. Handlers pass c.Request.Context() to the services,
+// which stop working when it times out or when the client disconnects. WebSocket upgrades and
+// Server-Sent Event streams are left alone because they outlive the handler's deadline.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.IsWebsocket() || c.GetHeader("Accept") == "text/event-stream" {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// AbortIfRequestDone responds to a request whose context ended while it was being served: 504 if
+// it timed out, and no body if the client went away. It reports whether the request was aborted,
+// so a handler whose service call failed can return instead of writing its usual error response.
+func AbortIfRequestDone(c *gin.Context) bool {
+	err := c.Request.Context().Err()
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Request %s %s timed out", c.Request.Method, c.Request.URL.Path)
+		abortWithError(c, http.StatusGatewayTimeout, "Request timed out")
+	default:
+		c.AbortWithStatus(StatusClientClosedRequest)
+	}
+	return true
+}
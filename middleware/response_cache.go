@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// responseCacheMaxEntries bounds one cache; expired entries are dropped first, then everything
+const responseCacheMaxEntries = 500
+
+// ResponseCache keeps the successful responses of expensive GET routes for a short while and
+// coalesces identical requests that arrive while one is being computed, so a burst of users
+// hitting the same screener runs it once. Requests are identical when they share the route, path
+// and query parameters, in any order, and the data version reported by the version func.
+type ResponseCache struct {
+	ttl     time.Duration
+	version func() string
+	skip    func(*gin.Context) bool
+	vary    func(*gin.Context) string
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	flight  singleflight.Group
+}
+
+// cachedResponse is a response as the handler wrote it. Headers holds only the headers set by the
+// handler chain, so request-scoped headers of earlier middleware are not replayed.
+type cachedResponse struct {
+	status    int
+	headers   http.Header
+	body      []byte
+	expiresAt time.Time
+	served    bool // false when the handler gave up because its request ended
+}
+
+// NewResponseCache creates a cache keeping responses for ttl. version, when set, is part of the
+// key, so a new version (e.g. a fresh indicator summary) misses the cache immediately.
+func NewResponseCache(ttl time.Duration, version func() string) *ResponseCache {
+	return &ResponseCache{ttl: ttl, version: version, entries: make(map[string]*cachedResponse)}
+}
+
+// SkipIf makes requests matching skip bypass the cache, e.g. ones asking for fresh results. It
+// returns rc for chaining.
+func (rc *ResponseCache) SkipIf(skip func(*gin.Context) bool) *ResponseCache {
+	rc.skip = skip
+	return rc
+}
+
+// VaryBy makes vary part of the key, for responses that differ between groups of callers, e.g.
+// experiment variants. It returns rc for chaining.
+func (rc *ResponseCache) VaryBy(vary func(*gin.Context) string) *ResponseCache {
+	rc.vary = vary
+	return rc
+}
+
+// Len returns the number of cached responses, expired ones included
+func (rc *ResponseCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.entries)
+}
+
+// CachedRoutes serves the matched route from its cache, keyed by method and full route path like
+// RouteLimits. Responses carry X-Cache: HIT when served from the cache, SHARED when they were
+// computed once for several concurrent requests and MISS otherwise.
+//
+// It runs before RouteLimits so cache hits and coalesced requests take no concurrency slot.
+func CachedRoutes(caches map[string]*ResponseCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc, ok := caches[c.Request.Method+" "+c.FullPath()]
+		if !ok || rc.skip != nil && rc.skip(c) {
+			c.Next()
+			return
+		}
+		rc.serve(c)
+	}
+}
+
+func (rc *ResponseCache) serve(c *gin.Context) {
+	key := rc.key(c)
+	if hit := rc.lookup(key); hit != nil {
+		hit.replay(c, "HIT")
+		return
+	}
+
+	led := false
+	value, _, _ := rc.flight.Do(key, func() (interface{}, error) {
+		led = true
+		response := rc.run(c)
+		if response.served && response.status == http.StatusOK {
+			rc.store(key, response)
+		}
+		return response, nil
+	})
+	if led {
+		return
+	}
+
+	// The leader's client went away or timed out before the work finished; compute it again
+	if response := value.(*cachedResponse); response.served {
+		response.replay(c, "SHARED")
+		return
+	}
+	c.Header("X-Cache", "MISS")
+	c.Next()
+}
+
+// run calls the rest of the handler chain and captures what it writes
+func (rc *ResponseCache) run(c *gin.Context) *cachedResponse {
+	before := c.Writer.Header().Clone()
+	c.Header("X-Cache", "MISS")
+
+	writer := &capturingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	headers := make(http.Header)
+	for name, values := range c.Writer.Header() {
+		if name != "X-Cache" && !slices.Equal(before[name], values) {
+			headers[name] = slices.Clone(values)
+		}
+	}
+	return &cachedResponse{
+		status:    c.Writer.Status(),
+		headers:   headers,
+		body:      writer.body.Bytes(),
+		expiresAt: time.Now().Add(rc.ttl),
+		served:    c.Request.Context().Err() == nil && c.Writer.Written(),
+	}
+}
+
+// key normalizes the request: query parameters sorted by name, empty ones dropped
+func (rc *ResponseCache) key(c *gin.Context) string {
+	query := c.Request.URL.Query()
+	names := make([]string, 0, len(query))
+	for name, values := range query {
+		if strings.Join(values, "") != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(c.Request.Method + " " + c.Request.URL.Path + "?")
+	for _, name := range names {
+		for _, value := range query[name] {
+			b.WriteString(url.QueryEscape(name) + "=" + url.QueryEscape(value) + "&")
+		}
+	}
+	if rc.version != nil {
+		b.WriteString("#" + rc.version())
+	}
+	if rc.vary != nil {
+		b.WriteString("|" + rc.vary(c))
+	}
+	return b.String()
+}
+
+func (rc *ResponseCache) lookup(key string) *cachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	return entry
+}
+
+func (rc *ResponseCache) store(key string, response *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.entries) >= responseCacheMaxEntries {
+		now := time.Now()
+		for k, entry := range rc.entries {
+			if now.After(entry.expiresAt) {
+				delete(rc.entries, k)
+			}
+		}
+		if len(rc.entries) >= responseCacheMaxEntries {
+			rc.entries = make(map[string]*cachedResponse)
+		}
+	}
+	rc.entries[key] = response
+}
+
+// replay writes a captured response and stops the handler chain
+func (r *cachedResponse) replay(c *gin.Context, cacheStatus string) {
+	for name, values := range r.headers {
+		c.Writer.Header()[name] = slices.Clone(values)
+	}
+	c.Header("X-Cache", cacheStatus)
+	c.Status(r.status)
+	c.Writer.Write(r.body)
+	c.Abort()
+}
+
+// capturingWriter copies the body written to the client
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
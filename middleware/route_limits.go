@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteLimit bounds one route: its handler's context times out after Timeout, when Rate is set
+// each caller gets a number of requests per window, and when Limiter is set the request must take
+// one of the limiter's slots before the handler runs
+type RouteLimit struct {
+	Timeout time.Duration
+	Rate    *RateLimiter
+	Limiter *ConcurrencyLimiter
+}
+
+// RouteLimits applies the limit of the matched route, keyed by method and full route path, e.g.
+// "GET /api/v1/signals". Routes without an entry run unbounded by this middleware.
+//
+// The timeout is enforced through the request context, which the expensive services observe.
+// A handler that ignores it still finishes, but one that has not written a response by the
+// deadline gets a 504 with the error envelope.
+func RouteLimits(limits map[string]RouteLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := limits[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if limit.Rate != nil {
+			if retry, ok := limit.Rate.allow(rateLimitKey(c)); !ok {
+				c.Header("Retry-After", strconv.Itoa(int(retry.Seconds()+1)))
+				abortWithError(c, http.StatusTooManyRequests, "Rate limit exceeded, please retry later")
+				return
+			}
+		}
+
+		if limit.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), limit.Timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		if limit.Limiter != nil {
+			release, ok := limit.Limiter.acquire(c.Request.Context())
+			if !ok {
+				if AbortIfRequestDone(c) {
+					return
+				}
+				c.Header("Retry-After", strconv.Itoa(int(limit.Limiter.wait.Seconds()+1)))
+				abortWithError(c, http.StatusServiceUnavailable, "Server busy, please retry shortly")
+				return
+			}
+			defer release()
+		}
+
+		c.Next()
+
+		if !c.Writer.Written() {
+			AbortIfRequestDone(c)
+		}
+	}
+}
+
+// ConcurrencyLimiter caps how many requests run at once across the routes that share it.
+// Requests over the cap queue for a free slot for a short while and are then rejected.
+type ConcurrencyLimiter struct {
+	name  string
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max concurrent requests, each waiting up to
+// wait for a slot
+func NewConcurrencyLimiter(name string, max int, wait time.Duration) *ConcurrencyLimiter {
+	if max < 1 {
+		max = 1
+	}
+	return &ConcurrencyLimiter{name: name, slots: make(chan struct{}, max), wait: wait}
+}
+
+// InFlight returns the number of requests holding a slot
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Capacity returns the maximum number of concurrent requests
+func (l *ConcurrencyLimiter) Capacity() int {
+	return cap(l.slots)
+}
+
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) (func(), bool) {
+	release := func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	case <-timer.C:
+		log.Printf("Concurrency limit %q reached (%d in flight), rejecting request", l.name, l.InFlight())
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// RateLimiter allows each caller a number of requests per fixed window across the routes that
+// share it. Callers are told apart by user, or by client IP when anonymous.
+type RateLimiter struct {
+	name   string
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	callers map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per caller every window
+func NewRateLimiter(name string, limit int, window time.Duration) *RateLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	return &RateLimiter{name: name, limit: limit, window: window, callers: make(map[string]*rateWindow)}
+}
+
+// allow counts a request of caller, returning false with the time until its window resets when
+// the caller is over the limit
+func (l *RateLimiter) allow(caller string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.callers[caller]
+	if !ok || now.Sub(w.start) >= l.window {
+		// Drop the expired windows now and then so one-off callers don't accumulate
+		if len(l.callers) >= rateLimiterSweepSize {
+			for key, old := range l.callers {
+				if now.Sub(old.start) >= l.window {
+					delete(l.callers, key)
+				}
+			}
+		}
+		l.callers[caller] = &rateWindow{start: now, count: 1}
+		return 0, true
+	}
+	if w.count >= l.limit {
+		log.Printf("Rate limit %q reached by %s", l.name, caller)
+		return l.window - now.Sub(w.start), false
+	}
+	w.count++
+	return 0, true
+}
+
+const rateLimiterSweepSize = 10_000
+
+// rateLimitKey identifies the caller of a request: the signed-in user, else the client IP
+func rateLimitKey(c *gin.Context) string {
+	if userID, err := GetSupabaseUserFromContext(c); err == nil && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + ClientIP(c)
+}
+
+// abortWithError writes the error envelope shared by the public API responses
+func abortWithError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"success":   false,
+		"error":     message,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
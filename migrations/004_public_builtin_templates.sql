@@ -0,0 +1,8 @@
+-- Publish the built-in signal templates in the marketplace
+-- The visibility column defaults to 'private', so built-in templates that existed before the
+-- marketplace was added were hidden from it. The server publishes them itself when it adds the
+-- column; run this once on databases where the column was added before that backfill existed.
+UPDATE signal_templates
+SET visibility = 'public'
+WHERE is_built_in = TRUE
+  AND (visibility IS NULL OR visibility = '' OR visibility = 'private');
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Admin notification levels
+const (
+	NotificationLevelInfo     = "info"
+	NotificationLevelWarning  = "warning"
+	NotificationLevelCritical = "critical"
+)
+
+// AdminNotification is a message raised by background jobs for admins to review
+type AdminNotification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Level     string     `gorm:"type:varchar(20);not null;default:'info'" json:"level"` // info, warning, critical
+	Category  string     `gorm:"type:varchar(50);index" json:"category"`                // e.g. rule_backtest
+	Title     string     `gorm:"not null" json:"title"`
+	Message   string     `json:"message"`
+	Metadata  string     `gorm:"type:jsonb" json:"metadata,omitempty"`
+	IsRead    bool       `gorm:"default:false;index" json:"is_read"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MigrateAdminNotificationModels runs database migrations for admin notifications
+func MigrateAdminNotificationModels(db *gorm.DB) error {
+	return db.AutoMigrate(&AdminNotification{})
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Analytics metrics rolled up per day
+const (
+	AnalyticsMetricDaily    = "daily"    // totals of the day; the key is empty and users are the active users
+	AnalyticsMetricEndpoint = "endpoint" // key "GET /api/v1/stocks/:symbol"
+	AnalyticsMetricStock    = "stock"    // key is the stock code viewed
+	AnalyticsMetricRule     = "rule"     // key is the ID of the rule that emitted signals
+)
+
+// APIUsageLog is one request to the public API. Logs are kept for the api_usage retention
+// period; the daily rollups in AnalyticsDaily are kept forever.
+type APIUsageLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	At         time.Time `gorm:"index;not null" json:"at"`
+	UserID     string    `gorm:"type:varchar(64);index" json:"user_id,omitempty"` // Supabase user ID, empty when anonymous
+	Method     string    `gorm:"type:varchar(10);not null" json:"method"`
+	Route      string    `gorm:"type:varchar(200);not null" json:"route"` // route pattern, not the requested path
+	StockCode  string    `gorm:"type:varchar(20)" json:"stock_code,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs int       `json:"duration_ms"`
+}
+
+// AnalyticsDaily is one metric of one day aggregated from the usage logs and signal history
+type AnalyticsDaily struct {
+	ID     uint      `gorm:"primaryKey" json:"-"`
+	Date   time.Time `gorm:"type:date;uniqueIndex:idx_analytics_daily_key;not null" json:"date"`
+	Metric string    `gorm:"type:varchar(20);uniqueIndex:idx_analytics_daily_key;not null" json:"metric"`
+	Key    string    `gorm:"column:metric_key;type:varchar(200);uniqueIndex:idx_analytics_daily_key" json:"key"`
+	Count  int64     `json:"count"`  // requests, views or emitted signals
+	Users  int64     `json:"users"`  // distinct signed-in users
+	Errors int64     `json:"errors"` // responses with status 500 and above
+	AvgMs  float64   `json:"avg_ms"`
+}
+
+// MigrateAnalyticsModels runs migrations for API usage logs and daily analytics
+func MigrateAnalyticsModels(db *gorm.DB) error {
+	return db.AutoMigrate(&APIUsageLog{}, &AnalyticsDaily{})
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Corporate event types
+const (
+	CorporateEventCashDividend  = "cash_dividend"
+	CorporateEventStockDividend = "stock_dividend"
+	CorporateEventBonusShares   = "bonus_shares"
+	CorporateEventRightsIssue   = "rights_issue"
+	CorporateEventAGM           = "agm"
+	CorporateEventOther         = "other"
+)
+
+// CorporateEvent is a dividend, shareholder meeting or share issue of a listed stock. EventDate
+// is the ex-rights date for dividends and issues and the meeting date for AGMs.
+type CorporateEvent struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	Code         string          `gorm:"type:varchar(20);not null;uniqueIndex:idx_corporate_event" json:"code"`
+	Type         string          `gorm:"type:varchar(20);not null;uniqueIndex:idx_corporate_event" json:"type"`
+	EventDate    time.Time       `gorm:"type:date;not null;uniqueIndex:idx_corporate_event;index" json:"event_date"`
+	RecordDate   *time.Time      `gorm:"type:date" json:"record_date,omitempty"`
+	PaymentDate  *time.Time      `gorm:"type:date" json:"payment_date,omitempty"`
+	CashPerShare decimal.Decimal `gorm:"type:decimal(15,2)" json:"cash_per_share"` // VND, cash dividends only
+	Ratio        string          `gorm:"type:varchar(50)" json:"ratio,omitempty"`  // e.g. "100:15" for stock dividends and issues
+	Title        string          `gorm:"type:varchar(500)" json:"title"`
+	Source       string          `gorm:"type:varchar(20)" json:"source"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// MigrateCorporateEventModels runs migrations for corporate events
+func MigrateCorporateEventModels(db *gorm.DB) error {
+	return db.AutoMigrate(&CorporateEvent{})
+}
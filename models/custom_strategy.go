@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CustomStrategy is a user-defined signal strategy scored by an expression over indicator fields
+type CustomStrategy struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	Name                string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	Description         string    `json:"description"`
+	ScoreExpression     string    `gorm:"type:text;not null" json:"score_expression"` // evaluates to a 0-100 strength
+	FilterExpression    string    `gorm:"type:text" json:"filter_expression"`         // optional; non-matching stocks are HOLD
+	BuyThreshold        float64   `json:"buy_threshold"`
+	StrongBuyThreshold  float64   `json:"strong_buy_threshold"`
+	SellThreshold       float64   `json:"sell_threshold"`
+	StrongSellThreshold float64   `json:"strong_sell_threshold"`
+	TargetPct           float64   `json:"target_pct"`    // target price above entry for buy signals
+	StopLossPct         float64   `json:"stop_loss_pct"` // stop loss below entry for buy signals
+	IsActive            bool      `json:"is_active"`
+	CreatedBy           uint      `json:"created_by"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// MigrateCustomStrategyModels runs migrations for user-defined strategies
+func MigrateCustomStrategyModels(db *gorm.DB) error {
+	return db.AutoMigrate(&CustomStrategy{})
+}
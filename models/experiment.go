@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Experiment statuses
+const (
+	ExperimentStatusDraft   = "draft"
+	ExperimentStatusRunning = "running"
+	ExperimentStatusStopped = "stopped"
+)
+
+// ExperimentVariant is one arm of an experiment with its share of the enrolled users
+type ExperimentVariant struct {
+	Key    string `json:"key"`
+	Weight int    `json:"weight"`
+}
+
+// Experiment is an A/B test of how part of the API presents its data, such as showing a signal's
+// confidence rather than its strength. Signed-in users calling a route under RoutePrefix are
+// enrolled by a hash of their ID, so they keep their variant on every device.
+type Experiment struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Key         string     `gorm:"type:varchar(50);uniqueIndex;not null" json:"key"` // e.g. signal_presentation
+	Name        string     `gorm:"not null" json:"name"`
+	Description string     `gorm:"type:text" json:"description"`
+	RoutePrefix string     `gorm:"type:varchar(200);not null" json:"route_prefix"` // e.g. /api/v1/signals
+	Variants    string     `gorm:"type:jsonb;not null" json:"variants"`            // []ExperimentVariant, the first is the control
+	TrafficPct  int        `gorm:"default:100" json:"traffic_pct"`                 // share of users enrolled
+	Status      string     `gorm:"type:varchar(20);default:'draft';index" json:"status"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	StoppedAt   *time.Time `json:"stopped_at,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// VariantList parses the experiment's variants
+func (e *Experiment) VariantList() ([]ExperimentVariant, error) {
+	var variants []ExperimentVariant
+	if e.Variants == "" {
+		return variants, nil
+	}
+	if err := json.Unmarshal([]byte(e.Variants), &variants); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// SetVariantList serializes variants into the experiment's Variants JSON
+func (e *Experiment) SetVariantList(variants []ExperimentVariant) error {
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return err
+	}
+	e.Variants = string(data)
+	return nil
+}
+
+// ExperimentExposure is one user enrolled in an experiment
+type ExperimentExposure struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	ExperimentID   uint      `gorm:"uniqueIndex:idx_experiment_exposure_user;not null" json:"experiment_id"`
+	UserID         string    `gorm:"type:varchar(64);uniqueIndex:idx_experiment_exposure_user;index;not null" json:"-"`
+	Variant        string    `gorm:"type:varchar(50);index;not null" json:"variant"`
+	Exposures      int64     `gorm:"default:1" json:"exposures"` // exposed requests, at most one per exposure window
+	FirstExposedAt time.Time `json:"first_exposed_at"`
+	LastExposedAt  time.Time `json:"last_exposed_at"`
+}
+
+// ExperimentEvent is an engagement event a client reports for an enrolled user, e.g. a click
+// on a signal
+type ExperimentEvent struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ExperimentID uint      `gorm:"index:idx_experiment_event;not null" json:"experiment_id"`
+	UserID       string    `gorm:"type:varchar(64);index;not null" json:"-"`
+	Variant      string    `gorm:"type:varchar(50);index:idx_experiment_event;not null" json:"variant"`
+	Event        string    `gorm:"type:varchar(50);index:idx_experiment_event;not null" json:"event"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MigrateExperimentModels runs migrations for experiments
+func MigrateExperimentModels(db *gorm.DB) error {
+	return db.AutoMigrate(&Experiment{}, &ExperimentExposure{}, &ExperimentEvent{})
+}
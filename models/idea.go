@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Trade idea directions
+const (
+	IdeaDirectionLong  = "long"
+	IdeaDirectionShort = "short"
+)
+
+// Moderation statuses of a trade idea
+const (
+	IdeaStatusPublished = "published"
+	IdeaStatusHidden    = "hidden" // by a moderator or enough reports; only the author still sees it
+)
+
+// Outcomes of a trade idea, following the signal lifecycle states
+const (
+	IdeaOutcomeOpen      = "open"
+	IdeaOutcomeHitTarget = "hit_target"
+	IdeaOutcomeStopped   = "stopped"
+	IdeaOutcomeExpired   = "expired"
+)
+
+// Idea is a trade idea a user posts on a stock, optionally linked to a signal rule or template.
+// The entry price is the stock's price when it was posted; the outcome is followed on the daily
+// bars after that until the target or stop is reached or the horizon passes.
+type Idea struct {
+	ID            uint    `gorm:"primaryKey" json:"id"`
+	AuthorID      string  `gorm:"type:varchar(64);index;not null" json:"author_id"` // Supabase user ID
+	AuthorName    string  `json:"author_name"`
+	StockCode     string  `gorm:"type:varchar(20);index;not null" json:"stock_code"`
+	Direction     string  `gorm:"type:varchar(10);not null" json:"direction"` // long, short
+	Title         string  `gorm:"type:varchar(150);not null" json:"title"`
+	Body          string  `gorm:"type:text" json:"body"`
+	RuleID        uint    `gorm:"index" json:"rule_id,omitempty"`
+	TemplateID    uint    `gorm:"index" json:"template_id,omitempty"`
+	EntryPrice    float64 `gorm:"type:decimal(15,2)" json:"entry_price"`
+	TargetPrice   float64 `gorm:"type:decimal(15,2)" json:"target_price,omitempty"`
+	StopLossPrice float64 `gorm:"type:decimal(15,2)" json:"stop_loss_price,omitempty"`
+	HorizonDays   int     `gorm:"not null" json:"horizon_days"` // trading sessions
+	// Outcome
+	Outcome        string     `gorm:"type:varchar(20);default:'open';index" json:"outcome"`
+	LastPrice      float64    `gorm:"type:decimal(15,2)" json:"last_price"`
+	ExitPrice      float64    `gorm:"type:decimal(15,2)" json:"exit_price,omitempty"`
+	ReturnPct      float64    `json:"return_pct"` // in the idea's direction, at the exit or the latest close
+	MaxGainPct     float64    `json:"max_gain_pct"`
+	MaxDrawdownPct float64    `json:"max_drawdown_pct"`
+	Sessions       int        `json:"sessions"` // trading sessions since posting
+	EvaluatedAt    *time.Time `json:"evaluated_at,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	// Community
+	LikeCount   int `gorm:"default:0" json:"like_count"`
+	FollowCount int `gorm:"default:0" json:"follow_count"`
+	ReportCount int `gorm:"default:0" json:"-"`
+	// Moderation
+	Status           string         `gorm:"type:varchar(20);default:'published';index" json:"status"`
+	ModerationReason string         `json:"moderation_reason,omitempty"`
+	ModeratedBy      string         `json:"-"`
+	ModeratedAt      *time.Time     `json:"moderated_at,omitempty"`
+	Liked            bool           `gorm:"-" json:"liked"`     // by the caller
+	Following        bool           `gorm:"-" json:"following"` // by the caller
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// IdeaLike is one user's like of an idea
+type IdeaLike struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	IdeaID    uint      `gorm:"uniqueIndex:idx_idea_like_user;not null" json:"idea_id"`
+	UserID    string    `gorm:"type:varchar(64);uniqueIndex:idx_idea_like_user;index;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IdeaFollow is one user following an idea to be notified of its outcome
+type IdeaFollow struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	IdeaID    uint      `gorm:"uniqueIndex:idx_idea_follow_user;not null" json:"idea_id"`
+	UserID    string    `gorm:"type:varchar(64);uniqueIndex:idx_idea_follow_user;index;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IdeaReport is one user's report of an idea to the moderators
+type IdeaReport struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	IdeaID    uint      `gorm:"uniqueIndex:idx_idea_report_user;not null" json:"idea_id"`
+	UserID    string    `gorm:"type:varchar(64);uniqueIndex:idx_idea_report_user;index;not null" json:"user_id"`
+	Reason    string    `gorm:"type:varchar(300)" json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MigrateIdeaModels runs migrations for trade ideas
+func MigrateIdeaModels(db *gorm.DB) error {
+	return db.AutoMigrate(&Idea{}, &IdeaLike{}, &IdeaFollow{}, &IdeaReport{})
+}
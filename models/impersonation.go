@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImpersonationSession records an impersonation token minted for an admin to act as a user
+type ImpersonationSession struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	TokenID       string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"token_id"` // jti of the token
+	AdminUsername string     `gorm:"index;not null" json:"admin_username"`
+	TargetUserID  string     `gorm:"type:varchar(64);index;not null" json:"target_user_id"` // Supabase user ID
+	TargetEmail   string     `json:"target_email"`
+	Reason        string     `gorm:"type:text;not null" json:"reason"`
+	ReadOnly      bool       `gorm:"default:true" json:"read_only"`
+	IPAddress     string     `json:"ip_address"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RequestCount  int        `gorm:"default:0" json:"request_count"`
+	LastRequestAt *time.Time `json:"last_request_at"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+}
+
+// ImpersonationRequest is one API request made with an impersonation token
+type ImpersonationRequest struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TokenID   string    `gorm:"type:varchar(64);index;not null" json:"token_id"`
+	Method    string    `gorm:"type:varchar(10)" json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MigrateImpersonationModels runs migrations for the impersonation audit log
+func MigrateImpersonationModels(db *gorm.DB) error {
+	return db.AutoMigrate(&ImpersonationSession{}, &ImpersonationRequest{})
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Health checks watched by the backend health monitor
+const (
+	HealthCheckPriceSync   = "price_sync"   // failed share of the latest price sync
+	HealthCheckServerError = "server_error" // 5xx share of API responses
+	HealthCheckMongoDB     = "mongodb"      // failed MongoDB commands and lost connections
+)
+
+// Incident severities and statuses
+const (
+	IncidentSeverityWarning  = "warning"
+	IncidentSeverityCritical = "critical"
+
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+)
+
+// Incident is a period when a health check was over its threshold. A check has at most one open
+// incident; it is resolved on the first check back under the threshold.
+type Incident struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Check      string     `gorm:"column:check_name;type:varchar(50);index;not null" json:"check"`
+	Severity   string     `gorm:"type:varchar(20);not null" json:"severity"` // highest severity reached
+	Status     string     `gorm:"type:varchar(20);index;not null;default:'open'" json:"status"`
+	Title      string     `gorm:"not null" json:"title"`
+	Message    string     `gorm:"type:text" json:"message"` // latest reading
+	Value      float64    `json:"value"`                    // latest value of the check
+	Threshold  float64    `json:"threshold"`
+	OpenedAt   time.Time  `gorm:"index" json:"opened_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"` // last operator alert
+}
+
+// MigrateIncidentModels runs migrations for health incidents
+func MigrateIncidentModels(db *gorm.DB) error {
+	return db.AutoMigrate(&Incident{})
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IntradayTick is one price/volume observation captured by the realtime poller
+type IntradayTick struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	Code       string    `gorm:"size:10;index:idx_tick_code_time" json:"code"`
+	TradedAt   time.Time `gorm:"index:idx_tick_code_time;index" json:"traded_at"`
+	Price      float64   `json:"price"`
+	CumVolume  float64   `json:"cum_volume"`  // session matched volume at capture time
+	TickVolume float64   `json:"tick_volume"` // volume matched since the previous tick
+}
+
+// MigrateIntradayTickModels runs migrations for intraday tick models
+func MigrateIntradayTickModels(db *gorm.DB) error {
+	return db.AutoMigrate(&IntradayTick{})
+}
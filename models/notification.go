@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User notification categories
+const (
+	NotificationCategorySignalAlert = "signal_alert" // a signal subscription matched new stocks
+	NotificationCategoryPriceAlert  = "price_alert"  // a price alert triggered
+	NotificationCategoryMembership  = "membership"   // subscription started, cancelled or expired
+	NotificationCategoryReport      = "report"       // a scheduled report is ready
+	NotificationCategoryWatchlist   = "watchlist"    // a shared watchlist invitation or change
+	NotificationCategoryIdea        = "idea"         // a followed trade idea closed or was moderated
+	NotificationCategoryStrategist  = "strategist"   // a followed strategist's rule emitted a signal
+	NotificationCategorySystem      = "system"
+)
+
+// NotificationRetentionDays is how long read notifications are kept
+const NotificationRetentionDays = 90
+
+// Notification is one entry of a user's in-app inbox
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    string     `gorm:"type:varchar(64);index:idx_notification_user_created;not null" json:"user_id"` // Supabase user ID
+	Category  string     `gorm:"type:varchar(30);index" json:"category"`
+	Title     string     `gorm:"not null" json:"title"`
+	Message   string     `gorm:"type:text" json:"message"`
+	Data      string     `gorm:"type:jsonb" json:"data"` // category-specific payload, e.g. the matched stocks
+	ReadAt    *time.Time `gorm:"index" json:"read_at"`
+	CreatedAt time.Time  `gorm:"index:idx_notification_user_created" json:"created_at"`
+}
+
+// Device platforms for push notifications
+const (
+	DevicePlatformAndroid = "android"
+	DevicePlatformIOS     = "ios"
+	DevicePlatformWeb     = "web"
+)
+
+// DeviceToken is an FCM registration token of one of a user's devices
+type DeviceToken struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     string    `gorm:"type:varchar(64);index;not null" json:"user_id"` // Supabase user ID
+	Token      string    `gorm:"type:varchar(512);uniqueIndex;not null" json:"-"`
+	Platform   string    `gorm:"type:varchar(10)" json:"platform"`
+	AppVersion string    `gorm:"type:varchar(30)" json:"app_version"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// NotificationPreference holds a user's push settings. During quiet hours notifications only go
+// to the inbox; a window may wrap midnight, e.g. 22:00-07:00.
+type NotificationPreference struct {
+	ID              uint      `gorm:"primaryKey" json:"-"`
+	UserID          string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	PushEnabled     bool      `gorm:"default:true" json:"push_enabled"`
+	QuietHoursStart string    `gorm:"type:varchar(5)" json:"quiet_hours_start"` // HH:MM, empty for none
+	QuietHoursEnd   string    `gorm:"type:varchar(5)" json:"quiet_hours_end"`   // HH:MM
+	Timezone        string    `gorm:"type:varchar(50)" json:"timezone"`         // IANA name, empty for market time
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// MigrateNotificationModels runs migrations for user notifications and push delivery
+func MigrateNotificationModels(db *gorm.DB) error {
+	return db.AutoMigrate(&Notification{}, &DeviceToken{}, &NotificationPreference{})
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderBookSnapshot stores the end-of-day top-of-book depth and intraday spread statistics of a stock
+type OrderBookSnapshot struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Code         string    `gorm:"size:10;uniqueIndex:idx_orderbook_code_date" json:"code"`
+	Date         string    `gorm:"size:10;uniqueIndex:idx_orderbook_code_date" json:"date"` // YYYY-MM-DD
+	Bids         string    `gorm:"type:jsonb" json:"bids"`                                  // last top-of-book bid levels
+	Asks         string    `gorm:"type:jsonb" json:"asks"`
+	BestBid      float64   `json:"best_bid"`
+	BestAsk      float64   `json:"best_ask"`
+	Spread       float64   `json:"spread"`
+	SpreadPct    float64   `json:"spread_pct"` // closing spread as percent of mid price
+	AvgSpreadPct float64   `json:"avg_spread_pct"`
+	MinSpreadPct float64   `json:"min_spread_pct"`
+	MaxSpreadPct float64   `json:"max_spread_pct"`
+	Samples      int       `json:"samples"` // intraday depth polls the averages are based on
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MigrateOrderBookModels runs migrations for order book models
+func MigrateOrderBookModels(db *gorm.DB) error {
+	return db.AutoMigrate(&OrderBookSnapshot{})
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles on a shared watchlist; the owner is not a member row
+const (
+	SharedWatchlistRoleOwner  = "owner"
+	SharedWatchlistRoleEditor = "editor" // adds and removes stocks, renames the list
+	SharedWatchlistRoleViewer = "viewer" // reads only
+)
+
+// Statuses of a shared watchlist member
+const (
+	SharedWatchlistMemberInvited = "invited"
+	SharedWatchlistMemberActive  = "active"
+)
+
+// SharedWatchlist is a watchlist a premium user shares with other users. Version goes up on every
+// change of its name or stocks, so an edit made against an older version is rejected instead of
+// overwriting someone else's change.
+type SharedWatchlist struct {
+	ID          uint                    `gorm:"primaryKey" json:"id"`
+	OwnerUserID string                  `gorm:"type:varchar(64);index;not null" json:"owner_user_id"` // Supabase user ID
+	Name        string                  `gorm:"type:varchar(100);not null" json:"name"`
+	Version     int                     `gorm:"not null;default:1" json:"version"`
+	Items       []SharedWatchlistItem   `gorm:"foreignKey:WatchlistID;constraint:OnDelete:CASCADE" json:"items,omitempty"`
+	Members     []SharedWatchlistMember `gorm:"foreignKey:WatchlistID;constraint:OnDelete:CASCADE" json:"members,omitempty"`
+	Role        string                  `gorm:"-" json:"role"` // the caller's role
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+// SharedWatchlistItem is a stock on a shared watchlist
+type SharedWatchlistItem struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	WatchlistID uint      `gorm:"uniqueIndex:idx_shared_watchlist_item;not null" json:"-"`
+	StockCode   string    `gorm:"type:varchar(20);uniqueIndex:idx_shared_watchlist_item;not null" json:"stock_code"`
+	Note        string    `gorm:"type:varchar(500)" json:"note"`
+	AddedBy     string    `gorm:"type:varchar(64)" json:"added_by"` // Supabase user ID
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SharedWatchlistMember is a user invited to a shared watchlist by email; UserID is set when the
+// invitation is accepted
+type SharedWatchlistMember struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WatchlistID uint       `gorm:"uniqueIndex:idx_shared_watchlist_member_email;not null" json:"watchlist_id"`
+	Email       string     `gorm:"type:varchar(255);uniqueIndex:idx_shared_watchlist_member_email;index;not null" json:"email"`
+	UserID      string     `gorm:"type:varchar(64);index" json:"user_id,omitempty"` // Supabase user ID
+	Role        string     `gorm:"type:varchar(10);not null" json:"role"`
+	Status      string     `gorm:"type:varchar(10);not null" json:"status"`
+	InvitedBy   string     `gorm:"type:varchar(64)" json:"invited_by"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// MigrateSharedWatchlistModels runs migrations for shared watchlists
+func MigrateSharedWatchlistModels(db *gorm.DB) error {
+	return db.AutoMigrate(&SharedWatchlist{}, &SharedWatchlistItem{}, &SharedWatchlistMember{})
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -31,24 +32,38 @@ func (c ConditionOperator) String() string {
 type IndicatorType string
 
 const (
-	IndicatorRSI           IndicatorType = "RSI"
-	IndicatorMACD          IndicatorType = "MACD"
-	IndicatorMACDSignal    IndicatorType = "MACD_SIGNAL"
-	IndicatorMACDHistogram IndicatorType = "MACD_HISTOGRAM"
-	IndicatorMA10          IndicatorType = "MA10"
-	IndicatorMA30          IndicatorType = "MA30"
-	IndicatorMA50          IndicatorType = "MA50"
-	IndicatorMA200         IndicatorType = "MA200"
-	IndicatorRS3D          IndicatorType = "RS_3D"
-	IndicatorRS1M          IndicatorType = "RS_1M"
-	IndicatorRS3M          IndicatorType = "RS_3M"
-	IndicatorRS1Y          IndicatorType = "RS_1Y"
-	IndicatorRSAvg         IndicatorType = "RS_AVG"
-	IndicatorVolume        IndicatorType = "VOLUME"
-	IndicatorVolRatio      IndicatorType = "VOL_RATIO"
-	IndicatorPrice         IndicatorType = "PRICE"
-	IndicatorPriceChange   IndicatorType = "PRICE_CHANGE"
-	IndicatorTradingValue  IndicatorType = "TRADING_VALUE"
+	IndicatorRSI               IndicatorType = "RSI"
+	IndicatorMACD              IndicatorType = "MACD"
+	IndicatorMACDSignal        IndicatorType = "MACD_SIGNAL"
+	IndicatorMACDHistogram     IndicatorType = "MACD_HISTOGRAM"
+	IndicatorMA10              IndicatorType = "MA10"
+	IndicatorMA30              IndicatorType = "MA30"
+	IndicatorMA50              IndicatorType = "MA50"
+	IndicatorMA200             IndicatorType = "MA200"
+	IndicatorRS3D              IndicatorType = "RS_3D"
+	IndicatorRS1M              IndicatorType = "RS_1M"
+	IndicatorRS3M              IndicatorType = "RS_3M"
+	IndicatorRS1Y              IndicatorType = "RS_1Y"
+	IndicatorRSAvg             IndicatorType = "RS_AVG"
+	IndicatorVolume            IndicatorType = "VOLUME"
+	IndicatorVolRatio          IndicatorType = "VOL_RATIO"
+	IndicatorPrice             IndicatorType = "PRICE"
+	IndicatorPriceChange       IndicatorType = "PRICE_CHANGE"
+	IndicatorTradingValue      IndicatorType = "TRADING_VALUE"
+	IndicatorPctFrom52WHigh    IndicatorType = "PCT_FROM_52W_HIGH"
+	IndicatorPctFrom52WLow     IndicatorType = "PCT_FROM_52W_LOW"
+	IndicatorGapPercent        IndicatorType = "GAP_PERCENT"
+	IndicatorOBVTrend          IndicatorType = "OBV_TREND"
+	IndicatorADTrend           IndicatorType = "AD_TREND"
+	IndicatorTenkan            IndicatorType = "ICHIMOKU_TENKAN"
+	IndicatorKijun             IndicatorType = "ICHIMOKU_KIJUN"
+	IndicatorSenkouA           IndicatorType = "ICHIMOKU_SENKOU_A"
+	IndicatorSenkouB           IndicatorType = "ICHIMOKU_SENKOU_B"
+	IndicatorChikou            IndicatorType = "ICHIMOKU_CHIKOU"
+	IndicatorPriceAboveCloud   IndicatorType = "PRICE_ABOVE_CLOUD"   // 1 when true, else 0
+	IndicatorTKCrossBull       IndicatorType = "TK_CROSS_BULL"       // 1 when true, else 0
+	IndicatorPctFromSupport    IndicatorType = "PCT_FROM_SUPPORT"    // 100 when there is no support level
+	IndicatorPctFromResistance IndicatorType = "PCT_FROM_RESISTANCE" // 100 when there is no resistance level
 )
 
 // String returns the string representation of IndicatorType
@@ -56,6 +71,10 @@ func (i IndicatorType) String() string {
 	return string(i)
 }
 
+// SignalTrashRetentionDays is how long soft-deleted groups, conditions, rules
+// and templates stay in the trash before being purged
+const SignalTrashRetentionDays = 30
+
 // LogicalOperator for combining conditions
 type LogicalOperator string
 
@@ -72,15 +91,17 @@ func (l LogicalOperator) String() string {
 // SignalConditionGroup represents a group of conditions that can be reused
 type SignalConditionGroup struct {
 	ID          uint              `gorm:"primaryKey" json:"id"`
-	Name        string            `gorm:"uniqueIndex;not null" json:"name"`
+	Name        string            `gorm:"uniqueIndex:idx_signal_condition_groups_live_name,where:deleted_at IS NULL;not null" json:"name"` // Unique among rows not in the trash
 	Description string            `json:"description"`
 	SignalType  string            `json:"signal_type"` // BUY, SELL, HOLD, ALERT
 	IsActive    bool              `gorm:"default:true" json:"is_active"`
 	Priority    int               `gorm:"default:0" json:"priority"` // Higher priority = evaluated first
 	Conditions  []SignalCondition `gorm:"foreignKey:GroupID" json:"conditions,omitempty"`
 	CreatedBy   uint              `json:"created_by"`
+	OwnerUserID string            `gorm:"type:varchar(64);index" json:"owner_user_id,omitempty"` // Set for user-owned groups
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 // SignalCondition represents a single condition rule
@@ -100,12 +121,13 @@ type SignalCondition struct {
 	OrderIndex       int               `gorm:"default:0" json:"order_index"`
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 // SignalRule represents a complete trading rule that combines condition groups
 type SignalRule struct {
 	ID              uint            `gorm:"primaryKey" json:"id"`
-	Name            string          `gorm:"uniqueIndex;not null" json:"name"`
+	Name            string          `gorm:"uniqueIndex:idx_signal_rules_live_name,where:deleted_at IS NULL;not null" json:"name"` // Unique among rows not in the trash
 	Description     string          `json:"description"`
 	SignalType      string          `gorm:"not null" json:"signal_type"` // BUY, SELL, ALERT
 	StrategyType    string          `json:"strategy_type"`               // momentum, trend, etc.
@@ -115,27 +137,85 @@ type SignalRule struct {
 	IsActive        bool            `gorm:"default:true" json:"is_active"`
 	Priority        int             `gorm:"default:0" json:"priority"`
 	ConditionGroups string          `gorm:"type:jsonb" json:"condition_groups"` // JSON array of group IDs with logic
+	// Signal deduplication
+	CooldownHours int `gorm:"default:24" json:"cooldown_hours"` // Minimum hours between signals for the same stock
+	ExpiryDays    int `gorm:"default:30" json:"expiry_days"`    // Trading days before an active signal expires
 	// Backtest Performance
 	BacktestWinRate     decimal.Decimal `gorm:"type:decimal(5,2)" json:"backtest_win_rate"`
 	BacktestAvgReturn   decimal.Decimal `gorm:"type:decimal(10,4)" json:"backtest_avg_return"`
 	BacktestTotalTrades int             `json:"backtest_total_trades"`
 	LastBacktestAt      *time.Time      `json:"last_backtest_at"`
 	CreatedBy           uint            `json:"created_by"`
+	OwnerUserID         string          `gorm:"type:varchar(64);index" json:"owner_user_id,omitempty"` // Set for user-owned rules
+	SourceTemplateID    *uint           `gorm:"index" json:"source_template_id,omitempty"`             // Template this rule was cloned from
 	CreatedAt           time.Time       `json:"created_at"`
 	UpdatedAt           time.Time       `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// RuleGroupRef is one entry of SignalRule.ConditionGroups
+type RuleGroupRef struct {
+	GroupID  uint   `json:"group_id"`
+	Logic    string `json:"logic"` // AND, OR
+	Required bool   `json:"required"`
+}
+
+// GroupRefs parses the rule's ConditionGroups JSON
+func (r *SignalRule) GroupRefs() ([]RuleGroupRef, error) {
+	var refs []RuleGroupRef
+	if r.ConditionGroups == "" {
+		return refs, nil
+	}
+	if err := json.Unmarshal([]byte(r.ConditionGroups), &refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// SetGroupRefs serializes refs into the rule's ConditionGroups JSON
+func (r *SignalRule) SetGroupRefs(refs []RuleGroupRef) error {
+	if refs == nil {
+		refs = []RuleGroupRef{}
+	}
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	r.ConditionGroups = string(data)
+	return nil
 }
 
-// SignalAlert represents an alert configuration
+// ReferencesGroup reports whether the rule's ConditionGroups includes groupID
+func (r *SignalRule) ReferencesGroup(groupID uint) bool {
+	refs, err := r.GroupRefs()
+	if err != nil {
+		return false
+	}
+	for _, ref := range refs {
+		if ref.GroupID == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalAlert represents an alert configuration. It fires when a stock starts matching its rule
+// or template; end-user subscriptions carry the subscriber's UserID.
 type SignalAlert struct {
 	ID              uint        `gorm:"primaryKey" json:"id"`
 	Name            string      `gorm:"not null" json:"name"`
 	RuleID          uint        `gorm:"index" json:"rule_id"`
 	Rule            *SignalRule `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
-	StockSymbol     string      `gorm:"type:varchar(20)" json:"stock_symbol"` // Empty = all stocks
-	AlertType       string      `gorm:"not null" json:"alert_type"`           // email, push, webhook
+	TemplateID      uint        `gorm:"index" json:"template_id"`                        // Set instead of RuleID for template alerts
+	UserID          string      `gorm:"type:varchar(64);index" json:"user_id,omitempty"` // Supabase user ID of the subscriber
+	StockSymbol     string      `gorm:"type:varchar(20)" json:"stock_symbol"`            // Empty = all stocks
+	AlertType       string      `gorm:"not null" json:"alert_type"`                      // in_app, email, push, webhook
 	WebhookURL      string      `json:"webhook_url,omitempty"`
 	IsActive        bool        `gorm:"default:true" json:"is_active"`
-	Cooldown        int         `gorm:"default:60" json:"cooldown"` // Minutes between alerts
+	Cooldown        int         `gorm:"default:60" json:"cooldown"`   // Minutes between alerts
+	MaxPerDay       int         `gorm:"default:0" json:"max_per_day"` // Notifications per trading day, 0 = unlimited
+	MatchedStocks   string      `gorm:"type:text" json:"-"`           // Comma-separated stocks already alerted and still matching
+	LastCheckedAt   *time.Time  `json:"last_checked_at"`
 	LastTriggeredAt *time.Time  `json:"last_triggered_at"`
 	TriggerCount    int         `gorm:"default:0" json:"trigger_count"`
 	CreatedBy       uint        `json:"created_by"`
@@ -143,6 +223,14 @@ type SignalAlert struct {
 	UpdatedAt       time.Time   `json:"updated_at"`
 }
 
+// Signal alert delivery channels
+const (
+	SignalAlertTypeInApp   = "in_app" // Read through the notifications API
+	SignalAlertTypeEmail   = "email"
+	SignalAlertTypePush    = "push"
+	SignalAlertTypeWebhook = "webhook"
+)
+
 // SignalAlertHistory stores triggered alert history
 type SignalAlertHistory struct {
 	ID          uint            `gorm:"primaryKey" json:"id"`
@@ -163,6 +251,7 @@ type SignalPerformance struct {
 	ID                uint            `gorm:"primaryKey" json:"id"`
 	RuleID            uint            `gorm:"index" json:"rule_id"`
 	Rule              *SignalRule     `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
+	TemplateID        uint            `gorm:"index" json:"template_id"` // Set when the signal came from a template
 	StockSymbol       string          `gorm:"type:varchar(20);index:idx_perf_stock_date" json:"stock_symbol"`
 	SignalDate        time.Time       `gorm:"index:idx_perf_stock_date" json:"signal_date"`
 	SignalType        string          `json:"signal_type"`
@@ -184,17 +273,97 @@ type SignalPerformance struct {
 	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
-// SignalTemplate provides preset condition templates
-type SignalTemplate struct {
+// Signal lifecycle states
+const (
+	SignalStateActive     = "active"
+	SignalStateHitTarget  = "hit_target"
+	SignalStateStopped    = "stopped"
+	SignalStateExpired    = "expired"
+	SignalStateSuperseded = "superseded" // replaced by a newer signal after the cool-down
+)
+
+// SignalStates lists every lifecycle state
+var SignalStates = []string{SignalStateActive, SignalStateHitTarget, SignalStateStopped, SignalStateExpired, SignalStateSuperseded}
+
+// SignalHistory records every signal a rule or template emitted for a stock; it is used to
+// suppress repeated signals until the cool-down passes or the previous signal closes
+type SignalHistory struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	RuleID        uint            `gorm:"index:idx_signal_history_source" json:"rule_id"`
+	TemplateID    uint            `gorm:"index:idx_signal_history_source" json:"template_id"`
+	StockSymbol   string          `gorm:"type:varchar(20);index:idx_signal_history_source" json:"stock_symbol"`
+	SignalType    string          `json:"signal_type"`
+	Score         int             `json:"score"`
+	Price         decimal.Decimal `gorm:"type:decimal(15,2)" json:"price" unit:"price"`
+	TargetPrice   decimal.Decimal `gorm:"type:decimal(15,2)" json:"target_price" unit:"price"`
+	StopLossPrice decimal.Decimal `gorm:"type:decimal(15,2)" json:"stop_loss_price" unit:"price"`
+	State         string          `gorm:"type:varchar(20);index;default:'active'" json:"state"` // active, hit_target, stopped, expired, superseded
+	EmittedAt     time.Time       `gorm:"index" json:"emitted_at"`
+	ExpiresAt     time.Time       `json:"expires_at"` // end of the expiry horizon in trading days
+	ClosedAt      *time.Time      `json:"closed_at"`  // when the signal left the active state
+	ClosePrice    decimal.Decimal `gorm:"type:decimal(15,2)" json:"close_price" unit:"price"`
+	PerformanceID uint            `json:"performance_id"` // SignalPerformance row tracking the outcome
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Template visibility values
+const (
+	TemplateVisibilityPrivate = "private"
+	TemplateVisibilityPublic  = "public"
+)
+
+// SignalRuleBacktestRun stores the metrics of one automated rolling backtest of a rule
+type SignalRuleBacktestRun struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`                              // momentum, trend, reversal, breakout, custom
-	Conditions  string    `gorm:"type:jsonb;not null" json:"conditions"` // JSON template
-	Popularity  int       `gorm:"default:0" json:"popularity"`
-	IsBuiltIn   bool      `gorm:"default:false" json:"is_built_in"`
+	RuleID      uint      `gorm:"index" json:"rule_id"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	TotalTrades int       `json:"total_trades"`
+	Wins        int       `json:"wins"`
+	WinRate     float64   `json:"win_rate"`     // percent
+	AvgReturn   float64   `json:"avg_return"`   // percent per trade
+	TotalReturn float64   `json:"total_return"` // sum of per-trade returns, percent
+	MaxLoss     float64   `json:"max_loss"`     // worst trade, percent
+	AvgHoldDays float64   `json:"avg_hold_days"`
+	Degraded    bool      `gorm:"index" json:"degraded"`
+	Reason      string    `json:"reason,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SignalTemplate provides preset condition templates
+type SignalTemplate struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"uniqueIndex:idx_signal_templates_live_name,where:deleted_at IS NULL;not null" json:"name"` // Unique among rows not in the trash
+	Description string `json:"description"`
+	Category    string `json:"category"`                              // momentum, trend, reversal, breakout, custom
+	Conditions  string `gorm:"type:jsonb;not null" json:"conditions"` // JSON template
+	Popularity  int    `gorm:"default:0" json:"popularity"`
+	IsBuiltIn   bool   `gorm:"default:false" json:"is_built_in"`
+	// Marketplace
+	AuthorID    string          `gorm:"type:varchar(64);index" json:"author_id"` // Supabase user ID, empty for admin/built-in
+	AuthorName  string          `json:"author_name"`
+	Visibility  string          `gorm:"type:varchar(20);default:'private';index" json:"visibility"` // private, public
+	IsFeatured  bool            `gorm:"default:false" json:"is_featured"`
+	RatingSum   int             `gorm:"default:0" json:"-"`
+	RatingCount int             `gorm:"default:0" json:"rating_count"`
+	RatingAvg   decimal.Decimal `gorm:"type:decimal(3,2);default:0" json:"rating_avg"`
+	CloneCount  int             `gorm:"default:0" json:"clone_count"`
+	ViewCount   int             `gorm:"default:0" json:"view_count"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// SignalTemplateRating stores one user's rating of a template
+type SignalTemplateRating struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TemplateID uint      `gorm:"uniqueIndex:idx_template_rating_user;not null" json:"template_id"`
+	UserID     string    `gorm:"type:varchar(64);uniqueIndex:idx_template_rating_user;not null" json:"user_id"`
+	Rating     int       `gorm:"not null" json:"rating"` // 1-5
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // BuiltInTemplates returns built-in signal templates
@@ -279,6 +448,19 @@ func BuiltInTemplates() []SignalTemplate {
 				{"indicator": "MACD_HISTOGRAM", "operator": "gt", "value": 0, "weight": 15}
 			]`,
 		},
+		{
+			Name:        "Ichimoku Bullish Breakout",
+			Description: "Price above the cloud with a bullish Tenkan/Kijun cross",
+			Category:    "trend",
+			IsBuiltIn:   true,
+			Conditions: `[
+				{"indicator": "PRICE_ABOVE_CLOUD", "operator": "eq", "value": 1, "weight": 30, "required": true},
+				{"indicator": "TK_CROSS_BULL", "operator": "eq", "value": 1, "weight": 25},
+				{"indicator": "ICHIMOKU_TENKAN", "operator": "gt", "compare_indicator": "ICHIMOKU_KIJUN", "weight": 15},
+				{"indicator": "ICHIMOKU_SENKOU_A", "operator": "gt", "compare_indicator": "ICHIMOKU_SENKOU_B", "weight": 15},
+				{"indicator": "VOL_RATIO", "operator": "gte", "value": 1.2, "weight": 15}
+			]`,
+		},
 		{
 			Name:        "Value + Momentum",
 			Description: "Undervalued with improving momentum",
@@ -296,6 +478,23 @@ func BuiltInTemplates() []SignalTemplate {
 
 // MigrateSignalConditionModels runs database migrations for signal condition models
 func MigrateSignalConditionModels(db *gorm.DB) error {
+	// Templates created before the marketplace get the private default when the column is added
+	addingVisibility := !db.Migrator().HasColumn(&SignalTemplate{}, "visibility")
+
+	// Names were unique across trashed rows too, blocking their reuse until the trash was purged
+	legacyNameIndexes := map[string]interface{}{
+		"idx_signal_condition_groups_name": &SignalConditionGroup{},
+		"idx_signal_rules_name":            &SignalRule{},
+		"idx_signal_templates_name":        &SignalTemplate{},
+	}
+	for name, model := range legacyNameIndexes {
+		if db.Migrator().HasIndex(model, name) {
+			if err := db.Migrator().DropIndex(model, name); err != nil {
+				return err
+			}
+		}
+	}
+
 	err := db.AutoMigrate(
 		&SignalConditionGroup{},
 		&SignalCondition{},
@@ -304,15 +503,28 @@ func MigrateSignalConditionModels(db *gorm.DB) error {
 		&SignalAlertHistory{},
 		&SignalPerformance{},
 		&SignalTemplate{},
+		&SignalTemplateRating{},
+		&SignalRuleBacktestRun{},
+		&SignalHistory{},
 	)
 	if err != nil {
 		return err
 	}
 
+	// Built-in templates are listed in the marketplace
+	if addingVisibility {
+		if err := db.Model(&SignalTemplate{}).Where("is_built_in = ?", true).
+			Update("visibility", TemplateVisibilityPublic).Error; err != nil {
+			return err
+		}
+	}
+
 	// Seed built-in templates
 	for _, template := range BuiltInTemplates() {
+		template.Visibility = TemplateVisibilityPublic
 		var existing SignalTemplate
-		if db.Where("name = ?", template.Name).First(&existing).Error == gorm.ErrRecordNotFound {
+		// Unscoped so a built-in template sitting in the trash is not re-seeded
+		if db.Unscoped().Where("name = ?", template.Name).First(&existing).Error == gorm.ErrRecordNotFound {
 			db.Create(&template)
 		}
 	}
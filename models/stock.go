@@ -28,14 +28,14 @@ type StockPrice struct {
 	StockID     uint      `gorm:"index:idx_stock_date" json:"stock_id"`
 	Stock       Stock     `gorm:"foreignKey:StockID" json:"stock,omitempty"`
 	Date        time.Time `gorm:"index:idx_stock_date" json:"date"`
-	Open        decimal.Decimal `gorm:"type:decimal(15,2)" json:"open"`
-	High        decimal.Decimal `gorm:"type:decimal(15,2)" json:"high"`
-	Low         decimal.Decimal `gorm:"type:decimal(15,2)" json:"low"`
-	Close       decimal.Decimal `gorm:"type:decimal(15,2)" json:"close"`
+	Open        decimal.Decimal `gorm:"type:decimal(15,2)" json:"open" unit:"price_vnd"`
+	High        decimal.Decimal `gorm:"type:decimal(15,2)" json:"high" unit:"price_vnd"`
+	Low         decimal.Decimal `gorm:"type:decimal(15,2)" json:"low" unit:"price_vnd"`
+	Close       decimal.Decimal `gorm:"type:decimal(15,2)" json:"close" unit:"price_vnd"`
 	Volume      int64     `json:"volume"`
-	Value       decimal.Decimal `gorm:"type:decimal(20,2)" json:"value"`
-	AdjClose    decimal.Decimal `gorm:"type:decimal(15,2)" json:"adj_close"`
-	Change      decimal.Decimal `gorm:"type:decimal(15,2)" json:"change"`
+	Value       decimal.Decimal `gorm:"type:decimal(20,2)" json:"value" unit:"value_vnd"`
+	AdjClose    decimal.Decimal `gorm:"type:decimal(15,2)" json:"adj_close" unit:"price_vnd"`
+	Change      decimal.Decimal `gorm:"type:decimal(15,2)" json:"change" unit:"price_vnd"`
 	ChangePercent decimal.Decimal `gorm:"type:decimal(10,4)" json:"change_percent"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockNote is a user's private note and tags on a stock code
+type StockNote struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	UserID    string         `gorm:"type:varchar(64);uniqueIndex:idx_stock_note_user_code;not null" json:"-"` // Supabase user ID
+	StockCode string         `gorm:"type:varchar(20);uniqueIndex:idx_stock_note_user_code;not null" json:"stock_code"`
+	Note      string         `gorm:"type:text" json:"note"`
+	TagRows   []StockNoteTag `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE" json:"-"`
+	Tags      []string       `gorm:"-" json:"tags"` // filled from TagRows
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// StockNoteTag is one tag of a stock note, kept in its own table so notes filter by tag on an index
+type StockNoteTag struct {
+	ID     uint   `gorm:"primaryKey" json:"-"`
+	NoteID uint   `gorm:"index;not null" json:"-"`
+	UserID string `gorm:"type:varchar(64);index:idx_stock_note_tag_user_tag;not null" json:"-"`
+	Tag    string `gorm:"type:varchar(30);index:idx_stock_note_tag_user_tag;not null" json:"tag"`
+}
+
+// MigrateStockNoteModels runs migrations for user stock notes and tags
+func MigrateStockNoteModels(db *gorm.DB) error {
+	return db.AutoMigrate(&StockNote{}, &StockNoteTag{})
+}
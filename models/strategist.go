@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Paper trade sides and statuses
+const (
+	PaperTradeSideLong  = "long"
+	PaperTradeSideShort = "short"
+
+	PaperTradeStatusOpen   = "open"
+	PaperTradeStatusClosed = "closed"
+)
+
+// StrategistFollow is one user following a template author to receive the signals of their strategies
+type StrategistFollow struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     string    `gorm:"type:varchar(64);uniqueIndex:idx_strategist_follow_user;not null" json:"-"` // Supabase user ID
+	AuthorID   string    `gorm:"type:varchar(64);uniqueIndex:idx_strategist_follow_user;index;not null" json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PaperTrade is a simulated position of a user's paper-trading bot, usually opened from a
+// followed strategist's signal. It is closed at the signal's close price when the signal
+// reaches its target or stop or expires, or by the user at the current price.
+type PaperTrade struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          string     `gorm:"type:varchar(64);index;not null" json:"-"` // Supabase user ID
+	SignalHistoryID uint       `gorm:"index" json:"signal_id,omitempty"`
+	AuthorID        string     `gorm:"type:varchar(64);index" json:"author_id,omitempty"` // strategist of the signal
+	RuleID          uint       `json:"rule_id,omitempty"`
+	StockCode       string     `gorm:"type:varchar(20);index;not null" json:"stock_code"`
+	Side            string     `gorm:"type:varchar(10);not null" json:"side"` // long, short
+	Quantity        int64      `gorm:"not null" json:"quantity"`
+	EntryPrice      float64    `gorm:"type:decimal(15,2)" json:"entry_price"`
+	TargetPrice     float64    `gorm:"type:decimal(15,2)" json:"target_price,omitempty"`
+	StopLossPrice   float64    `gorm:"type:decimal(15,2)" json:"stop_loss_price,omitempty"`
+	Status          string     `gorm:"type:varchar(10);default:'open';index" json:"status"`
+	ExitPrice       float64    `gorm:"type:decimal(15,2)" json:"exit_price,omitempty"`
+	ExitReason      string     `json:"exit_reason,omitempty"` // target_hit, stop_loss, timeout, manual
+	LastPrice       float64    `gorm:"-" json:"last_price,omitempty"`
+	PnLPercent      float64    `json:"pnl_percent"` // realized, or unrealized at the last price while open
+	PnLAmount       float64    `json:"pnl_amount"`
+	OpenedAt        time.Time  `json:"opened_at"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// MigrateStrategistModels runs migrations for strategist follows and paper trades
+func MigrateStrategistModels(db *gorm.DB) error {
+	return db.AutoMigrate(&StrategistFollow{}, &PaperTrade{})
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sync types recorded in sync history
+const (
+	SyncTypePriceFull  = "price_full"
+	SyncTypePriceRetry = "price_retry"
+	SyncTypePriceBatch = "price_batch"
+	SyncTypeStockList  = "stock_list"
+	SyncTypeIndicators = "indicators"
+	SyncTypeMongoDB    = "mongodb_backup"
+	SyncTypePipeline   = "data_pipeline"
+)
+
+// Sync statuses
+const (
+	SyncStatusSuccess = "success"
+	SyncStatusPartial = "partial" // finished with some failed items
+	SyncStatusFailed  = "failed"
+)
+
+// SyncHistory records one run of a data sync job
+type SyncHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SyncType     string    `gorm:"size:50;index" json:"sync_type"`
+	Status       string    `gorm:"size:20;index" json:"status"`
+	StartedAt    time.Time `gorm:"index" json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	TotalItems   int       `json:"total_items"`
+	SuccessItems int       `json:"success_items"`
+	FailedItems  int       `json:"failed_items"`
+	Error        string    `gorm:"type:text" json:"error,omitempty"`
+}
+
+// TableName keeps the singular table name used for sync history
+func (SyncHistory) TableName() string {
+	return "sync_history"
+}
+
+// MigrateSyncHistoryModels runs migrations for sync history
+func MigrateSyncHistoryModels(db *gorm.DB) error {
+	return db.AutoMigrate(&SyncHistory{})
+}
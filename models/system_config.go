@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SystemConfig is a JSON configuration value stored under a key (table created by migration 003)
+type SystemConfig struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:jsonb;not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName keeps the table name used by the SQL migrations
+func (SystemConfig) TableName() string {
+	return "system_config"
+}
+
+// MigrateSystemConfigModels runs migrations for system configuration storage
+func MigrateSystemConfigModels(db *gorm.DB) error {
+	return db.AutoMigrate(&SystemConfig{})
+}
@@ -66,6 +66,8 @@ type Backtest struct {
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
 	InitialCapital decimal.Decimal `gorm:"type:decimal(20,2)" json:"initial_capital"`
+	Mode        string    `gorm:"size:20" json:"mode"`   // "" or portfolio
+	MaxPositions int      `json:"max_positions"`        // portfolio mode, 0 for no limit
 	FinalCapital decimal.Decimal `gorm:"type:decimal(20,2)" json:"final_capital"`
 	TotalReturn decimal.Decimal `gorm:"type:decimal(15,4)" json:"total_return"`
 	AnnualReturn decimal.Decimal `gorm:"type:decimal(15,4)" json:"annual_return"`
@@ -78,6 +80,12 @@ type Backtest struct {
 	AvgWin      decimal.Decimal `gorm:"type:decimal(15,2)" json:"avg_win"`
 	AvgLoss     decimal.Decimal `gorm:"type:decimal(15,2)" json:"avg_loss"`
 	ProfitFactor decimal.Decimal `gorm:"type:decimal(10,4)" json:"profit_factor"`
+	// Buy & hold of the benchmark index over the same days, and the strategy against it
+	BenchmarkCode   string          `gorm:"size:20" json:"benchmark_code,omitempty"`
+	BenchmarkReturn decimal.Decimal `gorm:"type:decimal(15,4)" json:"benchmark_return"`
+	Alpha           decimal.Decimal `gorm:"type:decimal(15,4)" json:"alpha"` // annualized
+	Beta            decimal.Decimal `gorm:"type:decimal(10,4)" json:"beta"`
+	RelativeEquity  string          `gorm:"type:text" json:"relative_equity,omitempty"` // equity over the index by date, both rebased to 100
 	Results     string    `gorm:"type:jsonb" json:"results"` // Detailed results in JSON
 	CreatedAt   time.Time `json:"created_at"`
 	CompletedAt *time.Time `json:"completed_at"`
@@ -95,8 +103,14 @@ type BacktestTrade struct {
 	Quantity   int64     `json:"quantity"`
 	Price      decimal.Decimal `gorm:"type:decimal(15,2)" json:"price"`
 	Commission decimal.Decimal `gorm:"type:decimal(15,2)" json:"commission"`
+	Slippage   decimal.Decimal `gorm:"type:decimal(15,2)" json:"slippage"` // cost of filling away from the close
+	Partial    bool      `json:"partial"`                                   // the day's volume filled part of the order
 	PnL        decimal.Decimal `gorm:"type:decimal(15,2)" json:"pnl"`
 	Signal     string    `json:"signal"` // What triggered this trade
+	// Position sizing of a buy: the method, the share of the equity it was given and why
+	SizingMethod   string          `gorm:"size:20" json:"sizing_method,omitempty"`
+	SizingFraction decimal.Decimal `gorm:"type:decimal(10,4)" json:"sizing_fraction"`
+	SizingNote     string          `json:"sizing_note,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
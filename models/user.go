@@ -25,6 +25,7 @@ type User struct {
 	LastLoginAt     *time.Time `json:"last_login_at"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Set when the user's data is erased
 }
 
 // UserSession represents user session for tracking
@@ -85,6 +86,11 @@ const (
 	UserAlertTypePriceBelow    = "price_below"
 	UserAlertTypePercentChange = "percent_change"
 	UserAlertTypeVolumeSpike   = "volume_spike"
+
+	// Daily limit alerts, evaluated from realtime prices; they take no target value
+	UserAlertTypeHitCeiling         = "hit_ceiling"
+	UserAlertTypeHitFloor           = "hit_floor"
+	UserAlertTypeApproachingCeiling = "approaching_ceiling"
 )
 
 // ValidWatchlistAlertTypes returns valid alert types for watchlist
@@ -99,9 +105,21 @@ func ValidUserAlertTypes() []string {
 		UserAlertTypePriceBelow,
 		UserAlertTypePercentChange,
 		UserAlertTypeVolumeSpike,
+		UserAlertTypeHitCeiling,
+		UserAlertTypeHitFloor,
+		UserAlertTypeApproachingCeiling,
 	}
 }
 
+// IsLimitUserAlertType reports whether an alert type watches the daily ceiling or floor
+func IsLimitUserAlertType(alertType string) bool {
+	switch alertType {
+	case UserAlertTypeHitCeiling, UserAlertTypeHitFloor, UserAlertTypeApproachingCeiling:
+		return true
+	}
+	return false
+}
+
 // IsValidWatchlistAlertType checks if the alert type is valid
 func IsValidWatchlistAlertType(alertType string) bool {
 	for _, valid := range ValidWatchlistAlertTypes() {
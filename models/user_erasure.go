@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User erasure request statuses
+const (
+	ErasureStatusPending   = "pending"   // waiting for admin review
+	ErasureStatusCompleted = "completed" // data erased, receipt recorded
+	ErasureStatusRejected  = "rejected"
+	ErasureStatusCancelled = "cancelled" // withdrawn by the user
+	ErasureStatusFailed    = "failed"    // local data erased but the Supabase cleanup failed; can be retried
+)
+
+// ErasedAuthorID replaces the author of public templates whose author was erased
+const ErasedAuthorID = "erased"
+
+// UserErasureRequest is a user's request to have their personal data erased. Once completed it
+// keeps only the receipt: the subject is identified by a hash, not the Supabase user ID.
+type UserErasureRequest struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SupabaseUserID string     `gorm:"type:varchar(64);index" json:"supabase_user_id,omitempty"` // cleared on completion
+	Email          string     `json:"email,omitempty"`                                          // cleared on completion
+	SubjectHash    string     `gorm:"type:varchar(64);index" json:"subject_hash"`               // SHA-256 of the Supabase user ID
+	Reason         string     `gorm:"type:text" json:"reason"`
+	Status         string     `gorm:"type:varchar(20);index" json:"status"`
+	ExportedAt     *time.Time `json:"exported_at"` // last admin export of the data bundle
+	ExportedBy     string     `json:"exported_by,omitempty"`
+	ReviewedBy     string     `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	ReviewNote     string     `gorm:"type:text" json:"review_note,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	Receipt        string     `gorm:"type:jsonb" json:"receipt,omitempty"`
+	ReceiptHash    string     `gorm:"type:varchar(64)" json:"receipt_hash,omitempty"` // SHA-256 of Receipt
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MigrateUserErasureModels runs migrations for user erasure requests
+func MigrateUserErasureModels(db *gorm.DB) error {
+	return db.AutoMigrate(&UserErasureRequest{})
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Report kinds
+const (
+	ReportKindWeekly = "weekly"
+)
+
+// UserReport is a generated performance report of one user for one period. Regenerating a
+// period replaces its report.
+type UserReport struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      string    `gorm:"type:varchar(64);uniqueIndex:idx_user_report_period;not null" json:"user_id"` // Supabase user ID
+	Kind        string    `gorm:"type:varchar(20);uniqueIndex:idx_user_report_period" json:"kind"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `gorm:"uniqueIndex:idx_user_report_period" json:"period_end"`
+	HTML        string    `gorm:"type:text" json:"html,omitempty"`
+	PDF         []byte    `json:"-"` // empty when no PDF converter is configured
+	HasPDF      bool      `json:"has_pdf"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MigrateUserReportModels runs migrations for user reports
+func MigrateUserReportModels(db *gorm.DB) error {
+	return db.AutoMigrate(&UserReport{})
+}
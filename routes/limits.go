@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"runtime"
+	"time"
+
+	"go_backend_project/middleware"
+	"go_backend_project/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Expensive endpoints scan every stock or replay price history. They share small concurrency
+// pools sized to the instance so a burst of screener requests queues instead of starving the CPU.
+var (
+	signalLimiter   = middleware.NewConcurrencyLimiter("signals", max(2, runtime.NumCPU()), 5*time.Second)
+	backtestLimiter = middleware.NewConcurrencyLimiter("backtests", max(1, runtime.NumCPU()/2), 2*time.Second)
+)
+
+// templateBacktestRate limits each user, or anonymous IP, to a few template backtests a minute.
+// Only requests missing the cache count, so reloading a result already computed is free.
+var templateBacktestRate = middleware.NewRateLimiter("template backtests", 5, time.Minute)
+
+// templateBacktestCache holds template backtests until the next indicator recalculation, which
+// brings in a new close. Private templates are only visible to their author, so results are
+// cached per caller.
+var templateBacktestCache = middleware.NewResponseCache(templateBacktestCacheTTL, func() string {
+	return services.IndicatorFreshness(time.Now()).DataAsOf
+}).VaryBy(func(c *gin.Context) string {
+	userID, _ := middleware.GetSupabaseUserFromContext(c)
+	return userID
+})
+
+const templateBacktestCacheTTL = 6 * time.Hour
+
+// screenerCache holds the signal screener results. Screeners only change when the indicator
+// summary is recalculated, which also moves the cache to a new version. Admin ?live=true
+// recomputations are never cached. Results carry the caller's experiment variants, so each
+// combination of variants is cached apart.
+var screenerCache = middleware.NewResponseCache(screenerCacheTTL, func() string {
+	return services.IndicatorFreshness(time.Now()).DataAsOf
+}).SkipIf(func(c *gin.Context) bool { return c.Query("live") == "true" }).VaryBy(middleware.ExperimentCacheKey)
+
+// screenerCacheTTL keeps screener results briefly, long enough to absorb the after-close rush
+const screenerCacheTTL = 45 * time.Second
+
+// Time budgets of the limited routes. Portfolio backtests don't observe the request context, so
+// they get no timeout of their own and are bounded by the server-wide request timeout.
+const (
+	signalRouteTimeout   = 20 * time.Second
+	screenerRouteTimeout = 30 * time.Second
+)
+
+// apiRouteLimits lists the limited /api/v1 routes
+func apiRouteLimits() map[string]middleware.RouteLimit {
+	signal := middleware.RouteLimit{Timeout: signalRouteTimeout, Limiter: signalLimiter}
+	screener := middleware.RouteLimit{Timeout: screenerRouteTimeout, Limiter: signalLimiter}
+	backtest := middleware.RouteLimit{Limiter: backtestLimiter}
+	templateBacktest := middleware.RouteLimit{Timeout: signalRouteTimeout, Rate: templateBacktestRate, Limiter: backtestLimiter}
+
+	return map[string]middleware.RouteLimit{
+		// Public signal API
+		"GET /api/v1/signals":                       signal,
+		"POST /api/v1/signals/batch":                signal,
+		"GET /api/v1/signals/top":                   signal,
+		"GET /api/v1/signals/stats":                 signal,
+		"GET /api/v1/signals/strategy/:name":        signal,
+		"GET /api/v1/signals/screener/buy":          signal,
+		"GET /api/v1/signals/screener/sell":         signal,
+		"GET /api/v1/signals/screener/momentum":     signal,
+		"GET /api/v1/signals/screener/oversold":     signal,
+		"GET /api/v1/signals/screener/breakout":     signal,
+		"GET /api/v1/signals/screener/etf":          signal,
+		"GET /api/v1/signals/screener/52w":          signal,
+		"GET /api/v1/signals/screener/gaps":         signal,
+		"GET /api/v1/signals/screener/accumulation": signal,
+		"GET /api/v1/signals/indicators":            signal,
+
+		// Algorithmic signals
+		"GET /api/v1/algo/signals":      signal,
+		"GET /api/v1/algo/signals/buy":  signal,
+		"GET /api/v1/algo/signals/sell": signal,
+		"GET /api/v1/algo/signals/top":  signal,
+
+		// Stock screener
+		"POST /api/v1/screener/screen":     screener,
+		"GET /api/v1/screener/presets/:id": screener,
+
+		// Backtests
+		"POST /api/v1/backtests":             backtest,
+		"GET /api/v1/templates/:id/backtest": templateBacktest,
+	}
+}
+
+// apiResponseCaches lists the cached /api/v1 routes. These responses don't depend on the caller
+// unless their cache varies by it.
+func apiResponseCaches() map[string]*middleware.ResponseCache {
+	return map[string]*middleware.ResponseCache{
+		"GET /api/v1/signals/screener/buy":          screenerCache,
+		"GET /api/v1/signals/screener/sell":         screenerCache,
+		"GET /api/v1/signals/screener/momentum":     screenerCache,
+		"GET /api/v1/signals/screener/oversold":     screenerCache,
+		"GET /api/v1/signals/screener/breakout":     screenerCache,
+		"GET /api/v1/signals/screener/etf":          screenerCache,
+		"GET /api/v1/signals/screener/52w":          screenerCache,
+		"GET /api/v1/signals/screener/gaps":         screenerCache,
+		"GET /api/v1/signals/screener/accumulation": screenerCache,
+
+		"GET /api/v1/templates/:id/backtest": templateBacktestCache,
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"go_backend_project/admin"
+	"go_backend_project/apidocs"
 	"go_backend_project/controllers"
 	"go_backend_project/middleware"
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"go_backend_project/services/trading"
 
 	"github.com/gin-gonic/gin"
@@ -259,6 +263,7 @@ func setupProtectedRoutesImpl(router *gin.Engine, db *gorm.DB, tradingBot *tradi
 		protected.GET("/strategies", adminController.StrategiesPage)
 		protected.GET("/backtests", adminController.BacktestsPage)
 		protected.GET("/trading-bot", adminController.TradingBotPage)
+		protected.GET("/api-docs", apidocs.SwaggerUI)
 		protected.GET("/signals", adminController.SignalsPage)
 		protected.GET("/users", adminController.UsersPage)
 		protected.GET("/admin-users", adminController.AdminUsersPage)
@@ -266,6 +271,9 @@ func setupProtectedRoutesImpl(router *gin.Engine, db *gorm.DB, tradingBot *tradi
 		protected.GET("/signal-conditions", adminController.SignalConditionsPage)
 		protected.GET("/stock-indicators", adminController.StockIndicatorsPage)
 		protected.GET("/stock-indicators/search", adminController.SearchStockIndicators)
+		protected.GET("/data-browser", adminController.DataBrowserPage)
+		protected.GET("/analytics", adminController.AnalyticsPage)
+		protected.GET("/experiments", adminController.ExperimentsPage)
 
 		// Signal Conditions Management
 		signalConds := protected.Group("/signal-conditions")
@@ -274,28 +282,163 @@ func setupProtectedRoutesImpl(router *gin.Engine, db *gorm.DB, tradingBot *tradi
 			signalConds.POST("/groups", adminController.CreateConditionGroupAction)
 			signalConds.PUT("/groups/:id", adminController.UpdateConditionGroupAction)
 			signalConds.DELETE("/groups/:id", adminController.DeleteConditionGroupAction)
+			signalConds.POST("/groups/:id/restore", adminController.RestoreConditionGroupAction)
+			signalConds.GET("/groups/:id/dependents", adminController.GetConditionGroupDependentsAction)
 
 			// Individual Conditions
 			signalConds.POST("/conditions", adminController.AddConditionAction)
 			signalConds.PUT("/conditions/:id", adminController.UpdateConditionAction)
 			signalConds.DELETE("/conditions/:id", adminController.DeleteConditionAction)
+			signalConds.POST("/conditions/:id/restore", adminController.RestoreConditionAction)
 
 			// Signal Rules
 			signalConds.POST("/rules", adminController.CreateSignalRuleAction)
 			signalConds.PUT("/rules/:id", adminController.UpdateSignalRuleAction)
 			signalConds.DELETE("/rules/:id", adminController.DeleteSignalRuleAction)
+			signalConds.POST("/rules/:id/restore", adminController.RestoreSignalRuleAction)
 			signalConds.GET("/rules/:id/test", adminController.TestSignalRuleAction)
 			signalConds.GET("/rules/:id/stats", adminController.GetRuleStatisticsAction)
+			signalConds.GET("/rules/:id/simulate", adminController.SimulateSignalRuleAction)
+			signalConds.POST("/rules/:id/retire", adminController.RetireSignalRuleAction)
 
 			// Templates
 			signalConds.GET("/templates", adminController.GetTemplatesAction)
 			signalConds.GET("/templates/:id/test", adminController.TestTemplateAction)
 			signalConds.POST("/templates/from-group", adminController.CreateTemplateFromGroupAction)
+			signalConds.DELETE("/templates/:id", adminController.DeleteTemplateAction)
+			signalConds.POST("/templates/:id/restore", adminController.RestoreTemplateAction)
+			signalConds.POST("/templates/:id/feature", adminController.FeatureTemplateAction)
+			signalConds.POST("/templates/:id/unpublish", adminController.UnpublishTemplateAction)
+
+			// Trash
+			signalConds.GET("/trash", adminController.GetTrashAction)
+			signalConds.GET("/orphans", adminController.GetOrphanedReferencesAction)
 
 			// Testing
 			signalConds.GET("/test", adminController.TestStockWithConditionsAction)
 		}
 
+		// Realtime price streaming; these handlers don't use the Supabase client
+		supabaseDBClient, _ := services.NewSupabaseDBClient()
+		adminStockController := admin.NewStockController(supabaseDBClient)
+		protected.GET("/ws/realtime", adminStockController.HandleRealtimeWebSocket)
+
+		// Admin JSON API
+		adminAPI := protected.Group("/api")
+		{
+			adminAPI.POST("/realtime/start", adminStockController.StartRealtimePolling)
+			adminAPI.POST("/realtime/stop", adminStockController.StopRealtimePolling)
+			adminAPI.GET("/realtime/status", adminStockController.GetRealtimeStatus)
+			adminAPI.GET("/realtime/config", adminStockController.GetRealtimeConfig)
+			adminAPI.PUT("/realtime/config", adminStockController.UpdateRealtimeConfig)
+			adminAPI.POST("/prices/retry-failed", adminStockController.RetryFailedPrices)
+			adminAPI.GET("/prices/cache", adminStockController.GetPriceCache)
+			adminAPI.POST("/prices/cache/purge", adminStockController.PurgePriceCache)
+			adminAPI.GET("/stocks/lifecycle", adminStockController.GetStockLifecycle)
+			adminAPI.POST("/indicators/calculate", adminStockController.CalculateAllIndicators)
+			adminAPI.POST("/prices/sync", adminStockController.StartPriceSync)
+			adminAPI.POST("/prices/sync-batch", adminStockController.SyncPriceBatch)
+
+			// Background jobs started by heavy actions (backtests, indicator calculation, price sync)
+			jobController := admin.NewJobController()
+			adminAPI.GET("/jobs", jobController.ListJobs)
+			adminAPI.GET("/jobs/:id", jobController.GetJob)
+			adminAPI.GET("/jobs/:id/events", jobController.JobEvents)
+			adminAPI.POST("/jobs/:id/cancel", jobController.CancelJob)
+			adminAPI.GET("/orderbook/spreads", adminController.GetOrderBookSpreads)
+
+			adminAPI.GET("/market/holidays", adminController.GetMarketHolidays)
+			adminAPI.PUT("/market/holidays", adminController.UpdateMarketHolidays)
+			adminAPI.GET("/events", adminController.GetCorporateEvents)
+			adminAPI.POST("/events/sync", adminController.SyncCorporateEvents)
+
+			adminAPI.GET("/backtests/compare", adminController.CompareBacktests)
+			adminAPI.GET("/backtests/:id/export", adminController.ExportBacktest)
+
+			adminAPI.GET("/rule-backtests/config", adminController.GetRuleBacktestConfig)
+			adminAPI.PUT("/rule-backtests/config", adminController.UpdateRuleBacktestConfig)
+			adminAPI.POST("/rule-backtests/run", adminController.RunRuleBacktests)
+			adminAPI.GET("/rule-backtests/runs", adminController.GetRuleBacktestRuns)
+			adminAPI.GET("/pipeline", adminController.GetPipeline)
+			adminAPI.PUT("/pipeline/config", adminController.UpdatePipelineConfig)
+			adminAPI.POST("/pipeline/run", adminController.RunPipeline)
+			adminAPI.GET("/signal-history", adminController.GetSignalHistory)
+			adminAPI.POST("/signal-history/emit", adminController.EmitRuleSignals)
+			adminAPI.GET("/signal-history/changes", adminController.GetSignalChangelog)
+			adminAPI.GET("/sync/history", adminController.GetSyncHistory)
+			adminAPI.GET("/retention", adminController.GetRetention)
+			adminAPI.POST("/retention/prune", adminController.PruneRetention)
+			adminAPI.GET("/ideas", adminController.GetIdeas)
+			adminAPI.GET("/ideas/:id/reports", adminController.GetIdeaReports)
+			adminAPI.POST("/ideas/:id/moderate", adminController.ModerateIdea)
+			adminAPI.DELETE("/ideas/:id", adminController.DeleteIdea)
+			adminAPI.GET("/analytics", adminController.GetAnalytics)
+			adminAPI.POST("/analytics/rollup", adminController.RollupAnalytics)
+			adminAPI.GET("/experiments", adminController.GetExperiments)
+			adminAPI.POST("/experiments", adminController.CreateExperiment)
+			adminAPI.PUT("/experiments/:id", adminController.UpdateExperiment)
+			adminAPI.DELETE("/experiments/:id", adminController.DeleteExperiment)
+			adminAPI.POST("/experiments/:id/start", adminController.StartExperiment)
+			adminAPI.POST("/experiments/:id/stop", adminController.StopExperiment)
+			adminAPI.GET("/experiments/:id/results", adminController.GetExperimentResults)
+			adminAPI.GET("/incidents", adminController.GetIncidents)
+			adminAPI.POST("/health/check", adminController.RunHealthCheck)
+			adminAPI.POST("/health/test-alert", adminController.SendTestAlert)
+			adminAPI.POST("/selftest", adminController.RunSelfTest)
+			adminAPI.GET("/signals/composite-weights", adminController.GetCompositeWeights)
+			adminAPI.PUT("/signals/composite-weights", adminController.UpdateCompositeWeights)
+			adminAPI.GET("/signals/snapshot", adminController.GetSignalSnapshot)
+			adminAPI.POST("/signals/snapshot", adminController.PublishSignalSnapshot)
+			adminAPI.GET("/maintenance", adminController.GetMaintenance)
+			adminAPI.PUT("/maintenance", adminController.UpdateMaintenance)
+			adminAPI.GET("/debug-capture", adminController.GetDebugCapture)
+			adminAPI.PUT("/debug-capture", adminController.UpdateDebugCapture)
+			adminAPI.GET("/debug-capture/requests", adminController.GetCapturedRequests)
+			adminAPI.DELETE("/debug-capture/requests", adminController.ClearCapturedRequests)
+			adminAPI.GET("/profile-events", adminController.GetProfileEvents)
+			adminAPI.POST("/profile-events/test", adminController.SendTestProfileEvent)
+
+			// User data erasure requests: review, export the user's data, then erase
+			adminAPI.GET("/erasure-requests", adminController.GetErasureRequests)
+			adminAPI.GET("/erasure-requests/:id/export", adminController.ExportErasureRequestData)
+			adminAPI.POST("/erasure-requests/:id/approve", adminController.ApproveErasureRequest)
+			adminAPI.POST("/erasure-requests/:id/reject", adminController.RejectErasureRequest)
+
+			// Support impersonation: short-lived tokens acting as a user, audit-logged per request
+			adminAPI.POST("/users/:id/impersonate", adminController.ImpersonateUser)
+			adminAPI.GET("/impersonation", adminController.GetImpersonationSessions)
+			adminAPI.GET("/impersonation/:id/requests", adminController.GetImpersonationRequests)
+
+			// Weekly user reports: layout editor, preview and manual runs
+			adminAPI.GET("/reports/template", adminController.GetReportTemplate)
+			adminAPI.PUT("/reports/template", adminController.UpdateReportTemplate)
+			adminAPI.POST("/reports/preview", adminController.PreviewReport)
+			adminAPI.POST("/reports/run", adminController.RunWeeklyReports)
+
+			// Go profiler for performance work in staging
+			if admin.PprofEnabled() {
+				adminAPI.GET("/debug/pprof/*profile", adminController.Pprof)
+			}
+			adminAPI.GET("/indicators/profiles", adminController.GetIndicatorProfiles)
+			adminAPI.PUT("/indicators/profiles", adminController.UpdateIndicatorProfiles)
+			adminAPI.GET("/custom-strategies", adminController.GetCustomStrategies)
+			adminAPI.POST("/custom-strategies", adminController.CreateCustomStrategy)
+			adminAPI.POST("/custom-strategies/validate", adminController.ValidateCustomStrategy)
+			adminAPI.PUT("/custom-strategies/:id", adminController.UpdateCustomStrategy)
+			adminAPI.DELETE("/custom-strategies/:id", adminController.DeleteCustomStrategy)
+			adminAPI.GET("/strategy-plugins", adminController.GetStrategyPlugins)
+			adminAPI.POST("/strategy-plugins/:name/health", adminController.CheckStrategyPlugin)
+			adminAPI.GET("/data-browser/tables", adminController.GetDataBrowserTables)
+			adminAPI.POST("/data-browser/query", adminController.RunDataBrowserQuery)
+			adminAPI.GET("/snapshot", adminController.DownloadSnapshot)
+			adminAPI.POST("/snapshot/push", adminController.PushSnapshot)
+			adminAPI.POST("/snapshot/restore", adminController.RestoreSnapshot)
+			adminAPI.POST("/seed/demo", adminController.SeedDemoData)
+
+			adminAPI.GET("/notifications", adminController.GetNotifications)
+			adminAPI.POST("/notifications/:id/read", adminController.MarkNotificationRead)
+		}
+
 		// Admin actions
 		actions := protected.Group("/actions")
 		{
@@ -327,6 +470,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 	userController := controllers.NewUserController(db)
 	subscriptionController := controllers.NewSubscriptionController(db)
 	screenerController := controllers.NewScreenerController(db)
+	marketController := controllers.NewMarketController(db)
 
 	// Setup protected admin routes now that DB is ready
 	SetupAdminProtectedRoutes(router, db, tradingBot)
@@ -334,6 +478,47 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 	// Check if API auth is required (can be configured via environment)
 	requireAPIAuth := os.Getenv("REQUIRE_API_AUTH") == "true"
 
+	// Audit every request made with an admin impersonation token
+	middleware.ImpersonationAuditor = func(c *gin.Context, claims *middleware.SupabaseClaims, status int) {
+		services.RecordImpersonationRequest(db, claims.ID, c.Request.Method, c.Request.URL.RequestURI(), status)
+	}
+
+	// Log every API request for the admin analytics page; logs are buffered and written in batches
+	services.GlobalAPIUsage = services.NewAPIUsageRecorder(db)
+	middleware.APIUsageRecorder = func(c *gin.Context, status int, duration time.Duration) {
+		userID, _ := middleware.GetSupabaseUserFromContext(c)
+		code := c.Param("symbol")
+		if code == "" {
+			code = c.Param("code")
+		}
+		services.GlobalAPIUsage.Record(models.APIUsageLog{
+			At:         time.Now(),
+			UserID:     userID,
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			StockCode:  strings.ToUpper(code),
+			Status:     status,
+			DurationMs: int(duration.Milliseconds()),
+		})
+	}
+
+	// A/B experiments: signed-in users are assigned by a hash of their ID and exposures logged
+	middleware.ExperimentAssigner = func(c *gin.Context) map[string]string {
+		userID, _ := middleware.GetSupabaseUserFromContext(c)
+		return services.AssignExperiments(db, userID, c.Request.URL.Path)
+	}
+
+	// Maintenance mode, switched by the admins and stored in system_config
+	middleware.MaintenanceState = func() (bool, string, time.Duration) {
+		m := services.CurrentMaintenance()
+		return m.Enabled, m.Message, m.RetryAfter(time.Now())
+	}
+
+	// Sampled request/response capture, switched on by the admins to debug a route
+	middleware.DebugCaptureSampler = func(c *gin.Context) int {
+		return services.SampleDebugCapture(c.FullPath())
+	}
+
 	// API v1 group
 	api := router.Group("/api/v1")
 
@@ -345,6 +530,25 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 		api.Use(middleware.OptionalJWTAuthMiddleware())
 	}
 
+	// 503 while in maintenance, except the health endpoints and admins
+	api.Use(middleware.Maintenance())
+
+	// Usage logs, ahead of the cache so cache hits count too
+	api.Use(middleware.APIUsage())
+
+	// Debug capture of sampled requests, ahead of the cache so cached responses are seen as served
+	api.Use(middleware.DebugCapture())
+
+	// Experiment variants, ahead of the cache so cached responses vary by variant
+	api.Use(middleware.Experiments())
+
+	// Short-lived, coalesced caching of the signal screeners, ahead of the limits so cache hits
+	// take no slot
+	api.Use(middleware.CachedRoutes(apiResponseCaches()))
+
+	// Per-route timeouts and concurrency limits for signal generation, screening and backtests
+	api.Use(middleware.RouteLimits(apiRouteLimits()))
+
 	{
 		// Database health check endpoint (always public)
 		api.GET("/health/db", func(c *gin.Context) {
@@ -377,6 +581,16 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			})
 		})
 
+		// Status page: latest health checks, open incidents and those resolved today (always public)
+		api.GET("/health/status", func(c *gin.Context) {
+			status, err := services.GetHealthStatus(db, time.Now())
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Failed to load health status"})
+				return
+			}
+			c.JSON(http.StatusOK, status)
+		})
+
 		// Auth info endpoint - returns current authentication status
 		api.GET("/auth/me", func(c *gin.Context) {
 			authenticated, exists := c.Get("authenticated")
@@ -391,12 +605,14 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			userID, _ := c.Get("user_id")
 			email, _ := c.Get("user_email")
 			role, _ := c.Get("user_role")
+			impersonatedBy, _ := middleware.IsImpersonating(c)
 
 			c.JSON(http.StatusOK, gin.H{
-				"authenticated": true,
-				"user_id":       userID,
-				"email":         email,
-				"role":          role,
+				"authenticated":   true,
+				"user_id":         userID,
+				"email":           email,
+				"role":            role,
+				"impersonated_by": impersonatedBy,
 			})
 		})
 
@@ -439,10 +655,15 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 		{
 			stocks.GET("", stockController.GetStocks)
 			stocks.GET("/search", stockController.SearchStocks)
-			stocks.GET("/:id", stockController.GetStock)
-			stocks.GET("/:symbol/prices", stockController.GetStockPrice)
+			stocks.GET("/:symbol", stockController.GetStock)
+			stocks.GET("/:symbol/prices", controllers.UnitsOption(), stockController.GetStockPrice)
 			stocks.GET("/:symbol/quote", stockController.GetRealtimeQuote)
 			stocks.GET("/:symbol/indicators", stockController.GetTechnicalIndicators)
+			stocks.GET("/:symbol/intraday", stockController.GetIntraday)
+			stocks.GET("/:symbol/levels", controllers.UnitsOption(), stockController.GetStockLevels)
+			stocks.GET("/:symbol/overview", controllers.UnitsOption(), stockController.GetStockOverview)
+			stocks.GET("/:symbol/events", stockController.GetStockEvents)
+			stocks.GET("/:symbol/peers", stockController.GetStockPeers)
 			stocks.POST("/:symbol/indicators/calculate", stockController.CalculateIndicators)
 			stocks.POST("/:symbol/fetch-historical", stockController.FetchHistoricalData)
 		}
@@ -460,6 +681,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			screener.GET("/overbought", screenerController.GetOverboughtStocks)
 			screener.GET("/bullish", screenerController.GetBullishStocks)
 			screener.GET("/volume-spike", screenerController.GetVolumeSpike)
+			screener.GET("/price-limits", screenerController.GetPriceLimits)
 		}
 
 		// Market routes
@@ -469,6 +691,13 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			market.GET("/top-gainers", stockController.GetTopGainers)
 			market.GET("/top-losers", stockController.GetTopLosers)
 			market.GET("/most-active", stockController.GetMostActive)
+			market.GET("/calendar", marketController.GetCalendar)
+			market.GET("/status", marketController.GetStatus)
+			market.GET("/events", marketController.GetEventCalendar)
+			market.GET("/regime", marketController.GetRegime)
+			market.GET("/heatmap", marketController.GetHeatmap)
+			market.GET("/movers", marketController.GetMovers)
+			market.GET("/stats", marketController.GetMarketStats)
 		}
 
 		// Trading strategy routes
@@ -488,13 +717,58 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			backtests.POST("", tradingController.RunBacktest)
 		}
 
-		// Signal routes - using new SignalController with algorithmic trading strategies
-		controllers.RegisterSignalRoutes(api)
+		// Signal routes - using new SignalController with algorithmic trading strategies.
+		// Mounted under /algo so they don't collide with the public /signals API below.
+		controllers.RegisterSignalRoutes(api.Group("/algo"))
 
 		// Public Signal API routes - optimized for frontend consumption
 		publicSignalController := controllers.NewPublicSignalController()
 		publicSignalController.RegisterPublicSignalRoutes(api)
 
+		// Community signal template marketplace
+		templateController := controllers.NewTemplateController(db)
+		templateController.RegisterTemplateRoutes(api)
+
+		// End-user alerts when a new stock starts matching a signal rule or template
+		signalSubscriptionController := controllers.NewSignalSubscriptionController(db)
+		signalSubscriptionController.RegisterSignalSubscriptionRoutes(api)
+
+		// In-app notification inbox fed by alerts, signal subscriptions and membership events
+		notificationController := controllers.NewNotificationController(db)
+		notificationController.RegisterNotificationRoutes(api)
+
+		// Private notes and tags on stocks
+		stockNoteController := controllers.NewStockNoteController(db)
+		stockNoteController.RegisterStockNoteRoutes(api)
+
+		// Watchlists shared with other users as viewers or editors
+		sharedWatchlistController := controllers.NewSharedWatchlistController(db)
+		sharedWatchlistController.RegisterSharedWatchlistRoutes(api)
+
+		// Community trade ideas with likes, follows, moderation and tracked outcomes
+		ideaController := controllers.NewIdeaController(db)
+		ideaController.RegisterIdeaRoutes(api)
+
+		// Strategist follows and the paper-trading bot
+		strategistController := controllers.NewStrategistController(db)
+		strategistController.RegisterStrategistRoutes(api)
+
+		// A/B experiment assignments and engagement events
+		experimentController := controllers.NewExperimentController(db)
+		experimentController.RegisterExperimentRoutes(api)
+
+		// Weekly performance reports linked from the notification center
+		reportController := controllers.NewReportController(db)
+		reportController.RegisterReportRoutes(api)
+
+		// Account self-service, including data erasure requests
+		accountController := controllers.NewAccountController(db)
+		accountController.RegisterAccountRoutes(api)
+
+		// Realtime price WebSocket with membership-based subscription limits
+		realtimeController := controllers.NewRealtimeController()
+		realtimeController.RegisterRealtimeRoutes(api)
+
 		// Trading routes
 		trading := api.Group("/trading")
 		{
@@ -513,4 +787,11 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 			trading.GET("/portfolio", tradingController.GetPortfolio)
 		}
 	}
+
+	// OpenAPI contract of the routes above, always public so API consumers can fetch it.
+	// Swagger UI is public outside production; in production it is only served under /admin/api-docs.
+	router.GET(apidocs.SpecPath, apidocs.SpecHandler(router))
+	if env := os.Getenv("ENVIRONMENT"); env != "" && env != "production" {
+		router.GET(apidocs.APIPrefix+"/docs", apidocs.SwaggerUI)
+	}
 }
@@ -1,14 +1,23 @@
 package scheduler
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"go_backend_project/services/analysis"
 	"go_backend_project/services/datafetcher"
+	"go_backend_project/services/pipeline"
+	"go_backend_project/services/reports"
+	"go_backend_project/services/signals"
 	"github.com/go-co-op/gocron"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Scheduler manages scheduled jobs
@@ -64,11 +73,86 @@ func (s *Scheduler) Start() {
 		}
 	})
 
+	// Move active signals to hit_target/stopped/expired daily at 16:45 (after the daily price sync)
+	s.cron.Every(1).Day().At("16:45").Do(func() {
+		s.updateSignalLifecycles()
+	})
+
+	// Track trade idea outcomes on the day's prices daily at 16:50
+	s.cron.Every(1).Day().At("16:50").Do(func() {
+		s.evaluateIdeas()
+	})
+
+	// Emit deduplicated rule signals every 15 minutes during trading hours
+	s.cron.Every(15).Minutes().Do(func() {
+		if isMarketOpen() {
+			s.emitRuleSignals()
+		}
+	})
+
+	// Notify signal subscriptions of newly matching stocks every 15 minutes during trading hours
+	s.cron.Every(15).Minutes().Do(func() {
+		if isMarketOpen() {
+			s.checkSignalAlerts()
+		}
+	})
+
+	// Check sync failures, API error rates and MongoDB errors against their thresholds every minute
+	s.cron.Every(1).Minute().Do(func() {
+		s.checkHealth()
+	})
+
+	// Start the daily data pipeline (sync, validate, indicators, snapshot, signals, notify) at its configured time
+	s.cron.Every(1).Minute().Do(func() {
+		s.runDataPipeline()
+	})
+
+	// Expire cancelled subscriptions past their end date daily at 00:15
+	s.cron.Every(1).Day().At("00:15").Do(func() {
+		s.expireSubscriptions()
+	})
+
+	// Roll up yesterday's API usage and signal analytics daily at 00:20, before usage logs are pruned
+	s.cron.Every(1).Day().At("00:20").Do(func() {
+		s.rollupAnalytics()
+	})
+
 	// Cleanup old data weekly on Sunday at 01:00
 	s.cron.Every(1).Week().Sunday().At("01:00").Do(func() {
 		s.cleanupOldData()
 	})
 
+	// Generate the weekly user reports on Saturday at 07:00, after Friday's data is in
+	s.cron.Every(1).Week().Saturday().At("07:00").Do(func() {
+		s.generateWeeklyReports()
+	})
+
+	// Sync corporate events (dividends, AGMs, rights issues) daily at 06:00
+	s.cron.Every(1).Day().At("06:00").Do(func() {
+		s.syncCorporateEvents()
+	})
+
+	// Persist end-of-day order book snapshots daily at 15:05 (after market close)
+	s.cron.Every(1).Day().At("15:05").Do(func() {
+		s.persistOrderBookSnapshots()
+	})
+
+	// Prune price history, signal history, audit logs, sync history, intraday ticks and API
+	// usage logs past their retention periods daily at 01:30
+	s.cron.Every(1).Day().At("01:30").Do(func() {
+		s.pruneRetention()
+	})
+
+	// Purge expired signal trash daily at 02:00
+	s.cron.Every(1).Day().At("02:00").Do(func() {
+		s.purgeSignalTrash()
+	})
+
+	// Re-run the rolling backtest of all active signal rules nightly at 03:00
+	s.cron.Every(1).Day().At("03:00").Do(func() {
+		s.runNightlyRuleBacktests()
+	})
+
 	s.cron.StartAsync()
 	log.Println("Scheduler started successfully")
 }
@@ -102,10 +186,14 @@ func (s *Scheduler) fetchRealtimeData() {
 
 // fetchDailyHistoricalData fetches historical data for the previous day
 func (s *Scheduler) fetchDailyHistoricalData() {
+	today := time.Now()
+	if !services.MarketCalendar().IsTradingDay(today) {
+		log.Println("Not a trading day, skipping daily historical data fetch")
+		return
+	}
 	log.Println("Fetching daily historical data...")
 
-	today := time.Now()
-	yesterday := today.AddDate(0, 0, -1)
+	yesterday := services.MarketCalendar().PreviousTradingDay(today)
 
 	var stocks []models.Stock
 	if err := s.db.Where("status = ?", "active").Find(&stocks).Error; err != nil {
@@ -125,6 +213,10 @@ func (s *Scheduler) fetchDailyHistoricalData() {
 
 // calculateDailyIndicators calculates technical indicators for all stocks
 func (s *Scheduler) calculateDailyIndicators() {
+	if !services.MarketCalendar().IsTradingDay(time.Now()) {
+		log.Println("Not a trading day, skipping daily indicator calculation")
+		return
+	}
 	log.Println("Calculating daily technical indicators...")
 
 	var stocks []models.Stock
@@ -155,6 +247,11 @@ func (s *Scheduler) checkUserAlerts() {
 	}
 
 	for _, alert := range alerts {
+		if models.IsLimitUserAlertType(alert.AlertType) {
+			s.checkLimitAlert(alert)
+			continue
+		}
+
 		// Get latest price
 		var latestPrice models.StockPrice
 		if err := s.db.Where("stock_id = ?", alert.StockID).Order("date DESC").First(&latestPrice).Error; err != nil {
@@ -178,22 +275,59 @@ func (s *Scheduler) checkUserAlerts() {
 				"triggered_at": now,
 			})
 
-			// Here you would send email/push notification
+			services.NotifyLocalUser(s.db, alert.UserID, models.NotificationCategoryPriceAlert,
+				fmt.Sprintf("%s price alert", alert.Stock.Symbol),
+				fmt.Sprintf("%s closed at %s (%s %s)", alert.Stock.Symbol, latestPrice.Close.String(), alert.AlertType, alert.TargetValue.String()),
+				map[string]interface{}{"alert_id": alert.ID, "stock": alert.Stock.Symbol, "price": latestPrice.Close})
 			log.Printf("Alert triggered for user %d, stock %s", alert.UserID, alert.Stock.Symbol)
 		}
 	}
 }
 
+// checkLimitAlert triggers a ceiling or floor alert from the realtime price of its stock
+func (s *Scheduler) checkLimitAlert(alert models.UserAlert) {
+	if services.GlobalRealtimeService == nil {
+		return
+	}
+	price := services.GlobalRealtimeService.LatestPrice(alert.Stock.Symbol)
+	if price == nil || services.PriceLimitStatus(price) == "" {
+		// Codes nobody streams are not in the cache, or may be stale there
+		fresh, err := services.GlobalRealtimeService.RefreshPrice(alert.Stock.Symbol)
+		if err != nil {
+			return
+		}
+		price = fresh
+	}
+
+	status := services.PriceLimitStatus(price)
+	shouldTrigger := status == alert.AlertType ||
+		alert.AlertType == models.UserAlertTypeApproachingCeiling && status == services.LimitHitCeiling
+	if !shouldTrigger {
+		return
+	}
+
+	now := time.Now()
+	s.db.Model(&alert).Updates(map[string]interface{}{
+		"is_triggered": true,
+		"triggered_at": now,
+	})
+
+	title := map[string]string{
+		services.LimitHitCeiling:         "hit the ceiling",
+		services.LimitHitFloor:           "hit the floor",
+		services.LimitApproachingCeiling: "is approaching the ceiling",
+	}[status]
+	services.NotifyLocalUser(s.db, alert.UserID, models.NotificationCategoryPriceAlert,
+		fmt.Sprintf("%s %s", alert.Stock.Symbol, title),
+		fmt.Sprintf("%s at %.2f (%+.2f%%), ceiling %.2f, floor %.2f", alert.Stock.Symbol, price.Price, price.ChangePercent, price.Ceiling, price.Floor),
+		map[string]interface{}{"alert_id": alert.ID, "stock": alert.Stock.Symbol, "price": price.Price, "status": status})
+	log.Printf("Limit alert triggered for user %d, stock %s (%s)", alert.UserID, alert.Stock.Symbol, status)
+}
+
 // cleanupOldData removes old data to save storage
 func (s *Scheduler) cleanupOldData() {
 	log.Println("Cleaning up old data...")
 
-	// Delete price data older than 5 years
-	fiveYearsAgo := time.Now().AddDate(-5, 0, 0)
-	if err := s.db.Where("date < ?", fiveYearsAgo).Delete(&models.StockPrice{}).Error; err != nil {
-		log.Printf("Error cleaning up old prices: %v", err)
-	}
-
 	// Delete old signals (keep last 3 months)
 	threeMonthsAgo := time.Now().AddDate(0, -3, 0)
 	if err := s.db.Where("created_at < ?", threeMonthsAgo).Delete(&models.Signal{}).Error; err != nil {
@@ -207,19 +341,245 @@ func (s *Scheduler) cleanupOldData() {
 		log.Printf("Error cleaning up old alerts: %v", err)
 	}
 
+	// Delete read notifications past their retention period
+	notificationCutoff := time.Now().AddDate(0, 0, -models.NotificationRetentionDays)
+	if err := s.db.Where("read_at IS NOT NULL AND read_at < ?", notificationCutoff).
+		Delete(&models.Notification{}).Error; err != nil {
+		log.Printf("Error cleaning up old notifications: %v", err)
+	}
+
 	log.Println("Cleanup completed")
 }
 
-// isMarketOpen checks if Vietnamese stock market is currently open
-func isMarketOpen() bool {
-	now := time.Now()
+// expireSubscriptions marks subscriptions that won't renew as expired once they end and tells
+// their users
+func (s *Scheduler) expireSubscriptions() {
+	var subscriptions []models.Subscription
+	if err := s.db.Preload("Plan").Where("status IN ? AND auto_renew = ? AND end_date < ?",
+		[]string{"active", "cancelled"}, false, time.Now()).Find(&subscriptions).Error; err != nil {
+		log.Printf("Error loading ending subscriptions: %v", err)
+		return
+	}
+
+	for _, sub := range subscriptions {
+		if err := s.db.Model(&sub).Update("status", "expired").Error; err != nil {
+			log.Printf("Error expiring subscription %d: %v", sub.ID, err)
+			continue
+		}
+		services.NotifyLocalUser(s.db, sub.UserID, models.NotificationCategoryMembership,
+			"Membership expired",
+			fmt.Sprintf("Your %s membership ended on %s", sub.Plan.Name, sub.EndDate.Format("2006-01-02")),
+			map[string]interface{}{"subscription_id": sub.ID, "plan_id": sub.PlanID, "status": "expired"})
+	}
+	if len(subscriptions) > 0 {
+		log.Printf("Expired %d subscriptions", len(subscriptions))
+	}
+}
+
+// generateWeeklyReports renders last week's performance report of every user
+func (s *Scheduler) generateWeeklyReports() {
+	result, err := reports.GenerateWeekly(context.Background(), s.db, reports.WeekEnd(time.Now()), nil)
+	if err != nil {
+		log.Printf("Error generating weekly reports: %v", err)
+		return
+	}
+	log.Printf("Generated %d weekly reports (%d with PDF, %d failed) for %d users",
+		result.Generated, result.WithPDF, result.Failed, result.Users)
+}
+
+// syncCorporateEvents refreshes the recent and upcoming events of all listed stocks
+func (s *Scheduler) syncCorporateEvents() {
+	codes, err := services.EventSyncCodes()
+	if err != nil {
+		log.Printf("Error loading codes for event sync: %v", err)
+		return
+	}
+	result, err := services.SyncCorporateEvents(context.Background(), s.db, codes, nil)
+	if err != nil {
+		log.Printf("Error syncing corporate events: %v", err)
+		return
+	}
+	log.Printf("Synced %d corporate events for %d stocks (%d errors)", result.Events, result.Codes, len(result.Errors))
+}
+
+// purgeSignalTrash permanently deletes soft-deleted groups, conditions, rules
+// and templates once they exceed the trash retention period
+func (s *Scheduler) purgeSignalTrash() {
+	cutoff := time.Now().AddDate(0, 0, -models.SignalTrashRetentionDays)
+
+	trash := []struct {
+		name  string
+		model interface{}
+	}{
+		{"conditions", &models.SignalCondition{}},
+		{"condition groups", &models.SignalConditionGroup{}},
+		{"signal rules", &models.SignalRule{}},
+		{"signal templates", &models.SignalTemplate{}},
+	}
+
+	for _, t := range trash {
+		result := s.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(t.model)
+		if result.Error != nil {
+			log.Printf("Error purging deleted %s: %v", t.name, result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Purged %d deleted %s", result.RowsAffected, t.name)
+		}
+	}
+}
+
+// persistOrderBookSnapshots stores today's last depth and spread statistics per code
+func (s *Scheduler) persistOrderBookSnapshots() {
+	if services.GlobalRealtimeService == nil || !services.MarketCalendar().IsTradingDay(time.Now()) {
+		return
+	}
+
+	saved := 0
+	for _, stats := range services.GlobalRealtimeService.GetDepthDayStats() {
+		bids, _ := json.Marshal(stats.Last.Bids)
+		asks, _ := json.Marshal(stats.Last.Asks)
+
+		snapshot := models.OrderBookSnapshot{
+			Code:         stats.Last.Code,
+			Date:         stats.Date,
+			Bids:         string(bids),
+			Asks:         string(asks),
+			BestBid:      stats.Last.BestBid,
+			BestAsk:      stats.Last.BestAsk,
+			Spread:       stats.Last.Spread,
+			SpreadPct:    stats.Last.SpreadPct,
+			AvgSpreadPct: stats.AvgSpreadPct(),
+			MinSpreadPct: stats.MinSpreadPct,
+			MaxSpreadPct: stats.MaxSpreadPct,
+			Samples:      stats.Samples,
+		}
+
+		err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}, {Name: "date"}},
+			UpdateAll: true,
+		}).Create(&snapshot).Error
+		if err != nil {
+			log.Printf("Error saving order book snapshot for %s: %v", snapshot.Code, err)
+			continue
+		}
+		saved++
+	}
+
+	if saved > 0 {
+		log.Printf("Saved %d end-of-day order book snapshots", saved)
+	}
+}
+
+// pruneRetention removes the data of every dataset past its retention period; with
+// RETENTION_DRY_RUN=true it only logs what would be removed
+func (s *Scheduler) pruneRetention() {
+	dryRun := os.Getenv("RETENTION_DRY_RUN") == "true"
+	if _, err := services.RunRetention(context.Background(), s.db, dryRun, nil); err != nil {
+		log.Printf("Error pruning data past retention: %v", err)
+	}
+}
+
+// runNightlyRuleBacktests backtests active signal rules and flags degraded ones
+func (s *Scheduler) runNightlyRuleBacktests() {
+	if signals.GlobalConditionEvaluator == nil {
+		log.Println("Condition evaluator not initialized, skipping nightly rule backtest")
+		return
+	}
+	if err := signals.GlobalConditionEvaluator.RunNightlyRuleBacktests(); err != nil {
+		log.Printf("Error running nightly rule backtest: %v", err)
+	}
+}
 
-	// Check if weekend
-	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
-		return false
+// emitRuleSignals screens active rules and records signals that pass deduplication
+func (s *Scheduler) emitRuleSignals() {
+	if signals.GlobalConditionEvaluator == nil {
+		log.Println("Condition evaluator not initialized, skipping signal emission")
+		return
+	}
+	emitted, err := signals.GlobalConditionEvaluator.RunRuleSignalEmission()
+	if err != nil {
+		log.Printf("Rule signal emission failed: %v", err)
+		return
+	}
+	log.Printf("Emitted %d new rule signals", len(emitted))
+}
+
+// checkSignalAlerts notifies signal alerts and user subscriptions of stocks that started matching
+func (s *Scheduler) checkSignalAlerts() {
+	if signals.GlobalConditionEvaluator == nil {
+		log.Println("Condition evaluator not initialized, skipping signal alerts")
+		return
 	}
+	sent, err := signals.GlobalConditionEvaluator.CheckSignalAlerts()
+	if err != nil {
+		log.Printf("Signal alert check failed: %v", err)
+		return
+	}
+	log.Printf("Sent %d signal alert notifications", sent)
+}
 
-	// Vietnamese stock market hours: 9:00 - 15:00 (local time)
-	hour := now.Hour()
-	return hour >= 9 && hour < 15
+// updateSignalLifecycles checks active signals against the latest daily prices
+func (s *Scheduler) updateSignalLifecycles() {
+	if signals.GlobalConditionEvaluator == nil {
+		log.Println("Condition evaluator not initialized, skipping signal lifecycle update")
+		return
+	}
+	transitioned, err := signals.GlobalConditionEvaluator.UpdateSignalLifecycles()
+	if err != nil {
+		log.Printf("Signal lifecycle update failed: %v", err)
+		return
+	}
+	log.Printf("Signal lifecycle update: %d signals closed", transitioned)
+}
+
+// evaluateIdeas updates the returns of open trade ideas and closes those that reached their
+// target, stop or horizon
+func (s *Scheduler) evaluateIdeas() {
+	open, closed, err := services.EvaluateIdeas(s.db)
+	if err != nil {
+		log.Printf("Trade idea evaluation failed: %v", err)
+		return
+	}
+	log.Printf("Trade idea evaluation: %d closed, %d still open", closed, open)
+}
+
+// rollupAnalytics aggregates the API usage logs and emitted signals of the previous days into
+// the daily analytics shown on the admin analytics page
+func (s *Scheduler) rollupAnalytics() {
+	days, err := services.RollupAnalytics(s.db, time.Now())
+	if err != nil {
+		log.Printf("Analytics rollup failed: %v", err)
+		return
+	}
+	log.Printf("Rolled up analytics of %d day(s)", days)
+}
+
+// checkHealth runs the health checks, opening and resolving incidents and alerting operators
+func (s *Scheduler) checkHealth() {
+	if services.GlobalHealthMonitor == nil {
+		return
+	}
+	if _, err := services.GlobalHealthMonitor.Check(time.Now()); err != nil {
+		log.Printf("Health check failed: %v", err)
+	}
+}
+
+// runDataPipeline queues the daily data pipeline once its schedule time is reached
+func (s *Scheduler) runDataPipeline() {
+	if pipeline.GlobalDataPipeline == nil || !pipeline.GlobalDataPipeline.Due(time.Now()) {
+		return
+	}
+	job, err := pipeline.GlobalDataPipeline.Submit(pipeline.TriggerScheduled, "scheduler")
+	if err != nil {
+		log.Printf("Scheduled data pipeline not started: %v", err)
+		return
+	}
+	log.Printf("Scheduled data pipeline queued as job %s", job.ID)
+}
+
+// isMarketOpen checks if Vietnamese stock market is currently open
+// (trading day, ATO through ATC, excluding the lunch break)
+func isMarketOpen() bool {
+	return services.MarketCalendar().IsMarketOpen(time.Now())
 }
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// NotifyAdmins records an admin notification; metadata is stored as JSON
+func NotifyAdmins(db *gorm.DB, level, category, title, message string, metadata interface{}) {
+	if db == nil {
+		return
+	}
+
+	metaJSON := []byte("{}")
+	if metadata != nil {
+		if data, err := json.Marshal(metadata); err == nil {
+			metaJSON = data
+		}
+	}
+
+	notification := &models.AdminNotification{
+		Level:    level,
+		Category: category,
+		Title:    title,
+		Message:  message,
+		Metadata: string(metaJSON),
+	}
+
+	if err := db.Create(notification).Error; err != nil {
+		log.Printf("Failed to record admin notification %q: %v", title, err)
+		return
+	}
+	log.Printf("Admin notification [%s] %s: %s", level, title, message)
+}
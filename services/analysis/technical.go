@@ -249,6 +249,39 @@ func (ta *TechnicalAnalysis) CalculateStochastic(stockID uint, period int, date
 	}, nil
 }
 
+// CalculateATR calculates the Average True Range over period days, as the simple average of the
+// true ranges
+func (ta *TechnicalAnalysis) CalculateATR(stockID uint, period int, date time.Time) (decimal.Decimal, error) {
+	var prices []models.StockPrice
+	err := ta.db.Where("stock_id = ? AND date <= ?", stockID, date).
+		Order("date DESC").
+		Limit(period + 1). // The first true range needs the previous close
+		Find(&prices).Error
+
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if len(prices) < period+1 {
+		return decimal.Zero, fmt.Errorf("insufficient data for ATR%d calculation", period)
+	}
+
+	sum := decimal.Zero
+	for i := 0; i < period; i++ {
+		prevClose := prices[i+1].Close
+		trueRange := prices[i].High.Sub(prices[i].Low)
+		if r := prices[i].High.Sub(prevClose).Abs(); r.GreaterThan(trueRange) {
+			trueRange = r
+		}
+		if r := prices[i].Low.Sub(prevClose).Abs(); r.GreaterThan(trueRange) {
+			trueRange = r
+		}
+		sum = sum.Add(trueRange)
+	}
+
+	return sum.Div(decimal.NewFromInt(int64(period))), nil
+}
+
 // SaveIndicator saves calculated indicator to database
 func (ta *TechnicalAnalysis) SaveIndicator(stockID uint, date time.Time, indicatorType string, period int, value, signal, histogram decimal.Decimal) error {
 	indicator := models.TechnicalIndicator{
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// API usage logging and analytics settings
+const (
+	// APIUsageFlushInterval is how often buffered usage logs are written
+	APIUsageFlushInterval = 10 * time.Second
+	// APIUsageMaxBuffered bounds the logs waiting for a flush; newer ones are dropped beyond it
+	APIUsageMaxBuffered = 10000
+	apiUsageBatchSize   = 500
+
+	DefaultAnalyticsDays  = 30
+	MaxAnalyticsDays      = 366
+	DefaultAnalyticsTopN  = 20
+	MaxAnalyticsTopN      = 100
+	analyticsCatchUpDays  = 7 // days back the rollup job fills in when they have no rollup yet
+	AnalyticsDateFormat   = "2006-01-02"
+	analyticsServerErrors = 500
+)
+
+// ErrInvalidAnalyticsRange is returned for date ranges that are malformed or too long
+var ErrInvalidAnalyticsRange = errors.New("invalid analytics range")
+
+// APIUsageRecorder buffers API usage logs in memory and writes them in batches, so that logging
+// costs requests no database round trip
+type APIUsageRecorder struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	pending []models.APIUsageLog
+	dropped int64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// GlobalAPIUsage records the requests of the public API
+var GlobalAPIUsage *APIUsageRecorder
+
+// NewAPIUsageRecorder creates a recorder and starts its flush loop; nil without a database
+func NewAPIUsageRecorder(db *gorm.DB) *APIUsageRecorder {
+	if db == nil {
+		return nil
+	}
+	r := &APIUsageRecorder{db: db, stop: make(chan struct{}), done: make(chan struct{})}
+	go r.loop()
+	return r
+}
+
+// Record queues one usage log
+func (r *APIUsageRecorder) Record(entry models.APIUsageLog) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) >= APIUsageMaxBuffered {
+		r.dropped++
+		return
+	}
+	r.pending = append(r.pending, entry)
+}
+
+// Flush writes the buffered logs
+func (r *APIUsageRecorder) Flush() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	batch, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Dropped %d API usage logs while the buffer was full", dropped)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(batch, apiUsageBatchSize).Error
+}
+
+// Close stops the flush loop and writes what is still buffered
+func (r *APIUsageRecorder) Close() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+func (r *APIUsageRecorder) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(APIUsageFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Printf("Failed to write API usage logs: %v", err)
+			}
+		case <-r.stop:
+			if err := r.Flush(); err != nil {
+				log.Printf("Failed to write API usage logs: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// AnalyticsDayTotals are the totals of one day
+type AnalyticsDayTotals struct {
+	Date        string  `json:"date"`
+	Requests    int64   `json:"requests"`
+	ActiveUsers int64   `json:"active_users"` // distinct signed-in users
+	Errors      int64   `json:"errors"`
+	AvgMs       float64 `json:"avg_ms"`
+}
+
+// AnalyticsRank is one entry of a top list over the report's range
+type AnalyticsRank struct {
+	Key      string  `json:"key"`
+	Label    string  `json:"label,omitempty"`     // the rule name for rules
+	Count    int64   `json:"count"`               // requests, views or emitted signals
+	UserDays int64   `json:"user_days,omitempty"` // distinct users per day, summed over the days
+	Errors   int64   `json:"errors,omitempty"`
+	AvgMs    float64 `json:"avg_ms,omitempty"`
+}
+
+// AnalyticsReport aggregates usage and signal engagement over a date range
+type AnalyticsReport struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	Requests     int64                `json:"requests"`
+	AvgDAU       float64              `json:"avg_dau"`
+	PeakDAU      int64                `json:"peak_dau"`
+	Days         []AnalyticsDayTotals `json:"days"`
+	TopEndpoints []AnalyticsRank      `json:"top_endpoints"`
+	TopStocks    []AnalyticsRank      `json:"top_stocks"`
+	TopRules     []AnalyticsRank      `json:"top_rules"` // most triggered, by emitted signals
+	GeneratedAt  time.Time            `json:"generated_at"`
+}
+
+// analyticsDayBounds returns the market-time day of t as a date and its start and end instants
+func analyticsDayBounds(t time.Time) (time.Time, time.Time, time.Time) {
+	local := t.In(MarketCalendar().Location())
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	return date, start, start.AddDate(0, 0, 1)
+}
+
+// computeAnalyticsDay aggregates the usage logs and emitted signals of one market day
+func computeAnalyticsDay(db *gorm.DB, day time.Time) ([]models.AnalyticsDaily, error) {
+	date, start, end := analyticsDayBounds(day)
+	logs := func() *gorm.DB {
+		return db.Model(&models.APIUsageLog{}).Where("at >= ? AND at < ?", start, end)
+	}
+	selectStats := fmt.Sprintf("COUNT(*) AS count, COUNT(DISTINCT NULLIF(user_id, '')) AS users, "+
+		"COUNT(CASE WHEN status >= %d THEN 1 END) AS errors, COALESCE(AVG(duration_ms), 0) AS avg_ms", analyticsServerErrors)
+
+	type row struct {
+		Key    string
+		Method string
+		Count  int64
+		Users  int64
+		Errors int64
+		AvgMs  float64
+	}
+	var rows []models.AnalyticsDaily
+	add := func(metric string, r row) {
+		rows = append(rows, models.AnalyticsDaily{
+			Date: date, Metric: metric, Key: r.Key,
+			Count: r.Count, Users: r.Users, Errors: r.Errors, AvgMs: math.Round(r.AvgMs*10) / 10,
+		})
+	}
+
+	var total row
+	if err := logs().Select(selectStats).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+	add(models.AnalyticsMetricDaily, total)
+
+	var endpoints []row
+	if err := logs().Select("method, route AS key, " + selectStats).Group("method, route").Scan(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range endpoints {
+		r.Key = r.Method + " " + r.Key
+		add(models.AnalyticsMetricEndpoint, r)
+	}
+
+	var stocks []row
+	if err := logs().Select("stock_code AS key, "+selectStats).Where("stock_code <> '' AND method = ?", "GET").
+		Group("stock_code").Scan(&stocks).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range stocks {
+		add(models.AnalyticsMetricStock, r)
+	}
+
+	var rules []struct {
+		RuleID uint
+		Count  int64
+	}
+	if err := db.Model(&models.SignalHistory{}).Select("rule_id, COUNT(*) AS count").
+		Where("rule_id > 0 AND emitted_at >= ? AND emitted_at < ?", start, end).
+		Group("rule_id").Scan(&rules).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		add(models.AnalyticsMetricRule, row{Key: strconv.FormatUint(uint64(r.RuleID), 10), Count: r.Count})
+	}
+	return rows, nil
+}
+
+// RollupAnalyticsDay stores the analytics of one market day, replacing an earlier rollup
+func RollupAnalyticsDay(db *gorm.DB, day time.Time) (int, error) {
+	rows, err := computeAnalyticsDay(db, day)
+	if err != nil {
+		return 0, err
+	}
+	date, _, _ := analyticsDayBounds(day)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("date = ?", date.Format(AnalyticsDateFormat)).Delete(&models.AnalyticsDaily{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(rows, apiUsageBatchSize).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// RollupAnalytics rolls up yesterday, and the days of the last week that have no rollup yet,
+// e.g. because the server was down when the job was due. Returns the number of days rolled up.
+func RollupAnalytics(db *gorm.DB, now time.Time) (int, error) {
+	days := 0
+	for back := 1; back <= analyticsCatchUpDays; back++ {
+		day := now.AddDate(0, 0, -back)
+		if back > 1 {
+			date, _, _ := analyticsDayBounds(day)
+			var count int64
+			if err := db.Model(&models.AnalyticsDaily{}).Where("date = ? AND metric = ?",
+				date.Format(AnalyticsDateFormat), models.AnalyticsMetricDaily).Count(&count).Error; err != nil {
+				return days, err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+		if _, err := RollupAnalyticsDay(db, day); err != nil {
+			return days, err
+		}
+		days++
+	}
+	return days, nil
+}
+
+// ParseAnalyticsRange reads from and to dates (YYYY-MM-DD, market days, inclusive), defaulting
+// to the last DefaultAnalyticsDays days up to today
+func ParseAnalyticsRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	today, _, _ := analyticsDayBounds(now)
+	end := today
+	if to != "" {
+		t, err := time.Parse(AnalyticsDateFormat, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidAnalyticsRange)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -(DefaultAnalyticsDays - 1))
+	if from != "" {
+		t, err := time.Parse(AnalyticsDateFormat, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidAnalyticsRange)
+		}
+		start = t
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", ErrInvalidAnalyticsRange)
+	}
+	if end.Sub(start) >= MaxAnalyticsDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d days", ErrInvalidAnalyticsRange, MaxAnalyticsDays)
+	}
+	return start, end, nil
+}
+
+// GetAnalyticsReport aggregates the daily rollups between from and to, with today computed
+// from the live logs, into daily totals and the top n endpoints, stocks and rules
+func GetAnalyticsReport(db *gorm.DB, from, to time.Time, n int) (*AnalyticsReport, error) {
+	if n < 1 || n > MaxAnalyticsTopN {
+		n = DefaultAnalyticsTopN
+	}
+	now := time.Now()
+	today, _, _ := analyticsDayBounds(now)
+
+	var rows []models.AnalyticsDaily
+	if err := db.Where("date >= ? AND date <= ? AND date < ?", from.Format(AnalyticsDateFormat),
+		to.Format(AnalyticsDateFormat), today.Format(AnalyticsDateFormat)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if !to.Before(today) && !from.After(today) {
+		live, err := computeAnalyticsDay(db, now)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, live...)
+	}
+
+	report := &AnalyticsReport{
+		From:        from.Format(AnalyticsDateFormat),
+		To:          to.Format(AnalyticsDateFormat),
+		Days:        []AnalyticsDayTotals{},
+		GeneratedAt: now,
+	}
+	ranks := map[string]map[string]*AnalyticsRank{
+		models.AnalyticsMetricEndpoint: {},
+		models.AnalyticsMetricStock:    {},
+		models.AnalyticsMetricRule:     {},
+	}
+	var dauSum int64
+	for _, r := range rows {
+		if r.Metric == models.AnalyticsMetricDaily {
+			report.Days = append(report.Days, AnalyticsDayTotals{
+				Date: r.Date.Format(AnalyticsDateFormat), Requests: r.Count, ActiveUsers: r.Users, Errors: r.Errors, AvgMs: r.AvgMs,
+			})
+			report.Requests += r.Count
+			dauSum += r.Users
+			if r.Users > report.PeakDAU {
+				report.PeakDAU = r.Users
+			}
+			continue
+		}
+		byKey, ok := ranks[r.Metric]
+		if !ok {
+			continue
+		}
+		rank := byKey[r.Key]
+		if rank == nil {
+			rank = &AnalyticsRank{Key: r.Key}
+			byKey[r.Key] = rank
+		}
+		// Average latency weighted by requests
+		if total := rank.Count + r.Count; total > 0 {
+			rank.AvgMs = math.Round((rank.AvgMs*float64(rank.Count)+r.AvgMs*float64(r.Count))/float64(total)*10) / 10
+		}
+		rank.Count += r.Count
+		rank.UserDays += r.Users
+		rank.Errors += r.Errors
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	if len(report.Days) > 0 {
+		report.AvgDAU = math.Round(float64(dauSum)/float64(len(report.Days))*10) / 10
+	}
+
+	report.TopEndpoints = topAnalyticsRanks(ranks[models.AnalyticsMetricEndpoint], n)
+	report.TopStocks = topAnalyticsRanks(ranks[models.AnalyticsMetricStock], n)
+	report.TopRules = topAnalyticsRanks(ranks[models.AnalyticsMetricRule], n)
+	labelAnalyticsRules(db, report.TopRules)
+	return report, nil
+}
+
+// topAnalyticsRanks returns the n entries with the highest counts
+func topAnalyticsRanks(byKey map[string]*AnalyticsRank, n int) []AnalyticsRank {
+	list := make([]AnalyticsRank, 0, len(byKey))
+	for _, rank := range byKey {
+		list = append(list, *rank)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// labelAnalyticsRules names the ranked rules, deleted ones included
+func labelAnalyticsRules(db *gorm.DB, ranks []AnalyticsRank) {
+	if len(ranks) == 0 {
+		return
+	}
+	ids := make([]uint64, 0, len(ranks))
+	for _, r := range ranks {
+		if id, err := strconv.ParseUint(r.Key, 10, 32); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	var rules []models.SignalRule
+	db.Unscoped().Select("id", "name").Where("id IN ?", ids).Find(&rules)
+	names := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		names[strconv.FormatUint(uint64(rule.ID), 10)] = rule.Name
+	}
+	for i := range ranks {
+		ranks[i].Label = names[ranks[i].Key]
+	}
+}
@@ -0,0 +1,96 @@
+package backtesting
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+)
+
+// TradingDaysPerYear annualizes the daily alpha
+const TradingDaysPerYear = 252
+
+// compareWithBenchmark sets the VN-Index buy & hold return over the backtest's days, the alpha
+// and beta of the daily equity returns against the index, and the relative equity curve: the
+// equity over the index, both rebased to 100 on the first day. It leaves them empty without
+// index data for the period.
+func compareWithBenchmark(backtest *models.Backtest, daily map[string]decimal.Decimal) {
+	index := services.LoadBenchmark()
+	if index == nil || len(daily) == 0 {
+		return
+	}
+
+	days := make([]string, 0, len(daily))
+	for date := range daily {
+		days = append(days, date)
+	}
+	sort.Strings(days)
+
+	var equity, market []float64
+	for _, date := range days {
+		close, ok := index.CloseOn(date)
+		if !ok || close <= 0 {
+			continue
+		}
+		days[len(equity)] = date
+		equity = append(equity, daily[date].InexactFloat64())
+		market = append(market, close)
+	}
+	days = days[:len(equity)]
+	if len(equity) < 2 || equity[0] <= 0 {
+		return
+	}
+
+	backtest.BenchmarkCode = services.MarketIndexCode
+	backtest.BenchmarkReturn = decimal.NewFromFloat(market[len(market)-1]/market[0] - 1).Round(4)
+
+	relative := make(map[string]float64, len(days))
+	for i, date := range days {
+		relative[date] = math.Round((equity[i]/equity[0])/(market[i]/market[0])*100*100) / 100
+	}
+	if data, err := json.Marshal(relative); err == nil {
+		backtest.RelativeEquity = string(data)
+	}
+
+	alpha, beta, ok := alphaBeta(equity, market)
+	if ok {
+		backtest.Alpha = decimal.NewFromFloat(alpha).Round(4)
+		backtest.Beta = decimal.NewFromFloat(beta).Round(4)
+	}
+}
+
+// alphaBeta regresses the daily returns of equity on those of market, returning the annualized
+// alpha (without a risk-free rate) and the beta
+func alphaBeta(equity, market []float64) (alpha, beta float64, ok bool) {
+	n := len(equity) - 1
+	if n < 2 {
+		return 0, 0, false
+	}
+	rs := make([]float64, n)
+	rm := make([]float64, n)
+	var meanS, meanM float64
+	for i := 0; i < n; i++ {
+		rs[i] = equity[i+1]/equity[i] - 1
+		rm[i] = market[i+1]/market[i] - 1
+		meanS += rs[i]
+		meanM += rm[i]
+	}
+	meanS /= float64(n)
+	meanM /= float64(n)
+
+	var cov, variance float64
+	for i := 0; i < n; i++ {
+		cov += (rs[i] - meanS) * (rm[i] - meanM)
+		variance += (rm[i] - meanM) * (rm[i] - meanM)
+	}
+	if variance == 0 {
+		return 0, 0, false
+	}
+	beta = cov / variance
+	alpha = (meanS - beta*meanM) * TradingDaysPerYear
+	return alpha, beta, true
+}
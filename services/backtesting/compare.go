@@ -0,0 +1,155 @@
+package backtesting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go_backend_project/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrBacktestNotFound is returned when a requested backtest does not exist
+var ErrBacktestNotFound = errors.New("backtest not found")
+
+// ComparisonMetrics holds the headline metrics of one backtest in a comparison
+type ComparisonMetrics struct {
+	BacktestID   uint      `json:"backtest_id"`
+	Name         string    `json:"name"`
+	StrategyID   uint      `json:"strategy_id"`
+	StrategyName string    `json:"strategy_name"`
+	StartDate    time.Time `json:"start_date"`
+	EndDate      time.Time `json:"end_date"`
+	TotalReturn  float64   `json:"total_return"`
+	AnnualReturn float64   `json:"annual_return"`
+	MaxDrawdown  float64   `json:"max_drawdown"`
+	SharpeRatio  float64   `json:"sharpe_ratio"`
+	WinRate      float64   `json:"win_rate"`
+	TotalTrades  int       `json:"total_trades"`
+	ProfitFactor float64   `json:"profit_factor"`
+	// Against the benchmark index over the same days
+	BenchmarkReturn float64 `json:"benchmark_return"`
+	Alpha           float64 `json:"alpha"`
+	Beta            float64 `json:"beta"`
+}
+
+// EquitySeries is an equity curve rebased to 100 at the first aligned date;
+// nil entries mark dates with no data for this backtest
+type EquitySeries struct {
+	BacktestID uint       `json:"backtest_id"`
+	Values     []*float64 `json:"values"`
+}
+
+// BacktestComparison aligns several backtests on a common date axis
+type BacktestComparison struct {
+	Metrics      []ComparisonMetrics `json:"metrics"`
+	Dates        []string            `json:"dates"`
+	EquityCurves []EquitySeries      `json:"equity_curves"`
+	SameRange    bool                `json:"same_range"` // all backtests cover the same start/end dates
+}
+
+// CompareBacktests loads completed backtests and aligns their metrics and equity curves
+func (be *BacktestEngine) CompareBacktests(ids []uint) (*BacktestComparison, error) {
+	var backtests []models.Backtest
+	if err := be.db.Preload("Strategy").Where("id IN ?", ids).Find(&backtests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load backtests: %w", err)
+	}
+	if len(backtests) != len(ids) {
+		return nil, fmt.Errorf("%w: found %d of %d requested backtests", ErrBacktestNotFound, len(backtests), len(ids))
+	}
+
+	// Keep the caller's ordering
+	byID := make(map[uint]models.Backtest, len(backtests))
+	for _, bt := range backtests {
+		byID[bt.ID] = bt
+	}
+
+	comparison := &BacktestComparison{SameRange: true}
+	curves := make(map[uint]map[string]float64, len(ids))
+	dateSet := make(map[string]bool)
+
+	for i, id := range ids {
+		bt := byID[id]
+		comparison.Metrics = append(comparison.Metrics, ComparisonMetrics{
+			BacktestID:   bt.ID,
+			Name:         bt.Name,
+			StrategyID:   bt.StrategyID,
+			StrategyName: bt.Strategy.Name,
+			StartDate:    bt.StartDate,
+			EndDate:      bt.EndDate,
+			TotalReturn:  bt.TotalReturn.InexactFloat64(),
+			AnnualReturn: bt.AnnualReturn.InexactFloat64(),
+			MaxDrawdown:  bt.MaxDrawdown.InexactFloat64(),
+			SharpeRatio:  bt.SharpeRatio.InexactFloat64(),
+			WinRate:      bt.WinRate.InexactFloat64(),
+			TotalTrades:  bt.TotalTrades,
+			ProfitFactor: bt.ProfitFactor.InexactFloat64(),
+
+			BenchmarkReturn: bt.BenchmarkReturn.InexactFloat64(),
+			Alpha:           bt.Alpha.InexactFloat64(),
+			Beta:            bt.Beta.InexactFloat64(),
+		})
+
+		if i > 0 && (!bt.StartDate.Equal(byID[ids[0]].StartDate) || !bt.EndDate.Equal(byID[ids[0]].EndDate)) {
+			comparison.SameRange = false
+		}
+
+		equity, err := decodeEquity(bt.Results)
+		if err != nil {
+			return nil, fmt.Errorf("backtest %d has invalid results: %w", bt.ID, err)
+		}
+		curves[bt.ID] = equity
+		for date := range equity {
+			dateSet[date] = true
+		}
+	}
+
+	for date := range dateSet {
+		comparison.Dates = append(comparison.Dates, date)
+	}
+	sort.Strings(comparison.Dates)
+
+	for _, id := range ids {
+		equity := curves[id]
+		series := EquitySeries{BacktestID: id, Values: make([]*float64, len(comparison.Dates))}
+
+		var base float64
+		for i, date := range comparison.Dates {
+			value, ok := equity[date]
+			if !ok {
+				continue
+			}
+			if base == 0 {
+				base = value
+			}
+			if base == 0 {
+				continue
+			}
+			rebased := value / base * 100
+			series.Values[i] = &rebased
+		}
+		comparison.EquityCurves = append(comparison.EquityCurves, series)
+	}
+
+	return comparison, nil
+}
+
+// decodeEquity reads the daily equity map RunBacktest writes to Results, whose decimals are
+// encoded as strings
+func decodeEquity(results string) (map[string]float64, error) {
+	equity := make(map[string]float64)
+	if results == "" {
+		return equity, nil
+	}
+	var values map[string]decimal.Decimal
+	if err := json.Unmarshal([]byte(results), &values); err != nil {
+		return nil, err
+	}
+	for date, v := range values {
+		equity[date] = v.InexactFloat64()
+	}
+	return equity, nil
+}
@@ -0,0 +1,32 @@
+package backtesting
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// RunBacktest stores the daily equity as decimals, which encode as JSON strings
+func TestDecodeEquityReadsRunBacktestResults(t *testing.T) {
+	daily := map[string]decimal.Decimal{
+		"2024-01-02": decimal.RequireFromString("100000000"),
+		"2024-01-03": decimal.RequireFromString("100500000.5"),
+	}
+	data, err := json.Marshal(daily)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	equity, err := decodeEquity(string(data))
+	if err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if len(equity) != 2 || equity["2024-01-02"] != 100000000 || equity["2024-01-03"] != 100500000.5 {
+		t.Fatalf("equity = %v", equity)
+	}
+
+	if equity, err := decodeEquity(""); err != nil || len(equity) != 0 {
+		t.Fatalf("empty results = %v, %v", equity, err)
+	}
+}
@@ -1,13 +1,16 @@
 package backtesting
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"go_backend_project/services/analysis"
+	"go_backend_project/services/units"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -35,6 +38,24 @@ type BacktestConfig struct {
 	Commission     decimal.Decimal // Commission rate (e.g., 0.15% = 0.0015)
 	Symbols        []string        // Stocks to backtest
 	RiskPerTrade   decimal.Decimal // Risk per trade as % of capital
+	Mode           string          // ModePortfolio ranks each day's entries by signal strength
+	MaxPositions   int             // ModePortfolio: most positions held at once; 0 for no limit
+	Sizing         SizingConfig    // Position sizing; fixed fractional of RiskPerTrade by default
+	Execution      ExecutionConfig // Order matching: slippage, volume cap, settlement
+}
+
+// Validate checks the mode, the position sizing and the order matching of a backtest
+func (config *BacktestConfig) Validate() error {
+	if config.Mode != "" && config.Mode != ModePortfolio {
+		return fmt.Errorf("unknown backtest mode %q: use %s", config.Mode, ModePortfolio)
+	}
+	if config.MaxPositions < 0 {
+		return fmt.Errorf("max positions must not be negative")
+	}
+	if err := config.Sizing.Validate(); err != nil {
+		return err
+	}
+	return config.Execution.Validate()
 }
 
 // Position represents an open position
@@ -43,9 +64,11 @@ type Position struct {
 	Symbol        string
 	Quantity      int64
 	EntryPrice    decimal.Decimal
+	EntryCost     decimal.Decimal // cost of the shares and the buy commission, in VND
 	EntryDate     time.Time
 	CurrentPrice  decimal.Decimal
 	UnrealizedPnL decimal.Decimal
+	PendingSell   bool // a sell waits for settlement or for volume to fill the rest
 }
 
 // BacktestState holds current backtest state
@@ -53,6 +76,7 @@ type BacktestState struct {
 	Cash            decimal.Decimal
 	Equity          decimal.Decimal
 	Positions       map[uint]*Position
+	Unsettled       []unsettledCash // sale proceeds not settled yet
 	ClosedTrades    []models.BacktestTrade
 	DailyEquity     map[string]decimal.Decimal
 	MaxEquity       decimal.Decimal
@@ -61,6 +85,13 @@ type BacktestState struct {
 
 // RunBacktest executes a backtest
 func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest, error) {
+	return be.RunBacktestContext(context.Background(), config, nil)
+}
+
+// RunBacktestContext executes a backtest, reporting the day being replayed and the share of the
+// date range done so far (0-1) to progress when set. It stops between trading days once ctx is cancelled, leaving the
+// backtest record without results.
+func (be *BacktestEngine) RunBacktestContext(ctx context.Context, config *BacktestConfig, progress func(day time.Time, fraction float64)) (*models.Backtest, error) {
 	// Create backtest record
 	backtest := &models.Backtest{
 		Name:           fmt.Sprintf("Backtest %s", time.Now().Format("2006-01-02 15:04:05")),
@@ -68,6 +99,8 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 		StartDate:      config.StartDate,
 		EndDate:        config.EndDate,
 		InitialCapital: config.InitialCapital,
+		Mode:           config.Mode,
+		MaxPositions:   config.MaxPositions,
 	}
 
 	if err := be.db.Create(backtest).Error; err != nil {
@@ -97,14 +130,25 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 
 	// Iterate through each trading day
 	currentDate := config.StartDate
+	totalDays := config.EndDate.Sub(config.StartDate).Hours()/24 + 1
 	for currentDate.Before(config.EndDate) || currentDate.Equal(config.EndDate) {
-		// Skip weekends
-		if currentDate.Weekday() == time.Saturday || currentDate.Weekday() == time.Sunday {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(currentDate, currentDate.Sub(config.StartDate).Hours()/24/totalDays)
+		}
+
+		// Skip weekends and market holidays
+		if !services.MarketCalendar().IsTradingDay(currentDate) {
 			currentDate = currentDate.AddDate(0, 0, 1)
 			continue
 		}
 
+		state.settleCash(currentDate)
+
 		// Process each stock
+		var entries []entryCandidate
 		for _, stock := range stocks {
 			// Get price data for the day
 			var price models.StockPrice
@@ -118,27 +162,27 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 			// Update current positions
 			if pos, exists := state.Positions[stock.ID]; exists {
 				pos.CurrentPrice = price.Close
-				pos.UnrealizedPnL = price.Close.Sub(pos.EntryPrice).Mul(decimal.NewFromInt(pos.Quantity))
+				pos.UnrealizedPnL = units.PnL(pos.EntryPrice, price.Close, pos.Quantity, false)
 			}
 
 			// Generate signals based on strategy
-			signal := be.generateSignal(&strategy, stock.ID, currentDate)
-
-			// Execute trades based on signals
-			if signal == "BUY" && state.Cash.GreaterThan(decimal.Zero) {
-				be.executeBuy(backtest.ID, &stock, &price, state, config)
-			} else if signal == "SELL" {
-				if _, hasPosition := state.Positions[stock.ID]; hasPosition {
-					be.executeSell(backtest.ID, &stock, &price, state, config)
-				}
+			signal, strength := be.generateSignal(&strategy, stock.ID, currentDate)
+
+			// Execute trades based on signals; a stock is held in one position, and a pending sell
+			// is retried until it fills. Portfolio entries wait for every stock's signal.
+			pos, hasPosition := state.Positions[stock.ID]
+			if signal == "BUY" && !hasPosition && config.Mode == ModePortfolio {
+				entries = append(entries, entryCandidate{stock: stock, price: price, strength: strength})
+			} else if signal == "BUY" && !hasPosition && state.Cash.GreaterThan(decimal.Zero) {
+				be.executeBuy(backtest.ID, &stock, &price, state, config, "Strategy signal")
+			} else if hasPosition && (signal == "SELL" || pos.PendingSell) {
+				be.executeSell(backtest.ID, &stock, &price, state, config, false)
 			}
 		}
+		be.enterByStrength(backtest.ID, entries, state, config)
 
 		// Calculate daily equity
-		totalEquity := state.Cash
-		for _, pos := range state.Positions {
-			totalEquity = totalEquity.Add(pos.CurrentPrice.Mul(decimal.NewFromInt(pos.Quantity)))
-		}
+		totalEquity := state.equity()
 		state.Equity = totalEquity
 		state.DailyEquity[currentDate.Format("2006-01-02")] = totalEquity
 
@@ -155,7 +199,7 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 		currentDate = currentDate.AddDate(0, 0, 1)
 	}
 
-	// Close all remaining positions at end date
+	// Close all remaining positions at end date, whatever their settlement and the day's volume
 	for stockID := range state.Positions {
 		var stock models.Stock
 		be.db.First(&stock, stockID)
@@ -166,9 +210,12 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 			First(&price).Error
 
 		if err == nil {
-			be.executeSell(backtest.ID, &stock, &price, state, config)
+			be.executeSell(backtest.ID, &stock, &price, state, config, true)
 		}
 	}
+	// Proceeds still settling are cash once the backtest is over
+	state.Cash = state.Cash.Add(state.unsettledTotal())
+	state.Unsettled = nil
 
 	// Calculate metrics
 	be.calculateMetrics(backtest, state, config)
@@ -186,8 +233,9 @@ func (be *BacktestEngine) RunBacktest(config *BacktestConfig) (*models.Backtest,
 	return backtest, nil
 }
 
-// generateSignal generates trading signal based on strategy
-func (be *BacktestEngine) generateSignal(strategy *models.TradingStrategy, stockID uint, date time.Time) string {
+// generateSignal generates trading signal based on strategy, with its strength: how far past the
+// strategy's threshold the indicators are, relative to it, so signals of different stocks compare
+func (be *BacktestEngine) generateSignal(strategy *models.TradingStrategy, stockID uint, date time.Time) (string, decimal.Decimal) {
 	// Parse strategy parameters
 	var params map[string]interface{}
 	json.Unmarshal([]byte(strategy.Parameters), &params)
@@ -201,12 +249,12 @@ func (be *BacktestEngine) generateSignal(strategy *models.TradingStrategy, stock
 	case "macd_strategy":
 		return be.macdSignal(stockID, date, params)
 	default:
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 }
 
 // smaCrossoverSignal implements SMA crossover strategy
-func (be *BacktestEngine) smaCrossoverSignal(stockID uint, date time.Time, params map[string]interface{}) string {
+func (be *BacktestEngine) smaCrossoverSignal(stockID uint, date time.Time, params map[string]interface{}) (string, decimal.Decimal) {
 	shortPeriod := 20
 	longPeriod := 50
 
@@ -219,44 +267,46 @@ func (be *BacktestEngine) smaCrossoverSignal(stockID uint, date time.Time, param
 
 	smaShort, err := be.technicalAnalysis.CalculateSMA(stockID, shortPeriod, date)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
 	smaLong, err := be.technicalAnalysis.CalculateSMA(stockID, longPeriod, date)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
-	// Get previous day's SMAs
-	prevDate := date.AddDate(0, 0, -1)
+	// Get previous trading day's SMAs
+	prevDate := services.MarketCalendar().PreviousTradingDay(date)
 	prevSMAShort, err := be.technicalAnalysis.CalculateSMA(stockID, shortPeriod, prevDate)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
 	prevSMALong, err := be.technicalAnalysis.CalculateSMA(stockID, longPeriod, prevDate)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
+	strength := smaShort.Sub(smaLong).Abs().Div(smaLong)
+
 	// Bullish crossover: short SMA crosses above long SMA
 	if prevSMAShort.LessThanOrEqual(prevSMALong) && smaShort.GreaterThan(smaLong) {
-		return "BUY"
+		return "BUY", strength
 	}
 
 	// Bearish crossover: short SMA crosses below long SMA
 	if prevSMAShort.GreaterThanOrEqual(prevSMALong) && smaShort.LessThan(smaLong) {
-		return "SELL"
+		return "SELL", strength
 	}
 
-	return "HOLD"
+	return "HOLD", decimal.Zero
 }
 
 // rsiSignal implements RSI-based strategy
-func (be *BacktestEngine) rsiSignal(stockID uint, date time.Time, params map[string]interface{}) string {
+func (be *BacktestEngine) rsiSignal(stockID uint, date time.Time, params map[string]interface{}) (string, decimal.Decimal) {
 	rsi, err := be.technicalAnalysis.CalculateRSI(stockID, 14, date)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
 	oversold := decimal.NewFromInt(30)
@@ -270,47 +320,55 @@ func (be *BacktestEngine) rsiSignal(stockID uint, date time.Time, params map[str
 	}
 
 	if rsi.LessThan(oversold) {
-		return "BUY"
+		return "BUY", oversold.Sub(rsi).Div(oversold)
 	}
 	if rsi.GreaterThan(overbought) {
-		return "SELL"
+		return "SELL", rsi.Sub(overbought).Div(decimal.NewFromInt(100).Sub(overbought))
 	}
 
-	return "HOLD"
+	return "HOLD", decimal.Zero
 }
 
 // macdSignal implements MACD strategy
-func (be *BacktestEngine) macdSignal(stockID uint, date time.Time, params map[string]interface{}) string {
+func (be *BacktestEngine) macdSignal(stockID uint, date time.Time, params map[string]interface{}) (string, decimal.Decimal) {
 	macd, err := be.technicalAnalysis.CalculateMACD(stockID, date)
 	if err != nil {
-		return "HOLD"
+		return "HOLD", decimal.Zero
 	}
 
 	// Buy when MACD crosses above signal line (positive histogram)
+	strength := decimal.Zero
+	if !macd.Signal.IsZero() {
+		strength = macd.Histogram.Abs().Div(macd.Signal.Abs())
+	}
+
 	if macd.Histogram.GreaterThan(decimal.Zero) {
-		return "BUY"
+		return "BUY", strength
 	}
 
 	// Sell when MACD crosses below signal line (negative histogram)
 	if macd.Histogram.LessThan(decimal.Zero) {
-		return "SELL"
+		return "SELL", strength
 	}
 
-	return "HOLD"
+	return "HOLD", decimal.Zero
 }
 
-// executeBuy executes a buy order
-func (be *BacktestEngine) executeBuy(backtestID uint, stock *models.Stock, price *models.StockPrice, state *BacktestState, config *BacktestConfig) {
-	// Calculate position size based on risk
-	positionSize := state.Cash.Mul(config.RiskPerTrade)
-	quantity := positionSize.Div(price.Close).IntPart()
+// executeBuy executes a buy order, filling what the day's volume allows
+func (be *BacktestEngine) executeBuy(backtestID uint, stock *models.Stock, price *models.StockPrice, state *BacktestState, config *BacktestConfig, reason string) {
+	// Calculate position size, within the cash left after commission
+	size := be.sizePosition(stock, price, state, config, state.equity())
+	positionSize := decimal.Min(size.Budget, state.Cash.Div(decimal.NewFromInt(1).Add(config.Commission)))
+	ordered := positionSize.Div(price.Close).IntPart()
+	quantity := config.Execution.fillQuantity(ordered, price.Volume)
 
 	if quantity <= 0 {
 		return
 	}
 
-	totalCost := price.Close.Mul(decimal.NewFromInt(quantity))
-	commission := totalCost.Mul(config.Commission)
+	fillPrice := config.Execution.fillPrice(stock.Symbol, price.Close, quantity, price.Volume, true)
+	totalCost := fillPrice.Mul(decimal.NewFromInt(quantity))
+	commission := units.RoundVND(totalCost.Mul(config.Commission))
 	totalAmount := totalCost.Add(commission)
 
 	if totalAmount.GreaterThan(state.Cash) {
@@ -322,7 +380,8 @@ func (be *BacktestEngine) executeBuy(backtestID uint, stock *models.Stock, price
 		StockID:      stock.ID,
 		Symbol:       stock.Symbol,
 		Quantity:     quantity,
-		EntryPrice:   price.Close,
+		EntryPrice:   fillPrice,
+		EntryCost:    totalAmount,
 		EntryDate:    price.Date,
 		CurrentPrice: price.Close,
 	}
@@ -336,28 +395,68 @@ func (be *BacktestEngine) executeBuy(backtestID uint, stock *models.Stock, price
 		Type:       "BUY",
 		Date:       price.Date,
 		Quantity:   quantity,
-		Price:      price.Close,
+		Price:      fillPrice,
 		Commission: commission,
-		Signal:     "Strategy signal",
+		Slippage:   fillPrice.Sub(price.Close).Mul(decimal.NewFromInt(quantity)),
+		Partial:    quantity < ordered/BoardLot*BoardLot,
+		Signal:     reason,
+
+		SizingMethod:   size.Method,
+		SizingFraction: size.Fraction,
+		SizingNote:     size.Note,
 	}
 	be.db.Create(&trade)
 }
 
-// executeSell executes a sell order
-func (be *BacktestEngine) executeSell(backtestID uint, stock *models.Stock, price *models.StockPrice, state *BacktestState, config *BacktestConfig) {
+// executeSell executes a sell order of the settled shares of a position, as far as the day's
+// volume allows; what is left stays pending. force sells the whole position regardless.
+func (be *BacktestEngine) executeSell(backtestID uint, stock *models.Stock, price *models.StockPrice, state *BacktestState, config *BacktestConfig, force bool) {
 	pos, exists := state.Positions[stock.ID]
 	if !exists {
 		return
 	}
 
-	totalRevenue := price.Close.Mul(decimal.NewFromInt(pos.Quantity))
-	commission := totalRevenue.Mul(config.Commission)
+	quantity := pos.Quantity
+	if !force {
+		if !config.Execution.settled(pos.EntryDate, price.Date) {
+			pos.PendingSell = true
+			return
+		}
+		quantity = config.Execution.fillQuantity(pos.Quantity, price.Volume)
+		if quantity <= 0 {
+			pos.PendingSell = true
+			return
+		}
+	}
+
+	fillPrice := config.Execution.fillPrice(stock.Symbol, price.Close, quantity, price.Volume, false)
+	totalRevenue := fillPrice.Mul(decimal.NewFromInt(quantity))
+	commission := units.RoundVND(totalRevenue.Mul(config.Commission))
 	netRevenue := totalRevenue.Sub(commission)
 
-	pnl := netRevenue.Sub(pos.EntryPrice.Mul(decimal.NewFromInt(pos.Quantity)))
+	// The shares sold carry their share of the entry cost, so both commissions count against the trade
+	cost := pos.EntryCost
+	if quantity < pos.Quantity {
+		cost = units.RoundVND(pos.EntryCost.Mul(decimal.NewFromInt(quantity)).Div(decimal.NewFromInt(pos.Quantity)))
+	}
+	pnl := netRevenue.Sub(cost)
+
+	pos.Quantity -= quantity
+	pos.EntryCost = pos.EntryCost.Sub(cost)
+	if pos.Quantity == 0 {
+		delete(state.Positions, stock.ID)
+	} else {
+		pos.PendingSell = true
+	}
 
-	state.Cash = state.Cash.Add(netRevenue)
-	delete(state.Positions, stock.ID)
+	if force || config.Execution.IgnoreSettlement {
+		state.Cash = state.Cash.Add(netRevenue)
+	} else {
+		state.Unsettled = append(state.Unsettled, unsettledCash{
+			Amount:    netRevenue,
+			SettlesOn: services.MarketCalendar().AddTradingDays(price.Date, SettlementDays),
+		})
+	}
 
 	// Record trade
 	trade := models.BacktestTrade{
@@ -365,9 +464,11 @@ func (be *BacktestEngine) executeSell(backtestID uint, stock *models.Stock, pric
 		StockID:    stock.ID,
 		Type:       "SELL",
 		Date:       price.Date,
-		Quantity:   pos.Quantity,
-		Price:      price.Close,
+		Quantity:   quantity,
+		Price:      fillPrice,
 		Commission: commission,
+		Slippage:   price.Close.Sub(fillPrice).Mul(decimal.NewFromInt(quantity)),
+		Partial:    pos.Quantity > 0,
 		PnL:        pnl,
 		Signal:     "Strategy signal",
 	}
@@ -433,4 +534,7 @@ func (be *BacktestEngine) calculateMetrics(backtest *models.Backtest, state *Bac
 
 	// Simplified Sharpe ratio (would need risk-free rate and more data in production)
 	backtest.SharpeRatio = totalReturn.Div(state.MaxDrawdown.Add(decimal.NewFromFloat(0.01)))
+
+	// VN-Index buy & hold, alpha and beta
+	compareWithBenchmark(backtest, state.DailyEquity)
 }
@@ -0,0 +1,132 @@
+package backtesting
+
+import (
+	"fmt"
+	"time"
+
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+)
+
+// Slippage models of the simulated order matching
+const (
+	SlippageNone   = ""          // fills at the close
+	SlippageFixed  = "fixed_bps" // SlippageBps against the order
+	SlippageVolume = "volume"    // SlippageBps plus ImpactBps scaled by the share of the day's volume taken
+)
+
+// Vietnamese equities settle T+2: shares bought on T can be sold from the afternoon session of
+// T+2 ("T+2.5") and sale proceeds arrive on T+2. Backtests fill at the close, so both are usable
+// on the second trading day after the trade.
+const (
+	SettlementDays = 2
+	BoardLot       = 100
+)
+
+var basisPoints = decimal.NewFromInt(10_000)
+
+// ExecutionConfig sets how backtest orders are matched. The zero value fills whole board lots at
+// the close without slippage, under T+2 settlement.
+type ExecutionConfig struct {
+	SlippageModel string          // SlippageNone, SlippageFixed or SlippageVolume
+	SlippageBps   decimal.Decimal // slippage in basis points of the close, against the order
+	ImpactBps     decimal.Decimal // SlippageVolume: extra slippage of an order taking the whole day's volume
+	// MaxParticipation caps the share of the day's volume one order may fill (0.1 for 10%); the
+	// rest of a buy expires with the day and the rest of a sell is retried on the next days.
+	// Zero fills whole orders.
+	MaxParticipation decimal.Decimal
+	IgnoreSettlement bool // sell shares and spend proceeds on the trade day
+}
+
+// Validate checks the slippage model and that the amounts are in range
+func (ec ExecutionConfig) Validate() error {
+	switch ec.SlippageModel {
+	case SlippageNone, SlippageFixed, SlippageVolume:
+	default:
+		return fmt.Errorf("unknown slippage model %q: use %s or %s", ec.SlippageModel, SlippageFixed, SlippageVolume)
+	}
+	if ec.SlippageBps.IsNegative() || ec.ImpactBps.IsNegative() || ec.SlippageBps.Add(ec.ImpactBps).GreaterThanOrEqual(basisPoints) {
+		return fmt.Errorf("slippage must be between 0 and 10000 bps")
+	}
+	if ec.MaxParticipation.IsNegative() || ec.MaxParticipation.GreaterThan(decimal.NewFromInt(1)) {
+		return fmt.Errorf("max participation must be between 0 and 1")
+	}
+	return nil
+}
+
+// unsettledCash is sale proceeds waiting for settlement
+type unsettledCash struct {
+	Amount    decimal.Decimal
+	SettlesOn time.Time
+}
+
+// fillQuantity returns how many shares of an order of quantity fill on a day trading volume
+// shares, in whole board lots
+func (ec ExecutionConfig) fillQuantity(quantity, volume int64) int64 {
+	if ec.MaxParticipation.IsPositive() {
+		limit := decimal.NewFromInt(volume).Mul(ec.MaxParticipation).IntPart()
+		if quantity > limit {
+			quantity = limit
+		}
+	}
+	return quantity / BoardLot * BoardLot
+}
+
+// fillPrice returns the price quantity shares fill at against a close, on the stock's tick size:
+// above the close for buys and below it for sells
+func (ec ExecutionConfig) fillPrice(symbol string, close decimal.Decimal, quantity, volume int64, buy bool) decimal.Decimal {
+	bps := decimal.Zero
+	switch ec.SlippageModel {
+	case SlippageFixed:
+		bps = ec.SlippageBps
+	case SlippageVolume:
+		bps = ec.SlippageBps
+		if volume > 0 {
+			bps = bps.Add(ec.ImpactBps.Mul(decimal.NewFromInt(quantity)).Div(decimal.NewFromInt(volume)))
+		}
+	}
+	if !bps.IsPositive() {
+		return close
+	}
+	if !buy {
+		bps = bps.Neg()
+	}
+	return services.RoundPriceVND(symbol, close.Mul(basisPoints.Add(bps)).Div(basisPoints))
+}
+
+// settled reports whether shares bought on entry can be sold on day
+func (ec ExecutionConfig) settled(entry, day time.Time) bool {
+	return ec.IgnoreSettlement || services.MarketCalendar().TradingDaysBetween(entry, day) >= SettlementDays
+}
+
+// settleCash moves the sale proceeds settled by day into the cash
+func (state *BacktestState) settleCash(day time.Time) {
+	pending := state.Unsettled[:0]
+	for _, u := range state.Unsettled {
+		if day.Before(u.SettlesOn) {
+			pending = append(pending, u)
+			continue
+		}
+		state.Cash = state.Cash.Add(u.Amount)
+	}
+	state.Unsettled = pending
+}
+
+// unsettledTotal returns the sale proceeds not settled yet
+func (state *BacktestState) unsettledTotal() decimal.Decimal {
+	total := decimal.Zero
+	for _, u := range state.Unsettled {
+		total = total.Add(u.Amount)
+	}
+	return total
+}
+
+// equity returns the cash, the proceeds settling and the positions at their last price
+func (state *BacktestState) equity() decimal.Decimal {
+	total := state.Cash.Add(state.unsettledTotal())
+	for _, pos := range state.Positions {
+		total = total.Add(pos.CurrentPrice.Mul(decimal.NewFromInt(pos.Quantity)))
+	}
+	return total
+}
@@ -0,0 +1,153 @@
+package backtesting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"go_backend_project/models"
+	"go_backend_project/services/xlsx"
+
+	"github.com/shopspring/decimal"
+)
+
+// Export formats of a backtest
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+var (
+	exportTradeColumns  = []string{"date", "symbol", "type", "quantity", "price", "commission", "slippage", "pnl", "partial", "sizing_method", "sizing_fraction", "signal"}
+	exportEquityColumns = []string{"date", "equity", "relative_equity"}
+)
+
+// Export writes the trade log and the daily equity of a backtest to w. A CSV holds both tables,
+// told apart by a leading section column; a workbook has a Trades and an Equity sheet. Trades
+// are read from the database row by row and written as they come.
+func (be *BacktestEngine) Export(backtest *models.Backtest, format string, w io.Writer) error {
+	switch format {
+	case ExportCSV:
+		out := csv.NewWriter(w)
+		if err := be.exportTables(backtest, func(section string, row []interface{}) error {
+			record := make([]string, 0, len(row)+1)
+			record = append(record, section)
+			for _, v := range row {
+				record = append(record, csvValue(v))
+			}
+			return out.Write(record)
+		}); err != nil {
+			return err
+		}
+		out.Flush()
+		return out.Error()
+
+	case ExportXLSX:
+		book := xlsx.NewWriter(w)
+		current := ""
+		if err := be.exportTables(backtest, func(section string, row []interface{}) error {
+			if section != current {
+				current = section
+				if err := book.Sheet(section); err != nil {
+					return err
+				}
+			}
+			return book.WriteRow(row...)
+		}); err != nil {
+			return err
+		}
+		return book.Close()
+	}
+	return fmt.Errorf("unknown export format %q: use %s or %s", format, ExportCSV, ExportXLSX)
+}
+
+// exportTables calls write with each row of the Trades table, then of the Equity table,
+// headers first
+func (be *BacktestEngine) exportTables(backtest *models.Backtest, write func(section string, row []interface{}) error) error {
+	symbols := make(map[uint]string)
+	var stocks []models.Stock
+	be.db.Where("id IN (?)", be.db.Model(&models.BacktestTrade{}).Select("stock_id").Where("backtest_id = ?", backtest.ID)).Find(&stocks)
+	for _, s := range stocks {
+		symbols[s.ID] = s.Symbol
+	}
+
+	if err := write("Trades", columns(exportTradeColumns)); err != nil {
+		return err
+	}
+	rows, err := be.db.Model(&models.BacktestTrade{}).Where("backtest_id = ?", backtest.ID).Order("date, id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.BacktestTrade
+		if err := be.db.ScanRows(rows, &t); err != nil {
+			return err
+		}
+		pnl := interface{}(nil)
+		if t.Type == "SELL" {
+			pnl = t.PnL
+		}
+		if err := write("Trades", []interface{}{
+			t.Date, symbols[t.StockID], t.Type, t.Quantity, t.Price, t.Commission, t.Slippage, pnl,
+			strconv.FormatBool(t.Partial), t.SizingMethod, t.SizingFraction, t.Signal,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Results and RelativeEquity hold one value per trading day, small enough to decode whole
+	var equity map[string]decimal.Decimal
+	if backtest.Results != "" {
+		if err := json.Unmarshal([]byte(backtest.Results), &equity); err != nil {
+			return fmt.Errorf("backtest %d has invalid results: %w", backtest.ID, err)
+		}
+	}
+	var relative map[string]float64
+	if backtest.RelativeEquity != "" {
+		json.Unmarshal([]byte(backtest.RelativeEquity), &relative)
+	}
+	dates := make([]string, 0, len(equity))
+	for date := range equity {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	if err := write("Equity", columns(exportEquityColumns)); err != nil {
+		return err
+	}
+	for _, date := range dates {
+		rel := interface{}(nil)
+		if v, ok := relative[date]; ok {
+			rel = v
+		}
+		if err := write("Equity", []interface{}{date, equity[date], rel}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func columns(names []string) []interface{} {
+	row := make([]interface{}, len(names))
+	for i, name := range names {
+		row[i] = name
+	}
+	return row
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case interface{ Format(string) string }:
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(v)
+}
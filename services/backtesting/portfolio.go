@@ -0,0 +1,43 @@
+package backtesting
+
+import (
+	"fmt"
+	"sort"
+
+	"go_backend_project/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// ModePortfolio backtests the symbols as one portfolio: they share the capital, at most
+// MaxPositions are held at once, and each day's buy signals are entered strongest first. Without
+// it each symbol is traded in turn as its signals come, also from the shared cash.
+const ModePortfolio = "portfolio"
+
+// entryCandidate is a buy signal of a portfolio backtest waiting for its turn
+type entryCandidate struct {
+	stock    models.Stock
+	price    models.StockPrice
+	strength decimal.Decimal
+}
+
+// enterByStrength opens positions for the day's buy signals, strongest first, while positions
+// are free and cash is left
+func (be *BacktestEngine) enterByStrength(backtestID uint, entries []entryCandidate, state *BacktestState, config *BacktestConfig) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].strength.Equal(entries[j].strength) {
+			return entries[i].strength.GreaterThan(entries[j].strength)
+		}
+		return entries[i].stock.Symbol < entries[j].stock.Symbol
+	})
+	for rank, e := range entries {
+		if config.MaxPositions > 0 && len(state.Positions) >= config.MaxPositions {
+			return
+		}
+		if !state.Cash.IsPositive() {
+			return
+		}
+		reason := fmt.Sprintf("Strategy signal, strength %s, rank %d of %d", e.strength.Round(4), rank+1, len(entries))
+		be.executeBuy(backtestID, &e.stock, &e.price, state, config, reason)
+	}
+}
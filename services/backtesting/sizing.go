@@ -0,0 +1,172 @@
+package backtesting
+
+import (
+	"fmt"
+
+	"go_backend_project/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Position sizing methods of a backtest
+const (
+	SizingFixedFractional = "fixed_fractional" // RiskPerTrade of the cash; the default
+	SizingVolatility      = "volatility"       // ATR-based volatility targeting
+	SizingKelly           = "kelly"            // Kelly fraction of the closed trades so far, capped
+)
+
+// Defaults of the sizing parameters left at zero
+const (
+	DefaultATRPeriod        = 14
+	DefaultKellyFraction    = 0.5 // half Kelly
+	DefaultKellyCap         = 0.25
+	DefaultKellyMinTrades   = 10
+	DefaultTargetVolatility = 0.01
+)
+
+// SizingConfig chooses how much of the account a new position takes
+type SizingConfig struct {
+	Method string // SizingFixedFractional, SizingVolatility or SizingKelly
+	// SizingVolatility: size the position so that one ATR move is TargetVolatility of the equity
+	TargetVolatility decimal.Decimal
+	ATRPeriod        int
+	// SizingKelly: KellyFraction of the Kelly fraction of the trades closed so far, at most
+	// KellyCap of the equity. Until KellyMinTrades trades have closed, positions take
+	// RiskPerTrade of the cash.
+	KellyFraction  decimal.Decimal
+	KellyCap       decimal.Decimal
+	KellyMinTrades int
+}
+
+// Validate checks the sizing method and that the parameters are in range
+func (sc SizingConfig) Validate() error {
+	switch sc.Method {
+	case "", SizingFixedFractional, SizingVolatility, SizingKelly:
+	default:
+		return fmt.Errorf("unknown sizing method %q: use %s, %s or %s", sc.Method, SizingFixedFractional, SizingVolatility, SizingKelly)
+	}
+	one := decimal.NewFromInt(1)
+	for name, v := range map[string]decimal.Decimal{
+		"target volatility": sc.TargetVolatility,
+		"kelly fraction":    sc.KellyFraction,
+		"kelly cap":         sc.KellyCap,
+	} {
+		if v.IsNegative() || v.GreaterThan(one) {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if sc.ATRPeriod < 0 || sc.KellyMinTrades < 0 {
+		return fmt.Errorf("ATR period and kelly min trades must not be negative")
+	}
+	return nil
+}
+
+// sizeDecision is how much a new position is given and why; it is logged on the buy trade
+type sizeDecision struct {
+	Method   string
+	Budget   decimal.Decimal // VND to spend on the shares
+	Fraction decimal.Decimal // budget as a share of the equity
+	Note     string
+}
+
+// sizePosition decides the budget of a new position in stock at a close, given the equity of
+// the account
+func (be *BacktestEngine) sizePosition(stock *models.Stock, price *models.StockPrice, state *BacktestState, config *BacktestConfig, equity decimal.Decimal) sizeDecision {
+	sc := config.Sizing
+	fixed := func(note string) sizeDecision {
+		budget := state.Cash.Mul(config.RiskPerTrade)
+		return sizeDecision{Method: SizingFixedFractional, Budget: budget, Fraction: fractionOf(budget, equity), Note: note}
+	}
+
+	switch sc.Method {
+	case SizingVolatility:
+		period := sc.ATRPeriod
+		if period == 0 {
+			period = DefaultATRPeriod
+		}
+		target := sc.TargetVolatility
+		if target.IsZero() {
+			target = decimal.NewFromFloat(DefaultTargetVolatility)
+		}
+		atr, err := be.technicalAnalysis.CalculateATR(stock.ID, period, price.Date)
+		if err != nil || !atr.IsPositive() {
+			return fixed(fmt.Sprintf("no ATR%d, fixed fractional", period))
+		}
+		// shares × ATR = target × equity
+		budget := equity.Mul(target).Div(atr).Mul(price.Close)
+		return sizeDecision{
+			Method:   SizingVolatility,
+			Budget:   budget,
+			Fraction: fractionOf(budget, equity),
+			Note:     fmt.Sprintf("ATR%d %s (%s%% of close), target %s%% of equity", period, atr.Round(0), atr.Div(price.Close).Mul(hundred).Round(2), target.Mul(hundred).Round(2)),
+		}
+
+	case SizingKelly:
+		minTrades := sc.KellyMinTrades
+		if minTrades == 0 {
+			minTrades = DefaultKellyMinTrades
+		}
+		if len(state.ClosedTrades) < minTrades {
+			return fixed(fmt.Sprintf("%d of %d closed trades for Kelly, fixed fractional", len(state.ClosedTrades), minTrades))
+		}
+		winRate, payoff := tradeOdds(state.ClosedTrades)
+		scale, limit := sc.KellyFraction, sc.KellyCap
+		if scale.IsZero() {
+			scale = decimal.NewFromFloat(DefaultKellyFraction)
+		}
+		if limit.IsZero() {
+			limit = decimal.NewFromFloat(DefaultKellyCap)
+		}
+		// f* = W - (1 - W) / R; without losses the cap applies, without wins nothing is bought
+		kelly := decimal.Zero
+		switch {
+		case winRate.Equal(decimal.NewFromInt(1)):
+			kelly = limit
+		case payoff.IsPositive():
+			kelly = winRate.Sub(decimal.NewFromInt(1).Sub(winRate).Div(payoff))
+		}
+		fraction := decimal.Max(decimal.Zero, decimal.Min(kelly.Mul(scale), limit))
+		return sizeDecision{
+			Method:   SizingKelly,
+			Budget:   equity.Mul(fraction),
+			Fraction: fraction,
+			Note:     fmt.Sprintf("win rate %s%%, payoff %s over %d trades: Kelly %s%%", winRate.Mul(hundred).Round(2), payoff.Round(2), len(state.ClosedTrades), kelly.Mul(hundred).Round(2)),
+		}
+	}
+	return fixed("")
+}
+
+// tradeOdds returns the share of winning trades and the ratio of the average win to the
+// average loss; the ratio is zero unless there are both wins and losses
+func tradeOdds(trades []models.BacktestTrade) (winRate, payoff decimal.Decimal) {
+	var wins, losses int64
+	won, lost := decimal.Zero, decimal.Zero
+	for _, t := range trades {
+		if t.PnL.IsPositive() {
+			wins++
+			won = won.Add(t.PnL)
+		} else {
+			losses++
+			lost = lost.Add(t.PnL.Abs())
+		}
+	}
+	if wins+losses == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	winRate = decimal.NewFromInt(wins).Div(decimal.NewFromInt(wins + losses))
+	if wins == 0 || losses == 0 || lost.IsZero() {
+		return winRate, decimal.Zero
+	}
+	avgWin := won.Div(decimal.NewFromInt(wins))
+	avgLoss := lost.Div(decimal.NewFromInt(losses))
+	return winRate, avgWin.Div(avgLoss)
+}
+
+var hundred = decimal.NewFromInt(100)
+
+func fractionOf(budget, equity decimal.Decimal) decimal.Decimal {
+	if !equity.IsPositive() {
+		return decimal.Zero
+	}
+	return budget.Div(equity).Round(4)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RS methods select what the RS percentile ranks are computed from
+const (
+	// RSMethodBenchmark ranks the stock return minus the VN-Index return over the same dates
+	RSMethodBenchmark = "benchmark"
+	// RSMethodPriceChange ranks the raw price change, the original metric
+	RSMethodPriceChange = "price_change"
+)
+
+// rsMethodFromEnv reads RS_METHOD; anything other than price_change selects the benchmark method
+func rsMethodFromEnv() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("RS_METHOD")), RSMethodPriceChange) {
+		return RSMethodPriceChange
+	}
+	return RSMethodBenchmark
+}
+
+// RSMethod returns the configured RS method
+func (s *StockIndicatorService) RSMethod() string {
+	if s == nil || s.rsMethod == "" {
+		return RSMethodBenchmark
+	}
+	return s.rsMethod
+}
+
+// BenchmarkSeries is the VN-Index close by date, oldest first, for date-aligned returns
+type BenchmarkSeries struct {
+	dates  []string
+	closes []float64
+}
+
+// NewBenchmarkSeries builds the series from index prices in any order; it is nil without closes
+func NewBenchmarkSeries(prices []StockPriceData) *BenchmarkSeries {
+	if len(prices) == 0 {
+		return nil
+	}
+	sorted := append([]StockPriceData(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date < sorted[j].Date })
+
+	b := &BenchmarkSeries{}
+	for _, p := range sorted {
+		if p.Close > 0 {
+			b.dates = append(b.dates, p.Date)
+			b.closes = append(b.closes, p.Close)
+		}
+	}
+	if len(b.dates) == 0 {
+		return nil
+	}
+	return b
+}
+
+// CloseOn returns the index close on date, or on the last session before it
+func (b *BenchmarkSeries) CloseOn(date string) (float64, bool) {
+	i := sort.Search(len(b.dates), func(i int) bool { return b.dates[i] > date })
+	if i == 0 {
+		return 0, false
+	}
+	return b.closes[i-1], true
+}
+
+// returnBetween is the index % change from start to end (YYYY-MM-DD)
+func (b *BenchmarkSeries) returnBetween(start, end string) (float64, bool) {
+	from, ok := b.CloseOn(start)
+	if !ok || from == 0 {
+		return 0, false
+	}
+	to, ok := b.CloseOn(end)
+	if !ok {
+		return 0, false
+	}
+	return (to - from) / from * 100, true
+}
+
+// benchmarkFetchTTL is how long a fetched index series is reused while the price store has none
+const benchmarkFetchTTL = time.Hour
+
+// benchmarkCache keeps the series built from the last index price file. The price file cache
+// returns the same file until it changes on disk, so the series is only rebuilt after a sync; a
+// series fetched from VNDirect is kept for benchmarkFetchTTL.
+var benchmarkCache struct {
+	mu        sync.Mutex
+	source    *StockPriceFile
+	series    *BenchmarkSeries
+	fetchedAt time.Time
+}
+
+// LoadBenchmark reads VN-Index prices from the local price store, fetching them when absent.
+// It returns nil when no index data is available, in which case RS falls back to price change.
+// The series is shared between callers and must not be modified.
+func LoadBenchmark() *BenchmarkSeries {
+	if GlobalPriceService == nil {
+		return nil
+	}
+	benchmarkCache.mu.Lock()
+	defer benchmarkCache.mu.Unlock()
+
+	if file, err := GlobalPriceService.LoadStockPrice(MarketIndexCode); err == nil && len(file.Prices) > 0 {
+		if benchmarkCache.source != file {
+			benchmarkCache.source, benchmarkCache.series = file, NewBenchmarkSeries(file.Prices)
+			benchmarkCache.fetchedAt = time.Time{}
+		}
+		return benchmarkCache.series
+	}
+	if benchmarkCache.source == nil && benchmarkCache.series != nil && time.Since(benchmarkCache.fetchedAt) < benchmarkFetchTTL {
+		return benchmarkCache.series
+	}
+
+	resp, err := GlobalPriceService.FetchStockPrice(MarketIndexCode, DefaultPriceSize)
+	if err != nil {
+		log.Printf("Warning: %s prices unavailable, RS uses raw price change: %v", MarketIndexCode, err)
+		return nil
+	}
+	benchmarkCache.source, benchmarkCache.series = nil, NewBenchmarkSeries(resp.Data)
+	benchmarkCache.fetchedAt = time.Now()
+	return benchmarkCache.series
+}
+
+// applyBenchmarkRS sets the excess return over the benchmark for each RS window. Windows use the
+// stock's own dates, so a stock that missed sessions is compared with the index over the same
+// span rather than over the latest N index sessions.
+func applyBenchmarkRS(ind *ExtendedStockIndicators, prices []StockPriceData, bench *BenchmarkSeries) {
+	if ind == nil || bench == nil || len(prices) == 0 {
+		return
+	}
+
+	excess := func(raw float64, period int) float64 {
+		if len(prices) <= period {
+			return 0
+		}
+		idxReturn, ok := bench.returnBetween(prices[period].Date, prices[0].Date)
+		if !ok {
+			return raw
+		}
+		return math.Round((raw-idxReturn)*100) / 100
+	}
+
+	ind.RS3DExcess = excess(ind.RS3D, 3)
+	ind.RS1MExcess = excess(ind.RS1M, 22)
+	ind.RS3MExcess = excess(ind.RS3M, 66)
+	ind.RS1YExcess = excess(ind.RS1Y, 252)
+	ind.RSMethod = RSMethodBenchmark
+}
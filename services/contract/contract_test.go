@@ -0,0 +1,244 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// record calls the live provider and rewrites the fixture of contracts that allow it; run it
+// during the continuous session so the order book fixture has every level filled
+var record = flag.Bool("record", false, "call the live VNDirect and SSI endpoints and rewrite their fixtures")
+
+// fixtureDir holds one recorded response per contract
+const fixtureDir = "testdata"
+
+// Fixture is a recorded provider response. Method and Path must match the request the client
+// sends; Host is where it was recorded.
+type Fixture struct {
+	Description string            `json:"description"`
+	RecordedAt  string            `json:"recorded_at"`
+	Method      string            `json:"method"`
+	Host        string            `json:"host"`
+	Path        string            `json:"path"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        json.RawMessage   `json:"body"`
+}
+
+// contract calls one client against its fixture and checks what the client parsed
+type contract struct {
+	name    string
+	client  string
+	fixture string // file name in fixtureDir
+	live    bool   // the endpoint is public, so the fixture can be re-recorded
+	run     func(c *checker)
+}
+
+// checker reports the failures of one contract
+type checker struct {
+	t         *testing.T
+	transport *captureTransport // every client under test sends through it
+}
+
+// body returns the last response body the client received
+func (c *checker) body() []byte {
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+	return c.transport.body
+}
+
+func (c *checker) fail(format string, args ...interface{}) {
+	c.t.Helper()
+	c.t.Errorf(format, args...)
+}
+
+// check fails with the message when ok is false and reports ok
+func (c *checker) check(ok bool, format string, args ...interface{}) bool {
+	c.t.Helper()
+	if !ok {
+		c.fail(format, args...)
+	}
+	return ok
+}
+
+func TestProviderContracts(t *testing.T) {
+	for _, ct := range contracts {
+		t.Run(ct.name, func(t *testing.T) {
+			runContract(t, ct, *record && ct.live)
+		})
+	}
+}
+
+// runContract runs a contract against a replay server for its fixture, or against the provider
+// when recording. The transport is handed to the client under test, never installed globally.
+func runContract(t *testing.T, ct contract, record bool) {
+	path := filepath.Join(fixtureDir, ct.fixture)
+	fixture, err := loadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &checker{t: t, transport: &captureTransport{base: http.DefaultTransport}}
+	var replay *replayServer
+	if !record {
+		replay = &replayServer{fixture: fixture}
+		server := httptest.NewServer(replay)
+		defer server.Close()
+		target, _ := url.Parse(server.URL)
+		c.transport.base = &redirectTransport{target: target, base: server.Client().Transport}
+	}
+
+	ct.run(c)
+
+	if replay != nil {
+		replay.mu.Lock()
+		for _, msg := range replay.unexpected {
+			t.Errorf("%s: %s", ct.client, msg)
+		}
+		replay.mu.Unlock()
+	}
+
+	if record {
+		recorded, err := c.transport.fixture(fixture.Description)
+		if err != nil {
+			t.Fatalf("recording: %v", err)
+		}
+		if err := saveFixture(path, recorded); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("recorded %s", path)
+	}
+}
+
+func loadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+func saveFixture(path string, fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// replayServer answers with the fixture. A request it does not match fails the contract, since
+// the client no longer calls the endpoint the fixture was recorded from.
+type replayServer struct {
+	fixture *Fixture
+
+	mu         sync.Mutex
+	unexpected []string
+}
+
+func (s *replayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != s.fixture.Method || r.URL.Path != s.fixture.Path {
+		s.mu.Lock()
+		s.unexpected = append(s.unexpected, fmt.Sprintf("unexpected request %s %s, fixture has %s %s",
+			r.Method, r.URL.Path, s.fixture.Method, s.fixture.Path))
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	for k, v := range s.fixture.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(s.fixture.Status)
+	w.Write(s.fixture.Body)
+}
+
+// redirectTransport sends requests to the replay server, keeping their path and query
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme, out.URL.Host, out.Host = t.target.Scheme, t.target.Host, ""
+	return t.base.RoundTrip(out)
+}
+
+// recordedHeaders are the response headers the clients read
+var recordedHeaders = []string{"Content-Range"}
+
+// captureTransport keeps the last response, to check the raw payload and to record it
+type captureTransport struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	req  *http.Request
+	resp *http.Response
+	body []byte
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.mu.Lock()
+	t.req, t.resp, t.body = req, resp, body
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// fixture turns the recorded response into a fixture
+func (t *captureTransport) fixture(description string) (*Fixture, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resp == nil {
+		return nil, fmt.Errorf("the client sent no request")
+	}
+	if !json.Valid(t.body) {
+		return nil, fmt.Errorf("response of %s is not JSON", t.req.URL.Path)
+	}
+
+	fixture := &Fixture{
+		Description: description,
+		RecordedAt:  time.Now().UTC().Format(time.RFC3339),
+		Method:      t.req.Method,
+		Host:        t.req.URL.Host,
+		Path:        t.req.URL.Path,
+		Status:      t.resp.StatusCode,
+		Body:        t.body,
+	}
+	for _, name := range recordedHeaders {
+		if v := t.resp.Header.Get(name); v != "" {
+			if fixture.Headers == nil {
+				fixture.Headers = make(map[string]string)
+			}
+			fixture.Headers[name] = v
+		}
+	}
+	return fixture, nil
+}
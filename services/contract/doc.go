@@ -0,0 +1,7 @@
+// Package contract replays recorded VNDirect, SSI and Supabase responses through the clients that
+// parse them, so payload changes on the provider side show up as failed contracts instead of zero
+// values in production. The contracts are tests:
+//
+//	go test ./services/contract            # replay every fixture
+//	go test ./services/contract -record    # re-record the VNDirect and SSI fixtures
+package contract
@@ -0,0 +1,159 @@
+package contract
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go_backend_project/services"
+	"go_backend_project/services/datafetcher"
+)
+
+// Supabase fixtures are redacted by hand rather than recorded, since they hold user data; the
+// client is pointed at a placeholder project that the replay server stands in for
+const contractSupabaseURL = "https://contract.supabase.co"
+
+// contracts run in this order as subtests of TestProviderContracts. DataFetcher shares the price
+// fixture but queries without a size, so only the price service records it.
+var contracts = []contract{
+	{"vndirect/stock-prices", "StockPriceService.FetchStockPrice", "vndirect_stock_prices.json", true, checkStockPrices},
+	{"vndirect/datafetcher", "DataFetcher.FetchVNDirectData", "vndirect_stock_prices.json", false, checkDataFetcher},
+	{"ssi/order-book", "FetchOrderBook", "ssi_stock_info.json", true, checkOrderBook},
+	{"supabase/admin-user", "SupabaseDBClient.GetAdminUserByUsername", "supabase_admin_users.json", false, checkAdminUser},
+	{"supabase/profiles-page", "SupabaseDBClient.GetProfilesPage", "supabase_profiles.json", false, checkProfilesPage},
+	{"supabase/auth-users", "SupabaseDBClient.ListAuthUsers", "supabase_auth_users.json", false, checkAuthUsers},
+}
+
+// checkPriceBar checks the fields indicators and signals read from a daily bar. Prices are in
+// thousands of VND, so a close above 10000 means the provider changed its unit.
+func checkPriceBar(c *checker, code string, bar services.StockPriceData) {
+	prefix := bar.Code + " " + bar.Date
+	c.check(bar.Code == code, "%s: code %q, requested %s", prefix, bar.Code, code)
+	if _, err := time.Parse(services.PriceDateFormat, bar.Date); err != nil {
+		c.fail("%s: date %q is not %s", prefix, bar.Date, services.PriceDateFormat)
+	}
+	c.check(bar.Open > 0 && bar.High > 0 && bar.Low > 0 && bar.Close > 0, "%s: missing OHLC %v/%v/%v/%v", prefix, bar.Open, bar.High, bar.Low, bar.Close)
+	c.check(bar.Low <= bar.Close && bar.Close <= bar.High, "%s: close %v outside low %v and high %v", prefix, bar.Close, bar.Low, bar.High)
+	c.check(bar.AdClose > 0, "%s: missing adClose", prefix)
+	c.check(bar.NmVolume > 0 && bar.NmValue > 0, "%s: missing nmVolume or nmValue", prefix)
+	c.check(bar.Close < 10000, "%s: close %v is not in thousands of VND", prefix, bar.Close)
+	c.check(bar.CeilingPrice >= bar.High && bar.FloorPrice <= bar.Low, "%s: high/low outside ceiling %v and floor %v", prefix, bar.CeilingPrice, bar.FloorPrice)
+}
+
+func checkStockPrices(c *checker) {
+	resp, err := services.NewStockPriceService(c.transport).FetchStockPrice("VNM", 3)
+	if err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	if !c.check(len(resp.Data) > 0, "no price data") {
+		return
+	}
+	c.check(resp.TotalElements >= len(resp.Data), "totalElements %d below %d returned bars", resp.TotalElements, len(resp.Data))
+	for _, bar := range resp.Data {
+		checkPriceBar(c, "VNM", bar)
+	}
+	c.check(sort.SliceIsSorted(resp.Data, func(i, j int) bool { return resp.Data[i].Date > resp.Data[j].Date }),
+		"bars are not newest first")
+}
+
+func checkDataFetcher(c *checker) {
+	fetcher := datafetcher.NewDataFetcher(nil)
+	fetcher.SetTransport(c.transport)
+	if err := fetcher.FetchVNDirectData("VNM"); err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	// FetchVNDirectData does not return what it parsed, so decode the same payload into its type
+	var parsed datafetcher.VNDirectResponse
+	if err := json.Unmarshal(c.body(), &parsed); err != nil {
+		c.fail("decode: %v", err)
+		return
+	}
+	if !c.check(len(parsed.Data) > 0, "no price data") {
+		return
+	}
+	for _, bar := range parsed.Data {
+		c.check(bar.Code == "VNM" && bar.Date != "", "bar without code or date: %+v", bar)
+		c.check(bar.Close > 0 && bar.Volume > 0, "%s: missing close or nmVolume", bar.Date)
+	}
+}
+
+// checkOrderBook checks the three depth levels; SSI quotes full VND, unlike VNDirect. The fixture
+// is from the continuous session, when every level is filled.
+func checkOrderBook(c *checker) {
+	book, err := services.FetchOrderBook(&http.Client{Transport: c.transport, Timeout: 30 * time.Second}, "VNM")
+	if err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	c.check(len(book.Bids) == 3 && len(book.Asks) == 3, "%d bid and %d ask levels, expected 3 each", len(book.Bids), len(book.Asks))
+	c.check(book.BestBid >= 1000, "best bid %v is not in VND", book.BestBid)
+	c.check(book.BestAsk > book.BestBid, "best ask %v not above best bid %v", book.BestAsk, book.BestBid)
+	for i := 1; i < len(book.Bids); i++ {
+		c.check(book.Bids[i].Price < book.Bids[i-1].Price, "bid level %d not below level %d", i+1, i)
+	}
+	for i := 1; i < len(book.Asks); i++ {
+		c.check(book.Asks[i].Price > book.Asks[i-1].Price, "ask level %d not above level %d", i+1, i)
+	}
+	for _, level := range append(book.Bids, book.Asks...) {
+		c.check(level.Volume > 0, "level at %v without volume", level.Price)
+	}
+}
+
+// supabaseClient returns a client for the placeholder project that sends through the checker
+func supabaseClient(c *checker) *services.SupabaseDBClient {
+	client := services.NewSupabaseDBClientFor(contractSupabaseURL, "", "service-key")
+	client.SetTransport(c.transport)
+	return client
+}
+
+func checkAdminUser(c *checker) {
+	user, err := supabaseClient(c).GetAdminUserByUsername("admin")
+	if err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	c.check(user.ID > 0, "missing id")
+	c.check(user.Username == "admin", "username %q", user.Username)
+	c.check(strings.HasPrefix(user.PasswordHash, "$2"), "password_hash is not bcrypt")
+	c.check(user.Role != "" && user.IsActive, "missing role or is_active")
+	c.check(!user.CreatedAt.IsZero(), "missing created_at")
+}
+
+// checkProfilesPage checks the rows and the total read from the Content-Range header
+func checkProfilesPage(c *checker) {
+	page := services.PageRequest{Page: 1, PageSize: 2, Sort: "created_at"}
+	result, err := supabaseClient(c).GetProfilesPage(page, "", "created_at", "desc")
+	if err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	c.check(len(result.Profiles) == 2, "%d profiles on a page of 2", len(result.Profiles))
+	c.check(result.Total > 2 && result.TotalPages == int(result.Total+1)/2, "total %d, %d pages", result.Total, result.TotalPages)
+	c.check(result.NextCursor != "", "no next cursor although a third row was returned")
+	for _, p := range result.Profiles {
+		c.check(p.ID != "" && p.Email != "", "profile without id or email")
+		c.check(p.Membership != "", "%s: missing membership", p.ID)
+		c.check(p.Membership == "free" || p.MembershipExpiresAt != nil, "%s: %s membership without expiry", p.ID, p.Membership)
+		c.check(!p.CreatedAt.IsZero(), "%s: missing created_at", p.ID)
+	}
+}
+
+func checkAuthUsers(c *checker) {
+	list, err := supabaseClient(c).ListAuthUsers(1, 2)
+	if err != nil {
+		c.fail("fetch: %v", err)
+		return
+	}
+	if !c.check(len(list.Users) > 0, "no users") {
+		return
+	}
+	for _, u := range list.Users {
+		c.check(u.ID != "" && u.Email != "", "user without id or email")
+		c.check(!u.CreatedAt.IsZero(), "%s: missing created_at", u.ID)
+		c.check(u.AppMetadata["provider"] != nil, "%s: missing app_metadata.provider", u.ID)
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services/units"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VNDirectEventsAPIURL lists corporate events (rights, dividends, meetings) per stock
+const VNDirectEventsAPIURL = "https://api-finfo.vndirect.com.vn/v4/events"
+
+// Corporate event sync settings
+const (
+	EventLookbackDays = 400                  // past events fetched, enough for a trailing 12-month yield
+	EventFetchSize    = 100                  // events fetched per stock
+	PriceUnitVND      = units.VNDPerThousand // VNDirect quotes prices in thousands of VND
+)
+
+// vndirectEventTypes maps VNDirect event types to corporate event types
+var vndirectEventTypes = map[string]string{
+	"DIVIDEND": models.CorporateEventCashDividend,
+	"STOCKDIV": models.CorporateEventStockDividend,
+	"KINDDIV":  models.CorporateEventBonusShares,
+	"ISSUE":    models.CorporateEventRightsIssue,
+	"RIGHT":    models.CorporateEventRightsIssue,
+	"AGME":     models.CorporateEventAGM,
+	"AGMR":     models.CorporateEventAGM,
+}
+
+// vndirectEvent is the subset of the VNDirect events response that is ingested
+type vndirectEvent struct {
+	Code          string  `json:"code"`
+	Type          string  `json:"type"`
+	EffectiveDate string  `json:"effectiveDate"` // ex-rights or meeting date
+	ExpiredDate   string  `json:"expiredDate"`   // record date
+	ActualDate    string  `json:"actualDate"`    // payment date
+	Dividend      float64 `json:"dividend"`      // VND per share
+	Ratio         string  `json:"ratio"`
+	Note          string  `json:"note"`
+}
+
+// EventSyncResult summarizes a corporate event sync
+type EventSyncResult struct {
+	Codes  int      `json:"codes"`
+	Events int      `json:"events"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DividendYield is the trailing 12-month cash dividend yield of a stock
+type DividendYield struct {
+	Code         string  `json:"code"`
+	CashPerShare float64 `json:"cash_per_share"` // VND paid over the last 12 months
+	Payments     int     `json:"payments"`
+	Price        float64 `json:"price"`     // latest close, thousands of VND
+	YieldPct     float64 `json:"yield_pct"` // 0 when there is no price
+}
+
+// SyncCorporateEvents fetches the recent and upcoming events of codes from VNDirect and upserts
+// them; re-announced events update the stored ones
+func SyncCorporateEvents(ctx context.Context, db *gorm.DB, codes []string, progress JobProgress) (*EventSyncResult, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	since := time.Now().AddDate(0, 0, -EventLookbackDays).Format(PriceDateFormat)
+	result := &EventSyncResult{Codes: len(codes)}
+
+	for i, code := range codes {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if progress != nil {
+			progress(float64(i)/float64(len(codes))*100, code, fmt.Sprintf("%d/%d codes", i, len(codes)))
+		}
+
+		events, err := fetchVNDirectEvents(client, code, since)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", code, err))
+			continue
+		}
+		if len(events) > 0 {
+			if err := db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "code"}, {Name: "type"}, {Name: "event_date"}},
+				DoUpdates: clause.AssignmentColumns([]string{"record_date", "payment_date", "cash_per_share", "ratio", "title", "updated_at"}),
+			}).Create(&events).Error; err != nil {
+				return result, fmt.Errorf("save events of %s: %w", code, err)
+			}
+			result.Events += len(events)
+		}
+
+		if (i+1)%PriceFetchBatchSize == 0 {
+			time.Sleep(PriceFetchBatchDelay)
+		}
+	}
+	return result, nil
+}
+
+// fetchVNDirectEvents returns the events of code dated on or after since
+func fetchVNDirectEvents(client *http.Client, code, since string) ([]models.CorporateEvent, error) {
+	url := fmt.Sprintf("%s?sort=effectiveDate:desc&q=code:%s~effectiveDate:gte:%s&size=%d",
+		VNDirectEventsAPIURL, code, since, EventFetchSize)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Referer", "https://www.vndirect.com.vn/")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Data []vndirectEvent `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// The feed can list one event twice (e.g. announcement and correction); keep the first
+	seen := make(map[string]bool, len(response.Data))
+	events := make([]models.CorporateEvent, 0, len(response.Data))
+	for _, raw := range response.Data {
+		event, ok := convertVNDirectEvent(code, raw)
+		if !ok {
+			continue
+		}
+		key := event.Type + event.EventDate.Format(PriceDateFormat)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// convertVNDirectEvent maps a feed entry to a corporate event; entries without a date are dropped
+func convertVNDirectEvent(code string, raw vndirectEvent) (models.CorporateEvent, bool) {
+	date, ok := parseEventDate(raw.EffectiveDate)
+	if !ok {
+		return models.CorporateEvent{}, false
+	}
+	eventType, known := vndirectEventTypes[strings.ToUpper(raw.Type)]
+	if !known {
+		eventType = models.CorporateEventOther
+	}
+
+	event := models.CorporateEvent{
+		Code:      strings.ToUpper(code),
+		Type:      eventType,
+		EventDate: *date,
+		Ratio:     strings.TrimSpace(raw.Ratio),
+		Title:     strings.TrimSpace(raw.Note),
+		Source:    "vndirect",
+	}
+	if runes := []rune(event.Title); len(runes) > 500 {
+		event.Title = string(runes[:500])
+	}
+	if event.Title == "" {
+		event.Title = raw.Type
+	}
+	event.RecordDate, _ = parseEventDate(raw.ExpiredDate)
+	event.PaymentDate, _ = parseEventDate(raw.ActualDate)
+	if eventType == models.CorporateEventCashDividend && raw.Dividend > 0 {
+		event.CashPerShare = decimal.NewFromFloat(raw.Dividend)
+	}
+	return event, true
+}
+
+// parseEventDate parses the YYYY-MM-DD prefix of a feed date in market time
+func parseEventDate(value string) (*time.Time, bool) {
+	if len(value) < len(PriceDateFormat) {
+		return nil, false
+	}
+	t, err := time.ParseInLocation(PriceDateFormat, value[:len(PriceDateFormat)], MarketCalendar().Location())
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+// UpcomingCorporateEvents returns the events of codes from today through days ahead, soonest
+// first; all codes when codes is empty
+func UpcomingCorporateEvents(db *gorm.DB, codes []string, days int) ([]models.CorporateEvent, error) {
+	today := time.Now().In(MarketCalendar().Location()).Format(PriceDateFormat)
+	until := time.Now().In(MarketCalendar().Location()).AddDate(0, 0, days).Format(PriceDateFormat)
+
+	query := db.Where("event_date >= ? AND event_date <= ?", today, until)
+	if len(codes) > 0 {
+		query = query.Where("code IN ?", codes)
+	}
+	events := make([]models.CorporateEvent, 0)
+	err := query.Order("event_date, code").Find(&events).Error
+	return events, err
+}
+
+// RecentCorporateEvents returns the events of a stock in the days before today, newest first
+func RecentCorporateEvents(db *gorm.DB, code string, days int) ([]models.CorporateEvent, error) {
+	today := time.Now().In(MarketCalendar().Location())
+	events := make([]models.CorporateEvent, 0)
+	err := db.Where("code = ? AND event_date < ? AND event_date >= ?", code,
+		today.Format(PriceDateFormat), today.AddDate(0, 0, -days).Format(PriceDateFormat)).
+		Order("event_date DESC").Find(&events).Error
+	return events, err
+}
+
+// TrailingDividendYield sums the cash dividends that went ex in the last 12 months against the
+// latest close of the stock
+func TrailingDividendYield(db *gorm.DB, code string) (*DividendYield, error) {
+	now := time.Now().In(MarketCalendar().Location())
+	var events []models.CorporateEvent
+	if err := db.Where("code = ? AND type = ? AND event_date <= ? AND event_date > ?", code,
+		models.CorporateEventCashDividend, now.Format(PriceDateFormat), now.AddDate(-1, 0, 0).Format(PriceDateFormat)).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	yield := &DividendYield{Code: code, Payments: len(events)}
+	for _, e := range events {
+		yield.CashPerShare += e.CashPerShare.InexactFloat64()
+	}
+	if GlobalPriceService != nil {
+		if file, err := GlobalPriceService.LoadStockPrice(code); err == nil && len(file.Prices) > 0 {
+			yield.Price = file.Prices[0].Close
+		}
+	}
+	if yield.Price > 0 {
+		yield.YieldPct = math.Round(yield.CashPerShare/(yield.Price*PriceUnitVND)*10000) / 100
+	}
+	return yield, nil
+}
+
+// EventSyncCodes returns the listed stocks whose events are synced
+func EventSyncCodes() ([]string, error) {
+	stocks, err := LoadStocksWithFallback()
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		code := strings.ToUpper(stock.Code)
+		if InstrumentTypeOf(code) != InstrumentTypeStock || IsStockInactive(code) {
+			continue
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// WatchlistCodes returns the stock codes on the watchlist of a Supabase user
+func WatchlistCodes(db *gorm.DB, supabaseUserID string) ([]string, error) {
+	codes := make([]string, 0)
+	err := db.Table("watchlists").
+		Joins("JOIN users ON users.id = watchlists.user_id").
+		Joins("JOIN stocks ON stocks.id = watchlists.stock_id").
+		Where("users.supabase_user_id = ? AND users.deleted_at IS NULL", supabaseUserID).
+		Distinct().Pluck("stocks.symbol", &codes).Error
+	return codes, err
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Data browser limits
+const (
+	DataBrowserMaxPageSize   = 500
+	DataBrowserMaxExportRows = 10000
+	DataBrowserTimeout       = 5 * time.Second
+)
+
+// dataBrowserModels are the market data tables operators may query. User, subscription and
+// admin tables are deliberately left out.
+var dataBrowserModels = []interface{}{
+	&models.Stock{},
+	&models.StockPrice{},
+	&models.TechnicalIndicator{},
+	&models.MarketIndex{},
+	&models.IntradayTick{},
+	&models.OrderBookSnapshot{},
+	&models.SignalHistory{},
+	&models.SignalPerformance{},
+	&models.SignalRuleBacktestRun{},
+	&models.SystemConfig{},
+	&models.SyncHistory{},
+}
+
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlWordPattern   = regexp.MustCompile(`[a-z_][a-z0-9_]*`)
+)
+
+// sqlForbiddenWords are rejected anywhere outside string literals
+var sqlForbiddenWords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true, "upsert": true,
+	"create": true, "alter": true, "drop": true, "truncate": true, "rename": true,
+	"grant": true, "revoke": true, "copy": true, "into": true, "call": true, "do": true,
+	"execute": true, "prepare": true, "vacuum": true, "analyze": true, "lock": true,
+	"set": true, "reset": true, "listen": true, "notify": true, "dblink": true,
+	// Functions that change settings or run a query passed as a string
+	"set_config": true, "current_setting": true, "ts_stat": true,
+	"information_schema": true,
+}
+
+// sqlForbiddenPrefixes reject system catalogs, large objects and the *_to_xml functions,
+// which execute arbitrary SQL given as text
+var sqlForbiddenPrefixes = []string{"pg_", "lo_", "query_to_", "table_to_", "cursor_to_", "schema_to_", "database_to_"}
+
+// DataBrowserTable describes a table available in the data browser
+type DataBrowserTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// DataBrowserResult is one page of query results
+type DataBrowserResult struct {
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	HasMore  bool            `json:"has_more"`
+	Duration int64           `json:"duration_ms"`
+}
+
+// DataBrowserTables returns the whitelisted tables with their columns
+func DataBrowserTables(db *gorm.DB) ([]DataBrowserTable, error) {
+	tables := make([]DataBrowserTable, 0, len(dataBrowserModels))
+	for _, model := range dataBrowserModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := DataBrowserTable{Name: stmt.Schema.Table}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				table.Columns = append(table.Columns, field.DBName)
+			}
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// ValidateReadOnlyQuery accepts a single SELECT (or WITH ... SELECT) statement that only reads
+// whitelisted tables. It is a first line of defence; queries also run in a read-only transaction.
+func ValidateReadOnlyQuery(db *gorm.DB, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return "", fmt.Errorf("query is empty")
+	}
+
+	// Checks run on the query with string literals blanked so their contents cannot trip or evade them
+	stripped := strings.ToLower(sqlStringLiteral.ReplaceAllString(query, "''"))
+	if strings.Contains(stripped, ";") {
+		return "", fmt.Errorf("only a single statement is allowed")
+	}
+	if strings.Contains(stripped, "--") || strings.Contains(stripped, "/*") {
+		return "", fmt.Errorf("comments are not allowed")
+	}
+	if strings.Contains(stripped, "$") {
+		return "", fmt.Errorf("dollar-quoted strings and parameters are not allowed")
+	}
+	if strings.Contains(query, `\`) || strings.Contains(stripped, `u&`) {
+		return "", fmt.Errorf("escaped strings and identifiers are not allowed")
+	}
+	if !strings.HasPrefix(stripped, "select") && !strings.HasPrefix(stripped, "with") {
+		return "", fmt.Errorf("only SELECT queries are allowed")
+	}
+	for _, word := range sqlWordPattern.FindAllString(stripped, -1) {
+		if sqlForbiddenWords[word] {
+			return "", fmt.Errorf("keyword %q is not allowed", strings.ToUpper(word))
+		}
+		for _, prefix := range sqlForbiddenPrefixes {
+			if strings.HasPrefix(word, prefix) {
+				return "", fmt.Errorf("system function or catalog %q is not allowed", word)
+			}
+		}
+	}
+
+	tables, err := DataBrowserTables(db)
+	if err != nil {
+		return "", err
+	}
+	allowed := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		allowed[t.Name] = true
+	}
+
+	var existing []string
+	if err := db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema NOT IN ('pg_catalog', 'information_schema')").
+		Scan(&existing).Error; err != nil {
+		return "", err
+	}
+	forbidden := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		if name = strings.ToLower(name); !allowed[name] {
+			forbidden[name] = true
+		}
+	}
+
+	// Rather than parsing FROM clauses, any mention of a table outside the whitelist (in any
+	// schema) is rejected, which also covers comma joins, subqueries and quoted names
+	for _, word := range sqlWordPattern.FindAllString(stripped, -1) {
+		if forbidden[word] {
+			return "", fmt.Errorf("table %q is not available in the data browser", word)
+		}
+	}
+	return query, nil
+}
+
+// RunReadOnlyQuery validates a query and returns one page of its results. A pageSize of up to
+// DataBrowserMaxExportRows is accepted for exports.
+func RunReadOnlyQuery(db *gorm.DB, query string, page, pageSize int) (*DataBrowserResult, error) {
+	query, err := ValidateReadOnlyQuery(db, query)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > DataBrowserMaxExportRows {
+		pageSize = 100
+	}
+
+	start := time.Now()
+	tx := db.Begin(&sql.TxOptions{ReadOnly: true})
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", DataBrowserTimeout.Milliseconds())).Error; err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether another page exists
+	rows, err := tx.Raw("SELECT * FROM ("+query+") AS data_browser_query LIMIT ? OFFSET ?",
+		pageSize+1, (page-1)*pageSize).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DataBrowserResult{Columns: columns, Rows: [][]interface{}{}, Page: page, PageSize: pageSize}
+	for rows.Next() {
+		if len(result.Rows) == pageSize {
+			result.HasMore = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start).Milliseconds()
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultStaleAfterHours is how long indicators may lag a closed trading session before signal
+// responses flag them as stale (DATA_STALE_AFTER_HOURS overrides it)
+const DefaultStaleAfterHours = 24
+
+// DataFreshness describes how current the indicator data behind a signal response is. Data is
+// stale once a trading session closed after DataAsOf more than StaleAfterHours ago, so weekends
+// and holidays never make the last calculation stale.
+type DataFreshness struct {
+	DataAsOf        string  `json:"data_as_of"` // RFC3339; empty when no indicators were calculated
+	AgeHours        float64 `json:"age_hours"`
+	IsStale         bool    `json:"is_stale"`
+	StaleAfterHours float64 `json:"stale_after_hours"`
+}
+
+// indicatorSummaryStamp caches the updated_at of the indicator summary file by modification time
+var indicatorSummaryStamp struct {
+	mu        sync.Mutex
+	modTime   time.Time
+	updatedAt time.Time
+}
+
+// StaleAfter returns the configured staleness threshold
+func StaleAfter() time.Duration {
+	if v, err := strconv.ParseFloat(os.Getenv("DATA_STALE_AFTER_HOURS"), 64); err == nil && v > 0 {
+		return time.Duration(v * float64(time.Hour))
+	}
+	return DefaultStaleAfterHours * time.Hour
+}
+
+// IndicatorFreshness reports the freshness of the saved indicator summary at now
+func IndicatorFreshness(now time.Time) DataFreshness {
+	threshold := StaleAfter()
+	freshness := DataFreshness{IsStale: true, StaleAfterHours: threshold.Hours()}
+
+	updatedAt, ok := indicatorSummaryUpdatedAt()
+	if !ok {
+		return freshness
+	}
+	freshness.DataAsOf = updatedAt.Format(time.RFC3339)
+	freshness.AgeHours = math.Round(now.Sub(updatedAt).Hours()*10) / 10
+
+	missed, ok := firstCloseAfter(updatedAt)
+	freshness.IsStale = ok && now.Sub(missed) > threshold
+	return freshness
+}
+
+// indicatorSummaryUpdatedAt returns when the indicator summary was calculated, re-reading the
+// file only after it changed
+func indicatorSummaryUpdatedAt() (time.Time, bool) {
+	summaryPath := filepath.Join("data", "indicators_summary.json")
+	info, err := os.Stat(summaryPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	stamp := &indicatorSummaryStamp
+	stamp.mu.Lock()
+	defer stamp.mu.Unlock()
+	if !info.ModTime().Equal(stamp.modTime) {
+		data, err := os.ReadFile(summaryPath)
+		if err != nil {
+			return time.Time{}, false
+		}
+		var summary struct {
+			UpdatedAt string `json:"updated_at"`
+		}
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return time.Time{}, false
+		}
+		updatedAt, err := time.Parse(time.RFC3339, summary.UpdatedAt)
+		if err != nil {
+			return time.Time{}, false
+		}
+		stamp.modTime = info.ModTime()
+		stamp.updatedAt = updatedAt
+	}
+	return stamp.updatedAt, !stamp.updatedAt.IsZero()
+}
+
+// firstCloseAfter returns the close of the first trading session ending after t
+func firstCloseAfter(t time.Time) (time.Time, bool) {
+	cal := MarketCalendar()
+	day := t
+	if !cal.IsTradingDay(day) {
+		day = cal.NextTradingDay(day)
+	}
+	for i := 0; i < 2; i++ {
+		sessions := cal.Day(day).Sessions
+		if len(sessions) > 0 {
+			if end := sessions[len(sessions)-1].End; end.After(t) {
+				return end, true
+			}
+		}
+		day = cal.NextTradingDay(day)
+	}
+	return time.Time{}, false
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -28,6 +29,13 @@ func NewDataFetcher(db *gorm.DB) *DataFetcher {
 	}
 }
 
+// SetTransport replaces the transport of the VNDirect client and returns the previous one
+func (df *DataFetcher) SetTransport(rt http.RoundTripper) http.RoundTripper {
+	previous := df.httpClient.Transport
+	df.httpClient.Transport = rt
+	return previous
+}
+
 // SSIQuoteResponse represents SSI API response structure
 type SSIQuoteResponse struct {
 	Data []struct {
@@ -52,7 +60,7 @@ type VNDirectResponse struct {
 		High          float64 `json:"high"`
 		Low           float64 `json:"low"`
 		Close         float64 `json:"close"`
-		Volume        int64   `json:"nmVolume"`
+		Volume        float64 `json:"nmVolume"` // VNDirect sends volumes as decimals (2487600.0)
 		Value         float64 `json:"nmValue"`
 	} `json:"data"`
 }
@@ -109,8 +117,8 @@ func (df *DataFetcher) FetchHistoricalData(symbol string, startDate, endDate tim
 	basePrice := 50000.0 // Starting price
 
 	for currentDate.Before(endDate) || currentDate.Equal(endDate) {
-		// Skip weekends
-		if currentDate.Weekday() == time.Saturday || currentDate.Weekday() == time.Sunday {
+		// Skip weekends and market holidays
+		if !services.MarketCalendar().IsTradingDay(currentDate) {
 			currentDate = currentDate.AddDate(0, 0, 1)
 			continue
 		}
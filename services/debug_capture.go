@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DebugCaptureConfigKey is the system_config key holding the debug request capture settings
+const DebugCaptureConfigKey = "debug_capture"
+
+const (
+	// DebugCaptureDefaultDuration switches capture off when it is enabled without an end
+	DebugCaptureDefaultDuration = time.Hour
+	// DebugCaptureMaxDuration caps how long bodies are captured at once
+	DebugCaptureMaxDuration = 24 * time.Hour
+	debugCaptureDefaultBody = 16 << 10
+	debugCaptureMaxBody     = 64 << 10
+	debugCaptureRefresh     = 30 * time.Second
+)
+
+// DebugCaptureConfig picks the API requests whose bodies are captured for debugging
+type DebugCaptureConfig struct {
+	Enabled      bool       `json:"enabled"`
+	SamplePct    float64    `json:"sample_pct"`     // share of the matching requests captured, 0-100
+	Routes       []string   `json:"routes"`         // route prefixes as registered, e.g. /api/v1/signals/:code
+	MaxBodyBytes int        `json:"max_body_bytes"` // kept of each body, default 16 KiB
+	Until        *time.Time `json:"until,omitempty"`
+	UpdatedBy    string     `json:"updated_by,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate checks the sample rate, routes and body limit
+func (d DebugCaptureConfig) Validate() error {
+	if d.SamplePct < 0 || d.SamplePct > 100 {
+		return fmt.Errorf("sample_pct must be between 0 and 100")
+	}
+	if d.Enabled && len(d.Routes) == 0 {
+		return fmt.Errorf("routes are required when capture is enabled")
+	}
+	for _, route := range d.Routes {
+		if !strings.HasPrefix(route, "/api/") {
+			return fmt.Errorf("route %q must start with /api/", route)
+		}
+	}
+	if d.MaxBodyBytes < 0 || d.MaxBodyBytes > debugCaptureMaxBody {
+		return fmt.Errorf("max_body_bytes must be at most %d", debugCaptureMaxBody)
+	}
+	return nil
+}
+
+// Active reports whether requests are being captured at now
+func (d DebugCaptureConfig) Active(now time.Time) bool {
+	return d.Enabled && d.SamplePct > 0 && (d.Until == nil || now.Before(*d.Until))
+}
+
+// matches reports whether a registered route is one of the captured ones
+func (d DebugCaptureConfig) matches(route string) bool {
+	for _, prefix := range d.Routes {
+		if strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	debugCapture       DebugCaptureConfig
+	debugCaptureDB     *gorm.DB
+	debugCaptureLoaded time.Time
+	debugCaptureMu     sync.RWMutex
+)
+
+// LoadDebugCapture applies the capture settings stored in system_config and keeps db to pick up
+// changes made through other instances
+func LoadDebugCapture(db *gorm.DB) error {
+	debugCaptureMu.Lock()
+	debugCaptureDB = db
+	debugCaptureMu.Unlock()
+	return refreshDebugCapture()
+}
+
+// SaveDebugCapture validates, stores and applies new capture settings. Enabling without an end
+// captures for DebugCaptureDefaultDuration.
+func SaveDebugCapture(db *gorm.DB, cfg DebugCaptureConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	if cfg.Enabled && cfg.Until == nil {
+		until := now.Add(DebugCaptureDefaultDuration)
+		cfg.Until = &until
+	}
+	if cfg.Enabled && cfg.Until.Sub(now) > DebugCaptureMaxDuration {
+		return fmt.Errorf("until must be within %s", DebugCaptureMaxDuration)
+	}
+	cfg.UpdatedAt = now
+	if err := SaveSystemConfig(db, DebugCaptureConfigKey, cfg); err != nil {
+		return err
+	}
+
+	debugCaptureMu.Lock()
+	debugCapture, debugCaptureLoaded = cfg, now
+	debugCaptureMu.Unlock()
+	log.Printf("Debug capture %v at %.1f%% of %v (by %s)", cfg.Enabled, cfg.SamplePct, cfg.Routes, cfg.UpdatedBy)
+	return nil
+}
+
+// CurrentDebugCapture returns the capture settings, re-reading the stored ones when stale
+func CurrentDebugCapture() DebugCaptureConfig {
+	debugCaptureMu.RLock()
+	cfg, stale := debugCapture, debugCaptureDB != nil && time.Since(debugCaptureLoaded) > debugCaptureRefresh
+	debugCaptureMu.RUnlock()
+	if !stale {
+		return cfg
+	}
+	if err := refreshDebugCapture(); err != nil {
+		log.Printf("Warning: failed to refresh debug capture config: %v", err)
+	}
+	debugCaptureMu.RLock()
+	defer debugCaptureMu.RUnlock()
+	return debugCapture
+}
+
+// SampleDebugCapture decides whether a request to route is captured: it returns how many bytes of
+// each body to keep, or 0 when capture is off, the route isn't picked or the request isn't sampled
+func SampleDebugCapture(route string) int {
+	cfg := CurrentDebugCapture()
+	if !cfg.Active(time.Now()) || !cfg.matches(route) || rand.Float64()*100 >= cfg.SamplePct {
+		return 0
+	}
+	if cfg.MaxBodyBytes == 0 {
+		return debugCaptureDefaultBody
+	}
+	return cfg.MaxBodyBytes
+}
+
+func refreshDebugCapture() error {
+	debugCaptureMu.Lock()
+	db := debugCaptureDB
+	debugCaptureLoaded = time.Now()
+	debugCaptureMu.Unlock()
+
+	var cfg DebugCaptureConfig
+	found, err := LoadSystemConfig(db, DebugCaptureConfigKey, &cfg)
+	if err != nil || !found {
+		return err
+	}
+	debugCaptureMu.Lock()
+	debugCapture = cfg
+	debugCaptureMu.Unlock()
+	return nil
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Experiment limits and timings
+const (
+	MaxExperimentVariants = 5
+	// ExperimentExposureWindow is how long an exposure is counted once per user and experiment
+	ExperimentExposureWindow = 10 * time.Minute
+	// experimentCacheTTL is how long the running experiments are read from memory
+	experimentCacheTTL       = 30 * time.Second
+	experimentBuckets        = 10000
+	maxTrackedExposures      = 100000
+	maxExperimentEventLength = 50
+)
+
+var (
+	// ErrInvalidExperiment is wrapped by experiments and events that break a rule
+	ErrInvalidExperiment = errors.New("invalid experiment")
+	// ErrExperimentNotFound is returned for unknown experiments and those not running
+	ErrExperimentNotFound = errors.New("experiment not found")
+	// ErrNotEnrolled is returned for events of users outside an experiment
+	ErrNotEnrolled = errors.New("user is not enrolled in the experiment")
+
+	experimentKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]*$`)
+)
+
+// ExperimentInput is what an admin sets on an experiment
+type ExperimentInput struct {
+	Key         string                     `json:"key"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	RoutePrefix string                     `json:"route_prefix"`
+	Variants    []models.ExperimentVariant `json:"variants"`
+	TrafficPct  int                        `json:"traffic_pct"`
+}
+
+// ExperimentVariantResult is the engagement of one variant
+type ExperimentVariantResult struct {
+	Variant          string                    `json:"variant"`
+	Users            int64                     `json:"users"`
+	Exposures        int64                     `json:"exposures"`
+	RequestsPerUser  float64                   `json:"requests_per_user"` // API requests since first exposure
+	Events           map[string]ExperimentStat `json:"events"`
+	ReturningUserPct float64                   `json:"returning_user_pct"` // exposed again a day or more after the first exposure
+}
+
+// ExperimentStat counts one event of a variant
+type ExperimentStat struct {
+	Count         int64   `json:"count"`
+	Users         int64   `json:"users"`
+	ConversionPct float64 `json:"conversion_pct"` // of the variant's users
+}
+
+// ExperimentResults compares the variants of an experiment
+type ExperimentResults struct {
+	Experiment *models.Experiment        `json:"experiment"`
+	Variants   []ExperimentVariantResult `json:"variants"`
+}
+
+var experimentCache struct {
+	mu       sync.RWMutex
+	running  []models.Experiment
+	variants map[uint][]models.ExperimentVariant
+	loadedAt time.Time
+}
+
+var experimentExposures struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // experiment ID and user -> last logged exposure
+}
+
+// InvalidateExperimentCache makes the next assignment reload the running experiments
+func InvalidateExperimentCache() {
+	experimentCache.mu.Lock()
+	experimentCache.loadedAt = time.Time{}
+	experimentCache.mu.Unlock()
+}
+
+// runningExperiments returns the running experiments, reloaded every experimentCacheTTL
+func runningExperiments(db *gorm.DB) ([]models.Experiment, map[uint][]models.ExperimentVariant) {
+	experimentCache.mu.RLock()
+	if time.Since(experimentCache.loadedAt) < experimentCacheTTL {
+		running, variants := experimentCache.running, experimentCache.variants
+		experimentCache.mu.RUnlock()
+		return running, variants
+	}
+	experimentCache.mu.RUnlock()
+
+	var running []models.Experiment
+	if err := db.Where("status = ?", models.ExperimentStatusRunning).Order("id").Find(&running).Error; err != nil {
+		log.Printf("Failed to load running experiments: %v", err)
+	}
+	variants := make(map[uint][]models.ExperimentVariant, len(running))
+	for _, exp := range running {
+		list, err := exp.VariantList()
+		if err != nil || len(list) == 0 {
+			continue
+		}
+		variants[exp.ID] = list
+	}
+
+	experimentCache.mu.Lock()
+	experimentCache.running, experimentCache.variants, experimentCache.loadedAt = running, variants, time.Now()
+	experimentCache.mu.Unlock()
+	return running, variants
+}
+
+// experimentBucket places a user in one of experimentBuckets buckets of an experiment. The key
+// salts the hash so that the same users don't land in the control of every experiment.
+func experimentBucket(key, userID string) int {
+	sum := sha256.Sum256([]byte(key + ":" + userID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % experimentBuckets)
+}
+
+// variantBucket is a second, independent bucket choosing the variant of an enrolled user, so
+// that the traffic share can grow without moving anyone to another variant
+func variantBucket(key, userID string) int {
+	sum := sha256.Sum256([]byte(key + ":variant:" + userID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % experimentBuckets)
+}
+
+// AssignExperimentVariant returns the user's variant of an experiment, or "" when the user
+// falls outside its traffic share
+func AssignExperimentVariant(exp *models.Experiment, variants []models.ExperimentVariant, userID string) string {
+	if userID == "" || len(variants) == 0 {
+		return ""
+	}
+	if experimentBucket(exp.Key, userID) >= exp.TrafficPct*experimentBuckets/100 {
+		return ""
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return variants[0].Key
+	}
+	// Spread the buckets over the variants by weight
+	point := variantBucket(exp.Key, userID) * total / experimentBuckets
+	for _, v := range variants {
+		if point < v.Weight {
+			return v.Key
+		}
+		point -= v.Weight
+	}
+	return variants[len(variants)-1].Key
+}
+
+// AssignExperiments returns the user's variants of the running experiments covering path and
+// logs their exposures. Anonymous callers are not enrolled.
+func AssignExperiments(db *gorm.DB, userID, path string) map[string]string {
+	if db == nil || userID == "" {
+		return nil
+	}
+	running, variants := runningExperiments(db)
+	var assigned map[string]string
+	for i := range running {
+		exp := &running[i]
+		if !strings.HasPrefix(path, exp.RoutePrefix) {
+			continue
+		}
+		variant := AssignExperimentVariant(exp, variants[exp.ID], userID)
+		if variant == "" {
+			continue
+		}
+		if assigned == nil {
+			assigned = make(map[string]string)
+		}
+		assigned[exp.Key] = variant
+		logExperimentExposure(db, exp.ID, userID, variant)
+	}
+	return assigned
+}
+
+// ListExperimentAssignments returns the user's variants of every running experiment without
+// logging exposures, for clients that render before calling the covered routes
+func ListExperimentAssignments(db *gorm.DB, userID string) map[string]string {
+	assigned := map[string]string{}
+	if db == nil || userID == "" {
+		return assigned
+	}
+	running, variants := runningExperiments(db)
+	for i := range running {
+		if variant := AssignExperimentVariant(&running[i], variants[running[i].ID], userID); variant != "" {
+			assigned[running[i].Key] = variant
+		}
+	}
+	return assigned
+}
+
+// logExperimentExposure records an exposure at most once per ExperimentExposureWindow
+func logExperimentExposure(db *gorm.DB, experimentID uint, userID, variant string) {
+	now := time.Now()
+	key := fmt.Sprintf("%d:%s", experimentID, userID)
+
+	experimentExposures.mu.Lock()
+	if experimentExposures.seen == nil || len(experimentExposures.seen) >= maxTrackedExposures {
+		experimentExposures.seen = make(map[string]time.Time)
+	}
+	if last, ok := experimentExposures.seen[key]; ok && now.Sub(last) < ExperimentExposureWindow {
+		experimentExposures.mu.Unlock()
+		return
+	}
+	experimentExposures.seen[key] = now
+	experimentExposures.mu.Unlock()
+
+	exposure := &models.ExperimentExposure{
+		ExperimentID: experimentID, UserID: userID, Variant: variant,
+		Exposures: 1, FirstExposedAt: now, LastExposedAt: now,
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "experiment_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"exposures":       gorm.Expr("experiment_exposures.exposures + 1"),
+			"last_exposed_at": now,
+		}),
+	}).Create(exposure).Error
+	if err != nil {
+		log.Printf("Failed to log exposure to experiment %d: %v", experimentID, err)
+	}
+}
+
+// validateExperimentInput checks an experiment's settings and fills the defaults
+func validateExperimentInput(input *ExperimentInput) error {
+	input.Key = strings.ToLower(strings.TrimSpace(input.Key))
+	input.Name = strings.TrimSpace(input.Name)
+	if !experimentKeyPattern.MatchString(input.Key) || len(input.Key) > 50 {
+		return fmt.Errorf("%w: key must be lowercase letters, digits, _ or -, up to 50 characters", ErrInvalidExperiment)
+	}
+	if input.Name == "" {
+		input.Name = input.Key
+	}
+	if !strings.HasPrefix(input.RoutePrefix, "/api/v1/") {
+		return fmt.Errorf("%w: route_prefix must start with /api/v1/", ErrInvalidExperiment)
+	}
+	if input.TrafficPct == 0 {
+		input.TrafficPct = 100
+	}
+	if input.TrafficPct < 1 || input.TrafficPct > 100 {
+		return fmt.Errorf("%w: traffic_pct must be between 1 and 100", ErrInvalidExperiment)
+	}
+	if len(input.Variants) < 2 || len(input.Variants) > MaxExperimentVariants {
+		return fmt.Errorf("%w: an experiment needs between 2 and %d variants", ErrInvalidExperiment, MaxExperimentVariants)
+	}
+	seen := make(map[string]bool)
+	for i := range input.Variants {
+		v := &input.Variants[i]
+		v.Key = strings.ToLower(strings.TrimSpace(v.Key))
+		if !experimentKeyPattern.MatchString(v.Key) || len(v.Key) > 50 {
+			return fmt.Errorf("%w: variant keys must be lowercase letters, digits, _ or -", ErrInvalidExperiment)
+		}
+		if seen[v.Key] {
+			return fmt.Errorf("%w: duplicate variant %s", ErrInvalidExperiment, v.Key)
+		}
+		seen[v.Key] = true
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+		if v.Weight < 0 || v.Weight > 100 {
+			return fmt.Errorf("%w: variant weights must be between 1 and 100", ErrInvalidExperiment)
+		}
+	}
+	return nil
+}
+
+// CreateExperiment creates a draft experiment
+func CreateExperiment(db *gorm.DB, input ExperimentInput, createdBy string) (*models.Experiment, error) {
+	if err := validateExperimentInput(&input); err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := db.Model(&models.Experiment{}).Where("key = ?", input.Key).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: key %s is taken", ErrInvalidExperiment, input.Key)
+	}
+
+	exp := &models.Experiment{
+		Key:         input.Key,
+		Name:        input.Name,
+		Description: input.Description,
+		RoutePrefix: input.RoutePrefix,
+		TrafficPct:  input.TrafficPct,
+		Status:      models.ExperimentStatusDraft,
+		CreatedBy:   createdBy,
+	}
+	if err := exp.SetVariantList(input.Variants); err != nil {
+		return nil, err
+	}
+	if err := db.Create(exp).Error; err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+// UpdateExperiment changes an experiment's settings. Once it has started only the name,
+// description and traffic share can change, so users keep their variants; raising the traffic
+// share enrolls more users without moving the enrolled ones.
+func UpdateExperiment(db *gorm.DB, exp *models.Experiment, input ExperimentInput) error {
+	variants, err := exp.VariantList()
+	if err != nil {
+		return err
+	}
+	input.Key = exp.Key
+	if exp.Status != models.ExperimentStatusDraft {
+		input.RoutePrefix = exp.RoutePrefix
+		input.Variants = variants
+	}
+	if input.RoutePrefix == "" {
+		input.RoutePrefix = exp.RoutePrefix
+	}
+	if len(input.Variants) == 0 {
+		input.Variants = variants
+	}
+	if err := validateExperimentInput(&input); err != nil {
+		return err
+	}
+	if exp.Status != models.ExperimentStatusDraft && input.TrafficPct < exp.TrafficPct {
+		return fmt.Errorf("%w: the traffic share of a started experiment can't shrink", ErrInvalidExperiment)
+	}
+
+	exp.Name, exp.Description, exp.TrafficPct, exp.RoutePrefix = input.Name, input.Description, input.TrafficPct, input.RoutePrefix
+	if err := exp.SetVariantList(input.Variants); err != nil {
+		return err
+	}
+	if err := db.Save(exp).Error; err != nil {
+		return err
+	}
+	InvalidateExperimentCache()
+	return nil
+}
+
+// SetExperimentStatus starts, resumes or stops an experiment
+func SetExperimentStatus(db *gorm.DB, exp *models.Experiment, status string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case models.ExperimentStatusRunning:
+		if exp.StartedAt == nil {
+			updates["started_at"] = now
+			exp.StartedAt = &now
+		}
+		updates["stopped_at"] = nil
+		exp.StoppedAt = nil
+	case models.ExperimentStatusStopped:
+		if exp.Status != models.ExperimentStatusRunning {
+			return fmt.Errorf("%w: only a running experiment can be stopped", ErrInvalidExperiment)
+		}
+		updates["stopped_at"] = now
+		exp.StoppedAt = &now
+	default:
+		return fmt.Errorf("%w: status must be running or stopped", ErrInvalidExperiment)
+	}
+	if err := db.Model(exp).Updates(updates).Error; err != nil {
+		return err
+	}
+	exp.Status = status
+	InvalidateExperimentCache()
+	return nil
+}
+
+// DeleteExperiment removes an experiment with its exposures and events
+func DeleteExperiment(db *gorm.DB, exp *models.Experiment) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("experiment_id = ?", exp.ID).Delete(&models.ExperimentEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("experiment_id = ?", exp.ID).Delete(&models.ExperimentExposure{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(exp).Error
+	})
+	if err != nil {
+		return err
+	}
+	InvalidateExperimentCache()
+	return nil
+}
+
+// RecordExperimentEvent logs an engagement event of an enrolled user in a running experiment
+func RecordExperimentEvent(db *gorm.DB, key, userID, event string) (*models.ExperimentEvent, error) {
+	event = strings.ToLower(strings.TrimSpace(event))
+	if !experimentKeyPattern.MatchString(event) || len(event) > maxExperimentEventLength {
+		return nil, fmt.Errorf("%w: event must be lowercase letters, digits, _ or -, up to %d characters", ErrInvalidExperiment, maxExperimentEventLength)
+	}
+
+	running, variants := runningExperiments(db)
+	for i := range running {
+		exp := &running[i]
+		if exp.Key != key {
+			continue
+		}
+		variant := AssignExperimentVariant(exp, variants[exp.ID], userID)
+		if variant == "" {
+			return nil, ErrNotEnrolled
+		}
+		entry := &models.ExperimentEvent{ExperimentID: exp.ID, UserID: userID, Variant: variant, Event: event}
+		if err := db.Create(entry).Error; err != nil {
+			return nil, err
+		}
+		return entry, nil
+	}
+	return nil, ErrExperimentNotFound
+}
+
+// GetExperimentResults compares the users, exposures, API requests and events of each variant
+func GetExperimentResults(db *gorm.DB, exp *models.Experiment) (*ExperimentResults, error) {
+	variants, err := exp.VariantList()
+	if err != nil {
+		return nil, err
+	}
+	results := &ExperimentResults{Experiment: exp, Variants: make([]ExperimentVariantResult, 0, len(variants))}
+	byVariant := make(map[string]*ExperimentVariantResult, len(variants))
+	for _, v := range variants {
+		results.Variants = append(results.Variants, ExperimentVariantResult{Variant: v.Key, Events: map[string]ExperimentStat{}})
+	}
+	for i := range results.Variants {
+		byVariant[results.Variants[i].Variant] = &results.Variants[i]
+	}
+
+	var exposures []struct {
+		Variant        string
+		Users          int64
+		Exposures      int64
+		ReturningUsers int64
+	}
+	if err := db.Model(&models.ExperimentExposure{}).
+		Select("variant, COUNT(*) AS users, COALESCE(SUM(exposures), 0) AS exposures, "+
+			"COUNT(CASE WHEN last_exposed_at >= first_exposed_at + INTERVAL '1 day' THEN 1 END) AS returning_users").
+		Where("experiment_id = ?", exp.ID).Group("variant").Scan(&exposures).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range exposures {
+		if r := byVariant[e.Variant]; r != nil {
+			r.Users, r.Exposures = e.Users, e.Exposures
+			if e.Users > 0 {
+				r.ReturningUserPct = math.Round(float64(e.ReturningUsers)/float64(e.Users)*10000) / 100
+			}
+		}
+	}
+
+	// Requests of the enrolled users since they were first exposed, from the API usage logs
+	var requests []struct {
+		Variant  string
+		Requests int64
+	}
+	if err := db.Table("experiment_exposures AS e").
+		Select("e.variant, COUNT(l.id) AS requests").
+		Joins("JOIN api_usage_logs AS l ON l.user_id = e.user_id AND l.at >= e.first_exposed_at").
+		Where("e.experiment_id = ?", exp.ID).Group("e.variant").Scan(&requests).Error; err != nil {
+		return nil, err
+	}
+	for _, rq := range requests {
+		if r := byVariant[rq.Variant]; r != nil && r.Users > 0 {
+			r.RequestsPerUser = math.Round(float64(rq.Requests)/float64(r.Users)*100) / 100
+		}
+	}
+
+	var events []struct {
+		Variant string
+		Event   string
+		Count   int64
+		Users   int64
+	}
+	if err := db.Model(&models.ExperimentEvent{}).
+		Select("variant, event, COUNT(*) AS count, COUNT(DISTINCT user_id) AS users").
+		Where("experiment_id = ?", exp.ID).Group("variant, event").Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		r := byVariant[e.Variant]
+		if r == nil {
+			continue
+		}
+		stat := ExperimentStat{Count: e.Count, Users: e.Users}
+		if r.Users > 0 {
+			stat.ConversionPct = math.Round(float64(e.Users)/float64(r.Users)*10000) / 100
+		}
+		r.Events[e.Event] = stat
+	}
+	return results, nil
+}
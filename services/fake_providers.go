@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeHistoryStart is the first day of every synthetic price history. Histories always start
+// here, so a stock's price on a given day is the same whatever range is requested.
+const fakeHistoryStart = "2022-01-04"
+
+// Hosts of the market data providers answered by the fakes
+const (
+	fakeVNDirectHost       = "api-finfo.vndirect.com.vn"
+	fakeVNDirectLegacyHost = "finfo-api.vndirect.com.vn"
+)
+
+// fakeStockList is the synthetic universe: real codes on their exchanges, a few ETFs, so that
+// screeners, instrument filters and the per-exchange price limits all have data
+var fakeStockList = []struct {
+	code, floor, name string
+}{
+	{"VNM", "HOSE", "Vinamilk"}, {"FPT", "HOSE", "FPT Corporation"}, {"HPG", "HOSE", "Hoa Phat Group"},
+	{"VCB", "HOSE", "Vietcombank"}, {"BID", "HOSE", "BIDV"}, {"CTG", "HOSE", "VietinBank"},
+	{"TCB", "HOSE", "Techcombank"}, {"MBB", "HOSE", "MB Bank"}, {"ACB", "HOSE", "Asia Commercial Bank"},
+	{"VPB", "HOSE", "VPBank"}, {"MWG", "HOSE", "Mobile World"}, {"MSN", "HOSE", "Masan Group"},
+	{"VIC", "HOSE", "Vingroup"}, {"VHM", "HOSE", "Vinhomes"}, {"VRE", "HOSE", "Vincom Retail"},
+	{"GAS", "HOSE", "PV Gas"}, {"PLX", "HOSE", "Petrolimex"}, {"POW", "HOSE", "PV Power"},
+	{"SAB", "HOSE", "Sabeco"}, {"SSI", "HOSE", "SSI Securities"}, {"VND", "HOSE", "VNDirect Securities"},
+	{"HCM", "HOSE", "HSC Securities"}, {"VCI", "HOSE", "Vietcap Securities"}, {"DGC", "HOSE", "Duc Giang Chemicals"},
+	{"DPM", "HOSE", "PetroVietnam Fertilizer"}, {"GMD", "HOSE", "Gemadept"}, {"PNJ", "HOSE", "Phu Nhuan Jewelry"},
+	{"REE", "HOSE", "REE Corporation"}, {"KDH", "HOSE", "Khang Dien House"}, {"NLG", "HOSE", "Nam Long"},
+	{"DXG", "HOSE", "Dat Xanh Group"}, {"PDR", "HOSE", "Phat Dat Real Estate"}, {"HSG", "HOSE", "Hoa Sen Group"},
+	{"NKG", "HOSE", "Nam Kim Steel"}, {"FRT", "HOSE", "FPT Retail"}, {"DGW", "HOSE", "Digiworld"},
+	{"VHC", "HOSE", "Vinh Hoan"}, {"ANV", "HOSE", "Nam Viet"}, {"SHS", "HNX", "Saigon-Hanoi Securities"},
+	{"PVS", "HNX", "PetroVietnam Technical Services"}, {"IDC", "HNX", "IDICO"}, {"CEO", "HNX", "C.E.O Group"},
+	{"BSR", "UPCOM", "Binh Son Refining"}, {"ACV", "UPCOM", "Airports Corporation of Vietnam"},
+	{"VEA", "UPCOM", "VEAM"}, {"E1VFVN30", "HOSE", "DCVFM VN30 ETF"}, {"FUEVFVND", "HOSE", "DCVFM VNDiamond ETF"},
+	{"FUESSVFL", "HOSE", "SSIAM VNFIN Lead ETF"},
+}
+
+// fakeIndexCodes get index-like histories: no price limits, no tick rounding
+var fakeIndexCodes = map[string]bool{MarketIndexCode: true, "VN30": true, "HNXINDEX": true, "UPCOMINDEX": true}
+
+// FakeProviders answers VNDirect, SSI and Supabase requests with deterministic synthetic data, so
+// the whole stack runs offline for local development and integration tests. It replaces the
+// default HTTP transport; requests to loopback hosts pass through and any other host fails.
+type FakeProviders struct {
+	seed         uint64
+	real         http.RoundTripper
+	ssiHost      string
+	supabaseHost string
+	supabase     *fakeSupabase
+
+	mu     sync.Mutex
+	series map[string]fakeSeries // generated histories by code
+}
+
+type fakeSeries struct {
+	through string           // last day generated
+	prices  []StockPriceData // oldest first
+}
+
+// GlobalFakeProviders is nil unless FAKE_PROVIDERS is on
+var GlobalFakeProviders *FakeProviders
+
+// InitFakeProviders turns on the fake providers when FAKE_PROVIDERS=true. FAKE_PROVIDERS_SEED
+// changes the generated data; Supabase is faked on the host of SUPABASE_URL, which can be any URL.
+func InitFakeProviders() error {
+	if enabled, _ := strconv.ParseBool(os.Getenv("FAKE_PROVIDERS")); !enabled {
+		return nil
+	}
+
+	seed := uint64(1)
+	if v := os.Getenv("FAKE_PROVIDERS_SEED"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid FAKE_PROVIDERS_SEED %q", v)
+		}
+		seed = parsed
+	}
+
+	fake := NewFakeProviders(seed)
+	http.DefaultTransport = fake
+	GlobalFakeProviders = fake
+	log.Printf("⚠ FAKE_PROVIDERS on: market data and Supabase are synthetic (seed %d), other outgoing requests are blocked", seed)
+	return nil
+}
+
+// NewFakeProviders creates the fakes for a seed without installing them, for tools that need the
+// same synthetic data as a server running with FAKE_PROVIDERS
+func NewFakeProviders(seed uint64) *FakeProviders {
+	fake := &FakeProviders{
+		seed:         seed,
+		real:         http.DefaultTransport,
+		ssiHost:      hostOf(SSIStockInfoAPIURL),
+		supabaseHost: hostOf(os.Getenv("SUPABASE_URL")),
+		supabase:     newFakeSupabase(seed),
+		series:       make(map[string]fakeSeries),
+	}
+	if v := os.Getenv("SSI_IBOARD_API_URL"); v != "" {
+		fake.ssiHost = hostOf(v)
+	}
+	return fake
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// RoundTrip answers a request from the fakes
+func (f *FakeProviders) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	host := req.URL.Hostname()
+	switch {
+	case host == fakeVNDirectHost || host == fakeVNDirectLegacyHost:
+		return f.serveVNDirect(req), nil
+	case req.URL.Host == f.ssiHost:
+		return f.serveSSI(req), nil
+	case f.supabaseHost != "" && req.URL.Host == f.supabaseHost:
+		return f.supabase.serve(req), nil
+	case host == "localhost" || net.ParseIP(host).IsLoopback():
+		return f.real.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("fake providers: outgoing request to %s blocked", host)
+}
+
+// fakeResponse builds a response with a JSON body
+func fakeResponse(req *http.Request, status int, body interface{}) *http.Response {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+// serveVNDirect answers the stock list, daily prices and corporate events APIs
+func (f *FakeProviders) serveVNDirect(req *http.Request) *http.Response {
+	switch req.URL.Path {
+	case "/v4/stocks":
+		return fakeResponse(req, http.StatusOK, VNDirectResponse{Data: FakeStockList()})
+	case "/v4/events":
+		return fakeResponse(req, http.StatusOK, map[string]interface{}{"data": []interface{}{}})
+	case "/v4/stock_prices":
+		return f.serveVNDirectPrices(req)
+	}
+	return fakeResponse(req, http.StatusNotFound, map[string]string{"message": "not faked: " + req.URL.Path})
+}
+
+// serveVNDirectPrices answers ?q=code:VNM~date:gte:2024-01-01&size=270, newest first
+func (f *FakeProviders) serveVNDirectPrices(req *http.Request) *http.Response {
+	var code, from, to string
+	for _, part := range strings.Split(req.URL.Query().Get("q"), "~") {
+		switch {
+		case strings.HasPrefix(part, "code:"):
+			code = strings.ToUpper(strings.TrimPrefix(part, "code:"))
+		case strings.HasPrefix(part, "date:gte:"):
+			from = strings.TrimPrefix(part, "date:gte:")
+		case strings.HasPrefix(part, "date:lte:"):
+			to = strings.TrimPrefix(part, "date:lte:")
+		}
+	}
+	if code == "" {
+		return fakeResponse(req, http.StatusBadRequest, map[string]string{"message": "q=code: is required"})
+	}
+	size, err := strconv.Atoi(req.URL.Query().Get("size"))
+	if err != nil || size <= 0 {
+		size = 20
+		if from != "" {
+			size = math.MaxInt32
+		}
+	}
+
+	history := f.History(code, time.Now())
+	data := make([]StockPriceData, 0, min(size, len(history)))
+	for i := len(history) - 1; i >= 0 && len(data) < size; i-- {
+		day := history[i].Date
+		if (to != "" && day > to) || (from != "" && day < from) {
+			continue
+		}
+		data = append(data, history[i])
+	}
+	return fakeResponse(req, http.StatusOK, VNDirectPriceResponse{
+		Data: data, CurrentPage: 1, Size: size, TotalElements: len(data), TotalPages: 1,
+	})
+}
+
+// serveSSI answers the iBoard stock info API with a three-level book around the last close
+func (f *FakeProviders) serveSSI(req *http.Request) *http.Response {
+	code := strings.ToUpper(req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:])
+	history := f.History(code, time.Now())
+	if len(history) == 0 {
+		return fakeResponse(req, http.StatusNotFound, map[string]string{"message": "unknown stock"})
+	}
+	last := history[len(history)-1]
+	tick := fakeTick(last.Close)
+	rng := rand.New(rand.NewPCG(f.seed, fakeHash(code+":"+last.Date)))
+
+	var info ssiStockInfoResponse
+	d := &info.Data
+	d.StockSymbol = code
+	levels := []struct{ bid, bidVol, offer, offerVol *float64 }{
+		{&d.Best1Bid, &d.Best1BidVol, &d.Best1Offer, &d.Best1OfferVol},
+		{&d.Best2Bid, &d.Best2BidVol, &d.Best2Offer, &d.Best2OfferVol},
+		{&d.Best3Bid, &d.Best3BidVol, &d.Best3Offer, &d.Best3OfferVol},
+	}
+	for i, level := range levels {
+		*level.bid = math.Round((last.Close - tick*float64(i)) * PriceUnitVND)
+		*level.offer = math.Round((last.Close + tick*float64(i+1)) * PriceUnitVND)
+		*level.bidVol = float64(100 * (1 + rng.IntN(500)))
+		*level.offerVol = float64(100 * (1 + rng.IntN(500)))
+	}
+	return fakeResponse(req, http.StatusOK, info)
+}
+
+// FakeStockList returns the synthetic stock list
+func FakeStockList() []VNDirectStock {
+	stocks := make([]VNDirectStock, len(fakeStockList))
+	for i, s := range fakeStockList {
+		stocks[i] = VNDirectStock{
+			Code:           s.code,
+			Type:           "STOCK",
+			Floor:          s.floor,
+			Status:         "listed",
+			CompanyName:    s.name,
+			CompanyNameEng: s.name,
+			ShortName:      s.name,
+			ShortNameEng:   s.name,
+			ListedDate:     "2010-01-04",
+			CompanyID:      strconv.Itoa(1000 + i),
+		}
+		if classifyInstrumentCode(s.code) == InstrumentTypeETF {
+			stocks[i].Type = "ETF"
+		}
+	}
+	return stocks
+}
+
+// History returns the synthetic daily prices of a listed code or index on the trading days from
+// fakeHistoryStart through now, oldest first, and nil for other codes. The same seed always gives
+// the same prices.
+func (f *FakeProviders) History(code string, now time.Time) []StockPriceData {
+	floor := fakeFloor(code)
+	if floor == "" {
+		return nil
+	}
+	calendar := MarketCalendar()
+	through := now.In(calendar.Location()).Format(PriceDateFormat)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cached, ok := f.series[code]; ok && cached.through == through {
+		return cached.prices
+	}
+	prices := f.generate(code, floor, through, calendar)
+	f.series[code] = fakeSeries{through: through, prices: prices}
+	return prices
+}
+
+// generate runs a code's random walk from fakeHistoryStart to through
+func (f *FakeProviders) generate(code, floor, through string, calendar *TradingCalendar) []StockPriceData {
+	rng := rand.New(rand.NewPCG(f.seed, fakeHash(code)))
+
+	index := fakeIndexCodes[code]
+	limit := map[string]float64{"HOSE": 0.07, "HNX": 0.10, "UPCOM": 0.15}[floor]
+	price := 5 + 115*rng.Float64()*rng.Float64()
+	drift := 0.0004 * rng.NormFloat64()
+	volatility := 0.01 + 0.025*rng.Float64()
+	volume := math.Exp(11 + 3*rng.Float64())
+	kind := "STOCK"
+	if index {
+		price, drift, volatility, volume, limit, kind = 1000+200*rng.Float64(), 0.0003, 0.009, 6e8, 0, "INDEX"
+	} else if classifyInstrumentCode(code) == InstrumentTypeETF {
+		price, volatility, kind = 12+20*rng.Float64(), 0.008+0.005*rng.Float64(), "ETF"
+	}
+	round := func(p float64) float64 {
+		if index {
+			return math.Round(p*100) / 100
+		}
+		tick := fakeTick(p)
+		return math.Round(math.Max(tick, math.Round(p/tick)*tick)*100) / 100
+	}
+	clamp := func(p, basic float64) float64 {
+		if limit == 0 {
+			return p
+		}
+		return math.Min(math.Max(p, basic*(1-limit)), basic*(1+limit))
+	}
+	price = round(price)
+
+	var prices []StockPriceData
+	start, _ := time.ParseInLocation(PriceDateFormat, fakeHistoryStart, calendar.Location())
+	for day := start; day.Format(PriceDateFormat) <= through; day = day.AddDate(0, 0, 1) {
+		if !calendar.IsTradingDay(day) {
+			continue
+		}
+		basic := price
+		move := drift + volatility*rng.NormFloat64()
+		closePrice := round(clamp(basic*(1+move), basic))
+		open := round(clamp(basic*(1+0.3*volatility*rng.NormFloat64()), basic))
+		high := round(clamp(math.Max(open, closePrice)*(1+0.5*volatility*math.Abs(rng.NormFloat64())), basic))
+		low := round(clamp(math.Min(open, closePrice)*(1-0.5*volatility*math.Abs(rng.NormFloat64())), basic))
+		vol := math.Round(volume * math.Exp(0.4*rng.NormFloat64()) * (1 + math.Abs(move)/volatility*0.3))
+		average := math.Round((high+low+closePrice)/3*100) / 100
+		change := math.Round((closePrice-basic)*100) / 100
+
+		bar := StockPriceData{
+			Code: code, Date: day.Format(PriceDateFormat), Time: "15:00:00", Floor: floor, Type: kind,
+			BasicPrice: basic, Open: open, High: high, Low: low, Close: closePrice, Average: average,
+			AdOpen: open, AdHigh: high, AdLow: low, AdClose: closePrice, AdAverage: average,
+			NmVolume: vol, NmValue: math.Round(vol * average * PriceUnitVND),
+			Change: change, AdChange: change, PctChange: math.Round(change/basic*10000) / 100,
+		}
+		if limit > 0 {
+			bar.CeilingPrice, bar.FloorPrice = round(basic*(1+limit)-fakeTick(basic)/2), round(basic*(1-limit)+fakeTick(basic)/2)
+		}
+		prices = append(prices, bar)
+		price = closePrice
+	}
+	return prices
+}
+
+// fakeFloor returns the exchange of a synthetic code, or "" when the code is not faked
+func fakeFloor(code string) string {
+	if fakeIndexCodes[code] {
+		return "HOSE"
+	}
+	for _, s := range fakeStockList {
+		if s.code == code {
+			return s.floor
+		}
+	}
+	return ""
+}
+
+// fakeTick is the HOSE price step, in thousands of VND
+func fakeTick(price float64) float64 {
+	switch {
+	case price < 10:
+		return 0.01
+	case price < 50:
+		return 0.05
+	}
+	return 0.1
+}
+
+func fakeHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
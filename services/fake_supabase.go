@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Synthetic Supabase users and the admin account "admin"; every one signs in with FakeSupabasePassword
+const (
+	fakeProfileCount     = 24
+	FakeSupabasePassword = "fake-password"
+	fakeTokenTTL         = time.Hour
+)
+
+// PostgREST query parameters that are not column filters
+var fakeReservedParams = map[string]bool{"select": true, "order": true, "limit": true, "offset": true, "and": true, "or": true}
+
+// fakeSupabase is an in-memory stand-in for Supabase: PostgREST tables filtered by eq, and the
+// auth endpoints the backend calls, signing tokens with SUPABASE_JWT_SECRET
+type fakeSupabase struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]interface{}
+	rng    *rand.Rand
+}
+
+// newFakeSupabase seeds the profiles table with deterministic users, user01@fake.local is
+// premium and the others cycle through the free, basic and premium plans, and admin_users with admin
+func newFakeSupabase(seed uint64) *fakeSupabase {
+	fake := &fakeSupabase{
+		tables: make(map[string][]map[string]interface{}),
+		rng:    rand.New(rand.NewPCG(seed, fakeHash("supabase"))),
+	}
+	created, _ := time.Parse(PriceDateFormat, fakeHistoryStart)
+	plans := []string{"premium", "free", "basic", "free"}
+	for i := 1; i <= fakeProfileCount; i++ {
+		profile := map[string]interface{}{
+			"id":                    fake.newUUID(),
+			"email":                 fmt.Sprintf("user%02d@fake.local", i),
+			"phone_number":          fmt.Sprintf("09%08d", i),
+			"full_name":             fmt.Sprintf("Fake User %02d", i),
+			"nickname":              fmt.Sprintf("fake%02d", i),
+			"membership":            plans[(i-1)%len(plans)],
+			"membership_expires_at": nil,
+			"role":                  "user",
+			"is_active":             true,
+			"is_banned":             false,
+			"provider":              "email",
+			"created_at":            created.AddDate(0, 0, i).Format(time.RFC3339),
+			"updated_at":            created.AddDate(0, 0, i).Format(time.RFC3339),
+		}
+		if profile["membership"] != "free" {
+			profile["membership_expires_at"] = created.AddDate(10, 0, 0).Format(time.RFC3339)
+		}
+		fake.tables["profiles"] = append(fake.tables["profiles"], profile)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte(FakeSupabasePassword), bcrypt.MinCost)
+	fake.tables["admin_users"] = []map[string]interface{}{{
+		"id":            1,
+		"username":      "admin",
+		"password_hash": string(hash),
+		"email":         "admin@fake.local",
+		"full_name":     "Fake Admin",
+		"role":          "admin",
+		"is_active":     true,
+		"last_login_at": nil,
+		"created_at":    created.Format(time.RFC3339),
+		"updated_at":    created.Format(time.RFC3339),
+	}}
+	return fake
+}
+
+// newUUID draws a version 4 UUID from the seeded generator
+func (s *fakeSupabase) newUUID() string {
+	hi, lo := s.rng.Uint64(), s.rng.Uint64()
+	hi = hi&^(0xf<<12) | 0x4<<12
+	lo = lo&^(0x3<<62) | 0x2<<62
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+func (s *fakeSupabase) serve(req *http.Request) *http.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch path := req.URL.Path; {
+	case strings.HasPrefix(path, "/rest/v1/"):
+		return s.serveTable(req, strings.TrimPrefix(path, "/rest/v1/"))
+	case strings.HasPrefix(path, "/auth/v1/"):
+		return s.serveAuth(req, strings.TrimPrefix(path, "/auth/v1/"))
+	}
+	return fakeResponse(req, http.StatusNotFound, map[string]string{"message": "not faked: " + req.URL.Path})
+}
+
+// matches reports whether a row passes every col=eq.value filter; other operators are ignored
+func fakeRowMatches(row map[string]interface{}, query map[string][]string) bool {
+	for column, values := range query {
+		if fakeReservedParams[column] {
+			continue
+		}
+		for _, v := range values {
+			if want, ok := strings.CutPrefix(v, "eq."); ok && fmt.Sprint(row[column]) != want {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// serveTable answers PostgREST reads and writes on an in-memory table
+func (s *fakeSupabase) serveTable(req *http.Request, table string) *http.Response {
+	query := req.URL.Query()
+	rows := s.tables[table]
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		matched := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			if fakeRowMatches(row, query) {
+				matched = append(matched, row)
+			}
+		}
+		if order := query.Get("order"); order != "" {
+			parts := strings.Split(strings.Split(order, ",")[0], ".")
+			desc := len(parts) > 1 && parts[1] == "desc"
+			sort.SliceStable(matched, func(i, j int) bool {
+				a, b := fmt.Sprint(matched[i][parts[0]]), fmt.Sprint(matched[j][parts[0]])
+				return a < b != desc && a != b
+			})
+		}
+		total := len(matched)
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		matched = matched[min(offset, total):]
+		if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit < len(matched) {
+			matched = matched[:limit]
+		}
+
+		var body interface{} = matched
+		if query.Get("select") == "count" {
+			body = []map[string]int{{"count": total}}
+		}
+		resp := fakeResponse(req, http.StatusOK, body)
+		if len(matched) == 0 {
+			resp.Header.Set("Content-Range", fmt.Sprintf("*/%d", total))
+		} else {
+			resp.Header.Set("Content-Range", fmt.Sprintf("%d-%d/%d", offset, offset+len(matched)-1, total))
+		}
+		return resp
+
+	case http.MethodPost:
+		inserted, err := fakeDecodeRows(req)
+		if err != nil {
+			return fakeResponse(req, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		for _, row := range inserted {
+			if _, ok := row["id"]; !ok {
+				row["id"] = len(s.tables[table]) + 1
+			}
+			row["created_at"], row["updated_at"] = now, now
+			s.tables[table] = append(s.tables[table], row)
+		}
+		return fakeResponse(req, http.StatusCreated, inserted)
+
+	case http.MethodPatch:
+		changes, err := fakeDecodeRows(req)
+		if err != nil || len(changes) != 1 {
+			return fakeResponse(req, http.StatusBadRequest, map[string]string{"message": "expected one JSON object"})
+		}
+		updated := []map[string]interface{}{}
+		for _, row := range rows {
+			if fakeRowMatches(row, query) {
+				for k, v := range changes[0] {
+					row[k] = v
+				}
+				row["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+				updated = append(updated, row)
+			}
+		}
+		return fakeResponse(req, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		kept := rows[:0]
+		for _, row := range rows {
+			if !fakeRowMatches(row, query) {
+				kept = append(kept, row)
+			}
+		}
+		s.tables[table] = kept
+		return fakeResponse(req, http.StatusNoContent, nil)
+	}
+	return fakeResponse(req, http.StatusMethodNotAllowed, map[string]string{"message": "method not faked"})
+}
+
+// fakeDecodeRows reads a JSON object or array of objects
+func fakeDecodeRows(req *http.Request) ([]map[string]interface{}, error) {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]interface{}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &rows)
+	} else {
+		var row map[string]interface{}
+		err = json.Unmarshal(data, &row)
+		rows = []map[string]interface{}{row}
+	}
+	return rows, err
+}
+
+// findProfile returns the profile with a column value
+func (s *fakeSupabase) findProfile(column, value string) map[string]interface{} {
+	for _, row := range s.tables["profiles"] {
+		if strings.EqualFold(fmt.Sprint(row[column]), value) {
+			return row
+		}
+	}
+	return nil
+}
+
+// fakeUser renders a profile as a Supabase auth user
+func fakeUser(profile map[string]interface{}) SupabaseUser {
+	created := fmt.Sprint(profile["created_at"])
+	return SupabaseUser{
+		ID:               fmt.Sprint(profile["id"]),
+		Aud:              "authenticated",
+		Role:             "authenticated",
+		Email:            fmt.Sprint(profile["email"]),
+		EmailConfirmedAt: created,
+		ConfirmedAt:      created,
+		LastSignInAt:     time.Now().UTC().Format(time.RFC3339),
+		AppMetadata:      map[string]interface{}{"provider": "email", "providers": []string{"email"}},
+		UserMetadata:     map[string]interface{}{"full_name": profile["full_name"]},
+		Identities:       []interface{}{},
+		CreatedAt:        created,
+		UpdatedAt:        fmt.Sprint(profile["updated_at"]),
+	}
+}
+
+// session signs an access token for a profile like Supabase Auth does
+func (s *fakeSupabase) session(req *http.Request, profile map[string]interface{}) *http.Response {
+	secret := os.Getenv("SUPABASE_JWT_SECRET")
+	if secret == "" {
+		return fakeResponse(req, http.StatusInternalServerError, map[string]string{"msg": "set SUPABASE_JWT_SECRET to sign fake tokens"})
+	}
+	user := fakeUser(profile)
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":           user.ID,
+		"aud":           "authenticated",
+		"role":          "authenticated",
+		"email":         user.Email,
+		"app_metadata":  user.AppMetadata,
+		"user_metadata": user.UserMetadata,
+		"aal":           "aal1",
+		"session_id":    s.newUUID(),
+		"iat":           now.Unix(),
+		"exp":           now.Add(fakeTokenTTL).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return fakeResponse(req, http.StatusInternalServerError, map[string]string{"msg": err.Error()})
+	}
+	return fakeResponse(req, http.StatusOK, SupabaseAuthResponse{
+		AccessToken:  token,
+		TokenType:    "bearer",
+		ExpiresIn:    int(fakeTokenTTL.Seconds()),
+		ExpiresAt:    now.Add(fakeTokenTTL).Unix(),
+		RefreshToken: "fake-refresh-" + user.ID,
+		User:         user,
+	})
+}
+
+// serveAuth answers sign-in, token refresh, the current user, logout and user administration
+func (s *fakeSupabase) serveAuth(req *http.Request, path string) *http.Response {
+	var body map[string]interface{}
+	if req.Body != nil {
+		json.NewDecoder(req.Body).Decode(&body)
+	}
+	invalid := fakeResponse(req, http.StatusBadRequest, SupabaseErrorResponse{Error: "invalid_grant", ErrorDescription: "Invalid login credentials"})
+
+	switch {
+	case path == "token" && req.URL.Query().Get("grant_type") == "password":
+		profile := s.findProfile("email", fmt.Sprint(body["email"]))
+		if profile == nil || body["password"] != FakeSupabasePassword {
+			return invalid
+		}
+		return s.session(req, profile)
+
+	case path == "token" && req.URL.Query().Get("grant_type") == "refresh_token":
+		id, ok := strings.CutPrefix(fmt.Sprint(body["refresh_token"]), "fake-refresh-")
+		profile := s.findProfile("id", id)
+		if !ok || profile == nil {
+			return invalid
+		}
+		return s.session(req, profile)
+
+	case path == "user":
+		// The signature was checked by the caller's middleware; only the subject is needed here
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err == nil {
+			if profile := s.findProfile("id", fmt.Sprint(claims["sub"])); profile != nil {
+				return fakeResponse(req, http.StatusOK, fakeUser(profile))
+			}
+		}
+		return fakeResponse(req, http.StatusUnauthorized, SupabaseErrorResponse{Message: "invalid JWT"})
+
+	case path == "logout":
+		return fakeResponse(req, http.StatusNoContent, nil)
+
+	case path == "admin/users" && req.Method == http.MethodGet:
+		users := make([]SupabaseUser, 0, len(s.tables["profiles"]))
+		for _, profile := range s.tables["profiles"] {
+			users = append(users, fakeUser(profile))
+		}
+		return fakeResponse(req, http.StatusOK, map[string]interface{}{"users": users})
+
+	case path == "admin/users" && req.Method == http.MethodPost:
+		// Supabase creates the profile from a trigger; the fake does it directly
+		email := fmt.Sprint(body["email"])
+		if s.findProfile("email", email) != nil {
+			return fakeResponse(req, http.StatusUnprocessableEntity, SupabaseErrorResponse{Message: "User already registered"})
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		profile := map[string]interface{}{
+			"id": s.newUUID(), "email": email, "membership": "free", "role": "user",
+			"is_active": true, "is_banned": false, "provider": "email", "created_at": now, "updated_at": now,
+		}
+		s.tables["profiles"] = append(s.tables["profiles"], profile)
+		return fakeResponse(req, http.StatusOK, fakeUser(profile))
+
+	case strings.HasPrefix(path, "admin/users/"):
+		profile := s.findProfile("id", strings.TrimPrefix(path, "admin/users/"))
+		if profile == nil {
+			return fakeResponse(req, http.StatusNotFound, SupabaseErrorResponse{Message: "User not found"})
+		}
+		if req.Method == http.MethodDelete {
+			return fakeResponse(req, http.StatusOK, map[string]interface{}{})
+		}
+		if email, ok := body["email"].(string); ok && email != "" {
+			profile["email"] = email
+		}
+		return fakeResponse(req, http.StatusOK, fakeUser(profile))
+	}
+	return fakeResponse(req, http.StatusNotFound, SupabaseErrorResponse{Message: "not faked: /auth/v1/" + path})
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Google OAuth endpoints
+const (
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// serviceAccount is the part of a Google service account key file used to mint tokens
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// loadServiceAccount reads the key file given with GOOGLE_APPLICATION_CREDENTIALS, or returns nil
+// when it is unset
+func loadServiceAccount() (*serviceAccount, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read service account key: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("invalid service account key %s", path)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURL
+	}
+	return &account, nil
+}
+
+// googleTokenSource issues OAuth access tokens for one Google API scope
+type googleTokenSource struct {
+	scope      string
+	account    *serviceAccount // nil on Google Cloud, where the metadata server issues tokens
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func newGoogleTokenSource(scope string, account *serviceAccount, httpClient *http.Client) *googleTokenSource {
+	return &googleTokenSource{scope: scope, account: account, httpClient: httpClient}
+}
+
+// token returns a cached access token, refreshing it shortly before it expires
+func (t *googleTokenSource) token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken != "" && time.Until(t.expiresAt) > time.Minute {
+		return t.accessToken, nil
+	}
+
+	var req *http.Request
+	var err error
+	if t.account != nil {
+		req, err = t.serviceAccountTokenRequest(ctx)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL+"?scopes="+url.QueryEscape(t.scope), nil)
+		if req != nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("access token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("access token request failed: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", errors.New("invalid access token response")
+	}
+
+	t.accessToken = token.AccessToken
+	t.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return t.accessToken, nil
+}
+
+// serviceAccountTokenRequest exchanges a JWT signed with the service account key for a token
+func (t *googleTokenSource) serviceAccountTokenRequest(ctx context.Context) (*http.Request, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(t.account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   t.account.ClientEmail,
+		"scope": t.scope,
+		"aud":   t.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
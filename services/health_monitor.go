@@ -0,0 +1,378 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Default health thresholds, overridden with the HEALTH_* variables
+const (
+	DefaultHealthWindow           = 10 * time.Minute // HEALTH_WINDOW_MINUTES
+	DefaultSyncFailedPct          = 20.0             // HEALTH_SYNC_FAILED_PCT
+	DefaultServerErrorPct         = 5.0              // HEALTH_SERVER_ERROR_PCT
+	DefaultServerErrorMinRequests = 50               // HEALTH_SERVER_ERROR_MIN_REQUESTS
+	DefaultMongoFailures          = 10               // HEALTH_MONGO_FAILURES
+
+	// IncidentReminderInterval is how often operators are reminded of a critical open incident
+	IncidentReminderInterval = time.Hour
+	// recentIncidentWindow is how long resolved incidents stay on the status page
+	recentIncidentWindow = 24 * time.Hour
+)
+
+// Overall statuses of the status page
+const (
+	HealthStatusOperational = "operational"
+	HealthStatusDegraded    = "degraded"
+	HealthStatusOutage      = "major_outage"
+)
+
+// HealthThresholds are the limits above which a health check opens an incident. A reading at
+// twice its threshold is critical.
+type HealthThresholds struct {
+	Window                 time.Duration `json:"window"`
+	SyncFailedPct          float64       `json:"sync_failed_pct"`           // failed stocks of the latest price sync
+	ServerErrorPct         float64       `json:"server_error_pct"`          // 5xx responses within the window
+	ServerErrorMinRequests int64         `json:"server_error_min_requests"` // fewer requests are never an incident
+	MongoFailures          int64         `json:"mongo_failures"`            // failed MongoDB commands within the window
+}
+
+// HealthReading is the outcome of one health check
+type HealthReading struct {
+	Check     string  `json:"check"`
+	Breached  bool    `json:"breached"`
+	Severity  string  `json:"severity,omitempty"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Title     string  `json:"title"`
+	Message   string  `json:"message"`
+}
+
+// HealthStatus is the status page: the latest readings, open incidents and those resolved
+// within the last day
+type HealthStatus struct {
+	Status    string            `json:"status"`
+	CheckedAt *time.Time        `json:"checked_at"`
+	Checks    []HealthReading   `json:"checks"`
+	Incidents []models.Incident `json:"incidents"`
+	Recent    []models.Incident `json:"recent"`
+}
+
+// HealthMonitor compares failure rates to their thresholds, keeps one incident per failing check
+// and alerts the operators when incidents open, escalate and resolve
+type HealthMonitor struct {
+	db         *gorm.DB
+	thresholds HealthThresholds
+
+	mu           sync.Mutex
+	mongoSamples []mongoSample
+	readings     []HealthReading
+	checkedAt    time.Time
+}
+
+type mongoSample struct {
+	at     time.Time
+	failed int64
+}
+
+// GlobalHealthMonitor is nil until InitHealthMonitor runs
+var GlobalHealthMonitor *HealthMonitor
+
+// InitHealthMonitor initializes the health monitor with the thresholds of the environment
+func InitHealthMonitor(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	thresholds := HealthThresholds{
+		Window:                 DefaultHealthWindow,
+		SyncFailedPct:          DefaultSyncFailedPct,
+		ServerErrorPct:         DefaultServerErrorPct,
+		ServerErrorMinRequests: DefaultServerErrorMinRequests,
+		MongoFailures:          DefaultMongoFailures,
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTH_WINDOW_MINUTES")); err == nil && v > 0 {
+		thresholds.Window = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("HEALTH_SYNC_FAILED_PCT"), 64); err == nil && v > 0 {
+		thresholds.SyncFailedPct = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("HEALTH_SERVER_ERROR_PCT"), 64); err == nil && v > 0 {
+		thresholds.ServerErrorPct = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("HEALTH_SERVER_ERROR_MIN_REQUESTS"), 10, 64); err == nil && v > 0 {
+		thresholds.ServerErrorMinRequests = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("HEALTH_MONGO_FAILURES"), 10, 64); err == nil && v > 0 {
+		thresholds.MongoFailures = v
+	}
+
+	GlobalHealthMonitor = &HealthMonitor{db: db, thresholds: thresholds}
+	return nil
+}
+
+// Thresholds returns the monitor's thresholds
+func (m *HealthMonitor) Thresholds() HealthThresholds {
+	return m.thresholds
+}
+
+// Check runs every health check and opens, updates or resolves their incidents
+func (m *HealthMonitor) Check(now time.Time) ([]HealthReading, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	priceSync, err := m.checkPriceSync()
+	if err != nil {
+		return nil, err
+	}
+	serverErrors, err := m.checkServerErrors(now)
+	if err != nil {
+		return nil, err
+	}
+	readings := []HealthReading{priceSync, serverErrors, m.checkMongo(now)}
+
+	for _, reading := range readings {
+		if err := m.track(reading, now); err != nil {
+			return nil, err
+		}
+	}
+	m.readings, m.checkedAt = readings, now
+	return readings, nil
+}
+
+// severity is critical at twice the threshold
+func severity(value, threshold float64) string {
+	if value >= 2*threshold {
+		return models.IncidentSeverityCritical
+	}
+	return models.IncidentSeverityWarning
+}
+
+// checkPriceSync reads the failed share of the latest price sync run
+func (m *HealthMonitor) checkPriceSync() (HealthReading, error) {
+	reading := HealthReading{
+		Check:     models.HealthCheckPriceSync,
+		Threshold: m.thresholds.SyncFailedPct,
+		Title:     "Price sync failures",
+	}
+
+	var run models.SyncHistory
+	err := m.db.Where("sync_type IN ?", []string{models.SyncTypePriceFull, models.SyncTypePriceRetry, models.SyncTypePriceBatch}).
+		Order("finished_at DESC").Limit(1).Find(&run).Error
+	if err != nil {
+		return reading, err
+	}
+	if run.ID == 0 {
+		reading.Message = "No price sync recorded yet"
+		return reading, nil
+	}
+
+	reading.Value = 100
+	if run.TotalItems > 0 {
+		reading.Value = math.Round(float64(run.FailedItems)/float64(run.TotalItems)*10000) / 100
+	}
+	reading.Message = fmt.Sprintf("%s at %s: %d of %d stocks failed (%.1f%%)",
+		run.SyncType, run.FinishedAt.Format(time.RFC3339), run.FailedItems, run.TotalItems, reading.Value)
+	if run.Status == models.SyncStatusFailed {
+		reading.Breached, reading.Severity = true, models.IncidentSeverityCritical
+		if run.Error != "" {
+			reading.Message += ": " + run.Error
+		}
+		return reading, nil
+	}
+	if reading.Value >= reading.Threshold {
+		reading.Breached, reading.Severity = true, severity(reading.Value, reading.Threshold)
+	}
+	return reading, nil
+}
+
+// checkServerErrors reads the 5xx share of the API requests logged within the window
+func (m *HealthMonitor) checkServerErrors(now time.Time) (HealthReading, error) {
+	reading := HealthReading{
+		Check:     models.HealthCheckServerError,
+		Threshold: m.thresholds.ServerErrorPct,
+		Title:     "API server error rate",
+	}
+
+	var counts struct {
+		Requests int64
+		Errors   int64
+	}
+	err := m.db.Model(&models.APIUsageLog{}).
+		Select("COUNT(*) AS requests, COUNT(CASE WHEN status >= 500 THEN 1 END) AS errors").
+		Where("at >= ?", now.Add(-m.thresholds.Window)).Scan(&counts).Error
+	if err != nil {
+		return reading, err
+	}
+	if counts.Requests > 0 {
+		reading.Value = math.Round(float64(counts.Errors)/float64(counts.Requests)*10000) / 100
+	}
+	reading.Message = fmt.Sprintf("%d of %d requests failed with 5xx in the last %s (%.1f%%)",
+		counts.Errors, counts.Requests, m.thresholds.Window, reading.Value)
+	if counts.Requests >= m.thresholds.ServerErrorMinRequests && reading.Value >= reading.Threshold {
+		reading.Breached, reading.Severity = true, severity(reading.Value, reading.Threshold)
+	}
+	return reading, nil
+}
+
+// checkMongo counts the failed MongoDB commands within the window. A configured MongoDB that
+// is disconnected is critical.
+func (m *HealthMonitor) checkMongo(now time.Time) HealthReading {
+	reading := HealthReading{
+		Check:     models.HealthCheckMongoDB,
+		Threshold: float64(m.thresholds.MongoFailures),
+		Title:     "MongoDB errors",
+	}
+	if GlobalMongoClient == nil || !GlobalMongoClient.IsURISet() {
+		reading.Message = "MongoDB is not configured"
+		return reading
+	}
+	if !GlobalMongoClient.IsConfigured() {
+		reading.Breached, reading.Severity = true, models.IncidentSeverityCritical
+		reading.Message = "MongoDB is disconnected"
+		return reading
+	}
+
+	// Keep the samples of the window plus the last one before it, which is the baseline
+	_, failed := MongoCommandStats()
+	m.mongoSamples = append(m.mongoSamples, mongoSample{at: now, failed: failed})
+	for len(m.mongoSamples) > 1 && !m.mongoSamples[1].at.After(now.Add(-m.thresholds.Window)) {
+		m.mongoSamples = m.mongoSamples[1:]
+	}
+	reading.Value = float64(failed - m.mongoSamples[0].failed)
+	reading.Message = fmt.Sprintf("%d MongoDB commands failed in the last %s", int64(reading.Value), m.thresholds.Window)
+	if reading.Value >= reading.Threshold {
+		reading.Breached, reading.Severity = true, severity(reading.Value, reading.Threshold)
+	}
+	return reading
+}
+
+// track opens an incident for a breached check, updates and escalates an open one, and resolves
+// it once the check is back under its threshold
+func (m *HealthMonitor) track(reading HealthReading, now time.Time) error {
+	var incident models.Incident
+	err := m.db.Where("check_name = ? AND status = ?", reading.Check, models.IncidentStatusOpen).
+		Order("id DESC").Limit(1).Find(&incident).Error
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case reading.Breached && incident.ID == 0:
+		incident = models.Incident{
+			Check:      reading.Check,
+			Severity:   reading.Severity,
+			Status:     models.IncidentStatusOpen,
+			Title:      reading.Title,
+			Message:    reading.Message,
+			Value:      reading.Value,
+			Threshold:  reading.Threshold,
+			OpenedAt:   now,
+			LastSeenAt: now,
+			NotifiedAt: &now,
+		}
+		if err := m.db.Create(&incident).Error; err != nil {
+			return err
+		}
+		m.alert(&incident, "opened", reading.Severity)
+
+	case reading.Breached:
+		escalated := reading.Severity == models.IncidentSeverityCritical && incident.Severity != models.IncidentSeverityCritical
+		remind := incident.Severity == models.IncidentSeverityCritical &&
+			(incident.NotifiedAt == nil || now.Sub(*incident.NotifiedAt) >= IncidentReminderInterval)
+		updates := map[string]interface{}{"message": reading.Message, "value": reading.Value, "last_seen_at": now}
+		if escalated {
+			updates["severity"] = models.IncidentSeverityCritical
+		}
+		if escalated || remind {
+			updates["notified_at"] = now
+		}
+		if err := m.db.Model(&incident).Updates(updates).Error; err != nil {
+			return err
+		}
+		incident.Message, incident.Value = reading.Message, reading.Value
+		if escalated {
+			incident.Severity = models.IncidentSeverityCritical
+		}
+		switch {
+		case escalated:
+			m.alert(&incident, "escalated", models.IncidentSeverityCritical)
+		case remind:
+			m.alert(&incident, "still open", models.IncidentSeverityCritical)
+		}
+
+	case incident.ID != 0:
+		if err := m.db.Model(&incident).Updates(map[string]interface{}{
+			"status": models.IncidentStatusResolved, "resolved_at": now, "message": reading.Message, "value": reading.Value,
+		}).Error; err != nil {
+			return err
+		}
+		incident.Message, incident.Value = reading.Message, reading.Value
+		m.alert(&incident, "resolved", "info")
+	}
+	return nil
+}
+
+// alert tells the operators over Telegram and email and records an admin notification of the
+// level (info, warning, critical)
+func (m *HealthMonitor) alert(incident *models.Incident, event, level string) {
+	title := fmt.Sprintf("[%s] %s %s", strings.ToUpper(incident.Severity), incident.Title, event)
+	if event == "resolved" {
+		title = fmt.Sprintf("[RESOLVED] %s", incident.Title)
+	}
+	body := incident.Message
+	if event != "opened" {
+		body += fmt.Sprintf("\nOpen since %s (%s)", incident.OpenedAt.Format(time.RFC3339),
+			time.Since(incident.OpenedAt).Round(time.Minute))
+	}
+
+	NotifyAdmins(m.db, level, "health", title, body, map[string]interface{}{
+		"incident_id": incident.ID,
+		"check":       incident.Check,
+		"value":       incident.Value,
+		"threshold":   incident.Threshold,
+	})
+	if err := GlobalOperatorAlerts.Send(title, body); err != nil {
+		log.Printf("Failed to send operator alert %q: %v", title, err)
+	}
+}
+
+// GetHealthStatus returns the status page from the latest check and the incidents of the
+// last day
+func GetHealthStatus(db *gorm.DB, now time.Time) (*HealthStatus, error) {
+	status := &HealthStatus{Status: HealthStatusOperational, Checks: []HealthReading{}, Incidents: []models.Incident{}, Recent: []models.Incident{}}
+	if m := GlobalHealthMonitor; m != nil {
+		m.mu.Lock()
+		if !m.checkedAt.IsZero() {
+			checkedAt := m.checkedAt
+			status.CheckedAt = &checkedAt
+			status.Checks = append(status.Checks, m.readings...)
+		}
+		m.mu.Unlock()
+	}
+
+	if err := db.Where("status = ?", models.IncidentStatusOpen).Order("opened_at DESC").Find(&status.Incidents).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("status = ? AND resolved_at >= ?", models.IncidentStatusResolved, now.Add(-recentIncidentWindow)).
+		Order("resolved_at DESC").Find(&status.Recent).Error; err != nil {
+		return nil, err
+	}
+	for _, incident := range status.Incidents {
+		if incident.Severity == models.IncidentSeverityCritical {
+			status.Status = HealthStatusOutage
+			break
+		}
+		status.Status = HealthStatusDegraded
+	}
+	return status, nil
+}
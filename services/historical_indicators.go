@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PriceDateFormat is the date layout used by VNDirect price records
+const PriceDateFormat = "2006-01-02"
+
+// LoadPriceUniverse loads every local per-stock price file into memory
+func LoadPriceUniverse() (map[string]*StockPriceFile, error) {
+	if GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+
+	files, err := os.ReadDir(StockPriceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price directory: %w", err)
+	}
+
+	universe := make(map[string]*StockPriceFile, len(files))
+	for _, file := range files {
+		if filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		code := file.Name()[:len(file.Name())-5]
+		priceFile, err := GlobalPriceService.LoadStockPrice(code)
+		if err != nil || len(priceFile.Prices) == 0 {
+			continue
+		}
+		universe[code] = priceFile
+	}
+
+	if len(universe) == 0 {
+		return nil, fmt.Errorf("no price files found")
+	}
+	return universe, nil
+}
+
+// PricesAsOf returns a view of priceFile holding only prices on or before asOf (YYYY-MM-DD).
+// Prices are stored newest first, so the result shares the original backing array.
+func PricesAsOf(priceFile *StockPriceFile, asOf string) *StockPriceFile {
+	if priceFile == nil {
+		return nil
+	}
+	idx := sort.Search(len(priceFile.Prices), func(i int) bool {
+		return priceFile.Prices[i].Date <= asOf
+	})
+	return &StockPriceFile{
+		Code:        priceFile.Code,
+		LastUpdated: priceFile.LastUpdated,
+		DataCount:   len(priceFile.Prices) - idx,
+		Prices:      priceFile.Prices[idx:],
+	}
+}
+
+// CalculateIndicatorsAsOf computes indicators and RS ranks for the universe as they stood on asOf
+func CalculateIndicatorsAsOf(universe map[string]*StockPriceFile, asOf string) map[string]*ExtendedStockIndicators {
+	_, params := ActiveIndicatorParams()
+	return CalculateUniverseIndicators(universe, asOf, params, GlobalIndicatorService.RSMethod())
+}
+
+// IndicatorsAsOf recomputes the indicators of every stock from price history truncated at asOf
+// (YYYY-MM-DD), reproducing what the indicator summary held after that day's close. A non-trading
+// date resolves to the last trading date before it, which is returned with the indicators.
+func IndicatorsAsOf(asOf string) (map[string]*ExtendedStockIndicators, string, error) {
+	date, err := time.ParseInLocation(PriceDateFormat, asOf, MarketCalendar().Location())
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid as_of date %q, expected YYYY-MM-DD", asOf)
+	}
+	if date.After(time.Now()) {
+		return nil, "", fmt.Errorf("as_of date %s is in the future", asOf)
+	}
+
+	universe, err := LoadPriceUniverse()
+	if err != nil {
+		return nil, "", err
+	}
+	dates := TradingDates(universe, "", asOf)
+	if len(dates) == 0 {
+		return nil, "", fmt.Errorf("no price history on or before %s", asOf)
+	}
+	tradingDate := dates[len(dates)-1]
+
+	return CalculateIndicatorsAsOf(universe, tradingDate), tradingDate, nil
+}
+
+// StockIndicatorsAsOf computes the indicators of one stock as they stood after the close of asOf,
+// for replays too light to load the universe. RS ranks compare stocks with each other and are
+// left at zero; benchmark RS is measured against index, the VN-Index prices, when it is the
+// active method and index is set. Returns nil when the stock did not trade on asOf.
+func StockIndicatorsAsOf(priceFile, index *StockPriceFile, asOf string) *ExtendedStockIndicators {
+	view := PricesAsOf(priceFile, asOf)
+	if view == nil || len(view.Prices) == 0 || view.Prices[0].Date != asOf {
+		return nil
+	}
+	_, params := ActiveIndicatorParams()
+	ind := CalculateIndicatorsWithParams(view, params)
+	if ind == nil {
+		return nil
+	}
+	ind.UpdatedAt = asOf
+	if index != nil && GlobalIndicatorService.RSMethod() == RSMethodBenchmark {
+		applyBenchmarkRS(ind, view.Prices, NewBenchmarkSeries(PricesAsOf(index, asOf).Prices))
+	}
+	return ind
+}
+
+// CalculateUniverseIndicators computes indicators and RS ranks for the universe on asOf with
+// explicit parameters and RS method, independent of the active configuration
+func CalculateUniverseIndicators(universe map[string]*StockPriceFile, asOf string, params IndicatorParams, rsMethod string) map[string]*ExtendedStockIndicators {
+	result := make(map[string]*ExtendedStockIndicators, len(universe))
+
+	var bench *BenchmarkSeries
+	if index, ok := universe[MarketIndexCode]; ok && rsMethod == RSMethodBenchmark {
+		bench = NewBenchmarkSeries(PricesAsOf(index, asOf).Prices)
+	}
+
+	for code, priceFile := range universe {
+		if code == MarketIndexCode {
+			continue
+		}
+		view := PricesAsOf(priceFile, asOf)
+		// Skip stocks that did not trade on asOf so stale prices don't produce signals
+		if view == nil || len(view.Prices) == 0 || view.Prices[0].Date != asOf {
+			continue
+		}
+		if ind := CalculateIndicatorsWithParams(view, params); ind != nil {
+			ind.UpdatedAt = asOf
+			applyBenchmarkRS(ind, view.Prices, bench)
+			result[code] = ind
+		}
+	}
+	CalculateRSRanks(result)
+	return result
+}
+
+// TradingDates returns the sorted distinct price dates in the universe within [from, to]
+func TradingDates(universe map[string]*StockPriceFile, from, to string) []string {
+	seen := make(map[string]bool)
+	for _, priceFile := range universe {
+		for _, p := range priceFile.Prices {
+			if p.Date >= from && p.Date <= to {
+				seen[p.Date] = true
+			}
+		}
+	}
+
+	dates := make([]string, 0, len(seen))
+	for date := range seen {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
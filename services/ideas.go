@@ -0,0 +1,583 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Limits of trade ideas
+const (
+	MaxIdeasPerUserPerDay  = 10
+	MaxIdeaTitleLength     = 150  // characters
+	MaxIdeaBodyLength      = 5000 // characters
+	MaxIdeaReportLength    = 300  // characters
+	DefaultIdeaHorizonDays = 20   // trading sessions
+	MaxIdeaHorizonDays     = 120
+	// IdeaAutoHideReports is the number of reports that hides an idea until a moderator reviews it
+	IdeaAutoHideReports = 5
+	// TrendingIdeaWindowDays is how far back the trending feed looks
+	TrendingIdeaWindowDays = 7
+)
+
+var (
+	// ErrInvalidIdea is wrapped by the errors of ideas that break a rule or limit
+	ErrInvalidIdea = errors.New("invalid idea")
+	// ErrIdeaNotFound is returned for ideas that don't exist or that the caller can't see
+	ErrIdeaNotFound = errors.New("idea not found")
+)
+
+// IdeaInput is what a user posts as a trade idea
+type IdeaInput struct {
+	StockCode     string  `json:"stock_code" binding:"required"`
+	Direction     string  `json:"direction"` // long (default), short
+	Title         string  `json:"title" binding:"required"`
+	Body          string  `json:"body"`
+	RuleID        uint    `json:"rule_id"`
+	TemplateID    uint    `json:"template_id"`
+	TargetPrice   float64 `json:"target_price"`
+	StopLossPrice float64 `json:"stop_loss_price"`
+	HorizonDays   int     `json:"horizon_days"` // trading sessions, default 20
+}
+
+// IdeaFeedQuery selects a page of a feed
+type IdeaFeedQuery struct {
+	StockCode string
+	Trending  bool // ranked by likes and follows, decayed by age, over the last TrendingIdeaWindowDays
+	Page      int
+	Limit     int
+}
+
+// IdeaOutcomeStats summarizes the outcomes of the ideas on a stock
+type IdeaOutcomeStats struct {
+	Total        int64   `json:"total"`
+	Open         int64   `json:"open"`
+	HitTarget    int64   `json:"hit_target"`
+	Stopped      int64   `json:"stopped"`
+	Expired      int64   `json:"expired"`
+	HitRatePct   float64 `json:"hit_rate_pct"`   // of closed ideas, those that reached their target
+	AvgReturnPct float64 `json:"avg_return_pct"` // of closed ideas, in their direction
+}
+
+// currentIdeaPrice is the price an idea is posted at: the last polled price during the session,
+// otherwise the latest daily close
+func currentIdeaPrice(code string) float64 {
+	if MarketCalendar().IsMarketOpen(time.Now()) {
+		if p := GlobalRealtimeService.LatestPrice(code); p != nil && p.Price > 0 {
+			return p.Price
+		}
+	}
+	if GlobalPriceService != nil {
+		if file, err := GlobalPriceService.LoadStockPrice(code); err == nil && len(file.Prices) > 0 {
+			return file.Prices[0].Close
+		}
+	}
+	return 0
+}
+
+// validateIdeaText trims the title and body of an idea and checks their lengths
+func validateIdeaText(title, body string) (string, string, error) {
+	title, body = strings.TrimSpace(title), strings.TrimSpace(body)
+	if title == "" {
+		return "", "", fmt.Errorf("%w: title is required", ErrInvalidIdea)
+	}
+	if utf8.RuneCountInString(title) > MaxIdeaTitleLength {
+		return "", "", fmt.Errorf("%w: title is longer than %d characters", ErrInvalidIdea, MaxIdeaTitleLength)
+	}
+	if utf8.RuneCountInString(body) > MaxIdeaBodyLength {
+		return "", "", fmt.Errorf("%w: body is longer than %d characters", ErrInvalidIdea, MaxIdeaBodyLength)
+	}
+	return title, body, nil
+}
+
+// CreateIdea posts a trade idea at the stock's current price. A linked rule must be active and
+// admin-owned or the author's own, a linked template public or the author's own.
+func CreateIdea(db *gorm.DB, authorID, authorName string, input IdeaInput) (*models.Idea, error) {
+	title, body, err := validateIdeaText(input.Title, input.Body)
+	if err != nil {
+		return nil, err
+	}
+	code := strings.ToUpper(strings.TrimSpace(input.StockCode))
+	if code == "" || len(code) > 20 {
+		return nil, fmt.Errorf("%w: invalid stock code", ErrInvalidIdea)
+	}
+	direction := strings.ToLower(strings.TrimSpace(input.Direction))
+	if direction == "" {
+		direction = models.IdeaDirectionLong
+	}
+	if direction != models.IdeaDirectionLong && direction != models.IdeaDirectionShort {
+		return nil, fmt.Errorf("%w: direction must be long or short", ErrInvalidIdea)
+	}
+	horizon := input.HorizonDays
+	if horizon == 0 {
+		horizon = DefaultIdeaHorizonDays
+	}
+	if horizon < 1 || horizon > MaxIdeaHorizonDays {
+		return nil, fmt.Errorf("%w: horizon_days must be between 1 and %d", ErrInvalidIdea, MaxIdeaHorizonDays)
+	}
+	if input.TargetPrice < 0 || input.StopLossPrice < 0 {
+		return nil, fmt.Errorf("%w: prices can't be negative", ErrInvalidIdea)
+	}
+
+	entry := currentIdeaPrice(code)
+	if entry <= 0 {
+		return nil, fmt.Errorf("%w: no price data for %s", ErrInvalidIdea, code)
+	}
+	long := direction == models.IdeaDirectionLong
+	gain, loss := "above", "below"
+	if !long {
+		gain, loss = loss, gain
+	}
+	if input.TargetPrice > 0 && (long && input.TargetPrice <= entry || !long && input.TargetPrice >= entry) {
+		return nil, fmt.Errorf("%w: target must be %s the current price %.2f", ErrInvalidIdea, gain, entry)
+	}
+	if input.StopLossPrice > 0 && (long && input.StopLossPrice >= entry || !long && input.StopLossPrice <= entry) {
+		return nil, fmt.Errorf("%w: stop loss must be %s the current price %.2f", ErrInvalidIdea, loss, entry)
+	}
+
+	if input.RuleID > 0 {
+		var count int64
+		db.Model(&models.SignalRule{}).Where("id = ? AND is_active = ? AND (owner_user_id = '' OR owner_user_id IS NULL OR owner_user_id = ?)",
+			input.RuleID, true, authorID).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("%w: rule not found", ErrInvalidIdea)
+		}
+	}
+	if input.TemplateID > 0 {
+		var count int64
+		db.Model(&models.SignalTemplate{}).Where("id = ? AND (visibility = ? OR author_id = ?)",
+			input.TemplateID, models.TemplateVisibilityPublic, authorID).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("%w: template not found", ErrInvalidIdea)
+		}
+	}
+
+	var today int64
+	if err := db.Model(&models.Idea{}).Where("author_id = ? AND created_at > ?", authorID, time.Now().Add(-24*time.Hour)).
+		Count(&today).Error; err != nil {
+		return nil, err
+	}
+	if today >= MaxIdeasPerUserPerDay {
+		return nil, fmt.Errorf("%w: at most %d ideas a day", ErrInvalidIdea, MaxIdeasPerUserPerDay)
+	}
+
+	idea := &models.Idea{
+		AuthorID:      authorID,
+		AuthorName:    authorName,
+		StockCode:     code,
+		Direction:     direction,
+		Title:         title,
+		Body:          body,
+		RuleID:        input.RuleID,
+		TemplateID:    input.TemplateID,
+		EntryPrice:    entry,
+		TargetPrice:   input.TargetPrice,
+		StopLossPrice: input.StopLossPrice,
+		HorizonDays:   horizon,
+		Outcome:       models.IdeaOutcomeOpen,
+		LastPrice:     entry,
+		Status:        models.IdeaStatusPublished,
+	}
+	if err := db.Create(idea).Error; err != nil {
+		return nil, err
+	}
+	return idea, nil
+}
+
+// UpdateIdeaText changes the title and body of an idea; its prices and horizon can't change
+// once posted, so the outcome stays honest
+func UpdateIdeaText(db *gorm.DB, idea *models.Idea, title, body string) error {
+	title, body, err := validateIdeaText(title, body)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(idea).Updates(map[string]interface{}{"title": title, "body": body}).Error; err != nil {
+		return err
+	}
+	idea.Title, idea.Body = title, body
+	return nil
+}
+
+// GetIdea returns a published idea, or a hidden one to its author
+func GetIdea(db *gorm.DB, id uint, viewerID string) (*models.Idea, error) {
+	var idea models.Idea
+	err := db.First(&idea, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrIdeaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if idea.Status != models.IdeaStatusPublished && (viewerID == "" || viewerID != idea.AuthorID) {
+		return nil, ErrIdeaNotFound
+	}
+	return &idea, nil
+}
+
+// ListIdeas returns a page of published ideas, newest first or trending, and the total count
+func ListIdeas(db *gorm.DB, q IdeaFeedQuery) ([]models.Idea, int64, error) {
+	query := db.Model(&models.Idea{}).Where("status = ?", models.IdeaStatusPublished)
+	if q.StockCode != "" {
+		query = query.Where("stock_code = ?", strings.ToUpper(q.StockCode))
+	}
+	if q.Trending {
+		query = query.Where("created_at > ?", time.Now().AddDate(0, 0, -TrendingIdeaWindowDays))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Follows weigh more than likes; the score halves roughly every couple of days
+	if q.Trending {
+		query = query.Order("(like_count + 2 * follow_count) / POWER(EXTRACT(EPOCH FROM (NOW() - created_at)) / 3600 + 2, 1.5) DESC")
+	}
+	query = query.Order("created_at DESC")
+	ideas := []models.Idea{}
+	if err := query.Limit(q.Limit).Offset((q.Page - 1) * q.Limit).Find(&ideas).Error; err != nil {
+		return nil, 0, err
+	}
+	return ideas, total, nil
+}
+
+// ListFollowedIdeas returns the ideas the user follows, newest first
+func ListFollowedIdeas(db *gorm.DB, userID string) ([]models.Idea, error) {
+	ideas := []models.Idea{}
+	err := db.Where("status = ? AND id IN (?)", models.IdeaStatusPublished,
+		db.Model(&models.IdeaFollow{}).Select("idea_id").Where("user_id = ?", userID)).
+		Order("created_at DESC").Find(&ideas).Error
+	return ideas, err
+}
+
+// FillIdeaViewerState marks the ideas the viewer liked or follows
+func FillIdeaViewerState(db *gorm.DB, viewerID string, ideas []models.Idea) {
+	if viewerID == "" || len(ideas) == 0 {
+		return
+	}
+	ids := make([]uint, len(ideas))
+	for i := range ideas {
+		ids[i] = ideas[i].ID
+	}
+	var liked, followed []uint
+	db.Model(&models.IdeaLike{}).Where("user_id = ? AND idea_id IN ?", viewerID, ids).Pluck("idea_id", &liked)
+	db.Model(&models.IdeaFollow{}).Where("user_id = ? AND idea_id IN ?", viewerID, ids).Pluck("idea_id", &followed)
+
+	likedSet := make(map[uint]bool, len(liked))
+	for _, id := range liked {
+		likedSet[id] = true
+	}
+	followedSet := make(map[uint]bool, len(followed))
+	for _, id := range followed {
+		followedSet[id] = true
+	}
+	for i := range ideas {
+		ideas[i].Liked = likedSet[ideas[i].ID]
+		ideas[i].Following = followedSet[ideas[i].ID]
+	}
+}
+
+// setIdeaEngagement adds or removes the user's like or follow row of an idea and keeps the
+// idea's counter in step; counter is like_count or follow_count
+func setIdeaEngagement(db *gorm.DB, idea *models.Idea, row interface{}, userID, counter string, on bool) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var delta int
+		if on {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+			if result.Error != nil {
+				return result.Error
+			}
+			delta = int(result.RowsAffected)
+		} else {
+			result := tx.Where("idea_id = ? AND user_id = ?", idea.ID, userID).Delete(row)
+			if result.Error != nil {
+				return result.Error
+			}
+			delta = -int(result.RowsAffected)
+		}
+		if delta == 0 {
+			return nil
+		}
+		return tx.Model(&models.Idea{}).Where("id = ?", idea.ID).UpdateColumn(counter, gorm.Expr(counter+" + ?", delta)).Error
+	})
+	if err != nil {
+		return err
+	}
+	return db.Select("like_count", "follow_count").First(idea, idea.ID).Error
+}
+
+// LikeIdea adds or removes the user's like of an idea
+func LikeIdea(db *gorm.DB, idea *models.Idea, userID string, like bool) error {
+	return setIdeaEngagement(db, idea, &models.IdeaLike{IdeaID: idea.ID, UserID: userID}, userID, "like_count", like)
+}
+
+// FollowIdea starts or stops the user following an idea's outcome
+func FollowIdea(db *gorm.DB, idea *models.Idea, userID string, follow bool) error {
+	return setIdeaEngagement(db, idea, &models.IdeaFollow{IdeaID: idea.ID, UserID: userID}, userID, "follow_count", follow)
+}
+
+// ReportIdea records the user's report of an idea; the IdeaAutoHideReports-th report hides it
+// until a moderator reviews it. Reporting the same idea again only updates the reason.
+func ReportIdea(db *gorm.DB, idea *models.Idea, userID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > MaxIdeaReportLength {
+		return fmt.Errorf("%w: reason is longer than %d characters", ErrInvalidIdea, MaxIdeaReportLength)
+	}
+	if userID == idea.AuthorID {
+		return fmt.Errorf("%w: you can't report your own idea", ErrInvalidIdea)
+	}
+
+	hidden := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		report := &models.IdeaReport{IdeaID: idea.ID, UserID: userID, Reason: reason}
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "idea_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason"}),
+		}).Create(report)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var count int64
+		if err := tx.Model(&models.IdeaReport{}).Where("idea_id = ?", idea.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"report_count": count}
+		// A moderator who restored the idea after reviewing it decided on these reports already
+		if count >= IdeaAutoHideReports && idea.Status == models.IdeaStatusPublished && idea.ModeratedAt == nil {
+			updates["status"] = models.IdeaStatusHidden
+			updates["moderation_reason"] = fmt.Sprintf("Hidden pending review after %d reports", count)
+			hidden = true
+		}
+		return tx.Model(&models.Idea{}).Where("id = ?", idea.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	if hidden {
+		log.Printf("Idea %d hidden after %d reports", idea.ID, IdeaAutoHideReports)
+		NotifyUser(db, idea.AuthorID, models.NotificationCategoryIdea, "Idea hidden for review",
+			fmt.Sprintf("Your idea %q was reported and is hidden until a moderator reviews it", idea.Title),
+			map[string]interface{}{"idea_id": idea.ID, "status": models.IdeaStatusHidden})
+	}
+	return nil
+}
+
+// ModerateIdea publishes or hides an idea and tells the author
+func ModerateIdea(db *gorm.DB, idea *models.Idea, status, reason, moderator string) error {
+	if status != models.IdeaStatusPublished && status != models.IdeaStatusHidden {
+		return fmt.Errorf("%w: status must be published or hidden", ErrInvalidIdea)
+	}
+	now := time.Now()
+	reason = strings.TrimSpace(reason)
+	if err := db.Model(idea).Updates(map[string]interface{}{
+		"status":            status,
+		"moderation_reason": reason,
+		"moderated_by":      moderator,
+		"moderated_at":      now,
+	}).Error; err != nil {
+		return err
+	}
+	idea.Status, idea.ModerationReason, idea.ModeratedBy, idea.ModeratedAt = status, reason, moderator, &now
+
+	title, message := "Idea restored", fmt.Sprintf("Your idea %q is visible again", idea.Title)
+	if status == models.IdeaStatusHidden {
+		title, message = "Idea hidden by a moderator", fmt.Sprintf("Your idea %q was hidden", idea.Title)
+		if reason != "" {
+			message += ": " + reason
+		}
+	}
+	NotifyUser(db, idea.AuthorID, models.NotificationCategoryIdea, title, message,
+		map[string]interface{}{"idea_id": idea.ID, "status": status})
+	return nil
+}
+
+// DeleteIdea removes an idea with its likes, follows and reports
+func DeleteIdea(db *gorm.DB, idea *models.Idea) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.IdeaLike{}, &models.IdeaFollow{}, &models.IdeaReport{}} {
+			if err := tx.Where("idea_id = ?", idea.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(idea).Error
+	})
+}
+
+// recountIdeaEngagement recomputes the like, follow and report counts of ideas from their rows
+func recountIdeaEngagement(tx *gorm.DB, ideaIDs []uint) error {
+	return tx.Model(&models.Idea{}).Where("id IN ?", ideaIDs).UpdateColumns(map[string]interface{}{
+		"like_count":   gorm.Expr("(SELECT COUNT(*) FROM idea_likes WHERE idea_likes.idea_id = ideas.id)"),
+		"follow_count": gorm.Expr("(SELECT COUNT(*) FROM idea_follows WHERE idea_follows.idea_id = ideas.id)"),
+		"report_count": gorm.Expr("(SELECT COUNT(*) FROM idea_reports WHERE idea_reports.idea_id = ideas.id)"),
+	}).Error
+}
+
+// IdeaStatsForStock summarizes the outcomes of the published ideas on a stock
+func IdeaStatsForStock(db *gorm.DB, code string) (*IdeaOutcomeStats, error) {
+	var rows []struct {
+		Outcome   string
+		Count     int64
+		ReturnSum float64
+	}
+	if err := db.Model(&models.Idea{}).Select("outcome, COUNT(*) AS count, COALESCE(SUM(return_pct), 0) AS return_sum").
+		Where("stock_code = ? AND status = ?", strings.ToUpper(code), models.IdeaStatusPublished).
+		Group("outcome").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &IdeaOutcomeStats{}
+	var closed int64
+	var closedReturn float64
+	for _, row := range rows {
+		stats.Total += row.Count
+		switch row.Outcome {
+		case models.IdeaOutcomeOpen:
+			stats.Open = row.Count
+			continue
+		case models.IdeaOutcomeHitTarget:
+			stats.HitTarget = row.Count
+		case models.IdeaOutcomeStopped:
+			stats.Stopped = row.Count
+		case models.IdeaOutcomeExpired:
+			stats.Expired = row.Count
+		}
+		closed += row.Count
+		closedReturn += row.ReturnSum
+	}
+	if closed > 0 {
+		stats.HitRatePct = math.Round(float64(stats.HitTarget)/float64(closed)*10000) / 100
+		stats.AvgReturnPct = math.Round(closedReturn/float64(closed)*100) / 100
+	}
+	return stats, nil
+}
+
+// ideaReturnPct is the return from entry to price in the idea's direction
+func ideaReturnPct(idea *models.Idea, price float64) float64 {
+	if idea.EntryPrice <= 0 || price <= 0 {
+		return 0
+	}
+	pct := (price - idea.EntryPrice) / idea.EntryPrice * 100
+	if idea.Direction == models.IdeaDirectionShort {
+		pct = -pct
+	}
+	return math.Round(pct*100) / 100
+}
+
+// walkIdeaBars follows an open idea over the daily bars (newest first) after the day it was
+// posted and updates its outcome fields. Returns whether the idea closed.
+func walkIdeaBars(idea *models.Idea, file *StockPriceFile, cal *TradingCalendar, now time.Time) bool {
+	idea.EvaluatedAt = &now
+	if file == nil || len(file.Prices) == 0 {
+		return false
+	}
+
+	posted := idea.CreatedAt.In(cal.Location()).Format(PriceDateFormat)
+	short := idea.Direction == models.IdeaDirectionShort
+	start := sort.Search(len(file.Prices), func(i int) bool { return file.Prices[i].Date <= posted })
+
+	sessions := 0
+	for i := start - 1; i >= 0; i-- {
+		p := file.Prices[i]
+		sessions++
+
+		favorable, adverse := p.High, p.Low
+		if short {
+			favorable, adverse = p.Low, p.High
+		}
+		idea.MaxGainPct = math.Max(idea.MaxGainPct, ideaReturnPct(idea, favorable))
+		idea.MaxDrawdownPct = math.Min(idea.MaxDrawdownPct, ideaReturnPct(idea, adverse))
+		idea.LastPrice = p.Close
+		idea.Sessions = sessions
+
+		// Stop is checked before target so a bar that spans both counts as stopped
+		outcome, exit := "", 0.0
+		switch {
+		case idea.StopLossPrice > 0 && (!short && p.Low > 0 && p.Low <= idea.StopLossPrice || short && p.High >= idea.StopLossPrice):
+			outcome, exit = models.IdeaOutcomeStopped, idea.StopLossPrice
+		case idea.TargetPrice > 0 && (!short && p.High >= idea.TargetPrice || short && p.Low > 0 && p.Low <= idea.TargetPrice):
+			outcome, exit = models.IdeaOutcomeHitTarget, idea.TargetPrice
+		case sessions >= idea.HorizonDays:
+			outcome, exit = models.IdeaOutcomeExpired, p.Close
+		}
+		if outcome != "" {
+			at, _ := time.ParseInLocation(PriceDateFormat, p.Date, cal.Location())
+			idea.Outcome, idea.ExitPrice, idea.ClosedAt = outcome, exit, &at
+			idea.ReturnPct = ideaReturnPct(idea, exit)
+			return true
+		}
+	}
+	idea.ReturnPct = ideaReturnPct(idea, idea.LastPrice)
+	return false
+}
+
+// EvaluateIdeas follows every open idea over the daily bars since it was posted, recording its
+// return so far and closing it as hit_target, stopped or expired. The author and followers of
+// closed ideas are notified. Returns the number of open and closed ideas.
+func EvaluateIdeas(db *gorm.DB) (int, int, error) {
+	if GlobalPriceService == nil {
+		return 0, 0, errors.New("price service not initialized")
+	}
+	var open []models.Idea
+	if err := db.Where("outcome = ?", models.IdeaOutcomeOpen).Order("stock_code").Find(&open).Error; err != nil {
+		return 0, 0, err
+	}
+
+	cal := MarketCalendar()
+	now := time.Now()
+	files := make(map[string]*StockPriceFile)
+	closed := 0
+	for i := range open {
+		idea := &open[i]
+		file, ok := files[idea.StockCode]
+		if !ok {
+			file, _ = GlobalPriceService.LoadStockPrice(idea.StockCode)
+			files[idea.StockCode] = file
+		}
+
+		isClosed := walkIdeaBars(idea, file, cal, now)
+		if err := db.Model(idea).Select("outcome", "last_price", "exit_price", "return_pct", "max_gain_pct",
+			"max_drawdown_pct", "sessions", "evaluated_at", "closed_at").Updates(idea).Error; err != nil {
+			log.Printf("Failed to update idea %d on %s: %v", idea.ID, idea.StockCode, err)
+			continue
+		}
+		if isClosed {
+			closed++
+			notifyIdeaClosed(db, idea)
+		}
+	}
+	return len(open) - closed, closed, nil
+}
+
+// ideaOutcomeLabels describe closed outcomes in notifications
+var ideaOutcomeLabels = map[string]string{
+	models.IdeaOutcomeHitTarget: "reached its target",
+	models.IdeaOutcomeStopped:   "hit its stop loss",
+	models.IdeaOutcomeExpired:   "reached the end of its horizon",
+}
+
+// notifyIdeaClosed tells the author and followers of an idea how it ended
+func notifyIdeaClosed(db *gorm.DB, idea *models.Idea) {
+	var followers []string
+	db.Model(&models.IdeaFollow{}).Where("idea_id = ?", idea.ID).Pluck("user_id", &followers)
+
+	message := fmt.Sprintf("%s %s idea %q %s: %+.2f%%", idea.StockCode, idea.Direction, idea.Title,
+		ideaOutcomeLabels[idea.Outcome], idea.ReturnPct)
+	data := map[string]interface{}{"idea_id": idea.ID, "outcome": idea.Outcome, "return_pct": idea.ReturnPct}
+	NotifyUser(db, idea.AuthorID, models.NotificationCategoryIdea, "Trade idea closed", message, data)
+	for _, userID := range followers {
+		if userID != idea.AuthorID {
+			NotifyUser(db, userID, models.NotificationCategoryIdea, "Trade idea closed", message, data)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordImpersonationRequest appends a request made with an impersonation token to the audit log
+func RecordImpersonationRequest(db *gorm.DB, tokenID, method, path string, status int) {
+	if db == nil {
+		return
+	}
+
+	entry := &models.ImpersonationRequest{TokenID: tokenID, Method: method, Path: path, Status: status}
+	if err := db.Create(entry).Error; err != nil {
+		log.Printf("Failed to audit impersonation request %s %s: %v", method, path, err)
+		return
+	}
+	db.Model(&models.ImpersonationSession{}).Where("token_id = ?", tokenID).UpdateColumns(map[string]interface{}{
+		"request_count":   gorm.Expr("request_count + 1"),
+		"last_request_at": time.Now(),
+	})
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// IndicatorProfilesConfigKey is the system_config key holding the indicator calculation profiles
+const IndicatorProfilesConfigKey = "indicator_profiles"
+
+// DefaultIndicatorProfile is the profile name used when none is configured
+const DefaultIndicatorProfile = "default"
+
+// IndicatorParams are the periods used to calculate indicators. The MA fields keep their
+// historical JSON names (ma_10, ma_30, ...) but hold the configured short, medium, long and
+// trend windows.
+type IndicatorParams struct {
+	RSIPeriod    int `json:"rsi_period"`
+	MACDFast     int `json:"macd_fast"`
+	MACDSlow     int `json:"macd_slow"`
+	MACDSignal   int `json:"macd_signal"`
+	MAShort      int `json:"ma_short"`
+	MAMedium     int `json:"ma_medium"`
+	MALong       int `json:"ma_long"`
+	MATrend      int `json:"ma_trend"`
+	AvgVolPeriod int `json:"avg_vol_period"`
+}
+
+// Validate checks that periods are positive and ordered
+func (p IndicatorParams) Validate() error {
+	for name, v := range map[string]int{
+		"rsi_period":     p.RSIPeriod,
+		"macd_fast":      p.MACDFast,
+		"macd_slow":      p.MACDSlow,
+		"macd_signal":    p.MACDSignal,
+		"ma_short":       p.MAShort,
+		"ma_medium":      p.MAMedium,
+		"ma_long":        p.MALong,
+		"ma_trend":       p.MATrend,
+		"avg_vol_period": p.AvgVolPeriod,
+	} {
+		if v < 1 || v > 500 {
+			return fmt.Errorf("%s must be between 1 and 500", name)
+		}
+	}
+	if p.RSIPeriod < 2 {
+		return fmt.Errorf("rsi_period must be at least 2")
+	}
+	if p.MACDFast >= p.MACDSlow {
+		return fmt.Errorf("macd_fast must be shorter than macd_slow")
+	}
+	if p.MAShort >= p.MAMedium || p.MAMedium >= p.MALong || p.MALong >= p.MATrend {
+		return fmt.Errorf("MA windows must increase: ma_short < ma_medium < ma_long < ma_trend")
+	}
+	return nil
+}
+
+// IndicatorProfileConfig holds named parameter profiles and the one used for calculations
+type IndicatorProfileConfig struct {
+	Active   string                     `json:"active"`
+	Profiles map[string]IndicatorParams `json:"profiles"`
+}
+
+// Validate checks every profile and that the active profile exists
+func (c IndicatorProfileConfig) Validate() error {
+	if _, ok := c.Profiles[c.Active]; !ok {
+		return fmt.Errorf("active profile %q is not defined", c.Active)
+	}
+	for name, p := range c.Profiles {
+		if name == "" {
+			return fmt.Errorf("profile name is required")
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DefaultIndicatorParams are the classic periods: RSI 14, MACD 12/26/9, MA 10/30/50/200, 5-day volume
+func DefaultIndicatorParams() IndicatorParams {
+	return IndicatorParams{
+		RSIPeriod:    14,
+		MACDFast:     12,
+		MACDSlow:     26,
+		MACDSignal:   9,
+		MAShort:      10,
+		MAMedium:     30,
+		MALong:       50,
+		MATrend:      200,
+		AvgVolPeriod: 5,
+	}
+}
+
+// DefaultIndicatorProfileConfig has the single default profile
+func DefaultIndicatorProfileConfig() IndicatorProfileConfig {
+	return IndicatorProfileConfig{
+		Active:   DefaultIndicatorProfile,
+		Profiles: map[string]IndicatorParams{DefaultIndicatorProfile: DefaultIndicatorParams()},
+	}
+}
+
+var (
+	indicatorProfiles   = DefaultIndicatorProfileConfig()
+	indicatorProfilesMu sync.RWMutex
+)
+
+// LoadIndicatorProfiles applies the profiles stored in system_config, keeping defaults when none are stored
+func LoadIndicatorProfiles(db *gorm.DB) error {
+	var cfg IndicatorProfileConfig
+	found, err := LoadSystemConfig(db, IndicatorProfilesConfigKey, &cfg)
+	if err != nil || !found {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	setIndicatorProfiles(cfg)
+	return nil
+}
+
+// SaveIndicatorProfiles validates, stores and applies new indicator profiles. Indicators are
+// recalculated with the new active profile on the next indicator run.
+func SaveIndicatorProfiles(db *gorm.DB, cfg IndicatorProfileConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := SaveSystemConfig(db, IndicatorProfilesConfigKey, cfg); err != nil {
+		return err
+	}
+	setIndicatorProfiles(cfg)
+	return nil
+}
+
+func setIndicatorProfiles(cfg IndicatorProfileConfig) {
+	indicatorProfilesMu.Lock()
+	indicatorProfiles = cfg
+	indicatorProfilesMu.Unlock()
+}
+
+// GetIndicatorProfileConfig returns a copy of the indicator profile configuration
+func GetIndicatorProfileConfig() IndicatorProfileConfig {
+	indicatorProfilesMu.RLock()
+	defer indicatorProfilesMu.RUnlock()
+
+	cfg := IndicatorProfileConfig{Active: indicatorProfiles.Active, Profiles: make(map[string]IndicatorParams, len(indicatorProfiles.Profiles))}
+	for name, p := range indicatorProfiles.Profiles {
+		cfg.Profiles[name] = p
+	}
+	return cfg
+}
+
+// ActiveIndicatorParams returns the active profile name and its parameters
+func ActiveIndicatorParams() (string, IndicatorParams) {
+	indicatorProfilesMu.RLock()
+	defer indicatorProfilesMu.RUnlock()
+
+	if p, ok := indicatorProfiles.Profiles[indicatorProfiles.Active]; ok {
+		return indicatorProfiles.Active, p
+	}
+	return DefaultIndicatorProfile, DefaultIndicatorParams()
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrIndicatorsNotReady is returned while no indicator summary was calculated or restored yet,
+// typically on a fresh instance
+var ErrIndicatorsNotReady = errors.New("indicator summary not found")
+
+// JobTypeIndicatorRecovery restores or recalculates a missing indicator summary
+const JobTypeIndicatorRecovery = "indicator_recovery"
+
+// Indicator recovery states
+const (
+	RecoveryRunning     = "running"     // a recovery, calculation or pipeline run will write the summary
+	RecoveryFailed      = "failed"      // the last recovery failed and the next waits for the cooldown
+	RecoveryUnavailable = "unavailable" // no job manager to run it on, e.g. in cplsctl
+)
+
+// indicatorRecoveryCooldown is how long a failed recovery blocks the next attempt, so clients
+// retrying a 503 do not restart a calculation that cannot succeed
+const indicatorRecoveryCooldown = 10 * time.Minute
+
+// DataNotReady is returned with a 503 while the indicator summary is missing. RetryAfterSeconds
+// is also sent as the Retry-After header.
+type DataNotReady struct {
+	Reason            string  `json:"reason"`
+	Recovery          string  `json:"recovery"`
+	JobID             string  `json:"job_id,omitempty"`
+	Progress          float64 `json:"progress"`
+	Message           string  `json:"message,omitempty"`
+	LastError         string  `json:"last_error,omitempty"`
+	RetryAfterSeconds int     `json:"retry_after_seconds"`
+}
+
+// indicatorRecoveryMu keeps concurrent requests from submitting more than one recovery
+var indicatorRecoveryMu sync.Mutex
+
+// RecoverSummary starts a background recovery of the missing indicator summary unless one is
+// already running or the last one failed recently, and reports its state
+func (s *StockIndicatorService) RecoverSummary() DataNotReady {
+	status := DataNotReady{Reason: "indicators_missing"}
+	if GlobalJobManager == nil {
+		status.Recovery = RecoveryUnavailable
+		status.Message = "Indicators have not been calculated; run the indicator calculation"
+		status.RetryAfterSeconds = int(indicatorRecoveryCooldown.Seconds())
+		return status
+	}
+
+	indicatorRecoveryMu.Lock()
+	defer indicatorRecoveryMu.Unlock()
+
+	for _, jobType := range []string{JobTypeIndicatorRecovery, JobTypeCalculateIndicators, JobTypeDataPipeline} {
+		for _, job := range GlobalJobManager.List(jobType, 0) {
+			if !job.Finished() {
+				return runningRecovery(status, job)
+			}
+		}
+	}
+
+	if last := GlobalJobManager.List(JobTypeIndicatorRecovery, 1); len(last) > 0 && last[0].Status == JobFailed &&
+		last[0].FinishedAt != nil && time.Since(*last[0].FinishedAt) < indicatorRecoveryCooldown {
+		status.Recovery = RecoveryFailed
+		status.JobID = last[0].ID
+		status.LastError = last[0].Error
+		status.Message = "Indicator recovery failed; it is retried after a cooldown"
+		status.RetryAfterSeconds = int(math.Ceil((indicatorRecoveryCooldown - time.Since(*last[0].FinishedAt)).Seconds()))
+		return status
+	}
+
+	job, err := GlobalJobManager.Submit(JobTypeIndicatorRecovery, "system", s.recoverSummary)
+	if err != nil {
+		status.Recovery = RecoveryFailed
+		status.LastError = err.Error()
+		status.RetryAfterSeconds = 60
+		return status
+	}
+	log.Printf("Indicator summary missing, started recovery job %s", job.ID)
+	return runningRecovery(status, job)
+}
+
+// runningRecovery fills status from the job that will write the summary. Retry-After follows the
+// job's ETA, bounded so clients neither poll a long calculation nor wait far past its end.
+func runningRecovery(status DataNotReady, job Job) DataNotReady {
+	status.Recovery = RecoveryRunning
+	status.JobID = job.ID
+	status.Progress = job.Progress
+	status.Message = job.Message
+	if status.Message == "" {
+		status.Message = "Indicators are being prepared"
+	}
+	status.RetryAfterSeconds = 60
+	if job.ETASeconds > 0 {
+		status.RetryAfterSeconds = int(math.Min(math.Max(job.ETASeconds, 15), 600))
+	}
+	return status
+}
+
+// recoverSummary restores the price files from MongoDB Atlas when they are missing locally, then
+// calculates and saves the indicators from them. A summary saved in MongoDB is already picked up
+// by LoadIndicatorSummary, so only the prices are restored.
+func (s *StockIndicatorService) recoverSummary(ctx context.Context, progress JobProgress) (interface{}, error) {
+	if GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+	if !GlobalPriceService.HasLocalPriceData() {
+		if GlobalMongoClient == nil || !GlobalMongoClient.IsConfigured() {
+			return nil, fmt.Errorf("no local price data and MongoDB is not configured; run a price sync")
+		}
+		progress(0, "", "Restoring price data from MongoDB Atlas")
+		if err := GlobalPriceService.RestoreFromMongoDB(); err != nil {
+			return nil, fmt.Errorf("failed to restore price data: %w", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	progress(5, "", "Calculating indicators")
+	err := s.CalculateAndSaveAllIndicatorsContext(ctx, func(done, total int, code string) {
+		progress(5+float64(done)/float64(total)*90, code, fmt.Sprintf("Calculated %d/%d stocks", done, total))
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.LoadIndicatorSummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"stocks": summary.Count, "updated_at": summary.UpdatedAt}, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Instrument types stored in VNDirectStock.Type after classification
+const (
+	InstrumentTypeStock = "stock"
+	InstrumentTypeETF   = "etf"
+	InstrumentTypeCW    = "cw"   // covered warrant
+	InstrumentTypeFund  = "fund" // closed-end fund certificate
+)
+
+var instrumentTypeAliases = map[string]string{
+	"stock":   InstrumentTypeStock,
+	"etf":     InstrumentTypeETF,
+	"cw":      InstrumentTypeCW,
+	"warrant": InstrumentTypeCW,
+	"ifc":     InstrumentTypeFund,
+	"fund":    InstrumentTypeFund,
+}
+
+// ClassifyInstrument returns the instrument type of a stock list entry. A specific type reported
+// by the data source wins; entries reported as plain stocks, or without a type, are classified
+// by their code because the source mixes ETFs and warrants into its stock list.
+func ClassifyInstrument(stock VNDirectStock) string {
+	if t, ok := instrumentTypeAliases[strings.ToLower(strings.TrimSpace(stock.Type))]; ok && t != InstrumentTypeStock {
+		return t
+	}
+	return classifyInstrumentCode(stock.Code)
+}
+
+// classifyInstrumentCode infers the type from HOSE naming: covered warrants are "C" plus the
+// underlying code and a 4 digit series (CHPG2316), ETFs start with E1 or FUE (E1VFVN30, FUESSV50)
+func classifyInstrumentCode(code string) string {
+	code = strings.ToUpper(code)
+	switch {
+	case strings.HasPrefix(code, "E1") || strings.HasPrefix(code, "FUE"):
+		return InstrumentTypeETF
+	case strings.HasPrefix(code, "FUC"):
+		return InstrumentTypeFund
+	case len(code) == 8 && code[0] == 'C' && isDigits(code[4:]):
+		return InstrumentTypeCW
+	}
+	return InstrumentTypeStock
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ClassifyInstruments sets the normalized Type of every stock list entry
+func ClassifyInstruments(stocks []VNDirectStock) {
+	for i := range stocks {
+		stocks[i].Type = ClassifyInstrument(stocks[i])
+	}
+}
+
+// ParseInstrumentTypes parses a comma-separated type filter such as "stock,etf". An empty
+// value means no filter.
+func ParseInstrumentTypes(value string) ([]string, error) {
+	var types []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		t, ok := instrumentTypeAliases[part]
+		if !ok {
+			return nil, fmt.Errorf("invalid instrument type %q (use stock, etf, cw or fund)", part)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// MatchesInstrumentType reports whether an instrument passes a type filter. An empty filter
+// matches everything; an unknown type (e.g. from a summary written before classification) is
+// inferred from the code.
+func MatchesInstrumentType(code, instrumentType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	if instrumentType == "" {
+		instrumentType = InstrumentTypeOf(code)
+	}
+	for _, t := range types {
+		if t == instrumentType {
+			return true
+		}
+	}
+	return false
+}
+
+// instrumentTypeCache maps codes to types and stock list entries, refreshed every StockSearchIndexTTL
+var instrumentTypeCache struct {
+	mu      sync.RWMutex
+	types   map[string]string
+	stocks  map[string]VNDirectStock
+	builtAt time.Time
+}
+
+// InstrumentTypeOf returns the type of a code from the stock list, falling back to the code pattern
+func InstrumentTypeOf(code string) string {
+	code = strings.ToUpper(code)
+	if t, ok := instrumentTypes()[code]; ok {
+		return t
+	}
+	return classifyInstrumentCode(code)
+}
+
+// InstrumentCodes returns the codes in the stock list with one of the given types
+func InstrumentCodes(types []string) []string {
+	var codes []string
+	for code, t := range instrumentTypes() {
+		if MatchesInstrumentType(code, t, types) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// StockListEntry returns the stock list entry of a code (exchange, company name) from the cached list
+func StockListEntry(code string) (VNDirectStock, bool) {
+	_, stocks := stockListCache()
+	stock, ok := stocks[strings.ToUpper(code)]
+	return stock, ok
+}
+
+func instrumentTypes() map[string]string {
+	types, _ := stockListCache()
+	return types
+}
+
+func stockListCache() (map[string]string, map[string]VNDirectStock) {
+	instrumentTypeCache.mu.RLock()
+	if instrumentTypeCache.types != nil && time.Since(instrumentTypeCache.builtAt) < StockSearchIndexTTL {
+		types, stocks := instrumentTypeCache.types, instrumentTypeCache.stocks
+		instrumentTypeCache.mu.RUnlock()
+		return types, stocks
+	}
+	instrumentTypeCache.mu.RUnlock()
+
+	list, err := LoadStocksWithFallback()
+	if err != nil {
+		return map[string]string{}, map[string]VNDirectStock{}
+	}
+	types := make(map[string]string, len(list))
+	stocks := make(map[string]VNDirectStock, len(list))
+	for _, s := range list {
+		code := strings.ToUpper(s.Code)
+		types[code] = ClassifyInstrument(s)
+		stocks[code] = s
+	}
+
+	instrumentTypeCache.mu.Lock()
+	instrumentTypeCache.types = types
+	instrumentTypeCache.stocks = stocks
+	instrumentTypeCache.builtAt = time.Now()
+	instrumentTypeCache.mu.Unlock()
+	return types, stocks
+}
+
+// invalidateInstrumentTypes drops the cached type map after the stock list changes
+func invalidateInstrumentTypes() {
+	instrumentTypeCache.mu.Lock()
+	instrumentTypeCache.types = nil
+	instrumentTypeCache.stocks = nil
+	instrumentTypeCache.mu.Unlock()
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTickRetentionDays is how long intraday ticks are kept unless INTRADAY_TICK_RETENTION_DAYS is set
+const DefaultTickRetentionDays = 10
+
+// TickStore persists intraday ticks captured by the realtime poller
+type TickStore struct {
+	db            *gorm.DB
+	retentionDays int
+
+	mu         sync.Mutex
+	lastVolume map[string]tickState // code -> last observed session volume
+}
+
+type tickState struct {
+	date      string
+	cumVolume float64
+	price     float64
+}
+
+// IntradayPoint is one point of the cumulative intraday series
+type IntradayPoint struct {
+	Time      time.Time `json:"time"`
+	Price     float64   `json:"price"`
+	Volume    float64   `json:"volume"`
+	CumVolume float64   `json:"cum_volume"`
+	VWAP      float64   `json:"vwap"`
+}
+
+// VolumeProfileLevel is the volume traded at one price
+type VolumeProfileLevel struct {
+	Price   float64 `json:"price"`
+	Volume  float64 `json:"volume"`
+	Percent float64 `json:"percent"`
+}
+
+// IntradaySummary is the VWAP and volume profile of a stock for one session
+type IntradaySummary struct {
+	Code          string               `json:"code"`
+	Date          string               `json:"date"`
+	VWAP          float64              `json:"vwap"`
+	TotalVolume   float64              `json:"total_volume"`
+	LastPrice     float64              `json:"last_price"`
+	High          float64              `json:"high"`
+	Low           float64              `json:"low"`
+	Ticks         int                  `json:"ticks"`
+	Series        []IntradayPoint      `json:"series"`
+	VolumeProfile []VolumeProfileLevel `json:"volume_profile"`
+	POC           float64              `json:"poc"` // price with the highest traded volume
+}
+
+// Global tick store
+var GlobalTickStore *TickStore
+
+// InitTickStore initializes the intraday tick store
+func InitTickStore(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	retention := DefaultTickRetentionDays
+	if v, err := strconv.Atoi(os.Getenv("INTRADAY_TICK_RETENTION_DAYS")); err == nil && v > 0 {
+		retention = v
+	}
+
+	GlobalTickStore = &TickStore{
+		db:            db,
+		retentionDays: retention,
+		lastVolume:    make(map[string]tickState),
+	}
+	GlobalTickStore.resume()
+	return nil
+}
+
+// resume seeds the last observed volumes from the ticks stored today, so the first capture after
+// a restart records only the volume matched since then
+func (t *TickStore) resume() {
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	var ticks []models.IntradayTick
+	if err := t.db.Raw(`SELECT DISTINCT ON (code) code, traded_at, price, cum_volume FROM intraday_ticks
+		WHERE traded_at >= ? ORDER BY code, traded_at DESC`, day).Scan(&ticks).Error; err != nil {
+		log.Printf("Failed to resume intraday ticks: %v", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tick := range ticks {
+		t.lastVolume[tick.Code] = tickState{date: day.Format(PriceDateFormat), cumVolume: tick.CumVolume, price: tick.Price}
+	}
+	if len(ticks) > 0 {
+		log.Printf("Resumed intraday ticks of %d stocks", len(ticks))
+	}
+}
+
+// Record stores prices whose session volume or price changed since the last capture.
+// Volume matched before the first capture of the day is attributed to the first captured price.
+func (t *TickStore) Record(prices []RealtimePriceData) {
+	now := time.Now()
+	today := now.Format(PriceDateFormat)
+
+	ticks := make([]models.IntradayTick, 0, len(prices))
+	t.mu.Lock()
+	for _, p := range prices {
+		if p.Price <= 0 {
+			continue
+		}
+
+		last, seen := t.lastVolume[p.Code]
+		if seen && last.date == today && last.cumVolume == p.Volume && last.price == p.Price {
+			continue
+		}
+
+		delta := p.Volume
+		if seen && last.date == today && p.Volume >= last.cumVolume {
+			delta = p.Volume - last.cumVolume
+		}
+		t.lastVolume[p.Code] = tickState{date: today, cumVolume: p.Volume, price: p.Price}
+
+		ticks = append(ticks, models.IntradayTick{
+			Code:       p.Code,
+			TradedAt:   now,
+			Price:      p.Price,
+			CumVolume:  p.Volume,
+			TickVolume: delta,
+		})
+	}
+	t.mu.Unlock()
+
+	if len(ticks) == 0 {
+		return
+	}
+	if err := t.db.CreateInBatches(ticks, 200).Error; err != nil {
+		log.Printf("Failed to store %d intraday ticks: %v", len(ticks), err)
+	}
+}
+
+// RetentionDays returns how many days of ticks are kept
+func (t *TickStore) RetentionDays() int {
+	return t.retentionDays
+}
+
+// GetIntraday computes the VWAP series and volume profile of code for date (YYYY-MM-DD)
+func (t *TickStore) GetIntraday(code, date string) (*IntradaySummary, error) {
+	day, err := time.ParseInLocation(PriceDateFormat, date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", date)
+	}
+
+	var ticks []models.IntradayTick
+	if err := t.db.Where("code = ? AND traded_at >= ? AND traded_at < ?", code, day, day.AddDate(0, 0, 1)).
+		Order("traded_at ASC").Find(&ticks).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &IntradaySummary{
+		Code:          code,
+		Date:          date,
+		Ticks:         len(ticks),
+		Series:        make([]IntradayPoint, 0, len(ticks)),
+		VolumeProfile: []VolumeProfileLevel{},
+	}
+	if len(ticks) == 0 {
+		return summary, nil
+	}
+
+	var cumValue, cumVolume float64
+	profile := make(map[float64]float64)
+	summary.High, summary.Low = ticks[0].Price, ticks[0].Price
+
+	for _, tick := range ticks {
+		cumValue += tick.Price * tick.TickVolume
+		cumVolume += tick.TickVolume
+		profile[tick.Price] += tick.TickVolume
+
+		if tick.Price > summary.High {
+			summary.High = tick.Price
+		}
+		if tick.Price < summary.Low {
+			summary.Low = tick.Price
+		}
+
+		point := IntradayPoint{
+			Time:      tick.TradedAt,
+			Price:     tick.Price,
+			Volume:    tick.TickVolume,
+			CumVolume: cumVolume,
+		}
+		if cumVolume > 0 {
+			point.VWAP = cumValue / cumVolume
+		}
+		summary.Series = append(summary.Series, point)
+	}
+
+	summary.TotalVolume = cumVolume
+	summary.LastPrice = ticks[len(ticks)-1].Price
+	if cumVolume > 0 {
+		summary.VWAP = cumValue / cumVolume
+	}
+
+	var pocVolume float64
+	for price, volume := range profile {
+		level := VolumeProfileLevel{Price: price, Volume: volume}
+		if cumVolume > 0 {
+			level.Percent = volume / cumVolume * 100
+		}
+		summary.VolumeProfile = append(summary.VolumeProfile, level)
+		if volume > pocVolume {
+			pocVolume, summary.POC = volume, price
+		}
+	}
+	sort.Slice(summary.VolumeProfile, func(i, j int) bool {
+		return summary.VolumeProfile[i].Price > summary.VolumeProfile[j].Price
+	})
+
+	return summary, nil
+}
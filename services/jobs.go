@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobStatus is the lifecycle state of a background job
+type JobStatus string
+
+// Job statuses
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job types started from the admin panel
+const (
+	JobTypeBacktest            = "backtest"
+	JobTypeCalculateIndicators = "calculate_indicators"
+	JobTypePriceSync           = "price_sync"
+	JobTypePriceBatch          = "price_batch"
+	JobTypeDataPipeline        = "data_pipeline"
+	JobTypeSnapshotPush        = "snapshot_push"
+	JobTypeWeeklyReports       = "weekly_reports"
+	JobTypeEventSync           = "event_sync"
+	JobTypeSignalSnapshot      = "signal_snapshot"
+	JobTypeRetention           = "retention"
+	JobTypeSeedDemo            = "seed_demo"
+)
+
+// Job queue limits
+const (
+	DefaultJobWorkers = 2
+	jobQueueSize      = 50
+	maxFinishedJobs   = 100 // older finished jobs are forgotten
+)
+
+// ErrJobNotFound is returned for unknown or forgotten job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobFinished is returned when cancelling a job that already ended
+var ErrJobFinished = errors.New("job already finished")
+
+// Job is a snapshot of a background job. Progress goes from 0 to 100; ETASeconds estimates the
+// time left of a running job from its progress so far.
+type Job struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	Status      JobStatus   `json:"status"`
+	Progress    float64     `json:"progress"`
+	CurrentItem string      `json:"current_item,omitempty"`
+	ETASeconds  float64     `json:"eta_seconds,omitempty"`
+	Message     string      `json:"message,omitempty"`
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	CreatedBy   string      `json:"created_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	StartedAt   *time.Time  `json:"started_at,omitempty"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has reached a final status
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobProgress reports how far a running job got, the item it is working on and a progress
+// message. Empty strings keep the previous item and message.
+type JobProgress func(percent float64, currentItem, message string)
+
+// JobFunc is the work of a job. It must return promptly once ctx is cancelled.
+type JobFunc func(ctx context.Context, progress JobProgress) (interface{}, error)
+
+type jobEntry struct {
+	job     Job
+	fn      JobFunc
+	ctx     context.Context
+	cancel  context.CancelFunc
+	changed chan struct{} // closed and replaced on every change, waking up watchers
+}
+
+// snapshot returns the job with its ETA filled in
+func (e *jobEntry) snapshot() Job {
+	job := e.job
+	if job.Status == JobRunning && job.StartedAt != nil && job.Progress > 0 && job.Progress < 100 {
+		elapsed := time.Since(*job.StartedAt).Seconds()
+		job.ETASeconds = math.Round(elapsed * (100 - job.Progress) / job.Progress)
+	}
+	return job
+}
+
+// notifyLocked wakes up the watchers of the entry
+func (e *jobEntry) notifyLocked() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// JobManager runs heavy admin actions in the background on a fixed pool of workers, so the
+// request that starts one returns a job ID immediately. Jobs are kept in memory only.
+type JobManager struct {
+	mu    sync.RWMutex
+	jobs  map[string]*jobEntry
+	queue chan *jobEntry
+}
+
+// GlobalJobManager runs the admin background jobs
+var GlobalJobManager *JobManager
+
+// InitJobManager starts the global job manager
+func InitJobManager(workers int) {
+	GlobalJobManager = NewJobManager(workers)
+	log.Printf("Job manager started with %d workers", workers)
+}
+
+// NewJobManager creates a job manager and starts its workers
+func NewJobManager(workers int) *JobManager {
+	if workers < 1 {
+		workers = DefaultJobWorkers
+	}
+	m := &JobManager{
+		jobs:  make(map[string]*jobEntry),
+		queue: make(chan *jobEntry, jobQueueSize),
+	}
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+// Submit queues a job and returns its snapshot
+func (m *JobManager) Submit(jobType, createdBy string, fn JobFunc) (Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &jobEntry{
+		job: Job{
+			ID:        newJobID(),
+			Type:      jobType,
+			Status:    JobQueued,
+			CreatedBy: createdBy,
+			CreatedAt: time.Now(),
+		},
+		fn:      fn,
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.jobs[entry.job.ID] = entry
+	m.pruneLocked()
+	snapshot := entry.job
+	m.mu.Unlock()
+
+	select {
+	case m.queue <- entry:
+		return snapshot, nil
+	default:
+		m.mu.Lock()
+		delete(m.jobs, entry.job.ID)
+		m.mu.Unlock()
+		cancel()
+		return Job{}, fmt.Errorf("job queue is full, try again later")
+	}
+}
+
+// Get returns the snapshot of a job
+func (m *JobManager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return entry.snapshot(), nil
+}
+
+// Watch returns the snapshot of a job and a channel closed on its next change
+func (m *JobManager) Watch(id string) (Job, <-chan struct{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.jobs[id]
+	if !ok {
+		return Job{}, nil, ErrJobNotFound
+	}
+	return entry.snapshot(), entry.changed, nil
+}
+
+// List returns the jobs of a type, or of all types when jobType is empty, newest first
+func (m *JobManager) List(jobType string, limit int) []Job {
+	m.mu.RLock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, entry := range m.jobs {
+		if jobType == "" || entry.job.Type == jobType {
+			jobs = append(jobs, entry.snapshot())
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs
+}
+
+// Cancel stops a job. A queued job is cancelled at once; a running job is cancelled when its
+// work notices the cancelled context.
+func (m *JobManager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if entry.job.Finished() {
+		return entry.job, ErrJobFinished
+	}
+
+	entry.cancel()
+	if entry.job.Status == JobQueued {
+		m.finishLocked(entry, JobCancelled, nil, "")
+	} else {
+		entry.job.Message = "Cancelling..."
+		entry.notifyLocked()
+	}
+	return entry.snapshot(), nil
+}
+
+func (m *JobManager) worker() {
+	for entry := range m.queue {
+		m.run(entry)
+	}
+}
+
+func (m *JobManager) run(entry *jobEntry) {
+	m.mu.Lock()
+	if entry.job.Status != JobQueued {
+		m.mu.Unlock()
+		return // cancelled while queued
+	}
+	now := time.Now()
+	entry.job.Status = JobRunning
+	entry.job.StartedAt = &now
+	entry.notifyLocked()
+	m.mu.Unlock()
+
+	progress := func(percent float64, currentItem, message string) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if entry.job.Status != JobRunning {
+			return
+		}
+		if percent > entry.job.Progress {
+			entry.job.Progress = min(percent, 100)
+		}
+		if currentItem != "" {
+			entry.job.CurrentItem = currentItem
+		}
+		if message != "" {
+			entry.job.Message = message
+		}
+		entry.notifyLocked()
+	}
+
+	result, err := m.execute(entry, progress)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case entry.ctx.Err() != nil:
+		m.finishLocked(entry, JobCancelled, nil, "")
+	case err != nil:
+		log.Printf("Job %s (%s) failed: %v", entry.job.ID, entry.job.Type, err)
+		m.finishLocked(entry, JobFailed, nil, err.Error())
+	default:
+		entry.job.Progress = 100
+		m.finishLocked(entry, JobSucceeded, result, "")
+	}
+}
+
+// execute runs the job's work, turning a panic into a failure so a worker never dies
+func (m *JobManager) execute(entry *jobEntry, progress JobProgress) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return entry.fn(entry.ctx, progress)
+}
+
+func (m *JobManager) finishLocked(entry *jobEntry, status JobStatus, result interface{}, errMsg string) {
+	now := time.Now()
+	entry.job.Status = status
+	entry.job.Result = result
+	entry.job.Error = errMsg
+	entry.job.FinishedAt = &now
+	if status == JobCancelled {
+		entry.job.Message = "Cancelled"
+	}
+	entry.cancel()
+	entry.notifyLocked()
+}
+
+// pruneLocked forgets the oldest finished jobs beyond maxFinishedJobs
+func (m *JobManager) pruneLocked() {
+	var finished []*jobEntry
+	for _, entry := range m.jobs {
+		if entry.job.Finished() {
+			finished = append(finished, entry)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].job.FinishedAt.Before(*finished[j].job.FinishedAt) })
+	for _, entry := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, entry.job.ID)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
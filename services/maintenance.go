@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaintenanceConfigKey is the system_config key holding maintenance mode and the kill switches
+const MaintenanceConfigKey = "maintenance"
+
+// Subsystems an admin can switch off on their own
+const (
+	SubsystemRealtime = "realtime"          // price polling and the realtime WebSocket streams
+	SubsystemSignals  = "signal_generation" // live signal generation, snapshot publishing and rule signal emission
+)
+
+// Subsystems lists the subsystems with a kill switch
+var Subsystems = []string{SubsystemRealtime, SubsystemSignals}
+
+// ErrSubsystemDisabled is returned by work refused because an admin switched its subsystem off
+var ErrSubsystemDisabled = errors.New("disabled by an administrator")
+
+// maintenanceRefreshInterval is how often the stored config is re-read, so that every instance
+// picks up a change made through another one
+const maintenanceRefreshInterval = 30 * time.Second
+
+// MaintenanceConfig is the maintenance mode of the public API and the kill switches
+type MaintenanceConfig struct {
+	Enabled   bool            `json:"enabled"`
+	Message   string          `json:"message"`         // shown to API clients while in maintenance
+	Until     *time.Time      `json:"until,omitempty"` // expected end, announced as Retry-After
+	Disabled  map[string]bool `json:"disabled"`        // subsystems switched off
+	UpdatedBy string          `json:"updated_by,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// DefaultMaintenanceMessage is shown when maintenance is enabled without a message
+const DefaultMaintenanceMessage = "The service is under maintenance, please try again shortly"
+
+// Validate checks that the switched subsystems are known
+func (m MaintenanceConfig) Validate() error {
+	for name := range m.Disabled {
+		known := false
+		for _, s := range Subsystems {
+			known = known || s == name
+		}
+		if !known {
+			return fmt.Errorf("unknown subsystem %q: use %s or %s", name, SubsystemRealtime, SubsystemSignals)
+		}
+	}
+	if len(m.Message) > 500 {
+		return fmt.Errorf("message must be at most 500 characters")
+	}
+	return nil
+}
+
+// RetryAfter returns how long until the announced end of the maintenance, zero when none is set
+// or it has passed
+func (m MaintenanceConfig) RetryAfter(now time.Time) time.Duration {
+	if m.Until == nil || !m.Until.After(now) {
+		return 0
+	}
+	return m.Until.Sub(now)
+}
+
+var (
+	maintenance       = MaintenanceConfig{Disabled: map[string]bool{}}
+	maintenanceDB     *gorm.DB
+	maintenanceLoaded time.Time
+	maintenanceMu     sync.RWMutex
+)
+
+// LoadMaintenance applies the maintenance config stored in system_config and keeps db to re-read
+// it periodically
+func LoadMaintenance(db *gorm.DB) error {
+	maintenanceMu.Lock()
+	maintenanceDB = db
+	maintenanceMu.Unlock()
+	return refreshMaintenance()
+}
+
+// SaveMaintenance validates, stores and applies a new maintenance config. Switching realtime off
+// stops the price polling of this instance at once; other instances stop on their next refresh.
+func SaveMaintenance(db *gorm.DB, cfg MaintenanceConfig) error {
+	if cfg.Disabled == nil {
+		cfg.Disabled = map[string]bool{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Enabled && cfg.Message == "" {
+		cfg.Message = DefaultMaintenanceMessage
+	}
+	cfg.UpdatedAt = time.Now()
+	if err := SaveSystemConfig(db, MaintenanceConfigKey, cfg); err != nil {
+		return err
+	}
+	setMaintenance(cfg)
+	log.Printf("Maintenance mode %v, disabled subsystems %v (by %s)", cfg.Enabled, cfg.Disabled, cfg.UpdatedBy)
+	return nil
+}
+
+// CurrentMaintenance returns the maintenance config, re-reading the stored one when it is older
+// than the refresh interval
+func CurrentMaintenance() MaintenanceConfig {
+	maintenanceMu.RLock()
+	cfg, stale := maintenance, maintenanceDB != nil && time.Since(maintenanceLoaded) > maintenanceRefreshInterval
+	maintenanceMu.RUnlock()
+	if stale {
+		if err := refreshMaintenance(); err != nil {
+			log.Printf("Warning: failed to refresh maintenance config: %v", err)
+		}
+		maintenanceMu.RLock()
+		cfg = maintenance
+		maintenanceMu.RUnlock()
+	}
+	return cfg
+}
+
+// SubsystemEnabled reports whether a subsystem runs, i.e. its kill switch is off
+func SubsystemEnabled(name string) bool {
+	return !CurrentMaintenance().Disabled[name]
+}
+
+func refreshMaintenance() error {
+	maintenanceMu.Lock()
+	db := maintenanceDB
+	// Stamp first so concurrent readers don't all hit the database while one refreshes
+	maintenanceLoaded = time.Now()
+	maintenanceMu.Unlock()
+
+	var cfg MaintenanceConfig
+	found, err := LoadSystemConfig(db, MaintenanceConfigKey, &cfg)
+	if err != nil || !found {
+		return err
+	}
+	if cfg.Disabled == nil {
+		cfg.Disabled = map[string]bool{}
+	}
+	setMaintenance(cfg)
+	return nil
+}
+
+func setMaintenance(cfg MaintenanceConfig) {
+	maintenanceMu.Lock()
+	maintenance = cfg
+	maintenanceLoaded = time.Now()
+	maintenanceMu.Unlock()
+
+	if cfg.Disabled[SubsystemRealtime] && GlobalRealtimeService != nil && GlobalRealtimeService.IsPolling() {
+		GlobalRealtimeService.StopPolling()
+	}
+}
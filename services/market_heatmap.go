@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// HeatmapCacheTTL is how long a built heatmap is served before it is rebuilt
+const HeatmapCacheTTL = time.Minute
+
+// UnclassifiedSector groups stocks without a sector or industry
+const UnclassifiedSector = "Other"
+
+// HeatmapTile is one stock in the market map, sized by market cap and colored by daily change
+type HeatmapTile struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Price         float64 `json:"price"`
+	ChangePercent float64 `json:"change_percent"`
+	MarketCap     float64 `json:"market_cap"`
+	Weight        float64 `json:"weight"` // % of the sector market cap
+	RSAvg         float64 `json:"rs_avg"`
+}
+
+// HeatmapSector aggregates the tiles of one sector
+type HeatmapSector struct {
+	Sector        string        `json:"sector"`
+	MarketCap     float64       `json:"market_cap"`
+	Weight        float64       `json:"weight"`         // % of the total market cap
+	ChangePercent float64       `json:"change_percent"` // market cap weighted
+	AvgRS         float64       `json:"avg_rs"`         // mean RSAvg of the ranked stocks
+	Advancers     int           `json:"advancers"`
+	Decliners     int           `json:"decliners"`
+	Tiles         []HeatmapTile `json:"tiles"`
+}
+
+// MarketHeatmap is the sector-grouped market map
+type MarketHeatmap struct {
+	Sectors        []HeatmapSector `json:"sectors"`
+	TotalMarketCap float64         `json:"total_market_cap"`
+	ChangePercent  float64         `json:"change_percent"` // market cap weighted, all sectors
+	Stocks         int             `json:"stocks"`
+	PriceSource    string          `json:"price_source"` // realtime while polling, otherwise daily
+	GeneratedAt    time.Time       `json:"generated_at"`
+}
+
+// MarketHeatmapService caches the market heatmap for HeatmapCacheTTL
+type MarketHeatmapService struct {
+	mu      sync.Mutex
+	current *MarketHeatmap
+}
+
+// Global market heatmap service
+var GlobalMarketHeatmap = &MarketHeatmapService{}
+
+// Current returns the cached heatmap, rebuilding it when the cache has expired
+func (s *MarketHeatmapService) Current(db *gorm.DB) (*MarketHeatmap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && time.Since(s.current.GeneratedAt) < HeatmapCacheTTL {
+		return s.current, nil
+	}
+
+	heatmap, err := BuildMarketHeatmap(db)
+	if err != nil {
+		return nil, err
+	}
+	s.current = heatmap
+	return heatmap, nil
+}
+
+// BuildMarketHeatmap groups listed stocks with a market cap by sector (falling back to industry).
+// Daily change and RS come from the indicator summary; while realtime polling runs, the change
+// of polled stocks is taken from the latest realtime price instead.
+func BuildMarketHeatmap(db *gorm.DB) (*MarketHeatmap, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if GlobalIndicatorService == nil {
+		return nil, fmt.Errorf("indicator service not initialized")
+	}
+	summary, err := GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	var stocks []models.Stock
+	if err := db.Select("symbol, name, sector, industry, market_cap, status").
+		Where("market_cap > 0 AND (status IS NULL OR status <> ?)", "delisted").
+		Find(&stocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load stocks: %w", err)
+	}
+
+	realtime := GlobalRealtimeService != nil && GlobalRealtimeService.IsPolling()
+	heatmap := &MarketHeatmap{Sectors: []HeatmapSector{}, PriceSource: "daily", GeneratedAt: time.Now()}
+	if realtime {
+		heatmap.PriceSource = "realtime"
+	}
+
+	type sectorAcc struct {
+		sector   *HeatmapSector
+		weighted float64
+		rsSum    float64
+		rsCount  int
+	}
+	sectors := make(map[string]*sectorAcc)
+	var totalWeighted float64
+
+	for _, st := range stocks {
+		code := strings.ToUpper(st.Symbol)
+		ind := summary.Stocks[code]
+		if ind == nil || IsStockInactive(code) || InstrumentTypeOf(code) != InstrumentTypeStock {
+			continue
+		}
+
+		tile := HeatmapTile{
+			Code:          code,
+			Name:          st.Name,
+			Price:         ind.CurrentPrice,
+			ChangePercent: ind.PriceChange,
+			MarketCap:     st.MarketCap.InexactFloat64(),
+			RSAvg:         ind.RSAvg,
+		}
+		if realtime {
+			if p := GlobalRealtimeService.LatestPrice(code); p != nil && p.Price > 0 {
+				tile.Price = p.Price
+				tile.ChangePercent = p.ChangePercent
+			}
+		}
+
+		name := strings.TrimSpace(st.Sector)
+		if name == "" {
+			name = strings.TrimSpace(st.Industry)
+		}
+		if name == "" {
+			name = UnclassifiedSector
+		}
+		acc, ok := sectors[name]
+		if !ok {
+			acc = &sectorAcc{sector: &HeatmapSector{Sector: name, Tiles: []HeatmapTile{}}}
+			sectors[name] = acc
+		}
+
+		acc.sector.Tiles = append(acc.sector.Tiles, tile)
+		acc.sector.MarketCap += tile.MarketCap
+		acc.weighted += tile.ChangePercent * tile.MarketCap
+		switch {
+		case tile.ChangePercent > 0:
+			acc.sector.Advancers++
+		case tile.ChangePercent < 0:
+			acc.sector.Decliners++
+		}
+		// Stocks below the RS liquidity threshold have no rank and would drag the average to 0
+		if tile.RSAvg > 0 {
+			acc.rsSum += tile.RSAvg
+			acc.rsCount++
+		}
+
+		heatmap.TotalMarketCap += tile.MarketCap
+		totalWeighted += tile.ChangePercent * tile.MarketCap
+		heatmap.Stocks++
+	}
+
+	for _, acc := range sectors {
+		sector := acc.sector
+		sector.ChangePercent = roundHeatmap(acc.weighted / sector.MarketCap)
+		if acc.rsCount > 0 {
+			sector.AvgRS = roundHeatmap(acc.rsSum / float64(acc.rsCount))
+		}
+		if heatmap.TotalMarketCap > 0 {
+			sector.Weight = roundHeatmap(sector.MarketCap / heatmap.TotalMarketCap * 100)
+		}
+		for i := range sector.Tiles {
+			sector.Tiles[i].Weight = roundHeatmap(sector.Tiles[i].MarketCap / sector.MarketCap * 100)
+		}
+		sort.Slice(sector.Tiles, func(i, j int) bool { return sector.Tiles[i].MarketCap > sector.Tiles[j].MarketCap })
+		heatmap.Sectors = append(heatmap.Sectors, *sector)
+	}
+	sort.Slice(heatmap.Sectors, func(i, j int) bool { return heatmap.Sectors[i].MarketCap > heatmap.Sectors[j].MarketCap })
+
+	if heatmap.TotalMarketCap > 0 {
+		heatmap.ChangePercent = roundHeatmap(totalWeighted / heatmap.TotalMarketCap)
+	}
+	return heatmap, nil
+}
+
+func roundHeatmap(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Market mover list types
+const (
+	MoversGainers = "gainers"
+	MoversLosers  = "losers"
+	MoversVolume  = "volume"
+)
+
+// MarketMover is one stock in a top movers list
+type MarketMover struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Exchange      string  `json:"exchange"`
+	Type          string  `json:"type"`
+	Price         float64 `json:"price"`
+	ChangePercent float64 `json:"change_percent"`
+	Volume        float64 `json:"volume"`
+	AvgVol        float64 `json:"avg_vol"`
+	VolRatio      float64 `json:"vol_ratio"`
+	AvgTradingVal float64 `json:"avg_trading_val"` // billion VND, 5-day average
+	RSAvg         float64 `json:"rs_avg"`
+}
+
+// MarketMoverFilter selects and orders a top movers list
+type MarketMoverFilter struct {
+	Type            string   // gainers, losers or volume
+	Exchange        string   // HOSE, HNX, UPCOM; empty for all
+	MinTradingVal   float64  // minimum 5-day average trading value, billion VND
+	InstrumentTypes []string // empty for all types
+}
+
+// ParseMoversType validates a movers list type, defaulting to gainers
+func ParseMoversType(value string) (string, error) {
+	switch t := strings.ToLower(strings.TrimSpace(value)); t {
+	case "":
+		return MoversGainers, nil
+	case MoversGainers, MoversLosers, MoversVolume:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid movers type %q (use gainers, losers or volume)", value)
+	}
+}
+
+// MarketMovers returns the stocks of the latest indicator summary matching the filter, ordered
+// by daily change (gainers and losers) or session volume. Stocks that did not move are not
+// gainers or losers. The summary update time is returned with the list.
+func MarketMovers(filter MarketMoverFilter) ([]MarketMover, string, error) {
+	if GlobalIndicatorService == nil {
+		return nil, "", fmt.Errorf("indicator service not initialized")
+	}
+	summary, err := GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return nil, "", err
+	}
+
+	movers := make([]MarketMover, 0, len(summary.Stocks))
+	for code, ind := range summary.Stocks {
+		if ind == nil || code == MarketIndexCode || IsStockInactive(code) {
+			continue
+		}
+		if ind.AvgTradingVal < filter.MinTradingVal {
+			continue
+		}
+		if filter.Type == MoversGainers && ind.PriceChange <= 0 || filter.Type == MoversLosers && ind.PriceChange >= 0 {
+			continue
+		}
+		if !MatchesInstrumentType(code, ind.Type, filter.InstrumentTypes) {
+			continue
+		}
+
+		mover := MarketMover{
+			Code:          code,
+			Type:          ind.Type,
+			Price:         ind.CurrentPrice,
+			ChangePercent: ind.PriceChange,
+			Volume:        ind.Volume,
+			AvgVol:        ind.AvgVol,
+			VolRatio:      ind.VolRatio,
+			AvgTradingVal: ind.AvgTradingVal,
+			RSAvg:         ind.RSAvg,
+		}
+		if mover.Type == "" {
+			mover.Type = InstrumentTypeOf(code)
+		}
+		if stock, ok := StockListEntry(code); ok {
+			mover.Exchange = stock.Floor
+			mover.Name = stock.ShortName
+			if mover.Name == "" {
+				mover.Name = stock.CompanyName
+			}
+		}
+		if filter.Exchange != "" && !strings.EqualFold(mover.Exchange, filter.Exchange) {
+			continue
+		}
+		movers = append(movers, mover)
+	}
+
+	sort.Slice(movers, func(i, j int) bool {
+		a, b := movers[i], movers[j]
+		switch filter.Type {
+		case MoversLosers:
+			if a.ChangePercent != b.ChangePercent {
+				return a.ChangePercent < b.ChangePercent
+			}
+		case MoversVolume:
+			if a.Volume != b.Volume {
+				return a.Volume > b.Volume
+			}
+		default:
+			if a.ChangePercent != b.ChangePercent {
+				return a.ChangePercent > b.ChangePercent
+			}
+		}
+		return a.Code < b.Code
+	})
+	return movers, summary.UpdatedAt, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Market regimes
+const (
+	RegimeBull     = "bull"
+	RegimeBear     = "bear"
+	RegimeSideways = "sideways"
+)
+
+// MarketIndexCode is the benchmark index used for regime detection
+const MarketIndexCode = "VNINDEX"
+
+// Regime classification thresholds
+const (
+	RegimeBullBreadth   = 55.0 // % of stocks above MA50 required for a bull regime
+	RegimeBearBreadth   = 40.0 // % of stocks above MA50 at or below which the regime is bear
+	RegimeSlopeLookback = 20   // trading days used for the index MA50 slope
+	RegimeCacheTTL      = 30 * time.Minute
+)
+
+// MarketBreadth summarizes participation across the stock universe
+type MarketBreadth struct {
+	Stocks        int     `json:"stocks"`
+	AboveMA50Pct  float64 `json:"above_ma50_pct"`
+	AboveMA200Pct float64 `json:"above_ma200_pct"`
+	Advancers     int     `json:"advancers"`
+	Decliners     int     `json:"decliners"`
+	AdvDeclRatio  float64 `json:"adv_decl_ratio"`
+}
+
+// IndexTrend describes the benchmark index relative to its moving averages
+type IndexTrend struct {
+	Code       string  `json:"code"`
+	Date       string  `json:"date"`
+	Close      float64 `json:"close"`
+	MA50       float64 `json:"ma_50"`
+	MA200      float64 `json:"ma_200"`
+	AboveMA200 bool    `json:"above_ma200"`
+	MA50Slope  float64 `json:"ma50_slope_pct"` // % change of MA50 over RegimeSlopeLookback days
+}
+
+// MarketRegime is the classified market phase with the inputs it was derived from
+type MarketRegime struct {
+	Regime     string        `json:"regime"`
+	Score      int           `json:"score"` // -3 (strong bear) .. +3 (strong bull)
+	Reasons    []string      `json:"reasons"`
+	Index      *IndexTrend   `json:"index,omitempty"`
+	Breadth    MarketBreadth `json:"breadth"`
+	DetectedAt time.Time     `json:"detected_at"`
+}
+
+// MarketRegimeService classifies the market regime from index trend and breadth
+type MarketRegimeService struct {
+	mu      sync.Mutex
+	current *MarketRegime
+}
+
+// Global market regime service
+var GlobalMarketRegime = &MarketRegimeService{}
+
+// Current returns the cached regime, re-detecting it when the cache has expired
+func (s *MarketRegimeService) Current() (*MarketRegime, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && time.Since(s.current.DetectedAt) < RegimeCacheTTL {
+		return s.current, nil
+	}
+
+	regime, err := DetectMarketRegime()
+	if err != nil {
+		if s.current != nil {
+			// Keep serving the last known regime when inputs are temporarily unavailable
+			return s.current, nil
+		}
+		return nil, err
+	}
+	s.current = regime
+	return regime, nil
+}
+
+// CurrentName returns the current regime name, or "" when it cannot be determined
+func (s *MarketRegimeService) CurrentName() string {
+	regime, err := s.Current()
+	if err != nil {
+		return ""
+	}
+	return regime.Regime
+}
+
+// Refresh drops the cached regime and detects it again
+func (s *MarketRegimeService) Refresh() (*MarketRegime, error) {
+	s.mu.Lock()
+	s.current = nil
+	s.mu.Unlock()
+	return s.Current()
+}
+
+// DetectMarketRegime computes breadth from the indicator summary and the index trend from
+// VN-Index prices. Without index data the regime is classified from breadth alone.
+func DetectMarketRegime() (*MarketRegime, error) {
+	if GlobalIndicatorService == nil {
+		return nil, fmt.Errorf("indicator service not initialized")
+	}
+	summary, err := GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	regime := &MarketRegime{
+		Breadth:    calculateBreadth(summary.Stocks),
+		Reasons:    []string{},
+		DetectedAt: time.Now(),
+	}
+	if regime.Breadth.Stocks == 0 {
+		return nil, fmt.Errorf("no indicator data for breadth")
+	}
+
+	index, err := loadIndexTrend()
+	if err != nil {
+		log.Printf("Market regime: %s trend unavailable, using breadth only: %v", MarketIndexCode, err)
+	} else {
+		regime.Index = index
+	}
+
+	classifyRegime(regime)
+	return regime, nil
+}
+
+func calculateBreadth(stocks map[string]*ExtendedStockIndicators) MarketBreadth {
+	var b MarketBreadth
+	var above50, above200, with200 int
+	for _, ind := range stocks {
+		if ind == nil || ind.CurrentPrice <= 0 || ind.MA50 <= 0 {
+			continue
+		}
+		b.Stocks++
+		if ind.CurrentPrice > ind.MA50 {
+			above50++
+		}
+		if ind.MA200 > 0 {
+			with200++
+			if ind.CurrentPrice > ind.MA200 {
+				above200++
+			}
+		}
+		if ind.PriceChange > 0 {
+			b.Advancers++
+		} else if ind.PriceChange < 0 {
+			b.Decliners++
+		}
+	}
+
+	if b.Stocks > 0 {
+		b.AboveMA50Pct = math.Round(float64(above50)/float64(b.Stocks)*10000) / 100
+	}
+	if with200 > 0 {
+		b.AboveMA200Pct = math.Round(float64(above200)/float64(with200)*10000) / 100
+	}
+	if b.Decliners > 0 {
+		b.AdvDeclRatio = math.Round(float64(b.Advancers)/float64(b.Decliners)*100) / 100
+	} else if b.Advancers > 0 {
+		b.AdvDeclRatio = float64(b.Advancers)
+	}
+	return b
+}
+
+// loadIndexTrend reads VN-Index prices from the local price store, fetching them when absent
+func loadIndexTrend() (*IndexTrend, error) {
+	if GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+
+	var prices []StockPriceData
+	if file, err := GlobalPriceService.LoadStockPrice(MarketIndexCode); err == nil {
+		prices = file.Prices
+	}
+	if len(prices) < 200 {
+		resp, err := GlobalPriceService.FetchStockPrice(MarketIndexCode, DefaultPriceSize)
+		if err != nil {
+			return nil, err
+		}
+		prices = resp.Data
+	}
+	if len(prices) < 200+RegimeSlopeLookback {
+		return nil, fmt.Errorf("insufficient %s history: %d days", MarketIndexCode, len(prices))
+	}
+
+	// Prices are stored newest first; moving averages expect oldest first
+	closes := make([]float64, len(prices))
+	for i, p := range prices {
+		closes[len(prices)-1-i] = p.Close
+	}
+
+	trend := &IndexTrend{
+		Code:  MarketIndexCode,
+		Date:  prices[0].Date,
+		Close: prices[0].Close,
+		MA50:  CalculateMA(closes, 50),
+		MA200: CalculateMA(closes, 200),
+	}
+	trend.AboveMA200 = trend.Close > trend.MA200
+
+	prevMA50 := CalculateMA(closes[:len(closes)-RegimeSlopeLookback], 50)
+	if prevMA50 > 0 {
+		trend.MA50Slope = math.Round((trend.MA50-prevMA50)/prevMA50*10000) / 100
+	}
+	return trend, nil
+}
+
+// classifyRegime scores index trend and breadth; each signal adds or subtracts one point
+func classifyRegime(r *MarketRegime) {
+	score := 0
+
+	if idx := r.Index; idx != nil {
+		if idx.AboveMA200 {
+			score++
+			r.Reasons = append(r.Reasons, fmt.Sprintf("%s above MA200 (%.2f > %.2f)", idx.Code, idx.Close, idx.MA200))
+		} else {
+			score--
+			r.Reasons = append(r.Reasons, fmt.Sprintf("%s below MA200 (%.2f < %.2f)", idx.Code, idx.Close, idx.MA200))
+		}
+		if idx.MA50Slope > 0 {
+			score++
+			r.Reasons = append(r.Reasons, fmt.Sprintf("%s MA50 rising (%.2f%%)", idx.Code, idx.MA50Slope))
+		} else if idx.MA50Slope < 0 {
+			score--
+			r.Reasons = append(r.Reasons, fmt.Sprintf("%s MA50 falling (%.2f%%)", idx.Code, idx.MA50Slope))
+		}
+	}
+
+	b := r.Breadth
+	switch {
+	case b.AboveMA50Pct >= RegimeBullBreadth:
+		score++
+		r.Reasons = append(r.Reasons, fmt.Sprintf("Broad participation: %.1f%% of stocks above MA50", b.AboveMA50Pct))
+	case b.AboveMA50Pct <= RegimeBearBreadth:
+		score--
+		r.Reasons = append(r.Reasons, fmt.Sprintf("Weak breadth: %.1f%% of stocks above MA50", b.AboveMA50Pct))
+	default:
+		r.Reasons = append(r.Reasons, fmt.Sprintf("Mixed breadth: %.1f%% of stocks above MA50", b.AboveMA50Pct))
+	}
+
+	// Breadth alone is a single vote; require it to agree with the MA200 split when available
+	if r.Index == nil {
+		if b.AboveMA200Pct >= RegimeBullBreadth {
+			score++
+		} else if b.AboveMA200Pct > 0 && b.AboveMA200Pct <= RegimeBearBreadth {
+			score--
+		}
+	}
+
+	r.Score = score
+	switch {
+	case score >= 2:
+		r.Regime = RegimeBull
+	case score <= -2:
+		r.Regime = RegimeBear
+	default:
+		r.Regime = RegimeSideways
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MarketStatsCacheTTL is how long built market statistics are served before they are rebuilt
+const MarketStatsCacheTTL = time.Minute
+
+// IndicatorDistribution summarizes the values of one indicator across the stock universe
+type IndicatorDistribution struct {
+	Min    float64 `json:"min"`
+	P10    float64 `json:"p10"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// MarketStats is the distribution of key indicators across all listed stocks, used to place an
+// individual stock relative to the market
+type MarketStats struct {
+	Stocks              int                   `json:"stocks"`
+	RSI                 IndicatorDistribution `json:"rsi"`
+	RSAvg               IndicatorDistribution `json:"rs_avg"`
+	RSDeciles           []float64             `json:"rs_deciles"` // RSAvg boundaries between deciles 1-2 ... 9-10
+	RankedStocks        int                   `json:"ranked_stocks"`
+	PriceChange         IndicatorDistribution `json:"price_change"`
+	VolRatio            IndicatorDistribution `json:"vol_ratio"`
+	PctMACDHistPositive float64               `json:"pct_macd_hist_positive"`
+	PctAboveMA50        float64               `json:"pct_above_ma50"`
+	PctAboveMA200       float64               `json:"pct_above_ma200"`
+	PctMA50AboveMA200   float64               `json:"pct_ma50_above_ma200"`
+	DataAsOf            string                `json:"data_as_of"`
+	GeneratedAt         time.Time             `json:"generated_at"`
+
+	// Sorted samples, kept for percentile lookups of individual stocks
+	rsi, rsAvg, priceChange, volRatio []float64
+}
+
+// StockStatsPosition places one stock within the market distributions, as the percentage of
+// the universe with a lower value
+type StockStatsPosition struct {
+	Code                  string  `json:"code"`
+	RSI                   float64 `json:"rsi"`
+	RSIPercentile         float64 `json:"rsi_percentile"`
+	RSAvg                 float64 `json:"rs_avg"`
+	RSDecile              int     `json:"rs_decile,omitempty"` // 1 (weakest) to 10; 0 when not ranked
+	PriceChange           float64 `json:"price_change"`
+	PriceChangePercentile float64 `json:"price_change_percentile"`
+	VolRatio              float64 `json:"vol_ratio"`
+	VolRatioPercentile    float64 `json:"vol_ratio_percentile"`
+	MACDHistPositive      bool    `json:"macd_hist_positive"`
+	AboveMA50             bool    `json:"above_ma50"`
+	AboveMA200            bool    `json:"above_ma200"`
+}
+
+// MarketStatsService caches the market statistics for MarketStatsCacheTTL
+type MarketStatsService struct {
+	mu      sync.Mutex
+	current *MarketStats
+}
+
+// Global market statistics service
+var GlobalMarketStats = &MarketStatsService{}
+
+// Current returns the cached statistics, rebuilding them when the cache has expired
+func (s *MarketStatsService) Current() (*MarketStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && time.Since(s.current.GeneratedAt) < MarketStatsCacheTTL {
+		return s.current, nil
+	}
+
+	stats, err := BuildMarketStats()
+	if err != nil {
+		return nil, err
+	}
+	s.current = stats
+	return stats, nil
+}
+
+// BuildMarketStats computes the indicator distributions from the indicator summary. The universe
+// is the active listed stocks; the market index, ETFs and covered warrants are left out.
+func BuildMarketStats() (*MarketStats, error) {
+	if GlobalIndicatorService == nil {
+		return nil, fmt.Errorf("indicator service not initialized")
+	}
+	summary, err := GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &MarketStats{DataAsOf: summary.UpdatedAt, RSDeciles: []float64{}, GeneratedAt: time.Now()}
+	var macdPositive, aboveMA50, aboveMA200, ma50AboveMA200, withMA50, withMA200 int
+
+	for code, ind := range summary.Stocks {
+		if ind == nil || IsStockInactive(code) || InstrumentTypeOf(code) != InstrumentTypeStock {
+			continue
+		}
+		stats.Stocks++
+
+		stats.rsi = append(stats.rsi, ind.RSI)
+		stats.priceChange = append(stats.priceChange, ind.PriceChange)
+		stats.volRatio = append(stats.volRatio, ind.VolRatio)
+		if ind.RSAvg > 0 {
+			stats.rsAvg = append(stats.rsAvg, ind.RSAvg)
+		}
+		if ind.MACDHist > 0 {
+			macdPositive++
+		}
+		if ind.MA50 > 0 {
+			withMA50++
+			if ind.CurrentPrice > ind.MA50 {
+				aboveMA50++
+			}
+		}
+		if ind.MA200 > 0 {
+			withMA200++
+			if ind.CurrentPrice > ind.MA200 {
+				aboveMA200++
+			}
+			if ind.MA50AboveMA200 {
+				ma50AboveMA200++
+			}
+		}
+	}
+	if stats.Stocks == 0 {
+		return nil, fmt.Errorf("no stocks in the indicator summary")
+	}
+
+	for _, values := range [][]float64{stats.rsi, stats.rsAvg, stats.priceChange, stats.volRatio} {
+		sort.Float64s(values)
+	}
+	stats.RSI = distributionOf(stats.rsi)
+	stats.RSAvg = distributionOf(stats.rsAvg)
+	stats.PriceChange = distributionOf(stats.priceChange)
+	stats.VolRatio = distributionOf(stats.volRatio)
+	stats.RankedStocks = len(stats.rsAvg)
+	if len(stats.rsAvg) > 0 {
+		for d := 1; d < 10; d++ {
+			stats.RSDeciles = append(stats.RSDeciles, roundStat(quantile(stats.rsAvg, float64(d)/10)))
+		}
+	}
+
+	stats.PctMACDHistPositive = percentOf(macdPositive, stats.Stocks)
+	stats.PctAboveMA50 = percentOf(aboveMA50, withMA50)
+	stats.PctAboveMA200 = percentOf(aboveMA200, withMA200)
+	stats.PctMA50AboveMA200 = percentOf(ma50AboveMA200, withMA200)
+	return stats, nil
+}
+
+// Position places ind within the distributions of the statistics
+func (m *MarketStats) Position(ind *ExtendedStockIndicators) *StockStatsPosition {
+	position := &StockStatsPosition{
+		Code:                  ind.Code,
+		RSI:                   ind.RSI,
+		RSIPercentile:         percentileOf(m.rsi, ind.RSI),
+		RSAvg:                 ind.RSAvg,
+		PriceChange:           ind.PriceChange,
+		PriceChangePercentile: percentileOf(m.priceChange, ind.PriceChange),
+		VolRatio:              ind.VolRatio,
+		VolRatioPercentile:    percentileOf(m.volRatio, ind.VolRatio),
+		MACDHistPositive:      ind.MACDHist > 0,
+		AboveMA50:             ind.MA50 > 0 && ind.CurrentPrice > ind.MA50,
+		AboveMA200:            ind.MA200 > 0 && ind.CurrentPrice > ind.MA200,
+	}
+	if ind.RSAvg > 0 && len(m.RSDeciles) > 0 {
+		position.RSDecile = sort.Search(len(m.RSDeciles), func(i int) bool { return m.RSDeciles[i] > ind.RSAvg }) + 1
+	}
+	return position
+}
+
+// distributionOf summarizes sorted values
+func distributionOf(sorted []float64) IndicatorDistribution {
+	if len(sorted) == 0 {
+		return IndicatorDistribution{}
+	}
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return IndicatorDistribution{
+		Min:    roundStat(sorted[0]),
+		P10:    roundStat(quantile(sorted, 0.10)),
+		P25:    roundStat(quantile(sorted, 0.25)),
+		Median: roundStat(quantile(sorted, 0.50)),
+		P75:    roundStat(quantile(sorted, 0.75)),
+		P90:    roundStat(quantile(sorted, 0.90)),
+		Max:    roundStat(sorted[len(sorted)-1]),
+		Mean:   roundStat(sum / float64(len(sorted))),
+	}
+}
+
+// quantile returns the q-quantile of sorted values, interpolating between neighbours
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// percentileOf returns the percentage of sorted values below v
+func percentileOf(sorted []float64, v float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	below := sort.SearchFloat64s(sorted, v)
+	return roundStat(float64(below) / float64(len(sorted)) * 100)
+}
+
+// percentOf returns n as a percentage of total
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundStat(float64(n) / float64(total) * 100)
+}
+
+// roundStat rounds a statistic to two decimals
+func roundStat(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -1,25 +1,31 @@
 package services
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoDB collection names
 const (
-	MongoDBName               = "cpls_stock"
-	MongoStockListCollection  = "stock_list"
-	MongoPriceDataCollection  = "price_data"
-	MongoIndicatorsCollection = "indicators"
+	MongoDBName                   = "cpls_stock"
+	MongoStockListCollection      = "stock_list"
+	MongoPriceDataCollection      = "price_data"
+	MongoIndicatorsCollection     = "indicators"
+	MongoSignalSnapshotCollection = "signal_snapshots"
 )
 
 // MongoDBClient handles MongoDB Atlas connection and operations
@@ -60,6 +66,23 @@ type MongoIndicatorSummary struct {
 // Global MongoDB client instance
 var GlobalMongoClient *MongoDBClient
 
+// MongoDB commands run and failed since startup, watched by the health monitor
+var mongoCommands, mongoCommandFailures atomic.Int64
+
+// MongoCommandStats returns the number of MongoDB commands run and failed since startup
+func MongoCommandStats() (total, failed int64) {
+	return mongoCommands.Load(), mongoCommandFailures.Load()
+}
+
+// mongoCommandMonitor counts the outcome of every MongoDB command
+var mongoCommandMonitor = &event.CommandMonitor{
+	Succeeded: func(context.Context, *event.CommandSucceededEvent) { mongoCommands.Add(1) },
+	Failed: func(context.Context, *event.CommandFailedEvent) {
+		mongoCommands.Add(1)
+		mongoCommandFailures.Add(1)
+	},
+}
+
 // InitMongoDBClient initializes the MongoDB client
 func InitMongoDBClient() error {
 	mongoURI := os.Getenv("MONGODB_URI")
@@ -100,7 +123,8 @@ func (m *MongoDBClient) Connect() error {
 		SetMaxConnIdleTime(30 * time.Second).
 		SetConnectTimeout(30 * time.Second).
 		SetRetryWrites(true).
-		SetRetryReads(true)
+		SetRetryReads(true).
+		SetMonitor(mongoCommandMonitor)
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -375,11 +399,16 @@ func (m *MongoDBClient) SaveAllPriceData(priceFiles map[string]*StockPriceFile)
 
 // LoadPriceData loads price data for a single stock from MongoDB
 func (m *MongoDBClient) LoadPriceData(code string) (*StockPriceFile, error) {
+	return m.LoadPriceDataContext(context.Background(), code)
+}
+
+// LoadPriceDataContext is LoadPriceData cancelled with ctx
+func (m *MongoDBClient) LoadPriceDataContext(ctx context.Context, code string) (*StockPriceFile, error) {
 	if !m.IsConfigured() {
 		return nil, fmt.Errorf("MongoDB not configured")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	collection := m.database.Collection(MongoPriceDataCollection)
@@ -484,11 +513,16 @@ func (m *MongoDBClient) SaveIndicatorSummary(indicators map[string]*ExtendedStoc
 
 // LoadIndicatorSummary loads all indicators from MongoDB
 func (m *MongoDBClient) LoadIndicatorSummary() (map[string]*ExtendedStockIndicators, time.Time, error) {
+	return m.LoadIndicatorSummaryContext(context.Background())
+}
+
+// LoadIndicatorSummaryContext is LoadIndicatorSummary cancelled with ctx
+func (m *MongoDBClient) LoadIndicatorSummaryContext(ctx context.Context) (map[string]*ExtendedStockIndicators, time.Time, error) {
 	if !m.IsConfigured() {
 		return nil, time.Time{}, fmt.Errorf("MongoDB not configured")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	collection := m.database.Collection(MongoIndicatorsCollection)
@@ -529,6 +563,70 @@ func (m *MongoDBClient) GetIndicatorsMetadata() (count int, updatedAt time.Time,
 	return doc.Count, doc.UpdatedAt, nil
 }
 
+// ==================== Signal Snapshot Operations ====================
+
+// MongoSignalSnapshot holds the latest published signal snapshot as gzipped JSON, which keeps a
+// snapshot of every strategy well under the document size limit
+type MongoSignalSnapshot struct {
+	ID        string    `bson:"_id"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	Data      []byte    `bson:"data"`
+}
+
+// SaveSignalSnapshot replaces the stored signal snapshot with data, a JSON document
+func (m *MongoDBClient) SaveSignalSnapshot(data []byte) error {
+	if !m.IsConfigured() {
+		return fmt.Errorf("MongoDB not configured")
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to compress signal snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress signal snapshot: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	doc := MongoSignalSnapshot{ID: "latest", UpdatedAt: time.Now(), Data: compressed.Bytes()}
+	collection := m.database.Collection(MongoSignalSnapshotCollection)
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": "latest"}, doc, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to save signal snapshot to MongoDB: %w", err)
+	}
+
+	log.Printf("Saved signal snapshot to MongoDB Atlas (%d bytes compressed)", compressed.Len())
+	return nil
+}
+
+// LoadSignalSnapshot returns the JSON of the stored signal snapshot
+func (m *MongoDBClient) LoadSignalSnapshot() ([]byte, error) {
+	if !m.IsConfigured() {
+		return nil, fmt.Errorf("MongoDB not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var doc MongoSignalSnapshot
+	err := m.database.Collection(MongoSignalSnapshotCollection).FindOne(ctx, bson.M{"_id": "latest"}).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("signal snapshot not found in MongoDB")
+		}
+		return nil, fmt.Errorf("failed to load signal snapshot from MongoDB: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(doc.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress signal snapshot: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
 // ==================== Utility Functions ====================
 
 // SyncLocalToMongoDB syncs all local data to MongoDB
@@ -611,7 +709,7 @@ func (m *MongoDBClient) SyncMongoDBToLocal() error {
 				continue
 			}
 			filePath := fmt.Sprintf("%s/%s.json", StockPriceDir, code)
-			os.WriteFile(filePath, data, 0644)
+			writeFileAtomic(filePath, data, 0644)
 		}
 		log.Printf("Saved %d price files locally", len(priceFiles))
 	}
@@ -626,7 +724,7 @@ func (m *MongoDBClient) SyncMongoDBToLocal() error {
 		}
 		data, err := json.MarshalIndent(summary, "", "  ")
 		if err == nil {
-			os.WriteFile("data/indicators_summary.json", data, 0644)
+			writeFileAtomic("data/indicators_summary.json", data, 0644)
 			log.Printf("Saved indicators summary locally (%d stocks)", len(indicators))
 		}
 	}
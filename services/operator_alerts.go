@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const telegramSendURL = "https://api.telegram.org/bot%s/sendMessage"
+
+// OperatorAlerter sends backend health alerts to the operators over Telegram and email
+type OperatorAlerter struct {
+	telegramToken  string
+	telegramChatID string
+
+	smtpAddr     string // host:port
+	smtpHost     string
+	smtpUsername string
+	smtpPassword string
+	emailFrom    string
+	emailTo      []string
+
+	httpClient *http.Client
+}
+
+// GlobalOperatorAlerts is nil when no alert channel is configured
+var GlobalOperatorAlerts *OperatorAlerter
+
+// InitOperatorAlerts enables Telegram alerts when ALERT_TELEGRAM_BOT_TOKEN and
+// ALERT_TELEGRAM_CHAT_ID are set, and email alerts when ALERT_SMTP_HOST and ALERT_EMAIL_TO are
+func InitOperatorAlerts() error {
+	alerter := &OperatorAlerter{
+		telegramToken:  os.Getenv("ALERT_TELEGRAM_BOT_TOKEN"),
+		telegramChatID: os.Getenv("ALERT_TELEGRAM_CHAT_ID"),
+		smtpHost:       os.Getenv("ALERT_SMTP_HOST"),
+		smtpUsername:   os.Getenv("ALERT_SMTP_USERNAME"),
+		smtpPassword:   os.Getenv("ALERT_SMTP_PASSWORD"),
+		emailFrom:      os.Getenv("ALERT_EMAIL_FROM"),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+	if (alerter.telegramToken == "") != (alerter.telegramChatID == "") {
+		return fmt.Errorf("ALERT_TELEGRAM_BOT_TOKEN and ALERT_TELEGRAM_CHAT_ID must be set together")
+	}
+	for _, to := range strings.Split(os.Getenv("ALERT_EMAIL_TO"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			alerter.emailTo = append(alerter.emailTo, to)
+		}
+	}
+	if alerter.smtpHost != "" {
+		if len(alerter.emailTo) == 0 {
+			return fmt.Errorf("ALERT_EMAIL_TO is required with ALERT_SMTP_HOST")
+		}
+		port := os.Getenv("ALERT_SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		alerter.smtpAddr = net.JoinHostPort(alerter.smtpHost, port)
+		if alerter.emailFrom == "" {
+			alerter.emailFrom = alerter.smtpUsername
+		}
+		if alerter.emailFrom == "" {
+			return fmt.Errorf("ALERT_EMAIL_FROM is required without ALERT_SMTP_USERNAME")
+		}
+	}
+
+	channels := alerter.Channels()
+	if len(channels) == 0 {
+		log.Println("Operator alerts disabled (set ALERT_TELEGRAM_BOT_TOKEN or ALERT_SMTP_HOST)")
+		return nil
+	}
+	GlobalOperatorAlerts = alerter
+	log.Printf("✓ Operator alerts enabled over %s", strings.Join(channels, ", "))
+	return nil
+}
+
+// Channels lists the configured alert channels
+func (a *OperatorAlerter) Channels() []string {
+	var channels []string
+	if a == nil {
+		return channels
+	}
+	if a.telegramToken != "" {
+		channels = append(channels, "telegram")
+	}
+	if a.smtpAddr != "" {
+		channels = append(channels, "email")
+	}
+	return channels
+}
+
+// Send delivers an alert on every configured channel. It is a no-op without channels; the
+// errors of failed channels are joined.
+func (a *OperatorAlerter) Send(subject, body string) error {
+	if a == nil {
+		return nil
+	}
+	var errs []error
+	if a.telegramToken != "" {
+		if err := a.sendTelegram(subject + "\n\n" + body); err != nil {
+			errs = append(errs, fmt.Errorf("telegram: %w", err))
+		}
+	}
+	if a.smtpAddr != "" {
+		if err := a.sendEmail(subject, body); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *OperatorAlerter) sendTelegram(text string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  a.telegramChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := a.httpClient.Post(fmt.Sprintf(telegramSendURL, a.telegramToken), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// The URL holds the bot token, which must not end up in the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (a *OperatorAlerter) sendEmail(subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", a.emailFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(a.emailTo, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if a.smtpUsername != "" {
+		auth = smtp.PlainAuth("", a.smtpUsername, a.smtpPassword, a.smtpHost)
+	}
+	return smtp.SendMail(a.smtpAddr, auth, a.emailFrom, a.emailTo, []byte(msg.String()))
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSIStockInfoAPIURL is the SSI iBoard stock info endpoint providing top-of-book depth
+const SSIStockInfoAPIURL = "https://iboard-query.ssi.com.vn/v2/stock"
+
+// DepthChannelPrefix prefixes per-code order book channels, e.g. "depth:VNM"
+const DepthChannelPrefix = "depth:"
+
+// DepthLevel is one price level of the order book
+type DepthLevel struct {
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume"`
+}
+
+// OrderBook is the top-of-book bid/ask depth of a stock
+type OrderBook struct {
+	Code      string       `json:"code"`
+	Bids      []DepthLevel `json:"bids"` // best first
+	Asks      []DepthLevel `json:"asks"` // best first
+	BestBid   float64      `json:"best_bid"`
+	BestAsk   float64      `json:"best_ask"`
+	Spread    float64      `json:"spread"`
+	SpreadPct float64      `json:"spread_pct"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// ssiStockInfoResponse is the subset of the SSI stock info response carrying depth
+type ssiStockInfoResponse struct {
+	Data struct {
+		StockSymbol   string  `json:"stockSymbol"`
+		Best1Bid      float64 `json:"best1Bid"`
+		Best1BidVol   float64 `json:"best1BidVol"`
+		Best2Bid      float64 `json:"best2Bid"`
+		Best2BidVol   float64 `json:"best2BidVol"`
+		Best3Bid      float64 `json:"best3Bid"`
+		Best3BidVol   float64 `json:"best3BidVol"`
+		Best1Offer    float64 `json:"best1Offer"`
+		Best1OfferVol float64 `json:"best1OfferVol"`
+		Best2Offer    float64 `json:"best2Offer"`
+		Best2OfferVol float64 `json:"best2OfferVol"`
+		Best3Offer    float64 `json:"best3Offer"`
+		Best3OfferVol float64 `json:"best3OfferVol"`
+	} `json:"data"`
+}
+
+// DepthDayStats accumulates one trading day of depth samples for a stock
+type DepthDayStats struct {
+	Date         string     `json:"date"`
+	Last         *OrderBook `json:"last"`
+	Samples      int        `json:"samples"`
+	SpreadPctSum float64    `json:"-"`
+	MinSpreadPct float64    `json:"min_spread_pct"`
+	MaxSpreadPct float64    `json:"max_spread_pct"`
+}
+
+// AvgSpreadPct returns the mean sampled spread
+func (d *DepthDayStats) AvgSpreadPct() float64 {
+	if d.Samples == 0 {
+		return 0
+	}
+	return d.SpreadPctSum / float64(d.Samples)
+}
+
+// depthTracker keeps the current trading day's depth statistics per code
+type depthTracker struct {
+	mu    sync.Mutex
+	stats map[string]*DepthDayStats
+}
+
+func newDepthTracker() *depthTracker {
+	return &depthTracker{stats: make(map[string]*DepthDayStats)}
+}
+
+// record adds a depth sample, resetting the statistics when a new day starts
+func (t *depthTracker) record(book *OrderBook) {
+	if book.BestBid <= 0 || book.BestAsk <= 0 {
+		return
+	}
+	today := time.Now().Format(PriceDateFormat)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.stats[book.Code]
+	if stats == nil || stats.Date != today {
+		stats = &DepthDayStats{Date: today, MinSpreadPct: book.SpreadPct, MaxSpreadPct: book.SpreadPct}
+		t.stats[book.Code] = stats
+	}
+	stats.Last = book
+	stats.Samples++
+	stats.SpreadPctSum += book.SpreadPct
+	stats.MinSpreadPct = math.Min(stats.MinSpreadPct, book.SpreadPct)
+	stats.MaxSpreadPct = math.Max(stats.MaxSpreadPct, book.SpreadPct)
+}
+
+// snapshot returns a copy of today's statistics
+func (t *depthTracker) snapshot() []DepthDayStats {
+	today := time.Now().Format(PriceDateFormat)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]DepthDayStats, 0, len(t.stats))
+	for _, stats := range t.stats {
+		if stats.Date == today && stats.Last != nil {
+			result = append(result, *stats)
+		}
+	}
+	return result
+}
+
+// FetchOrderBook fetches the top three bid/ask levels of a stock from SSI iBoard.
+// SSI_IBOARD_API_URL overrides the endpoint.
+func FetchOrderBook(client *http.Client, code string) (*OrderBook, error) {
+	baseURL := os.Getenv("SSI_IBOARD_API_URL")
+	if baseURL == "" {
+		baseURL = SSIStockInfoAPIURL
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), code), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Referer", "https://iboard.ssi.com.vn/")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("SSI API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var info ssiStockInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	d := info.Data
+	book := &OrderBook{
+		Code:      code,
+		Bids:      depthLevels([][2]float64{{d.Best1Bid, d.Best1BidVol}, {d.Best2Bid, d.Best2BidVol}, {d.Best3Bid, d.Best3BidVol}}),
+		Asks:      depthLevels([][2]float64{{d.Best1Offer, d.Best1OfferVol}, {d.Best2Offer, d.Best2OfferVol}, {d.Best3Offer, d.Best3OfferVol}}),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if len(book.Bids) > 0 {
+		book.BestBid = book.Bids[0].Price
+	}
+	if len(book.Asks) > 0 {
+		book.BestAsk = book.Asks[0].Price
+	}
+	if book.BestBid > 0 && book.BestAsk > 0 {
+		book.Spread = book.BestAsk - book.BestBid
+		mid := (book.BestAsk + book.BestBid) / 2
+		book.SpreadPct = math.Round(book.Spread/mid*100*10000) / 10000
+	}
+
+	return book, nil
+}
+
+// depthLevels drops empty levels (ATO/ATC sessions report zero prices)
+func depthLevels(raw [][2]float64) []DepthLevel {
+	levels := make([]DepthLevel, 0, len(raw))
+	for _, level := range raw {
+		if level[0] > 0 {
+			levels = append(levels, DepthLevel{Price: level[0], Volume: level[1]})
+		}
+	}
+	return levels
+}
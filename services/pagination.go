@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Page sizes shared by list endpoints
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageCursor is the position after the last item of a page: the sort key value of that item and
+// its unique ID, which breaks ties. Sort names the ordering so a cursor cannot be reused with another.
+type PageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Float returns the sort key as a number, for numeric orderings
+func (c PageCursor) Float() float64 {
+	v, _ := strconv.ParseFloat(c.Value, 64)
+	return v
+}
+
+// DecodePageCursor parses a cursor token created for the given ordering
+func DecodePageCursor(token, sortName string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c PageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sortName {
+		return nil, fmt.Errorf("cursor was created for sort %q, not %q", c.Sort, sortName)
+	}
+	return &c, nil
+}
+
+// PageRequest selects a page by number (offset) or, when Cursor is set, by the position after the
+// previous page. Cursors stay stable when items are added or removed between requests.
+type PageRequest struct {
+	Page     int
+	PageSize int
+	Sort     string
+	Cursor   *PageCursor
+}
+
+// ParsePageRequest reads page, page_size and cursor query values. Invalid page numbers and sizes
+// above maxSize fall back to page 1 and defaultSize; an invalid cursor is an error.
+func ParsePageRequest(page, pageSize, cursor, sortName string, defaultSize, maxSize int) (PageRequest, error) {
+	req := PageRequest{Page: 1, PageSize: defaultSize, Sort: sortName}
+	if p, err := strconv.Atoi(page); err == nil && p > 0 {
+		req.Page = p
+	}
+	if s, err := strconv.Atoi(pageSize); err == nil && s > 0 && s <= maxSize {
+		req.PageSize = s
+	}
+	if cursor != "" {
+		c, err := DecodePageCursor(cursor, sortName)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = c
+		req.Page = 0
+	}
+	return req, nil
+}
+
+// Offset is the number of items skipped by a numbered page
+func (r PageRequest) Offset() int {
+	if r.Page < 1 {
+		return 0
+	}
+	return (r.Page - 1) * r.PageSize
+}
+
+// NewCursor returns the cursor of an item for this request's ordering
+func (r PageRequest) NewCursor(value, id string) string {
+	return PageCursor{Sort: r.Sort, Value: value, ID: id}.Encode()
+}
+
+// PageResult describes a returned page. Page is 0 when the page was selected by cursor.
+type PageResult struct {
+	Total      int
+	Page       int
+	PageSize   int
+	TotalPages int
+	NextCursor string // empty on the last page
+}
+
+// PaginateSlice returns one page of items, which must already be sorted in the ordering the cursor
+// encodes. after reports whether an item lies after a cursor position and cursorOf returns the
+// sort key value and ID of an item.
+func PaginateSlice[T any](items []T, req PageRequest, after func(T, *PageCursor) bool, cursorOf func(T) (string, string)) ([]T, PageResult) {
+	total := len(items)
+	result := PageResult{
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(req.PageSize))),
+	}
+
+	start := req.Offset()
+	if req.Cursor != nil {
+		start = sort.Search(total, func(i int) bool { return after(items[i], req.Cursor) })
+	}
+	if start > total {
+		start = total
+	}
+	end := start + req.PageSize
+	if end > total {
+		end = total
+	}
+	if end < total && end > start {
+		result.NextCursor = req.NewCursor(cursorOf(items[end-1]))
+	}
+	return items[start:end], result
+}
+
+// FormatCursorFloat formats a numeric sort key for a cursor
+func FormatCursorFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Peer selection limits
+const (
+	DefaultPeerCount = 8
+	MaxPeerCount     = 20
+)
+
+// PeerMetric names, in the order they are reported. Valuation metrics other than dividend yield
+// stay empty until fundamentals are ingested.
+var PeerMetrics = []string{"rs_avg", "rs_1y_rank", "rs_1m", "rs_3m", "price_change", "rsi", "vol_ratio", "dividend_yield", "pe", "pb"}
+
+// peerLowerIsBetter are metrics where the smallest value ranks first
+var peerLowerIsBetter = map[string]bool{"pe": true, "pb": true}
+
+// PeerRow is one stock of a peer comparison; nil metrics have no data
+type PeerRow struct {
+	Code      string              `json:"code"`
+	Name      string              `json:"name"`
+	MarketCap float64             `json:"market_cap"`
+	Price     float64             `json:"price"`
+	Subject   bool                `json:"subject,omitempty"`
+	Metrics   map[string]*float64 `json:"metrics"`
+}
+
+// PeerRank is where the subject stock stands among its peers on one metric
+type PeerRank struct {
+	Metric   string   `json:"metric"`
+	Rank     int      `json:"rank"` // 1 is best; 0 when the subject has no value
+	Of       int      `json:"of"`   // stocks with a value
+	Value    *float64 `json:"value"`
+	Median   *float64 `json:"median"`
+	Best     string   `json:"best"`
+	TopThird bool     `json:"top_third"`
+}
+
+// PeerComparison compares a stock with same-sector stocks of the closest market cap
+type PeerComparison struct {
+	Code    string     `json:"code"`
+	Sector  string     `json:"sector"`
+	Basis   string     `json:"basis"` // sector or industry, whichever the peers share
+	Subject PeerRow    `json:"subject"`
+	Peers   []PeerRow  `json:"peers"`
+	Ranks   []PeerRank `json:"ranks"`
+}
+
+// ComparePeers picks up to count stocks of the subject's sector (its industry when it has no
+// sector) closest to it in market cap and ranks the subject among them on each metric
+func ComparePeers(db *gorm.DB, code string, count int) (*PeerComparison, error) {
+	code = strings.ToUpper(code)
+	var subject models.Stock
+	if err := db.Where("symbol = ?", code).First(&subject).Error; err != nil {
+		return nil, fmt.Errorf("stock %s not found", code)
+	}
+
+	basis, group := "sector", strings.TrimSpace(subject.Sector)
+	if group == "" {
+		basis, group = "industry", strings.TrimSpace(subject.Industry)
+	}
+	if group == "" {
+		return nil, fmt.Errorf("%s has no sector or industry to find peers in", code)
+	}
+
+	var candidates []models.Stock
+	if err := db.Where(basis+" = ? AND symbol <> ? AND market_cap > 0 AND (status IS NULL OR status <> ?)",
+		group, code, "delisted").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	// Closest market caps on a log scale, so a 2x larger and a 2x smaller peer are equally close
+	subjectCap := subject.MarketCap.InexactFloat64()
+	distance := func(s models.Stock) float64 {
+		marketCap := s.MarketCap.InexactFloat64()
+		if subjectCap <= 0 {
+			return -marketCap // without a cap of its own, compare against the largest peers
+		}
+		return math.Abs(math.Log(marketCap / subjectCap))
+	}
+	filtered := candidates[:0]
+	for _, s := range candidates {
+		if !IsStockInactive(s.Symbol) && InstrumentTypeOf(s.Symbol) == InstrumentTypeStock {
+			filtered = append(filtered, s)
+		}
+	}
+	candidates = filtered
+	sort.Slice(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	var summary *IndicatorSummaryFile
+	if GlobalIndicatorService != nil {
+		summary, _ = GlobalIndicatorService.LoadIndicatorSummary()
+	}
+
+	comparison := &PeerComparison{Code: code, Sector: group, Basis: basis, Peers: make([]PeerRow, 0, len(candidates))}
+	comparison.Subject = peerRow(db, subject, summary)
+	comparison.Subject.Subject = true
+	for _, s := range candidates {
+		comparison.Peers = append(comparison.Peers, peerRow(db, s, summary))
+	}
+	sort.Slice(comparison.Peers, func(i, j int) bool { return comparison.Peers[i].MarketCap > comparison.Peers[j].MarketCap })
+
+	all := append([]PeerRow{comparison.Subject}, comparison.Peers...)
+	for _, metric := range PeerMetrics {
+		comparison.Ranks = append(comparison.Ranks, rankPeers(metric, all))
+	}
+	return comparison, nil
+}
+
+// peerRow collects the metrics of one stock
+func peerRow(db *gorm.DB, stock models.Stock, summary *IndicatorSummaryFile) PeerRow {
+	code := strings.ToUpper(stock.Symbol)
+	row := PeerRow{
+		Code:      code,
+		Name:      stock.Name,
+		MarketCap: stock.MarketCap.InexactFloat64(),
+		Metrics:   make(map[string]*float64, len(PeerMetrics)),
+	}
+	for _, metric := range PeerMetrics {
+		row.Metrics[metric] = nil
+	}
+
+	value := func(v float64) *float64 { return &v }
+	if summary != nil {
+		if ind := summary.Stocks[code]; ind != nil {
+			row.Price = ind.CurrentPrice
+			row.Metrics["rs_avg"] = value(ind.RSAvg)
+			row.Metrics["rs_1y_rank"] = value(ind.RS1YRank)
+			row.Metrics["rs_1m"] = value(ind.RS1M)
+			row.Metrics["rs_3m"] = value(ind.RS3M)
+			row.Metrics["price_change"] = value(ind.PriceChange)
+			row.Metrics["rsi"] = value(ind.RSI)
+			row.Metrics["vol_ratio"] = value(ind.VolRatio)
+		}
+	}
+	if yield, err := TrailingDividendYield(db, code); err == nil && yield.Price > 0 {
+		row.Metrics["dividend_yield"] = value(yield.YieldPct)
+	}
+	return row
+}
+
+// rankPeers ranks the subject (rows[0]) among the rows with a value for metric
+func rankPeers(metric string, rows []PeerRow) PeerRank {
+	rank := PeerRank{Metric: metric, Value: rows[0].Metrics[metric]}
+
+	type entry struct {
+		code  string
+		value float64
+	}
+	entries := make([]entry, 0, len(rows))
+	for _, row := range rows {
+		if v := row.Metrics[metric]; v != nil {
+			entries = append(entries, entry{row.Code, *v})
+		}
+	}
+	rank.Of = len(entries)
+	if len(entries) == 0 {
+		return rank
+	}
+
+	lower := peerLowerIsBetter[metric]
+	sort.SliceStable(entries, func(i, j int) bool {
+		if lower {
+			return entries[i].value < entries[j].value
+		}
+		return entries[i].value > entries[j].value
+	})
+	rank.Best = entries[0].code
+
+	values := make([]float64, len(entries))
+	for i, e := range entries {
+		values[i] = e.value
+	}
+	sort.Float64s(values)
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + values[len(values)/2]) / 2
+	}
+	rank.Median = &median
+
+	if rank.Value != nil {
+		// Ties share the better rank
+		rank.Rank = 1
+		for _, e := range entries {
+			if lower && e.value < *rank.Value || !lower && e.value > *rank.Value {
+				rank.Rank++
+			}
+		}
+		rank.TopThird = float64(rank.Rank) <= math.Ceil(float64(rank.Of)/3)
+	}
+	return rank
+}
@@ -0,0 +1,452 @@
+// Package pipeline runs the daily end-of-day data pipeline: price sync, price validation,
+// indicator recalculation, the signal snapshot, rule signal emission and notifications, one stage
+// after another.
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"gorm.io/gorm"
+)
+
+// ConfigFile stores the pipeline settings and its last run
+const ConfigFile = "data/pipeline_config.json"
+
+// Pipeline stages, in run order
+const (
+	StagePriceSync  = "price_sync"
+	StageValidate   = "validate"
+	StageIndicators = "indicators"
+	StageSnapshot   = "snapshot"
+	StageSignals    = "signals"
+	StageNotify     = "notify"
+)
+
+// Stage statuses
+const (
+	StagePending   = "pending"
+	StageRunning   = "running"
+	StageSucceeded = "succeeded"
+	StageFailed    = "failed"
+	StageSkipped   = "skipped"
+)
+
+// Run triggers
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// Config controls the daily pipeline. ScheduleTime is the market-local start time on trading
+// days; the default leaves the ATC close and the data provider's end-of-day update time to settle.
+type Config struct {
+	Enabled           bool    `json:"enabled"`
+	ScheduleTime      string  `json:"schedule_time"`       // HH:MM
+	MaxInvalidPercent float64 `json:"max_invalid_percent"` // validation fails above this share of bad stocks
+	LastRun           *Run    `json:"last_run,omitempty"`
+}
+
+// DefaultConfig returns the default pipeline settings
+func DefaultConfig() Config {
+	return Config{
+		Enabled:           true,
+		ScheduleTime:      "15:30",
+		MaxInvalidPercent: 10,
+	}
+}
+
+// StageResult is the outcome of one stage of a run
+type StageResult struct {
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	Message    string      `json:"message,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Duration   string      `json:"duration,omitempty"`
+}
+
+// Run is one execution of the pipeline
+type Run struct {
+	JobID      string        `json:"job_id"`
+	Trigger    string        `json:"trigger"`
+	Status     string        `json:"status"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Stages     []StageResult `json:"stages"`
+}
+
+// stage is a step of the pipeline. Weight is its share of the job progress; run reports its own
+// progress as a fraction and returns a summary message and details for the run record.
+type stage struct {
+	name   string
+	weight float64
+	run    func(ctx context.Context, report func(fraction float64, item string)) (string, interface{}, error)
+}
+
+// DataPipeline runs the daily pipeline as a background job, on schedule or on demand
+type DataPipeline struct {
+	db      *gorm.DB
+	mu      sync.RWMutex
+	config  Config
+	current *Run
+}
+
+// GlobalDataPipeline is the daily data pipeline
+var GlobalDataPipeline *DataPipeline
+
+// InitDataPipeline loads the pipeline settings
+func InitDataPipeline(db *gorm.DB) error {
+	p := &DataPipeline{db: db, config: DefaultConfig()}
+	if data, err := os.ReadFile(ConfigFile); err == nil {
+		if err := json.Unmarshal(data, &p.config); err != nil {
+			log.Printf("Invalid pipeline config, using defaults: %v", err)
+			p.config = DefaultConfig()
+		}
+	}
+	GlobalDataPipeline = p
+	log.Printf("Data pipeline initialized (enabled: %v, schedule: %s)", p.config.Enabled, p.config.ScheduleTime)
+	return nil
+}
+
+// GetConfig returns the settings and the last finished run
+func (p *DataPipeline) GetConfig() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// CurrentRun returns the run in progress, or nil
+func (p *DataPipeline) CurrentRun() *Run {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.current == nil {
+		return nil
+	}
+	run := *p.current
+	run.Stages = append([]StageResult(nil), p.current.Stages...)
+	return &run
+}
+
+// UpdateConfig changes and saves the settings
+func (p *DataPipeline) UpdateConfig(enabled bool, scheduleTime string, maxInvalidPercent float64) error {
+	if _, err := time.Parse("15:04", scheduleTime); err != nil {
+		return fmt.Errorf("invalid schedule_time %q, expected HH:MM", scheduleTime)
+	}
+	if maxInvalidPercent < 0 || maxInvalidPercent > 100 {
+		return fmt.Errorf("max_invalid_percent must be between 0 and 100")
+	}
+
+	p.mu.Lock()
+	p.config.Enabled = enabled
+	p.config.ScheduleTime = scheduleTime
+	p.config.MaxInvalidPercent = maxInvalidPercent
+	p.mu.Unlock()
+	return p.saveConfig()
+}
+
+func (p *DataPipeline) saveConfig() error {
+	p.mu.RLock()
+	data, err := json.MarshalIndent(p.config, "", "  ")
+	p.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ConfigFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(ConfigFile, data, 0644)
+}
+
+// Due reports whether the scheduled run should start at now: the pipeline is enabled, it is a
+// trading day, the market-local clock reads the schedule time and no run started today.
+func (p *DataPipeline) Due(now time.Time) bool {
+	cfg := p.GetConfig()
+	if !cfg.Enabled || !services.MarketCalendar().IsTradingDay(now) {
+		return false
+	}
+	local := now.In(services.MarketCalendar().Location())
+	if local.Format("15:04") != cfg.ScheduleTime {
+		return false
+	}
+	if cfg.LastRun != nil && cfg.LastRun.StartedAt.In(local.Location()).Format(services.PriceDateFormat) == local.Format(services.PriceDateFormat) {
+		return false
+	}
+	return true
+}
+
+// Submit queues a run on the job manager. Only one run may be queued or running at a time.
+func (p *DataPipeline) Submit(trigger, createdBy string) (services.Job, error) {
+	if services.GlobalJobManager == nil {
+		return services.Job{}, fmt.Errorf("job manager not initialized")
+	}
+	for _, job := range services.GlobalJobManager.List(services.JobTypeDataPipeline, 0) {
+		if !job.Finished() {
+			return services.Job{}, fmt.Errorf("pipeline run %s is already %s", job.ID, job.Status)
+		}
+	}
+
+	// The job's work needs its ID for the run record, which is only known once it is queued
+	jobID := make(chan string, 1)
+	job, err := services.GlobalJobManager.Submit(services.JobTypeDataPipeline, createdBy,
+		func(ctx context.Context, progress services.JobProgress) (interface{}, error) {
+			return p.run(ctx, <-jobID, trigger, progress)
+		})
+	if err == nil {
+		jobID <- job.ID
+	}
+	return job, err
+}
+
+// run executes the stages in order. A failed stage skips the remaining ones and alerts the admins.
+func (p *DataPipeline) run(ctx context.Context, jobID, trigger string, progress services.JobProgress) (*Run, error) {
+	stages := p.stages()
+	run := &Run{JobID: jobID, Trigger: trigger, Status: string(services.JobRunning), StartedAt: time.Now()}
+	for _, st := range stages {
+		run.Stages = append(run.Stages, StageResult{Name: st.name, Status: StagePending})
+	}
+	p.mu.Lock()
+	p.current = run
+	p.mu.Unlock()
+	log.Printf("Data pipeline run %s started (%s)", jobID, trigger)
+
+	var runErr error
+	done := 0.0
+	for i, st := range stages {
+		if runErr != nil {
+			p.updateStage(i, func(r *StageResult) { r.Status = StageSkipped })
+			continue
+		}
+
+		started := time.Now()
+		p.updateStage(i, func(r *StageResult) { r.Status = StageRunning; r.StartedAt = &started })
+		progress(done, st.name, fmt.Sprintf("Running %s", st.name))
+
+		base := done
+		message, details, err := st.run(ctx, func(fraction float64, item string) {
+			progress(base+st.weight*min(fraction, 1), item, "")
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		finished := time.Now()
+		p.updateStage(i, func(r *StageResult) {
+			r.Status = StageSucceeded
+			r.Message = message
+			r.Details = details
+			r.FinishedAt = &finished
+			r.Duration = finished.Sub(started).Round(time.Second).String()
+			if err != nil {
+				r.Status = StageFailed
+				r.Error = err.Error()
+			}
+		})
+		if err != nil {
+			runErr = fmt.Errorf("stage %s failed: %w", st.name, err)
+			if ctx.Err() == nil {
+				services.NotifyAdmins(p.db, models.NotificationLevelCritical, "data_pipeline",
+					fmt.Sprintf("Daily data pipeline failed at %s", st.name), err.Error(),
+					map[string]interface{}{"job_id": jobID, "stage": st.name, "trigger": trigger})
+			}
+			continue
+		}
+		done += st.weight
+	}
+
+	return p.finish(ctx, run, runErr)
+}
+
+// finish records the run as the last one and in the sync history
+func (p *DataPipeline) finish(ctx context.Context, run *Run, runErr error) (*Run, error) {
+	finished := time.Now()
+
+	p.mu.Lock()
+	run.FinishedAt = &finished
+	switch {
+	case ctx.Err() != nil:
+		run.Status = string(services.JobCancelled)
+	case runErr != nil:
+		run.Status = string(services.JobFailed)
+	default:
+		run.Status = string(services.JobSucceeded)
+	}
+	succeeded, failed := 0, 0
+	for _, stage := range run.Stages {
+		switch stage.Status {
+		case StageSucceeded:
+			succeeded++
+		case StageFailed:
+			failed++
+		}
+	}
+	p.config.LastRun = run
+	p.current = nil
+	p.mu.Unlock()
+
+	if err := p.saveConfig(); err != nil {
+		log.Printf("Failed to save pipeline config: %v", err)
+	}
+	services.RecordSync(models.SyncTypePipeline, run.StartedAt, len(run.Stages), succeeded, failed, runErr)
+	log.Printf("Data pipeline run %s %s in %v", run.JobID, run.Status, finished.Sub(run.StartedAt).Round(time.Second))
+	return run, runErr
+}
+
+func (p *DataPipeline) updateStage(i int, update func(*StageResult)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != nil {
+		update(&p.current.Stages[i])
+	}
+}
+
+func (p *DataPipeline) stages() []stage {
+	return []stage{
+		{StagePriceSync, 45, p.syncPrices},
+		{StageValidate, 5, p.validatePrices},
+		{StageIndicators, 30, p.calculateIndicators},
+		{StageSnapshot, 5, p.publishSnapshot},
+		{StageSignals, 10, p.emitSignals},
+		{StageNotify, 5, p.notify},
+	}
+}
+
+func (p *DataPipeline) syncPrices(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	if services.GlobalPriceService == nil {
+		return "", nil, fmt.Errorf("price service not initialized")
+	}
+	result, err := services.GlobalPriceService.RunFullSync(ctx, func(sp services.PriceSyncProgress) {
+		if sp.TotalStocks > 0 {
+			report(float64(sp.ProcessedStocks)/float64(sp.TotalStocks), sp.CurrentStock)
+		}
+	})
+	summary := map[string]interface{}{
+		"total_stocks":  result.TotalStocks,
+		"success_count": result.SuccessCount,
+		"failed_count":  result.FailedCount,
+		"failed_stocks": result.FailedStocks,
+	}
+	return fmt.Sprintf("Synced %d/%d stocks, %d failed", result.SuccessCount, result.TotalStocks, result.FailedCount), summary, err
+}
+
+func (p *DataPipeline) validatePrices(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	result, err := services.ValidatePriceData(ctx, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	message := fmt.Sprintf("%d of %d stocks have missing, stale or invalid prices (%.2f%%)",
+		result.ProblemCount, result.Checked, result.ProblemPercent)
+
+	limit := p.GetConfig().MaxInvalidPercent
+	if result.ProblemPercent > limit {
+		return message, result, fmt.Errorf("%s, above the %.2f%% limit", message, limit)
+	}
+	return message, result, nil
+}
+
+func (p *DataPipeline) calculateIndicators(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	if services.GlobalIndicatorService == nil {
+		return "", nil, fmt.Errorf("indicator service not initialized")
+	}
+	calculated := 0
+	err := services.GlobalIndicatorService.CalculateAndSaveAllIndicatorsContext(ctx, func(done, total int, code string) {
+		calculated = done
+		if total > 0 {
+			report(float64(done)/float64(total), code)
+		}
+	})
+	return fmt.Sprintf("Calculated indicators for %d stocks", calculated), nil, err
+}
+
+// publishSnapshot evaluates every strategy on the fresh indicators and publishes the snapshot the
+// public signal endpoints serve from
+func (p *DataPipeline) publishSnapshot(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	if signals.GlobalSignalService == nil {
+		return "", nil, fmt.Errorf("signal service not initialized")
+	}
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return signalsDisabledMessage, nil, nil
+	}
+	snapshot, err := signals.GlobalSignalService.BuildSnapshot(ctx, func(done, total int, strategy string) {
+		if total > 0 {
+			report(float64(done)/float64(total), strategy)
+		}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := signals.PublishSnapshot(snapshot); err != nil {
+		return "", nil, err
+	}
+
+	counts := make(map[string]int, len(snapshot.Strategies))
+	for name, list := range snapshot.Strategies {
+		counts[name] = len(list)
+	}
+	return fmt.Sprintf("Published signals of %d strategies for %d stocks", len(snapshot.Strategies), len(snapshot.Stocks)),
+		map[string]interface{}{"data_as_of": snapshot.DataAsOf, "strategies": counts}, nil
+}
+
+// signalsDisabledMessage is the result of the signal stages while the signal generation kill
+// switch is on; the pipeline carries on with the alerts
+const signalsDisabledMessage = "Skipped, signal generation is disabled by an administrator"
+
+func (p *DataPipeline) emitSignals(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	if signals.GlobalConditionEvaluator == nil {
+		return "", nil, fmt.Errorf("condition evaluator not initialized")
+	}
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return signalsDisabledMessage, nil, nil
+	}
+	emitted, err := signals.GlobalConditionEvaluator.RunRuleSignalEmission()
+	if err != nil {
+		return "", nil, err
+	}
+
+	byType := make(map[string]int)
+	for _, signal := range emitted {
+		byType[signal.SignalType]++
+	}
+	return fmt.Sprintf("Emitted %d new rule signals", len(emitted)), map[string]interface{}{
+		"emitted": len(emitted),
+		"by_type": byType,
+	}, nil
+}
+
+// notify delivers the signal alerts and subscriptions, then posts the run digest for the admins
+func (p *DataPipeline) notify(ctx context.Context, report func(float64, string)) (string, interface{}, error) {
+	if signals.GlobalConditionEvaluator == nil {
+		return "", nil, fmt.Errorf("condition evaluator not initialized")
+	}
+	sent, err := signals.GlobalConditionEvaluator.CheckSignalAlerts()
+	if err != nil {
+		return "", nil, err
+	}
+
+	run := p.CurrentRun()
+	digest := make(map[string]string)
+	if run != nil {
+		for _, stage := range run.Stages {
+			if stage.Message != "" {
+				digest[stage.Name] = stage.Message
+			}
+		}
+	}
+	message := fmt.Sprintf("Sent %d signal alert notifications", sent)
+	services.NotifyAdmins(p.db, models.NotificationLevelInfo, "data_pipeline", "Daily data pipeline completed",
+		fmt.Sprintf("%s; %s; %s; %s", digest[StagePriceSync], digest[StageValidate], digest[StageSignals], message),
+		digest)
+	return message, map[string]interface{}{"alert_notifications": sent}, nil
+}
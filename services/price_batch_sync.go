@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+)
+
+// Batch price refresh limits
+const (
+	MaxBatchSyncCodes    = 500
+	BatchSyncWorkerCount = 3 // kept below the full sync so both can run without rate limits
+)
+
+// BatchSyncRequest selects the stocks of a batch price refresh: an explicit code list, or every
+// active stock listed on a floor (HOSE, HNX, UPCOM)
+type BatchSyncRequest struct {
+	Codes []string `json:"codes"`
+	Floor string   `json:"floor"`
+}
+
+// BatchSyncProgress is the progress of one batch price refresh. It is tracked per batch and
+// never touches the full sync progress or its failed-stock retry queue.
+type BatchSyncProgress struct {
+	TotalStocks     int      `json:"total_stocks"`
+	ProcessedStocks int      `json:"processed_stocks"`
+	SuccessCount    int      `json:"success_count"`
+	FailedCount     int      `json:"failed_count"`
+	FailedStocks    []string `json:"failed_stocks"`
+	CurrentStock    string   `json:"current_stock"`
+	ElapsedTime     string   `json:"elapsed_time"`
+	WorkerCount     int      `json:"worker_count"`
+}
+
+// ResolveBatchCodes returns the deduplicated, upper-cased codes selected by req
+func ResolveBatchCodes(req BatchSyncRequest) ([]string, error) {
+	floor := strings.ToUpper(strings.TrimSpace(req.Floor))
+	if len(req.Codes) == 0 && floor == "" {
+		return nil, fmt.Errorf("codes or floor is required")
+	}
+
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range req.Codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	if floor != "" {
+		stocks, err := LoadStocksFromFile()
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, stock := range stocks {
+			if !strings.EqualFold(stock.Floor, floor) {
+				continue
+			}
+			found = true
+			if !seen[stock.Code] && !IsStockInactive(stock.Code) {
+				seen[stock.Code] = true
+				codes = append(codes, stock.Code)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no stocks listed on floor %s", floor)
+		}
+	}
+
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no stock codes to sync")
+	}
+	if len(codes) > MaxBatchSyncCodes {
+		return nil, fmt.Errorf("too many stocks (%d), at most %d per batch", len(codes), MaxBatchSyncCodes)
+	}
+	return codes, nil
+}
+
+// SyncBatch fetches and saves prices for codes on its own small worker pool, calling progress after
+// every stock. It stops handing out codes once ctx is cancelled and returns the final progress.
+func (s *StockPriceService) SyncBatch(ctx context.Context, codes []string, progress func(BatchSyncProgress)) (BatchSyncProgress, error) {
+	startTime := time.Now()
+
+	s.mu.RLock()
+	priceSize := s.config.PriceSize
+	delay := time.Duration(s.config.DelayMS) * time.Millisecond
+	s.mu.RUnlock()
+
+	state := BatchSyncProgress{TotalStocks: len(codes), WorkerCount: BatchSyncWorkerCount}
+	var mu sync.Mutex
+	report := func(code string, err error) {
+		mu.Lock()
+		state.ProcessedStocks++
+		state.CurrentStock = code
+		if err != nil {
+			state.FailedCount++
+			state.FailedStocks = append(state.FailedStocks, code)
+			log.Printf("Batch price sync: %s failed: %v", code, err)
+		} else {
+			state.SuccessCount++
+		}
+		state.ElapsedTime = time.Since(startTime).Round(time.Second).String()
+		snapshot := state
+		mu.Unlock()
+
+		if progress != nil {
+			progress(snapshot)
+		}
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < BatchSyncWorkerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for code := range jobs {
+				// Small delay to avoid rate limiting
+				select {
+				case <-ctx.Done():
+					continue
+				case <-time.After(delay):
+				}
+				report(code, s.syncBatchStock(code, priceSize))
+			}
+		}()
+	}
+
+feed:
+	for _, code := range codes {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- code:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	mu.Lock()
+	result := state
+	mu.Unlock()
+	sort.Strings(result.FailedStocks)
+	result.ElapsedTime = time.Since(startTime).Round(time.Second).String()
+
+	log.Printf("Batch price sync completed: %d/%d processed, success=%d, failed=%d, time=%s",
+		result.ProcessedStocks, result.TotalStocks, result.SuccessCount, result.FailedCount, result.ElapsedTime)
+	RecordSync(models.SyncTypePriceBatch, startTime, len(codes), result.SuccessCount, result.FailedCount, ctx.Err())
+
+	return result, ctx.Err()
+}
+
+// syncBatchStock fetches and saves the prices of one stock
+func (s *StockPriceService) syncBatchStock(code string, size int) error {
+	priceResp, err := s.FetchStockPrice(code, size)
+	if err != nil {
+		return err
+	}
+	if len(priceResp.Data) == 0 {
+		return fmt.Errorf("no data")
+	}
+	return s.SaveStockPrice(code, priceResp.Data)
+}
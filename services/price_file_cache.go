@@ -0,0 +1,187 @@
+package services
+
+import (
+	"container/list"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Price file cache defaults, overridden with PRICE_CACHE_MAX_ENTRIES and PRICE_CACHE_MAX_MB. A
+// year of daily prices parses to roughly 100KB, so the defaults keep the most used few hundred
+// stocks without holding the whole universe.
+const (
+	DefaultPriceCacheMaxEntries = 300
+	DefaultPriceCacheMaxMB      = 128
+)
+
+// PriceCacheStats reports the parsed price file cache
+type PriceCacheStats struct {
+	Entries    int     `json:"entries"`
+	Bytes      int64   `json:"bytes"` // estimated memory of the cached files
+	MaxEntries int     `json:"max_entries"`
+	MaxBytes   int64   `json:"max_bytes"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	Evictions  int64   `json:"evictions"`
+	HitRate    float64 `json:"hit_rate"` // percent of loads served from the cache
+}
+
+// priceFileCache is an LRU of parsed per-stock price files. An entry is only served while the
+// file on disk keeps the modification time and size it was parsed from, so files written outside
+// SaveStockPrice (restores, indicator saves) are picked up without explicit invalidation.
+//
+// Cached files are shared between callers and must be treated as read-only.
+type priceFileCache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu    sync.Mutex
+	order *list.List // front is most recently used
+	byKey map[string]*list.Element
+	bytes int64
+
+	hits, misses, evictions atomic.Int64
+}
+
+type priceCacheEntry struct {
+	code    string
+	file    *StockPriceFile
+	modTime time.Time
+	size    int64
+	bytes   int64
+}
+
+// newPriceFileCacheFromEnv creates the cache with the limits from the environment; a zero entry
+// or byte limit disables it
+func newPriceFileCacheFromEnv() *priceFileCache {
+	maxEntries := DefaultPriceCacheMaxEntries
+	if v, err := strconv.Atoi(os.Getenv("PRICE_CACHE_MAX_ENTRIES")); err == nil && v >= 0 {
+		maxEntries = v
+	}
+	maxMB := DefaultPriceCacheMaxMB
+	if v, err := strconv.Atoi(os.Getenv("PRICE_CACHE_MAX_MB")); err == nil && v >= 0 {
+		maxMB = v
+	}
+	if maxEntries == 0 || maxMB == 0 {
+		log.Println("Price file cache disabled")
+		return nil
+	}
+	return &priceFileCache{
+		maxEntries: maxEntries,
+		maxBytes:   int64(maxMB) << 20,
+		order:      list.New(),
+		byKey:      make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached file of code if it was parsed from the file described by info
+func (c *priceFileCache) get(code string, info os.FileInfo) (*StockPriceFile, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.byKey[code]
+	if ok {
+		entry := el.Value.(*priceCacheEntry)
+		if entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+			c.order.MoveToFront(el)
+			c.hits.Add(1)
+			return entry.file, true
+		}
+		c.remove(el)
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// put caches file as parsed from the file described by info, evicting the least recently used
+// files over the limits. A file larger than the byte limit on its own is not cached.
+func (c *priceFileCache) put(code string, info os.FileInfo, file *StockPriceFile) {
+	if c == nil {
+		return
+	}
+	entry := &priceCacheEntry{code: code, file: file, modTime: info.ModTime(), size: info.Size(), bytes: estimatePriceFileBytes(file)}
+	if entry.bytes > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.byKey[code]; ok {
+		c.remove(el)
+	}
+	c.byKey[code] = c.order.PushFront(entry)
+	c.bytes += entry.bytes
+
+	for c.order.Len() > c.maxEntries || c.bytes > c.maxBytes {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate drops the cached file of code
+func (c *priceFileCache) invalidate(code string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.byKey[code]; ok {
+		c.remove(el)
+	}
+}
+
+// purge drops every cached file and returns how many there were
+func (c *priceFileCache) purge() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.order.Len()
+	c.order.Init()
+	c.byKey = make(map[string]*list.Element)
+	c.bytes = 0
+	return n
+}
+
+func (c *priceFileCache) stats() PriceCacheStats {
+	if c == nil {
+		return PriceCacheStats{}
+	}
+	c.mu.Lock()
+	stats := PriceCacheStats{Entries: c.order.Len(), Bytes: c.bytes, MaxEntries: c.maxEntries, MaxBytes: c.maxBytes}
+	c.mu.Unlock()
+
+	stats.Hits, stats.Misses, stats.Evictions = c.hits.Load(), c.misses.Load(), c.evictions.Load()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits*10000/total) / 100
+	}
+	return stats
+}
+
+// remove unlinks an entry; the caller holds c.mu
+func (c *priceFileCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*priceCacheEntry)
+	delete(c.byKey, entry.code)
+	c.bytes -= entry.bytes
+}
+
+// estimatePriceFileBytes approximates the memory of a parsed price file: the price rows plus
+// their short strings (code, date, time, exchange, type)
+func estimatePriceFileBytes(file *StockPriceFile) int64 {
+	const rowStrings = 48
+	size := int64(unsafe.Sizeof(*file)) + int64(len(file.Code)+len(file.LastUpdated))
+	size += int64(cap(file.Prices)) * (int64(unsafe.Sizeof(StockPriceData{})) + rowStrings)
+	if file.Indicators != nil {
+		size += int64(unsafe.Sizeof(*file.Indicators))
+	}
+	return size
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Support/resistance detection defaults
+const (
+	LevelsLookback     = 120 // sessions scanned for swing highs and lows
+	LevelsSwingWindow  = 3   // sessions on each side a swing high/low must exceed
+	LevelsClusterPct   = 1.5 // swings within this % of a cluster's level join it
+	LevelsMinTouches   = 2   // swings needed for a cluster to count as a level
+	NoLevelDistancePct = 100 // distance reported when there is no level on that side
+	maxLevelsPerSide   = 5
+)
+
+// PivotLevels are the pivot point and three support/resistance levels around it
+type PivotLevels struct {
+	Pivot float64 `json:"pivot" unit:"price"`
+	R1    float64 `json:"r1" unit:"price"`
+	R2    float64 `json:"r2" unit:"price"`
+	R3    float64 `json:"r3" unit:"price"`
+	S1    float64 `json:"s1" unit:"price"`
+	S2    float64 `json:"s2" unit:"price"`
+	S3    float64 `json:"s3" unit:"price"`
+}
+
+// PriceLevel is a cluster of swing highs and lows at about the same price
+type PriceLevel struct {
+	Level       float64 `json:"level" unit:"price"`
+	Touches     int     `json:"touches"`
+	LastTouch   string  `json:"last_touch"`
+	DistancePct float64 `json:"distance_pct"` // absolute distance from the latest close, %
+}
+
+// StockLevels are the pivot levels for the next session and the historical support and
+// resistance clusters of a stock
+type StockLevels struct {
+	Code        string       `json:"code"`
+	Date        string       `json:"date"` // session the pivots were calculated from
+	Close       float64      `json:"close" unit:"price"`
+	Classic     PivotLevels  `json:"classic"`
+	Fibonacci   PivotLevels  `json:"fibonacci"`
+	Supports    []PriceLevel `json:"supports"`    // nearest first
+	Resistances []PriceLevel `json:"resistances"` // nearest first
+	Lookback    int          `json:"lookback"`
+}
+
+// CalculatePivotLevels returns the classic and Fibonacci pivots from one session's high, low and close
+func CalculatePivotLevels(high, low, closePrice float64) (classic, fibonacci PivotLevels) {
+	p := (high + low + closePrice) / 3
+	r := high - low
+
+	classic = PivotLevels{
+		Pivot: p,
+		R1:    2*p - low,
+		R2:    p + r,
+		R3:    high + 2*(p-low),
+		S1:    2*p - high,
+		S2:    p - r,
+		S3:    low - 2*(high-p),
+	}
+	fibonacci = PivotLevels{
+		Pivot: p,
+		R1:    p + 0.382*r,
+		R2:    p + 0.618*r,
+		R3:    p + r,
+		S1:    p - 0.382*r,
+		S2:    p - 0.618*r,
+		S3:    p - r,
+	}
+	return roundPivots(classic), roundPivots(fibonacci)
+}
+
+// CalculateStockLevels computes pivots from the latest session and support/resistance clusters
+// from the swing highs and lows of the latest lookback sessions (prices newest first). Pivots use
+// the traded prices of the session; clusters use adjusted prices so splits do not create levels.
+func CalculateStockLevels(code string, prices []StockPriceData, lookback int) *StockLevels {
+	levels := &StockLevels{Code: code, Supports: []PriceLevel{}, Resistances: []PriceLevel{}, Lookback: lookback}
+	if len(prices) == 0 {
+		return levels
+	}
+
+	latest := prices[0]
+	levels.Date = latest.Date
+	levels.Close = latest.Close
+	levels.Classic, levels.Fibonacci = CalculatePivotLevels(latest.High, latest.Low, latest.Close)
+
+	_, _, closePrice := adjustedBar(latest)
+	if closePrice <= 0 {
+		return levels
+	}
+	if len(prices) > lookback {
+		prices = prices[:lookback]
+	}
+
+	for _, cluster := range swingClusters(prices) {
+		if cluster.Touches < LevelsMinTouches {
+			continue
+		}
+		cluster.DistancePct = math.Round(math.Abs(cluster.Level-closePrice)/closePrice*10000) / 100
+		if cluster.Level < closePrice {
+			levels.Supports = append(levels.Supports, cluster)
+		} else {
+			levels.Resistances = append(levels.Resistances, cluster)
+		}
+	}
+
+	sort.Slice(levels.Supports, func(i, j int) bool { return levels.Supports[i].Level > levels.Supports[j].Level })
+	sort.Slice(levels.Resistances, func(i, j int) bool { return levels.Resistances[i].Level < levels.Resistances[j].Level })
+	if len(levels.Supports) > maxLevelsPerSide {
+		levels.Supports = levels.Supports[:maxLevelsPerSide]
+	}
+	if len(levels.Resistances) > maxLevelsPerSide {
+		levels.Resistances = levels.Resistances[:maxLevelsPerSide]
+	}
+	return levels
+}
+
+// NearestLevelDistances returns the % distance from the latest close down to the nearest support
+// and up to the nearest resistance, NoLevelDistancePct when there is none on that side
+func (l *StockLevels) NearestLevelDistances() (support, resistance float64) {
+	support, resistance = NoLevelDistancePct, NoLevelDistancePct
+	if len(l.Supports) > 0 {
+		support = l.Supports[0].DistancePct
+	}
+	if len(l.Resistances) > 0 {
+		resistance = l.Resistances[0].DistancePct
+	}
+	return support, resistance
+}
+
+type swing struct {
+	price float64
+	date  string
+}
+
+// swingClusters groups the swing highs and lows of prices into levels
+func swingClusters(prices []StockPriceData) []PriceLevel {
+	var swings []swing
+	w := LevelsSwingWindow
+	for i := w; i < len(prices)-w; i++ {
+		high, low, _ := adjustedBar(prices[i])
+		if high <= 0 || low <= 0 {
+			continue
+		}
+		isHigh, isLow := true, true
+		for j := i - w; j <= i+w && (isHigh || isLow); j++ {
+			if j == i {
+				continue
+			}
+			h, l, _ := adjustedBar(prices[j])
+			if h >= high {
+				isHigh = false
+			}
+			if l <= low {
+				isLow = false
+			}
+		}
+		if isHigh {
+			swings = append(swings, swing{high, prices[i].Date})
+		}
+		if isLow {
+			swings = append(swings, swing{low, prices[i].Date})
+		}
+	}
+	sort.Slice(swings, func(i, j int) bool { return swings[i].price < swings[j].price })
+
+	var clusters []PriceLevel
+	var sum float64
+	for _, s := range swings {
+		if n := len(clusters); n > 0 {
+			c := &clusters[n-1]
+			mean := sum / float64(c.Touches)
+			if (s.price-mean)/mean*100 <= LevelsClusterPct {
+				sum += s.price
+				c.Touches++
+				c.Level = sum / float64(c.Touches)
+				if s.date > c.LastTouch {
+					c.LastTouch = s.date
+				}
+				continue
+			}
+		}
+		sum = s.price
+		clusters = append(clusters, PriceLevel{Level: s.price, Touches: 1, LastTouch: s.date})
+	}
+	for i := range clusters {
+		clusters[i].Level = roundLevel(clusters[i].Level)
+	}
+	return clusters
+}
+
+// LoadStockLevels calculates the levels of a stock from its stored price history
+func LoadStockLevels(code string, lookback int) (*StockLevels, error) {
+	if GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+	code = strings.ToUpper(code)
+	file, err := GlobalPriceService.LoadStockPrice(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Prices) == 0 {
+		return nil, fmt.Errorf("no price data for %s", code)
+	}
+	return CalculateStockLevels(code, file.Prices, lookback), nil
+}
+
+func roundPivots(p PivotLevels) PivotLevels {
+	return PivotLevels{
+		Pivot: roundLevel(p.Pivot),
+		R1:    roundLevel(p.R1),
+		R2:    roundLevel(p.R2),
+		R3:    roundLevel(p.R3),
+		S1:    roundLevel(p.S1),
+		S2:    roundLevel(p.S2),
+		S3:    roundLevel(p.S3),
+	}
+}
+
+func roundLevel(v float64) float64 {
+	return math.Round(v*100) / 100
+}
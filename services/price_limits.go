@@ -0,0 +1,99 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// Daily price limit states of a stock
+const (
+	LimitHitCeiling         = "hit_ceiling"
+	LimitHitFloor           = "hit_floor"
+	LimitApproachingCeiling = "approaching_ceiling"
+)
+
+// ApproachingLimitGap is how close to the ceiling, in percentage points of the reference price,
+// a stock counts as approaching it: above +6.5% on HOSE's 7% band
+const ApproachingLimitGap = 0.5
+
+// limitTolerance absorbs float noise when comparing prices quoted in thousands of VND
+const limitTolerance = 1e-6
+
+// PriceLimitStock is a stock at or near its daily price limit
+type PriceLimitStock struct {
+	Code          string  `json:"code"`
+	Exchange      string  `json:"exchange"`
+	Status        string  `json:"status"`
+	Price         float64 `json:"price"`
+	RefPrice      float64 `json:"ref_price"`
+	Ceiling       float64 `json:"ceiling"`
+	Floor         float64 `json:"floor"`
+	ChangePercent float64 `json:"change_percent"`
+	Volume        float64 `json:"volume"`
+	Timestamp     string  `json:"timestamp"`
+}
+
+// PriceLimitStatus classifies a realtime price against its ceiling and floor; it returns ""
+// when the price is inside the band or the quote carries no limits
+func PriceLimitStatus(p *RealtimePriceData) string {
+	if p == nil || p.Price <= 0 || p.Ceiling <= 0 || p.Floor <= 0 {
+		return ""
+	}
+	switch {
+	case p.Price >= p.Ceiling-limitTolerance:
+		return LimitHitCeiling
+	case p.Price <= p.Floor+limitTolerance:
+		return LimitHitFloor
+	case p.RefPrice > 0 && (p.Ceiling-p.Price)/p.RefPrice*100 < ApproachingLimitGap:
+		return LimitApproachingCeiling
+	}
+	return ""
+}
+
+// PriceLimitStocks returns today's polled stocks in one of the given limit states (all states
+// when statuses is empty), ceiling hits first, then by change
+func PriceLimitStocks(statuses []string) []PriceLimitStock {
+	if GlobalRealtimeService == nil {
+		return []PriceLimitStock{}
+	}
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	stocks := make([]PriceLimitStock, 0)
+	for _, p := range GlobalRealtimeService.CachedPrices() {
+		status := PriceLimitStatus(&p)
+		if status == "" || len(wanted) > 0 && !wanted[status] {
+			continue
+		}
+		stock := PriceLimitStock{
+			Code:          p.Code,
+			Status:        status,
+			Price:         p.Price,
+			RefPrice:      p.RefPrice,
+			Ceiling:       p.Ceiling,
+			Floor:         p.Floor,
+			ChangePercent: p.ChangePercent,
+			Volume:        p.Volume,
+			Timestamp:     p.Timestamp,
+		}
+		if entry, ok := StockListEntry(p.Code); ok {
+			stock.Exchange = strings.ToUpper(entry.Floor)
+		}
+		stocks = append(stocks, stock)
+	}
+
+	rank := map[string]int{LimitHitCeiling: 0, LimitApproachingCeiling: 1, LimitHitFloor: 2}
+	sort.Slice(stocks, func(i, j int) bool {
+		a, b := stocks[i], stocks[j]
+		if rank[a.Status] != rank[b.Status] {
+			return rank[a.Status] < rank[b.Status]
+		}
+		if a.ChangePercent != b.ChangePercent {
+			return a.ChangePercent > b.ChangePercent
+		}
+		return a.Code < b.Code
+	})
+	return stocks
+}
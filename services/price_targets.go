@@ -0,0 +1,45 @@
+package services
+
+import (
+	"go_backend_project/services/units"
+
+	"github.com/shopspring/decimal"
+)
+
+var vndPerThousand = decimal.NewFromInt(units.VNDPerThousand)
+
+// OffsetPrice returns a price in thousands of VND moved by pct percent (15 for +15%, -5 for
+// -5%) and rounded to the stock's tick size. Signal targets and stop-losses go through it, so
+// they are computed in decimal and are prices an order can be placed at.
+func OffsetPrice(code string, price, pct float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	vnd := units.Offset(decimal.NewFromFloat(price).Mul(vndPerThousand), decimal.NewFromFloat(pct))
+	return RoundPriceVND(code, vnd).Div(vndPerThousand).InexactFloat64()
+}
+
+// RoundPrice rounds a price in thousands of VND to the stock's tick size
+func RoundPrice(code string, price float64) float64 {
+	return OffsetPrice(code, price, 0)
+}
+
+// RoundPriceVND rounds a price in VND to the tick size of the stock's exchange, halves to the
+// even tick. Codes missing from the stock list follow HOSE.
+func RoundPriceVND(code string, priceVND decimal.Decimal) decimal.Decimal {
+	entry, _ := StockListEntry(code)
+	return units.RoundToTick(priceVND, entry.Floor, InstrumentTypeOf(code))
+}
+
+// TickSizeOf returns the tick size of a stock at a price, both in thousands of VND
+func TickSizeOf(code string, price float64) float64 {
+	entry, _ := StockListEntry(code)
+	vnd := decimal.NewFromFloat(price).Mul(vndPerThousand)
+	return units.TickSize(entry.Floor, InstrumentTypeOf(code), vnd).Div(vndPerThousand).InexactFloat64()
+}
+
+// OnTick reports whether a price in thousands of VND is a valid tick of the stock, i.e. a price
+// an order can be placed at. Zero, for a level that is not set, counts as on tick.
+func OnTick(code string, price float64) bool {
+	return price == 0 || RoundPrice(code, price) == price
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxReportedPriceIssues bounds the issues listed in a validation report
+const maxReportedPriceIssues = 50
+
+// PriceValidationIssue is one problem found in a stock's stored prices
+type PriceValidationIssue struct {
+	Code    string `json:"code"`
+	Date    string `json:"date,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// PriceValidationReport summarizes the checks on the stored daily prices of the active stocks.
+// Stale stocks have no bar for ExpectedDate; invalid ones have an inconsistent latest bar.
+type PriceValidationReport struct {
+	ExpectedDate   string                 `json:"expected_date"`
+	Checked        int                    `json:"checked"`
+	Missing        []string               `json:"missing"`
+	Stale          []string               `json:"stale"`
+	Invalid        []PriceValidationIssue `json:"invalid"`
+	ProblemCount   int                    `json:"problem_count"`
+	ProblemPercent float64                `json:"problem_percent"`
+}
+
+// ValidatePriceData checks that every active stock has a price file whose latest bar is from the
+// last trading day on or before asOf and is internally consistent (positive close inside the
+// day's range and price band). Listed issues are capped; the counts cover every stock.
+func ValidatePriceData(ctx context.Context, asOf time.Time) (*PriceValidationReport, error) {
+	if GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+	stocks, err := LoadStocksFromFile()
+	if err != nil {
+		return nil, err
+	}
+
+	expected := asOf
+	if !MarketCalendar().IsTradingDay(expected) {
+		expected = MarketCalendar().PreviousTradingDay(expected)
+	}
+	report := &PriceValidationReport{
+		ExpectedDate: expected.Format(PriceDateFormat),
+		Missing:      []string{},
+		Stale:        []string{},
+		Invalid:      []PriceValidationIssue{},
+	}
+
+	for _, stock := range stocks {
+		if IsStockInactive(stock.Code) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		priceFile, err := GlobalPriceService.LoadStockPriceContext(ctx, stock.Code)
+		if err != nil || len(priceFile.Prices) == 0 {
+			report.ProblemCount++
+			report.Missing = appendCapped(report.Missing, stock.Code)
+			continue
+		}
+
+		latest := priceFile.Prices[0]
+		if latest.Date < report.ExpectedDate {
+			report.ProblemCount++
+			report.Stale = appendCapped(report.Stale, stock.Code)
+			continue
+		}
+		if problem := priceBarProblem(latest); problem != "" {
+			report.ProblemCount++
+			if len(report.Invalid) < maxReportedPriceIssues {
+				report.Invalid = append(report.Invalid, PriceValidationIssue{Code: stock.Code, Date: latest.Date, Problem: problem})
+			}
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Stale)
+	if report.Checked > 0 {
+		report.ProblemPercent = math.Round(float64(report.ProblemCount)/float64(report.Checked)*10000) / 100
+	}
+	return report, nil
+}
+
+// priceBarProblem describes what is wrong with a daily bar, or returns ""
+func priceBarProblem(bar StockPriceData) string {
+	const tolerance = 1e-6
+	switch {
+	case bar.Close <= 0:
+		return "non-positive close"
+	case bar.High < bar.Low:
+		return "high below low"
+	case bar.Low > 0 && (bar.Close < bar.Low-tolerance || bar.Close > bar.High+tolerance):
+		return "close outside the day's range"
+	case bar.NmVolume < 0:
+		return "negative volume"
+	case bar.CeilingPrice > 0 && bar.Close > bar.CeilingPrice+tolerance:
+		return "close above the ceiling price"
+	case bar.FloorPrice > 0 && bar.Close < bar.FloorPrice-tolerance:
+		return "close below the floor price"
+	}
+	return ""
+}
+
+func appendCapped(list []string, code string) []string {
+	if len(list) >= maxReportedPriceIssues {
+		return list
+	}
+	return append(list, code)
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile event types
+const (
+	ProfileEventMembershipChanged = "profile.membership_changed"
+	ProfileEventBanChanged        = "profile.ban_changed"
+	ProfileEventRoleChanged       = "profile.role_changed"
+	ProfileEventTest              = "profile.test" // sent from the admin API to check the endpoints
+)
+
+const (
+	pubsubPublishURL = "https://pubsub.googleapis.com/v1/%s:publish"
+	pubsubScope      = "https://www.googleapis.com/auth/pubsub"
+
+	// profileEventQueueSize bounds the events waiting for delivery; newer ones are dropped when full
+	profileEventQueueSize = 256
+	// profileEventHistorySize is how many deliveries are kept for the admin API
+	profileEventHistorySize = 100
+)
+
+// profileEventRetryDelays are the waits before each retry of a failed delivery
+var profileEventRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+var pubsubTopicPattern = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
+// ProfileState is the part of a user's profile the CRM and billing systems follow
+type ProfileState struct {
+	Membership          string     `json:"membership,omitempty"`
+	MembershipExpiresAt *time.Time `json:"membership_expires_at,omitempty"`
+	Role                string     `json:"role,omitempty"`
+	IsActive            bool       `json:"is_active"`
+	IsBanned            bool       `json:"is_banned"`
+	BanReason           string     `json:"ban_reason,omitempty"`
+}
+
+// ProfileStateOf returns the followed fields of a Supabase profile
+func ProfileStateOf(profile *UserProfile) ProfileState {
+	return ProfileState{
+		Membership:          profile.Membership,
+		MembershipExpiresAt: profile.MembershipExpiresAt,
+		Role:                profile.Role,
+		IsActive:            profile.IsActive,
+		IsBanned:            profile.IsBanned,
+		BanReason:           profile.BanReason,
+	}
+}
+
+// ProfileFieldChange is the value of a field before and after the change
+type ProfileFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ProfileEvent tells the external systems that an admin changed a user's membership, ban status
+// or role. UserID is the Supabase user ID, the key shared by the profile and the local user.
+type ProfileEvent struct {
+	ID         string                        `json:"id"`
+	Type       string                        `json:"type"`
+	UserID     string                        `json:"user_id"`
+	Email      string                        `json:"email,omitempty"`
+	Changes    map[string]ProfileFieldChange `json:"changes"`
+	Profile    ProfileState                  `json:"profile"`
+	Actor      string                        `json:"actor,omitempty"`
+	OccurredAt time.Time                     `json:"occurred_at"`
+}
+
+// ProfileChangeEvents returns one event per kind of change an actor made between two states of a
+// profile: the membership and its expiry, the ban status (banned, ban reason, active) and the role
+func ProfileChangeEvents(userID, email, actor string, before, after ProfileState) []ProfileEvent {
+	membership := map[string]ProfileFieldChange{}
+	if before.Membership != after.Membership {
+		membership["membership"] = ProfileFieldChange{before.Membership, after.Membership}
+	}
+	if !sameTime(before.MembershipExpiresAt, after.MembershipExpiresAt) {
+		membership["membership_expires_at"] = ProfileFieldChange{before.MembershipExpiresAt, after.MembershipExpiresAt}
+	}
+
+	ban := map[string]ProfileFieldChange{}
+	if before.IsBanned != after.IsBanned {
+		ban["is_banned"] = ProfileFieldChange{before.IsBanned, after.IsBanned}
+	}
+	if before.BanReason != after.BanReason {
+		ban["ban_reason"] = ProfileFieldChange{before.BanReason, after.BanReason}
+	}
+	if before.IsActive != after.IsActive {
+		ban["is_active"] = ProfileFieldChange{before.IsActive, after.IsActive}
+	}
+
+	role := map[string]ProfileFieldChange{}
+	if before.Role != after.Role {
+		role["role"] = ProfileFieldChange{before.Role, after.Role}
+	}
+
+	var events []ProfileEvent
+	for _, kind := range []struct {
+		eventType string
+		changes   map[string]ProfileFieldChange
+	}{
+		{ProfileEventMembershipChanged, membership},
+		{ProfileEventBanChanged, ban},
+		{ProfileEventRoleChanged, role},
+	} {
+		if len(kind.changes) > 0 {
+			events = append(events, ProfileEvent{
+				Type:    kind.eventType,
+				UserID:  userID,
+				Email:   email,
+				Changes: kind.changes,
+				Profile: after,
+				Actor:   actor,
+			})
+		}
+	}
+	return events
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// ProfileEventDelivery is the outcome of sending one event to one target
+type ProfileEventDelivery struct {
+	EventID   string    `json:"event_id"`
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id"`
+	Target    string    `json:"target"`
+	Attempts  int       `json:"attempts"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Delivered time.Time `json:"delivered_at"`
+}
+
+// ProfileEventPublisher delivers profile events to the configured webhooks and Pub/Sub topic in
+// the background, retrying failed deliveries
+type ProfileEventPublisher struct {
+	webhooks   []string
+	secret     string
+	topic      string // projects/<project>/topics/<topic>
+	tokens     *googleTokenSource
+	httpClient *http.Client
+	queue      chan ProfileEvent
+
+	mu         sync.Mutex
+	deliveries []ProfileEventDelivery // oldest first
+}
+
+// GlobalProfileEvents is nil when no profile webhook or topic is configured
+var GlobalProfileEvents *ProfileEventPublisher
+
+// InitProfileEvents enables profile events when PROFILE_WEBHOOK_URLS (comma separated, signed
+// with PROFILE_WEBHOOK_SECRET) or PROFILE_EVENTS_PUBSUB_TOPIC (projects/<project>/topics/<topic>)
+// is set
+func InitProfileEvents() error {
+	publisher := &ProfileEventPublisher{
+		secret:     os.Getenv("PROFILE_WEBHOOK_SECRET"),
+		topic:      strings.TrimSpace(os.Getenv("PROFILE_EVENTS_PUBSUB_TOPIC")),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan ProfileEvent, profileEventQueueSize),
+	}
+	for _, raw := range strings.Split(os.Getenv("PROFILE_WEBHOOK_URLS"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("PROFILE_WEBHOOK_URLS: %q is not an http(s) URL", raw)
+		}
+		publisher.webhooks = append(publisher.webhooks, raw)
+	}
+	if len(publisher.webhooks) > 0 && publisher.secret == "" {
+		return fmt.Errorf("PROFILE_WEBHOOK_SECRET is required with PROFILE_WEBHOOK_URLS")
+	}
+	if publisher.topic != "" {
+		if !pubsubTopicPattern.MatchString(publisher.topic) {
+			return fmt.Errorf("PROFILE_EVENTS_PUBSUB_TOPIC must look like projects/<project>/topics/<topic>")
+		}
+		account, err := loadServiceAccount()
+		if err != nil {
+			return err
+		}
+		publisher.tokens = newGoogleTokenSource(pubsubScope, account, publisher.httpClient)
+	}
+
+	targets := publisher.Targets()
+	if len(targets) == 0 {
+		log.Println("Profile events disabled (set PROFILE_WEBHOOK_URLS or PROFILE_EVENTS_PUBSUB_TOPIC)")
+		return nil
+	}
+	GlobalProfileEvents = publisher
+	go publisher.run()
+	log.Printf("✓ Profile events enabled to %s", strings.Join(targets, ", "))
+	return nil
+}
+
+// Targets lists the webhooks, by host since their URLs may hold tokens, and the Pub/Sub topic
+func (p *ProfileEventPublisher) Targets() []string {
+	var targets []string
+	if p == nil {
+		return targets
+	}
+	for _, webhook := range p.webhooks {
+		targets = append(targets, webhookTarget(webhook))
+	}
+	if p.topic != "" {
+		targets = append(targets, "pubsub:"+p.topic)
+	}
+	return targets
+}
+
+func webhookTarget(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// Publish queues events for delivery, stamping their ID and time. It never blocks: events are
+// dropped and logged when the queue is full. Nil publishers drop the events.
+func (p *ProfileEventPublisher) Publish(events ...ProfileEvent) {
+	if p == nil {
+		return
+	}
+	for _, event := range events {
+		event.ID = newProfileEventID()
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now().UTC()
+		}
+		select {
+		case p.queue <- event:
+		default:
+			log.Printf("Warning: profile event queue full, dropped %s for user %s", event.Type, event.UserID)
+		}
+	}
+}
+
+// Deliveries returns the latest deliveries, newest first
+func (p *ProfileEventPublisher) Deliveries() []ProfileEventDelivery {
+	deliveries := []ProfileEventDelivery{}
+	if p == nil {
+		return deliveries
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.deliveries) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, p.deliveries[i])
+	}
+	return deliveries
+}
+
+// run delivers the queued events one at a time, so each target receives them in order
+func (p *ProfileEventPublisher) run() {
+	for event := range p.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Profile event %s: %v", event.ID, err)
+			continue
+		}
+		for _, webhook := range p.webhooks {
+			p.deliver(event, webhookTarget(webhook), func(ctx context.Context) (bool, error) {
+				return p.postWebhook(ctx, webhook, event, body)
+			})
+		}
+		if p.topic != "" {
+			p.deliver(event, "pubsub:"+p.topic, func(ctx context.Context) (bool, error) {
+				return p.publishPubSub(ctx, event, body)
+			})
+		}
+	}
+}
+
+// deliver sends an event to one target, retrying after profileEventRetryDelays while send
+// reports the failure as retryable, and records the outcome
+func (p *ProfileEventPublisher) deliver(event ProfileEvent, target string, send func(ctx context.Context) (retry bool, err error)) {
+	delivery := ProfileEventDelivery{EventID: event.ID, Type: event.Type, UserID: event.UserID, Target: target}
+	for {
+		delivery.Attempts++
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		retry, err := send(ctx)
+		cancel()
+		if err == nil {
+			delivery.Success, delivery.Error = true, ""
+			break
+		}
+		delivery.Error = err.Error()
+		if !retry || delivery.Attempts > len(profileEventRetryDelays) {
+			log.Printf("Profile event %s to %s failed after %d attempts: %v", event.ID, target, delivery.Attempts, err)
+			break
+		}
+		time.Sleep(profileEventRetryDelays[delivery.Attempts-1])
+	}
+	delivery.Delivered = time.Now()
+
+	p.mu.Lock()
+	p.deliveries = append(p.deliveries, delivery)
+	if len(p.deliveries) > profileEventHistorySize {
+		p.deliveries = p.deliveries[len(p.deliveries)-profileEventHistorySize:]
+	}
+	p.mu.Unlock()
+}
+
+// postWebhook posts the event signed like "sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))"
+// in X-CPLS-Signature, timestamp being X-CPLS-Timestamp, so receivers can check it and reject
+// replays. Network errors, 429 and 5xx responses are retried.
+func (p *ProfileEventPublisher) postWebhook(ctx context.Context, webhook string, event ProfileEvent, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(p.secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CPLS-Event", event.Type)
+	req.Header.Set("X-CPLS-Event-ID", event.ID)
+	req.Header.Set("X-CPLS-Timestamp", timestamp)
+	req.Header.Set("X-CPLS-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		// The URL may hold a token, which must not end up in the deliveries
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+}
+
+// publishPubSub publishes the event with its type, ID and user as message attributes
+func (p *ProfileEventPublisher) publishPubSub(ctx context.Context, event ProfileEvent, body []byte) (bool, error) {
+	token, err := p.tokens.token(ctx)
+	if err != nil {
+		return true, err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"messages": []map[string]interface{}{{
+			"data":       base64.StdEncoding.EncodeToString(body),
+			"attributes": map[string]string{"type": event.Type, "user_id": event.UserID, "event_id": event.ID},
+		}},
+	})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(pubsubPublishURL, p.topic), bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("Pub/Sub returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
+
+func newProfileEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Firebase Cloud Messaging HTTP v1 API
+const (
+	fcmSendURL = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCM error codes that mean the registration token will never work again
+var staleTokenErrors = map[string]bool{
+	"UNREGISTERED":       true,
+	"SENDER_ID_MISMATCH": true,
+}
+
+// PushService sends notifications to users' devices through FCM
+type PushService struct {
+	projectID  string
+	tokens     *googleTokenSource
+	httpClient *http.Client
+}
+
+// GlobalPushService is nil when push delivery is not configured
+var GlobalPushService *PushService
+
+// InitPushService enables FCM delivery when FCM_PROJECT_ID is set or a service account key is
+// given with GOOGLE_APPLICATION_CREDENTIALS
+func InitPushService() error {
+	service := &PushService{
+		projectID:  os.Getenv("FCM_PROJECT_ID"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	account, err := loadServiceAccount()
+	if err != nil {
+		return err
+	}
+	if account != nil && service.projectID == "" {
+		service.projectID = account.ProjectID
+	}
+	service.tokens = newGoogleTokenSource(fcmScope, account, service.httpClient)
+	if service.projectID == "" {
+		log.Println("Push notifications disabled (set FCM_PROJECT_ID or GOOGLE_APPLICATION_CREDENTIALS)")
+		return nil
+	}
+
+	GlobalPushService = service
+	log.Printf("✓ Push notifications enabled for Firebase project %s", service.projectID)
+	return nil
+}
+
+// fcmMessage is the message resource of the FCM v1 send API
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification map[string]string `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      map[string]string `json:"android,omitempty"`
+	APNS         *fcmAPNSConfig    `json:"apns,omitempty"`
+	Webpush      *fcmWebpushConfig `json:"webpush,omitempty"`
+}
+
+type fcmAPNSConfig struct {
+	Headers map[string]string `json:"headers"`
+}
+
+type fcmWebpushConfig struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// Deliver pushes a notification to all devices of its user unless they turned push off or are in
+// their quiet hours. Tokens FCM reports as unregistered are deleted.
+func (s *PushService) Deliver(db *gorm.DB, notification *models.Notification) {
+	var pref models.NotificationPreference
+	if err := db.Where("user_id = ?", notification.UserID).First(&pref).Error; err == nil {
+		if !pref.PushEnabled || InQuietHours(&pref, time.Now()) {
+			return
+		}
+	}
+
+	var devices []models.DeviceToken
+	if err := db.Where("user_id = ?", notification.UserID).Find(&devices).Error; err != nil || len(devices) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	token, err := s.token(ctx)
+	if err != nil {
+		log.Printf("Push delivery skipped: %v", err)
+		return
+	}
+
+	// One collapse key per notification category: a newer notification of the same category
+	// replaces an undelivered older one
+	collapseKey := notification.Category
+	data := map[string]string{
+		"notification_id": fmt.Sprint(notification.ID),
+		"category":        notification.Category,
+	}
+	for _, device := range devices {
+		msg := fcmMessage{
+			Token:        device.Token,
+			Notification: map[string]string{"title": notification.Title, "body": notification.Message},
+			Data:         data,
+		}
+		switch device.Platform {
+		case models.DevicePlatformIOS:
+			msg.APNS = &fcmAPNSConfig{Headers: map[string]string{"apns-collapse-id": collapseKey}}
+		case models.DevicePlatformWeb:
+			msg.Webpush = &fcmWebpushConfig{Headers: map[string]string{"Topic": collapseKey}}
+		default:
+			msg.Android = map[string]string{"collapse_key": collapseKey}
+		}
+
+		errorCode, err := s.send(ctx, token, msg)
+		if err == nil {
+			continue
+		}
+		if staleTokenErrors[errorCode] {
+			db.Delete(&device)
+			log.Printf("Pruned stale push token %d of user %s (%s)", device.ID, device.UserID, errorCode)
+			continue
+		}
+		log.Printf("Push to device %d failed: %v", device.ID, err)
+	}
+}
+
+// send posts one message, returning the FCM error code when it was rejected
+func (s *PushService) send(ctx context.Context, accessToken string, msg fcmMessage) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{"message": msg})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmSendURL, s.projectID), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return "", nil
+	}
+
+	var errResp struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(raw, &errResp)
+	code := errResp.Error.Status
+	for _, detail := range errResp.Error.Details {
+		if detail.ErrorCode != "" {
+			code = detail.ErrorCode
+		}
+	}
+	return code, fmt.Errorf("FCM returned HTTP %d: %s %s", resp.StatusCode, code, errResp.Error.Message)
+}
+
+// token returns an OAuth access token for FCM
+func (s *PushService) token(ctx context.Context) (string, error) {
+	return s.tokens.token(ctx)
+}
+
+// InQuietHours reports whether t falls in the preference's quiet hours, in the user's timezone
+// (market time when unset)
+func InQuietHours(pref *models.NotificationPreference, t time.Time) bool {
+	start, okStart := parseClock(pref.QuietHoursStart)
+	end, okEnd := parseClock(pref.QuietHoursEnd)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+
+	loc := MarketCalendar().Location()
+	if pref.Timezone != "" {
+		if userLoc, err := time.LoadLocation(pref.Timezone); err == nil {
+			loc = userLoc
+		}
+	}
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// ValidClock reports whether s is an HH:MM time of day
+func ValidClock(s string) bool {
+	_, ok := parseClock(s)
+	return ok
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
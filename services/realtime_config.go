@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const RealtimeConfigFile = "data/realtime_config.json"
+
+// Membership tiers used for realtime subscription limits
+const (
+	RealtimeTierAdmin = "admin"
+	RealtimeTierFree  = "free"
+)
+
+// RealtimeChannels are the message channels a WebSocket client can subscribe to
+var RealtimeChannels = []string{"prices", "indicators", "top_rs", IndicesChannel}
+
+// RealtimeConfig holds WebSocket keepalive, queue and subscription limit settings
+type RealtimeConfig struct {
+	PingIntervalSec int            `json:"ping_interval_sec"`
+	PongTimeoutSec  int            `json:"pong_timeout_sec"`
+	WriteTimeoutSec int            `json:"write_timeout_sec"`
+	SendQueueSize   int            `json:"send_queue_size"` // oldest messages are dropped beyond this
+	TierLimits      map[string]int `json:"tier_limits"`     // max subscribed codes per membership tier, 0 = unlimited
+}
+
+// DefaultRealtimeConfig returns the default realtime configuration
+func DefaultRealtimeConfig() RealtimeConfig {
+	return RealtimeConfig{
+		PingIntervalSec: int(WebSocketPingInterval.Seconds()),
+		PongTimeoutSec:  int(WebSocketPongTimeout.Seconds()),
+		WriteTimeoutSec: int(WebSocketWriteTimeout.Seconds()),
+		SendQueueSize:   256,
+		TierLimits: map[string]int{
+			RealtimeTierFree:  20,
+			"basic":           50,
+			"premium":         200,
+			"enterprise":      500,
+			RealtimeTierAdmin: 0,
+		},
+	}
+}
+
+// LoadRealtimeConfig loads the realtime config file, falling back to defaults
+func LoadRealtimeConfig() RealtimeConfig {
+	cfg := DefaultRealtimeConfig()
+	data, err := os.ReadFile(RealtimeConfigFile)
+	if err != nil {
+		return cfg
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return DefaultRealtimeConfig()
+	}
+	return cfg
+}
+
+// SaveRealtimeConfig writes the realtime config file
+func SaveRealtimeConfig(cfg RealtimeConfig) error {
+	if err := os.MkdirAll(filepath.Dir(RealtimeConfigFile), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(RealtimeConfigFile, data, 0644)
+}
+
+// Validate checks that timeouts and queue sizes are usable
+func (cfg RealtimeConfig) Validate() error {
+	if cfg.PingIntervalSec < 1 || cfg.PongTimeoutSec <= cfg.PingIntervalSec {
+		return fmt.Errorf("pong_timeout_sec must be greater than ping_interval_sec")
+	}
+	if cfg.WriteTimeoutSec < 1 {
+		return fmt.Errorf("write_timeout_sec must be positive")
+	}
+	if cfg.SendQueueSize < 1 || cfg.SendQueueSize > 10000 {
+		return fmt.Errorf("send_queue_size must be between 1 and 10000")
+	}
+	for tier, limit := range cfg.TierLimits {
+		if limit < 0 {
+			return fmt.Errorf("tier limit for %s cannot be negative", tier)
+		}
+	}
+	return nil
+}
+
+// SubscriptionLimit returns the max subscribed codes for a tier; unknown tiers get the free limit
+func (cfg RealtimeConfig) SubscriptionLimit(tier string) int {
+	if limit, ok := cfg.TierLimits[strings.ToLower(tier)]; ok {
+		return limit
+	}
+	return cfg.TierLimits[RealtimeTierFree]
+}
+
+func (cfg RealtimeConfig) pingInterval() time.Duration {
+	return time.Duration(cfg.PingIntervalSec) * time.Second
+}
+
+func (cfg RealtimeConfig) pongTimeout() time.Duration {
+	return time.Duration(cfg.PongTimeoutSec) * time.Second
+}
+
+func (cfg RealtimeConfig) writeTimeout() time.Duration {
+	return time.Duration(cfg.WriteTimeoutSec) * time.Second
+}
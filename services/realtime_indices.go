@@ -0,0 +1,175 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndicesChannel carries index values and exchange breadth
+const IndicesChannel = "indices"
+
+// IndexPollInterval is the cadence of the indices channel, independent of the price poll interval
+const IndexPollInterval = 5 * time.Second
+
+// RealtimeIndexCodes are the indices streamed on the indices channel, with their exchange
+var RealtimeIndexCodes = []struct {
+	Code     string
+	Exchange string
+}{
+	{MarketIndexCode, ExchangeHOSE},
+	{"VN30", ExchangeHOSE},
+	{"HNX", ExchangeHNX},
+}
+
+// RealtimeIndex is the latest value of a market index
+type RealtimeIndex struct {
+	Code          string  `json:"code"`
+	Exchange      string  `json:"exchange"`
+	Value         float64 `json:"value"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+	Open          float64 `json:"open"`
+	High          float64 `json:"high"`
+	Low           float64 `json:"low"`
+	Volume        float64 `json:"volume"`
+	TradingValue  float64 `json:"trading_value"`
+	Timestamp     string  `json:"timestamp"`
+}
+
+// ExchangeBreadth counts the stocks of an exchange by their move on the day
+type ExchangeBreadth struct {
+	Exchange  string `json:"exchange"`
+	Advancers int    `json:"advancers"`
+	Decliners int    `json:"decliners"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// IndicesSnapshot is the payload of an indices message
+type IndicesSnapshot struct {
+	Indices []RealtimeIndex   `json:"indices"`
+	Breadth []ExchangeBreadth `json:"breadth"`
+	Session string            `json:"session"`
+}
+
+// indicesCache keeps the last snapshot for clients that ask for it between broadcasts
+type indicesCache struct {
+	mu       sync.RWMutex
+	snapshot *IndicesSnapshot
+}
+
+var lastIndices indicesCache
+
+// fetchAndBroadcastIndices polls the index values and breadth while any exchange is matching
+// orders and streams them on the indices channel
+func (s *RealtimePriceService) fetchAndBroadcastIndices() {
+	cal := MarketCalendar()
+	now := time.Now()
+	open := false
+	for _, exchange := range Exchanges {
+		if cal.ExchangeStatusAt(exchange, now).IsOpen {
+			open = true
+			break
+		}
+	}
+	if !open || GlobalPriceService == nil || s.GetClientCount() == 0 {
+		return
+	}
+
+	snapshot := &IndicesSnapshot{Session: cal.SessionAt(now), Breadth: exchangeBreadth()}
+	for _, index := range RealtimeIndexCodes {
+		resp, err := GlobalPriceService.FetchStockPrice(index.Code, 1)
+		if err != nil || len(resp.Data) == 0 {
+			if err != nil {
+				log.Printf("Error fetching index %s: %v", index.Code, err)
+			}
+			continue
+		}
+		data := resp.Data[0]
+		snapshot.Indices = append(snapshot.Indices, RealtimeIndex{
+			Code:          index.Code,
+			Exchange:      index.Exchange,
+			Value:         data.Close,
+			Change:        data.Change,
+			ChangePercent: data.PctChange,
+			Open:          data.Open,
+			High:          data.High,
+			Low:           data.Low,
+			Volume:        data.NmVolume,
+			TradingValue:  data.NmValue,
+			Timestamp:     now.Format(time.RFC3339),
+		})
+	}
+	if len(snapshot.Indices) == 0 {
+		return
+	}
+
+	lastIndices.mu.Lock()
+	lastIndices.snapshot = snapshot
+	lastIndices.mu.Unlock()
+
+	s.broadcast <- WebSocketMessage{
+		Type: IndicesChannel,
+		Data: snapshot,
+		Time: now.Format(time.RFC3339),
+	}
+}
+
+// exchangeBreadth counts advancing, declining and unchanged stocks per exchange from the daily
+// indicator summary, using realtime prices where they have been polled
+func exchangeBreadth() []ExchangeBreadth {
+	counts := make(map[string]*ExchangeBreadth, len(Exchanges))
+	breadth := make([]ExchangeBreadth, len(Exchanges))
+	for i, exchange := range Exchanges {
+		breadth[i].Exchange = exchange
+		counts[exchange] = &breadth[i]
+	}
+	if GlobalIndicatorService == nil {
+		return breadth
+	}
+	summary, err := GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return breadth
+	}
+
+	for code, ind := range summary.Stocks {
+		if ind == nil || IsStockInactive(code) || InstrumentTypeOf(code) != InstrumentTypeStock {
+			continue
+		}
+		stock, ok := StockListEntry(code)
+		if !ok {
+			continue
+		}
+		b := counts[strings.ToUpper(stock.Floor)]
+		if b == nil {
+			continue
+		}
+
+		change := ind.PriceChange
+		if p := GlobalRealtimeService.LatestPrice(code); p != nil && p.Price > 0 {
+			change = p.ChangePercent
+		}
+		switch {
+		case change > 0:
+			b.Advancers++
+		case change < 0:
+			b.Decliners++
+		default:
+			b.Unchanged++
+		}
+	}
+	return breadth
+}
+
+// sendIndicesToClient sends the last indices snapshot to a specific client
+func (s *RealtimePriceService) sendIndicesToClient(c *Client) {
+	lastIndices.mu.RLock()
+	snapshot := lastIndices.snapshot
+	lastIndices.mu.RUnlock()
+	if snapshot == nil {
+		c.sendMessage("error", map[string]string{"message": "No index data yet; indices are polled during market hours"})
+		return
+	}
+	c.sendMessage(IndicesChannel, snapshot)
+}
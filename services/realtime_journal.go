@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RealtimeJournalDir holds the append-only logs of polled realtime prices, one file per trading day
+const RealtimeJournalDir = "data/realtime"
+
+// DefaultRealtimeJournalInterval is how often polled prices are appended to the journal unless
+// REALTIME_PERSIST_INTERVAL_SEC is set; 0 disables the journal
+const DefaultRealtimeJournalInterval = 10 * time.Second
+
+// RealtimeJournalStatus describes the realtime price journal
+type RealtimeJournalStatus struct {
+	Enabled     bool   `json:"enabled"`
+	IntervalSec int    `json:"interval_sec,omitempty"`
+	File        string `json:"file,omitempty"`
+	Pending     int    `json:"pending"`   // prices polled since the last flush
+	Recovered   int    `json:"recovered"` // prices restored on startup
+	Written     int64  `json:"written"`   // prices appended since startup
+	LastFlush   string `json:"last_flush,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// priceJournal appends the prices polled since the last flush to the log of their trading day,
+// so the intraday price cache survives instance restarts. A crash loses at most one interval,
+// and a line cut short by it is skipped on recovery.
+type priceJournal struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	pending   map[string]RealtimePriceData
+	recovered int
+	written   int64
+	lastFlush time.Time
+	lastErr   error
+}
+
+// newPriceJournalFromEnv creates the journal with the interval from the environment, or nil
+// when it is disabled
+func newPriceJournalFromEnv() *priceJournal {
+	interval := DefaultRealtimeJournalInterval
+	if v, err := strconv.Atoi(os.Getenv("REALTIME_PERSIST_INTERVAL_SEC")); err == nil && v >= 0 {
+		interval = time.Duration(v) * time.Second
+	}
+	if interval == 0 {
+		log.Println("Realtime price journal disabled")
+		return nil
+	}
+	return &priceJournal{interval: interval, pending: make(map[string]RealtimePriceData)}
+}
+
+// journalFile is the log of the trading day date (YYYY-MM-DD)
+func journalFile(date string) string {
+	return filepath.Join(RealtimeJournalDir, "prices-"+date+".jsonl")
+}
+
+// priceDate is the trading day a polled price belongs to
+func priceDate(price RealtimePriceData) string {
+	loc := MarketCalendar().Location()
+	t, err := time.Parse(time.RFC3339, price.Timestamp)
+	if err != nil {
+		return time.Now().In(loc).Format(PriceDateFormat)
+	}
+	return t.In(loc).Format(PriceDateFormat)
+}
+
+// record queues a polled price for the next flush
+func (j *priceJournal) record(price *RealtimePriceData) {
+	if j == nil || price == nil {
+		return
+	}
+	j.mu.Lock()
+	j.pending[price.Code] = *price
+	j.mu.Unlock()
+}
+
+// run flushes the journal every interval until stop is closed
+func (j *priceJournal) run(stop chan struct{}) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			j.flush()
+		}
+	}
+}
+
+// flush appends the pending prices to their day's log in one write per file
+func (j *priceJournal) flush() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.pending) == 0 {
+		return nil
+	}
+
+	byDate := make(map[string]*bytes.Buffer)
+	for _, price := range j.pending {
+		line, err := json.Marshal(price)
+		if err != nil {
+			continue
+		}
+		date := priceDate(price)
+		buf, ok := byDate[date]
+		if !ok {
+			buf = &bytes.Buffer{}
+			byDate[date] = buf
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(RealtimeJournalDir, 0755); err != nil {
+		j.lastErr = err
+		return err
+	}
+	for date, buf := range byDate {
+		if err := appendFileSync(journalFile(date), buf.Bytes()); err != nil {
+			j.lastErr = err
+			log.Printf("Failed to append realtime prices to the journal: %v", err)
+			return err // keep the prices pending for the next flush
+		}
+	}
+
+	j.written += int64(len(j.pending))
+	j.pending = make(map[string]RealtimePriceData)
+	j.lastFlush = time.Now()
+	j.lastErr = nil
+	return nil
+}
+
+// appendFileSync appends data to path and syncs it to disk
+func appendFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recover returns the latest journaled price of every code polled today, compacting today's log
+// to those prices and removing the logs of previous days
+func (j *priceJournal) recover() map[string]*RealtimePriceData {
+	if j == nil {
+		return nil
+	}
+	today := time.Now().In(MarketCalendar().Location()).Format(PriceDateFormat)
+	removeStaleJournals(today)
+
+	path := journalFile(today)
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read the realtime price journal: %v", err)
+		}
+		return nil
+	}
+	defer f.Close()
+
+	prices := make(map[string]*RealtimePriceData)
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var price RealtimePriceData
+		if err := json.Unmarshal(scanner.Bytes(), &price); err != nil || price.Code == "" {
+			skipped++
+			continue
+		}
+		prices[price.Code] = &price // later lines are newer
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Realtime price journal read stopped early: %v", err)
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+
+	var compacted bytes.Buffer
+	for _, price := range prices {
+		if line, err := json.Marshal(price); err == nil {
+			compacted.Write(line)
+			compacted.WriteByte('\n')
+		}
+	}
+	if err := writeFileAtomic(path, compacted.Bytes(), 0644); err != nil {
+		log.Printf("Failed to compact the realtime price journal: %v", err)
+	}
+
+	j.mu.Lock()
+	j.recovered = len(prices)
+	j.mu.Unlock()
+	if skipped > 0 {
+		log.Printf("Skipped %d unreadable lines of the realtime price journal", skipped)
+	}
+	log.Printf("Recovered %d realtime prices from %s", len(prices), path)
+	return prices
+}
+
+// removeStaleJournals deletes the logs of trading days before today
+func removeStaleJournals(today string) {
+	files, err := os.ReadDir(RealtimeJournalDir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, "prices-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		if date := strings.TrimSuffix(strings.TrimPrefix(name, "prices-"), ".jsonl"); date < today {
+			os.Remove(filepath.Join(RealtimeJournalDir, name))
+		}
+	}
+}
+
+func (j *priceJournal) status() RealtimeJournalStatus {
+	if j == nil {
+		return RealtimeJournalStatus{}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := RealtimeJournalStatus{
+		Enabled:     true,
+		IntervalSec: int(j.interval.Seconds()),
+		File:        journalFile(time.Now().In(MarketCalendar().Location()).Format(PriceDateFormat)),
+		Pending:     len(j.pending),
+		Recovered:   j.recovered,
+		Written:     j.written,
+	}
+	if !j.lastFlush.IsZero() {
+		status.LastFlush = j.lastFlush.Format(time.RFC3339)
+	}
+	if j.lastErr != nil {
+		status.LastError = j.lastErr.Error()
+	}
+	return status
+}
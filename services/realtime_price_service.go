@@ -7,6 +7,7 @@ import (
 	"math"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,9 +20,11 @@ const (
 	WebSocketWriteTimeout = 10 * time.Second
 	WebSocketPongTimeout  = 60 * time.Second
 	WebSocketPingInterval = 30 * time.Second
+	WebSocketReadLimit    = 4096 // max size of a client protocol message
 	DefaultPollInterval   = 5 * time.Second
 	PriceFetchBatchSize   = 20
 	PriceFetchBatchDelay  = 100 * time.Millisecond
+	RealtimeIdleStopDelay = 60 * time.Second // stop polling after the last client has been gone this long
 )
 
 // RealtimePriceData represents realtime price data
@@ -35,6 +38,8 @@ type RealtimePriceData struct {
 	Low           float64 `json:"low"`
 	Open          float64 `json:"open"`
 	RefPrice      float64 `json:"ref_price"`
+	Ceiling       float64 `json:"ceiling"`
+	Floor         float64 `json:"floor"`
 	Timestamp     string  `json:"timestamp"`
 }
 
@@ -57,16 +62,22 @@ type RealtimeIndicators struct {
 
 // WebSocketMessage represents a message to broadcast
 type WebSocketMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
-	Time string      `json:"time"`
+	Type    string      `json:"type"`
+	Channel string      `json:"channel,omitempty"` // set for per-code channels such as depth:VNM
+	Data    interface{} `json:"data"`
+	Time    string      `json:"time"`
 }
 
 // Client represents a WebSocket client
 type Client struct {
 	conn       *websocket.Conn
-	send       chan []byte
-	subscribed map[string]bool
+	queue      *sendQueue
+	subscribed map[string]bool // stock codes
+	depth      map[string]bool // codes subscribed on depth:<code>
+	channels   map[string]bool
+	tier       string
+	limit      int // max subscribed codes, 0 = unlimited
+	config     RealtimeConfig
 	mu         sync.RWMutex
 }
 
@@ -88,10 +99,20 @@ type RealtimePriceService struct {
 	indicatorCache *IndicatorSummaryFile
 	indicatorMu    sync.RWMutex
 	lastCacheTime  time.Time
+	journal        *priceJournal // nil when REALTIME_PERSIST_INTERVAL_SEC=0
+
+	// Connection config (keepalive, queue size, tier limits)
+	config RealtimeConfig
 
 	// Polling config
 	pollingInterval time.Duration
-	stockCodes      []string
+	stockCodes      []string       // codes requested explicitly via StartPolling
+	subscriptions   map[string]int // code -> number of clients subscribed
+	depthSubs       map[string]int // code -> number of clients subscribed to depth
+	depth           *depthTracker
+	depthClient     *http.Client
+	autoStarted     bool           // polling was started by a client subscription
+	idleTimer       *time.Timer
 }
 
 // Global realtime service
@@ -113,8 +134,22 @@ func InitRealtimePriceService() error {
 			},
 		},
 		priceCache:      make(map[string]*RealtimePriceData),
+		subscriptions:   make(map[string]int),
+		depthSubs:       make(map[string]int),
+		depth:           newDepthTracker(),
+		depthClient:     &http.Client{Timeout: 10 * time.Second},
+		config:          LoadRealtimeConfig(),
 		pollingInterval: DefaultPollInterval,
 		stopChan:        make(chan struct{}),
+		journal:         newPriceJournalFromEnv(),
+	}
+
+	// Restore the prices polled today before a restart
+	if journal := GlobalRealtimeService.journal; journal != nil {
+		if prices := journal.recover(); len(prices) > 0 {
+			GlobalRealtimeService.priceCache = prices
+		}
+		go journal.run(GlobalRealtimeService.shutdown)
 	}
 
 	// Start the hub
@@ -128,11 +163,12 @@ func InitRealtimePriceService() error {
 func (s *RealtimePriceService) Shutdown() {
 	s.StopPolling()
 	close(s.shutdown)
+	s.journal.flush()
 
 	// Close all client connections
 	s.mu.Lock()
 	for client := range s.clients {
-		close(client.send)
+		client.queue.close()
 		client.conn.Close()
 	}
 	s.clients = make(map[*Client]bool)
@@ -160,15 +196,15 @@ func (s *RealtimePriceService) run() {
 				continue
 			}
 			s.clients[client] = true
+			s.cancelIdleStopLocked()
 			clientCount := len(s.clients)
 			s.mu.Unlock()
-			log.Printf("WebSocket client connected. Total clients: %d", clientCount)
+			log.Printf("WebSocket client connected (tier: %s). Total clients: %d", client.tier, clientCount)
 
 		case client := <-s.unregister:
 			s.mu.Lock()
 			if _, ok := s.clients[client]; ok {
-				delete(s.clients, client)
-				close(client.send)
+				s.removeClientLocked(client)
 			}
 			clientCount := len(s.clients)
 			s.mu.Unlock()
@@ -181,31 +217,34 @@ func (s *RealtimePriceService) run() {
 				continue
 			}
 
-			s.mu.Lock()
-			deadClients := make([]*Client, 0)
+			// Slow clients lose their oldest queued messages instead of being disconnected
+			s.mu.RLock()
 			for client := range s.clients {
-				select {
-				case client.send <- data:
-				default:
-					// Client buffer full, mark for removal
-					deadClients = append(deadClients, client)
+				if payload := client.render(message, data); payload != nil {
+					client.queue.push(payload)
 				}
 			}
-			// Remove dead clients
-			for _, client := range deadClients {
-				delete(s.clients, client)
-				close(client.send)
-			}
-			s.mu.Unlock()
+			s.mu.RUnlock()
 		}
 	}
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles admin WebSocket connections, which have no subscription limit
 func (s *RealtimePriceService) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.HandleWebSocketForTier(w, r, RealtimeTierAdmin)
+}
+
+// HandleWebSocketForTier handles WebSocket connections with the subscription limit of a membership tier
+func (s *RealtimePriceService) HandleWebSocketForTier(w http.ResponseWriter, r *http.Request, tier string) {
+	if !SubsystemEnabled(SubsystemRealtime) {
+		http.Error(w, "Realtime prices are disabled", http.StatusServiceUnavailable)
+		return
+	}
+
 	// Check if at capacity before upgrading
 	s.mu.RLock()
 	atCapacity := len(s.clients) >= MaxWebSocketClients
+	cfg := s.config
 	s.mu.RUnlock()
 
 	if atCapacity {
@@ -219,10 +258,22 @@ func (s *RealtimePriceService) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if tier == "" {
+		tier = RealtimeTierFree
+	}
+
 	client := &Client{
 		conn:       conn,
-		send:       make(chan []byte, 256),
+		queue:      newSendQueue(cfg.SendQueueSize),
 		subscribed: make(map[string]bool),
+		depth:      make(map[string]bool),
+		channels:   make(map[string]bool),
+		tier:       tier,
+		limit:      cfg.SubscriptionLimit(tier),
+		config:     cfg,
+	}
+	for _, channel := range RealtimeChannels {
+		client.channels[channel] = true
 	}
 
 	s.register <- client
@@ -231,9 +282,9 @@ func (s *RealtimePriceService) HandleWebSocket(w http.ResponseWriter, r *http.Re
 	go client.readPump(s)
 }
 
-// writePump writes messages to the WebSocket connection
+// writePump writes queued messages and keepalive pings to the WebSocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(WebSocketPingInterval)
+	ticker := time.NewTicker(c.config.pingInterval())
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -241,19 +292,21 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteTimeout))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+		case <-c.queue.notify:
+			for _, message := range c.queue.drain() {
+				c.conn.SetWriteDeadline(time.Now().Add(c.config.writeTimeout()))
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
 			}
 
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				return
-			}
+		case <-c.queue.done:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.writeTimeout()))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteTimeout))
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.writeTimeout()))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -261,17 +314,22 @@ func (c *Client) writePump() {
 	}
 }
 
-// readPump reads messages from the WebSocket connection
+// readPump reads protocol messages from the WebSocket connection:
+//
+//	{"action": "subscribe", "codes": ["VNM"], "channels": ["prices", "depth:VNM"]}
+//	{"action": "unsubscribe", "codes": ["VNM"], "channels": ["top_rs"]}
+//	{"action": "list"} | {"action": "ping"} | {"action": "get_top_rs"} | {"action": "get_indices"}
 func (c *Client) readPump(s *RealtimePriceService) {
 	defer func() {
 		s.unregister <- c
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
-	c.conn.SetReadDeadline(time.Now().Add(WebSocketPongTimeout))
+	pongTimeout := c.config.pongTimeout()
+	c.conn.SetReadLimit(WebSocketReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(WebSocketPongTimeout))
+		c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
 		return nil
 	})
 
@@ -285,47 +343,319 @@ func (c *Client) readPump(s *RealtimePriceService) {
 		}
 
 		var cmd struct {
-			Action string   `json:"action"`
-			Codes  []string `json:"codes"`
+			Action   string   `json:"action"`
+			Codes    []string `json:"codes"`
+			Channels []string `json:"channels"`
 		}
 		if err := json.Unmarshal(message, &cmd); err != nil {
+			c.sendMessage("error", map[string]string{"message": "Invalid message format"})
 			continue
 		}
 
 		switch cmd.Action {
 		case "subscribe":
-			c.mu.Lock()
-			for _, code := range cmd.Codes {
-				c.subscribed[code] = true
+			channels, depthCodes := splitDepthChannels(cmd.Channels)
+			added, rejected := c.subscribe(c.subscribed, cmd.Codes)
+			addedDepth, rejectedDepth := c.subscribe(c.depth, depthCodes)
+			rejectedChannels := c.setChannels(channels, true)
+			s.addSubscriptions(s.subscriptions, added)
+			s.addSubscriptions(s.depthSubs, addedDepth)
+			for _, channel := range channels {
+				if channel == IndicesChannel {
+					s.ensurePolling()
+				}
 			}
-			c.mu.Unlock()
-		case "unsubscribe":
-			c.mu.Lock()
-			for _, code := range cmd.Codes {
-				delete(c.subscribed, code)
+			for _, code := range rejectedDepth {
+				rejected = append(rejected, DepthChannelPrefix+code)
+			}
+
+			ack := c.subscriptionState()
+			if len(rejected) > 0 {
+				ack["rejected"] = rejected
+				ack["error"] = fmt.Sprintf("Subscription limit of %d codes reached for %s tier", c.limit, c.tier)
+			}
+			if len(rejectedChannels) > 0 {
+				ack["rejected_channels"] = rejectedChannels
 			}
-			c.mu.Unlock()
+			c.sendMessage("subscribed", ack)
+		case "unsubscribe":
+			channels, depthCodes := splitDepthChannels(cmd.Channels)
+			c.setChannels(channels, false)
+			s.removeSubscriptions(s.subscriptions, c.unsubscribe(c.subscribed, cmd.Codes))
+			s.removeSubscriptions(s.depthSubs, c.unsubscribe(c.depth, depthCodes))
+			c.sendMessage("unsubscribed", c.subscriptionState())
+		case "list":
+			c.sendMessage("subscriptions", c.subscriptionState())
+		case "ping":
+			c.sendMessage("pong", nil)
 		case "get_top_rs":
 			s.sendTopRSToClient(c)
+		case "get_indices":
+			s.sendIndicesToClient(c)
+		default:
+			c.sendMessage("error", map[string]string{"message": "Unknown action: " + cmd.Action})
+		}
+	}
+}
+
+// subscribe adds codes to set (price codes or depth codes) up to the client's tier limit,
+// which covers both sets, returning added and rejected codes
+func (c *Client) subscribe(set map[string]bool, codes []string) (added, rejected []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, code := range normalizeCodes(codes) {
+		if set[code] {
+			continue
+		}
+		if c.limit > 0 && len(c.subscribed)+len(c.depth) >= c.limit {
+			rejected = append(rejected, code)
+			continue
+		}
+		set[code] = true
+		added = append(added, code)
+	}
+	return added, rejected
+}
+
+// unsubscribe removes codes from set, returning the ones that were subscribed
+func (c *Client) unsubscribe(set map[string]bool, codes []string) (removed []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, code := range normalizeCodes(codes) {
+		if set[code] {
+			delete(set, code)
+			removed = append(removed, code)
+		}
+	}
+	return removed
+}
+
+// setChannels enables or disables channels, returning unknown channel names
+func (c *Client) setChannels(channels []string, enabled bool) (unknown []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, channel := range channels {
+		if !isRealtimeChannel(channel) {
+			unknown = append(unknown, channel)
+			continue
+		}
+		c.channels[channel] = enabled
+	}
+	return unknown
+}
+
+// subscriptionState returns the client's current codes, channels and limit
+func (c *Client) subscriptionState() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	codes := make([]string, 0, len(c.subscribed))
+	for code := range c.subscribed {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	channels := make([]string, 0, len(c.channels)+len(c.depth))
+	for _, channel := range RealtimeChannels {
+		if c.channels[channel] {
+			channels = append(channels, channel)
+		}
+	}
+	depthChannels := make([]string, 0, len(c.depth))
+	for code := range c.depth {
+		depthChannels = append(depthChannels, DepthChannelPrefix+code)
+	}
+	sort.Strings(depthChannels)
+	channels = append(channels, depthChannels...)
+
+	return map[string]interface{}{
+		"codes":    codes,
+		"channels": channels,
+		"tier":     c.tier,
+		"limit":    c.limit,
+		"dropped":  c.queue.droppedCount(),
+	}
+}
+
+// render returns the payload of a broadcast for this client, or nil if it is not subscribed.
+// Prices and indicators are narrowed to the client's codes when it has subscribed to any.
+func (c *Client) render(message WebSocketMessage, full []byte) []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if strings.HasPrefix(message.Channel, DepthChannelPrefix) {
+		if c.depth[strings.TrimPrefix(message.Channel, DepthChannelPrefix)] {
+			return full
+		}
+		return nil
+	}
+	if isRealtimeChannel(message.Type) && !c.channels[message.Type] {
+		return nil
+	}
+	if len(c.subscribed) == 0 || (message.Type != "prices" && message.Type != "indicators") {
+		return full
+	}
+
+	var filtered interface{}
+	switch data := message.Data.(type) {
+	case []RealtimePriceData:
+		items := make([]RealtimePriceData, 0, len(c.subscribed))
+		for _, item := range data {
+			if c.subscribed[item.Code] {
+				items = append(items, item)
+			}
 		}
+		if len(items) == 0 {
+			return nil
+		}
+		filtered = items
+	case []RealtimeIndicators:
+		items := make([]RealtimeIndicators, 0, len(c.subscribed))
+		for _, item := range data {
+			if c.subscribed[item.Code] {
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		filtered = items
+	default:
+		return full
+	}
+
+	message.Data = filtered
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil
+	}
+	return payload
+}
+
+// sendMessage queues a message for this client only
+func (c *Client) sendMessage(msgType string, data interface{}) {
+	payload, err := json.Marshal(WebSocketMessage{
+		Type: msgType,
+		Data: data,
+		Time: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return
+	}
+	c.queue.push(payload)
+}
+
+// splitDepthChannels separates depth:<code> channels from plain channel names
+func splitDepthChannels(channels []string) (plain []string, depthCodes []string) {
+	for _, channel := range channels {
+		if strings.HasPrefix(channel, DepthChannelPrefix) {
+			depthCodes = append(depthCodes, strings.TrimPrefix(channel, DepthChannelPrefix))
+		} else {
+			plain = append(plain, channel)
+		}
+	}
+	return plain, depthCodes
+}
+
+// isRealtimeChannel reports whether name is a subscribable channel
+func isRealtimeChannel(name string) bool {
+	for _, channel := range RealtimeChannels {
+		if channel == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sendQueue is a bounded per-connection outbound queue that drops the oldest message when full
+type sendQueue struct {
+	mu      sync.Mutex
+	items   [][]byte
+	size    int
+	dropped int64
+	closed  bool
+	notify  chan struct{}
+	done    chan struct{}
+}
+
+func newSendQueue(size int) *sendQueue {
+	if size < 1 {
+		size = 1
+	}
+	return &sendQueue{
+		size:   size,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues data, evicting the oldest message if the queue is full
+func (q *sendQueue) push(data []byte) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	if len(q.items) >= q.size {
+		q.items = q.items[1:]
+		q.dropped++
+	}
+	q.items = append(q.items, data)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain removes and returns all queued messages
+func (q *sendQueue) drain() [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// close stops the queue and signals the write pump to close the connection
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
 	}
 }
 
-// StartPolling starts polling prices from VNDirect
+func (q *sendQueue) droppedCount() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// StartPolling starts polling prices from VNDirect. Codes subscribed by connected
+// clients are always polled in addition to codes; with neither, top RS stocks are polled.
 func (s *RealtimePriceService) StartPolling(codes []string) error {
+	if !SubsystemEnabled(SubsystemRealtime) {
+		return fmt.Errorf("realtime %w", ErrSubsystemDisabled)
+	}
 	s.mu.Lock()
-	if s.isRunning {
+	if s.isRunning && !s.autoStarted {
 		s.mu.Unlock()
 		return fmt.Errorf("polling already running")
 	}
-	s.isRunning = true
-	s.stockCodes = codes
-	s.stopChan = make(chan struct{})
+	s.stockCodes = normalizeCodes(codes)
+	s.autoStarted = false
+	if !s.isRunning {
+		s.startPollingLocked()
+	}
+	codeCount := len(s.pollingCodesLocked())
 	s.mu.Unlock()
 
-	go s.pollPrices()
-
-	codeCount := len(codes)
 	if codeCount == 0 {
 		codeCount = len(s.loadTopRSCodes())
 	}
@@ -337,30 +667,196 @@ func (s *RealtimePriceService) StartPolling(codes []string) error {
 func (s *RealtimePriceService) StopPolling() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.stopPollingLocked()
+}
+
+// startPollingLocked launches the poll loop; s.mu must be held
+func (s *RealtimePriceService) startPollingLocked() {
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+	go s.pollPrices(s.stopChan)
+}
 
+// stopPollingLocked stops the poll loop; s.mu must be held
+func (s *RealtimePriceService) stopPollingLocked() {
+	s.cancelIdleStopLocked()
 	if !s.isRunning {
 		return
 	}
 
 	close(s.stopChan)
 	s.isRunning = false
+	s.autoStarted = false
 	log.Println("Price polling stopped")
 }
 
-// pollPrices polls prices and broadcasts updates
-func (s *RealtimePriceService) pollPrices() {
+// removeClientLocked drops a client and releases its subscriptions; s.mu must be held
+func (s *RealtimePriceService) removeClientLocked(client *Client) {
+	delete(s.clients, client)
+	client.queue.close()
+
+	client.mu.RLock()
+	for code := range client.subscribed {
+		s.releaseCodeLocked(s.subscriptions, code)
+	}
+	for code := range client.depth {
+		s.releaseCodeLocked(s.depthSubs, code)
+	}
+	client.mu.RUnlock()
+
+	if len(s.clients) == 0 {
+		s.scheduleIdleStopLocked()
+	}
+}
+
+// addSubscriptions adds client codes to a polling set (prices or depth), starting polling if idle
+func (s *RealtimePriceService) addSubscriptions(counts map[string]int, codes []string) {
+	if len(codes) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		counts[code]++
+	}
+
+	if !s.isRunning {
+		s.autoStarted = true
+		s.startPollingLocked()
+		log.Printf("Started price polling on client subscription (%d codes, %d depth)", len(s.subscriptions), len(s.depthSubs))
+	}
+}
+
+// ensurePolling starts polling for a client that subscribed to a channel without codes
+func (s *RealtimePriceService) ensurePolling() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		s.autoStarted = true
+		s.startPollingLocked()
+		log.Println("Started price polling on client channel subscription")
+	}
+}
+
+// removeSubscriptions removes client codes from a polling set
+func (s *RealtimePriceService) removeSubscriptions(counts map[string]int, codes []string) {
+	if len(codes) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		s.releaseCodeLocked(counts, code)
+	}
+}
+
+// releaseCodeLocked decrements the subscriber count of code; s.mu must be held
+func (s *RealtimePriceService) releaseCodeLocked(counts map[string]int, code string) {
+	if counts[code] <= 1 {
+		delete(counts, code)
+		return
+	}
+	counts[code]--
+}
+
+// scheduleIdleStopLocked stops polling once no clients have been connected for RealtimeIdleStopDelay
+func (s *RealtimePriceService) scheduleIdleStopLocked() {
+	if !s.isRunning || s.idleTimer != nil {
+		return
+	}
+
+	s.idleTimer = time.AfterFunc(RealtimeIdleStopDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.idleTimer = nil
+		if len(s.clients) == 0 && s.isRunning {
+			log.Printf("No WebSocket clients for %v, stopping idle price polling", RealtimeIdleStopDelay)
+			s.stopPollingLocked()
+		}
+	})
+}
+
+// cancelIdleStopLocked cancels a pending idle stop; s.mu must be held
+func (s *RealtimePriceService) cancelIdleStopLocked() {
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+}
+
+// pollingCodesLocked returns explicit codes merged with client subscriptions; s.mu must be held
+func (s *RealtimePriceService) pollingCodesLocked() []string {
+	if len(s.subscriptions) == 0 {
+		return s.stockCodes
+	}
+
+	codes := make([]string, 0, len(s.stockCodes)+len(s.subscriptions))
+	seen := make(map[string]bool, cap(codes))
+	for _, code := range s.stockCodes {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	for code := range s.subscriptions {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// normalizeCodes upper-cases and trims stock codes, dropping empty entries
+func normalizeCodes(codes []string) []string {
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// pollPrices polls prices and broadcasts updates until stop is closed; indices are polled on
+// their own IndexPollInterval ticker
+func (s *RealtimePriceService) pollPrices(stop chan struct{}) {
 	ticker := time.NewTicker(s.pollingInterval)
 	defer ticker.Stop()
+	indexTicker := time.NewTicker(IndexPollInterval)
+	defer indexTicker.Stop()
 
 	// Initial poll
-	s.fetchAndBroadcast()
+	if SubsystemEnabled(SubsystemRealtime) {
+		s.fetchAndBroadcast()
+		s.fetchAndBroadcastDepth()
+		s.fetchAndBroadcastIndices()
+	}
 
 	for {
 		select {
-		case <-s.stopChan:
+		case <-stop:
 			return
 		case <-ticker.C:
+			// A kill switch set on another instance stops this loop on the next refresh
+			if !SubsystemEnabled(SubsystemRealtime) {
+				continue
+			}
 			s.fetchAndBroadcast()
+			s.fetchAndBroadcastDepth()
+		case <-indexTicker.C:
+			if !SubsystemEnabled(SubsystemRealtime) {
+				continue
+			}
+			s.fetchAndBroadcastIndices()
 		}
 	}
 }
@@ -399,7 +895,7 @@ func (s *RealtimePriceService) getIndicatorCache() *IndicatorSummaryFile {
 // fetchAndBroadcast fetches prices and broadcasts them
 func (s *RealtimePriceService) fetchAndBroadcast() {
 	s.mu.RLock()
-	codes := s.stockCodes
+	codes := s.pollingCodesLocked()
 	s.mu.RUnlock()
 
 	if len(codes) == 0 {
@@ -432,6 +928,7 @@ func (s *RealtimePriceService) fetchAndBroadcast() {
 			s.priceMu.Lock()
 			s.priceCache[code] = price
 			s.priceMu.Unlock()
+			s.journal.record(price)
 
 			allPrices = append(allPrices, *price)
 
@@ -447,6 +944,11 @@ func (s *RealtimePriceService) fetchAndBroadcast() {
 		}
 	}
 
+	// Persist intraday ticks for VWAP and volume profiles
+	if GlobalTickStore != nil && len(allPrices) > 0 {
+		GlobalTickStore.Record(allPrices)
+	}
+
 	// Broadcast price updates
 	if len(allPrices) > 0 {
 		s.broadcast <- WebSocketMessage{
@@ -476,6 +978,42 @@ func (s *RealtimePriceService) fetchAndBroadcast() {
 	}
 }
 
+// fetchAndBroadcastDepth polls SSI depth for codes with depth subscribers and streams
+// each book on its depth:<code> channel
+func (s *RealtimePriceService) fetchAndBroadcastDepth() {
+	s.mu.RLock()
+	codes := make([]string, 0, len(s.depthSubs))
+	for code := range s.depthSubs {
+		codes = append(codes, code)
+	}
+	s.mu.RUnlock()
+
+	for i, code := range codes {
+		book, err := FetchOrderBook(s.depthClient, code)
+		if err != nil {
+			log.Printf("Error fetching order book for %s: %v", code, err)
+			continue
+		}
+		s.depth.record(book)
+
+		s.broadcast <- WebSocketMessage{
+			Type:    "depth",
+			Channel: DepthChannelPrefix + code,
+			Data:    book,
+			Time:    time.Now().Format(time.RFC3339),
+		}
+
+		if (i+1)%PriceFetchBatchSize == 0 {
+			time.Sleep(PriceFetchBatchDelay)
+		}
+	}
+}
+
+// GetDepthDayStats returns today's sampled depth and spread statistics per code
+func (s *RealtimePriceService) GetDepthDayStats() []DepthDayStats {
+	return s.depth.snapshot()
+}
+
 // fetchCurrentPrice fetches current price for a stock
 func (s *RealtimePriceService) fetchCurrentPrice(code string) (*RealtimePriceData, error) {
 	if GlobalPriceService == nil {
@@ -502,6 +1040,8 @@ func (s *RealtimePriceService) fetchCurrentPrice(code string) (*RealtimePriceDat
 		Low:           data.Low,
 		Open:          data.Open,
 		RefPrice:      data.BasicPrice,
+		Ceiling:       data.CeilingPrice,
+		Floor:         data.FloorPrice,
 		Timestamp:     time.Now().Format(time.RFC3339),
 	}, nil
 }
@@ -638,11 +1178,7 @@ func (s *RealtimePriceService) sendTopRSToClient(c *Client) {
 		return
 	}
 
-	select {
-	case c.send <- data:
-	default:
-		// Client buffer full, skip
-	}
+	c.queue.push(data)
 }
 
 // GetClientCount returns the number of connected clients
@@ -652,6 +1188,57 @@ func (s *RealtimePriceService) GetClientCount() int {
 	return len(s.clients)
 }
 
+// LatestPrice returns a copy of the last polled price of a code, or nil when it has none
+func (s *RealtimePriceService) LatestPrice(code string) *RealtimePriceData {
+	if s == nil {
+		return nil
+	}
+	s.priceMu.RLock()
+	defer s.priceMu.RUnlock()
+	price, ok := s.priceCache[code]
+	if !ok || price == nil {
+		return nil
+	}
+	copied := *price
+	return &copied
+}
+
+// RefreshPrice fetches the current price of a code into the price cache, for callers that need
+// codes nobody is subscribed to
+func (s *RealtimePriceService) RefreshPrice(code string) (*RealtimePriceData, error) {
+	price, err := s.fetchCurrentPrice(code)
+	if err != nil {
+		return nil, err
+	}
+	s.priceMu.Lock()
+	s.priceCache[code] = price
+	s.priceMu.Unlock()
+	s.journal.record(price)
+
+	copied := *price
+	return &copied, nil
+}
+
+// CachedPrices returns copies of all prices polled today
+func (s *RealtimePriceService) CachedPrices() []RealtimePriceData {
+	loc := MarketCalendar().Location()
+	today := time.Now().In(loc).Format(PriceDateFormat)
+
+	s.priceMu.RLock()
+	defer s.priceMu.RUnlock()
+	prices := make([]RealtimePriceData, 0, len(s.priceCache))
+	for _, price := range s.priceCache {
+		if price == nil {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, price.Timestamp); err != nil || t.In(loc).Format(PriceDateFormat) != today {
+			continue
+		}
+		prices = append(prices, *price)
+	}
+	return prices
+}
+
 // IsPolling returns whether polling is active
 func (s *RealtimePriceService) IsPolling() bool {
 	s.mu.RLock()
@@ -672,6 +1259,28 @@ func (s *RealtimePriceService) SetPollingInterval(seconds int) {
 	s.pollingInterval = time.Duration(seconds) * time.Second
 }
 
+// GetConfig returns the connection config applied to new WebSocket clients
+func (s *RealtimePriceService) GetConfig() RealtimeConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// UpdateConfig validates and saves the connection config; it applies to new connections
+func (s *RealtimePriceService) UpdateConfig(cfg RealtimeConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := SaveRealtimeConfig(cfg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.config = cfg
+	s.mu.Unlock()
+	return nil
+}
+
 // BroadcastMessage broadcasts a custom message to all clients
 func (s *RealtimePriceService) BroadcastMessage(msgType string, data interface{}) {
 	s.broadcast <- WebSocketMessage{
@@ -686,11 +1295,21 @@ func (s *RealtimePriceService) GetStatus() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	var dropped int64
+	for client := range s.clients {
+		dropped += client.queue.droppedCount()
+	}
+
 	return map[string]interface{}{
 		"is_polling":       s.isRunning,
 		"client_count":     len(s.clients),
 		"max_clients":      MaxWebSocketClients,
 		"poll_interval_sec": int(s.pollingInterval.Seconds()),
 		"stock_codes":      len(s.stockCodes),
+		"subscribed_codes": len(s.subscriptions),
+		"depth_codes":      len(s.depthSubs),
+		"auto_started":     s.autoStarted,
+		"dropped_messages": dropped,
+		"journal":          s.journal.status(),
 	}
 }
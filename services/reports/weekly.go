@@ -0,0 +1,527 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"gorm.io/gorm"
+)
+
+// TemplateConfigKey is the system_config key of the admin-edited weekly report layout
+const TemplateConfigKey = "weekly_report_template"
+
+// Report limits
+const (
+	MaxTemplateSize = 256 * 1024
+	MaxMovers       = 10
+	MaxSignals      = 50
+	moverSessions   = 5 // trading sessions in a report week
+)
+
+// ErrPDFUnavailable is returned when no HTML-to-PDF converter is configured
+var ErrPDFUnavailable = errors.New("PDF rendering is not configured (set REPORT_PDF_COMMAND)")
+
+// TemplateConfig is the stored report layout
+type TemplateConfig struct {
+	Source    string    `json:"source"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Data is what report templates render
+type Data struct {
+	UserName    string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	GeneratedAt time.Time
+	Portfolio   Portfolio
+	Signals     []SignalRow
+	PriceAlerts []PriceAlertRow
+	Movers      []MoverRow
+}
+
+// Portfolio summarizes the user's holdings as last valued by the trading service
+type Portfolio struct {
+	Positions   []PositionRow
+	TotalCost   float64
+	MarketValue float64
+	PnL         float64
+	PnLPercent  float64
+}
+
+// PositionRow is one holding
+type PositionRow struct {
+	Stock        string
+	Quantity     int64
+	AvgPrice     float64
+	CurrentPrice float64
+	MarketValue  float64
+	PnL          float64
+	PnLPercent   float64
+}
+
+// SignalRow is one signal subscription match of the week
+type SignalRow struct {
+	Time         time.Time
+	Subscription string
+	Stock        string
+	SignalType   string
+	Score        int
+}
+
+// PriceAlertRow is one price alert triggered during the week
+type PriceAlertRow struct {
+	Time        time.Time
+	Stock       string
+	AlertType   string
+	TargetValue float64
+}
+
+// MoverRow is a watchlist stock with its change over the week
+type MoverRow struct {
+	Stock      string
+	Close      float64
+	WeekChange float64
+}
+
+// DefaultTemplate is the built-in report layout, used until an admin saves another
+const DefaultTemplate = `<!DOCTYPE html>
+<html lang="vi">
+<head>
+<meta charset="utf-8">
+<title>Weekly report {{.PeriodEnd.Format "02/01/2006"}}</title>
+<style>
+body { font-family: Arial, sans-serif; color: #222; margin: 24px; }
+h1 { font-size: 20px; } h2 { font-size: 16px; margin-top: 24px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 6px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.up { color: #0a8f3c; } .down { color: #c62828; } .muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>Weekly report for {{.UserName}}</h1>
+<p class="muted">{{.PeriodStart.Format "02/01/2006"}} - {{.PeriodEnd.Format "02/01/2006"}}</p>
+
+<h2>Portfolio</h2>
+{{if .Portfolio.Positions}}
+<table>
+<tr><th>Stock</th><th>Quantity</th><th>Avg price</th><th>Price</th><th>Value</th><th>P&amp;L</th><th>%</th></tr>
+{{range .Portfolio.Positions}}<tr><td>{{.Stock}}</td><td>{{.Quantity}}</td><td>{{money .AvgPrice}}</td><td>{{money .CurrentPrice}}</td><td>{{money .MarketValue}}</td><td class="{{trend .PnL}}">{{money .PnL}}</td><td class="{{trend .PnLPercent}}">{{pct .PnLPercent}}</td></tr>
+{{end}}<tr><th>Total</th><th></th><th></th><th></th><th>{{money .Portfolio.MarketValue}}</th><th class="{{trend .Portfolio.PnL}}">{{money .Portfolio.PnL}}</th><th class="{{trend .Portfolio.PnLPercent}}">{{pct .Portfolio.PnLPercent}}</th></tr>
+</table>
+{{else}}<p class="muted">No holdings.</p>{{end}}
+
+<h2>Triggered signals</h2>
+{{if .Signals}}
+<table>
+<tr><th>Stock</th><th>Subscription</th><th>Signal</th><th>Score</th><th>Time</th></tr>
+{{range .Signals}}<tr><td>{{.Stock}}</td><td>{{.Subscription}}</td><td>{{.SignalType}}</td><td>{{.Score}}</td><td>{{.Time.Format "02/01 15:04"}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">No signal matches this week.</p>{{end}}
+{{if .PriceAlerts}}
+<h2>Price alerts</h2>
+<table>
+<tr><th>Stock</th><th>Alert</th><th>Target</th><th>Time</th></tr>
+{{range .PriceAlerts}}<tr><td>{{.Stock}}</td><td>{{.AlertType}}</td><td>{{money .TargetValue}}</td><td>{{.Time.Format "02/01 15:04"}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Watchlist movers</h2>
+{{if .Movers}}
+<table>
+<tr><th>Stock</th><th>Close</th><th>Week</th></tr>
+{{range .Movers}}<tr><td>{{.Stock}}</td><td>{{money .Close}}</td><td class="{{trend .WeekChange}}">{{pct .WeekChange}}</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">Your watchlist is empty.</p>{{end}}
+
+<p class="muted">Generated {{.GeneratedAt.Format "02/01/2006 15:04"}}</p>
+</body>
+</html>
+`
+
+// templateFuncs are the helpers available to report templates
+var templateFuncs = template.FuncMap{
+	"money": func(v float64) string { return formatThousands(v) },
+	"pct":   func(v float64) string { return fmt.Sprintf("%+.2f%%", v) },
+	"trend": func(v float64) string {
+		switch {
+		case v > 0:
+			return "up"
+		case v < 0:
+			return "down"
+		}
+		return ""
+	},
+}
+
+// LoadTemplate returns the stored report layout, or the default when none is stored
+func LoadTemplate(db *gorm.DB) (*TemplateConfig, error) {
+	var cfg TemplateConfig
+	found, err := services.LoadSystemConfig(db, TemplateConfigKey, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !found || strings.TrimSpace(cfg.Source) == "" {
+		return &TemplateConfig{Source: DefaultTemplate}, nil
+	}
+	return &cfg, nil
+}
+
+// SaveTemplate stores a new report layout once it renders the sample data
+func SaveTemplate(db *gorm.DB, source, updatedBy string) (*TemplateConfig, error) {
+	if _, err := Render(source, SampleData()); err != nil {
+		return nil, err
+	}
+	cfg := &TemplateConfig{Source: source, UpdatedBy: updatedBy, UpdatedAt: time.Now()}
+	if err := services.SaveSystemConfig(db, TemplateConfigKey, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Render executes a report template
+func Render(source string, data *Data) ([]byte, error) {
+	if len(source) > MaxTemplateSize {
+		return nil, fmt.Errorf("template is larger than %d KB", MaxTemplateSize/1024)
+	}
+	tmpl, err := template.New("report").Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("template failed to render: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderPDF converts rendered HTML with the command in REPORT_PDF_COMMAND, which reads the HTML
+// from stdin and writes the PDF to stdout, e.g. "wkhtmltopdf --quiet - -"
+func RenderPDF(ctx context.Context, html []byte) ([]byte, error) {
+	command := strings.Fields(os.Getenv("REPORT_PDF_COMMAND"))
+	if len(command) == 0 {
+		return nil, ErrPDFUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(html)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("PDF conversion failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if !bytes.HasPrefix(stdout.Bytes(), []byte("%PDF")) {
+		return nil, errors.New("PDF converter returned no PDF")
+	}
+	return stdout.Bytes(), nil
+}
+
+// Build collects the report data of one user for the week ending at end
+func Build(db *gorm.DB, userID string, end time.Time) (*Data, error) {
+	start := end.AddDate(0, 0, -7)
+	data := &Data{UserName: "investor", PeriodStart: start, PeriodEnd: end, GeneratedAt: time.Now()}
+
+	var user models.User
+	err := db.Where("supabase_user_id = ?", userID).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil {
+		if user.FullName != "" {
+			data.UserName = user.FullName
+		}
+		if err := buildLocalUserSections(db, &user, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var history []struct {
+		CreatedAt   time.Time
+		Name        string
+		StockSymbol string
+		SignalType  string
+		Score       int
+	}
+	if err := db.Table("signal_alert_histories AS h").
+		Select("h.created_at, a.name, h.stock_symbol, h.signal_type, h.score").
+		Joins("JOIN signal_alerts a ON a.id = h.alert_id").
+		Where("a.user_id = ? AND h.created_at >= ? AND h.created_at < ?", userID, start, end).
+		Order("h.created_at DESC").Limit(MaxSignals).
+		Scan(&history).Error; err != nil {
+		return nil, err
+	}
+	for _, h := range history {
+		data.Signals = append(data.Signals, SignalRow{
+			Time: h.CreatedAt, Subscription: h.Name, Stock: h.StockSymbol, SignalType: h.SignalType, Score: h.Score,
+		})
+	}
+	return data, nil
+}
+
+// buildLocalUserSections fills the sections kept against the local user row
+func buildLocalUserSections(db *gorm.DB, user *models.User, data *Data) error {
+	var positions []models.Portfolio
+	if err := db.Preload("Stock").Where("user_id = ? AND quantity > 0", user.ID).Find(&positions).Error; err != nil {
+		return err
+	}
+	for _, p := range positions {
+		row := PositionRow{
+			Stock:        p.Stock.Symbol,
+			Quantity:     p.Quantity,
+			AvgPrice:     p.AvgPrice.InexactFloat64(),
+			CurrentPrice: p.CurrentPrice.InexactFloat64(),
+			MarketValue:  p.MarketValue.InexactFloat64(),
+			PnL:          p.UnrealizedPnL.InexactFloat64(),
+			PnLPercent:   p.UnrealizedPnLPercent.InexactFloat64(),
+		}
+		data.Portfolio.Positions = append(data.Portfolio.Positions, row)
+		data.Portfolio.TotalCost += p.TotalCost.InexactFloat64()
+		data.Portfolio.MarketValue += row.MarketValue
+		data.Portfolio.PnL += row.PnL
+	}
+	if data.Portfolio.TotalCost > 0 {
+		data.Portfolio.PnLPercent = data.Portfolio.PnL / data.Portfolio.TotalCost * 100
+	}
+	sort.Slice(data.Portfolio.Positions, func(i, j int) bool {
+		return data.Portfolio.Positions[i].MarketValue > data.Portfolio.Positions[j].MarketValue
+	})
+
+	var alerts []models.UserAlert
+	if err := db.Preload("Stock").Where("user_id = ? AND triggered_at >= ? AND triggered_at < ?",
+		user.ID, data.PeriodStart, data.PeriodEnd).Order("triggered_at DESC").Find(&alerts).Error; err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		data.PriceAlerts = append(data.PriceAlerts, PriceAlertRow{
+			Time: *a.TriggeredAt, Stock: a.Stock.Symbol, AlertType: a.AlertType, TargetValue: a.TargetValue.InexactFloat64(),
+		})
+	}
+
+	var watchlist []models.Watchlist
+	if err := db.Preload("Stock").Where("user_id = ?", user.ID).Find(&watchlist).Error; err != nil {
+		return err
+	}
+	for _, w := range watchlist {
+		if mover, ok := weekMove(w.Stock.Symbol); ok {
+			data.Movers = append(data.Movers, mover)
+		}
+	}
+	sort.Slice(data.Movers, func(i, j int) bool {
+		return absFloat(data.Movers[i].WeekChange) > absFloat(data.Movers[j].WeekChange)
+	})
+	if len(data.Movers) > MaxMovers {
+		data.Movers = data.Movers[:MaxMovers]
+	}
+	return nil
+}
+
+// weekMove returns the change of a stock over the last trading week from the local price store
+func weekMove(code string) (MoverRow, bool) {
+	if services.GlobalPriceService == nil || code == "" {
+		return MoverRow{}, false
+	}
+	file, err := services.GlobalPriceService.LoadStockPrice(code)
+	if err != nil || len(file.Prices) <= moverSessions {
+		return MoverRow{}, false
+	}
+	// Prices are stored newest first
+	latest, weekAgo := file.Prices[0].Close, file.Prices[moverSessions].Close
+	if weekAgo <= 0 {
+		return MoverRow{}, false
+	}
+	return MoverRow{Stock: code, Close: latest, WeekChange: (latest - weekAgo) / weekAgo * 100}, true
+}
+
+// GenerateResult summarizes a weekly report run
+type GenerateResult struct {
+	PeriodEnd time.Time `json:"period_end"`
+	Users     int       `json:"users"`
+	Generated int       `json:"generated"`
+	WithPDF   int       `json:"with_pdf"`
+	Failed    int       `json:"failed"`
+}
+
+// GenerateWeekly renders the weekly report of every active user for the week ending at end,
+// stores it and links it in the user's notification center
+func GenerateWeekly(ctx context.Context, db *gorm.DB, end time.Time, progress services.JobProgress) (*GenerateResult, error) {
+	cfg, err := LoadTemplate(db)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Render(cfg.Source, SampleData()); err != nil {
+		return nil, fmt.Errorf("stored report template is broken: %w", err)
+	}
+
+	userIDs, err := reportRecipients(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &GenerateResult{PeriodEnd: end, Users: len(userIDs)}
+	pdfEnabled := true
+
+	for i, userID := range userIDs {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if progress != nil {
+			progress(float64(i)/float64(len(userIDs))*100, userID, fmt.Sprintf("%d/%d users", i, len(userIDs)))
+		}
+
+		report, err := generateOne(ctx, db, cfg.Source, userID, end, &pdfEnabled)
+		if err != nil {
+			result.Failed++
+			log.Printf("Weekly report for %s failed: %v", userID, err)
+			continue
+		}
+		result.Generated++
+		if report.HasPDF {
+			result.WithPDF++
+		}
+		services.NotifyUser(db, userID, models.NotificationCategoryReport, "Your weekly report is ready",
+			fmt.Sprintf("Performance report for %s - %s", report.PeriodStart.Format("02/01"), report.PeriodEnd.Format("02/01/2006")),
+			map[string]interface{}{"report_id": report.ID, "url": fmt.Sprintf("/api/v1/reports/%d", report.ID), "has_pdf": report.HasPDF})
+	}
+	return result, nil
+}
+
+// generateOne builds, renders and stores one user's report, replacing a report of the same period
+func generateOne(ctx context.Context, db *gorm.DB, source, userID string, end time.Time, pdfEnabled *bool) (*models.UserReport, error) {
+	data, err := Build(db, userID, end)
+	if err != nil {
+		return nil, err
+	}
+	html, err := Render(source, data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.UserReport{
+		UserID:      userID,
+		Kind:        models.ReportKindWeekly,
+		PeriodStart: data.PeriodStart,
+		PeriodEnd:   data.PeriodEnd,
+		HTML:        string(html),
+	}
+	if *pdfEnabled {
+		pdf, err := RenderPDF(ctx, html)
+		switch {
+		case err == nil:
+			report.PDF, report.HasPDF = pdf, true
+		case errors.Is(err, ErrPDFUnavailable):
+			*pdfEnabled = false
+		default:
+			log.Printf("Weekly report PDF for %s failed, keeping HTML only: %v", userID, err)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND kind = ? AND period_end = ?", userID, report.Kind, report.PeriodEnd).
+			Delete(&models.UserReport{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(report).Error
+	})
+	return report, err
+}
+
+// reportRecipients returns the Supabase IDs of active local users and of signal subscribers
+func reportRecipients(db *gorm.DB) ([]string, error) {
+	var local, subscribers []string
+	if err := db.Model(&models.User{}).Where("is_active = ?", true).Pluck("supabase_user_id", &local).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.SignalAlert{}).Where("user_id <> ''").Distinct().Pluck("user_id", &subscribers).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(local)+len(subscribers))
+	ids := make([]string, 0, len(local)+len(subscribers))
+	for _, id := range append(local, subscribers...) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// WeekEnd returns the market-time midnight that ends the report week containing t: reports
+// cover Saturday 00:00 to Saturday 00:00, so Friday's session is the last one in them
+func WeekEnd(t time.Time) time.Time {
+	local := t.In(services.MarketCalendar().Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return midnight.AddDate(0, 0, -int((local.Weekday()+1)%7))
+}
+
+// SampleData is rendered by the template editor's preview and when validating templates
+func SampleData() *Data {
+	end := WeekEnd(time.Now())
+	return &Data{
+		UserName:    "Nguyễn Văn A",
+		PeriodStart: end.AddDate(0, 0, -7),
+		PeriodEnd:   end,
+		GeneratedAt: time.Now(),
+		Portfolio: Portfolio{
+			Positions: []PositionRow{
+				{Stock: "FPT", Quantity: 1000, AvgPrice: 95000, CurrentPrice: 102000, MarketValue: 102000000, PnL: 7000000, PnLPercent: 7.37},
+				{Stock: "HPG", Quantity: 2000, AvgPrice: 28000, CurrentPrice: 26500, MarketValue: 53000000, PnL: -3000000, PnLPercent: -5.36},
+			},
+			TotalCost:   151000000,
+			MarketValue: 155000000,
+			PnL:         4000000,
+			PnLPercent:  2.65,
+		},
+		Signals: []SignalRow{
+			{Time: end.Add(-30 * time.Hour), Subscription: "Breakout", Stock: "VNM", SignalType: "BUY", Score: 78},
+		},
+		PriceAlerts: []PriceAlertRow{
+			{Time: end.Add(-50 * time.Hour), Stock: "MWG", AlertType: models.UserAlertTypePriceAbove, TargetValue: 60000},
+		},
+		Movers: []MoverRow{
+			{Stock: "SSI", Close: 34.5, WeekChange: 6.2},
+			{Stock: "VIC", Close: 41.2, WeekChange: -4.1},
+		},
+	}
+}
+
+// formatThousands formats v rounded to an integer with dot thousand separators (Vietnamese style)
+func formatThousands(v float64) string {
+	if v != float64(int64(v)) && absFloat(v) < 1000 {
+		return strings.Replace(fmt.Sprintf("%.2f", v), ".", ",", 1)
+	}
+	s := fmt.Sprintf("%.0f", absFloat(v))
+	var out []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			out = append(out, '.')
+		}
+		out = append(out, s[i])
+	}
+	if v < 0 {
+		return "-" + string(out)
+	}
+	return string(out)
+}
+
+func absFloat(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Datasets with a retention policy
+const (
+	RetentionPriceHistory  = "price_history"
+	RetentionSignalHistory = "signal_history"
+	RetentionAuditLogs     = "audit_logs"
+	RetentionSyncHistory   = "sync_history"
+	RetentionIntradayTicks = "intraday_ticks"
+	RetentionAPIUsage      = "api_usage"
+)
+
+// Retention defaults, overridden with RETENTION_PRICE_HISTORY_DAYS, RETENTION_SIGNAL_HISTORY_DAYS,
+// RETENTION_AUDIT_LOG_DAYS and RETENTION_API_USAGE_DAYS; 0 keeps a dataset forever. Sync history and intraday ticks keep
+// their SYNC_HISTORY_RETENTION_DAYS and INTRADAY_TICK_RETENTION_DAYS settings.
+const (
+	DefaultPriceHistoryRetentionDays  = 1825
+	DefaultSignalHistoryRetentionDays = 365
+	DefaultAuditLogRetentionDays      = 365
+	DefaultAPIUsageRetentionDays      = 90
+
+	// MinPriceHistoryRetentionDays keeps the year of sessions and warm-up the indicators need
+	MinPriceHistoryRetentionDays = 400
+)
+
+// Kinds of data a retention target removes
+const (
+	RetentionKindRows  = "rows"
+	RetentionKindFiles = "files"
+)
+
+// RetentionPolicy is how long one dataset is kept
+type RetentionPolicy struct {
+	Dataset     string `json:"dataset"`
+	Days        int    `json:"days"` // 0 keeps everything
+	Env         string `json:"env"`
+	Description string `json:"description"`
+}
+
+// RetentionTargetResult is what was removed from one table or directory of a dataset
+type RetentionTargetResult struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`    // rows or files
+	Removed int64  `json:"removed"` // what would be removed on a dry run
+	Error   string `json:"error,omitempty"`
+}
+
+// RetentionDatasetResult is what was removed from one dataset
+type RetentionDatasetResult struct {
+	Dataset string                  `json:"dataset"`
+	Days    int                     `json:"days"`
+	Cutoff  string                  `json:"cutoff,omitempty"` // data before this time is removed
+	Skipped bool                    `json:"skipped"`          // kept forever
+	Rows    int64                   `json:"rows"`
+	Files   int64                   `json:"files"`
+	Targets []RetentionTargetResult `json:"targets,omitempty"`
+}
+
+// RetentionReport is the outcome of one pruning run
+type RetentionReport struct {
+	DryRun     bool                     `json:"dry_run"`
+	StartedAt  time.Time                `json:"started_at"`
+	DurationMs int64                    `json:"duration_ms"`
+	Rows       int64                    `json:"rows"`
+	Files      int64                    `json:"files"`
+	Errors     int                      `json:"errors"`
+	Datasets   []RetentionDatasetResult `json:"datasets"`
+}
+
+// retentionTarget removes the data of a dataset older than cutoff, or only counts it on a dry run
+type retentionTarget struct {
+	name string
+	kind string
+	run  func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error)
+}
+
+var lastRetention struct {
+	mu     sync.RWMutex
+	report *RetentionReport
+}
+
+// RetentionPolicies returns the retention period of every dataset
+func RetentionPolicies() []RetentionPolicy {
+	syncDays := DefaultSyncHistoryRetentionDays
+	if GlobalSyncHistory != nil {
+		syncDays = GlobalSyncHistory.RetentionDays()
+	}
+	tickDays := DefaultTickRetentionDays
+	if GlobalTickStore != nil {
+		tickDays = GlobalTickStore.RetentionDays()
+	}
+
+	priceDays := retentionDaysFromEnv("RETENTION_PRICE_HISTORY_DAYS", DefaultPriceHistoryRetentionDays)
+	if priceDays > 0 && priceDays < MinPriceHistoryRetentionDays {
+		priceDays = MinPriceHistoryRetentionDays
+	}
+
+	return []RetentionPolicy{
+		{RetentionPriceHistory, priceDays, "RETENTION_PRICE_HISTORY_DAYS", "Daily prices in the database and price files, and archived price files of delisted stocks"},
+		{RetentionSignalHistory, retentionDaysFromEnv("RETENTION_SIGNAL_HISTORY_DAYS", DefaultSignalHistoryRetentionDays), "RETENTION_SIGNAL_HISTORY_DAYS", "Closed signals emitted by rules and templates, and triggered signal alerts"},
+		{RetentionAuditLogs, retentionDaysFromEnv("RETENTION_AUDIT_LOG_DAYS", DefaultAuditLogRetentionDays), "RETENTION_AUDIT_LOG_DAYS", "Impersonation sessions and the requests made with them"},
+		{RetentionSyncHistory, syncDays, "SYNC_HISTORY_RETENTION_DAYS", "Price, stock list and indicator sync runs"},
+		{RetentionIntradayTicks, tickDays, "INTRADAY_TICK_RETENTION_DAYS", "Intraday ticks captured by the realtime poller"},
+		{RetentionAPIUsage, retentionDaysFromEnv("RETENTION_API_USAGE_DAYS", DefaultAPIUsageRetentionDays), "RETENTION_API_USAGE_DAYS", "API usage logs; their daily analytics rollups are kept"},
+	}
+}
+
+// retentionDaysFromEnv reads a retention period in days, where 0 keeps everything
+func retentionDaysFromEnv(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return fallback
+}
+
+// LastRetentionReport returns the report of the latest pruning run since startup, or nil
+func LastRetentionReport() *RetentionReport {
+	lastRetention.mu.RLock()
+	defer lastRetention.mu.RUnlock()
+	return lastRetention.report
+}
+
+// RunRetention removes the data of every dataset past its retention period. On a dry run nothing
+// is removed and the report counts what would be. A failing target doesn't stop the others.
+func RunRetention(ctx context.Context, db *gorm.DB, dryRun bool, progress JobProgress) (*RetentionReport, error) {
+	report := &RetentionReport{DryRun: dryRun, StartedAt: time.Now()}
+	policies := RetentionPolicies()
+
+	for i, policy := range policies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(float64(i)/float64(len(policies))*100, policy.Dataset, "Pruning "+policy.Dataset)
+		}
+
+		result := RetentionDatasetResult{Dataset: policy.Dataset, Days: policy.Days, Skipped: policy.Days == 0}
+		if !result.Skipped {
+			cutoff := report.StartedAt.AddDate(0, 0, -policy.Days)
+			result.Cutoff = cutoff.Format(time.RFC3339)
+			for _, target := range retentionTargets(db, policy.Dataset) {
+				removed, err := target.run(ctx, cutoff, dryRun)
+				targetResult := RetentionTargetResult{Name: target.name, Kind: target.kind, Removed: removed}
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					targetResult.Error = err.Error()
+					report.Errors++
+					log.Printf("Error pruning %s: %v", target.name, err)
+				}
+				if target.kind == RetentionKindFiles {
+					result.Files += removed
+				} else {
+					result.Rows += removed
+				}
+				result.Targets = append(result.Targets, targetResult)
+			}
+		}
+		report.Rows += result.Rows
+		report.Files += result.Files
+		report.Datasets = append(report.Datasets, result)
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+
+	lastRetention.mu.Lock()
+	lastRetention.report = report
+	lastRetention.mu.Unlock()
+
+	verb := "Pruned"
+	if dryRun {
+		verb = "Dry run: would prune"
+	}
+	log.Printf("%s %d rows and %d files past their retention period (%d errors)", verb, report.Rows, report.Files, report.Errors)
+	return report, nil
+}
+
+// retentionTargets lists the tables and directories holding a dataset
+func retentionTargets(db *gorm.DB, dataset string) []retentionTarget {
+	switch dataset {
+	case RetentionPriceHistory:
+		return []retentionTarget{
+			{"stock_prices", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.StockPrice{}, dryRun, "date < ?", cutoff)
+			}},
+			{"price file rows", RetentionKindRows, trimPriceFiles},
+			{"archived price files", RetentionKindFiles, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneFiles(ctx, StockArchiveDir, ".json", cutoff, dryRun)
+			}},
+		}
+	case RetentionSignalHistory:
+		return []retentionTarget{
+			{"signal_histories", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				// Active signals still suppress repeats, so only closed ones go
+				return pruneRows(ctx, db, &models.SignalHistory{}, dryRun, "state <> ? AND emitted_at < ?", models.SignalStateActive, cutoff)
+			}},
+			{"signal_alert_histories", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.SignalAlertHistory{}, dryRun, "created_at < ?", cutoff)
+			}},
+		}
+	case RetentionAuditLogs:
+		return []retentionTarget{
+			{"impersonation_requests", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.ImpersonationRequest{}, dryRun, "created_at < ?", cutoff)
+			}},
+			{"impersonation_sessions", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.ImpersonationSession{}, dryRun, "created_at < ? AND expires_at < ?", cutoff, cutoff)
+			}},
+		}
+	case RetentionSyncHistory:
+		return []retentionTarget{
+			{"sync_histories", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.SyncHistory{}, dryRun, "started_at < ?", cutoff)
+			}},
+		}
+	case RetentionIntradayTicks:
+		return []retentionTarget{
+			{"intraday_ticks", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.IntradayTick{}, dryRun, "traded_at < ?", cutoff)
+			}},
+		}
+	case RetentionAPIUsage:
+		return []retentionTarget{
+			{"api_usage_logs", RetentionKindRows, func(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+				return pruneRows(ctx, db, &models.APIUsageLog{}, dryRun, "at < ?", cutoff)
+			}},
+		}
+	}
+	return nil
+}
+
+// pruneRows deletes the rows of model matching the query, or counts them on a dry run
+func pruneRows(ctx context.Context, db *gorm.DB, model interface{}, dryRun bool, query string, args ...interface{}) (int64, error) {
+	if db == nil {
+		return 0, fmt.Errorf("database not available")
+	}
+	db = db.WithContext(ctx)
+	if dryRun {
+		var count int64
+		err := db.Model(model).Where(query, args...).Count(&count).Error
+		return count, err
+	}
+	result := db.Where(query, args...).Delete(model)
+	return result.RowsAffected, result.Error
+}
+
+// pruneFiles deletes the files of dir with the extension last modified before cutoff
+func pruneFiles(ctx context.Context, dir, ext string, cutoff time.Time, dryRun bool) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var removed int64
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return removed, err
+			}
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// trimPriceFiles drops the daily prices before cutoff from every price file, mirroring the
+// trimmed files to MongoDB when it is configured
+func trimPriceFiles(ctx context.Context, cutoff time.Time, dryRun bool) (int64, error) {
+	if GlobalPriceService == nil {
+		return 0, fmt.Errorf("price service not initialized")
+	}
+	entries, err := os.ReadDir(StockPriceDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	before := cutoff.Format(PriceDateFormat)
+
+	var removed int64
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		code := strings.TrimSuffix(entry.Name(), ".json")
+		priceFile, err := GlobalPriceService.LoadStockPriceContext(ctx, code)
+		if err != nil {
+			continue
+		}
+
+		kept := make([]StockPriceData, 0, len(priceFile.Prices))
+		for _, p := range priceFile.Prices {
+			if p.Date >= before {
+				kept = append(kept, p)
+			}
+		}
+		dropped := len(priceFile.Prices) - len(kept)
+		if dropped == 0 {
+			continue
+		}
+		if !dryRun {
+			// Loaded price files are shared, so write a copy
+			trimmed := *priceFile
+			trimmed.Prices = kept
+			trimmed.DataCount = len(kept)
+			if err := savePriceFile(code, &trimmed); err != nil {
+				return removed, err
+			}
+		}
+		removed += int64(dropped)
+	}
+	return removed, nil
+}
+
+// savePriceFile replaces the price file of code as is, keeping its stored indicators
+func savePriceFile(code string, file *StockPriceFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal price data: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(StockPriceDir, code+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write price file: %w", err)
+	}
+	GlobalPriceService.cache.invalidate(code)
+
+	if GlobalMongoClient != nil && GlobalMongoClient.IsConfigured() {
+		if err := GlobalMongoClient.SavePriceData(code, file); err != nil {
+			log.Printf("Warning: failed to save %s prices to MongoDB: %v", code, err)
+		}
+	}
+	return nil
+}
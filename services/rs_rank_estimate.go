@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"math"
+)
+
+// GetRankedStockIndicators returns a stock's indicators with RS ranks. Stocks in the indicator
+// summary are returned as saved there. A stock missing from it, typically one listed since the
+// last full calculation, has its indicators computed from its price file and its RS ranks
+// estimated against the summary's distribution, without recalculating the whole market.
+func (s *StockIndicatorService) GetRankedStockIndicators(code string) (*ExtendedStockIndicators, error) {
+	summary, summaryErr := s.LoadIndicatorSummary()
+	if summaryErr == nil {
+		if ind, ok := summary.Stocks[code]; ok && ind != nil {
+			return ind, nil
+		}
+	}
+
+	priceFile, err := GlobalPriceService.LoadStockPrice(code)
+	if err != nil {
+		return nil, err
+	}
+	ind := CalculateIndicatorsForStock(priceFile)
+	if ind == nil {
+		return nil, fmt.Errorf("not enough price history for %s", code)
+	}
+	ind.Type = InstrumentTypeOf(code)
+	if s.RSMethod() == RSMethodBenchmark {
+		applyBenchmarkRS(ind, priceFile.Prices, LoadBenchmark())
+	}
+
+	if summaryErr == nil {
+		EstimateRSRanks(ind, summary.Stocks)
+	}
+	return ind, nil
+}
+
+// EstimateRSRanks sets the RS ranks of ind as if it had been ranked together with the stocks of
+// distribution: each rank is the percentile ind's value would take among the qualified stocks.
+// The ranks match a full calculation up to the shift the new stock causes in everyone else's.
+func EstimateRSRanks(ind *ExtendedStockIndicators, distribution map[string]*ExtendedStockIndicators) {
+	ind.RS3DRank, ind.RS1MRank, ind.RS3MRank, ind.RS1YRank, ind.RSAvg = 0, 0, 0, 0, 0
+	if !qualifiesForRS(ind) {
+		return
+	}
+
+	var lists [4][]float64
+	for code, other := range distribution {
+		if other == nil || code == ind.Code || !qualifiesForRS(other) {
+			continue
+		}
+		for i, v := range rsValues(other) {
+			lists[i] = append(lists[i], v)
+		}
+	}
+	if len(lists[0]) == 0 {
+		return
+	}
+
+	ranks := [4]float64{}
+	for i, v := range rsValues(ind) {
+		below := 0
+		for _, other := range lists[i] {
+			if other < v {
+				below++
+			}
+		}
+		rank := math.Round(float64(below+1) / float64(len(lists[i])+1) * 100)
+		ranks[i] = math.Max(1, math.Min(100, rank))
+	}
+
+	ind.RS3DRank, ind.RS1MRank, ind.RS3MRank, ind.RS1YRank = ranks[0], ranks[1], ranks[2], ranks[3]
+	ind.RSAvg = math.Round((ranks[0] + ranks[1] + ranks[2] + ranks[3]) / 4)
+	ind.RSRankEstimated = true
+}
+
+// qualifiesForRS mirrors the liquidity and instrument filter of CalculateRSRanks
+func qualifiesForRS(ind *ExtendedStockIndicators) bool {
+	return ind.AvgTradingVal >= MinTradingValForRS && ind.Type != InstrumentTypeCW
+}
+
+// rsValues returns the 3D, 1M, 3M and 1Y values ranked by CalculateRSRanks
+func rsValues(ind *ExtendedStockIndicators) [4]float64 {
+	if ind.RSMethod == RSMethodBenchmark {
+		return [4]float64{ind.RS3DExcess, ind.RS1MExcess, ind.RS3MExcess, ind.RS1YExcess}
+	}
+	return [4]float64{ind.RS3D, ind.RS1M, ind.RS3M, ind.RS1Y}
+}
@@ -21,6 +21,7 @@ func NewStockScreener(db *gorm.DB) *StockScreener {
 
 // ScreenerFilter represents filter criteria for stock screening
 type ScreenerFilter struct {
+	Symbols           []string `json:"symbols"`            // Only these stocks, e.g. the ones the user tagged
 	Exchange          []string `json:"exchange"`           // HOSE, HNX, UPCOM
 	Industry          []string `json:"industry"`           // Banking, Technology, etc.
 	Sector            []string `json:"sector"`             // Finance, IT, etc.
@@ -78,6 +79,11 @@ func (ss *StockScreener) Screen(filter *ScreenerFilter) ([]ScreenerResult, int64
 	// Base query for stocks
 	query := ss.db.Model(&models.Stock{}).Where("status = ?", "active")
 
+	// Restrict to the given stocks; a non-nil empty list matches nothing
+	if filter.Symbols != nil {
+		query = query.Where("symbol IN ?", filter.Symbols)
+	}
+
 	// Apply exchange filter
 	if len(filter.Exchange) > 0 {
 		query = query.Where("exchange IN ?", filter.Exchange)
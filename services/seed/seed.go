@@ -0,0 +1,454 @@
+// Package seed populates a demo environment from embedded fixtures: sample stocks with a price
+// history, condition groups, rules, templates, and users with portfolios and watchlists. Seeding
+// is idempotent: every row is matched on its natural key (symbol, name, email) and rows that
+// already exist are left as they are, so it can run on every start of a demo instance.
+package seed
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+//go:embed fixtures/demo.json
+var demoFixture []byte
+
+// demoUserPrefix marks the Supabase user IDs of the seeded users, which have no Supabase account
+const demoUserPrefix = "demo-"
+
+// Fixture is the content of a seed fixture file
+type Fixture struct {
+	Stocks      []string          `json:"stocks"`
+	HistoryDays int               `json:"history_days"`
+	Groups      []GroupFixture    `json:"groups"`
+	Rules       []RuleFixture     `json:"rules"`
+	Templates   []TemplateFixture `json:"templates"`
+	Users       []UserFixture     `json:"users"`
+}
+
+// GroupFixture is a condition group with its conditions
+type GroupFixture struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	SignalType  string                   `json:"signal_type"`
+	Conditions  []models.SignalCondition `json:"conditions"`
+}
+
+// RuleFixture is a rule referring to its groups by name
+type RuleFixture struct {
+	Name            string  `json:"name"`
+	Description     string  `json:"description"`
+	SignalType      string  `json:"signal_type"`
+	StrategyType    string  `json:"strategy_type"`
+	MinScore        int     `json:"min_score"`
+	TargetPercent   float64 `json:"target_percent"`
+	StopLossPercent float64 `json:"stop_loss_percent"`
+	Groups          []struct {
+		Group    string `json:"group"`
+		Logic    string `json:"logic"`
+		Required bool   `json:"required"`
+	} `json:"groups"`
+}
+
+// TemplateFixture is a template authored by a seeded user, or by the admins without author
+type TemplateFixture struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Category    string          `json:"category"`
+	Visibility  string          `json:"visibility"`
+	Author      string          `json:"author"` // email of a fixture user
+	Conditions  json.RawMessage `json:"conditions"`
+}
+
+// UserFixture is a user with the stocks they hold and watch
+type UserFixture struct {
+	Email     string `json:"email"`
+	FullName  string `json:"full_name"`
+	Role      string `json:"role"`
+	Portfolio []struct {
+		Code          string `json:"code"`
+		Quantity      int64  `json:"quantity"`
+		BoughtDaysAgo int    `json:"bought_days_ago"` // bought at the close that many sessions ago
+	} `json:"portfolio"`
+	Watchlist []string `json:"watchlist"`
+}
+
+// Result counts the rows seeded and those found in place, by kind
+type Result struct {
+	Created  map[string]int `json:"created"`
+	Existing map[string]int `json:"existing"`
+	Duration string         `json:"duration"`
+}
+
+func (r *Result) count(kind string, created bool) {
+	if r == nil {
+		return
+	}
+	if created {
+		r.Created[kind]++
+	} else {
+		r.Existing[kind]++
+	}
+}
+
+// Enabled reports whether this instance may be seeded: any environment but production, where it
+// must be switched on with SEED_DEMO_ENABLED=true
+func Enabled() bool {
+	return os.Getenv("ENVIRONMENT") != "production" || os.Getenv("SEED_DEMO_ENABLED") == "true"
+}
+
+// OnStartup reports whether the demo data is seeded when the server starts (SEED_DEMO_DATA=true)
+func OnStartup() bool {
+	return os.Getenv("SEED_DEMO_DATA") == "true"
+}
+
+// DemoFixture returns the embedded demo fixture
+func DemoFixture() (*Fixture, error) {
+	var fixture Fixture
+	if err := json.Unmarshal(demoFixture, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid demo fixture: %w", err)
+	}
+	return &fixture, nil
+}
+
+// Demo seeds the embedded demo fixture
+func Demo(ctx context.Context, db *gorm.DB) (*Result, error) {
+	fixture, err := DemoFixture()
+	if err != nil {
+		return nil, err
+	}
+	return Apply(ctx, db, fixture)
+}
+
+// Apply seeds a fixture, each kind in its own transaction, parents first. Prices come from the
+// FAKE_PROVIDERS generator, so they are the same on every instance; price files are written for
+// the stocks and the market index that have none locally, and the indicators must then be
+// recalculated for the signal screens.
+func Apply(ctx context.Context, db *gorm.DB, fixture *Fixture) (*Result, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if !Enabled() {
+		return nil, fmt.Errorf("seeding is disabled in production (set SEED_DEMO_ENABLED=true)")
+	}
+	start := time.Now()
+	result := &Result{Created: map[string]int{}, Existing: map[string]int{}}
+	db = db.WithContext(ctx)
+
+	stocks, err := Stocks(db, fixture.Stocks, fixture.HistoryDays, result)
+	if err != nil {
+		return nil, err
+	}
+	codes := append([]string{services.MarketIndexCode}, fixture.Stocks...)
+	if err := priceFiles(codes, result); err != nil {
+		return nil, err
+	}
+
+	steps := []struct {
+		kind string
+		run  func(tx *gorm.DB) error
+	}{
+		{"groups", func(tx *gorm.DB) error { return seedGroups(tx, fixture.Groups, result) }},
+		{"rules", func(tx *gorm.DB) error { return seedRules(tx, fixture.Rules, result) }},
+		{"users", func(tx *gorm.DB) error { return seedUsers(tx, fixture.Users, stocks, result) }},
+		{"templates", func(tx *gorm.DB) error { return seedTemplates(tx, fixture.Templates, result) }},
+	}
+	for _, step := range steps {
+		if err := db.Transaction(step.run); err != nil {
+			return nil, fmt.Errorf("failed to seed %s: %w", step.kind, err)
+		}
+	}
+
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	return result, nil
+}
+
+// Stocks stores the listed stocks of the synthetic list and their last historyDays sessions,
+// priced in VND, and returns the stocks by code. Stocks that already have prices keep them.
+// result may be nil.
+func Stocks(db *gorm.DB, codes []string, historyDays int, result *Result) (map[string]*models.Stock, error) {
+	names := make(map[string]services.VNDirectStock)
+	for _, s := range services.FakeStockList() {
+		names[s.Code] = s
+	}
+	fake := services.NewFakeProviders(1)
+	unit := decimal.NewFromInt(services.PriceUnitVND)
+	stocks := make(map[string]*models.Stock, len(codes))
+
+	for _, code := range codes {
+		listed, ok := names[code]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a fixture stock", code)
+		}
+		stock := models.Stock{Symbol: code, Name: listed.CompanyName, Exchange: listed.Floor, Status: "active"}
+		tx := db.Where(models.Stock{Symbol: code}).FirstOrCreate(&stock)
+		if tx.Error != nil {
+			return nil, fmt.Errorf("%s: %w", code, tx.Error)
+		}
+		result.count("stocks", tx.RowsAffected > 0)
+		stocks[code] = &stock
+
+		var count int64
+		db.Model(&models.StockPrice{}).Where("stock_id = ?", stock.ID).Count(&count)
+		if count > 0 {
+			result.count("stock_prices", false)
+			continue
+		}
+
+		history := lastSessions(fake.History(code, time.Now()), historyDays)
+		prices := make([]models.StockPrice, 0, len(history))
+		for _, bar := range history {
+			date, err := time.ParseInLocation(services.PriceDateFormat, bar.Date, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", code, err)
+			}
+			prices = append(prices, models.StockPrice{
+				StockID:       stock.ID,
+				Date:          date,
+				Open:          decimal.NewFromFloat(bar.Open).Mul(unit),
+				High:          decimal.NewFromFloat(bar.High).Mul(unit),
+				Low:           decimal.NewFromFloat(bar.Low).Mul(unit),
+				Close:         decimal.NewFromFloat(bar.Close).Mul(unit),
+				Volume:        int64(bar.NmVolume),
+				Value:         decimal.NewFromFloat(bar.NmValue),
+				AdjClose:      decimal.NewFromFloat(bar.AdClose).Mul(unit),
+				Change:        decimal.NewFromFloat(bar.Change).Mul(unit),
+				ChangePercent: decimal.NewFromFloat(bar.PctChange),
+			})
+		}
+		if err := db.Session(&gorm.Session{CreateBatchSize: 500}).Create(&prices).Error; err != nil {
+			return nil, fmt.Errorf("%s prices: %w", code, err)
+		}
+		result.count("stock_prices", true)
+	}
+	return stocks, nil
+}
+
+// priceFiles writes the whole synthetic history of the codes without local prices
+func priceFiles(codes []string, result *Result) error {
+	if services.GlobalPriceService == nil {
+		return nil
+	}
+	fake := services.NewFakeProviders(1)
+	for _, code := range codes {
+		if file, err := services.GlobalPriceService.LoadStockPrice(code); err == nil && len(file.Prices) > 0 {
+			result.count("price_files", false)
+			continue
+		}
+		history := fake.History(code, time.Now())
+		// Price files are newest first
+		prices := make([]services.StockPriceData, len(history))
+		for i, bar := range history {
+			prices[len(history)-1-i] = bar
+		}
+		if err := services.GlobalPriceService.SaveStockPrice(code, prices); err != nil {
+			return fmt.Errorf("%s price file: %w", code, err)
+		}
+		result.count("price_files", true)
+	}
+	return nil
+}
+
+func seedGroups(tx *gorm.DB, groups []GroupFixture, result *Result) error {
+	for _, fixture := range groups {
+		var existing models.SignalConditionGroup
+		err := tx.Unscoped().Where("name = ?", fixture.Name).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			result.count("groups", false)
+			continue
+		}
+		group := models.SignalConditionGroup{
+			Name:        fixture.Name,
+			Description: fixture.Description,
+			SignalType:  fixture.SignalType,
+			IsActive:    true,
+			Conditions:  fixture.Conditions,
+		}
+		for i := range group.Conditions {
+			group.Conditions[i].OrderIndex = i
+			if group.Conditions[i].LogicalOperator == "" {
+				group.Conditions[i].LogicalOperator = models.LogicalAnd
+			}
+		}
+		if err := tx.Create(&group).Error; err != nil {
+			return fmt.Errorf("%s: %w", fixture.Name, err)
+		}
+		result.count("groups", true)
+	}
+	return nil
+}
+
+func seedRules(tx *gorm.DB, rules []RuleFixture, result *Result) error {
+	for _, fixture := range rules {
+		var existing models.SignalRule
+		if err := tx.Unscoped().Where("name = ?", fixture.Name).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			result.count("rules", false)
+			continue
+		}
+
+		refs := make([]models.RuleGroupRef, 0, len(fixture.Groups))
+		for _, ref := range fixture.Groups {
+			var group models.SignalConditionGroup
+			if err := tx.Where("name = ?", ref.Group).First(&group).Error; err != nil {
+				return fmt.Errorf("%s: group %q: %w", fixture.Name, ref.Group, err)
+			}
+			refs = append(refs, models.RuleGroupRef{GroupID: group.ID, Logic: ref.Logic, Required: ref.Required})
+		}
+		rule := models.SignalRule{
+			Name:            fixture.Name,
+			Description:     fixture.Description,
+			SignalType:      fixture.SignalType,
+			StrategyType:    fixture.StrategyType,
+			MinScore:        fixture.MinScore,
+			TargetPercent:   decimal.NewFromFloat(fixture.TargetPercent),
+			StopLossPercent: decimal.NewFromFloat(fixture.StopLossPercent),
+			IsActive:        true,
+			CooldownHours:   24,
+			ExpiryDays:      30,
+		}
+		if err := rule.SetGroupRefs(refs); err != nil {
+			return err
+		}
+		if err := tx.Create(&rule).Error; err != nil {
+			return fmt.Errorf("%s: %w", fixture.Name, err)
+		}
+		result.count("rules", true)
+	}
+	return nil
+}
+
+// seedTemplates stores the built-in templates and the fixture ones
+func seedTemplates(tx *gorm.DB, templates []TemplateFixture, result *Result) error {
+	all := models.BuiltInTemplates()
+	for _, fixture := range templates {
+		template := models.SignalTemplate{
+			Name:        fixture.Name,
+			Description: fixture.Description,
+			Category:    fixture.Category,
+			Conditions:  string(fixture.Conditions),
+			Visibility:  fixture.Visibility,
+		}
+		if fixture.Author != "" {
+			var author models.User
+			if err := tx.Where("email = ?", fixture.Author).First(&author).Error; err != nil {
+				return fmt.Errorf("%s: author %s: %w", fixture.Name, fixture.Author, err)
+			}
+			template.AuthorID, template.AuthorName = author.SupabaseUserID, author.FullName
+		}
+		all = append(all, template)
+	}
+
+	for _, template := range all {
+		var existing models.SignalTemplate
+		if err := tx.Unscoped().Where("name = ?", template.Name).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			result.count("templates", false)
+			continue
+		}
+		if template.Visibility == "" {
+			template.Visibility = "public"
+		}
+		if err := tx.Create(&template).Error; err != nil {
+			return fmt.Errorf("%s: %w", template.Name, err)
+		}
+		result.count("templates", true)
+	}
+	return nil
+}
+
+// seedUsers stores the users, then the holdings and watched stocks they don't have yet. Holdings
+// are valued at the latest seeded close.
+func seedUsers(tx *gorm.DB, users []UserFixture, stocks map[string]*models.Stock, result *Result) error {
+	for _, fixture := range users {
+		user := models.User{
+			SupabaseUserID: demoUserPrefix + strings.SplitN(fixture.Email, "@", 2)[0],
+			Email:          fixture.Email,
+			FullName:       fixture.FullName,
+			Role:           fixture.Role,
+			IsActive:       true,
+			EmailVerified:  true,
+			Preferences:    "{}",
+		}
+		created := tx.Where(models.User{Email: fixture.Email}).FirstOrCreate(&user)
+		if created.Error != nil {
+			return fmt.Errorf("%s: %w", fixture.Email, created.Error)
+		}
+		result.count("users", created.RowsAffected > 0)
+
+		for _, holding := range fixture.Portfolio {
+			stock, ok := stocks[holding.Code]
+			if !ok {
+				return fmt.Errorf("%s holds %s, which is not a fixture stock", fixture.Email, holding.Code)
+			}
+			var closes []decimal.Decimal
+			if err := tx.Model(&models.StockPrice{}).Where("stock_id = ?", stock.ID).
+				Order("date DESC").Limit(holding.BoughtDaysAgo+1).Pluck("close", &closes).Error; err != nil {
+				return err
+			}
+			if len(closes) == 0 {
+				return fmt.Errorf("%s has no prices", holding.Code)
+			}
+			quantity := decimal.NewFromInt(holding.Quantity)
+			avg, last := closes[len(closes)-1], closes[0]
+			cost, value := avg.Mul(quantity), last.Mul(quantity)
+			position := models.Portfolio{
+				UserID:        user.ID,
+				StockID:       stock.ID,
+				Quantity:      holding.Quantity,
+				AvgPrice:      avg,
+				CurrentPrice:  last,
+				TotalCost:     cost,
+				MarketValue:   value,
+				UnrealizedPnL: value.Sub(cost),
+			}
+			if cost.IsPositive() {
+				position.UnrealizedPnLPercent = value.Sub(cost).Div(cost).Mul(decimal.NewFromInt(100)).Round(4)
+			}
+			created := tx.Where(models.Portfolio{UserID: user.ID, StockID: stock.ID}).FirstOrCreate(&position)
+			if created.Error != nil {
+				return fmt.Errorf("%s %s: %w", fixture.Email, holding.Code, created.Error)
+			}
+			result.count("portfolios", created.RowsAffected > 0)
+		}
+
+		for _, code := range fixture.Watchlist {
+			stock, ok := stocks[code]
+			if !ok {
+				return fmt.Errorf("%s watches %s, which is not a fixture stock", fixture.Email, code)
+			}
+			item := models.Watchlist{UserID: user.ID, StockID: stock.ID}
+			created := tx.Where(models.Watchlist{UserID: user.ID, StockID: stock.ID}).FirstOrCreate(&item)
+			if created.Error != nil {
+				return fmt.Errorf("%s %s: %w", fixture.Email, code, created.Error)
+			}
+			result.count("watchlists", created.RowsAffected > 0)
+		}
+	}
+	return nil
+}
+
+// lastSessions returns the last n bars of an oldest-first history, all of them when n is 0
+func lastSessions(history []services.StockPriceData, n int) []services.StockPriceData {
+	if n > 0 && len(history) > n {
+		return history[len(history)-n:]
+	}
+	return history
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Outcomes of a self-test check
+const (
+	SelfTestPass = "pass"
+	SelfTestFail = "fail"
+	SelfTestSkip = "skip" // the dependency is not configured
+)
+
+// selfTestTimeout bounds each check, so one hanging dependency doesn't hold up the report
+const selfTestTimeout = 15 * time.Second
+
+// selfTestCode is the stock fetched from the market data providers
+const selfTestCode = "VNM"
+
+// SelfTestCheck is the outcome of checking one external dependency
+type SelfTestCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SelfTestReport is the outcome of a self-test. It passes when no check failed; skipped checks
+// are dependencies that are not configured.
+type SelfTestReport struct {
+	Passed     bool            `json:"passed"`
+	StartedAt  time.Time       `json:"started_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Passes     int             `json:"passes"`
+	Failures   int             `json:"failures"`
+	Skipped    int             `json:"skipped"`
+	Checks     []SelfTestCheck `json:"checks"`
+}
+
+// selfTest checks one dependency: it returns a detail on success, errSelfTestSkipped wrapped with
+// the reason when the dependency is not configured, or the failure
+type selfTest struct {
+	name  string
+	check func(ctx context.Context) (string, error)
+}
+
+var errSelfTestSkipped = errors.New("not configured")
+
+// RunSelfTest checks the connectivity and credentials of the external dependencies concurrently:
+// Postgres, Supabase, MongoDB, VNDirect, SSI and the operator alert and push channels. Checks only
+// read or authenticate; no alert, email or notification is sent. db may be nil when the database
+// is unreachable, which fails the Postgres check.
+func RunSelfTest(ctx context.Context, db *gorm.DB) *SelfTestReport {
+	tests := []selfTest{
+		{"postgres", func(ctx context.Context) (string, error) { return checkPostgres(ctx, db) }},
+		{"supabase", checkSupabase},
+		{"mongodb", checkMongo},
+		{"vndirect", checkVNDirect},
+		{"ssi", checkSSI},
+		{"telegram", GlobalOperatorAlerts.checkTelegram},
+		{"email", GlobalOperatorAlerts.checkEmail},
+		{"push", GlobalPushService.checkCredentials},
+	}
+
+	report := &SelfTestReport{StartedAt: time.Now(), Checks: make([]SelfTestCheck, len(tests))}
+	var wg sync.WaitGroup
+	for i, test := range tests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := test.check(checkCtx)
+			result := SelfTestCheck{Name: test.name, Status: SelfTestPass, Detail: detail}
+			switch {
+			case errors.Is(err, errSelfTestSkipped):
+				result.Status, result.Detail = SelfTestSkip, err.Error()
+			case err != nil:
+				result.Status, result.Error = SelfTestFail, err.Error()
+			}
+			result.DurationMs = time.Since(start).Milliseconds()
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		switch check.Status {
+		case SelfTestPass:
+			report.Passes++
+		case SelfTestFail:
+			report.Failures++
+		default:
+			report.Skipped++
+		}
+	}
+	// Failures first, then by name
+	sort.SliceStable(report.Checks, func(i, j int) bool {
+		fi, fj := report.Checks[i].Status == SelfTestFail, report.Checks[j].Status == SelfTestFail
+		if fi != fj {
+			return fi
+		}
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	report.Passed = report.Failures == 0
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report
+}
+
+func checkPostgres(ctx context.Context, db *gorm.DB) (string, error) {
+	if db == nil {
+		return "", errors.New("database not connected")
+	}
+	var version string
+	if err := db.WithContext(ctx).Raw("SELECT version()").Scan(&version).Error; err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func checkSupabase(ctx context.Context) (string, error) {
+	if os.Getenv("SUPABASE_URL") == "" {
+		return "", fmt.Errorf("SUPABASE_URL %w", errSelfTestSkipped)
+	}
+	client, err := NewSupabaseDBClient()
+	if err != nil {
+		return "", err
+	}
+	if err := client.WithContext(ctx).TestConnection(); err != nil {
+		return "", err
+	}
+	key := "anon key"
+	if client.ServiceKey != "" {
+		key = "service key"
+	}
+	return "REST API reachable with the " + key, nil
+}
+
+func checkMongo(ctx context.Context) (string, error) {
+	if os.Getenv("MONGODB_URI") == "" {
+		return "", fmt.Errorf("MONGODB_URI %w", errSelfTestSkipped)
+	}
+	if GlobalMongoClient == nil || !GlobalMongoClient.IsConfigured() {
+		lastError := "client not initialized"
+		if GlobalMongoClient != nil && GlobalMongoClient.GetLastError() != "" {
+			lastError = GlobalMongoClient.GetLastError()
+		}
+		return "", errors.New(lastError)
+	}
+	GlobalMongoClient.mu.RLock()
+	client := GlobalMongoClient.client
+	GlobalMongoClient.mu.RUnlock()
+	if err := client.Ping(ctx, nil); err != nil {
+		return "", err
+	}
+	return "ping ok", nil
+}
+
+func checkVNDirect(ctx context.Context) (string, error) {
+	if GlobalPriceService == nil {
+		return "", errors.New("price service not initialized")
+	}
+	resp, err := GlobalPriceService.FetchStockPrice(selfTestCode, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("no price returned for %s", selfTestCode)
+	}
+	return fmt.Sprintf("%s last priced on %s%s", selfTestCode, resp.Data[0].Date, fakeProviderNote()), nil
+}
+
+func checkSSI(ctx context.Context) (string, error) {
+	book, err := FetchOrderBook(&http.Client{Timeout: selfTestTimeout}, selfTestCode)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s order book with %d bid levels%s", selfTestCode, len(book.Bids), fakeProviderNote()), nil
+}
+
+// notConfigured skips a check whose variable is unset, and fails it when the variable is set but
+// the service didn't initialize, e.g. because a companion variable is missing
+func notConfigured(variable, service string) error {
+	if os.Getenv(variable) == "" {
+		return fmt.Errorf("%s %w", variable, errSelfTestSkipped)
+	}
+	return fmt.Errorf("%s is set but %s failed to initialize, see the startup logs", variable, service)
+}
+
+// fakeProviderNote flags provider checks answered by the synthetic providers
+func fakeProviderNote() string {
+	if GlobalFakeProviders != nil {
+		return " (fake providers)"
+	}
+	return ""
+}
+
+// checkTelegram checks the bot token and that the bot can reach the alert chat, without sending
+func (a *OperatorAlerter) checkTelegram(ctx context.Context) (string, error) {
+	if a == nil || a.telegramToken == "" {
+		return "", notConfigured("ALERT_TELEGRAM_BOT_TOKEN", "operator alerts")
+	}
+	target := fmt.Sprintf("https://api.telegram.org/bot%s/getChat?chat_id=%s", a.telegramToken, url.QueryEscape(a.telegramChatID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", errors.New("invalid bot token")
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		// The URL holds the bot token, which must not end up in the report
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var chat struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			Type  string `json:"type"`
+			Title string `json:"title"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return "", fmt.Errorf("status %d: invalid response", resp.StatusCode)
+	}
+	if !chat.OK {
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, chat.Description)
+	}
+	return fmt.Sprintf("bot can reach %s chat %s", chat.Result.Type, chat.Result.Title), nil
+}
+
+// checkEmail connects to the SMTP server and authenticates, without sending
+func (a *OperatorAlerter) checkEmail(ctx context.Context) (string, error) {
+	if a == nil || a.smtpAddr == "" {
+		return "", notConfigured("ALERT_SMTP_HOST", "operator alerts")
+	}
+	done := make(chan error, 1)
+	go func() { done <- a.dialSMTP() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", err
+		}
+		return "connected to " + a.smtpAddr, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("connect to %s: %w", a.smtpAddr, ctx.Err())
+	}
+}
+
+func (a *OperatorAlerter) dialSMTP() error {
+	client, err := smtp.Dial(a.smtpAddr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: a.smtpHost}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if a.smtpUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", a.smtpUsername, a.smtpPassword, a.smtpHost)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	return client.Quit()
+}
+
+// checkCredentials mints an FCM access token, which proves the service account or the metadata
+// server credentials
+func (s *PushService) checkCredentials(ctx context.Context) (string, error) {
+	if s == nil && os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+		return "", notConfigured("GOOGLE_APPLICATION_CREDENTIALS", "push notifications")
+	}
+	if s == nil {
+		return "", notConfigured("FCM_PROJECT_ID", "push notifications")
+	}
+	if _, err := s.token(ctx); err != nil {
+		return "", err
+	}
+	return "access token issued for project " + s.projectID, nil
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Limits of shared watchlists
+const (
+	MaxSharedWatchlistsPerOwner  = 10
+	MaxSharedWatchlistMembers    = 20 // invited and active, per list
+	MaxSharedWatchlistItems      = 200
+	MaxSharedWatchlistNameLength = 100 // characters
+	MaxSharedWatchlistNoteLength = 500 // characters
+)
+
+// SharedWatchlistOwnerTiers are the memberships that can create shared watchlists; members need
+// no membership
+var SharedWatchlistOwnerTiers = map[string]bool{"premium": true, "enterprise": true}
+
+var (
+	// ErrInvalidSharedWatchlist is wrapped by the errors of requests that break a rule or limit
+	ErrInvalidSharedWatchlist = errors.New("invalid shared watchlist")
+	// ErrSharedWatchlistNotFound is returned for lists the user is not the owner or an active member of
+	ErrSharedWatchlistNotFound = errors.New("shared watchlist not found")
+	// ErrSharedWatchlistForbidden is returned when the user's role doesn't allow the change
+	ErrSharedWatchlistForbidden = errors.New("not allowed on this shared watchlist")
+	// ErrSharedWatchlistConflict is returned when the list changed since the version the edit was made against
+	ErrSharedWatchlistConflict = errors.New("shared watchlist was changed by someone else")
+)
+
+// canEditSharedWatchlist reports whether role can change the name and stocks of a list
+func canEditSharedWatchlist(role string) bool {
+	return role == models.SharedWatchlistRoleOwner || role == models.SharedWatchlistRoleEditor
+}
+
+// validSharedWatchlistMemberRole reports whether role can be given to a member
+func validSharedWatchlistMemberRole(role string) bool {
+	return role == models.SharedWatchlistRoleEditor || role == models.SharedWatchlistRoleViewer
+}
+
+// normalizeSharedWatchlistName trims a list name and checks its length
+func normalizeSharedWatchlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidSharedWatchlist)
+	}
+	if utf8.RuneCountInString(name) > MaxSharedWatchlistNameLength {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidSharedWatchlist, MaxSharedWatchlistNameLength)
+	}
+	return name, nil
+}
+
+// ListSharedWatchlists returns the lists the user owns or is an active member of, most recently
+// changed first, with the user's role on each
+func ListSharedWatchlists(db *gorm.DB, userID string) ([]models.SharedWatchlist, error) {
+	var memberships []models.SharedWatchlistMember
+	if err := db.Where("user_id = ? AND status = ?", userID, models.SharedWatchlistMemberActive).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	roles := make(map[uint]string, len(memberships))
+	ids := make([]uint, 0, len(memberships))
+	for _, member := range memberships {
+		roles[member.WatchlistID] = member.Role
+		ids = append(ids, member.WatchlistID)
+	}
+
+	query := db.Where("owner_user_id = ?", userID)
+	if len(ids) > 0 {
+		query = db.Where("owner_user_id = ? OR id IN ?", userID, ids)
+	}
+	var lists []models.SharedWatchlist
+	if err := query.Order("updated_at DESC").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	for i := range lists {
+		if lists[i].OwnerUserID == userID {
+			lists[i].Role = models.SharedWatchlistRoleOwner
+		} else {
+			lists[i].Role = roles[lists[i].ID]
+		}
+	}
+	return lists, nil
+}
+
+// GetSharedWatchlist returns a list with its stocks and members if the user is its owner or an
+// active member, with the user's role on it
+func GetSharedWatchlist(db *gorm.DB, id uint, userID string) (*models.SharedWatchlist, error) {
+	var list models.SharedWatchlist
+	err := db.Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("stock_code") }).
+		Preload("Members", func(tx *gorm.DB) *gorm.DB { return tx.Order("created_at") }).
+		First(&list, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrSharedWatchlistNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if list.OwnerUserID == userID {
+		list.Role = models.SharedWatchlistRoleOwner
+		return &list, nil
+	}
+	for _, member := range list.Members {
+		if member.UserID == userID && member.Status == models.SharedWatchlistMemberActive {
+			list.Role = member.Role
+			return &list, nil
+		}
+	}
+	return nil, ErrSharedWatchlistNotFound
+}
+
+// CreateSharedWatchlist creates an empty list owned by the user; the caller checks the owner's
+// membership against SharedWatchlistOwnerTiers
+func CreateSharedWatchlist(db *gorm.DB, ownerID, name string) (*models.SharedWatchlist, error) {
+	name, err := normalizeSharedWatchlistName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := db.Model(&models.SharedWatchlist{}).Where("owner_user_id = ?", ownerID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= MaxSharedWatchlistsPerOwner {
+		return nil, fmt.Errorf("%w: at most %d shared watchlists", ErrInvalidSharedWatchlist, MaxSharedWatchlistsPerOwner)
+	}
+
+	list := &models.SharedWatchlist{OwnerUserID: ownerID, Name: name, Version: 1}
+	if err := db.Create(list).Error; err != nil {
+		return nil, err
+	}
+	list.Role = models.SharedWatchlistRoleOwner
+	return list, nil
+}
+
+// editSharedWatchlist runs change in a transaction after moving the list from version to the next
+// one, failing with ErrSharedWatchlistConflict when it is no longer at version. This is what keeps
+// two members editing at once from silently undoing each other's changes.
+func editSharedWatchlist(db *gorm.DB, list *models.SharedWatchlist, version int, change func(tx *gorm.DB) error) error {
+	if !canEditSharedWatchlist(list.Role) {
+		return ErrSharedWatchlistForbidden
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.SharedWatchlist{}).Where("id = ? AND version = ?", list.ID, version).
+			UpdateColumns(map[string]interface{}{"version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSharedWatchlistConflict
+		}
+		return change(tx)
+	})
+	if err != nil {
+		return err
+	}
+	list.Version = version + 1
+	return nil
+}
+
+// RenameSharedWatchlist renames a list the user can edit
+func RenameSharedWatchlist(db *gorm.DB, list *models.SharedWatchlist, userID, name string, version int) error {
+	name, err := normalizeSharedWatchlistName(name)
+	if err != nil {
+		return err
+	}
+	previous := list.Name
+	err = editSharedWatchlist(db, list, version, func(tx *gorm.DB) error {
+		return tx.Model(&models.SharedWatchlist{}).Where("id = ?", list.ID).UpdateColumn("name", name).Error
+	})
+	if err != nil {
+		return err
+	}
+	list.Name = name
+	notifySharedWatchlist(db, list, userID, "Watchlist renamed",
+		fmt.Sprintf("%q was renamed to %q", previous, name), map[string]interface{}{"action": "renamed"})
+	return nil
+}
+
+// AddSharedWatchlistItem adds a stock to a list the user can edit, or updates its note when the
+// stock is already on it
+func AddSharedWatchlistItem(db *gorm.DB, list *models.SharedWatchlist, userID, code, note string, version int) (*models.SharedWatchlistItem, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	note = strings.TrimSpace(note)
+	if code == "" || len(code) > 20 {
+		return nil, fmt.Errorf("%w: invalid stock code", ErrInvalidSharedWatchlist)
+	}
+	if utf8.RuneCountInString(note) > MaxSharedWatchlistNoteLength {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidSharedWatchlist, MaxSharedWatchlistNoteLength)
+	}
+
+	var item models.SharedWatchlistItem
+	added := false
+	err := editSharedWatchlist(db, list, version, func(tx *gorm.DB) error {
+		err := tx.Where("watchlist_id = ? AND stock_code = ?", list.ID, code).First(&item).Error
+		if err == nil {
+			item.Note = note
+			return tx.Model(&item).UpdateColumn("note", note).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.SharedWatchlistItem{}).Where("watchlist_id = ?", list.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxSharedWatchlistItems {
+			return fmt.Errorf("%w: at most %d stocks per list", ErrInvalidSharedWatchlist, MaxSharedWatchlistItems)
+		}
+		item = models.SharedWatchlistItem{WatchlistID: list.ID, StockCode: code, Note: note, AddedBy: userID}
+		added = true
+		return tx.Create(&item).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	title, action := "Stock note updated", "note_updated"
+	if added {
+		title, action = "Stock added", "stock_added"
+	}
+	notifySharedWatchlist(db, list, userID, title, fmt.Sprintf("%s on %q", code, list.Name),
+		map[string]interface{}{"action": action, "stock_code": code})
+	return &item, nil
+}
+
+// RemoveSharedWatchlistItem removes a stock from a list the user can edit, reporting whether it was on it
+func RemoveSharedWatchlistItem(db *gorm.DB, list *models.SharedWatchlist, userID, code string, version int) (bool, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	err := editSharedWatchlist(db, list, version, func(tx *gorm.DB) error {
+		result := tx.Where("watchlist_id = ? AND stock_code = ?", list.ID, code).Delete(&models.SharedWatchlistItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound // roll the version back: nothing changed
+		}
+		return nil
+	})
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	notifySharedWatchlist(db, list, userID, "Stock removed", fmt.Sprintf("%s was removed from %q", code, list.Name),
+		map[string]interface{}{"action": "stock_removed", "stock_code": code})
+	return true, nil
+}
+
+// DeleteSharedWatchlist deletes a list with its stocks and members; only the owner can
+func DeleteSharedWatchlist(db *gorm.DB, list *models.SharedWatchlist, userID string) error {
+	if list.Role != models.SharedWatchlistRoleOwner {
+		return ErrSharedWatchlistForbidden
+	}
+	recipients := sharedWatchlistRecipients(db, list, userID) // before the members are gone
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("watchlist_id = ?", list.ID).Delete(&models.SharedWatchlistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("watchlist_id = ?", list.ID).Delete(&models.SharedWatchlistMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.SharedWatchlist{}, list.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	notifySharedWatchlistRecipients(db, recipients, list, "Watchlist deleted", fmt.Sprintf("%q is no longer shared with you", list.Name),
+		map[string]interface{}{"action": "deleted"})
+	return nil
+}
+
+// InviteSharedWatchlistMember invites an email address to a list with a role; only the owner can.
+// inviteeID is the Supabase user already registered with that email, if any, who is notified now;
+// others see the invitation once they sign up with it.
+func InviteSharedWatchlistMember(db *gorm.DB, list *models.SharedWatchlist, ownerID, email, role, inviteeID string) (*models.SharedWatchlistMember, error) {
+	if list.Role != models.SharedWatchlistRoleOwner {
+		return nil, ErrSharedWatchlistForbidden
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidSharedWatchlist)
+	}
+	email = strings.ToLower(address.Address)
+	if !validSharedWatchlistMemberRole(role) {
+		return nil, fmt.Errorf("%w: role must be viewer or editor", ErrInvalidSharedWatchlist)
+	}
+	if inviteeID == ownerID {
+		return nil, fmt.Errorf("%w: you already own this list", ErrInvalidSharedWatchlist)
+	}
+
+	var member models.SharedWatchlistMember
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.SharedWatchlistMember{}).Where("watchlist_id = ? AND email = ?", list.ID, email).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: %s is already invited", ErrInvalidSharedWatchlist, email)
+		}
+		var count int64
+		if err := tx.Model(&models.SharedWatchlistMember{}).Where("watchlist_id = ?", list.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxSharedWatchlistMembers {
+			return fmt.Errorf("%w: at most %d members per list", ErrInvalidSharedWatchlist, MaxSharedWatchlistMembers)
+		}
+
+		member = models.SharedWatchlistMember{
+			WatchlistID: list.ID,
+			Email:       email,
+			Role:        role,
+			Status:      models.SharedWatchlistMemberInvited,
+			InvitedBy:   ownerID,
+		}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	NotifyUser(db, inviteeID, models.NotificationCategoryWatchlist, "Watchlist invitation",
+		fmt.Sprintf("You were invited to %q as %s", list.Name, role),
+		map[string]interface{}{"action": "invited", "watchlist_id": list.ID, "invitation_id": member.ID})
+	return &member, nil
+}
+
+// loadSharedWatchlistMember returns a member row of list
+func loadSharedWatchlistMember(db *gorm.DB, list *models.SharedWatchlist, memberID uint) (*models.SharedWatchlistMember, error) {
+	var member models.SharedWatchlistMember
+	err := db.Where("id = ? AND watchlist_id = ?", memberID, list.ID).First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrSharedWatchlistNotFound
+	}
+	return &member, err
+}
+
+// UpdateSharedWatchlistMemberRole changes the role of a member; only the owner can
+func UpdateSharedWatchlistMemberRole(db *gorm.DB, list *models.SharedWatchlist, memberID uint, role string) (*models.SharedWatchlistMember, error) {
+	if list.Role != models.SharedWatchlistRoleOwner {
+		return nil, ErrSharedWatchlistForbidden
+	}
+	if !validSharedWatchlistMemberRole(role) {
+		return nil, fmt.Errorf("%w: role must be viewer or editor", ErrInvalidSharedWatchlist)
+	}
+	member, err := loadSharedWatchlistMember(db, list, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == role {
+		return member, nil
+	}
+	if err := db.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	NotifyUser(db, member.UserID, models.NotificationCategoryWatchlist, "Watchlist role changed",
+		fmt.Sprintf("You are now %s on %q", role, list.Name),
+		map[string]interface{}{"action": "role_changed", "watchlist_id": list.ID, "role": role})
+	return member, nil
+}
+
+// RemoveSharedWatchlistMember removes a member or withdraws an invitation. The owner can remove
+// anyone; a member can only remove themselves, leaving the list.
+func RemoveSharedWatchlistMember(db *gorm.DB, list *models.SharedWatchlist, userID string, memberID uint) error {
+	member, err := loadSharedWatchlistMember(db, list, memberID)
+	if err != nil {
+		return err
+	}
+	leaving := member.UserID != "" && member.UserID == userID
+	if list.Role != models.SharedWatchlistRoleOwner && !leaving {
+		return ErrSharedWatchlistForbidden
+	}
+	if err := db.Delete(member).Error; err != nil {
+		return err
+	}
+
+	if leaving {
+		NotifyUser(db, list.OwnerUserID, models.NotificationCategoryWatchlist, "Member left watchlist",
+			fmt.Sprintf("%s left %q", member.Email, list.Name),
+			map[string]interface{}{"action": "member_left", "watchlist_id": list.ID})
+	} else {
+		NotifyUser(db, member.UserID, models.NotificationCategoryWatchlist, "Removed from watchlist",
+			fmt.Sprintf("%q is no longer shared with you", list.Name),
+			map[string]interface{}{"action": "removed", "watchlist_id": list.ID})
+	}
+	return nil
+}
+
+// SharedWatchlistInvitation is a pending invitation with the name of the list
+type SharedWatchlistInvitation struct {
+	models.SharedWatchlistMember
+	WatchlistName string `json:"watchlist_name"`
+}
+
+// ListSharedWatchlistInvitations returns the pending invitations to email
+func ListSharedWatchlistInvitations(db *gorm.DB, email string) ([]SharedWatchlistInvitation, error) {
+	invitations := []SharedWatchlistInvitation{}
+	err := db.Model(&models.SharedWatchlistMember{}).
+		Select("shared_watchlist_members.*, shared_watchlists.name AS watchlist_name").
+		Joins("JOIN shared_watchlists ON shared_watchlists.id = shared_watchlist_members.watchlist_id").
+		Where("shared_watchlist_members.email = ? AND shared_watchlist_members.status = ?", strings.ToLower(email), models.SharedWatchlistMemberInvited).
+		Order("shared_watchlist_members.created_at DESC").Scan(&invitations).Error
+	return invitations, err
+}
+
+// loadSharedWatchlistInvitation returns a pending invitation to email
+func loadSharedWatchlistInvitation(db *gorm.DB, invitationID uint, email string) (*models.SharedWatchlistMember, error) {
+	var member models.SharedWatchlistMember
+	err := db.Where("id = ? AND email = ? AND status = ?", invitationID, strings.ToLower(email), models.SharedWatchlistMemberInvited).
+		First(&member).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrSharedWatchlistNotFound
+	}
+	return &member, err
+}
+
+// AcceptSharedWatchlistInvitation makes the user an active member of the list an invitation to
+// their email is for
+func AcceptSharedWatchlistInvitation(db *gorm.DB, invitationID uint, userID, email string) (*models.SharedWatchlistMember, error) {
+	member, err := loadSharedWatchlistInvitation(db, invitationID, email)
+	if err != nil {
+		return nil, err
+	}
+	var list models.SharedWatchlist
+	if err := db.First(&list, member.WatchlistID).Error; err != nil {
+		return nil, err
+	}
+	if list.OwnerUserID == userID {
+		return nil, fmt.Errorf("%w: you already own this list", ErrInvalidSharedWatchlist)
+	}
+
+	now := time.Now()
+	member.UserID, member.Status, member.AcceptedAt = userID, models.SharedWatchlistMemberActive, &now
+	if err := db.Model(member).Select("user_id", "status", "accepted_at").Updates(member).Error; err != nil {
+		return nil, err
+	}
+	NotifyUser(db, list.OwnerUserID, models.NotificationCategoryWatchlist, "Invitation accepted",
+		fmt.Sprintf("%s joined %q", member.Email, list.Name),
+		map[string]interface{}{"action": "member_joined", "watchlist_id": list.ID})
+	return member, nil
+}
+
+// DeclineSharedWatchlistInvitation removes an invitation to the user's email
+func DeclineSharedWatchlistInvitation(db *gorm.DB, invitationID uint, email string) error {
+	member, err := loadSharedWatchlistInvitation(db, invitationID, email)
+	if err != nil {
+		return err
+	}
+	return db.Delete(member).Error
+}
+
+// sharedWatchlistRecipients returns the owner and active members of a list other than the user
+// who made a change
+func sharedWatchlistRecipients(db *gorm.DB, list *models.SharedWatchlist, actorID string) []string {
+	var memberIDs []string
+	if err := db.Model(&models.SharedWatchlistMember{}).
+		Where("watchlist_id = ? AND status = ? AND user_id <> ''", list.ID, models.SharedWatchlistMemberActive).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("Failed to load members of shared watchlist %d: %v", list.ID, err)
+	}
+
+	recipients := make([]string, 0, len(memberIDs)+1)
+	for _, userID := range append([]string{list.OwnerUserID}, memberIDs...) {
+		if userID != actorID {
+			recipients = append(recipients, userID)
+		}
+	}
+	return recipients
+}
+
+// notifySharedWatchlist tells the owner and active members of a list about a change made by actorID
+func notifySharedWatchlist(db *gorm.DB, list *models.SharedWatchlist, actorID, title, message string, data map[string]interface{}) {
+	notifySharedWatchlistRecipients(db, sharedWatchlistRecipients(db, list, actorID), list, title, message, data)
+}
+
+func notifySharedWatchlistRecipients(db *gorm.DB, recipients []string, list *models.SharedWatchlist, title, message string, data map[string]interface{}) {
+	data["watchlist_id"] = list.ID
+	data["version"] = list.Version
+	for _, recipient := range recipients {
+		NotifyUser(db, recipient, models.NotificationCategoryWatchlist, title, message, data)
+	}
+}
@@ -0,0 +1,212 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go_backend_project/services"
+)
+
+// MaxCompositeScoreTerms caps the number of weighted fields in a user-defined composite score
+const MaxCompositeScoreTerms = 10
+
+// Normalization modes of composite score terms
+const (
+	NormalizeRank   = "rank"   // percentile of the value within the universe, 0-100
+	NormalizeZScore = "zscore" // standard deviations from the universe mean
+	NormalizeNone   = "none"   // raw indicator value
+)
+
+// CompositeScoreTerm is one weighted indicator field of a composite score
+type CompositeScoreTerm struct {
+	Field  string  `json:"field"`
+	Weight float64 `json:"weight"`
+}
+
+// CompositeScoreSpec defines a user ranking: either weighted terms, each normalized across the
+// universe before weighting so fields of different scales combine, or a raw score expression.
+// Filter optionally restricts the universe to the stocks for which it evaluates non-zero.
+type CompositeScoreSpec struct {
+	Terms      []CompositeScoreTerm `json:"terms"`
+	Expression string               `json:"expression"`
+	Normalize  string               `json:"normalize"` // rank (default), zscore or none; terms only
+	Filter     string               `json:"filter"`
+}
+
+// CompositeScore is a validated, compiled composite score
+type CompositeScore struct {
+	spec       CompositeScoreSpec
+	expression *Expression
+	filter     *Expression
+}
+
+// CompositeScoreResult is one ranked stock
+type CompositeScoreResult struct {
+	Code   string             `json:"code"`
+	Rank   int                `json:"rank"`
+	Score  float64            `json:"score"`
+	Values map[string]float64 `json:"values,omitempty"` // raw value of each term field
+}
+
+// NewCompositeScore validates a spec and compiles its expressions
+func NewCompositeScore(spec CompositeScoreSpec) (*CompositeScore, error) {
+	spec.Expression = strings.TrimSpace(spec.Expression)
+	spec.Normalize = strings.ToLower(strings.TrimSpace(spec.Normalize))
+	if spec.Normalize == "" {
+		spec.Normalize = NormalizeRank
+	}
+	switch spec.Normalize {
+	case NormalizeRank, NormalizeZScore, NormalizeNone:
+	default:
+		return nil, fmt.Errorf("normalize must be one of %s, %s or %s", NormalizeRank, NormalizeZScore, NormalizeNone)
+	}
+
+	score := &CompositeScore{}
+	switch {
+	case len(spec.Terms) > 0 && spec.Expression != "":
+		return nil, fmt.Errorf("use either terms or expression, not both")
+	case spec.Expression != "":
+		expr, err := CompileExpression(spec.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("expression: %w", err)
+		}
+		score.expression = expr
+	case len(spec.Terms) == 0:
+		return nil, fmt.Errorf("terms or expression is required")
+	case len(spec.Terms) > MaxCompositeScoreTerms:
+		return nil, fmt.Errorf("at most %d terms allowed", MaxCompositeScoreTerms)
+	default:
+		seen := make(map[string]bool, len(spec.Terms))
+		for i := range spec.Terms {
+			term := &spec.Terms[i]
+			term.Field = strings.ToLower(strings.TrimSpace(term.Field))
+			if _, ok := ExpressionFields[term.Field]; !ok {
+				return nil, fmt.Errorf("unknown field %q (available: %s)", term.Field, strings.Join(ExpressionFieldNames(), ", "))
+			}
+			if seen[term.Field] {
+				return nil, fmt.Errorf("field %s is used more than once", term.Field)
+			}
+			if math.IsNaN(term.Weight) || math.IsInf(term.Weight, 0) || term.Weight == 0 {
+				return nil, fmt.Errorf("weight of %s must be a non-zero number", term.Field)
+			}
+			seen[term.Field] = true
+		}
+	}
+
+	if spec.Filter = strings.TrimSpace(spec.Filter); spec.Filter != "" {
+		filter, err := CompileExpression(spec.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		score.filter = filter
+	}
+	score.spec = spec
+	return score, nil
+}
+
+// Spec returns the normalized specification of the score
+func (s *CompositeScore) Spec() CompositeScoreSpec {
+	return s.spec
+}
+
+// Rank scores the stocks passing the filter and returns them best first, ties broken by code
+func (s *CompositeScore) Rank(stocks map[string]*services.ExtendedStockIndicators) []CompositeScoreResult {
+	codes := make([]string, 0, len(stocks))
+	universe := make([]*services.ExtendedStockIndicators, 0, len(stocks))
+	for code, ind := range stocks {
+		if ind == nil || (s.filter != nil && s.filter.Eval(ind) == 0) {
+			continue
+		}
+		codes = append(codes, code)
+		universe = append(universe, ind)
+	}
+
+	results := make([]CompositeScoreResult, len(universe))
+	for i, ind := range universe {
+		results[i] = CompositeScoreResult{Code: codes[i]}
+		if s.expression != nil {
+			results[i].Score = s.expression.Eval(ind)
+		} else {
+			results[i].Values = make(map[string]float64, len(s.spec.Terms))
+		}
+	}
+
+	for _, term := range s.spec.Terms {
+		get := ExpressionFields[term.Field]
+		raw := make([]float64, len(universe))
+		for i, ind := range universe {
+			raw[i] = finiteOrZero(get(ind))
+			results[i].Values[term.Field] = raw[i]
+		}
+		for i, v := range normalizeValues(raw, s.spec.Normalize) {
+			results[i].Score += term.Weight * v
+		}
+	}
+
+	for i := range results {
+		results[i].Score = math.Round(results[i].Score*100) / 100
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Code < results[j].Code
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// normalizeValues rescales the values of one field across the universe
+func normalizeValues(values []float64, mode string) []float64 {
+	n := len(values)
+	out := make([]float64, n)
+	switch mode {
+	case NormalizeRank:
+		if n == 1 {
+			out[0] = 50
+			return out
+		}
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		for i, v := range values {
+			// Ties share the percentile of the middle of their run
+			below := sort.SearchFloat64s(sorted, v)
+			equal := sort.SearchFloat64s(sorted, math.Nextafter(v, math.Inf(1))) - below
+			out[i] = (float64(below) + float64(equal-1)/2) / float64(n-1) * 100
+		}
+	case NormalizeZScore:
+		if n == 0 {
+			return out
+		}
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(n)
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		std := math.Sqrt(variance / float64(n))
+		if std == 0 {
+			return out
+		}
+		for i, v := range values {
+			out[i] = (v - mean) / std
+		}
+	default:
+		copy(out, values)
+	}
+	return out
+}
+
+func finiteOrZero(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
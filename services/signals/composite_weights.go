@@ -0,0 +1,142 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"go_backend_project/services"
+
+	"gorm.io/gorm"
+)
+
+// CompositeWeightsConfigKey is the system_config key holding the composite strategy weights
+const CompositeWeightsConfigKey = "composite_strategy_weights"
+
+// StrategyWeights are the shares of each sub-strategy in the composite score; they sum to 1
+type StrategyWeights struct {
+	Momentum      float64 `json:"momentum"`
+	Trend         float64 `json:"trend"`
+	MeanReversion float64 `json:"mean_reversion"`
+	Breakout      float64 `json:"breakout"`
+}
+
+// Validate checks that every weight is within [0, 1] and that they sum to 1
+func (w StrategyWeights) Validate() error {
+	for name, v := range map[string]float64{
+		"momentum":       w.Momentum,
+		"trend":          w.Trend,
+		"mean_reversion": w.MeanReversion,
+		"breakout":       w.Breakout,
+	} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("%s weight must be between 0 and 1", name)
+		}
+	}
+	sum := w.Momentum + w.Trend + w.MeanReversion + w.Breakout
+	if math.Abs(sum-1) > 0.001 {
+		return fmt.Errorf("weights must sum to 1 (got %.3f)", sum)
+	}
+	return nil
+}
+
+// CompositeWeightConfig holds the default weights and the overrides per market regime
+type CompositeWeightConfig struct {
+	Default StrategyWeights            `json:"default"`
+	Regimes map[string]StrategyWeights `json:"regimes"`
+}
+
+// Validate checks every weight set and that regimes are known
+func (c CompositeWeightConfig) Validate() error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for regime, w := range c.Regimes {
+		switch regime {
+		case services.RegimeBull, services.RegimeBear, services.RegimeSideways:
+		default:
+			return fmt.Errorf("unknown regime: %s", regime)
+		}
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", regime, err)
+		}
+	}
+	return nil
+}
+
+// DefaultCompositeWeightConfig favours trend and breakout signals in bull markets,
+// mean reversion in sideways markets and defensive trend confirmation in bear markets
+func DefaultCompositeWeightConfig() CompositeWeightConfig {
+	return CompositeWeightConfig{
+		Default: StrategyWeights{Momentum: 0.30, Trend: 0.35, MeanReversion: 0.15, Breakout: 0.20},
+		Regimes: map[string]StrategyWeights{
+			services.RegimeBull:     {Momentum: 0.35, Trend: 0.30, MeanReversion: 0.05, Breakout: 0.30},
+			services.RegimeSideways: {Momentum: 0.20, Trend: 0.25, MeanReversion: 0.40, Breakout: 0.15},
+			services.RegimeBear:     {Momentum: 0.20, Trend: 0.45, MeanReversion: 0.25, Breakout: 0.10},
+		},
+	}
+}
+
+var (
+	compositeWeights   = DefaultCompositeWeightConfig()
+	compositeWeightsMu sync.RWMutex
+)
+
+// LoadCompositeWeights applies the weights stored in system_config, keeping defaults when none are stored
+func LoadCompositeWeights(db *gorm.DB) error {
+	var cfg CompositeWeightConfig
+	found, err := services.LoadSystemConfig(db, CompositeWeightsConfigKey, &cfg)
+	if err != nil || !found {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	setCompositeWeights(cfg)
+	return nil
+}
+
+// SaveCompositeWeights validates, stores and applies new composite weights
+func SaveCompositeWeights(db *gorm.DB, cfg CompositeWeightConfig) error {
+	if cfg.Regimes == nil {
+		cfg.Regimes = map[string]StrategyWeights{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := services.SaveSystemConfig(db, CompositeWeightsConfigKey, cfg); err != nil {
+		return err
+	}
+	setCompositeWeights(cfg)
+	dropSnapshotStrategy("composite")
+	return nil
+}
+
+func setCompositeWeights(cfg CompositeWeightConfig) {
+	compositeWeightsMu.Lock()
+	compositeWeights = cfg
+	compositeWeightsMu.Unlock()
+}
+
+// GetCompositeWeightConfig returns a copy of the active weight configuration
+func GetCompositeWeightConfig() CompositeWeightConfig {
+	compositeWeightsMu.RLock()
+	defer compositeWeightsMu.RUnlock()
+
+	cfg := CompositeWeightConfig{Default: compositeWeights.Default, Regimes: make(map[string]StrategyWeights, len(compositeWeights.Regimes))}
+	for regime, w := range compositeWeights.Regimes {
+		cfg.Regimes[regime] = w
+	}
+	return cfg
+}
+
+// CompositeWeights returns the weights used for a market regime
+func CompositeWeights(regime string) StrategyWeights {
+	compositeWeightsMu.RLock()
+	defer compositeWeightsMu.RUnlock()
+
+	if w, ok := compositeWeights.Regimes[regime]; ok {
+		return w
+	}
+	return compositeWeights.Default
+}
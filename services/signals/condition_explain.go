@@ -0,0 +1,202 @@
+package signals
+
+import (
+	"fmt"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+)
+
+// ConditionTrace is how one condition evaluated: the values compared and what it contributed
+type ConditionTrace struct {
+	ConditionID      uint    `json:"condition_id"`
+	Name             string  `json:"name,omitempty"`
+	Indicator        string  `json:"indicator"`
+	Operator         string  `json:"operator"`
+	CompareIndicator string  `json:"compare_indicator,omitempty"`
+	ActualValue      float64 `json:"actual_value"`
+	CompareValue     float64 `json:"compare_value,omitempty"` // value of CompareIndicator
+	// Threshold is what ActualValue was compared with: CompareValue when there is a compare
+	// indicator or a cross operator, else the condition's value. Between checks ActualValue, or
+	// its ratio to CompareValue, against [Threshold, Threshold2].
+	Threshold       float64 `json:"threshold"`
+	Threshold2      float64 `json:"threshold2,omitempty"`
+	Passed          bool    `json:"passed"`
+	Weight          int     `json:"weight"`
+	Score           int     `json:"score"` // weight contributed to the group score
+	Required        bool    `json:"required"`
+	LogicalOperator string  `json:"logical_operator,omitempty"` // joins the condition to the ones before it
+	Chain           bool    `json:"chain"`                      // the logic chain's value after this condition
+	Message         string  `json:"message"`
+}
+
+// GroupTrace is how a condition group evaluated. The group passes when no required condition
+// failed and the logic chain, folded left to right, ends true; DecidedBy names what settled it.
+type GroupTrace struct {
+	GroupID    uint             `json:"group_id"`
+	Name       string           `json:"name"`
+	Required   bool             `json:"required"` // the rule requires the group to pass
+	Passed     bool             `json:"passed"`
+	TotalScore int              `json:"total_score"`
+	MaxScore   int              `json:"max_score"`
+	Logic      bool             `json:"logic"`      // value of the logic chain
+	DecidedBy  string           `json:"decided_by"` // what settled the outcome
+	Conditions []ConditionTrace `json:"conditions"`
+}
+
+// RuleTrace is how a rule evaluated against one stock, group by group
+type RuleTrace struct {
+	RuleID       uint         `json:"rule_id"`
+	Name         string       `json:"name"`
+	SignalType   string       `json:"signal_type"`
+	IsActive     bool         `json:"is_active"`
+	Triggered    bool         `json:"triggered"`
+	TotalScore   int          `json:"total_score"`
+	MaxScore     int          `json:"max_score"`
+	ScorePercent int          `json:"score_percent"`
+	MinScore     int          `json:"min_score"`
+	DecidedBy    string       `json:"decided_by"`
+	Groups       []GroupTrace `json:"groups"`
+	MissingGroup []uint       `json:"missing_groups,omitempty"` // referenced groups that no longer exist, skipped
+}
+
+// ExplainConditionGroup evaluates a group like EvaluateConditionGroup and traces every condition
+// and step of the logic chain
+func (e *ConditionEvaluator) ExplainConditionGroup(group *models.SignalConditionGroup, ind *services.ExtendedStockIndicators) *GroupTrace {
+	result := e.EvaluateConditionGroup(group, ind)
+	trace := &GroupTrace{
+		GroupID:    group.ID,
+		Name:       group.Name,
+		Passed:     result.Passed,
+		TotalScore: result.TotalScore,
+		MaxScore:   result.MaxScore,
+		Conditions: make([]ConditionTrace, 0, len(result.Results)),
+	}
+
+	// Fold the chain as EvaluateConditionGroup does, remembering the last condition that set
+	// its value: a failing AND forces false and a passing OR forces true
+	decider := -1
+	var failedRequired *models.SignalCondition
+	for i, r := range result.Results {
+		cond := &group.Conditions[i]
+		switch {
+		case i == 0:
+			trace.Logic, decider = r.Passed, 0
+		case cond.LogicalOperator == models.LogicalAnd:
+			trace.Logic = trace.Logic && r.Passed
+			if !r.Passed {
+				decider = i
+			}
+		case cond.LogicalOperator == models.LogicalOr:
+			trace.Logic = trace.Logic || r.Passed
+			if r.Passed {
+				decider = i
+			}
+		}
+		if cond.IsRequired && !r.Passed && failedRequired == nil {
+			failedRequired = cond
+		}
+		trace.Conditions = append(trace.Conditions, e.traceCondition(cond, &r, ind, trace.Logic, i > 0))
+	}
+
+	switch {
+	case len(result.Results) == 0:
+		trace.Logic = true
+		trace.DecidedBy = "group has no conditions"
+	case failedRequired != nil:
+		trace.DecidedBy = fmt.Sprintf("required condition %s failed", conditionLabel(failedRequired))
+	case trace.Logic:
+		trace.DecidedBy = fmt.Sprintf("logic chain ends true, last set by %s", conditionLabel(&group.Conditions[decider]))
+	default:
+		trace.DecidedBy = fmt.Sprintf("logic chain ends false, last set by %s", conditionLabel(&group.Conditions[decider]))
+	}
+	return trace
+}
+
+// ExplainRule evaluates a rule like EvaluateRuleWithGroups, inactive rules included, and traces
+// each group and the score check that decides whether the signal triggers
+func (e *ConditionEvaluator) ExplainRule(rule *models.SignalRule, ind *services.ExtendedStockIndicators) (*RuleTrace, error) {
+	refs, groups, err := e.LoadRuleGroups(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &RuleTrace{
+		RuleID:     rule.ID,
+		Name:       rule.Name,
+		SignalType: rule.SignalType,
+		IsActive:   rule.IsActive,
+		MinScore:   rule.MinScore,
+		Groups:     []GroupTrace{},
+	}
+
+	var failedGroup *GroupTrace
+	for _, ref := range refs {
+		group, ok := groups[ref.GroupID]
+		if !ok {
+			trace.MissingGroup = append(trace.MissingGroup, ref.GroupID)
+			continue
+		}
+		groupTrace := e.ExplainConditionGroup(group, ind)
+		groupTrace.Required = ref.Required
+		trace.TotalScore += groupTrace.TotalScore
+		trace.MaxScore += groupTrace.MaxScore
+		trace.Groups = append(trace.Groups, *groupTrace)
+		if ref.Required && !groupTrace.Passed && failedGroup == nil {
+			failedGroup = groupTrace
+		}
+	}
+	if trace.MaxScore > 0 {
+		trace.ScorePercent = (trace.TotalScore * 100) / trace.MaxScore
+	}
+
+	switch {
+	case failedGroup != nil:
+		trace.DecidedBy = fmt.Sprintf("required group %q failed: %s", failedGroup.Name, failedGroup.DecidedBy)
+	case trace.ScorePercent < rule.MinScore:
+		trace.DecidedBy = fmt.Sprintf("score %d%% below the minimum %d%%", trace.ScorePercent, rule.MinScore)
+	default:
+		trace.Triggered = true
+		trace.DecidedBy = fmt.Sprintf("required groups passed and score %d%% meets the minimum %d%%", trace.ScorePercent, rule.MinScore)
+	}
+	return trace, nil
+}
+
+func (e *ConditionEvaluator) traceCondition(cond *models.SignalCondition, r *ConditionResult, ind *services.ExtendedStockIndicators, chain, joined bool) ConditionTrace {
+	trace := ConditionTrace{
+		ConditionID:      cond.ID,
+		Name:             cond.Name,
+		Indicator:        string(cond.Indicator),
+		Operator:         string(cond.Operator),
+		CompareIndicator: string(cond.CompareIndicator),
+		ActualValue:      r.ActualValue,
+		Threshold:        cond.Value.InexactFloat64(),
+		Passed:           r.Passed,
+		Weight:           cond.Weight,
+		Score:            r.Score,
+		Required:         cond.IsRequired,
+		Chain:            chain,
+		Message:          r.Message,
+	}
+	if joined {
+		trace.LogicalOperator = string(cond.LogicalOperator)
+	}
+	if cond.CompareIndicator != "" {
+		trace.CompareValue = e.GetIndicatorValue(ind, cond.CompareIndicator)
+	}
+	if cond.CompareIndicator != "" || cond.Operator == models.OperatorCrossAbove || cond.Operator == models.OperatorCrossBelow {
+		trace.Threshold = trace.CompareValue
+	}
+	if cond.Operator == models.OperatorBetween {
+		trace.Threshold2 = cond.Value2.InexactFloat64()
+	}
+	return trace
+}
+
+// conditionLabel names a condition in a trace: its name, else its comparison
+func conditionLabel(cond *models.SignalCondition) string {
+	if cond.Name != "" {
+		return fmt.Sprintf("%q", cond.Name)
+	}
+	return fmt.Sprintf("%s %s", cond.Indicator, cond.Operator)
+}
@@ -0,0 +1,449 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go_backend_project/services"
+)
+
+// Expression is a compiled scoring expression over indicator fields.
+//
+// Grammar (lowest to highest precedence):
+//
+//	or:      and ( ("||" | "or") and )*
+//	and:     cmp ( ("&&" | "and") cmp )*
+//	cmp:     sum ( ("<" | "<=" | ">" | ">=" | "==" | "!=") sum )?
+//	sum:     term ( ("+" | "-") term )*
+//	term:    unary ( ("*" | "/") unary )*
+//	unary:   ("-" | "!" | "not") unary | primary
+//	primary: number | field | func "(" args ")" | "(" or ")"
+//
+// Comparisons and logical operators yield 1 (true) or 0 (false).
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ExpressionFields maps the field names usable in expressions to indicator values
+var ExpressionFields = map[string]func(ind *services.ExtendedStockIndicators) float64{
+	"rs_3d":           func(i *services.ExtendedStockIndicators) float64 { return i.RS3DRank },
+	"rs_1m":           func(i *services.ExtendedStockIndicators) float64 { return i.RS1MRank },
+	"rs_3m":           func(i *services.ExtendedStockIndicators) float64 { return i.RS3MRank },
+	"rs_1y":           func(i *services.ExtendedStockIndicators) float64 { return i.RS1YRank },
+	"rs_avg":          func(i *services.ExtendedStockIndicators) float64 { return i.RSAvg },
+	"change_3d":       func(i *services.ExtendedStockIndicators) float64 { return i.RS3DChange },
+	"change_1m":       func(i *services.ExtendedStockIndicators) float64 { return i.RS1M },
+	"change_3m":       func(i *services.ExtendedStockIndicators) float64 { return i.RS3M },
+	"change_1y":       func(i *services.ExtendedStockIndicators) float64 { return i.RS1Y },
+	"macd":            func(i *services.ExtendedStockIndicators) float64 { return i.MACD },
+	"macd_signal":     func(i *services.ExtendedStockIndicators) float64 { return i.MACDSignal },
+	"macd_hist":       func(i *services.ExtendedStockIndicators) float64 { return i.MACDHist },
+	"rsi":             func(i *services.ExtendedStockIndicators) float64 { return i.RSI },
+	"ma10":            func(i *services.ExtendedStockIndicators) float64 { return i.MA10 },
+	"ma30":            func(i *services.ExtendedStockIndicators) float64 { return i.MA30 },
+	"ma50":            func(i *services.ExtendedStockIndicators) float64 { return i.MA50 },
+	"ma200":           func(i *services.ExtendedStockIndicators) float64 { return i.MA200 },
+	"avg_vol":         func(i *services.ExtendedStockIndicators) float64 { return i.AvgVol },
+	"vol_ratio":       func(i *services.ExtendedStockIndicators) float64 { return i.VolRatio },
+	"avg_trading_val": func(i *services.ExtendedStockIndicators) float64 { return i.AvgTradingVal },
+	"price":           func(i *services.ExtendedStockIndicators) float64 { return i.CurrentPrice },
+	"price_change":    func(i *services.ExtendedStockIndicators) float64 { return i.PriceChange },
+}
+
+// expressionFuncs are the functions callable from expressions with their arity
+var expressionFuncs = map[string]struct {
+	arity int
+	fn    func(args []float64) float64
+}{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"clamp": {3, func(a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+	"pct": {2, func(a []float64) float64 {
+		if a[1] == 0 {
+			return 0
+		}
+		return (a[0] - a[1]) / a[1] * 100
+	}},
+	"if": {3, func(a []float64) float64 {
+		if a[0] != 0 {
+			return a[1]
+		}
+		return a[2]
+	}},
+}
+
+// ExpressionFieldNames returns the sorted field names usable in expressions
+func ExpressionFieldNames() []string {
+	names := make([]string, 0, len(ExpressionFields))
+	for name := range ExpressionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompileExpression parses and validates an expression
+func CompileExpression(source string) (*Expression, error) {
+	p := &exprParser{}
+	if err := p.tokenize(source); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// Eval evaluates the expression against a stock's indicators
+func (e *Expression) Eval(ind *services.ExtendedStockIndicators) float64 {
+	v := e.root.eval(ind)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// =============================================================================
+// AST
+// =============================================================================
+
+type exprNode interface {
+	eval(ind *services.ExtendedStockIndicators) float64
+}
+
+type numberNode float64
+
+func (n numberNode) eval(*services.ExtendedStockIndicators) float64 { return float64(n) }
+
+type fieldNode struct {
+	get func(ind *services.ExtendedStockIndicators) float64
+}
+
+func (n fieldNode) eval(ind *services.ExtendedStockIndicators) float64 { return n.get(ind) }
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n unaryNode) eval(ind *services.ExtendedStockIndicators) float64 {
+	v := n.operand.eval(ind)
+	if n.op == "-" {
+		return -v
+	}
+	return boolValue(v == 0)
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n binaryNode) eval(ind *services.ExtendedStockIndicators) float64 {
+	l := n.left.eval(ind)
+	// Short-circuit logical operators
+	switch n.op {
+	case "&&":
+		if l == 0 {
+			return 0
+		}
+		return boolValue(n.right.eval(ind) != 0)
+	case "||":
+		if l != 0 {
+			return 1
+		}
+		return boolValue(n.right.eval(ind) != 0)
+	}
+
+	r := n.right.eval(ind)
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	case "<":
+		return boolValue(l < r)
+	case "<=":
+		return boolValue(l <= r)
+	case ">":
+		return boolValue(l > r)
+	case ">=":
+		return boolValue(l >= r)
+	case "==":
+		return boolValue(l == r)
+	case "!=":
+		return boolValue(l != r)
+	}
+	return 0
+}
+
+type callNode struct {
+	fn   func(args []float64) float64
+	args []exprNode
+}
+
+func (n callNode) eval(ind *services.ExtendedStockIndicators) float64 {
+	values := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		values[i] = arg.eval(ind)
+	}
+	return n.fn(values)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// =============================================================================
+// PARSER
+// =============================================================================
+
+const (
+	tokNumber = iota
+	tokIdent
+	tokOperator
+)
+
+type exprToken struct {
+	kind   int
+	text   string
+	offset int
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// keywordOperators are word forms of the logical operators
+var keywordOperators = map[string]string{"and": "&&", "or": "||", "not": "!"}
+
+func (p *exprParser) tokenize(src string) error {
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, exprToken{tokNumber, src[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_') {
+				i++
+			}
+			word := strings.ToLower(src[start:i])
+			if op, ok := keywordOperators[word]; ok {
+				p.tokens = append(p.tokens, exprToken{tokOperator, op, start})
+			} else {
+				p.tokens = append(p.tokens, exprToken{tokIdent, word, start})
+			}
+		default:
+			if i+1 < len(src) {
+				two := src[i : i+2]
+				switch two {
+				case "<=", ">=", "==", "!=", "&&", "||":
+					p.tokens = append(p.tokens, exprToken{tokOperator, two, i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/<>!(),", c) {
+				return fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			p.tokens = append(p.tokens, exprToken{tokOperator, string(c), i})
+			i++
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// accept consumes the next token when it is one of the given operators
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok == nil || tok.kind != tokOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseBinary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	return p.parseBinary(p.parseTerm, "+", "-")
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.accept("-", "!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch tok.kind {
+	case tokNumber:
+		p.pos++
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.offset)
+		}
+		return numberNode(v), nil
+
+	case tokIdent:
+		p.pos++
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok)
+		}
+		switch tok.text {
+		case "true":
+			return numberNode(1), nil
+		case "false":
+			return numberNode(0), nil
+		}
+		get, ok := ExpressionFields[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", tok.text, tok.offset)
+		}
+		return fieldNode{get: get}, nil
+	}
+
+	if _, ok := p.accept("("); ok {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", tok.offset)
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.offset)
+}
+
+func (p *exprParser) parseCall(name *exprToken) (exprNode, error) {
+	def, ok := expressionFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.offset)
+	}
+
+	var args []exprNode
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			return nil, fmt.Errorf("expected ',' or ')' in call to %s", name.text)
+		}
+	}
+	if len(args) != def.arity {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name.text, def.arity, len(args))
+	}
+	return callNode{fn: def.fn, args: args}, nil
+}
@@ -0,0 +1,209 @@
+package signals
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"gorm.io/gorm"
+)
+
+// builtinStrategyNames cannot be used by custom strategies
+var builtinStrategyNames = map[string]bool{
+	"momentum":        true,
+	"trend_following": true,
+	"mean_reversion":  true,
+	"breakout":        true,
+	"composite":       true,
+}
+
+var customStrategyNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// DefaultCustomStrategy returns a strategy definition with the built-in signal thresholds
+func DefaultCustomStrategy() models.CustomStrategy {
+	return models.CustomStrategy{
+		BuyThreshold:        60,
+		StrongBuyThreshold:  75,
+		SellThreshold:       40,
+		StrongSellThreshold: 25,
+		TargetPct:           10,
+		StopLossPct:         5,
+		IsActive:            true,
+	}
+}
+
+// ExpressionStrategy evaluates a user-defined strategy
+type ExpressionStrategy struct {
+	def    models.CustomStrategy
+	score  *Expression
+	filter *Expression
+}
+
+// NewExpressionStrategy validates a definition and compiles its expressions
+func NewExpressionStrategy(def models.CustomStrategy) (*ExpressionStrategy, error) {
+	if !customStrategyNamePattern.MatchString(def.Name) {
+		return nil, fmt.Errorf("name must be 2-50 lowercase letters, digits or underscores")
+	}
+	if builtinStrategyNames[def.Name] {
+		return nil, fmt.Errorf("%s is a built-in strategy", def.Name)
+	}
+	if !(def.StrongSellThreshold >= 0 && def.StrongSellThreshold <= def.SellThreshold &&
+		def.SellThreshold < def.BuyThreshold && def.BuyThreshold <= def.StrongBuyThreshold &&
+		def.StrongBuyThreshold <= 100) {
+		return nil, fmt.Errorf("thresholds must satisfy 0 <= strong_sell <= sell < buy <= strong_buy <= 100")
+	}
+	if def.TargetPct < 0 || def.StopLossPct < 0 || def.StopLossPct >= 100 {
+		return nil, fmt.Errorf("target_pct and stop_loss_pct must be positive percentages")
+	}
+
+	score, err := CompileExpression(def.ScoreExpression)
+	if err != nil {
+		return nil, fmt.Errorf("score_expression: %w", err)
+	}
+	s := &ExpressionStrategy{def: def, score: score}
+
+	if def.FilterExpression != "" {
+		if s.filter, err = CompileExpression(def.FilterExpression); err != nil {
+			return nil, fmt.Errorf("filter_expression: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *ExpressionStrategy) Name() string { return s.def.Name }
+func (s *ExpressionStrategy) Description() string {
+	if s.def.Description != "" {
+		return s.def.Description
+	}
+	return "Custom strategy: " + s.def.ScoreExpression
+}
+
+// Definition returns the stored definition of the strategy
+func (s *ExpressionStrategy) Definition() models.CustomStrategy { return s.def }
+
+// Evaluate scores a stock with the strategy expression; the score is clamped to 0-100
+func (s *ExpressionStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*TradingSignal, error) {
+	score := math.Max(0, math.Min(100, s.score.Eval(ind)))
+
+	signal := &TradingSignal{
+		Price:       ind.CurrentPrice,
+		Strategy:    s.Name(),
+		Strength:    int(math.Round(score)),
+		Confidence:  math.Round(math.Abs(score-50)/50*100) / 100,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Reasons:     []string{fmt.Sprintf("Score %s = %.1f", s.def.ScoreExpression, score)},
+		Indicators: &SignalIndicators{
+			RSAvg:         ind.RSAvg,
+			RS3D:          ind.RS3DRank,
+			RS1M:          ind.RS1MRank,
+			RS3M:          ind.RS3MRank,
+			RS1Y:          ind.RS1YRank,
+			MACD:          ind.MACD,
+			MACDSignal:    ind.MACDSignal,
+			MACDHist:      ind.MACDHist,
+			RSI:           ind.RSI,
+			MA10:          ind.MA10,
+			MA30:          ind.MA30,
+			MA50:          ind.MA50,
+			MA200:         ind.MA200,
+			VolRatio:      ind.VolRatio,
+			AvgTradingVal: ind.AvgTradingVal,
+		},
+	}
+
+	if s.filter != nil && s.filter.Eval(ind) == 0 {
+		signal.Signal = SignalHold
+		signal.Reasons = append(signal.Reasons, "Filter not matched: "+s.def.FilterExpression)
+		return withTicks(signal, ind.Code), nil
+	}
+
+	switch {
+	case score >= s.def.StrongBuyThreshold:
+		signal.Signal = SignalStrongBuy
+	case score >= s.def.BuyThreshold:
+		signal.Signal = SignalBuy
+	case score <= s.def.StrongSellThreshold:
+		signal.Signal = SignalStrongSell
+	case score <= s.def.SellThreshold:
+		signal.Signal = SignalSell
+	default:
+		signal.Signal = SignalHold
+	}
+
+	if signal.Signal == SignalBuy || signal.Signal == SignalStrongBuy {
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, s.def.TargetPct)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -s.def.StopLossPct)
+	}
+
+	return withTicks(signal, ind.Code), nil
+}
+
+// GetStrategy returns a registered strategy by name
+func (s *SignalService) GetStrategy(name string) (Strategy, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	strategy, ok := s.strategies[name]
+	return strategy, ok
+}
+
+// UnregisterStrategy removes a strategy; built-in strategies cannot be removed
+func (s *SignalService) UnregisterStrategy(name string) {
+	if builtinStrategyNames[name] {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.strategies, name)
+	dropSnapshotStrategy(name)
+}
+
+// ApplyCustomStrategy registers an active custom strategy or unregisters an inactive one.
+// previousName is the name the strategy was registered under before a rename, if any.
+func ApplyCustomStrategy(def models.CustomStrategy, previousName string) error {
+	if GlobalSignalService == nil {
+		return fmt.Errorf("signal service not initialized")
+	}
+
+	if previousName != "" && previousName != def.Name {
+		GlobalSignalService.UnregisterStrategy(previousName)
+	}
+	if !def.IsActive {
+		GlobalSignalService.UnregisterStrategy(def.Name)
+		return nil
+	}
+
+	strategy, err := NewExpressionStrategy(def)
+	if err != nil {
+		return err
+	}
+	GlobalSignalService.RegisterStrategy(strategy)
+	return nil
+}
+
+// LoadCustomStrategies registers all active custom strategies stored in the database
+func LoadCustomStrategies(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	var defs []models.CustomStrategy
+	if err := db.Where("is_active = ?", true).Find(&defs).Error; err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, def := range defs {
+		if err := ApplyCustomStrategy(def, ""); err != nil {
+			log.Printf("Skipping custom strategy %s: %v", def.Name, err)
+			continue
+		}
+		loaded++
+	}
+	log.Printf("Loaded %d custom strategies", loaded)
+	return nil
+}
@@ -0,0 +1,355 @@
+package signals
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"sync"
+	"time"
+
+	"go_backend_project/services"
+)
+
+// DefaultStrategyPluginDir is scanned for *.so strategy plugins unless STRATEGY_PLUGIN_DIR is set
+const DefaultStrategyPluginDir = "plugins"
+
+// StrategyPluginSymbol is the exported symbol a plugin must provide
+const StrategyPluginSymbol = "Strategy"
+
+// Plugin sandboxing limits. Go plugins run in-process, so these bound the damage a
+// misbehaving plugin can do rather than isolating it completely.
+const (
+	PluginEvalTimeout            = 200 * time.Millisecond
+	PluginMaxConcurrent          = 4  // evaluations in flight per plugin, including timed-out ones
+	PluginMaxConsecutiveFailures = 10 // failures before a plugin is disabled until a health check passes
+	PluginMaxReasons             = 10
+)
+
+// Plugin statuses
+const (
+	PluginStatusActive   = "active"
+	PluginStatusDisabled = "disabled"
+	PluginStatusFailed   = "failed" // could not be loaded
+)
+
+// StrategyPlugin is implemented by third-party strategy modules. It deliberately uses only
+// built-in types so plugins do not need to import this module. Fields are the indicator
+// values listed in ExpressionFields; signal is one of BUY, STRONG_BUY, SELL, STRONG_SELL, HOLD.
+//
+// A plugin is built with `go build -buildmode=plugin` using the same Go toolchain as the server,
+// which must itself be built with CGO_ENABLED=1. It exports a variable named Strategy:
+//
+//	type myStrategy struct{}
+//	func (myStrategy) Name() string        { return "partner_alpha" }
+//	func (myStrategy) Description() string { return "..." }
+//	func (myStrategy) Evaluate(fields map[string]float64) (string, int, float64, []string, error) { ... }
+//	var Strategy myStrategy
+type StrategyPlugin interface {
+	Name() string
+	Description() string
+	Evaluate(fields map[string]float64) (signal string, strength int, confidence float64, reasons []string, err error)
+}
+
+// StrategyPluginHealthChecker is optionally implemented by plugins that can report their own health
+type StrategyPluginHealthChecker interface {
+	HealthCheck() error
+}
+
+// PluginInfo reports the state of a loaded or failed plugin
+type PluginInfo struct {
+	Name                string     `json:"name"`
+	File                string     `json:"file"`
+	Description         string     `json:"description"`
+	Status              string     `json:"status"`
+	Error               string     `json:"error,omitempty"`
+	Evaluations         int64      `json:"evaluations"`
+	Failures            int64      `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastHealthCheck     *time.Time `json:"last_health_check,omitempty"`
+	LoadedAt            time.Time  `json:"loaded_at"`
+}
+
+// PluginStrategy adapts a StrategyPlugin to Strategy and enforces the sandboxing limits
+type PluginStrategy struct {
+	impl StrategyPlugin
+	slot chan struct{}
+
+	mu   sync.Mutex
+	info PluginInfo
+}
+
+var (
+	strategyPlugins   = make(map[string]*PluginStrategy)
+	strategyPluginsMu sync.RWMutex
+)
+
+func (p *PluginStrategy) Name() string        { return p.info.Name }
+func (p *PluginStrategy) Description() string { return p.info.Description }
+
+// Evaluate runs the plugin with a timeout and panic recovery, disabling it after repeated failures
+func (p *PluginStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*TradingSignal, error) {
+	p.mu.Lock()
+	disabled := p.info.Status != PluginStatusActive
+	p.mu.Unlock()
+	if disabled {
+		return nil, fmt.Errorf("plugin %s is disabled", p.info.Name)
+	}
+
+	select {
+	case p.slot <- struct{}{}:
+	default:
+		return nil, p.fail(fmt.Errorf("plugin %s is busy", p.info.Name))
+	}
+
+	fields := make(map[string]float64, len(ExpressionFields))
+	for name, get := range ExpressionFields {
+		fields[name] = get(ind)
+	}
+
+	type result struct {
+		signal     string
+		strength   int
+		confidence float64
+		reasons    []string
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// The slot is released when the plugin returns, so hung calls keep counting against the limit
+		defer func() { <-p.slot }()
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("plugin panic: %v", r)}
+			}
+		}()
+		var r result
+		r.signal, r.strength, r.confidence, r.reasons, r.err = p.impl.Evaluate(fields)
+		done <- r
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(PluginEvalTimeout):
+		return nil, p.fail(fmt.Errorf("plugin %s timed out after %v", p.info.Name, PluginEvalTimeout))
+	}
+	if r.err != nil {
+		return nil, p.fail(r.err)
+	}
+
+	signalType := SignalType(r.signal)
+	switch signalType {
+	case SignalBuy, SignalStrongBuy, SignalSell, SignalStrongSell, SignalHold:
+	default:
+		return nil, p.fail(fmt.Errorf("plugin %s returned unknown signal %q", p.info.Name, r.signal))
+	}
+	if len(r.reasons) > PluginMaxReasons {
+		r.reasons = r.reasons[:PluginMaxReasons]
+	}
+	if r.reasons == nil {
+		r.reasons = []string{}
+	}
+
+	p.succeed()
+	return withTicks(&TradingSignal{
+		Signal:      signalType,
+		Strength:    int(math.Max(0, math.Min(100, float64(r.strength)))),
+		Confidence:  math.Max(0, math.Min(1, r.confidence)),
+		Price:       ind.CurrentPrice,
+		Reasons:     r.reasons,
+		Strategy:    p.info.Name,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}, ind.Code), nil
+}
+
+func (p *PluginStrategy) fail(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.info.Evaluations++
+	p.info.Failures++
+	p.info.ConsecutiveFailures++
+	p.info.LastError = err.Error()
+	if p.info.Status == PluginStatusActive && p.info.ConsecutiveFailures >= PluginMaxConsecutiveFailures {
+		p.info.Status = PluginStatusDisabled
+		log.Printf("Strategy plugin %s disabled after %d consecutive failures: %v", p.info.Name, p.info.ConsecutiveFailures, err)
+	}
+	return err
+}
+
+func (p *PluginStrategy) succeed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.info.Evaluations++
+	p.info.ConsecutiveFailures = 0
+}
+
+// HealthCheck runs the plugin's own health check, or a probe evaluation when it has none.
+// A passing check re-enables a plugin disabled by failures.
+func (p *PluginStrategy) HealthCheck() error {
+	var err error
+	if checker, ok := p.impl.(StrategyPluginHealthChecker); ok {
+		done := make(chan error, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- fmt.Errorf("plugin panic: %v", r)
+				}
+			}()
+			done <- checker.HealthCheck()
+		}()
+		select {
+		case err = <-done:
+		case <-time.After(PluginEvalTimeout):
+			err = fmt.Errorf("health check timed out after %v", PluginEvalTimeout)
+		}
+	} else {
+		p.mu.Lock()
+		p.info.Status = PluginStatusActive
+		p.mu.Unlock()
+		_, err = p.Evaluate(&services.ExtendedStockIndicators{})
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.info.LastHealthCheck = &now
+	if err != nil {
+		p.info.Status = PluginStatusDisabled
+		p.info.LastError = "health check: " + err.Error()
+		return err
+	}
+	p.info.Status = PluginStatusActive
+	p.info.ConsecutiveFailures = 0
+	return nil
+}
+
+// Info returns a snapshot of the plugin state
+func (p *PluginStrategy) Info() PluginInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.info
+}
+
+// LoadStrategyPlugins opens every *.so file in the plugin directory, health-checks it and
+// registers its strategy into the signal service. A missing directory is not an error.
+func LoadStrategyPlugins() error {
+	if GlobalSignalService == nil {
+		return fmt.Errorf("signal service not initialized")
+	}
+
+	dir := os.Getenv("STRATEGY_PLUGIN_DIR")
+	if dir == "" {
+		dir = DefaultStrategyPluginDir
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, file := range files {
+		p, err := openStrategyPlugin(file)
+		if err != nil {
+			log.Printf("Failed to load strategy plugin %s: %v", file, err)
+			name := filepath.Base(file)
+			if p == nil {
+				p = &PluginStrategy{info: PluginInfo{Name: name, File: file, LoadedAt: time.Now()}}
+			}
+			p.info.Status = PluginStatusFailed
+			p.info.Error = err.Error()
+			strategyPluginsMu.Lock()
+			strategyPlugins[p.info.Name] = p
+			strategyPluginsMu.Unlock()
+			continue
+		}
+
+		if err := p.HealthCheck(); err != nil {
+			log.Printf("Strategy plugin %s failed its health check: %v", p.info.Name, err)
+		}
+		strategyPluginsMu.Lock()
+		strategyPlugins[p.info.Name] = p
+		strategyPluginsMu.Unlock()
+		GlobalSignalService.RegisterStrategy(p)
+		loaded++
+	}
+
+	if len(files) > 0 {
+		log.Printf("Loaded %d of %d strategy plugins from %s", loaded, len(files), dir)
+	}
+	return nil
+}
+
+func openStrategyPlugin(file string) (p *PluginStrategy, err error) {
+	// Plugin init code runs inside plugin.Open
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin panic during load: %v", r)
+		}
+	}()
+
+	lib, err := plugin.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := lib.Lookup(StrategyPluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	impl, ok := sym.(StrategyPlugin)
+	if !ok {
+		return nil, fmt.Errorf("symbol %s does not implement StrategyPlugin", StrategyPluginSymbol)
+	}
+
+	p = &PluginStrategy{
+		impl: impl,
+		slot: make(chan struct{}, PluginMaxConcurrent),
+		info: PluginInfo{
+			Name:        impl.Name(),
+			File:        file,
+			Description: impl.Description(),
+			Status:      PluginStatusActive,
+			LoadedAt:    time.Now(),
+		},
+	}
+	if !customStrategyNamePattern.MatchString(p.info.Name) || builtinStrategyNames[p.info.Name] {
+		return p, fmt.Errorf("invalid or reserved strategy name %q", p.info.Name)
+	}
+	if _, exists := GlobalSignalService.GetStrategy(p.info.Name); exists {
+		return p, fmt.Errorf("strategy %q is already registered", p.info.Name)
+	}
+	return p, nil
+}
+
+// GetStrategyPlugins returns the state of all plugins discovered at startup
+func GetStrategyPlugins() []PluginInfo {
+	strategyPluginsMu.RLock()
+	defer strategyPluginsMu.RUnlock()
+
+	result := make([]PluginInfo, 0, len(strategyPlugins))
+	for _, p := range strategyPlugins {
+		result = append(result, p.Info())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// CheckStrategyPlugin runs the health check of a loaded plugin by name
+func CheckStrategyPlugin(name string) (PluginInfo, error) {
+	strategyPluginsMu.RLock()
+	p, ok := strategyPlugins[name]
+	strategyPluginsMu.RUnlock()
+	if !ok {
+		return PluginInfo{}, fmt.Errorf("plugin not found: %s", name)
+	}
+	if p.impl == nil {
+		return p.Info(), fmt.Errorf("plugin failed to load: %s", p.info.Error)
+	}
+	err := p.HealthCheck()
+	return p.Info(), err
+}
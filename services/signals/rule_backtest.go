@@ -0,0 +1,358 @@
+package signals
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// RuleBacktestConfigKey is the system_config key holding the nightly rule backtest settings
+const RuleBacktestConfigKey = "rule_backtest"
+
+// RuleBacktestConfig controls the nightly rolling backtest of active rules
+type RuleBacktestConfig struct {
+	Enabled        bool    `json:"enabled"`
+	WindowMonths   int     `json:"window_months"`     // rolling window length
+	StepDays       int     `json:"step_days"`         // evaluate rules every N trading days
+	MaxHoldingDays int     `json:"max_holding_days"`  // exit at close after this many trading days
+	MinTrades      int     `json:"min_trades"`        // runs with fewer trades are not judged
+	MinWinRate     float64 `json:"min_win_rate"`      // percent
+	MinAvgReturn   float64 `json:"min_avg_return"`    // percent per trade
+	MaxWinRateDrop float64 `json:"max_win_rate_drop"` // percentage points vs previous run
+}
+
+// DefaultRuleBacktestConfig returns the default nightly backtest settings
+func DefaultRuleBacktestConfig() RuleBacktestConfig {
+	return RuleBacktestConfig{
+		Enabled:        true,
+		WindowMonths:   6,
+		StepDays:       5,
+		MaxHoldingDays: 20,
+		MinTrades:      5,
+		MinWinRate:     40,
+		MinAvgReturn:   0,
+		MaxWinRateDrop: 15,
+	}
+}
+
+// LoadRuleBacktestConfig reads the settings from system_config, falling back to defaults when
+// none are stored or the database is unavailable
+func LoadRuleBacktestConfig(db *gorm.DB) RuleBacktestConfig {
+	cfg := DefaultRuleBacktestConfig()
+	found, err := services.LoadSystemConfig(db, RuleBacktestConfigKey, &cfg)
+	if err != nil {
+		log.Printf("Invalid rule backtest config, using defaults: %v", err)
+		return DefaultRuleBacktestConfig()
+	}
+	if !found {
+		return DefaultRuleBacktestConfig()
+	}
+	return cfg
+}
+
+// SaveRuleBacktestConfig stores the settings in system_config
+func SaveRuleBacktestConfig(db *gorm.DB, cfg RuleBacktestConfig) error {
+	return services.SaveSystemConfig(db, RuleBacktestConfigKey, cfg)
+}
+
+// LastRuleBacktestRun returns when the nightly backtest last stored a run, or nil if it never has
+func LastRuleBacktestRun(db *gorm.DB) *time.Time {
+	if db == nil {
+		return nil
+	}
+	var last models.SignalRuleBacktestRun
+	if err := db.Order("created_at DESC").First(&last).Error; err != nil {
+		return nil
+	}
+	return &last.CreatedAt
+}
+
+// RuleBacktestTrade is one simulated trade from a rule signal
+type RuleBacktestTrade struct {
+	Code        string  `json:"code"`
+	SignalDate  string  `json:"signal_date"`
+	ExitDate    string  `json:"exit_date"`
+	EntryPrice  float64 `json:"entry_price"`
+	ExitPrice   float64 `json:"exit_price"`
+	ReturnPct   float64 `json:"return_pct"`
+	HoldingDays int     `json:"holding_days"`
+	ExitReason  string  `json:"exit_reason"` // target_hit, stop_loss, timeout
+}
+
+// RuleBacktestResult aggregates simulated trades of one rule
+type RuleBacktestResult struct {
+	RuleID      uint                `json:"rule_id"`
+	WindowStart string              `json:"window_start"`
+	WindowEnd   string              `json:"window_end"`
+	Trades      []RuleBacktestTrade `json:"trades"`
+	TotalTrades int                 `json:"total_trades"`
+	Wins        int                 `json:"wins"`
+	WinRate     float64             `json:"win_rate"`
+	AvgReturn   float64             `json:"avg_return"`
+	TotalReturn float64             `json:"total_return"`
+	MaxLoss     float64             `json:"max_loss"`
+	AvgHoldDays float64             `json:"avg_hold_days"`
+}
+
+// BacktestRules replays rules over [start, end] using indicators recomputed as of each
+// sampled trading day; the indicator snapshot of each day is shared across all rules
+func (e *ConditionEvaluator) BacktestRules(rules []models.SignalRule, universe map[string]*services.StockPriceFile, start, end time.Time, cfg RuleBacktestConfig) (map[uint]*RuleBacktestResult, error) {
+	if cfg.StepDays < 1 {
+		cfg.StepDays = 1
+	}
+	if cfg.MaxHoldingDays < 1 {
+		cfg.MaxHoldingDays = 20
+	}
+
+	from, to := start.Format(services.PriceDateFormat), end.Format(services.PriceDateFormat)
+	dates := services.TradingDates(universe, from, to)
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no price data between %s and %s", from, to)
+	}
+
+	type ruleState struct {
+		rule      *models.SignalRule
+		refs      []models.RuleGroupRef
+		groups    map[uint]*models.SignalConditionGroup
+		result    *RuleBacktestResult
+		openUntil map[string]string // code -> exit date of the open trade
+	}
+
+	states := make([]*ruleState, 0, len(rules))
+	for i := range rules {
+		refs, groups, err := e.LoadRuleGroups(&rules[i])
+		if err != nil {
+			log.Printf("Skipping rule %d in backtest: %v", rules[i].ID, err)
+			continue
+		}
+		states = append(states, &ruleState{
+			rule:      &rules[i],
+			refs:      refs,
+			groups:    groups,
+			result:    &RuleBacktestResult{RuleID: rules[i].ID, WindowStart: from, WindowEnd: to},
+			openUntil: make(map[string]string),
+		})
+	}
+
+	for i := 0; i < len(dates); i += cfg.StepDays {
+		date := dates[i]
+		snapshot := services.CalculateIndicatorsAsOf(universe, date)
+
+		for _, st := range states {
+			for code, ind := range snapshot {
+				if until, open := st.openUntil[code]; open && until >= date {
+					continue
+				}
+
+				signal := e.EvaluateRuleWithGroups(st.rule, st.refs, st.groups, ind)
+				if signal == nil {
+					continue
+				}
+
+				trade, ok := SimulateTrade(universe[code], date, signal.SignalType, signal.Price, signal.TargetPrice, signal.StopLoss, cfg.MaxHoldingDays)
+				if !ok {
+					continue
+				}
+				st.result.Trades = append(st.result.Trades, trade)
+				st.openUntil[code] = trade.ExitDate
+			}
+		}
+	}
+
+	results := make(map[uint]*RuleBacktestResult, len(states))
+	for _, st := range states {
+		summarizeRuleBacktest(st.result)
+		results[st.rule.ID] = st.result
+	}
+	return results, nil
+}
+
+// SimulateTrade walks forward from signalDate until target, stop or the holding limit is hit.
+// Returns false when there is no price data after the signal.
+func SimulateTrade(priceFile *services.StockPriceFile, signalDate, signalType string, entry, target, stop float64, maxHoldingDays int) (RuleBacktestTrade, bool) {
+	trade := RuleBacktestTrade{SignalDate: signalDate, EntryPrice: entry}
+	if priceFile == nil || entry <= 0 {
+		return trade, false
+	}
+	trade.Code = priceFile.Code
+
+	// Prices are newest first; locate the signal day
+	idx := sort.Search(len(priceFile.Prices), func(i int) bool {
+		return priceFile.Prices[i].Date <= signalDate
+	})
+	if idx == 0 || idx >= len(priceFile.Prices) {
+		return trade, false
+	}
+
+	isSell := signalType == "SELL" || signalType == "STRONG_SELL"
+	days := 0
+	for i := idx - 1; i >= 0; i-- {
+		p := priceFile.Prices[i]
+		days++
+		trade.ExitDate = p.Date
+		trade.HoldingDays = days
+
+		// Stop is checked before target so intraday ambiguity is resolved conservatively
+		switch {
+		case stop > 0 && !isSell && p.Low <= stop, stop > 0 && isSell && p.High >= stop:
+			trade.ExitPrice, trade.ExitReason = stop, "stop_loss"
+		case target > 0 && !isSell && p.High >= target, target > 0 && isSell && p.Low <= target:
+			trade.ExitPrice, trade.ExitReason = target, "target_hit"
+		case days >= maxHoldingDays || i == 0:
+			trade.ExitPrice, trade.ExitReason = p.Close, "timeout"
+		default:
+			continue
+		}
+		break
+	}
+
+	if trade.ExitReason == "" {
+		return trade, false
+	}
+
+	trade.ReturnPct = (trade.ExitPrice - entry) / entry * 100
+	if isSell {
+		trade.ReturnPct = -trade.ReturnPct
+	}
+	return trade, true
+}
+
+// summarizeRuleBacktest fills the aggregate metrics from the trade list
+func summarizeRuleBacktest(result *RuleBacktestResult) {
+	result.TotalTrades = len(result.Trades)
+	if result.TotalTrades == 0 {
+		return
+	}
+
+	var holdDays int
+	for i, t := range result.Trades {
+		if t.ReturnPct > 0 {
+			result.Wins++
+		}
+		result.TotalReturn += t.ReturnPct
+		holdDays += t.HoldingDays
+		if i == 0 || t.ReturnPct < result.MaxLoss {
+			result.MaxLoss = t.ReturnPct
+		}
+	}
+
+	n := float64(result.TotalTrades)
+	result.WinRate = float64(result.Wins) / n * 100
+	result.AvgReturn = result.TotalReturn / n
+	result.AvgHoldDays = float64(holdDays) / n
+}
+
+// RunNightlyRuleBacktests backtests every active admin rule over the rolling window, stores a
+// run record per rule and raises an admin notification when performance degrades
+func (e *ConditionEvaluator) RunNightlyRuleBacktests() error {
+	cfg := LoadRuleBacktestConfig(e.db)
+	if !cfg.Enabled {
+		log.Println("Nightly rule backtest disabled, skipping")
+		return nil
+	}
+
+	var rules []models.SignalRule
+	if err := e.db.Where("is_active = ? AND (owner_user_id = '' OR owner_user_id IS NULL)", true).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to load active rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	universe, err := services.LoadPriceUniverse()
+	if err != nil {
+		return err
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, -cfg.WindowMonths, 0)
+	startedAt := time.Now()
+
+	results, err := e.BacktestRules(rules, universe, start, end, cfg)
+	if err != nil {
+		return err
+	}
+
+	degradedCount := 0
+	for _, rule := range rules {
+		result, ok := results[rule.ID]
+		if !ok {
+			continue
+		}
+
+		var previous models.SignalRuleBacktestRun
+		hasPrevious := e.db.Where("rule_id = ?", rule.ID).Order("created_at DESC").First(&previous).Error == nil
+
+		degraded, reason := judgeRuleBacktest(result, cfg, hasPrevious, &previous)
+
+		run := &models.SignalRuleBacktestRun{
+			RuleID:      rule.ID,
+			WindowStart: start,
+			WindowEnd:   end,
+			TotalTrades: result.TotalTrades,
+			Wins:        result.Wins,
+			WinRate:     result.WinRate,
+			AvgReturn:   result.AvgReturn,
+			TotalReturn: result.TotalReturn,
+			MaxLoss:     result.MaxLoss,
+			AvgHoldDays: result.AvgHoldDays,
+			Degraded:    degraded,
+			Reason:      reason,
+		}
+		if err := e.db.Create(run).Error; err != nil {
+			log.Printf("Failed to store backtest run for rule %d: %v", rule.ID, err)
+		}
+
+		now := time.Now()
+		e.db.Model(&models.SignalRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+			"backtest_win_rate":     decimal.NewFromFloat(result.WinRate).Round(2),
+			"backtest_avg_return":   decimal.NewFromFloat(result.AvgReturn).Round(4),
+			"backtest_total_trades": result.TotalTrades,
+			"last_backtest_at":      now,
+		})
+
+		if degraded {
+			degradedCount++
+			services.NotifyAdmins(e.db, models.NotificationLevelWarning, "rule_backtest",
+				fmt.Sprintf("Signal rule %q is underperforming", rule.Name),
+				reason,
+				map[string]interface{}{
+					"rule_id":      rule.ID,
+					"win_rate":     result.WinRate,
+					"avg_return":   result.AvgReturn,
+					"total_trades": result.TotalTrades,
+					"window_start": start.Format(services.PriceDateFormat),
+					"window_end":   end.Format(services.PriceDateFormat),
+				})
+		}
+	}
+
+	log.Printf("Nightly rule backtest completed: %d rules, %d degraded, took %v",
+		len(results), degradedCount, time.Since(startedAt).Round(time.Second))
+	return nil
+}
+
+// judgeRuleBacktest compares a run against the configured thresholds and the previous run
+func judgeRuleBacktest(result *RuleBacktestResult, cfg RuleBacktestConfig, hasPrevious bool, previous *models.SignalRuleBacktestRun) (bool, string) {
+	if result.TotalTrades < cfg.MinTrades {
+		return false, ""
+	}
+	if result.WinRate < cfg.MinWinRate {
+		return true, fmt.Sprintf("Win rate %.1f%% is below the %.1f%% threshold over %d trades", result.WinRate, cfg.MinWinRate, result.TotalTrades)
+	}
+	if result.AvgReturn < cfg.MinAvgReturn {
+		return true, fmt.Sprintf("Average return %.2f%% is below the %.2f%% threshold over %d trades", result.AvgReturn, cfg.MinAvgReturn, result.TotalTrades)
+	}
+	if hasPrevious && previous.TotalTrades >= cfg.MinTrades && cfg.MaxWinRateDrop > 0 &&
+		previous.WinRate-result.WinRate > cfg.MaxWinRateDrop {
+		return true, fmt.Sprintf("Win rate dropped from %.1f%% to %.1f%% since the previous run", previous.WinRate, result.WinRate)
+	}
+	return false, ""
+}
@@ -0,0 +1,165 @@
+package signals
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+)
+
+// SimulationHorizons are the trading-day horizons of the forward returns of a rule simulation
+var SimulationHorizons = []int{5, 10, 20}
+
+// RuleTrigger is a day a rule fired, with the stock's returns after the close of that day
+type RuleTrigger struct {
+	Date       string  `json:"date"`
+	SignalType string  `json:"signal_type"`
+	Score      int     `json:"score"`
+	MaxScore   int     `json:"max_score"`
+	Price      float64 `json:"price" unit:"price"`
+	FirstOfRun bool    `json:"first_of_run"` // the rule did not fire on the previous trading day
+	// Returns by horizon in trading days, e.g. "5d", in percent of the trigger close; missing
+	// when the history ends before the horizon
+	Returns map[string]float64 `json:"returns"`
+}
+
+// HorizonStats aggregates the forward returns of the triggers at one horizon. WinRate counts
+// rises for buy rules and falls for sell rules.
+type HorizonStats struct {
+	Days      int     `json:"days"`
+	Count     int     `json:"count"`
+	AvgReturn float64 `json:"avg_return"`
+	WinRate   float64 `json:"win_rate"`
+}
+
+// RuleSimulation lists the days a rule would have fired for one stock
+type RuleSimulation struct {
+	RuleID        uint           `json:"rule_id"`
+	RuleName      string         `json:"rule_name"`
+	Code          string         `json:"code"`
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	DaysEvaluated int            `json:"days_evaluated"`
+	Runs          int            `json:"runs"` // streaks of consecutive trigger days
+	LastTrigger   string         `json:"last_trigger,omitempty"`
+	Triggers      []RuleTrigger  `json:"triggers"`
+	Horizons      []HorizonStats `json:"horizons"`
+}
+
+// SimulateRule replays a rule, active or not, over the stored history of one stock within
+// [from, to] (YYYY-MM-DD, empty for the whole history): the stock's indicators are recomputed as
+// of every trading day and the rule evaluated on them. RS ranks need the whole universe and stay
+// at zero, so rules requiring them never fire here. The replay stops when ctx ends.
+func (e *ConditionEvaluator) SimulateRule(ctx context.Context, rule *models.SignalRule, code, from, to string) (*RuleSimulation, error) {
+	if services.GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+	refs, groups, err := e.LoadRuleGroups(rule)
+	if err != nil {
+		return nil, err
+	}
+	priceFile, err := services.GlobalPriceService.LoadStockPrice(code)
+	if err != nil || len(priceFile.Prices) == 0 {
+		return nil, fmt.Errorf("no price data for %s", code)
+	}
+	index, _ := services.GlobalPriceService.LoadStockPrice(services.MarketIndexCode)
+
+	if to == "" {
+		to = priceFile.Prices[0].Date
+	}
+	dates := services.TradingDates(map[string]*services.StockPriceFile{code: priceFile}, from, to)
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no price data for %s between %s and %s", code, from, to)
+	}
+
+	// Prices are newest first; closes oldest first index the forward returns
+	closes := make(map[string]int, len(priceFile.Prices))
+	history := make([]float64, len(priceFile.Prices))
+	for i, p := range priceFile.Prices {
+		j := len(priceFile.Prices) - 1 - i
+		history[j] = p.Close
+		closes[p.Date] = j
+	}
+
+	sim := &RuleSimulation{
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Code:     code,
+		From:     dates[0],
+		To:       dates[len(dates)-1],
+		Triggers: []RuleTrigger{},
+	}
+
+	firedYesterday := false
+	for _, date := range dates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		ind := services.StockIndicatorsAsOf(priceFile, index, date)
+		if ind == nil {
+			firedYesterday = false
+			continue
+		}
+		sim.DaysEvaluated++
+
+		signal := e.EvaluateRuleWithGroups(rule, refs, groups, ind)
+		if signal == nil {
+			firedYesterday = false
+			continue
+		}
+
+		trigger := RuleTrigger{
+			Date:       date,
+			SignalType: signal.SignalType,
+			Score:      signal.Score,
+			MaxScore:   signal.MaxScore,
+			Price:      signal.Price,
+			FirstOfRun: !firedYesterday,
+			Returns:    make(map[string]float64, len(SimulationHorizons)),
+		}
+		at := closes[date]
+		for _, days := range SimulationHorizons {
+			if at+days < len(history) && history[at] > 0 {
+				trigger.Returns[horizonKey(days)] = (history[at+days] - history[at]) / history[at] * 100
+			}
+		}
+		if trigger.FirstOfRun {
+			sim.Runs++
+		}
+		sim.Triggers = append(sim.Triggers, trigger)
+		sim.LastTrigger = date
+		firedYesterday = true
+	}
+
+	sell := rule.SignalType == "SELL" || rule.SignalType == "STRONG_SELL"
+	for _, days := range SimulationHorizons {
+		stats := HorizonStats{Days: days}
+		wins := 0
+		for _, t := range sim.Triggers {
+			ret, ok := t.Returns[horizonKey(days)]
+			if !ok {
+				continue
+			}
+			stats.Count++
+			stats.AvgReturn += ret
+			if ret > 0 && !sell || ret < 0 && sell {
+				wins++
+			}
+		}
+		if stats.Count > 0 {
+			stats.AvgReturn /= float64(stats.Count)
+			stats.WinRate = float64(wins) / float64(stats.Count) * 100
+		}
+		sim.Horizons = append(sim.Horizons, stats)
+	}
+
+	// Newest first, so the last firing leads
+	sort.Slice(sim.Triggers, func(i, j int) bool { return sim.Triggers[i].Date > sim.Triggers[j].Date })
+	return sim, nil
+}
+
+func horizonKey(days int) string {
+	return fmt.Sprintf("%dd", days)
+}
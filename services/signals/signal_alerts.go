@@ -0,0 +1,262 @@
+package signals
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+)
+
+// Defaults and limits for end-user signal subscriptions
+const (
+	DefaultSubscriptionCooldown  = 60 // minutes
+	DefaultSubscriptionMaxPerDay = 10
+	MaxSubscriptionsPerUser      = 20
+)
+
+var alertWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// SignalAlertNotification is one stock that started matching an alert's rule or template
+type SignalAlertNotification struct {
+	StockCode  string   `json:"stock_code"`
+	SignalType string   `json:"signal_type"`
+	Score      int      `json:"score"`
+	Confidence float64  `json:"confidence"`
+	Price      float64  `json:"price"`
+	Reasons    []string `json:"reasons,omitempty"`
+}
+
+// signalAlertWebhookPayload is posted to webhook alerts
+type signalAlertWebhookPayload struct {
+	AlertID    uint                      `json:"alert_id"`
+	Name       string                    `json:"name"`
+	RuleID     uint                      `json:"rule_id,omitempty"`
+	TemplateID uint                      `json:"template_id,omitempty"`
+	Matches    []SignalAlertNotification `json:"matches"`
+	SentAt     time.Time                 `json:"sent_at"`
+}
+
+// CheckSignalAlerts screens the rule or template of every active alert and notifies stocks that
+// started matching since the previous check. The first check of an alert only records the current
+// matches. Stocks held back by the alert's cool-down or daily cap are not marked as alerted, so
+// they are notified on a later check if they still match. Returns the number of notifications.
+func (e *ConditionEvaluator) CheckSignalAlerts() (int, error) {
+	var alerts []models.SignalAlert
+	if err := e.db.Where("is_active = ? AND (rule_id > 0 OR template_id > 0)", true).Find(&alerts).Error; err != nil {
+		return 0, err
+	}
+
+	// Alerts on the same rule or template share one screening
+	screened := make(map[signalSourceKey][]*RuleSignal)
+	sent := 0
+	for i := range alerts {
+		alert := &alerts[i]
+		key := signalSourceKey{ruleID: alert.RuleID, templateID: alert.TemplateID}
+		matches, ok := screened[key]
+		if !ok {
+			var err error
+			if alert.RuleID > 0 {
+				matches, err = e.ScreenStocksWithRule(alert.RuleID, services.MinTradingValForRS, 0)
+			} else {
+				matches, err = e.ScreenStocksWithTemplate(alert.TemplateID, services.MinTradingValForRS, 0)
+			}
+			if err != nil {
+				log.Printf("Failed to screen signal alert %d: %v", alert.ID, err)
+				continue
+			}
+			screened[key] = matches
+		}
+
+		n, err := e.processSignalAlert(alert, matches)
+		if err != nil {
+			log.Printf("Failed to process signal alert %d: %v", alert.ID, err)
+			continue
+		}
+		sent += n
+	}
+	return sent, nil
+}
+
+func (e *ConditionEvaluator) processSignalAlert(alert *models.SignalAlert, matches []*RuleSignal) (int, error) {
+	now := time.Now()
+	alerted := make(map[string]bool)
+	for _, code := range strings.Split(alert.MatchedStocks, ",") {
+		if code != "" {
+			alerted[code] = true
+		}
+	}
+
+	current := make(map[string]bool, len(matches))
+	var fresh []*RuleSignal
+	for _, m := range matches {
+		if alert.StockSymbol != "" && !strings.EqualFold(alert.StockSymbol, m.StockCode) {
+			continue
+		}
+		current[m.StockCode] = true
+		if !alerted[m.StockCode] {
+			fresh = append(fresh, m)
+		}
+	}
+
+	// Keep only alerted stocks that still match, so a stock that drops out and comes back alerts again
+	kept := make([]string, 0, len(current))
+	for code := range alerted {
+		if current[code] {
+			kept = append(kept, code)
+		}
+	}
+
+	updates := map[string]interface{}{"last_checked_at": now}
+	if alert.LastCheckedAt == nil {
+		for _, m := range fresh {
+			kept = append(kept, m.StockCode)
+		}
+		fresh = nil
+	}
+	fresh = e.applySignalAlertCaps(alert, fresh, now)
+
+	if len(fresh) > 0 {
+		notifications := make([]SignalAlertNotification, len(fresh))
+		for i, m := range fresh {
+			notifications[i] = SignalAlertNotification{
+				StockCode:  m.StockCode,
+				SignalType: m.SignalType,
+				Score:      m.Score,
+				Confidence: m.Confidence,
+				Price:      m.Price,
+				Reasons:    m.Reasons,
+			}
+			kept = append(kept, m.StockCode)
+		}
+		if err := e.deliverSignalAlert(alert, notifications, now); err != nil {
+			return 0, err
+		}
+		updates["last_triggered_at"] = now
+		updates["trigger_count"] = alert.TriggerCount + len(fresh)
+	}
+
+	sort.Strings(kept)
+	updates["matched_stocks"] = strings.Join(kept, ",")
+	if err := e.db.Model(alert).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	return len(fresh), nil
+}
+
+// applySignalAlertCaps drops all matches while the alert is cooling down and trims them to what is
+// left of the daily cap. Matches are ordered best first so the cap keeps the strongest.
+func (e *ConditionEvaluator) applySignalAlertCaps(alert *models.SignalAlert, fresh []*RuleSignal, now time.Time) []*RuleSignal {
+	if len(fresh) == 0 {
+		return fresh
+	}
+	if alert.Cooldown > 0 && alert.LastTriggeredAt != nil && now.Before(alert.LastTriggeredAt.Add(time.Duration(alert.Cooldown)*time.Minute)) {
+		return nil
+	}
+
+	sort.Slice(fresh, func(i, j int) bool {
+		if fresh[i].Score != fresh[j].Score {
+			return fresh[i].Score > fresh[j].Score
+		}
+		return fresh[i].StockCode < fresh[j].StockCode
+	})
+	if alert.MaxPerDay <= 0 {
+		return fresh
+	}
+
+	cal := services.MarketCalendar()
+	local := now.In(cal.Location())
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, cal.Location())
+	var today int64
+	e.db.Model(&models.SignalAlertHistory{}).Where("alert_id = ? AND created_at >= ?", alert.ID, dayStart).Count(&today)
+
+	remaining := alert.MaxPerDay - int(today)
+	if remaining <= 0 {
+		return nil
+	}
+	if len(fresh) > remaining {
+		fresh = fresh[:remaining]
+	}
+	return fresh
+}
+
+// deliverSignalAlert records one alert history entry per stock and sends webhook alerts. In-app
+// alerts are delivered once recorded; email and push alerts stay undelivered until a sender exists.
+func (e *ConditionEvaluator) deliverSignalAlert(alert *models.SignalAlert, notifications []SignalAlertNotification, now time.Time) error {
+	var deliveredAt *time.Time
+	switch alert.AlertType {
+	case models.SignalAlertTypeInApp:
+		deliveredAt = &now
+	case models.SignalAlertTypeWebhook:
+		if err := postSignalAlertWebhook(alert, notifications, now); err != nil {
+			log.Printf("Signal alert %d webhook failed: %v", alert.ID, err)
+		} else {
+			deliveredAt = &now
+		}
+	}
+
+	for _, n := range notifications {
+		metadata, _ := json.Marshal(n)
+		history := &models.SignalAlertHistory{
+			AlertID:     alert.ID,
+			StockSymbol: n.StockCode,
+			SignalType:  n.SignalType,
+			Score:       n.Score,
+			Price:       decimal.NewFromFloat(n.Price),
+			Message:     fmt.Sprintf("%s now matches %s (score %d)", n.StockCode, alert.Name, n.Score),
+			Metadata:    string(metadata),
+			DeliveredAt: deliveredAt,
+		}
+		if err := e.db.Create(history).Error; err != nil {
+			return err
+		}
+	}
+
+	// End-user subscriptions also land in the subscriber's inbox, whatever the channel
+	if alert.UserID != "" && len(notifications) > 0 {
+		codes := make([]string, len(notifications))
+		for i, n := range notifications {
+			codes[i] = n.StockCode
+		}
+		services.NotifyUser(e.db, alert.UserID, models.NotificationCategorySignalAlert,
+			fmt.Sprintf("%s: %d new match(es)", alert.Name, len(notifications)),
+			fmt.Sprintf("%s now match %s", strings.Join(codes, ", "), alert.Name),
+			map[string]interface{}{"subscription_id": alert.ID, "matches": notifications})
+	}
+	return nil
+}
+
+func postSignalAlertWebhook(alert *models.SignalAlert, notifications []SignalAlertNotification, now time.Time) error {
+	if alert.WebhookURL == "" {
+		return fmt.Errorf("no webhook URL configured")
+	}
+	body, err := json.Marshal(signalAlertWebhookPayload{
+		AlertID:    alert.ID,
+		Name:       alert.Name,
+		RuleID:     alert.RuleID,
+		TemplateID: alert.TemplateID,
+		Matches:    notifications,
+		SentAt:     now,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := alertWebhookClient.Post(alert.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
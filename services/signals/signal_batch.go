@@ -0,0 +1,82 @@
+package signals
+
+import (
+	"fmt"
+	"sync"
+
+	"go_backend_project/services"
+)
+
+// MaxBatchSignalCodes is the largest number of codes accepted by one batch request
+const MaxBatchSignalCodes = 100
+
+// batchSignalWorkers is the size of the worker pool evaluating a batch
+const batchSignalWorkers = 8
+
+// BatchSignalResult is the signal for one code of a batch request
+type BatchSignalResult struct {
+	Code   string         `json:"code"`
+	Signal *TradingSignal `json:"signal,omitempty"` // includes the key indicators used
+	Error  string         `json:"error,omitempty"`
+}
+
+// GenerateBatchSignals evaluates a strategy for a list of codes using the in-memory indicator
+// summary. Results keep the order of codes; codes missing from the summary carry an error.
+func (s *SignalService) GenerateBatchSignals(codes []string, strategyName string) ([]*BatchSignalResult, error) {
+	if len(codes) > MaxBatchSignalCodes {
+		return nil, fmt.Errorf("at most %d codes allowed", MaxBatchSignalCodes)
+	}
+
+	s.mu.RLock()
+	strategy, ok := s.strategies[strategyName]
+	s.mu.RUnlock()
+
+	if !ok {
+		strategy = &CompositeStrategy{}
+	}
+
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchSignalResult, len(codes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := batchSignalWorkers
+	if len(codes) < workers {
+		workers = len(codes)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := &BatchSignalResult{Code: codes[i]}
+				results[i] = result
+
+				ind, ok := summary.Stocks[codes[i]]
+				if !ok || ind == nil {
+					result.Error = "stock not found"
+					continue
+				}
+				signal, err := strategy.Evaluate(ind)
+				if err != nil {
+					result.Error = err.Error()
+					continue
+				}
+				signal.Code = codes[i]
+				result.Signal = signal
+			}
+		}()
+	}
+
+	for i := range codes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
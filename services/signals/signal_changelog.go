@@ -0,0 +1,233 @@
+package signals
+
+import (
+	"sort"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+)
+
+// Signal changelog change kinds
+const (
+	SignalChangeNew        = "new"
+	SignalChangeDropped    = "dropped"
+	SignalChangeUpgraded   = "upgraded"
+	SignalChangeDowngraded = "downgraded"
+)
+
+// SignalChange is one difference between the live signals of two trading days for a stock and
+// signal source (rule or template)
+type SignalChange struct {
+	Change     string  `json:"change"`
+	StockCode  string  `json:"stock_code"`
+	RuleID     uint    `json:"rule_id,omitempty"`
+	RuleName   string  `json:"rule_name,omitempty"`
+	TemplateID uint    `json:"template_id,omitempty"`
+	FromType   string  `json:"from_type,omitempty"`
+	ToType     string  `json:"to_type,omitempty"`
+	FromScore  int     `json:"from_score,omitempty"`
+	ToScore    int     `json:"to_score,omitempty"`
+	Price      float64 `json:"price"`
+	Reason     string  `json:"reason,omitempty"` // lifecycle state a dropped signal closed with
+}
+
+// SignalChangelog lists what changed in the live signals from one trading day to the next
+type SignalChangelog struct {
+	Date         string         `json:"date"`
+	PreviousDate string         `json:"previous_date"`
+	New          []SignalChange `json:"new"`
+	Dropped      []SignalChange `json:"dropped"`
+	Upgraded     []SignalChange `json:"upgraded"`
+	Downgraded   []SignalChange `json:"downgraded"`
+	ActiveCount  int            `json:"active_count"`
+	Counts       map[string]int `json:"counts"`         // change kind -> count
+	BySignalType map[string]int `json:"by_signal_type"` // live signals at the end of Date
+}
+
+// signalTypeLevel orders signal types from most bearish to most bullish for upgrade detection
+func signalTypeLevel(signalType string) int {
+	switch SignalType(signalType) {
+	case SignalStrongBuy:
+		return 2
+	case SignalBuy:
+		return 1
+	case SignalSell:
+		return -1
+	case SignalStrongSell:
+		return -2
+	}
+	return 0
+}
+
+type signalSourceKey struct {
+	ruleID, templateID uint
+	code               string
+}
+
+// liveSignalsAt returns the newest signal of each source that was emitted before end and had
+// not closed by then. closed_at is used rather than state so past days are reconstructed correctly.
+func (e *ConditionEvaluator) liveSignalsAt(end time.Time) (map[signalSourceKey]models.SignalHistory, error) {
+	var rows []models.SignalHistory
+	if err := e.db.Where("emitted_at < ? AND (closed_at IS NULL OR closed_at >= ?)", end, end).
+		Order("emitted_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	live := make(map[signalSourceKey]models.SignalHistory, len(rows))
+	for _, h := range rows {
+		live[signalSourceKey{h.RuleID, h.TemplateID, h.StockSymbol}] = h
+	}
+	return live, nil
+}
+
+// SignalChangelog diffs the signals live at the close of the trading day containing date with
+// those live at the close of the previous trading day: new and dropped signals, and signals whose
+// type moved up or down (BUY -> STRONG_BUY is an upgrade) or that were re-emitted with another score
+func (e *ConditionEvaluator) SignalChangelog(date time.Time) (*SignalChangelog, error) {
+	cal := services.MarketCalendar()
+	local := date.In(cal.Location())
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, cal.Location())
+	if !cal.IsTradingDay(day) {
+		day = cal.PreviousTradingDay(day)
+	}
+	previous := cal.PreviousTradingDay(day)
+
+	current, err := e.liveSignalsAt(day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	before, err := e.liveSignalsAt(previous.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	changelog := &SignalChangelog{
+		Date:         day.Format(services.PriceDateFormat),
+		PreviousDate: previous.Format(services.PriceDateFormat),
+		New:          []SignalChange{},
+		Dropped:      []SignalChange{},
+		Upgraded:     []SignalChange{},
+		Downgraded:   []SignalChange{},
+		ActiveCount:  len(current),
+		Counts:       map[string]int{},
+		BySignalType: map[string]int{},
+	}
+
+	for key, cur := range current {
+		changelog.BySignalType[cur.SignalType]++
+
+		prev, existed := before[key]
+		change := SignalChange{
+			StockCode:  key.code,
+			RuleID:     key.ruleID,
+			TemplateID: key.templateID,
+			ToType:     cur.SignalType,
+			ToScore:    cur.Score,
+			Price:      cur.Price.InexactFloat64(),
+		}
+		if !existed {
+			change.Change = SignalChangeNew
+			changelog.New = append(changelog.New, change)
+			continue
+		}
+		if prev.ID == cur.ID {
+			continue
+		}
+
+		change.FromType = prev.SignalType
+		change.FromScore = prev.Score
+		from, to := signalTypeLevel(prev.SignalType), signalTypeLevel(cur.SignalType)
+		switch {
+		case to > from, to == from && cur.Score > prev.Score:
+			change.Change = SignalChangeUpgraded
+			changelog.Upgraded = append(changelog.Upgraded, change)
+		case to < from, to == from && cur.Score < prev.Score:
+			change.Change = SignalChangeDowngraded
+			changelog.Downgraded = append(changelog.Downgraded, change)
+		}
+	}
+
+	for key, prev := range before {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		changelog.Dropped = append(changelog.Dropped, SignalChange{
+			Change:     SignalChangeDropped,
+			StockCode:  key.code,
+			RuleID:     key.ruleID,
+			TemplateID: key.templateID,
+			FromType:   prev.SignalType,
+			FromScore:  prev.Score,
+			Price:      prev.ClosePrice.InexactFloat64(),
+			Reason:     prev.State,
+		})
+	}
+
+	e.attachRuleNames(changelog)
+	for _, list := range [][]SignalChange{changelog.New, changelog.Dropped, changelog.Upgraded, changelog.Downgraded} {
+		sortSignalChanges(list)
+	}
+	changelog.Counts[SignalChangeNew] = len(changelog.New)
+	changelog.Counts[SignalChangeDropped] = len(changelog.Dropped)
+	changelog.Counts[SignalChangeUpgraded] = len(changelog.Upgraded)
+	changelog.Counts[SignalChangeDowngraded] = len(changelog.Downgraded)
+	return changelog, nil
+}
+
+// attachRuleNames fills RuleName on every change from the signal rules table
+func (e *ConditionEvaluator) attachRuleNames(changelog *SignalChangelog) {
+	lists := [][]SignalChange{changelog.New, changelog.Dropped, changelog.Upgraded, changelog.Downgraded}
+
+	ids := make(map[uint]bool)
+	for _, list := range lists {
+		for _, c := range list {
+			if c.RuleID > 0 {
+				ids[c.RuleID] = true
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	idList := make([]uint, 0, len(ids))
+	for id := range ids {
+		idList = append(idList, id)
+	}
+
+	var rules []models.SignalRule
+	if err := e.db.Select("id, name").Where("id IN ?", idList).Find(&rules).Error; err != nil {
+		return
+	}
+	names := make(map[uint]string, len(rules))
+	for _, r := range rules {
+		names[r.ID] = r.Name
+	}
+	for _, list := range lists {
+		for i := range list {
+			list[i].RuleName = names[list[i].RuleID]
+		}
+	}
+}
+
+// signalType is the type after the change, or the dropped type
+func (c SignalChange) signalType() string {
+	if c.ToType != "" {
+		return c.ToType
+	}
+	return c.FromType
+}
+
+// sortSignalChanges orders bullish changes first, then by score and code
+func sortSignalChanges(list []SignalChange) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if la, lb := signalTypeLevel(a.signalType()), signalTypeLevel(b.signalType()); la != lb {
+			return la > lb
+		}
+		if sa, sb := a.ToScore+a.FromScore, b.ToScore+b.FromScore; sa != sb {
+			return sa > sb
+		}
+		return a.StockCode < b.StockCode
+	})
+}
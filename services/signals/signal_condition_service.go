@@ -11,6 +11,7 @@ import (
 
 	"go_backend_project/models"
 	"go_backend_project/services"
+	"go_backend_project/services/units"
 
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -43,6 +44,7 @@ type GroupEvaluationResult struct {
 // RuleSignal represents a signal generated from a rule
 type RuleSignal struct {
 	Rule         *models.SignalRule
+	TemplateID   uint
 	StockCode    string
 	SignalType   string
 	Score        int
@@ -119,11 +121,47 @@ func (e *ConditionEvaluator) GetIndicatorValue(ind *services.ExtendedStockIndica
 		return ind.RS3DChange // 3-day change as proxy for recent price change
 	case models.IndicatorTradingValue:
 		return ind.AvgTradingVal
+	case models.IndicatorPctFrom52WHigh:
+		return ind.PctFrom52WHigh
+	case models.IndicatorPctFrom52WLow:
+		return ind.PctFrom52WLow
+	case models.IndicatorGapPercent:
+		return ind.GapPercent
+	case models.IndicatorOBVTrend:
+		return ind.OBVTrend
+	case models.IndicatorADTrend:
+		return ind.ADTrend
+	case models.IndicatorTenkan:
+		return ind.Tenkan
+	case models.IndicatorKijun:
+		return ind.Kijun
+	case models.IndicatorSenkouA:
+		return ind.SenkouA
+	case models.IndicatorSenkouB:
+		return ind.SenkouB
+	case models.IndicatorChikou:
+		return ind.Chikou
+	case models.IndicatorPriceAboveCloud:
+		return boolIndicator(ind.PriceAboveCloud)
+	case models.IndicatorTKCrossBull:
+		return boolIndicator(ind.TKCrossBull)
+	case models.IndicatorPctFromSupport:
+		return ind.PctFromSupport
+	case models.IndicatorPctFromResistance:
+		return ind.PctFromResistance
 	default:
 		return 0
 	}
 }
 
+// boolIndicator exposes a boolean indicator to conditions as 1 or 0
+func boolIndicator(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
 // EvaluateCondition evaluates a single condition against stock indicators
 func (e *ConditionEvaluator) EvaluateCondition(condition *models.SignalCondition, ind *services.ExtendedStockIndicators) *ConditionResult {
 	result := &ConditionResult{
@@ -238,6 +276,37 @@ func (e *ConditionEvaluator) EvaluateRule(rule *models.SignalRule, ind *services
 		return nil, errors.New("rule is not active")
 	}
 
+	groupConfigs, groups, err := e.LoadRuleGroups(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.EvaluateRuleWithGroups(rule, groupConfigs, groups, ind), nil
+}
+
+// LoadRuleGroups parses the rule's group references and loads the referenced groups with conditions
+func (e *ConditionEvaluator) LoadRuleGroups(rule *models.SignalRule) ([]models.RuleGroupRef, map[uint]*models.SignalConditionGroup, error) {
+	// Parse condition groups from JSON
+	groupConfigs, err := rule.GroupRefs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make(map[uint]*models.SignalConditionGroup, len(groupConfigs))
+	for _, groupConfig := range groupConfigs {
+		// Load condition group
+		var group models.SignalConditionGroup
+		if err := e.db.Preload("Conditions").First(&group, groupConfig.GroupID).Error; err != nil {
+			continue
+		}
+		groups[group.ID] = &group
+	}
+
+	return groupConfigs, groups, nil
+}
+
+// EvaluateRuleWithGroups evaluates a rule against preloaded groups; returns nil when no signal triggers
+func (e *ConditionEvaluator) EvaluateRuleWithGroups(rule *models.SignalRule, groupConfigs []models.RuleGroupRef, groups map[uint]*models.SignalConditionGroup, ind *services.ExtendedStockIndicators) *RuleSignal {
 	signal := &RuleSignal{
 		Rule:         rule,
 		StockCode:    ind.Code,
@@ -249,31 +318,17 @@ func (e *ConditionEvaluator) EvaluateRule(rule *models.SignalRule, ind *services
 		GeneratedAt:  time.Now(),
 	}
 
-	// Parse condition groups from JSON
-	var groupConfigs []struct {
-		GroupID  uint   `json:"group_id"`
-		Logic    string `json:"logic"` // AND, OR
-		Required bool   `json:"required"`
-	}
-
-	if rule.ConditionGroups != "" {
-		if err := json.Unmarshal([]byte(rule.ConditionGroups), &groupConfigs); err != nil {
-			return nil, err
-		}
-	}
-
 	totalScore := 0
 	maxScore := 0
 	allPassed := true
 
 	for _, groupConfig := range groupConfigs {
-		// Load condition group
-		var group models.SignalConditionGroup
-		if err := e.db.Preload("Conditions").First(&group, groupConfig.GroupID).Error; err != nil {
+		group, ok := groups[groupConfig.GroupID]
+		if !ok {
 			continue
 		}
 
-		groupResult := e.EvaluateConditionGroup(&group, ind)
+		groupResult := e.EvaluateConditionGroup(group, ind)
 		signal.GroupResults = append(signal.GroupResults, *groupResult)
 
 		totalScore += groupResult.TotalScore
@@ -305,7 +360,7 @@ func (e *ConditionEvaluator) EvaluateRule(rule *models.SignalRule, ind *services
 	}
 
 	if !allPassed || scorePercent < rule.MinScore {
-		return nil, nil // Signal not triggered
+		return nil // Signal not triggered
 	}
 
 	// Calculate target and stop loss
@@ -313,11 +368,11 @@ func (e *ConditionEvaluator) EvaluateRule(rule *models.SignalRule, ind *services
 	stopLossPercent := rule.StopLossPercent.InexactFloat64()
 
 	if rule.SignalType == "BUY" || rule.SignalType == "STRONG_BUY" {
-		signal.TargetPrice = ind.CurrentPrice * (1 + targetPercent/100)
-		signal.StopLoss = ind.CurrentPrice * (1 - stopLossPercent/100)
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, targetPercent)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -stopLossPercent)
 	} else if rule.SignalType == "SELL" || rule.SignalType == "STRONG_SELL" {
-		signal.TargetPrice = ind.CurrentPrice * (1 - targetPercent/100)
-		signal.StopLoss = ind.CurrentPrice * (1 + stopLossPercent/100)
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, -targetPercent)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, stopLossPercent)
 	}
 
 	// Store key indicators
@@ -328,13 +383,15 @@ func (e *ConditionEvaluator) EvaluateRule(rule *models.SignalRule, ind *services
 	signal.Indicators["ma50"] = ind.MA50
 	signal.Indicators["ma200"] = ind.MA200
 
-	return signal, nil
+	return signal
 }
 
-// EvaluateAllRules evaluates all active rules for a stock
+// EvaluateAllRules evaluates all active admin rules for a stock; rules users cloned from
+// templates are theirs alone and not part of the global signals
 func (e *ConditionEvaluator) EvaluateAllRules(ind *services.ExtendedStockIndicators) ([]*RuleSignal, error) {
 	var rules []models.SignalRule
-	if err := e.db.Where("is_active = ?", true).Order("priority DESC").Find(&rules).Error; err != nil {
+	if err := e.db.Where("is_active = ? AND (owner_user_id = '' OR owner_user_id IS NULL)", true).
+		Order("priority DESC").Find(&rules).Error; err != nil {
 		return nil, err
 	}
 
@@ -357,6 +414,7 @@ func (e *ConditionEvaluator) EvaluateTemplate(template *models.SignalTemplate, i
 	}
 
 	signal := &RuleSignal{
+		TemplateID:  template.ID,
 		StockCode:   ind.Code,
 		Price:       ind.CurrentPrice,
 		Reasons:     []string{},
@@ -408,23 +466,23 @@ func (e *ConditionEvaluator) EvaluateTemplate(template *models.SignalTemplate, i
 	case "momentum", "breakout", "trend":
 		if signal.Confidence >= 0.8 {
 			signal.SignalType = "STRONG_BUY"
-			signal.TargetPrice = ind.CurrentPrice * 1.15
-			signal.StopLoss = ind.CurrentPrice * 0.95
+			signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 15)
+			signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 		} else {
 			signal.SignalType = "BUY"
-			signal.TargetPrice = ind.CurrentPrice * 1.10
-			signal.StopLoss = ind.CurrentPrice * 0.95
+			signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 10)
+			signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 		}
 	case "reversal":
 		// For reversal, check if it's oversold or overbought
 		if ind.RSI < 40 {
 			signal.SignalType = "BUY"
-			signal.TargetPrice = ind.MA50
-			signal.StopLoss = ind.CurrentPrice * 0.93
+			signal.TargetPrice = services.RoundPrice(ind.Code, ind.MA50)
+			signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -7)
 		} else {
 			signal.SignalType = "SELL"
-			signal.TargetPrice = ind.MA50
-			signal.StopLoss = ind.CurrentPrice * 1.07
+			signal.TargetPrice = services.RoundPrice(ind.Code, ind.MA50)
+			signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, 7)
 		}
 	default:
 		signal.SignalType = "ALERT"
@@ -435,13 +493,18 @@ func (e *ConditionEvaluator) EvaluateTemplate(template *models.SignalTemplate, i
 
 // ScreenStocksWithRule screens all stocks with a specific rule
 func (e *ConditionEvaluator) ScreenStocksWithRule(ruleID uint, minTradingVal float64, limit int) ([]*RuleSignal, error) {
-	var rule models.SignalRule
-	if err := e.db.First(&rule, ruleID).Error; err != nil {
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
 		return nil, err
 	}
+	return e.ScreenIndicatorsWithRule(ruleID, summary.Stocks, minTradingVal, limit)
+}
 
-	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
-	if err != nil {
+// ScreenIndicatorsWithRule screens the given indicators with a rule, e.g. indicators recomputed
+// as of a past date
+func (e *ConditionEvaluator) ScreenIndicatorsWithRule(ruleID uint, indicators map[string]*services.ExtendedStockIndicators, minTradingVal float64, limit int) ([]*RuleSignal, error) {
+	var rule models.SignalRule
+	if err := e.db.First(&rule, ruleID).Error; err != nil {
 		return nil, err
 	}
 
@@ -451,7 +514,7 @@ func (e *ConditionEvaluator) ScreenStocksWithRule(ruleID uint, minTradingVal flo
 
 	semaphore := make(chan struct{}, 10)
 
-	for code, ind := range summary.Stocks {
+	for code, ind := range indicators {
 		if ind == nil || ind.AvgTradingVal < minTradingVal {
 			continue
 		}
@@ -487,13 +550,17 @@ func (e *ConditionEvaluator) ScreenStocksWithRule(ruleID uint, minTradingVal flo
 
 // ScreenStocksWithTemplate screens all stocks with a template
 func (e *ConditionEvaluator) ScreenStocksWithTemplate(templateID uint, minTradingVal float64, limit int) ([]*RuleSignal, error) {
-	var template models.SignalTemplate
-	if err := e.db.First(&template, templateID).Error; err != nil {
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	if err != nil {
 		return nil, err
 	}
+	return e.ScreenIndicatorsWithTemplate(templateID, summary.Stocks, minTradingVal, limit)
+}
 
-	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
-	if err != nil {
+// ScreenIndicatorsWithTemplate screens the given indicators with a template
+func (e *ConditionEvaluator) ScreenIndicatorsWithTemplate(templateID uint, indicators map[string]*services.ExtendedStockIndicators, minTradingVal float64, limit int) ([]*RuleSignal, error) {
+	var template models.SignalTemplate
+	if err := e.db.First(&template, templateID).Error; err != nil {
 		return nil, err
 	}
 
@@ -503,7 +570,7 @@ func (e *ConditionEvaluator) ScreenStocksWithTemplate(templateID uint, minTradin
 
 	semaphore := make(chan struct{}, 10)
 
-	for code, ind := range summary.Stocks {
+	for code, ind := range indicators {
 		if ind == nil || ind.AvgTradingVal < minTradingVal {
 			continue
 		}
@@ -537,12 +604,17 @@ func (e *ConditionEvaluator) ScreenStocksWithTemplate(templateID uint, minTradin
 	return signals, nil
 }
 
-// RecordSignalPerformance records a signal for performance tracking
-func (e *ConditionEvaluator) RecordSignalPerformance(signal *RuleSignal) error {
+func (e *ConditionEvaluator) createSignalPerformance(signal *RuleSignal) (*models.SignalPerformance, error) {
 	indicatorJSON, _ := json.Marshal(signal.Indicators)
 
+	var ruleID uint
+	if signal.Rule != nil {
+		ruleID = signal.Rule.ID
+	}
+
 	perf := &models.SignalPerformance{
-		RuleID:            signal.Rule.ID,
+		RuleID:            ruleID,
+		TemplateID:        signal.TemplateID,
 		StockSymbol:       signal.StockCode,
 		SignalDate:        signal.GeneratedAt,
 		SignalType:        signal.SignalType,
@@ -553,7 +625,10 @@ func (e *ConditionEvaluator) RecordSignalPerformance(signal *RuleSignal) error {
 		IndicatorSnapshot: string(indicatorJSON),
 	}
 
-	return e.db.Create(perf).Error
+	if err := e.db.Create(perf).Error; err != nil {
+		return nil, err
+	}
+	return perf, nil
 }
 
 // UpdateSignalPerformance updates signal performance with exit data
@@ -563,24 +638,21 @@ func (e *ConditionEvaluator) UpdateSignalPerformance(perfID uint, exitPrice floa
 		return err
 	}
 
-	entryPrice := perf.EntryPrice.InexactFloat64()
-	pnlPercent := (exitPrice - entryPrice) / entryPrice * 100
-
-	if perf.SignalType == "SELL" || perf.SignalType == "STRONG_SELL" {
-		pnlPercent = -pnlPercent // Reverse for sell signals
-	}
+	exit := decimal.NewFromFloat(exitPrice)
+	short := perf.SignalType == "SELL" || perf.SignalType == "STRONG_SELL" // Reverse for sell signals
+	pnlPercent := units.ReturnPct(perf.EntryPrice, exit, short)
 
 	now := time.Now()
 	holdingDays := int(now.Sub(perf.SignalDate).Hours() / 24)
 
 	updates := map[string]interface{}{
-		"exit_price":   decimal.NewFromFloat(exitPrice),
+		"exit_price":   exit,
 		"exit_date":    now,
 		"exit_reason":  exitReason,
-		"pnl_percent":  decimal.NewFromFloat(pnlPercent),
-		"pnl_amount":   decimal.NewFromFloat((exitPrice - entryPrice) * 100), // Assuming 100 shares
+		"pnl_percent":  pnlPercent,
+		"pnl_amount":   units.PnL(perf.EntryPrice, exit, 100, short), // Assuming 100 shares
 		"holding_days": holdingDays,
-		"is_win":       pnlPercent > 0,
+		"is_win":       pnlPercent.IsPositive(),
 	}
 
 	return e.db.Model(&perf).Updates(updates).Error
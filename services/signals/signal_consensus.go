@@ -0,0 +1,202 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+)
+
+// ConsensusEntry is the verdict of one strategy or rule for a stock
+type ConsensusEntry struct {
+	Source     string     `json:"source"` // strategy or rule
+	Name       string     `json:"name"`
+	RuleID     uint       `json:"rule_id,omitempty"`
+	Signal     SignalType `json:"signal"`
+	Strength   int        `json:"strength"`
+	Confidence float64    `json:"confidence"`
+	Direction  int        `json:"direction"` // +1 bullish, 0 neutral, -1 bearish
+	Agrees     bool       `json:"agrees"`    // direction matches the consensus
+	Reasons    []string   `json:"reasons"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// SignalConsensus combines the verdicts of every strategy and active rule for one stock
+type SignalConsensus struct {
+	Code           string            `json:"code"`
+	Price          float64           `json:"price"`
+	Consensus      SignalType        `json:"consensus"`
+	AgreementScore float64           `json:"agreement_score"` // share of voters agreeing with the consensus, 0-1
+	WeightedScore  float64           `json:"weighted_score"`  // mean direction * strength, -100..100
+	Bullish        int               `json:"bullish"`
+	Bearish        int               `json:"bearish"`
+	Neutral        int               `json:"neutral"`
+	Entries        []*ConsensusEntry `json:"entries"`
+	GeneratedAt    string            `json:"generated_at"`
+}
+
+func signalDirection(signal SignalType) int {
+	switch signal {
+	case SignalBuy, SignalStrongBuy:
+		return 1
+	case SignalSell, SignalStrongSell:
+		return -1
+	}
+	return 0
+}
+
+// LoadStockIndicators returns indicators for a stock from the indicator summary (which carries
+// RS ranks), falling back to calculating them from the price file with estimated RS ranks
+func LoadStockIndicators(code string) (*services.ExtendedStockIndicators, error) {
+	if services.GlobalIndicatorService == nil {
+		return nil, fmt.Errorf("indicator service not initialized")
+	}
+	return services.GlobalIndicatorService.GetRankedStockIndicators(code)
+}
+
+// BuildConsensus evaluates every registered strategy and every active admin rule for a stock
+// concurrently against a single indicator load
+func BuildConsensus(code string) (*SignalConsensus, error) {
+	if GlobalSignalService == nil {
+		return nil, fmt.Errorf("signal service not initialized")
+	}
+	ind, err := LoadStockIndicators(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*ConsensusEntry
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	add := func(entry *ConsensusEntry) {
+		mu.Lock()
+		entries = append(entries, entry)
+		mu.Unlock()
+	}
+
+	for _, name := range GlobalSignalService.GetStrategies() {
+		strategy, ok := GlobalSignalService.GetStrategy(name)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, strategy Strategy) {
+			defer wg.Done()
+			entry := &ConsensusEntry{Source: "strategy", Name: name, Signal: SignalHold, Reasons: []string{}}
+			signal, err := strategy.Evaluate(ind)
+			if err != nil {
+				entry.Error = err.Error()
+			} else if signal != nil {
+				entry.Signal = signal.Signal
+				entry.Strength = signal.Strength
+				entry.Confidence = signal.Confidence
+				entry.Reasons = signal.Reasons
+			}
+			add(entry)
+		}(name, strategy)
+	}
+
+	if e := GlobalConditionEvaluator; e != nil && e.db != nil {
+		var rules []models.SignalRule
+		e.db.Where("is_active = ? AND (owner_user_id = '' OR owner_user_id IS NULL)", true).
+			Order("priority DESC").Find(&rules)
+		for i := range rules {
+			wg.Add(1)
+			go func(rule *models.SignalRule) {
+				defer wg.Done()
+				add(e.ruleConsensusEntry(rule, ind))
+			}(&rules[i])
+		}
+	}
+
+	wg.Wait()
+	return summarizeConsensus(code, ind.CurrentPrice, entries), nil
+}
+
+// ruleConsensusEntry evaluates a rule; an untriggered rule is a neutral vote
+func (e *ConditionEvaluator) ruleConsensusEntry(rule *models.SignalRule, ind *services.ExtendedStockIndicators) *ConsensusEntry {
+	entry := &ConsensusEntry{Source: "rule", Name: rule.Name, RuleID: rule.ID, Signal: SignalHold, Reasons: []string{}}
+
+	groupConfigs, groups, err := e.LoadRuleGroups(rule)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	signal := e.EvaluateRuleWithGroups(rule, groupConfigs, groups, ind)
+	if signal == nil {
+		entry.Reasons = []string{"Rule conditions not met"}
+		return entry
+	}
+
+	entry.Signal = SignalType(signal.SignalType)
+	entry.Confidence = signal.Confidence
+	entry.Strength = int(math.Round(signal.Confidence * 100))
+	entry.Reasons = signal.Reasons
+	return entry
+}
+
+func summarizeConsensus(code string, price float64, entries []*ConsensusEntry) *SignalConsensus {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source > entries[j].Source // strategies first
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	c := &SignalConsensus{
+		Code:        code,
+		Price:       price,
+		Consensus:   SignalHold,
+		Entries:     entries,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+
+	voters := 0
+	var weighted float64
+	for _, entry := range entries {
+		if entry.Error != "" {
+			continue
+		}
+		entry.Direction = signalDirection(entry.Signal)
+		voters++
+		weighted += float64(entry.Direction * entry.Strength)
+		switch entry.Direction {
+		case 1:
+			c.Bullish++
+		case -1:
+			c.Bearish++
+		default:
+			c.Neutral++
+		}
+	}
+	if voters == 0 {
+		return c
+	}
+	c.WeightedScore = math.Round(weighted/float64(voters)*100) / 100
+
+	direction, agreeing := 0, c.Neutral
+	switch {
+	case c.Bullish > c.Bearish && c.Bullish >= c.Neutral:
+		direction, agreeing = 1, c.Bullish
+		c.Consensus = SignalBuy
+		if float64(c.Bullish)/float64(voters) >= 0.75 {
+			c.Consensus = SignalStrongBuy
+		}
+	case c.Bearish > c.Bullish && c.Bearish >= c.Neutral:
+		direction, agreeing = -1, c.Bearish
+		c.Consensus = SignalSell
+		if float64(c.Bearish)/float64(voters) >= 0.75 {
+			c.Consensus = SignalStrongSell
+		}
+	}
+	c.AgreementScore = math.Round(float64(agreeing)/float64(voters)*100) / 100
+
+	for _, entry := range entries {
+		entry.Agrees = entry.Error == "" && entry.Direction == direction
+	}
+	return c
+}
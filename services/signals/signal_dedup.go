@@ -0,0 +1,165 @@
+package signals
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Fallbacks when a rule has no cool-down or expiry configured (e.g. template signals)
+const (
+	DefaultSignalCooldownHours = 24
+	DefaultSignalExpiryDays    = 30 // trading days
+)
+
+// signalSourceScope restricts a query to the history of one rule or template for a stock
+func signalSourceScope(ruleID, templateID uint, code string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("rule_id = ? AND template_id = ? AND stock_symbol = ?", ruleID, templateID, code)
+	}
+}
+
+// ShouldEmitSignal reports whether a signal may be emitted: either no previous signal exists,
+// the previous signal is no longer active, or the cool-down since the previous signal has passed
+func (e *ConditionEvaluator) ShouldEmitSignal(signal *RuleSignal) (bool, string, error) {
+	var ruleID uint
+	cooldown := DefaultSignalCooldownHours
+	if signal.Rule != nil {
+		ruleID = signal.Rule.ID
+		if signal.Rule.CooldownHours > 0 {
+			cooldown = signal.Rule.CooldownHours
+		}
+	}
+
+	var last models.SignalHistory
+	err := e.db.Scopes(signalSourceScope(ruleID, signal.TemplateID, signal.StockCode)).
+		Order("emitted_at DESC").First(&last).Error
+	if err == gorm.ErrRecordNotFound {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	if err := e.UpdateSignalAtPrice(&last, signal.Price); err != nil {
+		return false, "", err
+	}
+	if last.State != models.SignalStateActive {
+		return true, "", nil
+	}
+
+	next := last.EmittedAt.Add(time.Duration(cooldown) * time.Hour)
+	if time.Now().Before(next) {
+		return false, fmt.Sprintf("previous signal still active, cool-down until %s", next.Format(time.RFC3339)), nil
+	}
+	return true, "", nil
+}
+
+// EmitSignals filters out repeated signals and records the remaining ones in SignalHistory
+// and SignalPerformance. An active signal replaced after its cool-down becomes superseded.
+func (e *ConditionEvaluator) EmitSignals(candidates []*RuleSignal) ([]*RuleSignal, error) {
+	emitted := make([]*RuleSignal, 0, len(candidates))
+	for _, signal := range candidates {
+		ok, _, err := e.ShouldEmitSignal(signal)
+		if err != nil {
+			return emitted, err
+		}
+		if !ok {
+			continue
+		}
+		if err := e.recordEmittedSignal(signal); err != nil {
+			return emitted, err
+		}
+		emitted = append(emitted, signal)
+	}
+	return emitted, nil
+}
+
+func (e *ConditionEvaluator) recordEmittedSignal(signal *RuleSignal) error {
+	var ruleID uint
+	expiryDays := DefaultSignalExpiryDays
+	if signal.Rule != nil {
+		ruleID = signal.Rule.ID
+		if signal.Rule.ExpiryDays > 0 {
+			expiryDays = signal.Rule.ExpiryDays
+		}
+	}
+	now := time.Now()
+
+	// Supersede a still-active signal whose cool-down has passed
+	var active []models.SignalHistory
+	e.db.Scopes(signalSourceScope(ruleID, signal.TemplateID, signal.StockCode)).
+		Where("state = ?", models.SignalStateActive).Find(&active)
+	for i := range active {
+		if err := e.transitionSignal(&active[i], models.SignalStateSuperseded, signal.Price, now); err != nil {
+			return err
+		}
+	}
+
+	perf, err := e.createSignalPerformance(signal)
+	if err != nil {
+		return err
+	}
+
+	history := &models.SignalHistory{
+		RuleID:        ruleID,
+		TemplateID:    signal.TemplateID,
+		StockSymbol:   signal.StockCode,
+		SignalType:    signal.SignalType,
+		Score:         signal.Score,
+		Price:         decimal.NewFromFloat(signal.Price),
+		TargetPrice:   decimal.NewFromFloat(signal.TargetPrice),
+		StopLossPrice: decimal.NewFromFloat(signal.StopLoss),
+		State:         models.SignalStateActive,
+		EmittedAt:     now,
+		ExpiresAt:     services.MarketCalendar().AddTradingDays(now, expiryDays),
+		PerformanceID: perf.ID,
+	}
+	if err := e.db.Create(history).Error; err != nil {
+		return err
+	}
+	// Rules run from an author's public template also reach the author's followers
+	services.NotifyStrategistFollowers(e.db, signal.Rule, history)
+	return nil
+}
+
+// RunRuleSignalEmission screens the active published rules and emits new, deduplicated signals.
+// Active signals of a screened stock are first checked against its current price.
+func (e *ConditionEvaluator) RunRuleSignalEmission() ([]*RuleSignal, error) {
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return nil, errSignalsDisabled
+	}
+	rules, err := e.emittingRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var emitted []*RuleSignal
+	for _, rule := range rules {
+		candidates, err := e.ScreenStocksWithRule(rule.ID, services.MinTradingValForRS, 0)
+		if err != nil {
+			log.Printf("Failed to screen rule %s: %v", rule.Name, err)
+			continue
+		}
+		fresh, err := e.EmitSignals(candidates)
+		if err != nil {
+			log.Printf("Failed to emit signals for rule %s: %v", rule.Name, err)
+		}
+		emitted = append(emitted, fresh...)
+	}
+	return emitted, nil
+}
+
+// emittingRules returns the active rules that emit signals: admin rules, and strategist rules
+// whose signals reach the author's followers. Other user clones stay private to their owner.
+func (e *ConditionEvaluator) emittingRules() ([]models.SignalRule, error) {
+	var rules []models.SignalRule
+	err := e.db.Scopes(services.PublishedRules).Where("signal_rules.is_active = ?", true).Order("signal_rules.id").Find(&rules).Error
+	return rules, err
+}
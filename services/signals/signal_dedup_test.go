@@ -0,0 +1,77 @@
+package signals
+
+import (
+	"testing"
+	"time"
+
+	"go_backend_project/models"
+)
+
+func TestStrategistRuleSignalReachesFollowers(t *testing.T) {
+	db := testDB(t)
+	e := &ConditionEvaluator{db: db}
+
+	const author, follower = "test-strategist", "test-follower"
+	conditions := `[{"indicator": "RSI", "operator": "lt", "value": 30, "weight": 100}]`
+	public := &models.SignalTemplate{Name: "test public template", Conditions: conditions,
+		AuthorID: author, AuthorName: "Test Strategist", Visibility: models.TemplateVisibilityPublic}
+	private := &models.SignalTemplate{Name: "test private template", Conditions: conditions,
+		AuthorID: author, Visibility: models.TemplateVisibilityPrivate}
+	foreign := &models.SignalTemplate{Name: "test foreign template", Conditions: conditions,
+		AuthorID: "test-other-author", Visibility: models.TemplateVisibilityPublic}
+	for _, template := range []*models.SignalTemplate{public, private, foreign} {
+		if err := db.Create(template).Error; err != nil {
+			t.Fatalf("create template: %v", err)
+		}
+	}
+
+	admin := &models.SignalRule{Name: "test admin rule", SignalType: "BUY", IsActive: true}
+	strategist := &models.SignalRule{Name: "test strategist rule", SignalType: "BUY", IsActive: true,
+		OwnerUserID: author, SourceTemplateID: &public.ID}
+	privateClone := &models.SignalRule{Name: "test private clone", SignalType: "BUY", IsActive: true,
+		OwnerUserID: author, SourceTemplateID: &private.ID}
+	foreignClone := &models.SignalRule{Name: "test clone of another author", SignalType: "BUY", IsActive: true,
+		OwnerUserID: author, SourceTemplateID: &foreign.ID}
+	for _, rule := range []*models.SignalRule{admin, strategist, privateClone, foreignClone} {
+		if err := db.Create(rule).Error; err != nil {
+			t.Fatalf("create rule: %v", err)
+		}
+	}
+	if err := db.Create(&models.StrategistFollow{UserID: follower, AuthorID: author}).Error; err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+
+	rules, err := e.emittingRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitting := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		emitting[rule.ID] = true
+	}
+	if !emitting[admin.ID] || !emitting[strategist.ID] {
+		t.Errorf("admin and strategist rules must emit, got %v", emitting)
+	}
+	if emitting[privateClone.ID] || emitting[foreignClone.ID] {
+		t.Errorf("private clones must not emit, got %v", emitting)
+	}
+
+	signal := &RuleSignal{Rule: strategist, StockCode: "VNM", SignalType: "BUY", Score: 80,
+		Price: 70, TargetPrice: 77, StopLoss: 66.5, GeneratedAt: time.Now()}
+	emitted, err := e.EmitSignals([]*RuleSignal{signal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emitted) != 1 {
+		t.Fatalf("emitted %d signals, want 1", len(emitted))
+	}
+
+	var notifications []models.Notification
+	if err := db.Where("user_id = ? AND category = ?", follower, models.NotificationCategoryStrategist).
+		Find(&notifications).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("follower has %d strategist notifications, want 1", len(notifications))
+	}
+}
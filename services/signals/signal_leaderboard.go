@@ -0,0 +1,163 @@
+package signals
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go_backend_project/models"
+)
+
+// LeaderboardWindows are the lookback windows reported on the leaderboard
+var LeaderboardWindows = []struct {
+	Key  string
+	Days int
+}{
+	{"1M", 30},
+	{"3M", 90},
+	{"6M", 180},
+}
+
+// WindowPerformance is the closed-signal performance of a source over one window
+type WindowPerformance struct {
+	Signals   int64   `json:"signals"`
+	Wins      int64   `json:"wins"`
+	HitRate   float64 `json:"hit_rate"`   // percent
+	AvgReturn float64 `json:"avg_return"` // percent
+}
+
+// LeaderboardEntry is the performance of one rule or template
+type LeaderboardEntry struct {
+	SourceType string                        `json:"source_type"` // rule, template
+	SourceID   uint                          `json:"source_id"`
+	Name       string                        `json:"name"`
+	SignalType string                        `json:"signal_type,omitempty"`
+	IsActive   bool                          `json:"is_active"`
+	Windows    map[string]*WindowPerformance `json:"windows"`
+}
+
+// GetPerformanceLeaderboard aggregates closed SignalPerformance outcomes per rule
+// and template over the 1M/3M/6M windows, sorted by hit rate in sortWindow.
+// A rule's signals also count towards the template the rule was cloned from.
+func (e *ConditionEvaluator) GetPerformanceLeaderboard(sourceType, sortWindow string, minSignals int) ([]*LeaderboardEntry, error) {
+	entries := make(map[string]*LeaderboardEntry)
+	now := time.Now()
+
+	for _, window := range LeaderboardWindows {
+		var rows []struct {
+			RuleID           uint
+			TemplateID       uint
+			SourceTemplateID uint
+			Signals          int64
+			Wins             int64
+			TotalReturn      float64
+		}
+
+		// Deleted rules keep their source template, so the join ignores soft deletes
+		err := e.db.Model(&models.SignalPerformance{}).
+			Select(`signal_performances.rule_id, signal_performances.template_id,
+				COALESCE(signal_rules.source_template_id, 0) as source_template_id,
+				COUNT(*) as signals,
+				SUM(CASE WHEN signal_performances.is_win THEN 1 ELSE 0 END) as wins,
+				COALESCE(SUM(signal_performances.pnl_percent), 0) as total_return`).
+			Joins("LEFT JOIN signal_rules ON signal_rules.id = signal_performances.rule_id").
+			Where("signal_performances.exit_date IS NOT NULL AND signal_performances.signal_date >= ?", now.AddDate(0, 0, -window.Days)).
+			Group("signal_performances.rule_id, signal_performances.template_id, signal_rules.source_template_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		add := func(entryType string, id uint, signals, wins int64, totalReturn float64) {
+			if id == 0 || (sourceType != "" && sourceType != entryType) {
+				return
+			}
+
+			key := fmt.Sprintf("%s:%d", entryType, id)
+			entry, ok := entries[key]
+			if !ok {
+				entry = &LeaderboardEntry{
+					SourceType: entryType,
+					SourceID:   id,
+					Windows:    make(map[string]*WindowPerformance),
+				}
+				entries[key] = entry
+			}
+
+			perf := entry.Windows[window.Key]
+			if perf == nil {
+				perf = &WindowPerformance{}
+				entry.Windows[window.Key] = perf
+			}
+			total := perf.AvgReturn*float64(perf.Signals) + totalReturn
+			perf.Signals += signals
+			perf.Wins += wins
+			if perf.Signals > 0 {
+				perf.HitRate = float64(perf.Wins) / float64(perf.Signals) * 100
+				perf.AvgReturn = total / float64(perf.Signals)
+			}
+		}
+
+		for _, row := range rows {
+			add("rule", row.RuleID, row.Signals, row.Wins, row.TotalReturn)
+			templateID := row.TemplateID
+			if templateID == 0 {
+				templateID = row.SourceTemplateID
+			}
+			add("template", templateID, row.Signals, row.Wins, row.TotalReturn)
+		}
+	}
+
+	// Attach names, including rules/templates that have since been deleted
+	var ruleIDs, templateIDs []uint
+	for _, entry := range entries {
+		if entry.SourceType == "rule" {
+			ruleIDs = append(ruleIDs, entry.SourceID)
+		} else {
+			templateIDs = append(templateIDs, entry.SourceID)
+		}
+	}
+	if len(ruleIDs) > 0 {
+		var rules []models.SignalRule
+		e.db.Unscoped().Where("id IN ?", ruleIDs).Find(&rules)
+		for _, rule := range rules {
+			if entry := entries[fmt.Sprintf("rule:%d", rule.ID)]; entry != nil {
+				entry.Name = rule.Name
+				entry.SignalType = rule.SignalType
+				entry.IsActive = rule.IsActive && !rule.DeletedAt.Valid
+			}
+		}
+	}
+	if len(templateIDs) > 0 {
+		var templates []models.SignalTemplate
+		e.db.Unscoped().Where("id IN ?", templateIDs).Find(&templates)
+		for _, template := range templates {
+			if entry := entries[fmt.Sprintf("template:%d", template.ID)]; entry != nil {
+				entry.Name = template.Name
+				entry.IsActive = !template.DeletedAt.Valid
+			}
+		}
+	}
+
+	result := make([]*LeaderboardEntry, 0, len(entries))
+	for _, entry := range entries {
+		perf := entry.Windows[sortWindow]
+		if minSignals > 0 && (perf == nil || perf.Signals < int64(minSignals)) {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		pi, pj := result[i].Windows[sortWindow], result[j].Windows[sortWindow]
+		if pi == nil || pj == nil {
+			return pi != nil
+		}
+		if pi.HitRate != pj.HitRate {
+			return pi.HitRate > pj.HitRate
+		}
+		return pi.AvgReturn > pj.AvgReturn
+	})
+
+	return result, nil
+}
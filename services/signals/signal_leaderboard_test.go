@@ -0,0 +1,59 @@
+package signals
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go_backend_project/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLeaderboardAttributesRuleSignalsToSourceTemplate(t *testing.T) {
+	db := testDB(t)
+	e := &ConditionEvaluator{db: db}
+
+	template := &models.SignalTemplate{Name: "test leaderboard template",
+		Conditions: `[{"indicator": "RSI", "operator": "lt", "value": 30, "weight": 100}]`}
+	if err := db.Create(template).Error; err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	rule := &models.SignalRule{Name: "test leaderboard rule", SignalType: "BUY", IsActive: true,
+		SourceTemplateID: &template.ID}
+	if err := db.Create(rule).Error; err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	exit := time.Now()
+	for i, pnl := range []float64{10, -4} {
+		perf := &models.SignalPerformance{RuleID: rule.ID, StockSymbol: fmt.Sprintf("T%d", i),
+			SignalDate: exit.AddDate(0, 0, -5), SignalType: "BUY", ExitDate: &exit,
+			PnLPercent: decimal.NewFromFloat(pnl), IsWin: pnl > 0, IndicatorSnapshot: "{}"}
+		if err := db.Create(perf).Error; err != nil {
+			t.Fatalf("create performance: %v", err)
+		}
+	}
+
+	entries, err := e.GetPerformanceLeaderboard("", "1M", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]*LeaderboardEntry)
+	for _, entry := range entries {
+		found[fmt.Sprintf("%s:%d", entry.SourceType, entry.SourceID)] = entry
+	}
+	for _, key := range []string{fmt.Sprintf("rule:%d", rule.ID), fmt.Sprintf("template:%d", template.ID)} {
+		entry := found[key]
+		if entry == nil {
+			t.Fatalf("no leaderboard entry for %s", key)
+		}
+		perf := entry.Windows["1M"]
+		if perf == nil || perf.Signals != 2 || perf.Wins != 1 || perf.HitRate != 50 || perf.AvgReturn != 3 {
+			t.Errorf("%s 1M = %+v, want 2 signals, 1 win, 50%% hit rate, 3%% average return", key, perf)
+		}
+	}
+	if found[fmt.Sprintf("template:%d", template.ID)].Name != template.Name {
+		t.Errorf("template entry is not named")
+	}
+}
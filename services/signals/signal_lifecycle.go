@@ -0,0 +1,283 @@
+package signals
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+)
+
+// signalExitReasons maps terminal lifecycle states to SignalPerformance exit reasons
+var signalExitReasons = map[string]string{
+	models.SignalStateHitTarget:  "target_hit",
+	models.SignalStateStopped:    "stop_loss",
+	models.SignalStateExpired:    "timeout",
+	models.SignalStateSuperseded: "superseded",
+}
+
+// signalBar is the price range a signal is checked against
+type signalBar struct {
+	high, low float64
+}
+
+// signalTransition returns the state an active signal moves to for a bar and the exit price,
+// or "" when it stays active. Stop is checked before target so ambiguity is resolved conservatively.
+func signalTransition(h *models.SignalHistory, bar signalBar) (string, float64) {
+	target := h.TargetPrice.InexactFloat64()
+	stop := h.StopLossPrice.InexactFloat64()
+	isSell := h.SignalType == "SELL" || h.SignalType == "STRONG_SELL"
+
+	switch {
+	case stop > 0 && !isSell && bar.low > 0 && bar.low <= stop, stop > 0 && isSell && bar.high >= stop:
+		return models.SignalStateStopped, stop
+	case target > 0 && !isSell && bar.high >= target, target > 0 && isSell && bar.low > 0 && bar.low <= target:
+		return models.SignalStateHitTarget, target
+	}
+	return "", 0
+}
+
+// transitionSignal moves a signal out of the active state and records the outcome on its performance row
+func (e *ConditionEvaluator) transitionSignal(h *models.SignalHistory, state string, price float64, at time.Time) error {
+	if price <= 0 {
+		price = h.Price.InexactFloat64()
+	}
+	if err := e.db.Model(h).Updates(map[string]interface{}{
+		"state":       state,
+		"closed_at":   at,
+		"close_price": decimal.NewFromFloat(price),
+	}).Error; err != nil {
+		return err
+	}
+	h.State = state
+	services.ClosePaperTradesForSignal(e.db, h, price, signalExitReasons[state])
+
+	if h.PerformanceID > 0 {
+		return e.UpdateSignalPerformance(h.PerformanceID, price, signalExitReasons[state])
+	}
+	return nil
+}
+
+// UpdateSignalAtPrice transitions an active signal when price reached its target or stop, or it expired
+func (e *ConditionEvaluator) UpdateSignalAtPrice(h *models.SignalHistory, price float64) error {
+	if h.State != models.SignalStateActive {
+		return nil
+	}
+	now := time.Now()
+	if state, exit := signalTransition(h, signalBar{high: price, low: price}); state != "" {
+		return e.transitionSignal(h, state, exit, now)
+	}
+	if !h.ExpiresAt.IsZero() && !now.Before(h.ExpiresAt) {
+		return e.transitionSignal(h, models.SignalStateExpired, price, now)
+	}
+	return nil
+}
+
+// UpdateSignalLifecycles checks every active signal against the daily bars since it was emitted
+// and moves it to hit_target, stopped or expired. Returns the number of transitioned signals.
+func (e *ConditionEvaluator) UpdateSignalLifecycles() (int, error) {
+	var active []models.SignalHistory
+	if err := e.db.Where("state = ?", models.SignalStateActive).Order("stock_symbol").Find(&active).Error; err != nil {
+		return 0, err
+	}
+
+	cal := services.MarketCalendar()
+	now := time.Now()
+	files := make(map[string]*services.StockPriceFile)
+	transitioned := 0
+
+	for i := range active {
+		h := &active[i]
+
+		file, ok := files[h.StockSymbol]
+		if !ok {
+			file, _ = services.GlobalPriceService.LoadStockPrice(h.StockSymbol)
+			files[h.StockSymbol] = file
+		}
+
+		state, exit, at := e.walkSignalBars(h, file, cal)
+		if state == "" && !h.ExpiresAt.IsZero() && !now.Before(h.ExpiresAt) {
+			state, at = models.SignalStateExpired, now
+			if file != nil && len(file.Prices) > 0 {
+				exit = file.Prices[0].Close
+			}
+		}
+		if state == "" {
+			continue
+		}
+
+		if err := e.transitionSignal(h, state, exit, at); err != nil {
+			log.Printf("Failed to update signal %d for %s: %v", h.ID, h.StockSymbol, err)
+			continue
+		}
+		transitioned++
+	}
+	return transitioned, nil
+}
+
+// walkSignalBars returns the first transition among the daily bars after the emission date
+func (e *ConditionEvaluator) walkSignalBars(h *models.SignalHistory, file *services.StockPriceFile, cal *services.TradingCalendar) (string, float64, time.Time) {
+	if file == nil || len(file.Prices) == 0 {
+		return "", 0, time.Time{}
+	}
+
+	emitted := h.EmittedAt.In(cal.Location()).Format(services.PriceDateFormat)
+	expires := ""
+	if !h.ExpiresAt.IsZero() {
+		expires = h.ExpiresAt.In(cal.Location()).Format(services.PriceDateFormat)
+	}
+
+	// Prices are newest first; walk forward from the first bar after emission
+	start := sort.Search(len(file.Prices), func(i int) bool { return file.Prices[i].Date <= emitted })
+	for i := start - 1; i >= 0; i-- {
+		p := file.Prices[i]
+		if expires != "" && p.Date > expires {
+			break
+		}
+		if state, exit := signalTransition(h, signalBar{high: p.High, low: p.Low}); state != "" {
+			at, _ := time.ParseInLocation(services.PriceDateFormat, p.Date, cal.Location())
+			return state, exit, at
+		}
+	}
+	return "", 0, time.Time{}
+}
+
+// SignalStateCounts returns the number of signals in each lifecycle state
+func (e *ConditionEvaluator) SignalStateCounts() (map[string]int64, error) {
+	var rows []struct {
+		State string
+		Count int64
+	}
+	if err := e.db.Model(&models.SignalHistory{}).Select("state, COUNT(*) AS count").Group("state").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(models.SignalStates))
+	for _, state := range models.SignalStates {
+		counts[state] = 0
+	}
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
+
+// IsSignalState reports whether state is a known lifecycle state
+func IsSignalState(state string) bool {
+	for _, s := range models.SignalStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalHistoryFilter selects persisted signals for list endpoints
+type SignalHistoryFilter struct {
+	State      string
+	StockCode  string
+	RuleID     uint
+	PublicOnly bool // only signals of admin-owned rules, never user-owned ones
+	Page       int
+	PageSize   int
+	Before     *signalHistoryPosition // only signals listed after this position (cursor paging)
+}
+
+// signalHistoryPosition is a position in the newest-first signal history ordering
+type signalHistoryPosition struct {
+	emittedAt time.Time
+	id        uint
+}
+
+// ListSignalHistory returns persisted signals newest first with the total match count
+func (e *ConditionEvaluator) ListSignalHistory(filter SignalHistoryFilter) ([]models.SignalHistory, int64, error) {
+	query := e.db.Model(&models.SignalHistory{})
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.StockCode != "" {
+		query = query.Where("stock_symbol = ?", filter.StockCode)
+	}
+	if filter.RuleID > 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.PublicOnly {
+		query = query.Where("rule_id IN (?)", e.db.Model(&models.SignalRule{}).Select("id").
+			Where("owner_user_id = '' OR owner_user_id IS NULL"))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// The total counts all matches; the cursor only moves the window
+	if filter.Before != nil {
+		query = query.Where("(emitted_at < ? OR (emitted_at = ? AND id < ?))",
+			filter.Before.emittedAt, filter.Before.emittedAt, filter.Before.id)
+	}
+
+	var history []models.SignalHistory
+	err := query.Order("emitted_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&history).Error
+	return history, total, err
+}
+
+// SignalHistorySort names the newest-first ordering of signal history cursors
+const SignalHistorySort = "emitted_at"
+
+// ListSignalHistoryPage returns one page of persisted signals newest first, selected by page
+// number or by a cursor on (emitted_at, id). Filter.Page and PageSize are taken from the request.
+func (e *ConditionEvaluator) ListSignalHistoryPage(filter SignalHistoryFilter, req services.PageRequest) ([]models.SignalHistory, services.PageResult, error) {
+	filter.Page, filter.PageSize = req.Page, req.PageSize
+	if req.Cursor == nil {
+		history, total, err := e.ListSignalHistory(filter)
+		result := services.PageResult{
+			Total:      int(total),
+			Page:       req.Page,
+			PageSize:   req.PageSize,
+			TotalPages: int(math.Ceil(float64(total) / float64(req.PageSize))),
+		}
+		if err == nil && req.Offset()+len(history) < int(total) && len(history) > 0 {
+			last := history[len(history)-1]
+			result.NextCursor = req.NewCursor(last.EmittedAt.Format(time.RFC3339Nano), strconv.FormatUint(uint64(last.ID), 10))
+		}
+		return history, result, err
+	}
+
+	emittedAt, err := time.Parse(time.RFC3339Nano, req.Cursor.Value)
+	if err != nil {
+		return nil, services.PageResult{}, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseUint(req.Cursor.ID, 10, 64)
+	if err != nil {
+		return nil, services.PageResult{}, fmt.Errorf("invalid cursor")
+	}
+
+	// Fetch one extra row to know whether another page follows
+	filter.Page, filter.PageSize = 1, req.PageSize+1
+	filter.Before = &signalHistoryPosition{emittedAt: emittedAt, id: uint(id)}
+	history, total, err := e.ListSignalHistory(filter)
+	if err != nil {
+		return nil, services.PageResult{}, err
+	}
+
+	result := services.PageResult{
+		Total:      int(total),
+		PageSize:   req.PageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(req.PageSize))),
+	}
+	if len(history) > req.PageSize {
+		history = history[:req.PageSize]
+		last := history[len(history)-1]
+		result.NextCursor = req.NewCursor(last.EmittedAt.Format(time.RFC3339Nano), strconv.FormatUint(uint64(last.ID), 10))
+	}
+	return history, result, nil
+}
@@ -0,0 +1,297 @@
+package signals
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	regressionFixtureName  = "fixture.json"
+	regressionSnapshotName = "snapshot.json"
+)
+
+// regressionTolerance absorbs float formatting noise when comparing snapshot values
+const regressionTolerance = 1e-6
+
+// regressionFixture is a fixed price history, oldest first, with the rules to evaluate on it
+type regressionFixture struct {
+	Description string   `json:"description"`
+	AsOf        string   `json:"as_of"`
+	Dates       []string `json:"dates"`
+	Stocks      []struct {
+		Code   string    `json:"code"`
+		Close  []float64 `json:"close"`
+		Volume []float64 `json:"volume"`
+	} `json:"stocks"`
+	Rules []struct {
+		Name            string  `json:"name"`
+		SignalType      string  `json:"signal_type"`
+		MinScore        int     `json:"min_score"`
+		TargetPercent   float64 `json:"target_percent"`
+		StopLossPercent float64 `json:"stop_loss_percent"`
+		Groups          []struct {
+			Required   bool                     `json:"required"`
+			Conditions []models.SignalCondition `json:"conditions"`
+		} `json:"groups"`
+	} `json:"rules"`
+}
+
+// RegressionEntry is the deterministic part of one strategy signal or rule evaluation
+type RegressionEntry struct {
+	Signal     string             `json:"signal"`
+	Strength   int                `json:"strength"`
+	Confidence float64            `json:"confidence"`
+	Values     map[string]float64 `json:"values,omitempty"`
+}
+
+// RegressionSnapshot is the committed output for the fixture, keyed by "<strategy|rule:name>/<code>"
+type RegressionSnapshot struct {
+	GeneratedAt string                     `json:"generated_at"`
+	AsOf        string                     `json:"as_of"`
+	Params      services.IndicatorParams   `json:"params"`
+	RSMethod    string                     `json:"rs_method"`
+	Entries     map[string]RegressionEntry `json:"entries"`
+}
+
+// RegressionDiff is one value that no longer matches the snapshot
+type RegressionDiff struct {
+	Key      string      `json:"key"`
+	Field    string      `json:"field"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// RegressionReport is the outcome of a regression run
+type RegressionReport struct {
+	Entries  int              `json:"entries"`
+	Updated  bool             `json:"updated"`
+	Passed   bool             `json:"passed"`
+	Diffs    []RegressionDiff `json:"diffs"`
+	Missing  []string         `json:"missing"` // In the snapshot but no longer produced
+	Extra    []string         `json:"extra"`   // Produced but not in the snapshot
+	Duration string           `json:"duration"`
+}
+
+// RunSignalRegression evaluates the built-in strategies and the fixture rules on the fixture in
+// dir with default indicator parameters, benchmark RS and default composite weights, so the
+// result does not depend on live configuration. With update the snapshot is rewritten instead of
+// compared, which should only be done when a signal change is intended.
+func RunSignalRegression(dir string, update bool) (*RegressionReport, error) {
+	start := time.Now()
+
+	fixture, err := loadRegressionFixture(filepath.Join(dir, regressionFixtureName))
+	if err != nil {
+		return nil, err
+	}
+	actual := buildRegressionSnapshot(fixture)
+
+	report := &RegressionReport{Entries: len(actual.Entries), Diffs: []RegressionDiff{}, Missing: []string{}, Extra: []string{}}
+	if update {
+		if err := writeRegressionSnapshot(filepath.Join(dir, regressionSnapshotName), actual); err != nil {
+			return nil, err
+		}
+		report.Updated = true
+		report.Passed = true
+		report.Duration = time.Since(start).Round(time.Millisecond).String()
+		return report, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, regressionSnapshotName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot (regenerate it with -update): %w", err)
+	}
+	var expected RegressionSnapshot
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	compareRegressionSnapshots(&expected, actual, report)
+	report.Passed = len(report.Diffs) == 0 && len(report.Missing) == 0 && len(report.Extra) == 0
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	return report, nil
+}
+
+func loadRegressionFixture(path string) (*regressionFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regression fixture: %w", err)
+	}
+	var fixture regressionFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse regression fixture: %w", err)
+	}
+	for _, s := range fixture.Stocks {
+		if len(s.Close) != len(fixture.Dates) || len(s.Volume) != len(fixture.Dates) {
+			return nil, fmt.Errorf("fixture stock %s has %d closes and %d volumes for %d dates",
+				s.Code, len(s.Close), len(s.Volume), len(fixture.Dates))
+		}
+	}
+	return &fixture, nil
+}
+
+// regressionUniverse converts the fixture into price files, newest first like the price store
+func regressionUniverse(fixture *regressionFixture) map[string]*services.StockPriceFile {
+	universe := make(map[string]*services.StockPriceFile, len(fixture.Stocks))
+	n := len(fixture.Dates)
+	for _, s := range fixture.Stocks {
+		prices := make([]services.StockPriceData, n)
+		for i := 0; i < n; i++ {
+			close := s.Close[n-1-i]
+			prices[i] = services.StockPriceData{
+				Code:     s.Code,
+				Date:     fixture.Dates[n-1-i],
+				Open:     close,
+				High:     close,
+				Low:      close,
+				Close:    close,
+				AdClose:  close,
+				NmVolume: s.Volume[n-1-i],
+				NmValue:  s.Volume[n-1-i] * close,
+			}
+		}
+		universe[s.Code] = &services.StockPriceFile{Code: s.Code, DataCount: n, Prices: prices}
+	}
+	return universe
+}
+
+func buildRegressionSnapshot(fixture *regressionFixture) *RegressionSnapshot {
+	params := services.DefaultIndicatorParams()
+	snapshot := &RegressionSnapshot{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		AsOf:        fixture.AsOf,
+		Params:      params,
+		RSMethod:    services.RSMethodBenchmark,
+		Entries:     map[string]RegressionEntry{},
+	}
+
+	indicators := services.CalculateUniverseIndicators(regressionUniverse(fixture), fixture.AsOf, params, services.RSMethodBenchmark)
+
+	weights := DefaultCompositeWeightConfig().Default
+	strategies := []Strategy{
+		&MomentumStrategy{},
+		&TrendFollowingStrategy{},
+		&MeanReversionStrategy{},
+		&BreakoutStrategy{},
+		&CompositeStrategy{Weights: &weights},
+	}
+
+	evaluator := &ConditionEvaluator{}
+	for code, ind := range indicators {
+		snapshot.Entries["indicators/"+code] = RegressionEntry{Values: map[string]float64{
+			"rsi":       ind.RSI,
+			"macd":      ind.MACD,
+			"macd_hist": ind.MACDHist,
+			"ma_50":     ind.MA50,
+			"ma_200":    ind.MA200,
+			"rs_avg":    ind.RSAvg,
+			"rs_1m":     ind.RS1MRank,
+			"vol_ratio": ind.VolRatio,
+		}}
+
+		for _, strategy := range strategies {
+			signal, err := strategy.Evaluate(ind)
+			if err != nil {
+				continue
+			}
+			snapshot.Entries[strategy.Name()+"/"+code] = RegressionEntry{
+				Signal:     string(signal.Signal),
+				Strength:   signal.Strength,
+				Confidence: roundRegression(signal.Confidence),
+			}
+		}
+
+		for i, r := range fixture.Rules {
+			rule := &models.SignalRule{
+				ID:         uint(i + 1),
+				Name:       r.Name,
+				SignalType: r.SignalType,
+				MinScore:   r.MinScore,
+				IsActive:   true,
+			}
+			rule.TargetPercent = decimal.NewFromFloat(r.TargetPercent)
+			rule.StopLossPercent = decimal.NewFromFloat(r.StopLossPercent)
+
+			refs := make([]models.RuleGroupRef, len(r.Groups))
+			groups := make(map[uint]*models.SignalConditionGroup, len(r.Groups))
+			for j, g := range r.Groups {
+				id := uint(j + 1)
+				refs[j] = models.RuleGroupRef{GroupID: id, Logic: "AND", Required: g.Required}
+				groups[id] = &models.SignalConditionGroup{ID: id, IsActive: true, Conditions: append([]models.SignalCondition(nil), g.Conditions...)}
+			}
+
+			entry := RegressionEntry{Signal: "NONE"}
+			if signal := evaluator.EvaluateRuleWithGroups(rule, refs, groups, ind); signal != nil {
+				entry = RegressionEntry{
+					Signal:     signal.SignalType,
+					Strength:   signal.Score,
+					Confidence: roundRegression(signal.Confidence),
+				}
+			}
+			snapshot.Entries["rule:"+r.Name+"/"+code] = entry
+		}
+	}
+	return snapshot
+}
+
+func writeRegressionSnapshot(path string, snapshot *RegressionSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func compareRegressionSnapshots(expected, actual *RegressionSnapshot, report *RegressionReport) {
+	keys := make([]string, 0, len(expected.Entries))
+	for key := range expected.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		want := expected.Entries[key]
+		got, ok := actual.Entries[key]
+		if !ok {
+			report.Missing = append(report.Missing, key)
+			continue
+		}
+		if want.Signal != got.Signal {
+			report.Diffs = append(report.Diffs, RegressionDiff{Key: key, Field: "signal", Expected: want.Signal, Actual: got.Signal})
+		}
+		if want.Strength != got.Strength {
+			report.Diffs = append(report.Diffs, RegressionDiff{Key: key, Field: "strength", Expected: want.Strength, Actual: got.Strength})
+		}
+		if math.Abs(want.Confidence-got.Confidence) > regressionTolerance {
+			report.Diffs = append(report.Diffs, RegressionDiff{Key: key, Field: "confidence", Expected: want.Confidence, Actual: got.Confidence})
+		}
+		for name, v := range want.Values {
+			if g, ok := got.Values[name]; !ok || math.Abs(v-g) > regressionTolerance {
+				report.Diffs = append(report.Diffs, RegressionDiff{Key: key, Field: name, Expected: v, Actual: got.Values[name]})
+			}
+		}
+	}
+
+	for key := range actual.Entries {
+		if _, ok := expected.Entries[key]; !ok {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+	sort.Strings(report.Extra)
+}
+
+func roundRegression(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,34 @@
+package signals
+
+import (
+	"flag"
+	"testing"
+)
+
+// Rewrite the snapshot after an intended signal change with
+//
+//	go test ./services/signals -run TestSignalRegression -update
+var update = flag.Bool("update", false, "rewrite the signal regression snapshot instead of comparing")
+
+const regressionTestDir = "testdata/signal_regression"
+
+func TestSignalRegression(t *testing.T) {
+	report, err := RunSignalRegression(regressionTestDir, *update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated {
+		t.Logf("rewrote %s/%s with %d entries", regressionTestDir, regressionSnapshotName, report.Entries)
+		return
+	}
+
+	for _, d := range report.Diffs {
+		t.Errorf("%s %s: expected %v, got %v", d.Key, d.Field, d.Expected, d.Actual)
+	}
+	for _, key := range report.Missing {
+		t.Errorf("%s is in the snapshot but no longer produced", key)
+	}
+	for _, key := range report.Extra {
+		t.Errorf("%s is produced but not in the snapshot", key)
+	}
+}
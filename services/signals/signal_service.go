@@ -1,6 +1,8 @@
 package signals
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"math"
 	"sort"
@@ -37,9 +39,11 @@ type TradingSignal struct {
 	Signal         SignalType      `json:"signal"`
 	Strength       int             `json:"strength"`        // 0-100
 	Confidence     float64         `json:"confidence"`      // 0-1
-	Price          float64         `json:"price"`
-	TargetPrice    float64         `json:"target_price,omitempty"`
-	StopLoss       float64         `json:"stop_loss,omitempty"`
+	Price          float64         `json:"price" unit:"price"`
+	TargetPrice    float64         `json:"target_price,omitempty" unit:"price"`
+	StopLoss       float64         `json:"stop_loss,omitempty" unit:"price"`
+	TickSize       float64         `json:"tick_size,omitempty" unit:"price"` // at Price
+	OnTick         bool            `json:"on_tick"`                          // target and stop are valid ticks
 	Reasons        []string        `json:"reasons"`
 	Indicators     *SignalIndicators `json:"indicators"`
 	Strategy       string          `json:"strategy"`
@@ -57,12 +61,12 @@ type SignalIndicators struct {
 	MACDSignal     float64 `json:"macd_signal"`
 	MACDHist       float64 `json:"macd_hist"`
 	RSI            float64 `json:"rsi"`
-	MA10           float64 `json:"ma_10"`
-	MA30           float64 `json:"ma_30"`
-	MA50           float64 `json:"ma_50"`
-	MA200          float64 `json:"ma_200"`
+	MA10           float64 `json:"ma_10" unit:"price"`
+	MA30           float64 `json:"ma_30" unit:"price"`
+	MA50           float64 `json:"ma_50" unit:"price"`
+	MA200          float64 `json:"ma_200" unit:"price"`
 	VolRatio       float64 `json:"vol_ratio"`
-	AvgTradingVal  float64 `json:"avg_trading_val"`
+	AvgTradingVal  float64 `json:"avg_trading_val" unit:"value"`
 }
 
 // SignalFilter defines criteria for filtering signals
@@ -73,6 +77,8 @@ type SignalFilter struct {
 	MinTradingVal  float64    `json:"min_trading_val"`
 	Strategies     []string   `json:"strategies"`
 	Limit          int        `json:"limit"`
+	// InstrumentTypes limits signals to stocks, ETFs, covered warrants or funds; empty means all
+	InstrumentTypes []string `json:"instrument_types"`
 }
 
 // Strategy defines a trading strategy
@@ -94,6 +100,9 @@ type SignalService struct {
 // Global signal service instance
 var GlobalSignalService *SignalService
 
+// errSignalsDisabled wraps services.ErrSubsystemDisabled for the signal generation kill switch
+var errSignalsDisabled = fmt.Errorf("signal generation %w", services.ErrSubsystemDisabled)
+
 // InitSignalService initializes the signal service
 func InitSignalService() error {
 	GlobalSignalService = &SignalService{
@@ -118,6 +127,7 @@ func (s *SignalService) RegisterStrategy(strategy Strategy) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.strategies[strategy.Name()] = strategy
+	dropSnapshotStrategy(strategy.Name())
 }
 
 // GetStrategies returns all registered strategies
@@ -144,6 +154,16 @@ func (s *SignalService) GenerateSignal(code string, strategyName string) (*Tradi
 		strategy = &CompositeStrategy{}
 	}
 
+	if snapshot := currentSnapshot(); snapshot != nil {
+		if signal, ok := snapshotSignal(snapshot, strategy.Name(), code); ok {
+			return signal, nil
+		}
+	}
+
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return nil, errSignalsDisabled
+	}
+
 	// Get indicators for the stock
 	indicators, err := services.GlobalIndicatorService.GetStockIndicators(code)
 	if err != nil {
@@ -155,6 +175,31 @@ func (s *SignalService) GenerateSignal(code string, strategyName string) (*Tradi
 
 // GenerateAllSignals generates signals for all stocks
 func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFilter) ([]*TradingSignal, error) {
+	return s.GenerateAllSignalsContext(context.Background(), strategyName, filter)
+}
+
+// GenerateAllSignalsContext is GenerateAllSignals stopped early when ctx is done, e.g. because the
+// client that asked for the signals disconnected. It then returns ctx's error.
+//
+// Signals come from the published snapshot while it matches the indicator summary, unless ctx
+// was marked with WithLiveSignals.
+func (s *SignalService) GenerateAllSignalsContext(ctx context.Context, strategyName string, filter *SignalFilter) ([]*TradingSignal, error) {
+	if !IsLiveSignals(ctx) {
+		if snapshot := currentSnapshot(); snapshot != nil {
+			if list, ok := snapshotSignals(snapshot, strategyName, filter); ok {
+				return list, nil
+			}
+		}
+	}
+	return s.generateAllSignals(ctx, strategyName, filter)
+}
+
+// generateAllSignals evaluates a strategy for every stock of the indicator summary
+func (s *SignalService) generateAllSignals(ctx context.Context, strategyName string, filter *SignalFilter) ([]*TradingSignal, error) {
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return nil, errSignalsDisabled
+	}
+
 	s.mu.RLock()
 	strategy, ok := s.strategies[strategyName]
 	s.mu.RUnlock()
@@ -164,8 +209,11 @@ func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFi
 	}
 
 	// Load indicator summary
-	summary, err := services.GlobalIndicatorService.LoadIndicatorSummary()
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummaryContext(ctx)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
@@ -180,16 +228,22 @@ func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFi
 		if ind == nil {
 			continue
 		}
+		if ctx.Err() != nil {
+			break
+		}
 
-		// Apply trading value filter
-		if filter != nil && filter.MinTradingVal > 0 && ind.AvgTradingVal < filter.MinTradingVal {
+		if !matchesStockFilter(code, ind.Type, ind.AvgTradingVal, filter) {
 			continue
 		}
 
 		wg.Add(1)
 		go func(stockCode string, indicators *services.ExtendedStockIndicators) {
 			defer wg.Done()
-			semaphore <- struct{}{}
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
 			defer func() { <-semaphore }()
 
 			signal, err := strategy.Evaluate(indicators)
@@ -199,26 +253,8 @@ func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFi
 
 			signal.Code = stockCode
 
-			// Apply filters
-			if filter != nil {
-				if filter.MinStrength > 0 && signal.Strength < filter.MinStrength {
-					return
-				}
-				if filter.MinConfidence > 0 && signal.Confidence < filter.MinConfidence {
-					return
-				}
-				if len(filter.SignalTypes) > 0 {
-					found := false
-					for _, st := range filter.SignalTypes {
-						if signal.Signal == st {
-							found = true
-							break
-						}
-					}
-					if !found {
-						return
-					}
-				}
+			if !matchesSignalFilter(signal, filter) {
+				return
 			}
 
 			mu.Lock()
@@ -228,6 +264,9 @@ func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFi
 	}
 
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Sort by strength descending
 	sort.Slice(signals, func(i, j int) bool {
@@ -242,6 +281,43 @@ func (s *SignalService) GenerateAllSignals(strategyName string, filter *SignalFi
 	return signals, nil
 }
 
+// matchesStockFilter applies the trading value and instrument type filters to a stock
+func matchesStockFilter(code, instrumentType string, avgTradingVal float64, filter *SignalFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.MinTradingVal > 0 && avgTradingVal < filter.MinTradingVal {
+		return false
+	}
+	return services.MatchesInstrumentType(code, instrumentType, filter.InstrumentTypes)
+}
+
+// matchesSignalFilter applies the strength, confidence and signal type filters to a signal
+func matchesSignalFilter(signal *TradingSignal, filter *SignalFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.MinStrength > 0 && signal.Strength < filter.MinStrength {
+		return false
+	}
+	if filter.MinConfidence > 0 && signal.Confidence < filter.MinConfidence {
+		return false
+	}
+	if len(filter.SignalTypes) > 0 {
+		found := false
+		for _, st := range filter.SignalTypes {
+			if signal.Signal == st {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // GetBuySignals returns all BUY and STRONG_BUY signals
 func (s *SignalService) GetBuySignals(minStrength int, limit int) ([]*TradingSignal, error) {
 	filter := &SignalFilter{
@@ -360,12 +436,12 @@ func (s *MomentumStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tra
 	// Determine signal type
 	if strength >= 80 {
 		signal.Signal = SignalStrongBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.15 // 15% target
-		signal.StopLoss = ind.CurrentPrice * 0.95    // 5% stop loss
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 15) // 15% target
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)    // 5% stop loss
 	} else if strength >= 60 {
 		signal.Signal = SignalBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.10
-		signal.StopLoss = ind.CurrentPrice * 0.95
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 10)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 	} else if strength <= 20 {
 		signal.Signal = SignalStrongSell
 	} else if strength <= 40 {
@@ -374,7 +450,7 @@ func (s *MomentumStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tra
 		signal.Signal = SignalHold
 	}
 
-	return signal, nil
+	return withTicks(signal, ind.Code), nil
 }
 
 // =============================================================================
@@ -458,12 +534,12 @@ func (s *TrendFollowingStrategy) Evaluate(ind *services.ExtendedStockIndicators)
 	// Determine signal
 	if strength >= 80 {
 		signal.Signal = SignalStrongBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.12
-		signal.StopLoss = ind.MA50 * 0.98 // Below MA50
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 12)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.MA50, -2) // Below MA50
 	} else if strength >= 60 {
 		signal.Signal = SignalBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.08
-		signal.StopLoss = ind.MA50 * 0.98
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 8)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.MA50, -2)
 	} else if strength <= 20 {
 		signal.Signal = SignalStrongSell
 	} else if strength <= 40 {
@@ -472,7 +548,7 @@ func (s *TrendFollowingStrategy) Evaluate(ind *services.ExtendedStockIndicators)
 		signal.Signal = SignalHold
 	}
 
-	return signal, nil
+	return withTicks(signal, ind.Code), nil
 }
 
 // =============================================================================
@@ -551,12 +627,12 @@ func (s *MeanReversionStrategy) Evaluate(ind *services.ExtendedStockIndicators)
 	// Determine signal (inverted logic for mean reversion)
 	if ind.RSI < 30 && ma50Deviation < -5 {
 		signal.Signal = SignalStrongBuy
-		signal.TargetPrice = ind.MA50 // Target return to MA50
-		signal.StopLoss = ind.CurrentPrice * 0.93
+		signal.TargetPrice = services.RoundPrice(ind.Code, ind.MA50) // Target return to MA50
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -7)
 	} else if ind.RSI < 40 {
 		signal.Signal = SignalBuy
-		signal.TargetPrice = ind.MA50 * 0.98
-		signal.StopLoss = ind.CurrentPrice * 0.95
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.MA50, -2)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 	} else if ind.RSI > 70 && ma50Deviation > 5 {
 		signal.Signal = SignalStrongSell
 	} else if ind.RSI > 60 {
@@ -565,7 +641,7 @@ func (s *MeanReversionStrategy) Evaluate(ind *services.ExtendedStockIndicators)
 		signal.Signal = SignalHold
 	}
 
-	return signal, nil
+	return withTicks(signal, ind.Code), nil
 }
 
 // =============================================================================
@@ -648,19 +724,19 @@ func (s *BreakoutStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tra
 	// Determine signal - breakout strategy is aggressive
 	if strength >= 75 && ind.VolRatio >= 1.5 {
 		signal.Signal = SignalStrongBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.20 // 20% target for breakouts
-		signal.StopLoss = ind.CurrentPrice * 0.92    // 8% stop loss
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 20) // 20% target for breakouts
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -8)    // 8% stop loss
 	} else if strength >= 60 {
 		signal.Signal = SignalBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.12
-		signal.StopLoss = ind.CurrentPrice * 0.95
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 12)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 	} else if strength <= 30 {
 		signal.Signal = SignalSell
 	} else {
 		signal.Signal = SignalHold
 	}
 
-	return signal, nil
+	return withTicks(signal, ind.Code), nil
 }
 
 // =============================================================================
@@ -668,7 +744,10 @@ func (s *BreakoutStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tra
 // Combines all strategies with weighted scoring
 // =============================================================================
 
-type CompositeStrategy struct{}
+type CompositeStrategy struct {
+	// Weights, when set, replace the market regime weights (used for reproducible regression runs)
+	Weights *StrategyWeights
+}
 
 func (s *CompositeStrategy) Name() string { return "composite" }
 func (s *CompositeStrategy) Description() string {
@@ -687,19 +766,26 @@ func (s *CompositeStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tr
 	meanRevSignal, _ := meanRev.Evaluate(ind)
 	breakoutSignal, _ := breakout.Evaluate(ind)
 
-	// Weighted average of strengths
-	// Weights: Momentum 30%, Trend 35%, Mean Reversion 15%, Breakout 20%
+	// Weighted average of strengths, weighted by the current market regime
+	var regime string
+	w := StrategyWeights{}
+	if s.Weights != nil {
+		w = *s.Weights
+	} else {
+		regime = services.GlobalMarketRegime.CurrentName()
+		w = CompositeWeights(regime)
+	}
 	compositeStrength := int(
-		float64(momSignal.Strength)*0.30 +
-		float64(trendSignal.Strength)*0.35 +
-		float64(meanRevSignal.Strength)*0.15 +
-		float64(breakoutSignal.Strength)*0.20,
+		float64(momSignal.Strength)*w.Momentum +
+		float64(trendSignal.Strength)*w.Trend +
+		float64(meanRevSignal.Strength)*w.MeanReversion +
+		float64(breakoutSignal.Strength)*w.Breakout,
 	)
 
-	compositeConfidence := momSignal.Confidence*0.30 +
-		trendSignal.Confidence*0.35 +
-		meanRevSignal.Confidence*0.15 +
-		breakoutSignal.Confidence*0.20
+	compositeConfidence := momSignal.Confidence*w.Momentum +
+		trendSignal.Confidence*w.Trend +
+		meanRevSignal.Confidence*w.MeanReversion +
+		breakoutSignal.Confidence*w.Breakout
 
 	signal := &TradingSignal{
 		Price:       ind.CurrentPrice,
@@ -727,6 +813,10 @@ func (s *CompositeStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tr
 		},
 	}
 
+	if regime != "" {
+		signal.Reasons = append(signal.Reasons, "[Regime] "+regime+" market weighting")
+	}
+
 	// Aggregate reasons from strongest signals
 	if momSignal.Strength >= 60 && len(momSignal.Reasons) > 0 {
 		signal.Reasons = append(signal.Reasons, "[Momentum] "+momSignal.Reasons[0])
@@ -759,12 +849,12 @@ func (s *CompositeStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tr
 	// Determine final signal based on votes and strength
 	if compositeStrength >= 75 && buyVotes >= 4 {
 		signal.Signal = SignalStrongBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.15
-		signal.StopLoss = ind.CurrentPrice * 0.95
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 15)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 	} else if compositeStrength >= 60 && buyVotes >= 2 {
 		signal.Signal = SignalBuy
-		signal.TargetPrice = ind.CurrentPrice * 1.10
-		signal.StopLoss = ind.CurrentPrice * 0.95
+		signal.TargetPrice = services.OffsetPrice(ind.Code, ind.CurrentPrice, 10)
+		signal.StopLoss = services.OffsetPrice(ind.Code, ind.CurrentPrice, -5)
 	} else if compositeStrength <= 25 && sellVotes >= 4 {
 		signal.Signal = SignalStrongSell
 	} else if compositeStrength <= 40 && sellVotes >= 2 {
@@ -773,5 +863,13 @@ func (s *CompositeStrategy) Evaluate(ind *services.ExtendedStockIndicators) (*Tr
 		signal.Signal = SignalHold
 	}
 
-	return signal, nil
+	return withTicks(signal, ind.Code), nil
+}
+
+// withTicks records the tick size of the stock at the signal price and whether the target and
+// the stop-loss are valid ticks, so clients can tell executable levels apart
+func withTicks(signal *TradingSignal, code string) *TradingSignal {
+	signal.TickSize = services.TickSizeOf(code, signal.Price)
+	signal.OnTick = services.OnTick(code, signal.TargetPrice) && services.OnTick(code, signal.StopLoss)
+	return signal
 }
@@ -0,0 +1,40 @@
+package signals
+
+import (
+	"sync"
+	"testing"
+
+	"go_backend_project/services"
+)
+
+// benchIndicators are the indicators of the synthetic universe, calculated once for every
+// benchmark in the package with the same fixed configuration as the regression suite
+var benchIndicators = sync.OnceValue(func() map[string]*services.ExtendedStockIndicators {
+	universe, asOf := services.SyntheticUniverse(services.BenchmarkUniverseStocks, services.BenchmarkUniverseDays, 1)
+	return services.CalculateUniverseIndicators(universe, asOf, services.DefaultIndicatorParams(), services.RSMethodBenchmark)
+})
+
+// BenchmarkStrategies evaluates each built-in strategy on the whole universe per op
+func BenchmarkStrategies(b *testing.B) {
+	indicators := benchIndicators()
+	weights := DefaultCompositeWeightConfig().Default
+	strategies := []Strategy{
+		&MomentumStrategy{},
+		&TrendFollowingStrategy{},
+		&MeanReversionStrategy{},
+		&BreakoutStrategy{},
+		&CompositeStrategy{Weights: &weights},
+	}
+
+	for _, strategy := range strategies {
+		b.Run(strategy.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, ind := range indicators {
+					strategy.Evaluate(ind)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/1e3/float64(b.N)/float64(len(indicators)), "µs/stock")
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_backend_project/services"
+)
+
+// SnapshotFile holds the latest published signal snapshot
+const SnapshotFile = "data/signal_snapshot.json"
+
+// snapshotReloadInterval is how often a snapshot that doesn't match the indicator summary in use
+// is looked up again on disk and in MongoDB, e.g. after another instance ran the pipeline
+const snapshotReloadInterval = time.Minute
+
+// Snapshot sources
+const (
+	SnapshotSourceBuild   = "build"
+	SnapshotSourceFile    = "file"
+	SnapshotSourceMongoDB = "mongodb"
+)
+
+// SnapshotStock is what a snapshot keeps of a stock to apply the request filters
+type SnapshotStock struct {
+	Type          string  `json:"type,omitempty"`
+	AvgTradingVal float64 `json:"avg_trading_val"`
+}
+
+// SignalSnapshot is every registered strategy evaluated for every stock of one indicator summary.
+// The public signal endpoints serve from it instead of evaluating the strategies per request.
+type SignalSnapshot struct {
+	GeneratedAt time.Time                   `json:"generated_at"`
+	DataAsOf    string                      `json:"data_as_of"` // the indicator summary the signals come from
+	Stocks      map[string]SnapshotStock    `json:"stocks"`
+	Strategies  map[string][]*TradingSignal `json:"strategies"` // strongest first
+}
+
+// SnapshotStatus describes the snapshot in use
+type SnapshotStatus struct {
+	Published   bool           `json:"published"`
+	Current     bool           `json:"current"` // built from the indicator summary in use
+	Source      string         `json:"source,omitempty"`
+	GeneratedAt string         `json:"generated_at,omitempty"`
+	DataAsOf    string         `json:"data_as_of,omitempty"`
+	Strategies  map[string]int `json:"strategies,omitempty"` // signals per strategy
+}
+
+// signalSnapshotState is the snapshot in memory, indexed by strategy and code
+var signalSnapshotState struct {
+	mu        sync.RWMutex
+	snapshot  *SignalSnapshot
+	byCode    map[string]map[string]*TradingSignal
+	source    string
+	checkedAt time.Time
+}
+
+type liveSignalsKey struct{}
+
+// WithLiveSignals marks ctx so signals are evaluated on demand instead of served from the snapshot
+func WithLiveSignals(ctx context.Context) context.Context {
+	return context.WithValue(ctx, liveSignalsKey{}, true)
+}
+
+// IsLiveSignals reports whether ctx asks for signals evaluated on demand
+func IsLiveSignals(ctx context.Context) bool {
+	live, _ := ctx.Value(liveSignalsKey{}).(bool)
+	return live
+}
+
+// BuildSnapshot evaluates every registered strategy for every stock of the indicator summary
+func (s *SignalService) BuildSnapshot(ctx context.Context, progress func(done, total int, strategy string)) (*SignalSnapshot, error) {
+	if !services.SubsystemEnabled(services.SubsystemSignals) {
+		return nil, errSignalsDisabled
+	}
+	dataAsOf := services.IndicatorFreshness(time.Now()).DataAsOf
+	summary, err := services.GlobalIndicatorService.LoadIndicatorSummaryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dataAsOf == "" {
+		return nil, fmt.Errorf("indicator summary has no calculation time")
+	}
+
+	snapshot := &SignalSnapshot{
+		GeneratedAt: time.Now(),
+		DataAsOf:    dataAsOf,
+		Stocks:      make(map[string]SnapshotStock, len(summary.Stocks)),
+		Strategies:  make(map[string][]*TradingSignal),
+	}
+	for code, ind := range summary.Stocks {
+		if ind != nil {
+			snapshot.Stocks[code] = SnapshotStock{Type: ind.Type, AvgTradingVal: ind.AvgTradingVal}
+		}
+	}
+
+	names := s.GetStrategies()
+	for i, name := range names {
+		if progress != nil {
+			progress(i, len(names), name)
+		}
+		list, err := s.generateAllSignals(ctx, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", name, err)
+		}
+		if list == nil {
+			list = []*TradingSignal{}
+		}
+		snapshot.Strategies[name] = list
+	}
+	if progress != nil {
+		progress(len(names), len(names), "")
+	}
+	return snapshot, nil
+}
+
+// PublishSnapshot makes snapshot the one served, saving it to SnapshotFile and, when configured,
+// to MongoDB for the other instances
+func PublishSnapshot(snapshot *SignalSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode signal snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(SnapshotFile), 0755); err != nil {
+		return err
+	}
+	tmp := SnapshotFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write signal snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, SnapshotFile); err != nil {
+		return fmt.Errorf("failed to write signal snapshot: %w", err)
+	}
+	installSnapshot(snapshot, SnapshotSourceBuild)
+
+	if services.GlobalMongoClient != nil && services.GlobalMongoClient.IsConfigured() {
+		if err := services.GlobalMongoClient.SaveSignalSnapshot(data); err != nil {
+			log.Printf("Warning: signal snapshot not saved to MongoDB: %v", err)
+		}
+	}
+	log.Printf("Published signal snapshot of %d strategies (data as of %s)", len(snapshot.Strategies), snapshot.DataAsOf)
+	return nil
+}
+
+// GetSnapshotStatus describes the snapshot in memory
+func GetSnapshotStatus() SnapshotStatus {
+	current := currentSnapshot() != nil
+
+	state := &signalSnapshotState
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	status := SnapshotStatus{Published: state.snapshot != nil, Current: current, Source: state.source}
+	if state.snapshot != nil {
+		status.GeneratedAt = state.snapshot.GeneratedAt.Format(time.RFC3339)
+		status.DataAsOf = state.snapshot.DataAsOf
+		status.Strategies = make(map[string]int, len(state.snapshot.Strategies))
+		for name, list := range state.snapshot.Strategies {
+			status.Strategies[name] = len(list)
+		}
+	}
+	return status
+}
+
+// currentSnapshot returns the snapshot built from the indicator summary in use, or nil when there
+// is none and signals have to be evaluated on demand
+func currentSnapshot() *SignalSnapshot {
+	dataAsOf := services.IndicatorFreshness(time.Now()).DataAsOf
+	if dataAsOf == "" {
+		return nil
+	}
+
+	state := &signalSnapshotState
+	state.mu.RLock()
+	snapshot, checkedAt := state.snapshot, state.checkedAt
+	state.mu.RUnlock()
+	if snapshot != nil && snapshot.DataAsOf == dataAsOf {
+		return snapshot
+	}
+	if time.Since(checkedAt) < snapshotReloadInterval {
+		return nil
+	}
+
+	state.mu.Lock()
+	if time.Since(state.checkedAt) < snapshotReloadInterval {
+		state.mu.Unlock()
+		return nil // another request is already looking
+	}
+	state.checkedAt = time.Now()
+	state.mu.Unlock()
+	return reloadSnapshot(dataAsOf)
+}
+
+// reloadSnapshot installs the snapshot of SnapshotFile, or else of MongoDB, if it matches dataAsOf
+func reloadSnapshot(dataAsOf string) *SignalSnapshot {
+	if data, err := os.ReadFile(SnapshotFile); err == nil {
+		if snapshot := decodeSnapshot(data); snapshot != nil && snapshot.DataAsOf == dataAsOf {
+			installSnapshot(snapshot, SnapshotSourceFile)
+			return snapshot
+		}
+	}
+	if services.GlobalMongoClient != nil && services.GlobalMongoClient.IsConfigured() {
+		data, err := services.GlobalMongoClient.LoadSignalSnapshot()
+		if err != nil {
+			return nil
+		}
+		if snapshot := decodeSnapshot(data); snapshot != nil && snapshot.DataAsOf == dataAsOf {
+			installSnapshot(snapshot, SnapshotSourceMongoDB)
+			return snapshot
+		}
+	}
+	return nil
+}
+
+func decodeSnapshot(data []byte) *SignalSnapshot {
+	var snapshot SignalSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Printf("Warning: ignoring unreadable signal snapshot: %v", err)
+		return nil
+	}
+	if snapshot.Strategies == nil {
+		return nil
+	}
+	return &snapshot
+}
+
+func installSnapshot(snapshot *SignalSnapshot, source string) {
+	byCode := make(map[string]map[string]*TradingSignal, len(snapshot.Strategies))
+	for name, list := range snapshot.Strategies {
+		codes := make(map[string]*TradingSignal, len(list))
+		for _, signal := range list {
+			codes[signal.Code] = signal
+		}
+		byCode[name] = codes
+	}
+
+	state := &signalSnapshotState
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.snapshot, state.byCode, state.source = snapshot, byCode, source
+}
+
+// dropSnapshotStrategy stops serving a strategy from the snapshot after its definition changed;
+// it is evaluated on demand until the next snapshot
+func dropSnapshotStrategy(name string) {
+	state := &signalSnapshotState
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.snapshot == nil {
+		return
+	}
+	if _, ok := state.snapshot.Strategies[name]; !ok {
+		return
+	}
+
+	// Copy so readers holding the previous snapshot are unaffected
+	strategies := make(map[string][]*TradingSignal, len(state.snapshot.Strategies))
+	for n, list := range state.snapshot.Strategies {
+		if n != name {
+			strategies[n] = list
+		}
+	}
+	next := *state.snapshot
+	next.Strategies = strategies
+	state.snapshot = &next
+	delete(state.byCode, name)
+}
+
+// snapshotSignals returns copies of the snapshot signals of a strategy passing filter
+func snapshotSignals(snapshot *SignalSnapshot, strategyName string, filter *SignalFilter) ([]*TradingSignal, bool) {
+	list, ok := snapshot.Strategies[strategyName]
+	if !ok {
+		return nil, false
+	}
+
+	var result []*TradingSignal
+	for _, signal := range list {
+		stock := snapshot.Stocks[signal.Code]
+		if !matchesStockFilter(signal.Code, stock.Type, stock.AvgTradingVal, filter) || !matchesSignalFilter(signal, filter) {
+			continue
+		}
+		copied := *signal
+		result = append(result, &copied)
+		if filter != nil && filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, true
+}
+
+// snapshotSignal returns a copy of the snapshot signal of a strategy for one stock
+func snapshotSignal(snapshot *SignalSnapshot, strategyName, code string) (*TradingSignal, bool) {
+	state := &signalSnapshotState
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	if state.snapshot == nil || state.snapshot.DataAsOf != snapshot.DataAsOf {
+		return nil, false
+	}
+	signal, ok := state.byCode[strategyName][code]
+	if !ok {
+		return nil, false
+	}
+	copied := *signal
+	return &copied, true
+}
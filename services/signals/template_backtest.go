@@ -0,0 +1,145 @@
+package signals
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+)
+
+// TemplateBacktestMaxDays caps the period of a template backtest: each day of it recomputes the
+// stock's indicators, and the endpoint is open to every user
+const TemplateBacktestMaxDays = 2 * 366
+
+// TemplateTradeMarker is one point of a trade to draw on a price chart
+type TemplateTradeMarker struct {
+	Date   string  `json:"date"`
+	Kind   string  `json:"kind"` // entry, exit
+	Side   string  `json:"side"` // BUY, SELL
+	Price  float64 `json:"price" unit:"price"`
+	Reason string  `json:"reason,omitempty"` // exits: target_hit, stop_loss, timeout
+}
+
+// TemplateBacktestResult is how a template's signals would have traded one stock over a period
+type TemplateBacktestResult struct {
+	TemplateID     uint                  `json:"template_id"`
+	Code           string                `json:"code"`
+	From           string                `json:"from"`
+	To             string                `json:"to"`
+	MaxHoldingDays int                   `json:"max_holding_days"`
+	TotalTrades    int                   `json:"total_trades"`
+	Wins           int                   `json:"wins"`
+	WinRate        float64               `json:"win_rate"`
+	AvgReturn      float64               `json:"avg_return"`
+	TotalReturn    float64               `json:"total_return"`
+	MaxLoss        float64               `json:"max_loss"`
+	AvgHoldDays    float64               `json:"avg_hold_days"`
+	BuyHoldReturn  float64               `json:"buy_hold_return"` // close of From to close of To
+	Trades         []RuleBacktestTrade   `json:"trades"`
+	Markers        []TemplateTradeMarker `json:"markers"`
+}
+
+// BacktestTemplate replays a template on one stock over [from, to] (YYYY-MM-DD): every trading
+// day its indicators are recomputed from the prices up to that close and the template evaluated;
+// a signal is traded like the nightly rule backtests until its target, its stop or
+// maxHoldingDays, and no new trade opens while one is running. ALERT signals have no direction
+// and are not traded. RS ranks need the whole universe, so conditions on them never pass here.
+// The replay stops when ctx ends.
+func (e *ConditionEvaluator) BacktestTemplate(ctx context.Context, template *models.SignalTemplate, code, from, to string, maxHoldingDays int) (*TemplateBacktestResult, error) {
+	if services.GlobalPriceService == nil {
+		return nil, fmt.Errorf("price service not initialized")
+	}
+	start, err := time.Parse(services.PriceDateFormat, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	end, err := time.Parse(services.PriceDateFormat, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if end.Sub(start) > TemplateBacktestMaxDays*24*time.Hour {
+		return nil, fmt.Errorf("period is limited to 2 years")
+	}
+	if maxHoldingDays < 1 {
+		maxHoldingDays = DefaultRuleBacktestConfig().MaxHoldingDays
+	}
+
+	priceFile, err := services.GlobalPriceService.LoadStockPrice(code)
+	if err != nil || len(priceFile.Prices) == 0 {
+		return nil, fmt.Errorf("no price data for %s", code)
+	}
+	index, _ := services.GlobalPriceService.LoadStockPrice(services.MarketIndexCode)
+
+	dates := services.TradingDates(map[string]*services.StockPriceFile{code: priceFile}, from, to)
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no price data for %s between %s and %s", code, from, to)
+	}
+
+	result := &TemplateBacktestResult{
+		TemplateID:     template.ID,
+		Code:           code,
+		From:           dates[0],
+		To:             dates[len(dates)-1],
+		MaxHoldingDays: maxHoldingDays,
+		Trades:         []RuleBacktestTrade{},
+		Markers:        []TemplateTradeMarker{},
+	}
+
+	openUntil := ""
+	for _, date := range dates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if date <= openUntil {
+			continue
+		}
+		ind := services.StockIndicatorsAsOf(priceFile, index, date)
+		if ind == nil {
+			continue
+		}
+		signal, err := e.EvaluateTemplate(template, ind)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template conditions: %w", err)
+		}
+		if signal == nil || signal.SignalType == "ALERT" {
+			continue
+		}
+
+		trade, ok := SimulateTrade(priceFile, date, signal.SignalType, signal.Price, signal.TargetPrice, signal.StopLoss, maxHoldingDays)
+		if !ok {
+			continue
+		}
+		side := "BUY"
+		if signal.SignalType == "SELL" || signal.SignalType == "STRONG_SELL" {
+			side = "SELL"
+		}
+		result.Trades = append(result.Trades, trade)
+		result.Markers = append(result.Markers,
+			TemplateTradeMarker{Date: trade.SignalDate, Kind: "entry", Side: side, Price: trade.EntryPrice},
+			TemplateTradeMarker{Date: trade.ExitDate, Kind: "exit", Side: side, Price: trade.ExitPrice, Reason: trade.ExitReason},
+		)
+		openUntil = trade.ExitDate
+	}
+
+	summary := &RuleBacktestResult{Trades: result.Trades}
+	summarizeRuleBacktest(summary)
+	result.TotalTrades = summary.TotalTrades
+	result.Wins = summary.Wins
+	result.WinRate = summary.WinRate
+	result.AvgReturn = summary.AvgReturn
+	result.TotalReturn = summary.TotalReturn
+	result.MaxLoss = summary.MaxLoss
+	result.AvgHoldDays = summary.AvgHoldDays
+
+	first := services.PricesAsOf(priceFile, result.From).Prices[0].Close
+	last := services.PricesAsOf(priceFile, result.To).Prices[0].Close
+	if first > 0 {
+		result.BuyHoldReturn = (last - first) / first * 100
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package signals
+
+import (
+	"os"
+	"testing"
+
+	"go_backend_project/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens the Postgres database in TEST_DATABASE_URL and returns a migrated transaction that
+// is rolled back when the test ends, so tests leave no rows behind. Tests that need it are skipped
+// when the variable is unset; make test-db runs them against the e2e stack's Postgres.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("begin: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	for _, migrate := range []func(*gorm.DB) error{
+		models.MigrateSignalConditionModels,
+		models.MigrateStrategistModels,
+		models.MigrateNotificationModels,
+	} {
+		if err := migrate(tx); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+	}
+	return tx
+}
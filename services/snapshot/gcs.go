@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Google Cloud Storage JSON API endpoints and the metadata server token endpoint used on Cloud Run
+const (
+	gcsUploadURL   = "https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s"
+	gcsDownloadURL = "https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media"
+	gcsTokenURL    = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcsClient has no overall timeout: snapshots with the price history take minutes to transfer
+var gcsClient = &http.Client{}
+
+// DefaultBucket returns the bucket configured with SNAPSHOT_GCS_BUCKET
+func DefaultBucket() string {
+	return os.Getenv("SNAPSHOT_GCS_BUCKET")
+}
+
+// DefaultObjectName names a snapshot taken at t
+func DefaultObjectName(t time.Time) string {
+	return fmt.Sprintf("snapshots/cpls-%s.tar.gz", t.Format("20060102-150405"))
+}
+
+// Push streams a snapshot to gs://bucket/object without buffering it locally
+func Push(ctx context.Context, db *gorm.DB, bucket, object, createdBy string) (*Manifest, error) {
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("bucket and object are required")
+	}
+	token, err := accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	var manifest *Manifest
+	writeErr := make(chan error, 1)
+	go func() {
+		var err error
+		manifest, err = Write(ctx, db, pw, createdBy)
+		pw.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf(gcsUploadURL, url.PathEscape(bucket), url.QueryEscape(object)), pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		<-writeErr
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := gcsClient.Do(req)
+	if err != nil {
+		pr.CloseWithError(err)
+		if werr := <-writeErr; werr != nil {
+			return nil, werr
+		}
+		return nil, fmt.Errorf("upload to gs://%s/%s failed: %w", bucket, object, err)
+	}
+	defer resp.Body.Close()
+	pr.Close()
+	if err := <-writeErr; err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upload to gs://%s/%s failed: %s", bucket, object, gcsError(resp))
+	}
+	return manifest, nil
+}
+
+// Pull opens gs://bucket/object for reading; the caller closes it
+func Pull(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("bucket and object are required")
+	}
+	token, err := accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf(gcsDownloadURL, url.PathEscape(bucket), url.PathEscape(object)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := gcsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download of gs://%s/%s failed: %w", bucket, object, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("download of gs://%s/%s failed: %s", bucket, object, gcsError(resp))
+	}
+	return resp.Body, nil
+}
+
+// accessToken returns GCS_ACCESS_TOKEN when set (local runs), otherwise a token of the
+// instance's service account from the metadata server
+func accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GCS_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcsTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := gcsClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no GCS credentials: set GCS_ACCESS_TOKEN or run on Google Cloud (%v)", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server token request failed: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid metadata server token response")
+	}
+	return token.AccessToken, nil
+}
+
+// gcsError summarizes an error response of the storage API
+func gcsError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return strings.TrimSpace(resp.Status + " " + string(body))
+}
@@ -0,0 +1,453 @@
+// Package snapshot packages the application state (the data directory with stock lists, price
+// history files, indicators and file-based settings, plus the stock, price, rule and config
+// tables) into a single archive, and restores such an archive, to clone production into staging.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services"
+	"go_backend_project/services/signals"
+
+	"gorm.io/gorm"
+)
+
+// FormatVersion is the archive layout version written to the manifest
+const FormatVersion = 1
+
+// ChunkRows is the number of table rows per archive entry
+const ChunkRows = 5000
+
+// Archive layout: db/<table>/<chunk>.json entries hold JSON arrays of rows, tables in insert
+// order, files/<path> entries the data directory, and the manifest comes last
+const (
+	DataDir      = "data"
+	ManifestName = "manifest.json"
+	tablesPrefix = "db/"
+	filesPrefix  = "files/"
+)
+
+// snapshotModels are the tables carried by a snapshot, in insert order (parents first). User,
+// subscription, admin and signal history tables stay with their environment.
+var snapshotModels = []interface{}{
+	&models.Stock{},
+	&models.StockPrice{},
+	&models.TechnicalIndicator{},
+	&models.MarketIndex{},
+	&models.SystemConfig{},
+	&models.SignalConditionGroup{},
+	&models.SignalCondition{},
+	&models.SignalRule{},
+	&models.SignalTemplate{},
+	&models.CustomStrategy{},
+}
+
+// Manifest describes a snapshot archive
+type Manifest struct {
+	Version     int            `json:"version"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CreatedBy   string         `json:"created_by"`
+	Environment string         `json:"environment"`
+	Tables      map[string]int `json:"tables"` // rows per table
+	Files       int            `json:"files"`
+	FileBytes   int64          `json:"file_bytes"`
+}
+
+// RestoreResult reports what a restore replaced
+type RestoreResult struct {
+	Source   Manifest       `json:"source"`
+	Tables   map[string]int `json:"tables"` // rows restored per table
+	Files    int            `json:"files"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// snapshotTable is a resolved snapshot model
+type snapshotTable struct {
+	name string
+	id   string // auto-increment primary key column, empty when the table has none
+}
+
+// RestoreEnabled reports whether this instance accepts restores. Restoring replaces its data, so
+// it must be switched on per environment with SNAPSHOT_RESTORE_ENABLED=true (staging only).
+func RestoreEnabled() bool {
+	return os.Getenv("SNAPSHOT_RESTORE_ENABLED") == "true"
+}
+
+// Write streams a gzipped tar snapshot of the snapshot tables and the data directory to w. Rows
+// are exported by PostgreSQL as JSON within one repeatable-read transaction, so every column
+// round-trips exactly and the tables are consistent with each other.
+func Write(ctx context.Context, db *gorm.DB, w io.Writer, createdBy string) (*Manifest, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	tables, err := resolveTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	manifest := &Manifest{
+		Version:     FormatVersion,
+		CreatedAt:   now,
+		CreatedBy:   createdBy,
+		Environment: os.Getenv("ENVIRONMENT"),
+		Tables:      make(map[string]int, len(tables)),
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Error; err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if err := exportTable(ctx, tx, tw, table, manifest, now); err != nil {
+				return fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(DataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == DataDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(DataDir, p)
+		if err != nil {
+			return err
+		}
+		// Read whole files so a concurrent sync cannot change the size after the header is written
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := writeEntry(tw, filesPrefix+filepath.ToSlash(rel), data, info.ModTime()); err != nil {
+			return err
+		}
+		manifest.Files++
+		manifest.FileBytes += int64(len(data))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive %s: %w", DataDir, err)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, ManifestName, manifestJSON, now); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportTable writes the rows of one table as chunks of ChunkRows, paging by id. Tables without
+// an auto-increment id are small configuration tables and go out in a single chunk.
+func exportTable(ctx context.Context, tx *gorm.DB, tw *tar.Writer, table snapshotTable, manifest *Manifest, now time.Time) error {
+	var lastID int64
+	for chunk := 1; ; chunk++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var page struct {
+			Rows  string
+			Count int
+			MaxID int64
+		}
+		var query string
+		var args []interface{}
+		if table.id != "" {
+			id := quoteIdent(table.id)
+			query = fmt.Sprintf(`SELECT COALESCE(json_agg(t ORDER BY %[1]s), '[]'::json)::text AS rows, COUNT(*) AS count, COALESCE(MAX(%[1]s), 0) AS max_id
+				FROM (SELECT * FROM %[2]s WHERE %[1]s > ? ORDER BY %[1]s LIMIT ?) t`, id, quoteIdent(table.name))
+			args = []interface{}{lastID, ChunkRows}
+		} else {
+			query = fmt.Sprintf(`SELECT COALESCE(json_agg(t), '[]'::json)::text AS rows, COUNT(*) AS count, 0 AS max_id FROM %s t`, quoteIdent(table.name))
+		}
+		if err := tx.Raw(query, args...).Scan(&page).Error; err != nil {
+			return err
+		}
+
+		// Every table gets at least one chunk, so restores can tell an empty table from a missing one
+		if page.Count > 0 || chunk == 1 {
+			name := fmt.Sprintf("%s%s/%06d.json", tablesPrefix, table.name, chunk)
+			if err := writeEntry(tw, name, []byte(page.Rows), now); err != nil {
+				return err
+			}
+		}
+		manifest.Tables[table.name] += page.Count
+		if table.id == "" || page.Count < ChunkRows {
+			return nil
+		}
+		lastID = page.MaxID
+	}
+}
+
+// Restore replaces the snapshot tables and the data directory with the contents of a snapshot
+// read from r. All tables are cleared, together with the rows of other tables referencing them,
+// and reloaded in one transaction that only commits when the archive is complete; the data
+// directory is staged next to the live one and swapped in after the commit. Rule, strategy,
+// indicator profile and calendar registries are reloaded; other file-based service settings
+// are read at startup, so restart the instance afterwards.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader) (*RestoreResult, error) {
+	if !RestoreEnabled() {
+		return nil, fmt.Errorf("restore is disabled on this instance (set SNAPSHOT_RESTORE_ENABLED=true)")
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	tables, err := resolveTables(db)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table.name] = true
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzipped snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	staging := DataDir + ".restore"
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	result := &RestoreResult{Tables: make(map[string]int, len(tables))}
+	var manifest *Manifest
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Rows elsewhere referencing the replaced stocks and rules would point at the wrong ids
+		// after the reload, so they are cleared with them
+		names := make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = quoteIdent(table.name)
+		}
+		if err := tx.Exec("TRUNCATE TABLE " + strings.Join(names, ", ") + " CASCADE").Error; err != nil {
+			return fmt.Errorf("failed to clear the snapshot tables: %w", err)
+		}
+
+		loaded := make(map[string]bool, len(tables))
+		tr := tar.NewReader(gz)
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("corrupt snapshot: %w", err)
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			name := path.Clean(hdr.Name)
+			switch {
+			case name == ManifestName:
+				manifest = &Manifest{}
+				if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+					return fmt.Errorf("invalid %s: %w", ManifestName, err)
+				}
+			case strings.HasPrefix(name, tablesPrefix):
+				table := path.Dir(strings.TrimPrefix(name, tablesPrefix))
+				if !known[table] {
+					log.Printf("Snapshot restore: skipping unknown table %s", table)
+					continue
+				}
+				rows, err := io.ReadAll(tr)
+				if err != nil {
+					return err
+				}
+				insert := fmt.Sprintf("INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, ?::json)", quoteIdent(table))
+				res := tx.Exec(insert, string(rows))
+				if res.Error != nil {
+					return fmt.Errorf("failed to load %s: %w", table, res.Error)
+				}
+				result.Tables[table] += int(res.RowsAffected)
+				loaded[table] = true
+			case strings.HasPrefix(name, filesPrefix):
+				rel := strings.TrimPrefix(name, filesPrefix)
+				if rel == "" || !filepath.IsLocal(rel) {
+					return fmt.Errorf("snapshot entry %q escapes the data directory", hdr.Name)
+				}
+				target := filepath.Join(staging, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return err
+				}
+				if err := writeFile(target, tr, hdr.ModTime); err != nil {
+					return err
+				}
+				result.Files++
+			}
+		}
+
+		if manifest == nil {
+			return fmt.Errorf("archive has no %s, not a complete snapshot", ManifestName)
+		}
+		if manifest.Version != FormatVersion {
+			return fmt.Errorf("unsupported snapshot version %d (expected %d)", manifest.Version, FormatVersion)
+		}
+		for _, table := range tables {
+			if !loaded[table.name] {
+				return fmt.Errorf("snapshot has no rows entry for %s", table.name)
+			}
+			if result.Tables[table.name] != manifest.Tables[table.name] {
+				return fmt.Errorf("snapshot is truncated: %s has %d of %d rows", table.name, result.Tables[table.name], manifest.Tables[table.name])
+			}
+			if err := resetSequence(tx, table); err != nil {
+				return fmt.Errorf("failed to reset the id sequence of %s: %w", table.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Source = *manifest
+
+	if result.Files > 0 {
+		if err := swapDataDir(staging); err != nil {
+			return nil, fmt.Errorf("database restored but the data directory was not replaced: %w", err)
+		}
+	}
+
+	result.Warnings = reload(db)
+	log.Printf("Snapshot of %s restored: %d tables, %d files", manifest.CreatedAt.Format(time.RFC3339), len(result.Tables), result.Files)
+	return result, nil
+}
+
+// swapDataDir replaces the live data directory with staging, keeping the old one until the
+// swap succeeded
+func swapDataDir(staging string) error {
+	previous := DataDir + ".previous"
+	if err := os.RemoveAll(previous); err != nil {
+		return err
+	}
+	if err := os.Rename(DataDir, previous); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staging, DataDir); err != nil {
+		os.Rename(previous, DataDir)
+		return err
+	}
+	return os.RemoveAll(previous)
+}
+
+// reload re-reads the registries backed by restored tables and files, returning failures
+func reload(db *gorm.DB) []string {
+	var warnings []string
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"trading calendar", services.InitTradingCalendar},
+		{"indicator profiles", func() error { return services.LoadIndicatorProfiles(db) }},
+		{"composite weights", func() error { return signals.LoadCompositeWeights(db) }},
+		{"custom strategies", func() error { return signals.LoadCustomStrategies(db) }},
+	}
+	for _, step := range steps {
+		if err := step.fn(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to reload %s: %v", step.name, err))
+		}
+	}
+	return warnings
+}
+
+// resetSequence moves the id sequence of a table past the restored ids
+func resetSequence(tx *gorm.DB, table snapshotTable) error {
+	if table.id == "" {
+		return nil
+	}
+	id := quoteIdent(table.id)
+	query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%[1]s), 1), MAX(%[1]s) IS NOT NULL) FROM %[2]s`,
+		id, quoteIdent(table.name))
+	return tx.Exec(query, table.name, table.id).Error
+}
+
+// resolveTables resolves the table name and id column of the snapshot models
+func resolveTables(db *gorm.DB) ([]snapshotTable, error) {
+	tables := make([]snapshotTable, 0, len(snapshotModels))
+	for _, model := range snapshotModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := snapshotTable{name: stmt.Schema.Table}
+		if field := stmt.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
+			table.id = field.DBName
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeFile(target string, r io.Reader, modTime time.Time) error {
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, modTime, modTime)
+}
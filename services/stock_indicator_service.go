@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,12 +13,15 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"go_backend_project/models"
 )
 
 // ExtendedStockIndicators holds all calculated technical indicators
 type ExtendedStockIndicators struct {
 	// Stock identifier
-	Code string `json:"code"` // Stock code/symbol
+	Code string `json:"code"`           // Stock code/symbol
+	Type string `json:"type,omitempty"` // Instrument type: stock, etf, cw, fund
 
 	// Relative Strength (change percentage)
 	RS3D       float64 `json:"rs_3d"`        // 3 days change %
@@ -26,12 +30,21 @@ type ExtendedStockIndicators struct {
 	RS3M       float64 `json:"rs_3m"`        // 3 months (~66 days) change %
 	RS1Y       float64 `json:"rs_1y"`        // 1 year (~252 days) change %
 
+	// Benchmark-relative RS: change % minus the VN-Index change % over the same dates
+	RS3DExcess float64 `json:"rs_3d_excess"`
+	RS1MExcess float64 `json:"rs_1m_excess"`
+	RS3MExcess float64 `json:"rs_3m_excess"`
+	RS1YExcess float64 `json:"rs_1y_excess"`
+	RSMethod   string  `json:"rs_method,omitempty"` // Values the ranks were computed from: benchmark or price_change
+
 	// Relative Strength Ranks (1-100 percentile)
 	RS3DRank float64 `json:"rs_3d_rank"`
 	RS1MRank float64 `json:"rs_1m_rank"`
 	RS3MRank float64 `json:"rs_3m_rank"`
 	RS1YRank float64 `json:"rs_1y_rank"`
 	RSAvg    float64 `json:"rs_avg"` // Average of all RS ranks
+	// Ranks estimated against the summary for a stock missing from it, see EstimateRSRanks
+	RSRankEstimated bool `json:"rs_rank_estimated,omitempty"`
 
 	// MACD
 	MACD       float64 `json:"macd"`        // MACD line (12-26 EMA)
@@ -39,34 +52,70 @@ type ExtendedStockIndicators struct {
 	MACDHist   float64 `json:"macd_hist"`   // MACD Histogram
 
 	// Volume
-	AvgVol        float64 `json:"avg_vol"`         // 5-day average volume
-	AvgTradingVal float64 `json:"avg_trading_val"` // 5-day average trading value (volume * price)
-	VolRatio      float64 `json:"vol_ratio"`       // Current vol / Avg vol
+	Volume        float64 `json:"volume"`                       // Latest session volume
+	AvgVol        float64 `json:"avg_vol"`                      // 5-day average volume
+	AvgTradingVal float64 `json:"avg_trading_val" unit:"value"` // 5-day average trading value (volume * price)
+	VolRatio      float64 `json:"vol_ratio"`                    // Current vol / Avg vol
 
 	// RSI
 	RSI float64 `json:"rsi"` // 14-day RSI
 
 	// Moving Averages
-	MA10  float64 `json:"ma_10"`
-	MA30  float64 `json:"ma_30"`
-	MA50  float64 `json:"ma_50"`
-	MA200 float64 `json:"ma_200"`
+	MA10  float64 `json:"ma_10" unit:"price"`
+	MA30  float64 `json:"ma_30" unit:"price"`
+	MA50  float64 `json:"ma_50" unit:"price"`
+	MA200 float64 `json:"ma_200" unit:"price"`
 
 	// MA Conditions (for filtering)
 	MA10AboveMA30  bool `json:"ma10_above_ma30"`  // MA10 >= MA30
 	MA50AboveMA200 bool `json:"ma50_above_ma200"` // MA50 >= MA200
 
 	// Price info
-	CurrentPrice float64 `json:"current_price"`
+	CurrentPrice float64 `json:"current_price" unit:"price"`
 	PriceChange  float64 `json:"price_change"` // Today's change %
 
+	// Volume flow over the stored history. The lines are cumulative and only comparable within a
+	// stock; the trends are the change of each line over AccumulationPeriod sessions as a % of the
+	// volume traded in them (-100 all distribution .. +100 all accumulation).
+	OBV      float64 `json:"obv"`       // On-Balance Volume
+	OBVTrend float64 `json:"obv_trend"` // OBV change over AccumulationPeriod, % of volume
+	ADLine   float64 `json:"ad_line"`   // Accumulation/Distribution Line
+	ADTrend  float64 `json:"ad_trend"`  // A/D change over AccumulationPeriod, % of volume
+
+	// Ichimoku (9/26/52). Senkou A/B are the cloud at the latest session, i.e. projected 26
+	// sessions ago; Chikou is the latest close, plotted 26 sessions back.
+	Tenkan          float64 `json:"tenkan" unit:"price"`
+	Kijun           float64 `json:"kijun" unit:"price"`
+	SenkouA         float64 `json:"senkou_a" unit:"price"`
+	SenkouB         float64 `json:"senkou_b" unit:"price"`
+	Chikou          float64 `json:"chikou" unit:"price"`
+	PriceAboveCloud bool    `json:"price_above_cloud"`
+	TKCrossBull     bool    `json:"tk_cross_bull"` // Tenkan crossed above Kijun in the latest session
+
+	// Opening gap: today's open vs the previous close, %, on adjusted prices
+	GapPercent float64 `json:"gap_percent"`
+
+	// 52-week range over the last FiftyTwoWeekSessions sessions, on adjusted prices
+	High52W        float64 `json:"high_52w" unit:"price"`
+	Low52W         float64 `json:"low_52w" unit:"price"`
+	PctFrom52WHigh float64 `json:"pct_from_52w_high"` // Close vs 52-week high, % (0 at the high)
+	PctFrom52WLow  float64 `json:"pct_from_52w_low"`  // Close vs 52-week low, % (0 at the low)
+	New52WHigh     bool    `json:"new_52w_high"`      // Closed above the high of the previous sessions
+	New52WLow      bool    `json:"new_52w_low"`       // Closed below the low of the previous sessions
+
+	// Distance from the latest close to the nearest support cluster below and resistance cluster
+	// above, %. NoLevelDistancePct when there is no level on that side.
+	PctFromSupport    float64 `json:"pct_from_support"`
+	PctFromResistance float64 `json:"pct_from_resistance"`
+
 	// Metadata
 	UpdatedAt string `json:"updated_at"`
 }
 
 // StockIndicatorService handles indicator calculations
 type StockIndicatorService struct {
-	mu sync.RWMutex
+	mu       sync.RWMutex
+	rsMethod string
 }
 
 // Global indicator service instance
@@ -74,8 +123,8 @@ var GlobalIndicatorService *StockIndicatorService
 
 // InitIndicatorService initializes the indicator service
 func InitIndicatorService() error {
-	GlobalIndicatorService = &StockIndicatorService{}
-	log.Println("Stock Indicator Service initialized")
+	GlobalIndicatorService = &StockIndicatorService{rsMethod: rsMethodFromEnv()}
+	log.Printf("Stock Indicator Service initialized (RS method: %s)", GlobalIndicatorService.rsMethod)
 	return nil
 }
 
@@ -140,18 +189,21 @@ func CalculateEMASeries(prices []float64, period int) []float64 {
 	return result
 }
 
-// CalculateRSI calculates Relative Strength Index
+// CalculateRSI calculates the Relative Strength Index with Wilder's smoothing: the first
+// average gain/loss is the simple mean of the oldest period changes, after which each change
+// is blended in as avg = (prev*(period-1) + change) / period. Prices are newest first.
 func CalculateRSI(prices []float64, period int) float64 {
-	if len(prices) <= period {
+	if period < 1 || len(prices) <= period {
 		return 50 // Default neutral
 	}
 
+	oldest := len(prices) - 1
 	gains := 0.0
 	losses := 0.0
 
-	// Calculate average gains and losses
-	for i := 0; i < period; i++ {
-		change := prices[i] - prices[i+1]
+	// Seed with the simple average of the oldest period changes
+	for i := oldest; i > oldest-period; i-- {
+		change := prices[i-1] - prices[i]
 		if change > 0 {
 			gains += change
 		} else {
@@ -162,6 +214,20 @@ func CalculateRSI(prices []float64, period int) float64 {
 	avgGain := gains / float64(period)
 	avgLoss := losses / float64(period)
 
+	// Smooth forward through the remaining changes
+	p := float64(period)
+	for i := oldest - period; i > 0; i-- {
+		change := prices[i-1] - prices[i]
+		gain, loss := 0.0, 0.0
+		if change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		avgGain = (avgGain*(p-1) + gain) / p
+		avgLoss = (avgLoss*(p-1) + loss) / p
+	}
+
 	if avgLoss == 0 {
 		return 100
 	}
@@ -172,39 +238,44 @@ func CalculateRSI(prices []float64, period int) float64 {
 	return math.Round(rsi*100) / 100
 }
 
-// CalculateMACD calculates MACD, Signal, and Histogram
+// CalculateMACD calculates MACD, Signal, and Histogram with the standard 12/26/9 periods
 func CalculateMACD(prices []float64) (macd, signal, hist float64) {
-	if len(prices) < 26 {
+	return CalculateMACDWithPeriods(prices, 12, 26, 9)
+}
+
+// CalculateMACDWithPeriods calculates MACD, Signal, and Histogram for custom fast/slow/signal periods
+func CalculateMACDWithPeriods(prices []float64, fast, slow, signalPeriod int) (macd, signal, hist float64) {
+	if len(prices) < slow {
 		return 0, 0, 0
 	}
 
-	// Calculate EMA 12 and EMA 26
-	ema12 := CalculateEMA(prices, 12)
-	ema26 := CalculateEMA(prices, 26)
+	// Calculate fast and slow EMA
+	emaFast := CalculateEMA(prices, fast)
+	emaSlow := CalculateEMA(prices, slow)
 
-	macd = ema12 - ema26
+	macd = emaFast - emaSlow
 
 	// For signal line, we need MACD series
 	// Simplified: calculate signal as EMA of recent MACD values
-	if len(prices) < 35 { // Need enough data for signal
+	if len(prices) < slow+signalPeriod { // Need enough data for signal
 		return macd, 0, macd
 	}
 
 	// Calculate MACD series
-	ema12Series := CalculateEMASeries(prices, 12)
-	ema26Series := CalculateEMASeries(prices, 26)
+	emaFastSeries := CalculateEMASeries(prices, fast)
+	emaSlowSeries := CalculateEMASeries(prices, slow)
 
-	if ema12Series == nil || ema26Series == nil {
+	if emaFastSeries == nil || emaSlowSeries == nil {
 		return macd, 0, macd
 	}
 
-	macdSeries := make([]float64, len(prices)-25)
+	macdSeries := make([]float64, len(prices)-slow+1)
 	for i := 0; i < len(macdSeries); i++ {
-		macdSeries[i] = ema12Series[i] - ema26Series[i]
+		macdSeries[i] = emaFastSeries[i] - emaSlowSeries[i]
 	}
 
-	// Signal is 9-day EMA of MACD
-	signal = CalculateEMA(macdSeries, 9)
+	// Signal is an EMA of MACD
+	signal = CalculateEMA(macdSeries, signalPeriod)
 	hist = macd - signal
 
 	return math.Round(macd*100) / 100,
@@ -277,8 +348,183 @@ func CalculateAvgTradingValue(volumes []float64, prices []float64, period int) f
 	return math.Round(avgInBillions*100) / 100
 }
 
-// CalculateIndicatorsForStock calculates all indicators for a single stock
+// AccumulationPeriod is the number of sessions the OBV and A/D trends are measured over
+const AccumulationPeriod = 20
+
+// VolumeFlow holds the On-Balance Volume and Accumulation/Distribution lines with their trends
+type VolumeFlow struct {
+	OBV, OBVTrend   float64
+	ADLine, ADTrend float64
+}
+
+// CalculateVolumeFlow accumulates OBV and the A/D line from the oldest stored session (prices
+// newest first). OBV adds the volume of up closes and subtracts that of down closes; A/D adds
+// volume weighted by where the close sits in the day's range, from -1 at the low to +1 at the high.
+func CalculateVolumeFlow(prices []StockPriceData, period int) VolumeFlow {
+	var flow VolumeFlow
+	n := len(prices)
+	if n < 2 {
+		return flow
+	}
+
+	obv := make([]float64, n)
+	ad := make([]float64, n)
+	var obvSum, adSum float64
+	for i := n - 1; i >= 0; i-- {
+		p := prices[i]
+		high, low, closePrice := adjustedBar(p)
+		if i < n-1 {
+			_, _, prevClose := adjustedBar(prices[i+1])
+			switch {
+			case closePrice > prevClose:
+				obvSum += p.NmVolume
+			case closePrice < prevClose:
+				obvSum -= p.NmVolume
+			}
+		}
+		if high > low {
+			adSum += ((closePrice - low) - (high - closePrice)) / (high - low) * p.NmVolume
+		}
+		obv[i], ad[i] = obvSum, adSum
+	}
+
+	flow.OBV = obvSum
+	flow.ADLine = math.Round(adSum)
+	if period > 0 && n > period {
+		var volume float64
+		for i := 0; i < period; i++ {
+			volume += prices[i].NmVolume
+		}
+		if volume > 0 {
+			flow.OBVTrend = math.Round((obv[0]-obv[period])/volume*10000) / 100
+			flow.ADTrend = math.Round((ad[0]-ad[period])/volume*10000) / 100
+		}
+	}
+	return flow
+}
+
+// Ichimoku periods
+const (
+	IchimokuTenkanPeriod = 9
+	IchimokuKijunPeriod  = 26
+	IchimokuSenkouPeriod = 52
+	IchimokuDisplacement = 26
+)
+
+// Ichimoku holds the Ichimoku lines at the latest session
+type Ichimoku struct {
+	Tenkan, Kijun    float64
+	SenkouA, SenkouB float64
+	Chikou           float64
+	PriceAboveCloud  bool
+	TKCrossBull      bool
+}
+
+// CalculateIchimoku computes the Ichimoku lines on adjusted prices (newest first). It needs
+// IchimokuSenkouPeriod + IchimokuDisplacement sessions for the current cloud.
+func CalculateIchimoku(prices []StockPriceData) (Ichimoku, bool) {
+	var ich Ichimoku
+	if len(prices) < IchimokuSenkouPeriod+IchimokuDisplacement {
+		return ich, false
+	}
+
+	// midpoint is the (highest high + lowest low) / 2 of period sessions starting at offset
+	midpoint := func(offset, period int) float64 {
+		var high, low float64
+		for i := offset; i < offset+period; i++ {
+			h, l, _ := adjustedBar(prices[i])
+			if h > high {
+				high = h
+			}
+			if low == 0 || l < low {
+				low = l
+			}
+		}
+		return (high + low) / 2
+	}
+
+	ich.Tenkan = midpoint(0, IchimokuTenkanPeriod)
+	ich.Kijun = midpoint(0, IchimokuKijunPeriod)
+	d := IchimokuDisplacement
+	ich.SenkouA = (midpoint(d, IchimokuTenkanPeriod) + midpoint(d, IchimokuKijunPeriod)) / 2
+	ich.SenkouB = midpoint(d, IchimokuSenkouPeriod)
+
+	_, _, closePrice := adjustedBar(prices[0])
+	ich.Chikou = closePrice
+	ich.PriceAboveCloud = closePrice > math.Max(ich.SenkouA, ich.SenkouB)
+
+	prevTenkan, prevKijun := midpoint(1, IchimokuTenkanPeriod), midpoint(1, IchimokuKijunPeriod)
+	ich.TKCrossBull = ich.Tenkan > ich.Kijun && prevTenkan <= prevKijun
+	return ich, true
+}
+
+// adjustedBar returns the adjusted high, low and close of a session, or the raw ones when the
+// adjusted prices are missing
+func adjustedBar(p StockPriceData) (high, low, closePrice float64) {
+	if p.AdHigh > 0 && p.AdLow > 0 && p.AdClose > 0 {
+		return p.AdHigh, p.AdLow, p.AdClose
+	}
+	return p.High, p.Low, p.Close
+}
+
+// CalculateGapPercent returns the % gap between the latest open and the previous close (prices
+// newest first), using adjusted prices when available
+func CalculateGapPercent(prices []StockPriceData) float64 {
+	if len(prices) < 2 {
+		return 0
+	}
+	open, prevClose := prices[0].AdOpen, prices[1].AdClose
+	if open <= 0 || prevClose <= 0 {
+		open, prevClose = prices[0].Open, prices[1].Close
+	}
+	if open <= 0 || prevClose <= 0 {
+		return 0
+	}
+	return math.Round((open-prevClose)/prevClose*10000) / 100
+}
+
+// FiftyTwoWeekSessions is the number of trading sessions in the 52-week range
+const FiftyTwoWeekSessions = 252
+
+// Calculate52WeekRange returns the highest high and lowest low of the latest period sessions
+// (prices newest first) on adjusted prices, and the same range excluding the latest session.
+// Stocks with a shorter history use all their sessions.
+func Calculate52WeekRange(prices []StockPriceData, period int) (high, low, priorHigh, priorLow float64) {
+	if len(prices) > period {
+		prices = prices[:period]
+	}
+	for i, p := range prices {
+		h, l, _ := adjustedBar(p)
+		if h <= 0 || l <= 0 {
+			continue
+		}
+		if h > high {
+			high = h
+		}
+		if low == 0 || l < low {
+			low = l
+		}
+		if i == 0 {
+			continue
+		}
+		if h > priorHigh {
+			priorHigh = h
+		}
+		if priorLow == 0 || l < priorLow {
+			priorLow = l
+		}
+	}
+	return high, low, priorHigh, priorLow
+}
+
+// CalculateIndicatorsForStock calculates all indicators for a single stock with the active indicator profile
 func CalculateIndicatorsForStock(priceFile *StockPriceFile) *ExtendedStockIndicators {
+	_, params := ActiveIndicatorParams()
+	return CalculateIndicatorsWithParams(priceFile, params)
+}
+
+// CalculateIndicatorsWithParams calculates all indicators for a single stock with the given periods
+func CalculateIndicatorsWithParams(priceFile *StockPriceFile, params IndicatorParams) *ExtendedStockIndicators {
 	if priceFile == nil || len(priceFile.Prices) < 10 {
 		return nil
 	}
@@ -309,26 +555,61 @@ func CalculateIndicatorsForStock(priceFile *StockPriceFile) *ExtendedStockIndica
 	indicators.RS3M = CalculatePriceChange(closePrices, 66)  // ~3 months
 	indicators.RS1Y = CalculatePriceChange(closePrices, 252) // ~1 year
 
-	// RSI (14-day)
-	indicators.RSI = CalculateRSI(closePrices, 14)
+	// RSI (Wilder, 14-day by default)
+	indicators.RSI = CalculateRSI(closePrices, params.RSIPeriod)
 
 	// MACD
-	indicators.MACD, indicators.MACDSignal, indicators.MACDHist = CalculateMACD(closePrices)
+	indicators.MACD, indicators.MACDSignal, indicators.MACDHist = CalculateMACDWithPeriods(closePrices,
+		params.MACDFast, params.MACDSlow, params.MACDSignal)
 
-	// Average Volume (5-day)
-	indicators.AvgVol = CalculateAvgVolume(volumes, 5)
+	// Average Volume (5-day by default)
+	indicators.Volume = volumes[0]
+	indicators.AvgVol = CalculateAvgVolume(volumes, params.AvgVolPeriod)
 	if indicators.AvgVol > 0 && volumes[0] > 0 {
 		indicators.VolRatio = math.Round((volumes[0]/indicators.AvgVol)*100) / 100
 	}
 
 	// Average Trading Value (5-day) = volume * price
-	indicators.AvgTradingVal = CalculateAvgTradingValue(volumes, closePrices, 5)
+	indicators.AvgTradingVal = CalculateAvgTradingValue(volumes, closePrices, params.AvgVolPeriod)
+
+	// Moving Averages (short/medium/long/trend windows, 10/30/50/200 by default)
+	indicators.MA10 = math.Round(CalculateMA(closePrices, params.MAShort)*100) / 100
+	indicators.MA30 = math.Round(CalculateMA(closePrices, params.MAMedium)*100) / 100
+	indicators.MA50 = math.Round(CalculateMA(closePrices, params.MALong)*100) / 100
+	indicators.MA200 = math.Round(CalculateMA(closePrices, params.MATrend)*100) / 100
+
+	// On-Balance Volume and Accumulation/Distribution
+	flow := CalculateVolumeFlow(prices, AccumulationPeriod)
+	indicators.OBV, indicators.OBVTrend = flow.OBV, flow.OBVTrend
+	indicators.ADLine, indicators.ADTrend = flow.ADLine, flow.ADTrend
+
+	// Ichimoku cloud
+	if ichimoku, ok := CalculateIchimoku(prices); ok {
+		indicators.Tenkan = math.Round(ichimoku.Tenkan*100) / 100
+		indicators.Kijun = math.Round(ichimoku.Kijun*100) / 100
+		indicators.SenkouA = math.Round(ichimoku.SenkouA*100) / 100
+		indicators.SenkouB = math.Round(ichimoku.SenkouB*100) / 100
+		indicators.Chikou = ichimoku.Chikou
+		indicators.PriceAboveCloud = ichimoku.PriceAboveCloud
+		indicators.TKCrossBull = ichimoku.TKCrossBull
+	}
 
-	// Moving Averages
-	indicators.MA10 = math.Round(CalculateMA(closePrices, 10)*100) / 100
-	indicators.MA30 = math.Round(CalculateMA(closePrices, 30)*100) / 100
-	indicators.MA50 = math.Round(CalculateMA(closePrices, 50)*100) / 100
-	indicators.MA200 = math.Round(CalculateMA(closePrices, 200)*100) / 100
+	// Opening gap vs previous close
+	indicators.GapPercent = CalculateGapPercent(prices)
+
+	// 52-week range
+	high, low, priorHigh, priorLow := Calculate52WeekRange(prices, FiftyTwoWeekSessions)
+	if high > 0 && low > 0 {
+		indicators.High52W = math.Round(high*100) / 100
+		indicators.Low52W = math.Round(low*100) / 100
+		indicators.PctFrom52WHigh = math.Round((closePrices[0]-high)/high*10000) / 100
+		indicators.PctFrom52WLow = math.Round((closePrices[0]-low)/low*10000) / 100
+		indicators.New52WHigh = priorHigh > 0 && closePrices[0] > priorHigh
+		indicators.New52WLow = priorLow > 0 && closePrices[0] < priorLow
+	}
+
+	// Support/resistance clusters
+	indicators.PctFromSupport, indicators.PctFromResistance = CalculateStockLevels(priceFile.Code, prices, LevelsLookback).NearestLevelDistances()
 
 	// MA Conditions
 	indicators.MA10AboveMA30 = indicators.MA10 > 0 && indicators.MA30 > 0 && indicators.MA10 >= indicators.MA30
@@ -371,8 +652,9 @@ func CalculateRSRanks(allIndicators map[string]*ExtendedStockIndicators) {
 		}
 
 		// Filter: Only include stocks with significant trading value
-		// This approximates the "market cap >= 1000 billion VND" requirement
-		if ind.AvgTradingVal < MinTradingValForRS {
+		// This approximates the "market cap >= 1000 billion VND" requirement.
+		// Covered warrants are leveraged and expire, so they are never ranked against stocks.
+		if ind.AvgTradingVal < MinTradingValForRS || ind.Type == InstrumentTypeCW {
 			// Set default ranks for small cap stocks (not ranked)
 			ind.RS3DRank = 0
 			ind.RS1MRank = 0
@@ -382,11 +664,16 @@ func CalculateRSRanks(allIndicators map[string]*ExtendedStockIndicators) {
 			continue
 		}
 
-		// Add to each RS period list separately
-		rs3dList = append(rs3dList, rsEntry{code: code, value: ind.RS3D})
-		rs1mList = append(rs1mList, rsEntry{code: code, value: ind.RS1M})
-		rs3mList = append(rs3mList, rsEntry{code: code, value: ind.RS3M})
-		rs1yList = append(rs1yList, rsEntry{code: code, value: ind.RS1Y})
+		// Add to each RS period list separately, ranking excess returns when the
+		// benchmark method was applied and raw price change otherwise
+		rs3d, rs1m, rs3m, rs1y := ind.RS3D, ind.RS1M, ind.RS3M, ind.RS1Y
+		if ind.RSMethod == RSMethodBenchmark {
+			rs3d, rs1m, rs3m, rs1y = ind.RS3DExcess, ind.RS1MExcess, ind.RS3MExcess, ind.RS1YExcess
+		}
+		rs3dList = append(rs3dList, rsEntry{code: code, value: rs3d})
+		rs1mList = append(rs1mList, rsEntry{code: code, value: rs1m})
+		rs3mList = append(rs3mList, rsEntry{code: code, value: rs3m})
+		rs1yList = append(rs1yList, rsEntry{code: code, value: rs1y})
 	}
 
 	// Helper function to sort and assign ranks
@@ -395,9 +682,13 @@ func CalculateRSRanks(allIndicators map[string]*ExtendedStockIndicators) {
 			return
 		}
 
-		// Sort ascending by value (lower value = lower rank)
+		// Sort ascending by value (lower value = lower rank); ties are broken by code so
+		// the ranks do not depend on map iteration order
 		sort.Slice(list, func(i, j int) bool {
-			return list[i].value < list[j].value
+			if list[i].value != list[j].value {
+				return list[i].value < list[j].value
+			}
+			return list[i].code < list[j].code
 		})
 
 		// Assign percentile ranks (1-100)
@@ -471,6 +762,18 @@ type indicatorResult struct {
 
 // CalculateAllIndicators calculates indicators for all stocks with price data (concurrent)
 func (s *StockIndicatorService) CalculateAllIndicators() (map[string]*ExtendedStockIndicators, error) {
+	return s.CalculateAllIndicatorsContext(context.Background())
+}
+
+// CalculateAllIndicatorsContext is CalculateAllIndicators stopped early when ctx is done, in which
+// case the partial results are discarded and ctx's error is returned
+func (s *StockIndicatorService) CalculateAllIndicatorsContext(ctx context.Context) (map[string]*ExtendedStockIndicators, error) {
+	return s.calculateAllIndicators(ctx, nil)
+}
+
+// calculateAllIndicators calculates the indicators of every stock, calling progress (when set)
+// from the workers as stocks are done
+func (s *StockIndicatorService) calculateAllIndicators(ctx context.Context, progress func(done, total int, code string)) (map[string]*ExtendedStockIndicators, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -489,6 +792,9 @@ func (s *StockIndicatorService) CalculateAllIndicators() (map[string]*ExtendedSt
 			continue
 		}
 		code := file.Name()[:len(file.Name())-5]
+		if IsStockInactive(code) || code == MarketIndexCode {
+			continue // delisted stocks would skew RS rankings; the index is the benchmark
+		}
 		codes = append(codes, code)
 	}
 
@@ -507,28 +813,47 @@ func (s *StockIndicatorService) CalculateAllIndicators() (map[string]*ExtendedSt
 
 	log.Printf("Calculating indicators for %d stocks with %d workers", len(codes), workerCount)
 
+	// Use one profile for the whole run so a concurrent config change can't mix periods
+	_, params := ActiveIndicatorParams()
+
+	var bench *BenchmarkSeries
+	if s.RSMethod() == RSMethodBenchmark {
+		bench = LoadBenchmark()
+	}
+
 	jobs := make(chan indicatorJob, len(codes))
 	results := make(chan indicatorResult, len(codes))
 
 	// Start workers
 	var wg sync.WaitGroup
 	var processedCount int64
+	stockDone := func(code string) {
+		n := atomic.AddInt64(&processedCount, 1)
+		if progress != nil {
+			progress(int(n), len(codes), code)
+		}
+	}
 
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				priceFile, err := GlobalPriceService.LoadStockPrice(job.code)
+				if ctx.Err() != nil {
+					continue // drain the remaining jobs
+				}
+				priceFile, err := GlobalPriceService.LoadStockPriceContext(ctx, job.code)
 				if err != nil {
-					atomic.AddInt64(&processedCount, 1)
+					stockDone(job.code)
 					continue
 				}
 
-				indicators := CalculateIndicatorsForStock(priceFile)
-				atomic.AddInt64(&processedCount, 1)
+				indicators := CalculateIndicatorsWithParams(priceFile, params)
+				stockDone(job.code)
 
 				if indicators != nil {
+					indicators.Type = InstrumentTypeOf(job.code)
+					applyBenchmarkRS(indicators, priceFile.Prices, bench)
 					results <- indicatorResult{code: job.code, indicators: indicators}
 				}
 			}
@@ -554,6 +879,9 @@ func (s *StockIndicatorService) CalculateAllIndicators() (map[string]*ExtendedSt
 	for result := range results {
 		allIndicators[result.code] = result.indicators
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Calculate RS ranks across all stocks
 	CalculateRSRanks(allIndicators)
@@ -564,64 +892,103 @@ func (s *StockIndicatorService) CalculateAllIndicators() (map[string]*ExtendedSt
 	return allIndicators, nil
 }
 
-// SaveIndicatorsToFile saves indicators back to price files
+// SaveIndicatorsToFile saves indicators back to price files, with the same worker pool as the
+// calculation. Each file is replaced atomically, so a crash mid-save leaves it intact.
 func (s *StockIndicatorService) SaveIndicatorsToFile(allIndicators map[string]*ExtendedStockIndicators) error {
-	savedCount := 0
+	startTime := time.Now()
 
-	for code, indicators := range allIndicators {
-		if indicators == nil {
-			continue
-		}
+	workerCount := runtime.NumCPU()
+	if workerCount > 8 {
+		workerCount = 8
+	}
+	if workerCount < 2 {
+		workerCount = 2
+	}
 
-		// Load existing price file
-		priceFile, err := GlobalPriceService.LoadStockPrice(code)
-		if err != nil {
-			continue
-		}
+	jobs := make(chan string, len(allIndicators))
+	var wg sync.WaitGroup
+	var savedCount, failedCount int64
 
-		// Convert to StockIndicators for storage
-		priceFile.Indicators = &StockIndicators{
-			RS3D:      indicators.RS3DRank, // Store rank instead of raw value
-			RS1M:      indicators.RS1MRank,
-			RS3M:      indicators.RS3MRank,
-			RS1Y:      indicators.RS1YRank,
-			RSAvg:     indicators.RSAvg,
-			MACDHist:  indicators.MACDHist,
-			AvgVol:    indicators.AvgVol,
-			RSI:       indicators.RSI,
-			UpdatedAt: indicators.UpdatedAt,
-		}
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for code := range jobs {
+				if err := saveIndicatorsToPriceFile(code, allIndicators[code]); err != nil {
+					atomic.AddInt64(&failedCount, 1)
+					continue
+				}
+				atomic.AddInt64(&savedCount, 1)
+			}
+		}()
+	}
 
-		// Save to file
-		data, err := json.MarshalIndent(priceFile, "", "  ")
-		if err != nil {
-			continue
+	for code, indicators := range allIndicators {
+		if indicators != nil {
+			jobs <- code
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		filePath := filepath.Join(StockPriceDir, fmt.Sprintf("%s.json", code))
-		if err := os.WriteFile(filePath, data, 0644); err != nil {
-			continue
-		}
+	if failedCount > 0 {
+		log.Printf("Warning: failed to save indicators for %d stocks", failedCount)
+	}
+	log.Printf("Saved indicators for %d stocks in %v (workers: %d)", savedCount, time.Since(startTime).Round(time.Millisecond), workerCount)
+	return nil
+}
 
-		savedCount++
+// saveIndicatorsToPriceFile stores the indicators of one stock in its price file
+func saveIndicatorsToPriceFile(code string, indicators *ExtendedStockIndicators) error {
+	// Load existing price file
+	priceFile, err := GlobalPriceService.LoadStockPrice(code)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Saved indicators for %d stocks", savedCount)
-	return nil
+	// Convert to StockIndicators for storage, on a copy since loaded price files are shared
+	updated := *priceFile
+	priceFile = &updated
+	priceFile.Indicators = &StockIndicators{
+		RS3D:      indicators.RS3DRank, // Store rank instead of raw value
+		RS1M:      indicators.RS1MRank,
+		RS3M:      indicators.RS3MRank,
+		RS1Y:      indicators.RS1YRank,
+		RSAvg:     indicators.RSAvg,
+		MACDHist:  indicators.MACDHist,
+		AvgVol:    indicators.AvgVol,
+		RSI:       indicators.RSI,
+		UpdatedAt: indicators.UpdatedAt,
+	}
+
+	data, err := json.MarshalIndent(priceFile, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(StockPriceDir, fmt.Sprintf("%s.json", code))
+	return writeFileAtomic(filePath, data, 0644)
 }
 
 // IndicatorSummaryFile stores summary of all stock indicators
 type IndicatorSummaryFile struct {
 	UpdatedAt string                              `json:"updated_at"`
 	Count     int                                 `json:"count"`
+	Profile   string                              `json:"profile,omitempty"` // Indicator profile the values were calculated with
+	Params    *IndicatorParams                    `json:"params,omitempty"`
+	RSMethod  string                              `json:"rs_method,omitempty"`
 	Stocks    map[string]*ExtendedStockIndicators `json:"stocks"`
 }
 
 // SaveIndicatorSummary saves all indicators to a summary file
 func (s *StockIndicatorService) SaveIndicatorSummary(indicators map[string]*ExtendedStockIndicators) error {
+	profile, params := ActiveIndicatorParams()
 	summary := IndicatorSummaryFile{
 		UpdatedAt: time.Now().Format(time.RFC3339),
 		Count:     len(indicators),
+		Profile:   profile,
+		Params:    &params,
+		RSMethod:  s.RSMethod(),
 		Stocks:    indicators,
 	}
 
@@ -631,7 +998,7 @@ func (s *StockIndicatorService) SaveIndicatorSummary(indicators map[string]*Exte
 	}
 
 	summaryPath := filepath.Join("data", "indicators_summary.json")
-	if err := os.WriteFile(summaryPath, data, 0644); err != nil {
+	if err := writeFileAtomic(summaryPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write summary: %w", err)
 	}
 
@@ -641,6 +1008,12 @@ func (s *StockIndicatorService) SaveIndicatorSummary(indicators map[string]*Exte
 
 // LoadIndicatorSummary loads the indicator summary file from local file or MongoDB
 func (s *StockIndicatorService) LoadIndicatorSummary() (*IndicatorSummaryFile, error) {
+	return s.LoadIndicatorSummaryContext(context.Background())
+}
+
+// LoadIndicatorSummaryContext is LoadIndicatorSummary with the MongoDB fallback cancelled with ctx.
+// It returns ErrIndicatorsNotReady when neither has a summary.
+func (s *StockIndicatorService) LoadIndicatorSummaryContext(ctx context.Context) (*IndicatorSummaryFile, error) {
 	summaryPath := filepath.Join("data", "indicators_summary.json")
 
 	// Try local JSON file first (fastest)
@@ -648,14 +1021,14 @@ func (s *StockIndicatorService) LoadIndicatorSummary() (*IndicatorSummaryFile, e
 	if err == nil {
 		var summary IndicatorSummaryFile
 		if err := json.Unmarshal(data, &summary); err == nil && len(summary.Stocks) > 0 {
-			return &summary, nil
+			return excludeInactiveStocks(&summary), nil
 		}
 	}
 
 	// Fallback to MongoDB Atlas (persists across deploys)
 	if GlobalMongoClient != nil && GlobalMongoClient.IsConfigured() {
 		log.Println("Loading indicators from MongoDB Atlas...")
-		indicators, updatedAt, err := GlobalMongoClient.LoadIndicatorSummary()
+		indicators, updatedAt, err := GlobalMongoClient.LoadIndicatorSummaryContext(ctx)
 		if err == nil && len(indicators) > 0 {
 			summary := &IndicatorSummaryFile{
 				UpdatedAt: updatedAt.Format(time.RFC3339),
@@ -664,23 +1037,53 @@ func (s *StockIndicatorService) LoadIndicatorSummary() (*IndicatorSummaryFile, e
 			}
 			// Cache to local file for faster future reads
 			if cacheData, err := json.MarshalIndent(summary, "", "  "); err == nil {
-				os.WriteFile(summaryPath, cacheData, 0644)
+				writeFileAtomic(summaryPath, cacheData, 0644)
 				log.Printf("Cached %d indicators from MongoDB to local file", len(indicators))
 			}
-			return summary, nil
+			return excludeInactiveStocks(summary), nil
 		}
 	}
 
-	return nil, fmt.Errorf("indicator summary not found")
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Nothing to serve yet, so restore or recalculate it in the background for the next request
+	s.RecoverSummary()
+	return nil, ErrIndicatorsNotReady
+}
+
+// excludeInactiveStocks drops delisted stocks from a summary written before they were delisted,
+// so screeners and signals stop seeing them without waiting for the next recalculation
+func excludeInactiveStocks(summary *IndicatorSummaryFile) *IndicatorSummaryFile {
+	for code := range summary.Stocks {
+		if IsStockInactive(code) {
+			delete(summary.Stocks, code)
+		}
+	}
+	summary.Count = len(summary.Stocks)
+	return summary
 }
 
 // CalculateAndSaveAllIndicators calculates and saves all indicators
 func (s *StockIndicatorService) CalculateAndSaveAllIndicators() error {
+	return s.CalculateAndSaveAllIndicatorsContext(context.Background(), nil)
+}
+
+// CalculateAndSaveAllIndicatorsContext is CalculateAndSaveAllIndicators reporting the calculation
+// progress and stopping before anything is saved when ctx is cancelled
+func (s *StockIndicatorService) CalculateAndSaveAllIndicatorsContext(ctx context.Context, progress func(done, total int, code string)) (err error) {
+	startTime := time.Now()
+	calculated := 0
+	defer func() {
+		RecordSync(models.SyncTypeIndicators, startTime, calculated, calculated, 0, err)
+	}()
+
 	// Calculate all indicators
-	indicators, err := s.CalculateAllIndicators()
+	indicators, err := s.calculateAllIndicators(ctx, progress)
 	if err != nil {
 		return err
 	}
+	calculated = len(indicators)
 
 	// Save to individual files
 	if err := s.SaveIndicatorsToFile(indicators); err != nil {
@@ -752,10 +1155,19 @@ type IndicatorFilter struct {
 
 // FilterStocks filters stocks by indicator criteria
 func (s *StockIndicatorService) FilterStocks(filter IndicatorFilter) ([]string, error) {
-	summary, err := s.LoadIndicatorSummary()
+	return s.FilterStocksContext(context.Background(), filter)
+}
+
+// FilterStocksContext is FilterStocks cancelled with ctx, which matters when the summary is missing
+// and all indicators have to be calculated first
+func (s *StockIndicatorService) FilterStocksContext(ctx context.Context, filter IndicatorFilter) ([]string, error) {
+	summary, err := s.LoadIndicatorSummaryContext(ctx)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// Calculate if summary doesn't exist
-		indicators, err := s.CalculateAllIndicators()
+		indicators, err := s.CalculateAllIndicatorsContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"sync"
+	"testing"
+)
+
+// benchUniverse is generated once for every benchmark in the package
+var benchUniverse = sync.OnceValues(func() (map[string]*StockPriceFile, string) {
+	return SyntheticUniverse(BenchmarkUniverseStocks, BenchmarkUniverseDays, 1)
+})
+
+// reportPerStock adds the time per stock, the figure that scales to the real universe
+func reportPerStock(b *testing.B) {
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/1e3/float64(b.N)/BenchmarkUniverseStocks, "µs/stock")
+}
+
+func BenchmarkCalculateUniverseIndicators(b *testing.B) {
+	universe, asOf := benchUniverse()
+	params := DefaultIndicatorParams()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateUniverseIndicators(universe, asOf, params, RSMethodBenchmark)
+	}
+	reportPerStock(b)
+}
+
+func BenchmarkCalculateRSRanks(b *testing.B) {
+	universe, asOf := benchUniverse()
+	indicators := CalculateUniverseIndicators(universe, asOf, DefaultIndicatorParams(), RSMethodBenchmark)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateRSRanks(indicators)
+	}
+	reportPerStock(b)
+}
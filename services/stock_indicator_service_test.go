@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// indicatorGoldenFile holds reference values computed by testdata/indicators/reference.py from
+// the TA-Lib definitions, independently of this package
+const indicatorGoldenFile = "testdata/indicators/golden.json"
+
+type indicatorGolden struct {
+	Indicators []struct {
+		Name   string             `json:"name"`
+		Closes []float64          `json:"closes"` // newest first
+		MA     map[string]float64 `json:"ma"`
+		EMA    map[string]float64 `json:"ema"`
+		RSI    map[string]float64 `json:"rsi"`
+		MACD   *struct {
+			Fast       int     `json:"fast"`
+			Slow       int     `json:"slow"`
+			Signal     int     `json:"signal"`
+			MACD       float64 `json:"macd"`
+			SignalLine float64 `json:"signal_line"`
+			Hist       float64 `json:"hist"`
+		} `json:"macd"`
+	} `json:"indicators"`
+	RSUniverse []struct {
+		Code          string  `json:"code"`
+		AvgTradingVal float64 `json:"avg_trading_val"`
+		Type          string  `json:"type"`
+		RS3D          float64 `json:"rs_3d"`
+		RS1M          float64 `json:"rs_1m"`
+		RS3M          float64 `json:"rs_3m"`
+		RS1Y          float64 `json:"rs_1y"`
+	} `json:"rs_universe"`
+	RSRanks map[string]struct {
+		RS3DRank float64 `json:"rs_3d_rank"`
+		RS1MRank float64 `json:"rs_1m_rank"`
+		RS3MRank float64 `json:"rs_3m_rank"`
+		RS1YRank float64 `json:"rs_1y_rank"`
+		RSAvg    float64 `json:"rs_avg"`
+	} `json:"rs_ranks"`
+}
+
+// Tolerances: MA and EMA are returned unrounded; RSI and MACD are rounded to 2 decimals, and
+// the MACD fast EMA is seeded a few sessions earlier than in TA-Lib, which has decayed away on
+// these series
+const (
+	exactTolerance   = 1e-9
+	roundedTolerance = 0.005 + 1e-9
+	macdTolerance    = 0.01
+)
+
+func loadIndicatorGolden(t *testing.T) *indicatorGolden {
+	t.Helper()
+	data, err := os.ReadFile(filepath.FromSlash(indicatorGoldenFile))
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	var golden indicatorGolden
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("parse golden file: %v", err)
+	}
+	return &golden
+}
+
+func period(t *testing.T, key string) int {
+	t.Helper()
+	p, err := strconv.Atoi(key)
+	if err != nil {
+		t.Fatalf("invalid period %q", key)
+	}
+	return p
+}
+
+func assertClose(t *testing.T, what string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.6f, want %.6f (±%g)", what, got, want, tolerance)
+	}
+}
+
+func TestIndicatorsMatchGolden(t *testing.T) {
+	golden := loadIndicatorGolden(t)
+	for _, tc := range golden.Indicators {
+		t.Run(tc.Name, func(t *testing.T) {
+			for key, want := range tc.MA {
+				assertClose(t, "MA"+key, CalculateMA(tc.Closes, period(t, key)), want, exactTolerance)
+			}
+			for key, want := range tc.EMA {
+				assertClose(t, "EMA"+key, CalculateEMA(tc.Closes, period(t, key)), want, exactTolerance)
+			}
+			for key, want := range tc.RSI {
+				assertClose(t, "RSI"+key, CalculateRSI(tc.Closes, period(t, key)), want, roundedTolerance)
+			}
+			if m := tc.MACD; m != nil {
+				macd, signal, hist := CalculateMACDWithPeriods(tc.Closes, m.Fast, m.Slow, m.Signal)
+				assertClose(t, "MACD", macd, m.MACD, macdTolerance)
+				assertClose(t, "MACD signal", signal, m.SignalLine, macdTolerance)
+				assertClose(t, "MACD hist", hist, m.Hist, macdTolerance)
+			}
+		})
+	}
+}
+
+func TestRSRanksMatchGolden(t *testing.T) {
+	golden := loadIndicatorGolden(t)
+	universe := make(map[string]*ExtendedStockIndicators, len(golden.RSUniverse))
+	for _, s := range golden.RSUniverse {
+		universe[s.Code] = &ExtendedStockIndicators{
+			Code:          s.Code,
+			Type:          s.Type,
+			AvgTradingVal: s.AvgTradingVal,
+			RS3D:          s.RS3D,
+			RS1M:          s.RS1M,
+			RS3M:          s.RS3M,
+			RS1Y:          s.RS1Y,
+		}
+	}
+
+	CalculateRSRanks(universe)
+	for code, want := range golden.RSRanks {
+		got := universe[code]
+		if got == nil {
+			t.Fatalf("%s missing from the universe", code)
+		}
+		assertClose(t, code+" RS3DRank", got.RS3DRank, want.RS3DRank, exactTolerance)
+		assertClose(t, code+" RS1MRank", got.RS1MRank, want.RS1MRank, exactTolerance)
+		assertClose(t, code+" RS3MRank", got.RS3MRank, want.RS3MRank, exactTolerance)
+		assertClose(t, code+" RS1YRank", got.RS1YRank, want.RS1YRank, exactTolerance)
+		assertClose(t, code+" RSAvg", got.RSAvg, want.RSAvg, exactTolerance)
+	}
+}
+
+// randomWalk returns n closes, newest first, of a positive random walk
+func randomWalk(rng *rand.Rand, n int) []float64 {
+	closes := make([]float64, n)
+	price := 20 + rng.Float64()*80
+	for i := n - 1; i >= 0; i-- {
+		price = math.Max(0.1, price*(1+rng.NormFloat64()*0.03))
+		closes[i] = math.Round(price*100) / 100
+	}
+	return closes
+}
+
+func TestRSIWithinBounds(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		closes := randomWalk(rng, 20+rng.Intn(250))
+		for _, p := range []int{2, 6, 14, 21} {
+			if rsi := CalculateRSI(closes, p); rsi < 0 || rsi > 100 || math.IsNaN(rsi) {
+				t.Fatalf("RSI%d = %v out of [0, 100] for %v", p, rsi, closes)
+			}
+		}
+	}
+
+	flat := []float64{10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10}
+	if rsi := CalculateRSI(flat, 14); rsi < 0 || rsi > 100 {
+		t.Fatalf("RSI of a flat series = %v", rsi)
+	}
+}
+
+func TestMAOfMonotonicSeriesIsMonotonic(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		n := 60 + rng.Intn(200)
+		closes := make([]float64, n) // newest first, rising over time
+		price := 10.0
+		for j := n - 1; j >= 0; j-- {
+			price += rng.Float64() * 2
+			closes[j] = price
+		}
+
+		for _, p := range []int{5, 10, 20, 50} {
+			// The MA as of each session, oldest first, must never fall
+			prev := math.Inf(-1)
+			for asOf := n - p; asOf >= 0; asOf-- {
+				ma := CalculateMA(closes[asOf:], p)
+				if ma < prev-1e-9 {
+					t.Fatalf("MA%d fell from %v to %v at session %d of a rising series", p, prev, ma, asOf)
+				}
+				prev = ma
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StockLifecycleFile = "data/stock_lifecycle.json"
+	StockArchiveDir    = "data/archive/stocks"
+
+	// minListRatio guards against treating a truncated stock list response as mass delisting:
+	// codes missing from the new list only count as delisted when it has at least this share
+	// of the previous list
+	minListRatio = 0.9
+)
+
+// InactiveStock is a stock that was delisted or dropped from the listed stock list
+type InactiveStock struct {
+	Code         string `json:"code"`
+	Status       string `json:"status"`
+	DelistedDate string `json:"delisted_date,omitempty"`
+	DetectedAt   string `json:"detected_at"`
+	Archived     bool   `json:"archived"`
+}
+
+// StockLifecycleState is persisted in StockLifecycleFile
+type StockLifecycleState struct {
+	Inactive map[string]InactiveStock `json:"inactive"`
+	// Newly listed codes waiting to be fetched by the next full price sync
+	PendingListings []string `json:"pending_listings"`
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+// StockLifecycleChange is what a stock list sync changed
+type StockLifecycleChange struct {
+	Delisted     []string `json:"delisted"`
+	Reactivated  []string `json:"reactivated"`
+	NewlyListed  []string `json:"newly_listed"`
+	ArchiveError []string `json:"archive_errors,omitempty"`
+}
+
+// StockLifecycleService tracks delisted and newly listed stocks across stock list syncs
+type StockLifecycleService struct {
+	mu    sync.RWMutex
+	db    *gorm.DB
+	state StockLifecycleState
+}
+
+// GlobalStockLifecycle is the shared lifecycle tracker
+var GlobalStockLifecycle *StockLifecycleService
+
+// InitStockLifecycle loads the lifecycle state. The database is optional; when set, the status
+// of delisted stocks is also updated in the stocks table used by the screener.
+func InitStockLifecycle(db *gorm.DB) error {
+	l := &StockLifecycleService{db: db, state: StockLifecycleState{Inactive: map[string]InactiveStock{}}}
+
+	if data, err := os.ReadFile(StockLifecycleFile); err == nil {
+		if err := json.Unmarshal(data, &l.state); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", StockLifecycleFile, err)
+		}
+		if l.state.Inactive == nil {
+			l.state.Inactive = map[string]InactiveStock{}
+		}
+	}
+
+	GlobalStockLifecycle = l
+	log.Printf("Stock lifecycle initialized (%d inactive, %d pending listings)",
+		len(l.state.Inactive), len(l.state.PendingListings))
+	return nil
+}
+
+// IsStockInactive reports whether a stock has been delisted. It returns false when the
+// lifecycle tracker is not initialized.
+func IsStockInactive(code string) bool {
+	if GlobalStockLifecycle == nil {
+		return false
+	}
+	GlobalStockLifecycle.mu.RLock()
+	defer GlobalStockLifecycle.mu.RUnlock()
+	_, ok := GlobalStockLifecycle.state.Inactive[strings.ToUpper(code)]
+	return ok
+}
+
+// isListed reports whether the stock list entry describes an actively traded stock
+func isListed(stock VNDirectStock) bool {
+	status := strings.ToLower(stock.Status)
+	return (status == "" || status == "listed") && stock.DelistedDate == ""
+}
+
+// GetState returns a copy of the lifecycle state
+func (l *StockLifecycleService) GetState() StockLifecycleState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	state := StockLifecycleState{
+		Inactive:        make(map[string]InactiveStock, len(l.state.Inactive)),
+		PendingListings: append([]string{}, l.state.PendingListings...),
+		UpdatedAt:       l.state.UpdatedAt,
+	}
+	for code, s := range l.state.Inactive {
+		state.Inactive[code] = s
+	}
+	return state
+}
+
+// TakePendingListings returns the newly listed codes waiting for their first price sync and
+// clears the queue; codes that then fail to sync are kept by the price retry queue
+func (l *StockLifecycleService) TakePendingListings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	codes := l.state.PendingListings
+	if len(codes) == 0 {
+		return nil
+	}
+	l.state.PendingListings = nil
+	l.saveLocked()
+	return codes
+}
+
+// ApplyStockList compares a freshly synced stock list with the previous one. Stocks marked
+// delisted, or missing from the new list, become inactive and their price files are archived;
+// codes new to the list are queued for the next price sync.
+func (l *StockLifecycleService) ApplyStockList(previous, current []VNDirectStock) *StockLifecycleChange {
+	change := &StockLifecycleChange{}
+	if len(current) == 0 {
+		return change
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	currentByCode := make(map[string]VNDirectStock, len(current))
+	for _, s := range current {
+		currentByCode[strings.ToUpper(s.Code)] = s
+	}
+	previousCodes := make(map[string]bool, len(previous))
+	for _, s := range previous {
+		previousCodes[strings.ToUpper(s.Code)] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	markInactive := func(code, status, delistedDate string) {
+		if _, ok := l.state.Inactive[code]; ok {
+			return
+		}
+		entry := InactiveStock{Code: code, Status: status, DelistedDate: delistedDate, DetectedAt: now}
+		if err := archiveStockPrices(code); err != nil {
+			change.ArchiveError = append(change.ArchiveError, fmt.Sprintf("%s: %v", code, err))
+		} else {
+			entry.Archived = true
+		}
+		l.state.Inactive[code] = entry
+		change.Delisted = append(change.Delisted, code)
+	}
+
+	for code, s := range currentByCode {
+		if !isListed(s) {
+			status := s.Status
+			if status == "" {
+				status = "delisted"
+			}
+			markInactive(code, status, s.DelistedDate)
+			continue
+		}
+		if _, ok := l.state.Inactive[code]; ok {
+			if err := restoreStockPrices(code); err != nil {
+				change.ArchiveError = append(change.ArchiveError, fmt.Sprintf("%s: %v", code, err))
+			}
+			delete(l.state.Inactive, code)
+			change.Reactivated = append(change.Reactivated, code)
+		}
+		// The first sync has nothing to compare against; the full price sync covers every code
+		if len(previous) > 0 && !previousCodes[code] {
+			change.NewlyListed = append(change.NewlyListed, code)
+		}
+	}
+
+	if float64(len(current)) >= float64(len(previous))*minListRatio {
+		for code := range previousCodes {
+			if _, ok := currentByCode[code]; !ok {
+				markInactive(code, "removed", "")
+			}
+		}
+	} else if len(previous) > 0 {
+		log.Printf("Stock list shrank from %d to %d stocks, skipping delisting of missing codes", len(previous), len(current))
+	}
+
+	sort.Strings(change.Delisted)
+	sort.Strings(change.Reactivated)
+	sort.Strings(change.NewlyListed)
+
+	if len(change.NewlyListed) > 0 {
+		pending := make(map[string]bool, len(l.state.PendingListings))
+		for _, code := range l.state.PendingListings {
+			pending[code] = true
+		}
+		for _, code := range change.NewlyListed {
+			if !pending[code] {
+				l.state.PendingListings = append(l.state.PendingListings, code)
+			}
+		}
+	}
+
+	if len(change.Delisted) == 0 && len(change.Reactivated) == 0 && len(change.NewlyListed) == 0 {
+		return change
+	}
+
+	l.updateStockStatus(change)
+	l.saveLocked()
+	log.Printf("Stock lifecycle: %d delisted, %d reactivated, %d newly listed",
+		len(change.Delisted), len(change.Reactivated), len(change.NewlyListed))
+	return change
+}
+
+// updateStockStatus mirrors lifecycle changes into the stocks table used by the screener
+func (l *StockLifecycleService) updateStockStatus(change *StockLifecycleChange) {
+	if l.db == nil {
+		return
+	}
+	if len(change.Delisted) > 0 {
+		if err := l.db.Model(&models.Stock{}).Where("symbol IN ?", change.Delisted).
+			Update("status", "delisted").Error; err != nil {
+			log.Printf("Warning: failed to mark delisted stocks: %v", err)
+		}
+	}
+	if len(change.Reactivated) > 0 {
+		if err := l.db.Model(&models.Stock{}).Where("symbol IN ?", change.Reactivated).
+			Update("status", "active").Error; err != nil {
+			log.Printf("Warning: failed to reactivate stocks: %v", err)
+		}
+	}
+}
+
+func (l *StockLifecycleService) saveLocked() {
+	l.state.UpdatedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(l.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(StockLifecycleFile), 0755); err != nil {
+		log.Printf("Warning: failed to save stock lifecycle: %v", err)
+		return
+	}
+	if err := os.WriteFile(StockLifecycleFile, data, 0644); err != nil {
+		log.Printf("Warning: failed to save stock lifecycle: %v", err)
+	}
+}
+
+// archiveStockPrices moves a stock's price file out of StockPriceDir so indicator and RS
+// calculations no longer see it. A missing file is not an error.
+func archiveStockPrices(code string) error {
+	src := filepath.Join(StockPriceDir, code+".json")
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.MkdirAll(StockArchiveDir, 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, filepath.Join(StockArchiveDir, code+".json"))
+}
+
+// restoreStockPrices moves an archived price file back for a stock that is listed again
+func restoreStockPrices(code string) error {
+	src := filepath.Join(StockArchiveDir, code+".json")
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return os.Rename(src, filepath.Join(StockPriceDir, code+".json"))
+}
+
+// saveStockListWithLifecycle saves a synced stock list and applies lifecycle changes against
+// the list it replaces
+func saveStockListWithLifecycle(stocks []VNDirectStock) error {
+	previous, _ := LoadStocksFromFile()
+	if err := SaveStocksToFile(stocks); err != nil {
+		return err
+	}
+	if GlobalStockLifecycle != nil {
+		GlobalStockLifecycle.ApplyStockList(previous, stocks)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// Per-user limits of stock notes and tags
+const (
+	MaxStockNotesPerUser = 500
+	MaxStockNoteLength   = 5000 // characters
+	MaxTagsPerStockNote  = 10
+	MaxStockTagLength    = 30 // characters
+	MaxStockTagsPerUser  = 50 // distinct tags across all notes
+)
+
+// ErrInvalidStockNote is wrapped by the errors of notes or tags that break a limit
+var ErrInvalidStockNote = errors.New("invalid stock note")
+
+// StockTagCount is a tag and how many of the user's stocks carry it
+type StockTagCount struct {
+	Tag    string `json:"tag"`
+	Stocks int64  `json:"stocks"`
+}
+
+// NormalizeStockTag lowercases a tag and collapses its whitespace, e.g. " Earnings  Play" becomes
+// "earnings play"
+func NormalizeStockTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// normalizeStockTags normalizes tags, dropping empty and repeated ones, and checks the per-note limits
+func normalizeStockTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeStockTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxStockTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidStockNote, tag, MaxStockTagLength)
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > MaxTagsPerStockNote {
+		return nil, fmt.Errorf("%w: at most %d tags per stock", ErrInvalidStockNote, MaxTagsPerStockNote)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// fillStockNoteTags copies the loaded tag rows of notes into their Tags
+func fillStockNoteTags(notes ...*models.StockNote) {
+	for _, note := range notes {
+		note.Tags = make([]string, 0, len(note.TagRows))
+		for _, row := range note.TagRows {
+			note.Tags = append(note.Tags, row.Tag)
+		}
+		sort.Strings(note.Tags)
+	}
+}
+
+// GetStockNote returns the user's note on code, or nil when there is none
+func GetStockNote(db *gorm.DB, userID, code string) (*models.StockNote, error) {
+	var note models.StockNote
+	err := db.Preload("TagRows").Where("user_id = ? AND stock_code = ?", userID, strings.ToUpper(code)).First(&note).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fillStockNoteTags(&note)
+	return &note, nil
+}
+
+// ListStockNotes returns the user's notes, newest first, only those tagged tag when it is set
+func ListStockNotes(db *gorm.DB, userID, tag string) ([]models.StockNote, error) {
+	query := db.Preload("TagRows").Where("user_id = ?", userID)
+	if tag = NormalizeStockTag(tag); tag != "" {
+		query = query.Where("id IN (?)", db.Model(&models.StockNoteTag{}).Select("note_id").Where("user_id = ? AND tag = ?", userID, tag))
+	}
+
+	var notes []models.StockNote
+	if err := query.Order("updated_at DESC").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	for i := range notes {
+		fillStockNoteTags(&notes[i])
+	}
+	return notes, nil
+}
+
+// StockCodesWithTag returns the codes the user tagged tag
+func StockCodesWithTag(db *gorm.DB, userID, tag string) ([]string, error) {
+	var codes []string
+	err := db.Model(&models.StockNote{}).
+		Joins("JOIN stock_note_tags ON stock_note_tags.note_id = stock_notes.id").
+		Where("stock_notes.user_id = ? AND stock_note_tags.tag = ?", userID, NormalizeStockTag(tag)).
+		Order("stock_notes.stock_code").Pluck("stock_notes.stock_code", &codes).Error
+	return codes, err
+}
+
+// UserStockTags returns the user's tags with how many stocks carry each, most used first
+func UserStockTags(db *gorm.DB, userID string) ([]StockTagCount, error) {
+	tags := []StockTagCount{}
+	err := db.Model(&models.StockNoteTag{}).Select("tag, COUNT(*) AS stocks").
+		Where("user_id = ?", userID).Group("tag").Order("stocks DESC, tag").Scan(&tags).Error
+	return tags, err
+}
+
+// SaveStockNote creates or replaces the user's note and tags on code
+func SaveStockNote(db *gorm.DB, userID, code, text string, tags []string) (*models.StockNote, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > MaxStockNoteLength {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidStockNote, MaxStockNoteLength)
+	}
+	tags, err := normalizeStockTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" && len(tags) == 0 {
+		return nil, fmt.Errorf("%w: note or tags are required", ErrInvalidStockNote)
+	}
+
+	var note models.StockNote
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND stock_code = ?", userID, code).First(&note).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		isNew := err == gorm.ErrRecordNotFound
+		if isNew {
+			var count int64
+			if err := tx.Model(&models.StockNote{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= MaxStockNotesPerUser {
+				return fmt.Errorf("%w: at most %d stocks with notes", ErrInvalidStockNote, MaxStockNotesPerUser)
+			}
+		}
+
+		// Tags used elsewhere don't count again towards the distinct tag limit
+		if len(tags) > 0 {
+			others := tx.Model(&models.StockNoteTag{}).Where("user_id = ?", userID)
+			if !isNew {
+				others = others.Where("note_id <> ?", note.ID)
+			}
+			var used []string
+			if err := others.Distinct("tag").Pluck("tag", &used).Error; err != nil {
+				return err
+			}
+			distinct := len(used)
+			usedSet := make(map[string]bool, len(used))
+			for _, tag := range used {
+				usedSet[tag] = true
+			}
+			for _, tag := range tags {
+				if !usedSet[tag] {
+					distinct++
+				}
+			}
+			if distinct > MaxStockTagsPerUser {
+				return fmt.Errorf("%w: at most %d different tags", ErrInvalidStockNote, MaxStockTagsPerUser)
+			}
+		}
+
+		note.UserID, note.StockCode, note.Note = userID, code, text
+		if err := tx.Save(&note).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", note.ID).Delete(&models.StockNoteTag{}).Error; err != nil {
+			return err
+		}
+		note.TagRows = make([]models.StockNoteTag, 0, len(tags))
+		for _, tag := range tags {
+			note.TagRows = append(note.TagRows, models.StockNoteTag{NoteID: note.ID, UserID: userID, Tag: tag})
+		}
+		if len(note.TagRows) > 0 {
+			return tx.Create(&note.TagRows).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fillStockNoteTags(&note)
+	return &note, nil
+}
+
+// DeleteStockNote removes the user's note and tags on code, reporting whether there was one
+func DeleteStockNote(db *gorm.DB, userID, code string) (bool, error) {
+	var deleted bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var note models.StockNote
+		err := tx.Where("user_id = ? AND stock_code = ?", userID, strings.ToUpper(code)).First(&note).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", note.ID).Delete(&models.StockNoteTag{}).Error; err != nil {
+			return err
+		}
+		deleted = true
+		return tx.Delete(&note).Error
+	})
+	return deleted, err
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +12,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"go_backend_project/models"
 )
 
 // Price data constants
@@ -20,6 +23,8 @@ const (
 	PriceSyncConfigFile = "data/price_sync_config.json"
 	DefaultPriceSize    = 270 // ~1 year of trading days
 	DefaultWorkerCount  = 10  // Concurrent workers for fetching
+	RetryWorkerCount    = 2   // Workers for re-fetching failed stocks, kept low to avoid rate limits
+	AutoRetryDelay      = 30 * time.Minute
 )
 
 // VNDirectPriceResponse represents the API response
@@ -92,6 +97,10 @@ type PriceSyncConfig struct {
 	LastFullSync   string `json:"last_full_sync"`
 	CurrentStock   string `json:"current_stock"`
 	SyncInProgress bool   `json:"sync_in_progress"`
+
+	// Stocks that failed in the last full sync or retry pass, kept for the retry queue
+	FailedStocks []string `json:"failed_stocks"`
+	LastRetry    string   `json:"last_retry"`
 }
 
 // PriceSyncProgress represents sync progress
@@ -107,6 +116,7 @@ type PriceSyncProgress struct {
 	EstimatedTime   string   `json:"estimated_time"`
 	Status          string   `json:"status"`
 	WorkerCount     int      `json:"worker_count"`
+	Retry           bool     `json:"retry"` // progress of a failed-stock retry pass
 }
 
 // fetchJob represents a job for the worker pool
@@ -130,6 +140,8 @@ type StockPriceService struct {
 	stopChan   chan struct{}
 	isRunning  bool
 	httpClient *http.Client
+	retryTimer *time.Timer // pending automatic retry after a full sync
+	cache      *priceFileCache // parsed price files; nil when disabled
 
 	// Atomic counters for concurrent updates
 	successCount   int64
@@ -140,18 +152,28 @@ type StockPriceService struct {
 // Global price service instance
 var GlobalPriceService *StockPriceService
 
-// InitPriceService initializes the price service
-func InitPriceService() error {
-	GlobalPriceService = &StockPriceService{
+// NewStockPriceService creates a price service whose VNDirect client sends through rt, without
+// the sync config and price directory that InitPriceService sets up
+func NewStockPriceService(rt http.RoundTripper) *StockPriceService {
+	return &StockPriceService{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 20,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   30 * time.Second,
+			Transport: rt,
 		},
 		stopChan: make(chan struct{}),
+		cache:    newPriceFileCacheFromEnv(),
+	}
+}
+
+// InitPriceService initializes the price service
+func InitPriceService() error {
+	GlobalPriceService = NewStockPriceService(&http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     90 * time.Second,
+	})
+	if GlobalFakeProviders != nil {
+		GlobalPriceService.SetTransport(GlobalFakeProviders)
 	}
 
 	if err := os.MkdirAll(StockPriceDir, 0755); err != nil {
@@ -256,6 +278,13 @@ func (s *StockPriceService) IsRunning() bool {
 	return s.isRunning
 }
 
+// SetTransport replaces the transport of the VNDirect client and returns the previous one
+func (s *StockPriceService) SetTransport(rt http.RoundTripper) http.RoundTripper {
+	previous := s.httpClient.Transport
+	s.httpClient.Transport = rt
+	return previous
+}
+
 // FetchStockPrice fetches price data for a single stock
 func (s *StockPriceService) FetchStockPrice(code string, size int) (*VNDirectPriceResponse, error) {
 	url := fmt.Sprintf("%s?sort=date:desc&q=code:%s&size=%d", VNDirectPriceAPIURL, code, size)
@@ -309,9 +338,10 @@ func (s *StockPriceService) SaveStockPrice(code string, prices []StockPriceData)
 	}
 
 	filePath := filepath.Join(StockPriceDir, fmt.Sprintf("%s.json", code))
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write price file: %w", err)
 	}
+	s.cache.invalidate(code)
 
 	// Save to MongoDB Atlas (async to not block)
 	go func() {
@@ -327,24 +357,40 @@ func (s *StockPriceService) SaveStockPrice(code string, prices []StockPriceData)
 
 // LoadStockPrice loads price data from file or MongoDB Atlas
 func (s *StockPriceService) LoadStockPrice(code string) (*StockPriceFile, error) {
+	return s.LoadStockPriceContext(context.Background(), code)
+}
+
+// LoadStockPriceContext is LoadStockPrice with the MongoDB fallback cancelled with ctx.
+//
+// Parsed local files are kept in an LRU cache while the file is unchanged, so the returned file
+// may be shared with other callers and must not be modified.
+func (s *StockPriceService) LoadStockPriceContext(ctx context.Context, code string) (*StockPriceFile, error) {
 	filePath := filepath.Join(StockPriceDir, fmt.Sprintf("%s.json", code))
 
-	// Try local file first (fastest)
-	data, err := os.ReadFile(filePath)
-	if err == nil {
-		var priceFile StockPriceFile
-		if err := json.Unmarshal(data, &priceFile); err == nil {
-			return &priceFile, nil
+	// Try the cache, then the local file (fastest)
+	if info, err := os.Stat(filePath); err == nil {
+		if priceFile, ok := s.cache.get(code, info); ok {
+			return priceFile, nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err == nil {
+			var priceFile StockPriceFile
+			if err := json.Unmarshal(data, &priceFile); err == nil {
+				s.cache.put(code, info, &priceFile)
+				return &priceFile, nil
+			}
 		}
+	} else {
+		s.cache.invalidate(code)
 	}
 
 	// Fallback to MongoDB Atlas (persists across deploys)
 	if GlobalMongoClient != nil && GlobalMongoClient.IsConfigured() {
-		priceFile, err := GlobalMongoClient.LoadPriceData(code)
+		priceFile, err := GlobalMongoClient.LoadPriceDataContext(ctx, code)
 		if err == nil && priceFile != nil && len(priceFile.Prices) > 0 {
 			// Cache to local file for faster future reads
 			if cacheData, err := json.MarshalIndent(priceFile, "", "  "); err == nil {
-				os.WriteFile(filePath, cacheData, 0644)
+				writeFileAtomic(filePath, cacheData, 0644)
 			}
 			return priceFile, nil
 		}
@@ -360,6 +406,10 @@ func (s *StockPriceService) StartFullSync() error {
 		s.mu.Unlock()
 		return fmt.Errorf("sync already in progress")
 	}
+	if s.retryTimer != nil {
+		s.retryTimer.Stop()
+		s.retryTimer = nil
+	}
 	s.isRunning = true
 	s.stopChan = make(chan struct{})
 	s.mu.Unlock()
@@ -368,6 +418,91 @@ func (s *StockPriceService) StartFullSync() error {
 	return nil
 }
 
+// RunFullSync starts a full sync and waits for it to end, reporting its progress every second.
+// The sync is stopped when ctx is cancelled.
+func (s *StockPriceService) RunFullSync(ctx context.Context, progress func(PriceSyncProgress)) (PriceSyncProgress, error) {
+	if err := s.StartFullSync(); err != nil {
+		return PriceSyncProgress{}, err
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.StopSync()
+			return s.GetProgress(), ctx.Err()
+		case <-ticker.C:
+		}
+
+		p := s.GetProgress()
+		if progress != nil {
+			progress(p)
+		}
+		if s.IsRunning() {
+			continue
+		}
+		if p.Status != "completed" {
+			return p, fmt.Errorf("price sync ended with status %s", p.Status)
+		}
+		return p, nil
+	}
+}
+
+// GetFailedStocks returns the stocks waiting in the retry queue
+func (s *StockPriceService) GetFailedStocks() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.config.FailedStocks...)
+}
+
+// RetryFailedStocks re-fetches only the stocks that failed in the last sync, with RetryWorkerCount workers
+func (s *StockPriceService) RetryFailedStocks() (int, error) {
+	s.mu.Lock()
+	if s.isRunning {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("sync already in progress")
+	}
+	codes := append([]string(nil), s.config.FailedStocks...)
+	if len(codes) == 0 {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("no failed stocks to retry")
+	}
+	if s.retryTimer != nil {
+		s.retryTimer.Stop()
+		s.retryTimer = nil
+	}
+	s.isRunning = true
+	s.stopChan = make(chan struct{})
+	s.mu.Unlock()
+
+	go s.runSyncConcurrent(codes, RetryWorkerCount, true)
+	return len(codes), nil
+}
+
+// scheduleAutoRetry runs one retry pass AutoRetryDelay after a full sync that had failures
+func (s *StockPriceService) scheduleAutoRetry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.retryTimer != nil {
+		s.retryTimer.Stop()
+	}
+	s.retryTimer = time.AfterFunc(AutoRetryDelay, func() {
+		s.mu.Lock()
+		s.retryTimer = nil
+		s.mu.Unlock()
+
+		count, err := s.RetryFailedStocks()
+		if err != nil {
+			log.Printf("Skipping automatic price retry: %v", err)
+			return
+		}
+		log.Printf("Started automatic retry for %d failed stocks", count)
+	})
+	log.Printf("Scheduled automatic retry of %d failed stocks in %v", len(s.config.FailedStocks), AutoRetryDelay)
+}
+
 // StopSync stops the current sync
 func (s *StockPriceService) StopSync() {
 	s.mu.Lock()
@@ -449,17 +584,47 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 		s.progress.Status = "error"
 		s.mu.Unlock()
 		log.Printf("Failed to load stock list: %v", err)
+		RecordSync(models.SyncTypePriceFull, startTime, 0, 0, 0, err)
 		return
 	}
 
+	// Newly listed stocks go first so they have prices before the next indicator run
+	var codes []string
+	seen := make(map[string]bool, len(stocks))
+	if GlobalStockLifecycle != nil {
+		for _, code := range GlobalStockLifecycle.TakePendingListings() {
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	for _, stock := range stocks {
+		if seen[stock.Code] || IsStockInactive(stock.Code) {
+			continue
+		}
+		seen[stock.Code] = true
+		codes = append(codes, stock.Code)
+	}
+
+	s.mu.RLock()
+	workerCount := s.config.WorkerCount
+	s.mu.RUnlock()
+
+	s.runSyncConcurrent(codes, workerCount, false)
+}
+
+// runSyncConcurrent fetches and saves prices for codes using a worker pool. Failed codes are
+// persisted for the retry queue; a full sync with failures schedules one automatic retry.
+func (s *StockPriceService) runSyncConcurrent(codes []string, workerCount int, retry bool) {
+	startTime := time.Now()
+
 	// Reset atomic counters
 	atomic.StoreInt64(&s.successCount, 0)
 	atomic.StoreInt64(&s.failedCount, 0)
 	atomic.StoreInt64(&s.processedCount, 0)
 
-	// Get worker count
 	s.mu.RLock()
-	workerCount := s.config.WorkerCount
 	priceSize := s.config.PriceSize
 	s.mu.RUnlock()
 
@@ -470,7 +635,7 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 	// Initialize progress
 	s.mu.Lock()
 	s.progress = PriceSyncProgress{
-		TotalStocks:     len(stocks),
+		TotalStocks:     len(codes),
 		ProcessedStocks: 0,
 		SuccessCount:    0,
 		FailedCount:     0,
@@ -478,15 +643,16 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 		StartTime:       startTime.Format(time.RFC3339),
 		Status:          "running",
 		WorkerCount:     workerCount,
+		Retry:           retry,
 	}
 	s.config.SyncInProgress = true
 	s.mu.Unlock()
 
-	log.Printf("Starting concurrent price sync for %d stocks with %d workers", len(stocks), workerCount)
+	log.Printf("Starting concurrent price sync for %d stocks with %d workers (retry: %v)", len(codes), workerCount, retry)
 
 	// Create channels
-	jobs := make(chan fetchJob, len(stocks))
-	results := make(chan fetchResult, len(stocks))
+	jobs := make(chan fetchJob, len(codes))
+	results := make(chan fetchResult, len(codes))
 	done := make(chan bool)
 
 	// Track failed stocks
@@ -505,11 +671,11 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 
 	// Send jobs
 	go func() {
-		for _, stock := range stocks {
+		for _, code := range codes {
 			select {
 			case <-s.stopChan:
 				break
-			case jobs <- fetchJob{code: stock.Code, size: priceSize}:
+			case jobs <- fetchJob{code: code, size: priceSize}:
 			}
 		}
 		close(jobs)
@@ -536,7 +702,7 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 
 				if processed > 0 {
 					avgTime := elapsed / time.Duration(processed)
-					remaining := avgTime * time.Duration(len(stocks)-processed)
+					remaining := avgTime * time.Duration(len(codes)-processed)
 					s.progress.EstimatedTime = remaining.Round(time.Second).String()
 				}
 				s.mu.Unlock()
@@ -562,13 +728,31 @@ func (s *StockPriceService) runFullSyncConcurrent() {
 	s.progress.ElapsedTime = time.Since(startTime).Round(time.Second).String()
 	s.progress.FailedStocks = failedStocks
 	s.config.SyncInProgress = false
-	s.config.LastFullSync = time.Now().Format(time.RFC3339)
+	// A retry pass that was stopped early keeps its whole queue, since untried codes are not failures
+	if !retry || s.progress.ProcessedStocks == len(codes) {
+		s.config.FailedStocks = failedStocks
+	}
+	if retry {
+		s.config.LastRetry = time.Now().Format(time.RFC3339)
+	} else {
+		s.config.LastFullSync = time.Now().Format(time.RFC3339)
+	}
 	s.mu.Unlock()
 
 	s.SaveConfig()
 
 	log.Printf("Price sync completed: success=%d, failed=%d, time=%s (workers: %d)",
 		s.progress.SuccessCount, s.progress.FailedCount, s.progress.ElapsedTime, workerCount)
+
+	syncType := models.SyncTypePriceFull
+	if retry {
+		syncType = models.SyncTypePriceRetry
+	}
+	RecordSync(syncType, startTime, len(codes), s.progress.SuccessCount, s.progress.FailedCount, nil)
+
+	if !retry && len(failedStocks) > 0 {
+		s.scheduleAutoRetry()
+	}
 }
 
 // SyncSingleStock syncs price for a single stock
@@ -613,17 +797,11 @@ func (s *StockPriceService) GetPriceSyncStats() (map[string]interface{}, error)
 		}
 		totalFiles++
 
-		filePath := filepath.Join(StockPriceDir, file.Name())
-		data, err := os.ReadFile(filePath)
+		priceFile, err := s.LoadStockPrice(file.Name()[:len(file.Name())-5])
 		if err != nil {
 			continue
 		}
 
-		var priceFile StockPriceFile
-		if err := json.Unmarshal(data, &priceFile); err != nil {
-			continue
-		}
-
 		updateTime, err := time.Parse(time.RFC3339, priceFile.LastUpdated)
 		if err != nil {
 			continue
@@ -641,6 +819,7 @@ func (s *StockPriceService) GetPriceSyncStats() (map[string]interface{}, error)
 		"total_files":  totalFiles,
 		"last_sync":    s.config.LastFullSync,
 		"worker_count": s.config.WorkerCount,
+		"cache":        s.CacheStats(),
 	}
 
 	if !oldestUpdate.IsZero() {
@@ -653,6 +832,16 @@ func (s *StockPriceService) GetPriceSyncStats() (map[string]interface{}, error)
 	return stats, nil
 }
 
+// CacheStats reports the parsed price file cache
+func (s *StockPriceService) CacheStats() PriceCacheStats {
+	return s.cache.stats()
+}
+
+// PurgeCache empties the parsed price file cache and returns the number of files dropped
+func (s *StockPriceService) PurgeCache() int {
+	return s.cache.purge()
+}
+
 // HasLocalPriceData checks if local price data exists
 func (s *StockPriceService) HasLocalPriceData() bool {
 	files, err := os.ReadDir(StockPriceDir)
@@ -706,7 +895,7 @@ func (s *StockPriceService) RestoreFromMongoDB() error {
 		}
 
 		filePath := filepath.Join(StockPriceDir, fmt.Sprintf("%s.json", code))
-		if err := os.WriteFile(filePath, data, 0644); err != nil {
+		if err := writeFileAtomic(filePath, data, 0644); err != nil {
 			continue
 		}
 
@@ -718,7 +907,13 @@ func (s *StockPriceService) RestoreFromMongoDB() error {
 }
 
 // SyncAllToMongoDB syncs all local price data to MongoDB Atlas
-func (s *StockPriceService) SyncAllToMongoDB() error {
+func (s *StockPriceService) SyncAllToMongoDB() (err error) {
+	startTime := time.Now()
+	synced := 0
+	defer func() {
+		RecordSync(models.SyncTypeMongoDB, startTime, synced, synced, 0, err)
+	}()
+
 	if GlobalMongoClient == nil || !GlobalMongoClient.IsConfigured() {
 		return fmt.Errorf("MongoDB not configured")
 	}
@@ -746,6 +941,7 @@ func (s *StockPriceService) SyncAllToMongoDB() error {
 		return fmt.Errorf("no price files to sync")
 	}
 
+	synced = len(priceFiles)
 	if err := GlobalMongoClient.SaveAllPriceData(priceFiles); err != nil {
 		return fmt.Errorf("failed to sync price data to MongoDB: %w", err)
 	}
@@ -171,7 +171,7 @@ func (s *StockScheduler) run() {
 			enabled := s.config.Enabled
 			s.mu.RUnlock()
 
-			if !enabled {
+			if !enabled || !MarketCalendar().IsTradingDay(now) {
 				continue
 			}
 
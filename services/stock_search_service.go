@@ -0,0 +1,287 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// StockSearchIndexTTL is how long the search index built from the stock list is reused
+const StockSearchIndexTTL = 10 * time.Minute
+
+// Match kinds, in ranking order
+const (
+	SearchMatchCodeExact    = "code_exact"
+	SearchMatchCodePrefix   = "code_prefix"
+	SearchMatchNamePrefix   = "name_prefix"
+	SearchMatchWordPrefix   = "word_prefix"
+	SearchMatchCodeContains = "code_contains"
+	SearchMatchNameContains = "name_contains"
+	SearchMatchCodeFuzzy    = "code_fuzzy"
+	SearchMatchNameFuzzy    = "name_fuzzy"
+)
+
+var searchMatchScores = map[string]int{
+	SearchMatchCodeExact:    1000,
+	SearchMatchCodePrefix:   900,
+	SearchMatchNamePrefix:   700,
+	SearchMatchWordPrefix:   600,
+	SearchMatchCodeContains: 500,
+	SearchMatchNameContains: 400,
+	SearchMatchCodeFuzzy:    300,
+	SearchMatchNameFuzzy:    200,
+}
+
+// StockSearchResult is one ranked match for a typeahead query
+type StockSearchResult struct {
+	Code        string  `json:"code"`
+	CompanyName string  `json:"company_name"`
+	ShortName   string  `json:"short_name"`
+	Floor       string  `json:"floor"`
+	Type        string  `json:"type"`
+	Price       float64 `json:"price"`
+	PriceChange float64 `json:"price_change"`
+	Match       string  `json:"match"`
+	Score       int     `json:"score"`
+}
+
+// searchEntry is a stock with its pre-normalized search keys
+type searchEntry struct {
+	stock VNDirectStock
+	code  string   // lower-case code
+	names []string // normalized company, short and English names
+	words []string // words of all names
+}
+
+// StockSearchService ranks stocks by code and company name with diacritics-insensitive matching
+type StockSearchService struct {
+	mu      sync.RWMutex
+	entries []searchEntry
+	builtAt time.Time
+}
+
+// GlobalStockSearch is the shared stock search index
+var GlobalStockSearch = &StockSearchService{}
+
+var diacriticsRemover = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeSearchText lower-cases text and strips Vietnamese diacritics, so "Sữa Việt Nam"
+// matches "sua viet nam". Punctuation becomes a space.
+func NormalizeSearchText(s string) string {
+	s = strings.NewReplacer("đ", "d", "Đ", "d").Replace(s)
+	if out, _, err := transform.String(diacriticsRemover, s); err == nil {
+		s = out
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Invalidate forces the index to be rebuilt on the next search, e.g. after a stock list import
+func (s *StockSearchService) Invalidate() {
+	s.mu.Lock()
+	s.builtAt = time.Time{}
+	s.mu.Unlock()
+}
+
+func (s *StockSearchService) index() ([]searchEntry, error) {
+	s.mu.RLock()
+	if s.entries != nil && time.Since(s.builtAt) < StockSearchIndexTTL {
+		entries := s.entries
+		s.mu.RUnlock()
+		return entries, nil
+	}
+	s.mu.RUnlock()
+
+	stocks, err := LoadStocksWithFallback()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]searchEntry, 0, len(stocks))
+	for _, stock := range stocks {
+		entry := searchEntry{stock: stock, code: strings.ToLower(stock.Code)}
+		for _, name := range []string{stock.CompanyName, stock.ShortName, stock.CompanyNameEng} {
+			if n := NormalizeSearchText(name); n != "" {
+				entry.names = append(entry.names, n)
+				entry.words = append(entry.words, strings.Fields(n)...)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	s.mu.Lock()
+	s.entries = entries
+	s.builtAt = time.Now()
+	s.mu.Unlock()
+	return entries, nil
+}
+
+// Search returns up to limit stocks ranked by match quality: exact code first, then code prefix,
+// name prefix, word prefix, substring and finally fuzzy (one edit) matches. Ties go to the more
+// liquid stock. Prices come from the indicator summary when it is available. A non-empty types
+// list restricts results to those instrument types.
+func (s *StockSearchService) Search(query string, limit int, types []string) ([]StockSearchResult, error) {
+	q := NormalizeSearchText(query)
+	if q == "" {
+		return []StockSearchResult{}, nil
+	}
+	entries, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+
+	var summary *IndicatorSummaryFile
+	if GlobalIndicatorService != nil {
+		summary, _ = GlobalIndicatorService.LoadIndicatorSummary()
+	}
+
+	type ranked struct {
+		result    StockSearchResult
+		liquidity float64
+	}
+	var matches []ranked
+	for i := range entries {
+		stock := entries[i].stock
+		instrumentType := ClassifyInstrument(stock)
+		if !MatchesInstrumentType(stock.Code, instrumentType, types) {
+			continue
+		}
+		match, penalty := matchSearchEntry(&entries[i], q)
+		if match == "" {
+			continue
+		}
+		r := ranked{result: StockSearchResult{
+			Code:        stock.Code,
+			CompanyName: stock.CompanyName,
+			ShortName:   stock.ShortName,
+			Floor:       stock.Floor,
+			Type:        instrumentType,
+			Match:       match,
+			Score:       searchMatchScores[match] - penalty,
+		}}
+		if summary != nil {
+			if ind, ok := summary.Stocks[stock.Code]; ok && ind != nil {
+				r.result.Price = ind.CurrentPrice
+				r.result.PriceChange = ind.PriceChange
+				r.liquidity = ind.AvgTradingVal
+			}
+		}
+		matches = append(matches, r)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].result.Score != matches[j].result.Score {
+			return matches[i].result.Score > matches[j].result.Score
+		}
+		if matches[i].liquidity != matches[j].liquidity {
+			return matches[i].liquidity > matches[j].liquidity
+		}
+		return matches[i].result.Code < matches[j].result.Code
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]StockSearchResult, len(matches))
+	for i, m := range matches {
+		results[i] = m.result
+	}
+	return results, nil
+}
+
+// matchSearchEntry returns the best match kind of a normalized query against an entry and a
+// small penalty that orders results within the same kind
+func matchSearchEntry(e *searchEntry, q string) (string, int) {
+	compact := strings.ReplaceAll(q, " ", "")
+	switch {
+	case e.code == compact:
+		return SearchMatchCodeExact, 0
+	case strings.HasPrefix(e.code, compact):
+		return SearchMatchCodePrefix, len(e.code) - len(compact)
+	}
+
+	for _, name := range e.names {
+		if strings.HasPrefix(name, q) {
+			return SearchMatchNamePrefix, 0
+		}
+	}
+
+	tokens := strings.Fields(q)
+	if allTokensMatch(tokens, e.words, strings.HasPrefix) {
+		return SearchMatchWordPrefix, 0
+	}
+	if strings.Contains(e.code, compact) {
+		return SearchMatchCodeContains, 0
+	}
+	for _, name := range e.names {
+		if strings.Contains(name, q) {
+			return SearchMatchNameContains, 0
+		}
+	}
+
+	if len(compact) >= 2 && len(compact) <= len(e.code)+1 {
+		if editDistance(e.code, compact) == 1 {
+			return SearchMatchCodeFuzzy, 0
+		}
+	}
+	fuzzyWord := func(word, token string) bool {
+		return len(token) >= 4 && editDistance(word, token) <= 1
+	}
+	if allTokensMatch(tokens, e.words, fuzzyWord) {
+		return SearchMatchNameFuzzy, 0
+	}
+	return "", 0
+}
+
+// allTokensMatch reports whether every query token matches at least one word
+func allTokensMatch(tokens, words []string, match func(word, token string) bool) bool {
+	if len(tokens) == 0 || len(words) == 0 {
+		return false
+	}
+	for _, token := range tokens {
+		found := false
+		for _, word := range words {
+			if match(word, token) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +12,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"go_backend_project/models"
 )
 
 // VNDirectAPIURL is the endpoint for fetching stock list
@@ -135,10 +138,11 @@ func FetchStocksFromVNDirect() ([]VNDirectStock, error) {
 	}
 
 	log.Printf("VNDirect API fetched %d stocks", len(response.Data))
+	ClassifyInstruments(response.Data)
 
 	// Save to local file and MongoDB for persistence
 	go func() {
-		SaveStocksToFile(response.Data)
+		saveStockListWithLifecycle(response.Data)
 		// Save to MongoDB Atlas for persistence across deploys
 		if GlobalMongoClient != nil && GlobalMongoClient.IsConfigured() {
 			if err := GlobalMongoClient.SaveStockList(response.Data); err != nil {
@@ -210,6 +214,8 @@ func SaveStocksToFile(stocks []VNDirectStock) error {
 	}
 
 	log.Printf("Saved %d stocks to file: %s", len(stocks), StockListFile)
+	GlobalStockSearch.Invalidate()
+	invalidateInstrumentTypes()
 	return nil
 }
 
@@ -226,9 +232,10 @@ func ImportStocksFromJSON(jsonData []byte) (*StockSyncResult, error) {
 	}
 
 	result.TotalFetched = len(stocks)
+	ClassifyInstruments(stocks)
 
 	// Save to local file
-	if err := SaveStocksToFile(stocks); err != nil {
+	if err := saveStockListWithLifecycle(stocks); err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to save to local file: %v", err))
 	}
 
@@ -255,10 +262,14 @@ func syncStocksFromVNDirectInternal() (*StockSyncResult, error) {
 		SyncedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 
+	startTime := time.Now()
+
 	// Fetch stocks from VNDirect
 	stocks, err := FetchStocksFromVNDirect()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch stocks from VNDirect: %w", err)
+		err = fmt.Errorf("failed to fetch stocks from VNDirect: %w", err)
+		RecordSync(models.SyncTypeStockList, startTime, 0, 0, 0, err)
+		return nil, err
 	}
 
 	result.TotalFetched = len(stocks)
@@ -278,6 +289,12 @@ func syncStocksFromVNDirectInternal() (*StockSyncResult, error) {
 	log.Printf("Stock sync completed: fetched=%d, created=%d, errors=%d",
 		result.TotalFetched, result.Created, len(result.Errors))
 
+	var syncErr error
+	if result.Created == 0 && len(result.Errors) > 0 {
+		syncErr = errors.New(strings.Join(result.Errors, "; "))
+	}
+	RecordSync(models.SyncTypeStockList, startTime, result.TotalFetched, result.Created, result.TotalFetched-result.Created, syncErr)
+
 	return result, nil
 }
 
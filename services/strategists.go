@@ -0,0 +1,470 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go_backend_project/models"
+	"go_backend_project/services/units"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Limits of strategist follows and the paper-trading bot
+const (
+	MaxFollowedStrategists = 50
+	MaxOpenPaperTrades     = 50
+	// DefaultPaperTradeQuantity is one board lot
+	DefaultPaperTradeQuantity = 100
+	MaxPaperTradeQuantity     = 1000000
+)
+
+var (
+	// ErrStrategistNotFound is returned for authors without a public template
+	ErrStrategistNotFound = errors.New("strategist not found")
+	// ErrInvalidStrategistFollow is wrapped by follows that break a rule or limit
+	ErrInvalidStrategistFollow = errors.New("invalid strategist follow")
+	// ErrInvalidPaperTrade is wrapped by paper trades that break a rule or limit
+	ErrInvalidPaperTrade = errors.New("invalid paper trade")
+	// ErrPaperTradeNotFound is returned for paper trades and signals the caller can't trade
+	ErrPaperTradeNotFound = errors.New("paper trade not found")
+)
+
+// StrategistStats is the public profile and signal performance of a template author
+type StrategistStats struct {
+	AuthorID        string     `json:"author_id"`
+	AuthorName      string     `json:"author_name"`
+	PublicTemplates int64      `json:"public_templates"`
+	Rules           int        `json:"rules"` // rules the author runs from their public templates
+	Followers       int64      `json:"followers"`
+	Signals         int64      `json:"signals"`
+	ClosedSignals   int64      `json:"closed_signals"`
+	Wins            int64      `json:"wins"`
+	WinRatePct      float64    `json:"win_rate_pct"`    // of closed signals
+	AvgPnLPercent   float64    `json:"avg_pnl_percent"` // of closed signals
+	LastSignalAt    *time.Time `json:"last_signal_at,omitempty"`
+	FollowedAt      *time.Time `json:"followed_at,omitempty"` // when the caller followed, in their list
+}
+
+// PaperBotSummary totals a user's paper trades
+type PaperBotSummary struct {
+	Open            int     `json:"open"`
+	Closed          int     `json:"closed"`
+	Wins            int     `json:"wins"`
+	RealizedPnL     float64 `json:"realized_pnl"`
+	UnrealizedPnL   float64 `json:"unrealized_pnl"`
+	WinRatePct      float64 `json:"win_rate_pct"`
+	AvgClosedPnLPct float64 `json:"avg_closed_pnl_percent"`
+}
+
+// publicTemplateIDs selects the live public templates of an author
+func publicTemplateIDs(db *gorm.DB, authorID string) *gorm.DB {
+	return db.Model(&models.SignalTemplate{}).Select("id").
+		Where("author_id = ? AND visibility = ?", authorID, models.TemplateVisibilityPublic)
+}
+
+// strategistRuleIDs returns the rules an author runs from their own public templates, deleted
+// ones included so that their signals still count. Only these rules' signals reach followers;
+// the author's other rules stay private.
+func strategistRuleIDs(db *gorm.DB, authorID string) ([]uint, error) {
+	var ids []uint
+	err := db.Unscoped().Model(&models.SignalRule{}).
+		Where("owner_user_id = ? AND source_template_id IN (?)", authorID, publicTemplateIDs(db, authorID)).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PublishedRules scopes a signal_rules query to the rules whose signals are published: admin
+// rules, and strategist rules an author runs from one of their own public templates. Other user
+// clones stay private to their owner; StrategistOfRule applies the same test to a single rule.
+func PublishedRules(db *gorm.DB) *gorm.DB {
+	ownPublicTemplates := db.Session(&gorm.Session{NewDB: true}).Model(&models.SignalTemplate{}).Select("signal_templates.id").
+		Where("signal_templates.author_id = signal_rules.owner_user_id AND signal_templates.visibility = ?", models.TemplateVisibilityPublic)
+	return db.Where("signal_rules.owner_user_id = '' OR signal_rules.owner_user_id IS NULL OR signal_rules.source_template_id IN (?)", ownPublicTemplates)
+}
+
+// StrategistOfRule returns the author whose signals the rule publishes to followers, i.e. the
+// rule's owner when it was cloned from one of their public templates, or "" otherwise
+func StrategistOfRule(db *gorm.DB, rule *models.SignalRule) string {
+	if rule == nil || rule.OwnerUserID == "" || rule.SourceTemplateID == nil {
+		return ""
+	}
+	var count int64
+	db.Model(&models.SignalTemplate{}).
+		Where("id = ? AND author_id = ? AND visibility = ?", *rule.SourceTemplateID, rule.OwnerUserID, models.TemplateVisibilityPublic).
+		Count(&count)
+	if count == 0 {
+		return ""
+	}
+	return rule.OwnerUserID
+}
+
+// strategistName is the author name of the author's latest public template
+func strategistName(db *gorm.DB, authorID string) string {
+	var names []string
+	db.Model(&models.SignalTemplate{}).Where("author_id = ? AND visibility = ?", authorID, models.TemplateVisibilityPublic).
+		Order("created_at DESC").Limit(1).Pluck("author_name", &names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// GetStrategistStats returns the performance of the signals of an author's strategist rules
+func GetStrategistStats(db *gorm.DB, authorID string) (*StrategistStats, error) {
+	stats := &StrategistStats{AuthorID: authorID}
+	if err := publicTemplateIDs(db, authorID).Count(&stats.PublicTemplates).Error; err != nil {
+		return nil, err
+	}
+	if authorID == "" || stats.PublicTemplates == 0 {
+		return nil, ErrStrategistNotFound
+	}
+	stats.AuthorName = strategistName(db, authorID)
+	if err := db.Model(&models.StrategistFollow{}).Where("author_id = ?", authorID).Count(&stats.Followers).Error; err != nil {
+		return nil, err
+	}
+
+	ruleIDs, err := strategistRuleIDs(db, authorID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Rules = len(ruleIDs)
+	if len(ruleIDs) == 0 {
+		return stats, nil
+	}
+
+	var row struct {
+		Signals int64
+		Closed  int64
+		Wins    int64
+		AvgPnl  float64
+		Last    *time.Time
+	}
+	err = db.Model(&models.SignalPerformance{}).
+		Select("COUNT(*) AS signals, "+
+			"COUNT(exit_date) AS closed, "+
+			"COUNT(CASE WHEN exit_date IS NOT NULL AND is_win THEN 1 END) AS wins, "+
+			"COALESCE(AVG(CASE WHEN exit_date IS NOT NULL THEN pnl_percent END), 0) AS avg_pnl, "+
+			"MAX(signal_date) AS last").
+		Where("rule_id IN ?", ruleIDs).Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.Signals, stats.ClosedSignals, stats.Wins = row.Signals, row.Closed, row.Wins
+	stats.AvgPnLPercent = roundStrategist(row.AvgPnl)
+	stats.LastSignalAt = row.Last
+	if row.Closed > 0 {
+		stats.WinRatePct = roundStrategist(float64(row.Wins) / float64(row.Closed) * 100)
+	}
+	return stats, nil
+}
+
+// FollowStrategist makes the user follow an author with at least one public template
+func FollowStrategist(db *gorm.DB, userID, authorID string) (*models.StrategistFollow, error) {
+	if authorID == userID {
+		return nil, fmt.Errorf("%w: you can't follow yourself", ErrInvalidStrategistFollow)
+	}
+	var templates int64
+	if err := publicTemplateIDs(db, authorID).Count(&templates).Error; err != nil {
+		return nil, err
+	}
+	if authorID == "" || templates == 0 {
+		return nil, ErrStrategistNotFound
+	}
+
+	var follow models.StrategistFollow
+	err := db.Where("user_id = ? AND author_id = ?", userID, authorID).First(&follow).Error
+	if err == nil {
+		return &follow, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var count int64
+	if err := db.Model(&models.StrategistFollow{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= MaxFollowedStrategists {
+		return nil, fmt.Errorf("%w: you can follow at most %d strategists", ErrInvalidStrategistFollow, MaxFollowedStrategists)
+	}
+
+	follow = models.StrategistFollow{UserID: userID, AuthorID: authorID, AuthorName: strategistName(db, authorID)}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+// UnfollowStrategist stops the user following an author; unfollowing twice is not an error
+func UnfollowStrategist(db *gorm.DB, userID, authorID string) error {
+	return db.Where("user_id = ? AND author_id = ?", userID, authorID).Delete(&models.StrategistFollow{}).Error
+}
+
+// ListFollowedStrategists returns the stats of the authors the user follows, latest first.
+// Authors who since unpublished all their templates are listed without stats.
+func ListFollowedStrategists(db *gorm.DB, userID string) ([]StrategistStats, error) {
+	var follows []models.StrategistFollow
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&follows).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]StrategistStats, 0, len(follows))
+	for _, f := range follows {
+		followedAt := f.CreatedAt
+		stats, err := GetStrategistStats(db, f.AuthorID)
+		if errors.Is(err, ErrStrategistNotFound) {
+			stats = &StrategistStats{AuthorID: f.AuthorID, AuthorName: f.AuthorName}
+		} else if err != nil {
+			return nil, err
+		}
+		stats.FollowedAt = &followedAt
+		list = append(list, *stats)
+	}
+	return list, nil
+}
+
+// NotifyStrategistFollowers sends a newly emitted signal of a strategist rule to the author's
+// followers, with the signal ID they can add to their paper-trading bot. Returns the number
+// of notified followers.
+func NotifyStrategistFollowers(db *gorm.DB, rule *models.SignalRule, signal *models.SignalHistory) int {
+	authorID := StrategistOfRule(db, rule)
+	if authorID == "" {
+		return 0
+	}
+	var followers []string
+	if err := db.Model(&models.StrategistFollow{}).Where("author_id = ?", authorID).Pluck("user_id", &followers).Error; err != nil {
+		log.Printf("Failed to load followers of strategist %s: %v", authorID, err)
+		return 0
+	}
+	if len(followers) == 0 {
+		return 0
+	}
+
+	name := strategistName(db, authorID)
+	if name == "" {
+		name = "A strategist you follow"
+	}
+	title := fmt.Sprintf("%s: %s %s", name, signal.SignalType, signal.StockSymbol)
+	message := fmt.Sprintf("%s signaled %s %s at %s (target %s, stop %s). Add it to your paper-trading bot to follow it.",
+		name, signal.SignalType, signal.StockSymbol, signal.Price.StringFixed(2),
+		signal.TargetPrice.StringFixed(2), signal.StopLossPrice.StringFixed(2))
+	data := map[string]interface{}{
+		"author_id":       authorID,
+		"rule_id":         signal.RuleID,
+		"signal_id":       signal.ID,
+		"stock_code":      signal.StockSymbol,
+		"signal_type":     signal.SignalType,
+		"score":           signal.Score,
+		"price":           signal.Price.InexactFloat64(),
+		"target_price":    signal.TargetPrice.InexactFloat64(),
+		"stop_loss_price": signal.StopLossPrice.InexactFloat64(),
+	}
+	for _, userID := range followers {
+		NotifyUser(db, userID, models.NotificationCategoryStrategist, title, message, data)
+	}
+	return len(followers)
+}
+
+// paperTradePnL returns the return and profit of a paper trade at a price, in its direction
+func paperTradePnL(trade *models.PaperTrade, price float64) (float64, float64) {
+	if trade.EntryPrice <= 0 || price <= 0 {
+		return 0, 0
+	}
+	entry, exit := decimal.NewFromFloat(trade.EntryPrice), decimal.NewFromFloat(price)
+	short := trade.Side == models.PaperTradeSideShort
+	pct := units.ReturnPct(entry, exit, short).RoundBank(2)
+	amount := units.PnL(entry, exit, trade.Quantity, short).RoundBank(2)
+	return pct.InexactFloat64(), amount.InexactFloat64()
+}
+
+// AddSignalToPaperBot opens a paper trade on an active signal of a strategist rule, at the
+// stock's current price: BUY signals open long positions, SELL signals short ones. A signal
+// can be in a user's bot once.
+func AddSignalToPaperBot(db *gorm.DB, userID string, signalID uint, quantity int64) (*models.PaperTrade, error) {
+	if quantity == 0 {
+		quantity = DefaultPaperTradeQuantity
+	}
+	if quantity < 0 || quantity > MaxPaperTradeQuantity {
+		return nil, fmt.Errorf("%w: quantity must be between 1 and %d", ErrInvalidPaperTrade, MaxPaperTradeQuantity)
+	}
+
+	var signal models.SignalHistory
+	if err := db.First(&signal, signalID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPaperTradeNotFound
+		}
+		return nil, err
+	}
+	var rule models.SignalRule
+	if signal.RuleID == 0 || db.Unscoped().First(&rule, signal.RuleID).Error != nil {
+		return nil, ErrPaperTradeNotFound
+	}
+	authorID := StrategistOfRule(db, &rule)
+	if authorID == "" {
+		return nil, ErrPaperTradeNotFound
+	}
+	if signal.State != models.SignalStateActive {
+		return nil, fmt.Errorf("%w: the signal is no longer active", ErrInvalidPaperTrade)
+	}
+
+	var side string
+	switch signal.SignalType {
+	case "BUY":
+		side = models.PaperTradeSideLong
+	case "SELL":
+		side = models.PaperTradeSideShort
+	default:
+		return nil, fmt.Errorf("%w: only BUY and SELL signals can be traded", ErrInvalidPaperTrade)
+	}
+
+	var existing int64
+	if err := db.Model(&models.PaperTrade{}).Where("user_id = ? AND signal_history_id = ?", userID, signal.ID).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: the signal is already in your paper bot", ErrInvalidPaperTrade)
+	}
+	var open int64
+	if err := db.Model(&models.PaperTrade{}).Where("user_id = ? AND status = ?", userID, models.PaperTradeStatusOpen).Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open >= MaxOpenPaperTrades {
+		return nil, fmt.Errorf("%w: at most %d paper trades can be open", ErrInvalidPaperTrade, MaxOpenPaperTrades)
+	}
+
+	price := currentIdeaPrice(signal.StockSymbol)
+	if price <= 0 {
+		price = signal.Price.InexactFloat64()
+	}
+	trade := &models.PaperTrade{
+		UserID:          userID,
+		SignalHistoryID: signal.ID,
+		AuthorID:        authorID,
+		RuleID:          signal.RuleID,
+		StockCode:       signal.StockSymbol,
+		Side:            side,
+		Quantity:        quantity,
+		EntryPrice:      price,
+		TargetPrice:     signal.TargetPrice.InexactFloat64(),
+		StopLossPrice:   signal.StopLossPrice.InexactFloat64(),
+		Status:          models.PaperTradeStatusOpen,
+		OpenedAt:        time.Now(),
+	}
+	if err := db.Create(trade).Error; err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+// ListPaperTrades returns the user's paper trades, latest first, with open ones marked to the
+// current price, and the totals of the whole bot
+func ListPaperTrades(db *gorm.DB, userID, status string) ([]models.PaperTrade, *PaperBotSummary, error) {
+	var trades []models.PaperTrade
+	if err := db.Where("user_id = ?", userID).Order("opened_at DESC").Find(&trades).Error; err != nil {
+		return nil, nil, err
+	}
+
+	summary := &PaperBotSummary{}
+	prices := make(map[string]float64)
+	var closedPnLPct float64
+	list := make([]models.PaperTrade, 0, len(trades))
+	for i := range trades {
+		t := &trades[i]
+		if t.Status == models.PaperTradeStatusOpen {
+			price, ok := prices[t.StockCode]
+			if !ok {
+				price = currentIdeaPrice(t.StockCode)
+				prices[t.StockCode] = price
+			}
+			t.LastPrice = price
+			t.PnLPercent, t.PnLAmount = paperTradePnL(t, price)
+			summary.Open++
+			summary.UnrealizedPnL += t.PnLAmount
+		} else {
+			summary.Closed++
+			summary.RealizedPnL += t.PnLAmount
+			closedPnLPct += t.PnLPercent
+			if t.PnLAmount > 0 {
+				summary.Wins++
+			}
+		}
+		if status == "" || t.Status == status {
+			list = append(list, *t)
+		}
+	}
+	summary.RealizedPnL = roundStrategist(summary.RealizedPnL)
+	summary.UnrealizedPnL = roundStrategist(summary.UnrealizedPnL)
+	if summary.Closed > 0 {
+		summary.WinRatePct = roundStrategist(float64(summary.Wins) / float64(summary.Closed) * 100)
+		summary.AvgClosedPnLPct = roundStrategist(closedPnLPct / float64(summary.Closed))
+	}
+	return list, summary, nil
+}
+
+// GetPaperTrade loads one of the user's paper trades
+func GetPaperTrade(db *gorm.DB, userID string, id uint) (*models.PaperTrade, error) {
+	var trade models.PaperTrade
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&trade).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPaperTradeNotFound
+		}
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// ClosePaperTrade closes an open paper trade at a price; at the current price when price is 0
+func ClosePaperTrade(db *gorm.DB, trade *models.PaperTrade, price float64, reason string) error {
+	if trade.Status != models.PaperTradeStatusOpen {
+		return fmt.Errorf("%w: the trade is already closed", ErrInvalidPaperTrade)
+	}
+	if price <= 0 {
+		price = currentIdeaPrice(trade.StockCode)
+	}
+	if price <= 0 {
+		return fmt.Errorf("%w: no current price for %s", ErrInvalidPaperTrade, trade.StockCode)
+	}
+	now := time.Now()
+	pct, amount := paperTradePnL(trade, price)
+	if err := db.Model(trade).Updates(map[string]interface{}{
+		"status":      models.PaperTradeStatusClosed,
+		"exit_price":  price,
+		"exit_reason": reason,
+		"pnl_percent": pct,
+		"pnl_amount":  amount,
+		"closed_at":   now,
+	}).Error; err != nil {
+		return err
+	}
+	trade.Status, trade.ExitPrice, trade.ExitReason = models.PaperTradeStatusClosed, price, reason
+	trade.PnLPercent, trade.PnLAmount, trade.ClosedAt = pct, amount, &now
+	return nil
+}
+
+// ClosePaperTradesForSignal closes the open paper trades of a signal that reached its target or
+// stop or expired, at the signal's close price. A superseded signal leaves its trades open.
+func ClosePaperTradesForSignal(db *gorm.DB, signal *models.SignalHistory, price float64, reason string) {
+	if signal.State == models.SignalStateSuperseded {
+		return
+	}
+	var trades []models.PaperTrade
+	if err := db.Where("signal_history_id = ? AND status = ?", signal.ID, models.PaperTradeStatusOpen).Find(&trades).Error; err != nil {
+		log.Printf("Failed to load paper trades of signal %d: %v", signal.ID, err)
+		return
+	}
+	for i := range trades {
+		if err := ClosePaperTrade(db, &trades[i], price, reason); err != nil {
+			log.Printf("Failed to close paper trade %d: %v", trades[i].ID, err)
+		}
+	}
+}
+
+func roundStrategist(v float64) float64 {
+	return math.Round(v*100) / 100
+}
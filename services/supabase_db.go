@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,6 +21,7 @@ type SupabaseDBClient struct {
 	AnonKey    string
 	ServiceKey string
 	httpClient *http.Client
+	ctx        context.Context // set by WithContext; requests use context.Background() otherwise
 }
 
 // AdminUserRecord represents an admin user from the database
@@ -49,12 +51,42 @@ func NewSupabaseDBClient() (*SupabaseDBClient, error) {
 		return nil, errors.New("SUPABASE_ANON_KEY or SUPABASE_SERVICE_KEY is required")
 	}
 
+	return NewSupabaseDBClientFor(supabaseURL, anonKey, serviceKey), nil
+}
+
+// NewSupabaseDBClientFor creates a client for a project URL and keys given by the caller instead
+// of the environment
+func NewSupabaseDBClientFor(supabaseURL, anonKey, serviceKey string) *SupabaseDBClient {
 	return &SupabaseDBClient{
 		URL:        supabaseURL,
 		AnonKey:    anonKey,
 		ServiceKey: serviceKey,
 		httpClient: &http.Client{Timeout: 30 * time.Second},
-	}, nil
+	}
+}
+
+// SetTransport replaces the transport of the client and returns the previous one
+func (c *SupabaseDBClient) SetTransport(rt http.RoundTripper) http.RoundTripper {
+	previous := c.httpClient.Transport
+	c.httpClient.Transport = rt
+	return previous
+}
+
+// WithContext returns a copy of the client whose requests are bound to ctx, so that they are
+// cancelled together with the HTTP request they serve
+func (c *SupabaseDBClient) WithContext(ctx context.Context) *SupabaseDBClient {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// newRequest creates a request bound to the client's context
+func (c *SupabaseDBClient) newRequest(method, target string, body io.Reader) (*http.Request, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return http.NewRequestWithContext(ctx, method, target, body)
 }
 
 // getAPIKey returns the best available API key (service key preferred)
@@ -71,7 +103,7 @@ func (c *SupabaseDBClient) GetAdminUserByUsername(username string) (*AdminUserRe
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_users?username=eq.%s&is_active=eq.true&limit=1",
 		c.URL, url.QueryEscape(username))
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -114,7 +146,7 @@ func (c *SupabaseDBClient) GetAdminUserByEmail(email string) (*AdminUserRecord,
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_users?email=eq.%s&is_active=eq.true&limit=1",
 		c.URL, url.QueryEscape(email))
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -164,7 +196,7 @@ func (c *SupabaseDBClient) UpdateLastLogin(userID int) error {
 	// PATCH request to update last_login_at
 	payload := `{"last_login_at": "` + time.Now().UTC().Format(time.RFC3339) + `"}`
 
-	req, err := http.NewRequest("PATCH", queryURL, nil)
+	req, err := c.newRequest("PATCH", queryURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -197,7 +229,7 @@ func (c *SupabaseDBClient) TestConnection() error {
 	// Try to query admin_users table with limit 0 just to test connection
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_users?limit=0", c.URL)
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -250,7 +282,7 @@ func (c *SupabaseDBClient) CreateAdminSession(session *AdminSessionRecord) error
 	}`, session.Token, session.AdminUser, session.IPAddress,
 		escapeJSON(session.UserAgent), session.ExpiresAt.UTC().Format(time.RFC3339))
 
-	req, err := http.NewRequest("POST", queryURL, strings.NewReader(payload))
+	req, err := c.newRequest("POST", queryURL, strings.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -289,7 +321,7 @@ func (c *SupabaseDBClient) GetAdminSessionByToken(token string) (*AdminSessionRe
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_sessions?token=eq.%s&limit=1",
 		c.URL, url.QueryEscape(token))
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -353,7 +385,7 @@ func (c *SupabaseDBClient) GetAdminSessionByToken(token string) (*AdminSessionRe
 func (c *SupabaseDBClient) GetAdminUserByID(userID int) (*AdminUserRecord, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_users?id=eq.%d&limit=1", c.URL, userID)
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -394,7 +426,7 @@ func (c *SupabaseDBClient) DeleteAdminSession(token string) error {
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_sessions?token=eq.%s",
 		c.URL, url.QueryEscape(token))
 
-	req, err := http.NewRequest("DELETE", queryURL, nil)
+	req, err := c.newRequest("DELETE", queryURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -418,7 +450,7 @@ func (c *SupabaseDBClient) ExtendAdminSession(token string, newExpiry time.Time)
 
 	payload := fmt.Sprintf(`{"expires_at": "%s"}`, newExpiry.UTC().Format(time.RFC3339))
 
-	req, err := http.NewRequest("PATCH", queryURL, strings.NewReader(payload))
+	req, err := c.newRequest("PATCH", queryURL, strings.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -443,7 +475,7 @@ func (c *SupabaseDBClient) CleanupExpiredSessions() error {
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_sessions?expires_at=lt.%s",
 		c.URL, url.QueryEscape(now))
 
-	req, err := http.NewRequest("DELETE", queryURL, nil)
+	req, err := c.newRequest("DELETE", queryURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -464,7 +496,7 @@ func (c *SupabaseDBClient) CleanupExpiredSessions() error {
 func (c *SupabaseDBClient) GetAdminUserCount() (int64, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/admin_users?select=count", c.URL)
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
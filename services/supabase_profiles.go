@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -67,6 +69,7 @@ type ProfilesListResponse struct {
 	Page       int           `json:"page"`
 	PageSize   int           `json:"page_size"`
 	TotalPages int           `json:"total_pages"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
 
 // SupabaseAuthUser represents a user in Supabase Auth
@@ -96,12 +99,29 @@ func (c *SupabaseDBClient) GetProfiles(page, pageSize int, search, sortBy, sortO
 	if pageSize < 1 || pageSize > 100 {
 		pageSize = 20
 	}
+	return c.GetProfilesPage(PageRequest{Page: page, PageSize: pageSize, Sort: sortBy + "." + sortOrder}, search, sortBy, sortOrder)
+}
 
-	offset := (page - 1) * pageSize
-
-	// Build query URL
-	queryURL := fmt.Sprintf("%s/rest/v1/profiles?select=*&order=%s.%s&limit=%d&offset=%d",
-		c.URL, sortBy, sortOrder, pageSize, offset)
+// GetProfilesPage fetches one page of profiles by page number or by a cursor on (sortBy, id).
+// The page's Sort must be "<sortBy>.<sortOrder>" so a cursor is only reused with its ordering.
+func (c *SupabaseDBClient) GetProfilesPage(page PageRequest, search, sortBy, sortOrder string) (*ProfilesListResponse, error) {
+	pageSize := page.PageSize
+
+	// Order by id as well so rows with the same sort value keep their order between pages, and
+	// fetch one extra row to know whether another page follows
+	queryURL := fmt.Sprintf("%s/rest/v1/profiles?select=*&order=%s.%s,id.%s&limit=%d",
+		c.URL, sortBy, sortOrder, sortOrder, pageSize+1)
+	if page.Cursor != nil {
+		op := "gt"
+		if sortOrder == "desc" {
+			op = "lt"
+		}
+		value, id := strconv.Quote(page.Cursor.Value), strconv.Quote(page.Cursor.ID)
+		queryURL += "&and=" + url.QueryEscape(fmt.Sprintf("(or(%s.%s.%s,and(%s.eq.%s,id.%s.%s)))",
+			sortBy, op, value, sortBy, value, op, id))
+	} else {
+		queryURL += fmt.Sprintf("&offset=%d", page.Offset())
+	}
 
 	// Add search filter if provided
 	if search != "" {
@@ -110,7 +130,7 @@ func (c *SupabaseDBClient) GetProfiles(page, pageSize int, search, sortBy, sortO
 		queryURL += searchFilter
 	}
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -156,20 +176,43 @@ func (c *SupabaseDBClient) GetProfiles(page, pageSize int, search, sortBy, sortO
 		totalPages++
 	}
 
+	var nextCursor string
+	if len(profiles) > pageSize {
+		profiles = profiles[:pageSize]
+		last := profiles[len(profiles)-1]
+		nextCursor = page.NewCursor(profileSortValue(last, sortBy), last.ID)
+	}
+
 	return &ProfilesListResponse{
 		Profiles:   profiles,
 		Total:      total,
-		Page:       page,
+		Page:       page.Page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
+		NextCursor: nextCursor,
 	}, nil
 }
 
+// profileSortValue returns the JSON value of a profile column as text for a cursor
+func profileSortValue(p UserProfile, column string) string {
+	data, _ := json.Marshal(p)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	switch v := fields[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 // GetProfileByID fetches a single profile by ID
 func (c *SupabaseDBClient) GetProfileByID(id string) (*UserProfile, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/profiles?id=eq.%s&limit=1", c.URL, url.QueryEscape(id))
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -209,7 +252,7 @@ func (c *SupabaseDBClient) GetProfileByID(id string) (*UserProfile, error) {
 func (c *SupabaseDBClient) GetProfileByEmail(email string) (*UserProfile, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/profiles?email=eq.%s&limit=1", c.URL, url.QueryEscape(email))
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -245,6 +288,22 @@ func (c *SupabaseDBClient) GetProfileByEmail(email string) (*UserProfile, error)
 	return &profiles[0], nil
 }
 
+// ActiveMembership returns the lowercased membership of a user, or free when the client is not
+// configured, the profile is missing or the membership has expired
+func (c *SupabaseDBClient) ActiveMembership(userID string) string {
+	if c == nil {
+		return RealtimeTierFree
+	}
+	profile, err := c.GetProfileByID(userID)
+	if err != nil || profile == nil || profile.Membership == "" {
+		return RealtimeTierFree
+	}
+	if profile.MembershipExpiresAt != nil && profile.MembershipExpiresAt.Before(time.Now()) {
+		return RealtimeTierFree
+	}
+	return strings.ToLower(profile.Membership)
+}
+
 // UpdateProfile updates an existing profile
 func (c *SupabaseDBClient) UpdateProfile(id string, input *UserProfileInput) (*UserProfile, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/profiles?id=eq.%s", c.URL, url.QueryEscape(id))
@@ -312,7 +371,7 @@ func (c *SupabaseDBClient) UpdateProfile(id string, input *UserProfileInput) (*U
 		return nil, fmt.Errorf("failed to marshal update data: %w", err)
 	}
 
-	req, err := http.NewRequest("PATCH", queryURL, bytes.NewReader(payload))
+	req, err := c.newRequest("PATCH", queryURL, bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -380,7 +439,7 @@ func (c *SupabaseDBClient) CreateProfile(id string, input *UserProfileInput) (*U
 		return nil, fmt.Errorf("failed to marshal profile data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", queryURL, bytes.NewReader(payload))
+	req, err := c.newRequest("POST", queryURL, bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -421,7 +480,7 @@ func (c *SupabaseDBClient) CreateProfile(id string, input *UserProfileInput) (*U
 func (c *SupabaseDBClient) DeleteProfile(id string) error {
 	queryURL := fmt.Sprintf("%s/rest/v1/profiles?id=eq.%s", c.URL, url.QueryEscape(id))
 
-	req, err := http.NewRequest("DELETE", queryURL, nil)
+	req, err := c.newRequest("DELETE", queryURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -447,7 +506,7 @@ func (c *SupabaseDBClient) DeleteProfile(id string) error {
 func (c *SupabaseDBClient) GetProfileCount() (int64, error) {
 	queryURL := fmt.Sprintf("%s/rest/v1/profiles?select=count", c.URL)
 
-	req, err := http.NewRequest("GET", queryURL, nil)
+	req, err := c.newRequest("GET", queryURL, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -532,7 +591,7 @@ func (c *SupabaseDBClient) CreateAuthUser(email, password string, metadata map[s
 		return nil, fmt.Errorf("failed to marshal user data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", authURL, bytes.NewReader(payload))
+	req, err := c.newRequest("POST", authURL, bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -572,7 +631,7 @@ func (c *SupabaseDBClient) DeleteAuthUser(id string) error {
 
 	authURL := fmt.Sprintf("%s/auth/v1/admin/users/%s", c.URL, url.PathEscape(id))
 
-	req, err := http.NewRequest("DELETE", authURL, nil)
+	req, err := c.newRequest("DELETE", authURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -602,7 +661,7 @@ func (c *SupabaseDBClient) GetAuthUser(id string) (*SupabaseAuthUser, error) {
 
 	authURL := fmt.Sprintf("%s/auth/v1/admin/users/%s", c.URL, url.PathEscape(id))
 
-	req, err := http.NewRequest("GET", authURL, nil)
+	req, err := c.newRequest("GET", authURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -650,7 +709,7 @@ func (c *SupabaseDBClient) UpdateAuthUserPassword(id, newPassword string) error
 		return fmt.Errorf("failed to marshal user data: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", authURL, bytes.NewReader(payload))
+	req, err := c.newRequest("PUT", authURL, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -681,7 +740,7 @@ func (c *SupabaseDBClient) ListAuthUsers(page, perPage int) (*SupabaseAuthUserLi
 
 	authURL := fmt.Sprintf("%s/auth/v1/admin/users?page=%d&per_page=%d", c.URL, page, perPage)
 
-	req, err := http.NewRequest("GET", authURL, nil)
+	req, err := c.newRequest("GET", authURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -730,7 +789,7 @@ func (c *SupabaseDBClient) GeneratePasswordResetLink(email string) (string, erro
 		return "", fmt.Errorf("failed to marshal link data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", authURL, bytes.NewReader(payload))
+	req, err := c.newRequest("POST", authURL, bytes.NewReader(payload))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -810,7 +869,7 @@ func (c *SupabaseDBClient) GetProfileStats() (map[string]interface{}, error) {
 
 	// Get active count
 	activeURL := fmt.Sprintf("%s/rest/v1/profiles?is_active=eq.true&select=count", c.URL)
-	activeReq, _ := http.NewRequest("GET", activeURL, nil)
+	activeReq, _ := c.newRequest("GET", activeURL, nil)
 	activeReq.Header.Set("apikey", c.getAPIKey())
 	activeReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.getAPIKey()))
 	activeReq.Header.Set("Prefer", "count=exact")
@@ -828,7 +887,7 @@ func (c *SupabaseDBClient) GetProfileStats() (map[string]interface{}, error) {
 
 	// Get banned count
 	bannedURL := fmt.Sprintf("%s/rest/v1/profiles?is_banned=eq.true&select=count", c.URL)
-	bannedReq, _ := http.NewRequest("GET", bannedURL, nil)
+	bannedReq, _ := c.newRequest("GET", bannedURL, nil)
 	bannedReq.Header.Set("apikey", c.getAPIKey())
 	bannedReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.getAPIKey()))
 	bannedReq.Header.Set("Prefer", "count=exact")
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSyncHistoryRetentionDays is how long sync history is kept unless SYNC_HISTORY_RETENTION_DAYS is set
+const DefaultSyncHistoryRetentionDays = 90
+
+// SyncHistoryStore persists and queries sync job runs
+type SyncHistoryStore struct {
+	db            *gorm.DB
+	retentionDays int
+}
+
+// SyncHistoryFilter selects sync history entries
+type SyncHistoryFilter struct {
+	SyncType string
+	Status   string
+	From     time.Time // inclusive, zero = unbounded
+	To       time.Time // exclusive, zero = unbounded
+	Page     int
+	PageSize int
+}
+
+// SyncHistoryStats aggregates the runs of one sync type
+type SyncHistoryStats struct {
+	SyncType      string     `json:"sync_type"`
+	Runs          int64      `json:"runs"`
+	Succeeded     int64      `json:"succeeded"`
+	Partial       int64      `json:"partial"`
+	Failed        int64      `json:"failed"`
+	SuccessRate   float64    `json:"success_rate"` // share of runs without failures, 0-100
+	AvgDurationMs float64    `json:"avg_duration_ms"`
+	ItemsSynced   int64      `json:"items_synced"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+}
+
+// Global sync history store
+var GlobalSyncHistory *SyncHistoryStore
+
+// InitSyncHistory initializes the sync history store
+func InitSyncHistory(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	retention := DefaultSyncHistoryRetentionDays
+	if v, err := strconv.Atoi(os.Getenv("SYNC_HISTORY_RETENTION_DAYS")); err == nil && v > 0 {
+		retention = v
+	}
+
+	GlobalSyncHistory = &SyncHistoryStore{db: db, retentionDays: retention}
+	return nil
+}
+
+// RecordSync stores the outcome of a sync run. It is a no-op when the store is not initialized,
+// so sync jobs keep working without a database.
+func RecordSync(syncType string, startedAt time.Time, total, succeeded, failed int, syncErr error) {
+	if GlobalSyncHistory == nil {
+		return
+	}
+
+	entry := models.SyncHistory{
+		SyncType:     syncType,
+		Status:       models.SyncStatusSuccess,
+		StartedAt:    startedAt,
+		FinishedAt:   time.Now(),
+		TotalItems:   total,
+		SuccessItems: succeeded,
+		FailedItems:  failed,
+	}
+	entry.DurationMs = entry.FinishedAt.Sub(startedAt).Milliseconds()
+	switch {
+	case syncErr != nil:
+		entry.Status = models.SyncStatusFailed
+		entry.Error = syncErr.Error()
+	case failed > 0 && succeeded == 0:
+		entry.Status = models.SyncStatusFailed
+	case failed > 0:
+		entry.Status = models.SyncStatusPartial
+	}
+
+	if err := GlobalSyncHistory.db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record %s sync history: %v", syncType, err)
+	}
+}
+
+// RetentionDays returns how many days of history are kept
+func (s *SyncHistoryStore) RetentionDays() int {
+	return s.retentionDays
+}
+
+func (s *SyncHistoryStore) filtered(filter SyncHistoryFilter) *gorm.DB {
+	query := s.db.Model(&models.SyncHistory{})
+	if filter.SyncType != "" {
+		query = query.Where("sync_type = ?", filter.SyncType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("started_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("started_at < ?", filter.To)
+	}
+	return query
+}
+
+// List returns sync runs newest first with the total match count
+func (s *SyncHistoryStore) List(filter SyncHistoryFilter) ([]models.SyncHistory, int64, error) {
+	var total int64
+	if err := s.filtered(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var history []models.SyncHistory
+	err := s.filtered(filter).Order("started_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&history).Error
+	return history, total, err
+}
+
+// Stats aggregates the matching runs per sync type. Status and paging in filter are ignored.
+func (s *SyncHistoryStore) Stats(filter SyncHistoryFilter) ([]SyncHistoryStats, error) {
+	filter.Status = ""
+
+	var stats []SyncHistoryStats
+	err := s.filtered(filter).Select(`sync_type,
+		COUNT(*) AS runs,
+		COUNT(*) FILTER (WHERE status = ?) AS succeeded,
+		COUNT(*) FILTER (WHERE status = ?) AS partial,
+		COUNT(*) FILTER (WHERE status = ?) AS failed,
+		COALESCE(AVG(duration_ms), 0) AS avg_duration_ms,
+		COALESCE(SUM(success_items), 0) AS items_synced,
+		MAX(started_at) AS last_run_at,
+		MAX(started_at) FILTER (WHERE status = ?) AS last_success_at`,
+		models.SyncStatusSuccess, models.SyncStatusPartial, models.SyncStatusFailed, models.SyncStatusSuccess).
+		Group("sync_type").Order("sync_type").Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		if stats[i].Runs > 0 {
+			stats[i].SuccessRate = float64(stats[i].Succeeded) / float64(stats[i].Runs) * 100
+		}
+	}
+	return stats, nil
+}
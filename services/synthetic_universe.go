@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Benchmark universe size: about the number of listed stocks on HOSE, HNX and UPCOM, and a year
+// of sessions plus the warm-up the 200-day average needs
+const (
+	BenchmarkUniverseStocks = 1700
+	BenchmarkUniverseDays   = 260
+)
+
+// SyntheticUniverse generates random-walk daily prices, newest first like the price store, for
+// stocks plus the VN-Index, and returns them with the latest trading date. The same seed always
+// gives the same prices, so benchmark runs compare across machines and commits.
+func SyntheticUniverse(stocks, days int, seed int64) (map[string]*StockPriceFile, string) {
+	rng := rand.New(rand.NewSource(seed))
+
+	// Weekdays ending on the last one before today, newest first
+	dates := make([]string, 0, days)
+	for d := time.Now().AddDate(0, 0, -1); len(dates) < days; d = d.AddDate(0, 0, -1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			dates = append(dates, d.Format(PriceDateFormat))
+		}
+	}
+
+	series := func(code string, start, drift, volatility, volume float64) *StockPriceFile {
+		prices := make([]StockPriceData, days)
+		price := start
+		for i := days - 1; i >= 0; i-- {
+			open := price
+			price = math.Max(0.1, price*(1+drift+volatility*rng.NormFloat64()))
+			high := math.Max(open, price) * (1 + volatility*rng.Float64()/2)
+			low := math.Min(open, price) * (1 - volatility*rng.Float64()/2)
+			vol := volume * math.Exp(0.5*rng.NormFloat64())
+			prices[i] = StockPriceData{
+				Code: code, Date: dates[i],
+				Open: open, High: high, Low: low, Close: price,
+				AdOpen: open, AdHigh: high, AdLow: low, AdClose: price,
+				NmVolume: vol, NmValue: vol * price * PriceUnitVND,
+			}
+		}
+		return &StockPriceFile{Code: code, DataCount: days, LastUpdated: dates[0], Prices: prices}
+	}
+
+	universe := make(map[string]*StockPriceFile, stocks+1)
+	universe[MarketIndexCode] = series(MarketIndexCode, 1200, 0.0003, 0.01, 5e8)
+	for i := 0; i < stocks; i++ {
+		code := fmt.Sprintf("S%04d", i)
+		universe[code] = series(code, 5+rng.Float64()*95, 0.001*rng.NormFloat64(), 0.01+0.03*rng.Float64(), math.Exp(10+3*rng.Float64()))
+	}
+	return universe, dates[0]
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoadSystemConfig decodes the value stored under key into out; found is false when the key is absent
+func LoadSystemConfig(db *gorm.DB, key string, out interface{}) (found bool, err error) {
+	if db == nil {
+		return false, fmt.Errorf("database not available")
+	}
+
+	var row models.SystemConfig
+	if err := db.Where("key = ?", key).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(row.Value), out); err != nil {
+		return true, fmt.Errorf("invalid %s config: %w", key, err)
+	}
+	return true, nil
+}
+
+// SaveSystemConfig stores value as JSON under key, replacing any previous value
+func SaveSystemConfig(db *gorm.DB, key string, value interface{}) error {
+	if db == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	row := models.SystemConfig{Key: key, Value: string(data), UpdatedAt: time.Now()}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&row).Error
+}
@@ -0,0 +1,120 @@
+#!/usr/bin/env python3
+"""Reference values for stock_indicator_service_test.go.
+
+Implements the TA-Lib definitions of SMA, EMA (seeded with the SMA of the first period closes),
+RSI (Wilder smoothing) and MACD (EMA of the MACD line for the signal), independently of the Go
+code, and the RS percentile ranks as specified for CalculateRSRanks. Series are generated
+deterministically and written oldest first here, newest first in golden.json like the price files.
+
+Regenerate with: python3 services/testdata/indicators/reference.py > services/testdata/indicators/golden.json
+"""
+import json
+import math
+
+
+def round_half_away(x, digits=0):
+    m = 10 ** digits
+    return math.copysign(math.floor(abs(x) * m + 0.5), x) / m
+
+
+def sma(closes, period):
+    return sum(closes[-period:]) / period
+
+
+def ema_series(closes, period):
+    k = 2.0 / (period + 1)
+    out = [None] * len(closes)
+    out[period - 1] = sum(closes[:period]) / period
+    for i in range(period, len(closes)):
+        out[i] = (closes[i] - out[i - 1]) * k + out[i - 1]
+    return out
+
+
+def rsi(closes, period):
+    changes = [closes[i] - closes[i - 1] for i in range(1, len(closes))]
+    gain = sum(max(c, 0) for c in changes[:period]) / period
+    loss = sum(max(-c, 0) for c in changes[:period]) / period
+    for c in changes[period:]:
+        gain = (gain * (period - 1) + max(c, 0)) / period
+        loss = (loss * (period - 1) + max(-c, 0)) / period
+    if loss == 0:
+        return 100.0
+    return 100 - 100 / (1 + gain / loss)
+
+
+def macd(closes, fast, slow, signal):
+    fast_ema = ema_series(closes, fast)
+    slow_ema = ema_series(closes, slow)
+    line = [fast_ema[i] - slow_ema[i] for i in range(slow - 1, len(closes))]
+    sig = ema_series(line, signal)[-1]
+    return line[-1], sig, line[-1] - sig
+
+
+def series(name, n, f):
+    return name, [round(f(i), 2) for i in range(n)]
+
+
+SERIES = [
+    series("uptrend_noisy", 260, lambda i: 50 + 0.08 * i + 3 * math.sin(i / 7) + 1.5 * math.sin(i / 2.3)),
+    series("downtrend_noisy", 260, lambda i: 120 - 0.15 * i + 2 * math.sin(i / 5) + math.cos(i / 1.7)),
+    series("sideways", 260, lambda i: 30 + 2.5 * math.sin(i / 4) + 0.8 * math.sin(i * 1.3)),
+    series("monotonic_up", 60, lambda i: 10 + 0.5 * i),
+]
+
+
+def indicator_cases():
+    cases = []
+    for name, closes in SERIES:
+        case = {"name": name, "closes": list(reversed(closes)), "ma": {}, "ema": {}, "rsi": {}}
+        for p in (5, 10, 20, 50, 200):
+            if len(closes) >= p:
+                case["ma"][str(p)] = sma(closes, p)
+        for p in (12, 26, 50):
+            case["ema"][str(p)] = ema_series(closes, p)[-1]
+        for p in (6, 14):
+            case["rsi"][str(p)] = rsi(closes, p)
+        if len(closes) >= 26 + 9:
+            m, s, h = macd(closes, 12, 26, 9)
+            case["macd"] = {"fast": 12, "slow": 26, "signal": 9, "macd": m, "signal_line": s, "hist": h}
+        cases.append(case)
+    return cases
+
+
+# RS rank universe: (code, avg_trading_val, type, rs_3d, rs_1m, rs_3m, rs_1y)
+RS_UNIVERSE = [
+    ("AAA", 5.0, "stock", 1.2, 4.0, 10.0, 25.0),
+    ("BBB", 3.0, "stock", -0.5, 2.0, 12.0, -5.0),
+    ("CCC", 8.0, "stock", 1.2, -3.0, 4.0, 40.0),
+    ("DDD", 0.4, "stock", 9.0, 9.0, 9.0, 9.0),  # below the trading value floor
+    ("EEE", 2.0, "etf", 0.3, 1.0, 1.0, 8.0),
+    ("FFF", 6.0, "cw", 15.0, 30.0, 50.0, 90.0),  # covered warrants are never ranked
+    ("GGG", 1.5, "stock", 2.5, 4.0, -2.0, 12.0),
+]
+MIN_TRADING_VAL = 1.0
+
+
+def rs_ranks():
+    ranked = [s for s in RS_UNIVERSE if s[1] >= MIN_TRADING_VAL and s[2] != "cw"]
+    ranks = {s[0]: [0, 0, 0, 0] for s in RS_UNIVERSE}
+    for period in range(4):
+        ordered = sorted(ranked, key=lambda s: (s[3 + period], s[0]))
+        for i, s in enumerate(ordered):
+            rank = round_half_away((i + 1) / len(ordered) * 100)
+            ranks[s[0]][period] = min(max(rank, 1), 100)
+    out = {}
+    for code, r in ranks.items():
+        avg = round_half_away(sum(r) / 4) if any(r) else 0
+        out[code] = {"rs_3d_rank": r[0], "rs_1m_rank": r[1], "rs_3m_rank": r[2], "rs_1y_rank": r[3], "rs_avg": avg}
+    return out
+
+
+if __name__ == "__main__":
+    golden = {
+        "indicators": indicator_cases(),
+        "rs_universe": [
+            {"code": c, "avg_trading_val": v, "type": t, "rs_3d": a, "rs_1m": b, "rs_3m": d, "rs_1y": e}
+            for c, v, t, a, b, d, e in RS_UNIVERSE
+        ],
+        "rs_ranks": rs_ranks(),
+    }
+    print(json.dumps(golden, indent=2))
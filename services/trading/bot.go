@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"go_backend_project/models"
+	"go_backend_project/services"
 	"go_backend_project/services/analysis"
+	"go_backend_project/services/units"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -108,18 +110,11 @@ func (bot *TradingBot) loadStrategies() error {
 
 // executeTradingCycle executes one trading cycle
 func (bot *TradingBot) executeTradingCycle() {
-	// Check if market is open (Vietnamese stock market: 9:00-15:00)
-	now := time.Now()
-	hour := now.Hour()
-	if hour < 9 || hour >= 15 {
+	// Check if market is open (trading day and matching session)
+	if !services.MarketCalendar().IsMarketOpen(time.Now()) {
 		return // Market closed
 	}
 
-	// Skip weekends
-	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
-		return
-	}
-
 	log.Println("Executing trading cycle...")
 
 	// Get all stocks
@@ -139,7 +134,7 @@ func (bot *TradingBot) executeTradingCycle() {
 func (bot *TradingBot) executeStrategy(strategy *models.TradingStrategy, stocks []models.Stock) {
 	for _, stock := range stocks {
 		// Generate signal
-		signal := bot.generateSignal(strategy, stock.ID)
+		signal := bot.generateSignal(strategy, &stock)
 		if signal == nil {
 			continue
 		}
@@ -154,8 +149,9 @@ func (bot *TradingBot) executeStrategy(strategy *models.TradingStrategy, stocks
 }
 
 // generateSignal generates a trading signal for a stock
-func (bot *TradingBot) generateSignal(strategy *models.TradingStrategy, stockID uint) *models.Signal {
+func (bot *TradingBot) generateSignal(strategy *models.TradingStrategy, stock *models.Stock) *models.Signal {
 	now := time.Now()
+	stockID := stock.ID
 
 	// Get latest price
 	var latestPrice models.StockPrice
@@ -193,13 +189,13 @@ func (bot *TradingBot) generateSignal(strategy *models.TradingStrategy, stockID
 		return nil
 	}
 
-	// Calculate target price and stop loss
+	// Calculate target price and stop loss, on the stock's tick size
 	if signalType == "BUY" {
-		targetPrice = latestPrice.Close.Mul(decimal.NewFromFloat(1.05)) // 5% target
-		stopLoss = latestPrice.Close.Mul(decimal.NewFromFloat(0.97))    // 3% stop loss
+		targetPrice = services.RoundPriceVND(stock.Symbol, units.Offset(latestPrice.Close, decimal.NewFromInt(5))) // 5% target
+		stopLoss = services.RoundPriceVND(stock.Symbol, units.Offset(latestPrice.Close, decimal.NewFromInt(-3)))   // 3% stop loss
 	} else if signalType == "SELL" {
-		targetPrice = latestPrice.Close.Mul(decimal.NewFromFloat(0.95))
-		stopLoss = latestPrice.Close.Mul(decimal.NewFromFloat(1.03))
+		targetPrice = services.RoundPriceVND(stock.Symbol, units.Offset(latestPrice.Close, decimal.NewFromInt(-5)))
+		stopLoss = services.RoundPriceVND(stock.Symbol, units.Offset(latestPrice.Close, decimal.NewFromInt(3)))
 	}
 
 	signal := &models.Signal{
@@ -392,6 +388,8 @@ func (bot *TradingBot) executeBuyOrder(signal *models.Signal, stock *models.Stoc
 	// Calculate quantity based on risk management
 	// This is simplified - in production, use proper position sizing
 	quantity := int64(100) // Example: 100 shares
+	// Limit orders must be placed on a tick
+	price := services.RoundPriceVND(stock.Symbol, signal.Price)
 
 	trade := models.Trade{
 		UserID:     1, // System user
@@ -399,8 +397,8 @@ func (bot *TradingBot) executeBuyOrder(signal *models.Signal, stock *models.Stoc
 		StrategyID: strategy.ID,
 		Type:       "BUY",
 		Quantity:   quantity,
-		Price:      signal.Price,
-		Commission: signal.Price.Mul(decimal.NewFromInt(quantity)).Mul(decimal.NewFromFloat(0.0015)),
+		Price:      price,
+		Commission: units.RoundVND(price.Mul(decimal.NewFromInt(quantity)).Mul(decimal.NewFromFloat(0.0015))),
 		Status:     "pending",
 		OrderType:  "limit",
 	}
@@ -412,7 +410,7 @@ func (bot *TradingBot) executeBuyOrder(signal *models.Signal, stock *models.Stoc
 		return
 	}
 
-	log.Printf("Buy order created for %s: %d shares at %s", stock.Symbol, quantity, signal.Price.StringFixed(2))
+	log.Printf("Buy order created for %s: %d shares at %s", stock.Symbol, quantity, price.String())
 }
 
 // executeSellOrder executes a sell order
@@ -426,14 +424,17 @@ func (bot *TradingBot) executeSellOrder(signal *models.Signal, stock *models.Sto
 
 	log.Printf("SELL signal for %s: %s (confidence: %s)", stock.Symbol, signal.Reason, signal.Confidence.StringFixed(2))
 
+	// Limit orders must be placed on a tick
+	price := services.RoundPriceVND(stock.Symbol, signal.Price)
+
 	trade := models.Trade{
 		UserID:     1, // System user
 		StockID:    stock.ID,
 		StrategyID: strategy.ID,
 		Type:       "SELL",
 		Quantity:   portfolio.Quantity,
-		Price:      signal.Price,
-		Commission: signal.Price.Mul(decimal.NewFromInt(portfolio.Quantity)).Mul(decimal.NewFromFloat(0.0015)),
+		Price:      price,
+		Commission: units.RoundVND(price.Mul(decimal.NewFromInt(portfolio.Quantity)).Mul(decimal.NewFromFloat(0.0015))),
 		Status:     "pending",
 		OrderType:  "limit",
 	}
@@ -445,7 +446,7 @@ func (bot *TradingBot) executeSellOrder(signal *models.Signal, stock *models.Sto
 		return
 	}
 
-	log.Printf("Sell order created for %s: %d shares at %s", stock.Symbol, portfolio.Quantity, signal.Price.StringFixed(2))
+	log.Printf("Sell order created for %s: %d shares at %s", stock.Symbol, portfolio.Quantity, price.String())
 }
 
 // ManualTrade allows manual trade execution
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const TradingCalendarFile = "data/market_holidays.json"
+
+// Session names of a Vietnamese trading day (HOSE schedule)
+const (
+	SessionPreOpen    = "pre_open"
+	SessionATO        = "ato"
+	SessionMorning    = "continuous_morning"
+	SessionLunchBreak = "lunch_break"
+	SessionAfternoon  = "continuous_afternoon"
+	SessionATC        = "atc"
+	SessionPutThrough = "put_through"
+	SessionClosed     = "closed"
+)
+
+// Exchanges with their own session schedules
+const (
+	ExchangeHOSE  = "HOSE"
+	ExchangeHNX   = "HNX"
+	ExchangeUPCOM = "UPCOM"
+)
+
+// Exchanges lists the exchanges in the order they are reported
+var Exchanges = []string{ExchangeHOSE, ExchangeHNX, ExchangeUPCOM}
+
+// MarketHoliday is a full-day closure or a half day (morning session only)
+type MarketHoliday struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Name    string `json:"name"`
+	HalfDay bool   `json:"half_day"`
+}
+
+// MarketSession is one phase of a trading day in market local time
+type MarketSession struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// TradingDay describes the sessions of one trading date
+type TradingDay struct {
+	Date     string          `json:"date"`
+	Weekday  string          `json:"weekday"`
+	HalfDay  bool            `json:"half_day"`
+	Note     string          `json:"note,omitempty"`
+	Sessions []MarketSession `json:"sessions"`
+}
+
+// sessionTemplate is a session defined by HH:MM offsets within a day
+type sessionTemplate struct {
+	name       string
+	start, end string
+}
+
+// fullDaySessions follows the HOSE schedule
+var fullDaySessions = []sessionTemplate{
+	{SessionATO, "09:00", "09:15"},
+	{SessionMorning, "09:15", "11:30"},
+	{SessionLunchBreak, "11:30", "13:00"},
+	{SessionAfternoon, "13:00", "14:30"},
+	{SessionATC, "14:30", "14:45"},
+	{SessionPutThrough, "14:45", "15:00"},
+}
+
+var halfDaySessions = []sessionTemplate{
+	{SessionATO, "09:00", "09:15"},
+	{SessionMorning, "09:15", "11:30"},
+}
+
+// HNX opens straight into continuous matching and closes with an ATC
+var hnxFullDaySessions = []sessionTemplate{
+	{SessionMorning, "09:00", "11:30"},
+	{SessionLunchBreak, "11:30", "13:00"},
+	{SessionAfternoon, "13:00", "14:30"},
+	{SessionATC, "14:30", "14:45"},
+	{SessionPutThrough, "14:45", "15:00"},
+}
+
+// UPCOM matches continuously all day, without opening or closing auctions
+var upcomFullDaySessions = []sessionTemplate{
+	{SessionMorning, "09:00", "11:30"},
+	{SessionLunchBreak, "11:30", "13:00"},
+	{SessionAfternoon, "13:00", "15:00"},
+}
+
+var upcomHalfDaySessions = []sessionTemplate{
+	{SessionMorning, "09:00", "11:30"},
+}
+
+// exchangeSchedules maps each exchange to its full-day and half-day sessions
+var exchangeSchedules = map[string][2][]sessionTemplate{
+	ExchangeHOSE:  {fullDaySessions, halfDaySessions},
+	ExchangeHNX:   {hnxFullDaySessions, upcomHalfDaySessions},
+	ExchangeUPCOM: {upcomFullDaySessions, upcomHalfDaySessions},
+}
+
+// defaultMarketHolidays are exchange closures used when no calendar file exists
+var defaultMarketHolidays = []MarketHoliday{
+	{Date: "2025-01-01", Name: "New Year"},
+	{Date: "2025-01-27", Name: "Lunar New Year"},
+	{Date: "2025-01-28", Name: "Lunar New Year"},
+	{Date: "2025-01-29", Name: "Lunar New Year"},
+	{Date: "2025-01-30", Name: "Lunar New Year"},
+	{Date: "2025-01-31", Name: "Lunar New Year"},
+	{Date: "2025-04-07", Name: "Hung Kings Commemoration"},
+	{Date: "2025-04-30", Name: "Reunification Day"},
+	{Date: "2025-05-01", Name: "Labour Day"},
+	{Date: "2025-05-02", Name: "Labour Day (compensatory)"},
+	{Date: "2025-09-01", Name: "National Day"},
+	{Date: "2025-09-02", Name: "National Day"},
+	{Date: "2026-01-01", Name: "New Year"},
+	{Date: "2026-01-02", Name: "New Year (compensatory)"},
+	{Date: "2026-02-16", Name: "Lunar New Year"},
+	{Date: "2026-02-17", Name: "Lunar New Year"},
+	{Date: "2026-02-18", Name: "Lunar New Year"},
+	{Date: "2026-02-19", Name: "Lunar New Year"},
+	{Date: "2026-02-20", Name: "Lunar New Year"},
+	{Date: "2026-04-27", Name: "Hung Kings Commemoration (compensatory)"},
+	{Date: "2026-04-30", Name: "Reunification Day"},
+	{Date: "2026-05-01", Name: "Labour Day"},
+	{Date: "2026-09-01", Name: "National Day"},
+	{Date: "2026-09-02", Name: "National Day"},
+	{Date: "2027-01-01", Name: "New Year"},
+	{Date: "2027-02-04", Name: "Lunar New Year"},
+	{Date: "2027-02-05", Name: "Lunar New Year"},
+	{Date: "2027-02-08", Name: "Lunar New Year"},
+	{Date: "2027-02-09", Name: "Lunar New Year"},
+	{Date: "2027-02-10", Name: "Lunar New Year"},
+	{Date: "2027-04-16", Name: "Hung Kings Commemoration"},
+	{Date: "2027-04-30", Name: "Reunification Day"},
+	{Date: "2027-05-03", Name: "Labour Day (compensatory)"},
+	{Date: "2027-09-02", Name: "National Day"},
+	{Date: "2027-09-03", Name: "National Day"},
+}
+
+// TradingCalendar answers trading-day and session questions for the Vietnamese market
+type TradingCalendar struct {
+	location *time.Location
+	holidays map[string]MarketHoliday
+	lastYear int // last year with a configured holiday
+	mu       sync.RWMutex
+
+	uncoveredMu    sync.Mutex
+	uncoveredYears map[int]bool // years past lastYear already reported
+}
+
+// Global trading calendar
+var GlobalTradingCalendar *TradingCalendar
+
+// InitTradingCalendar loads market holidays and initializes the global calendar
+func InitTradingCalendar() error {
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		// Minimal containers may ship without tzdata; Vietnam has no DST
+		loc = time.FixedZone("ICT", 7*3600)
+	}
+
+	cal := &TradingCalendar{location: loc}
+	holidays, err := LoadMarketHolidays()
+	if err != nil {
+		log.Printf("No market holiday file found, using built-in holidays: %v", err)
+		holidays = defaultMarketHolidays
+	}
+	cal.setHolidays(holidays)
+
+	GlobalTradingCalendar = cal
+	log.Printf("Trading calendar initialized with %d holidays", len(holidays))
+	return nil
+}
+
+// MarketCalendar returns the global calendar, initializing it on first use
+func MarketCalendar() *TradingCalendar {
+	if GlobalTradingCalendar == nil {
+		InitTradingCalendar()
+	}
+	return GlobalTradingCalendar
+}
+
+// LoadMarketHolidays reads the holiday file
+func LoadMarketHolidays() ([]MarketHoliday, error) {
+	data, err := os.ReadFile(TradingCalendarFile)
+	if err != nil {
+		return nil, err
+	}
+	var holidays []MarketHoliday
+	if err := json.Unmarshal(data, &holidays); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// SetHolidays validates, saves and applies a new holiday list
+func (tc *TradingCalendar) SetHolidays(holidays []MarketHoliday) error {
+	for _, h := range holidays {
+		if _, err := time.Parse(PriceDateFormat, h.Date); err != nil {
+			return fmt.Errorf("invalid holiday date: %s", h.Date)
+		}
+	}
+
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date < holidays[j].Date })
+
+	if err := os.MkdirAll(filepath.Dir(TradingCalendarFile), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(holidays, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(TradingCalendarFile, data, 0644); err != nil {
+		return err
+	}
+
+	tc.setHolidays(holidays)
+	return nil
+}
+
+func (tc *TradingCalendar) setHolidays(holidays []MarketHoliday) {
+	m := make(map[string]MarketHoliday, len(holidays))
+	lastYear := 0
+	for _, h := range holidays {
+		m[h.Date] = h
+		if t, err := time.Parse(PriceDateFormat, h.Date); err == nil && t.Year() > lastYear {
+			lastYear = t.Year()
+		}
+	}
+	tc.mu.Lock()
+	tc.holidays = m
+	tc.lastYear = lastYear
+	tc.mu.Unlock()
+}
+
+// LastHolidayYear returns the last year the calendar has holidays for; later weekdays are all
+// treated as trading days
+func (tc *TradingCalendar) LastHolidayYear() int {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return tc.lastYear
+}
+
+// reportUncoveredYear logs an error and alerts the operators, once per year, when a date past the
+// last configured year is looked up, since its holidays would silently count as trading days
+func (tc *TradingCalendar) reportUncoveredYear(year int) {
+	lastYear := tc.LastHolidayYear()
+	if year <= lastYear {
+		return
+	}
+
+	tc.uncoveredMu.Lock()
+	if tc.uncoveredYears[year] {
+		tc.uncoveredMu.Unlock()
+		return
+	}
+	if tc.uncoveredYears == nil {
+		tc.uncoveredYears = make(map[int]bool)
+	}
+	tc.uncoveredYears[year] = true
+	tc.uncoveredMu.Unlock()
+
+	body := fmt.Sprintf("The trading calendar has no holidays for %d (last configured year: %d), so every weekday of %d is treated as a trading day. Add the %d holidays with PUT /admin/api/market/holidays.",
+		year, lastYear, year, year)
+	log.Printf("ERROR: %s", body)
+	go func() {
+		if err := GlobalOperatorAlerts.Send(fmt.Sprintf("Trading calendar missing %d holidays", year), body); err != nil {
+			log.Printf("Failed to send trading calendar alert: %v", err)
+		}
+	}()
+}
+
+// Holidays returns all configured holidays sorted by date
+func (tc *TradingCalendar) Holidays() []MarketHoliday {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+
+	result := make([]MarketHoliday, 0, len(tc.holidays))
+	for _, h := range tc.holidays {
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
+
+// Location returns the market time zone
+func (tc *TradingCalendar) Location() *time.Location {
+	return tc.location
+}
+
+// holiday returns the holiday entry for the market-local date of t
+func (tc *TradingCalendar) holiday(t time.Time) (MarketHoliday, bool) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	h, ok := tc.holidays[t.In(tc.location).Format(PriceDateFormat)]
+	return h, ok
+}
+
+// IsTradingDay reports whether the market trades on the market-local date of t
+func (tc *TradingCalendar) IsTradingDay(t time.Time) bool {
+	local := t.In(tc.location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	h, ok := tc.holiday(local)
+	if !ok {
+		tc.reportUncoveredYear(local.Year())
+	}
+	return !ok || h.HalfDay
+}
+
+// IsTradingDate is IsTradingDay for a YYYY-MM-DD date string
+func (tc *TradingCalendar) IsTradingDate(date string) bool {
+	t, err := time.ParseInLocation(PriceDateFormat, date, tc.location)
+	if err != nil {
+		return false
+	}
+	return tc.IsTradingDay(t)
+}
+
+// NextTradingDay returns the first trading day strictly after t (at market-local midnight)
+func (tc *TradingCalendar) NextTradingDay(t time.Time) time.Time {
+	return tc.AddTradingDays(t, 1)
+}
+
+// PreviousTradingDay returns the last trading day strictly before t (at market-local midnight)
+func (tc *TradingCalendar) PreviousTradingDay(t time.Time) time.Time {
+	return tc.AddTradingDays(t, -1)
+}
+
+// AddTradingDays moves n trading days from t; negative n moves backwards
+func (tc *TradingCalendar) AddTradingDays(t time.Time, n int) time.Time {
+	local := t.In(tc.location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, tc.location)
+
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		day = day.AddDate(0, 0, step)
+		if tc.IsTradingDay(day) {
+			n--
+		}
+	}
+	return day
+}
+
+// TradingDaysBetween counts trading days in (from, to]
+func (tc *TradingCalendar) TradingDaysBetween(from, to time.Time) int {
+	if to.Before(from) {
+		return -tc.TradingDaysBetween(to, from)
+	}
+
+	count := 0
+	day := from.In(tc.location)
+	end := to.In(tc.location).Format(PriceDateFormat)
+	for {
+		day = day.AddDate(0, 0, 1)
+		if day.Format(PriceDateFormat) > end {
+			return count
+		}
+		if tc.IsTradingDay(day) {
+			count++
+		}
+	}
+}
+
+// Day returns the HOSE sessions of the market-local date of t; non-trading days have no sessions
+func (tc *TradingCalendar) Day(t time.Time) TradingDay {
+	return tc.ExchangeDay(ExchangeHOSE, t)
+}
+
+// ExchangeDay returns the sessions of an exchange on the market-local date of t; unknown
+// exchanges follow the HOSE schedule
+func (tc *TradingCalendar) ExchangeDay(exchange string, t time.Time) TradingDay {
+	schedule, ok := exchangeSchedules[exchange]
+	if !ok {
+		schedule = exchangeSchedules[ExchangeHOSE]
+	}
+
+	local := t.In(tc.location)
+	day := TradingDay{
+		Date:     local.Format(PriceDateFormat),
+		Weekday:  local.Weekday().String(),
+		Sessions: []MarketSession{},
+	}
+
+	h, isHoliday := tc.holiday(local)
+	if isHoliday {
+		day.Note = h.Name
+		day.HalfDay = h.HalfDay
+	}
+	if !tc.IsTradingDay(local) {
+		return day
+	}
+
+	templates := schedule[0]
+	if day.HalfDay {
+		templates = schedule[1]
+	}
+	for _, st := range templates {
+		day.Sessions = append(day.Sessions, MarketSession{
+			Name:  st.name,
+			Start: tc.atClock(local, st.start),
+			End:   tc.atClock(local, st.end),
+		})
+	}
+	return day
+}
+
+// SessionAt returns the HOSE session in progress at t
+func (tc *TradingCalendar) SessionAt(t time.Time) string {
+	return sessionIn(tc.Day(t), t)
+}
+
+// sessionIn returns the session of day in progress at t
+func sessionIn(day TradingDay, t time.Time) string {
+	if len(day.Sessions) == 0 {
+		return SessionClosed
+	}
+	if t.Before(day.Sessions[0].Start) {
+		return SessionPreOpen
+	}
+	for _, s := range day.Sessions {
+		if !t.Before(s.Start) && t.Before(s.End) {
+			return s.Name
+		}
+	}
+	return SessionClosed
+}
+
+// IsMarketOpen reports whether orders are being matched at t (ATO through ATC, excluding lunch)
+func (tc *TradingCalendar) IsMarketOpen(t time.Time) bool {
+	return isMatchingSession(tc.SessionAt(t))
+}
+
+// isMatchingSession reports whether orders are matched during a session
+func isMatchingSession(session string) bool {
+	switch session {
+	case SessionATO, SessionMorning, SessionAfternoon, SessionATC:
+		return true
+	}
+	return false
+}
+
+// ExchangeStatus is the state of one exchange at a point in time
+type ExchangeStatus struct {
+	Exchange          string     `json:"exchange"`
+	Session           string     `json:"session"`
+	IsOpen            bool       `json:"is_open"`
+	SessionEndsAt     *time.Time `json:"session_ends_at,omitempty"` // nil while closed
+	NextSession       string     `json:"next_session"`
+	NextSessionAt     time.Time  `json:"next_session_at"`
+	SecondsToNext     int64      `json:"seconds_to_next"`
+	NextOpenAt        time.Time  `json:"next_open_at"` // start of the next matching session
+	SecondsToNextOpen int64      `json:"seconds_to_next_open"`
+}
+
+// ExchangeStatusAt returns the session an exchange is in at t and when the next one starts
+func (tc *TradingCalendar) ExchangeStatusAt(exchange string, t time.Time) ExchangeStatus {
+	day := tc.ExchangeDay(exchange, t)
+	status := ExchangeStatus{Exchange: exchange, Session: sessionIn(day, t)}
+	status.IsOpen = isMatchingSession(status.Session)
+
+	// Sessions ahead of t: the rest of today, then the next trading day
+	upcoming := make([]MarketSession, 0, len(day.Sessions)*2)
+	for _, s := range day.Sessions {
+		if !t.Before(s.Start) && t.Before(s.End) {
+			end := s.End
+			status.SessionEndsAt = &end
+		}
+		if s.Start.After(t) {
+			upcoming = append(upcoming, s)
+		}
+	}
+	if len(day.Sessions) > 0 && t.Before(day.Sessions[0].Start) {
+		first := day.Sessions[0].Start
+		status.SessionEndsAt = &first
+	}
+	upcoming = append(upcoming, tc.ExchangeDay(exchange, tc.NextTradingDay(t)).Sessions...)
+
+	if len(upcoming) > 0 {
+		status.NextSession = upcoming[0].Name
+		status.NextSessionAt = upcoming[0].Start
+		status.SecondsToNext = int64(upcoming[0].Start.Sub(t).Seconds())
+	}
+	for _, s := range upcoming {
+		if isMatchingSession(s.Name) {
+			status.NextOpenAt = s.Start
+			status.SecondsToNextOpen = int64(s.Start.Sub(t).Seconds())
+			break
+		}
+	}
+	return status
+}
+
+// UpcomingSessions returns the next n trading days starting with the date of from
+func (tc *TradingCalendar) UpcomingSessions(from time.Time, n int) []TradingDay {
+	days := make([]TradingDay, 0, n)
+	day := from
+	if !tc.IsTradingDay(day) {
+		day = tc.NextTradingDay(day)
+	}
+	for len(days) < n {
+		days = append(days, tc.Day(day))
+		day = tc.NextTradingDay(day)
+	}
+	return days
+}
+
+// atClock returns the HH:MM time on the date of day in market time
+func (tc *TradingCalendar) atClock(day time.Time, clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, tc.location)
+}
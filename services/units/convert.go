@@ -0,0 +1,224 @@
+package units
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// Struct fields kept as plain numbers name their unit with a unit tag, so the structs shared with
+// the price files, MongoDB and the calculations keep their float64 arithmetic:
+//
+//	MA50      float64         `json:"ma_50" unit:"price"`
+//	Close     decimal.Decimal `json:"close" unit:"price_vnd"`
+const (
+	TagPrice    = "price"     // thousands of VND, as Price
+	TagPriceVND = "price_vnd" // raw VND
+	TagValue    = "value"     // billions of VND, as Value
+	TagValueVND = "value_vnd" // raw VND
+)
+
+var (
+	priceType   = reflect.TypeOf(Price(0))
+	valueType   = reflect.TypeOf(Value(0))
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	marshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Apply returns data with every Price, Value and unit-tagged field reported in the mode. Structs
+// holding amounts become maps keyed by their JSON names; data without amounts is returned as is.
+func Apply(data interface{}, mode Mode) interface{} {
+	if mode == ModeStored || data == nil {
+		return data
+	}
+	return convert(reflect.ValueOf(data), "", mode)
+}
+
+// convert reports v, tagged with unit, in the mode
+func convert(v reflect.Value, unit string, mode Mode) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Type() {
+	case priceType:
+		return mode.Price(Price(v.Float()))
+	case valueType:
+		return mode.Value(Value(v.Float()))
+	case decimalType:
+		if unit != "" {
+			f, _ := v.Interface().(decimal.Decimal).Float64()
+			return convertTagged(f, unit, mode)
+		}
+	}
+	if unit != "" && (v.Kind() == reflect.Float64 || v.Kind() == reflect.Float32) {
+		return convertTagged(v.Float(), unit, mode)
+	}
+	if unit == "" && !holdsAmounts(v.Type()) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return convert(v.Elem(), unit, mode)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = convert(v.Index(i), unit, mode)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[mapKey(iter.Key())] = convert(iter.Value(), unit, mode)
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		convertStruct(v, mode, out)
+		return out
+	}
+	return v.Interface()
+}
+
+// convertStruct adds the fields of v to out the way encoding/json names them, flattening
+// embedded structs
+func convertStruct(v reflect.Value, mode Mode, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if field.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				convertStruct(fv, mode, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		out[name] = convert(fv, field.Tag.Get("unit"), mode)
+	}
+}
+
+// convertTagged reports a plain number in the unit of its tag in the mode
+func convertTagged(f float64, unit string, mode Mode) interface{} {
+	switch unit {
+	case TagPrice:
+		return mode.Price(Price(f))
+	case TagPriceVND:
+		return mode.Price(PriceFromVND(f))
+	case TagValue:
+		return mode.Value(Value(f))
+	case TagValueVND:
+		return mode.Value(ValueFromVND(f))
+	}
+	return f
+}
+
+// amountTypes caches whether values of a type can hold amounts to convert
+var amountTypes = struct {
+	sync.Mutex
+	holds map[reflect.Type]bool
+}{holds: make(map[reflect.Type]bool)}
+
+// holdsAmounts reports whether a value of type t may contain a Price, a Value or a unit-tagged
+// field. Interfaces and interface-valued containers may hold anything, so they are walked.
+func holdsAmounts(t reflect.Type) bool {
+	amountTypes.Lock()
+	defer amountTypes.Unlock()
+	return holdsAmountsLocked(t)
+}
+
+func holdsAmountsLocked(t reflect.Type) bool {
+	if holds, ok := amountTypes.holds[t]; ok {
+		return holds
+	}
+	// A recursive type is taken to hold none while its own fields are checked
+	amountTypes.holds[t] = false
+	holds := checkAmounts(t)
+	amountTypes.holds[t] = holds
+	return holds
+}
+
+func checkAmounts(t reflect.Type) bool {
+	if t == priceType || t == valueType {
+		return true
+	}
+	if t.Kind() != reflect.Interface && (t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler)) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsAmountsLocked(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get("json") == "-" {
+				continue
+			}
+			if field.Tag.Get("unit") != "" || holdsAmountsLocked(field.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	b, _ := json.Marshal(k.Interface())
+	return strings.Trim(string(b), `"`)
+}
+
+// isEmptyValue matches the omitempty rules of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package units
+
+import "github.com/shopspring/decimal"
+
+// Targets, stops, P&L and backtest accounting are calculated in decimal and rounded half to even,
+// so repeated arithmetic does not drift and ties round the same way in every path. Statistical
+// indicators (averages, RSI, MACD) stay float64.
+
+// PercentPlaces is the precision of returns in percent, as the decimal(10,4) columns keep them
+const PercentPlaces = 4
+
+var hundred = decimal.NewFromInt(100)
+
+// RoundVND rounds an amount in VND to the whole đồng, halves to even
+func RoundVND(vnd decimal.Decimal) decimal.Decimal {
+	return vnd.RoundBank(0)
+}
+
+// Offset returns price moved by pct percent: 15 for a target 15% above, -5 for a stop 5% below
+func Offset(price, pct decimal.Decimal) decimal.Decimal {
+	return price.Mul(hundred.Add(pct)).Div(hundred)
+}
+
+// ReturnPct returns the change from entry to exit in percent, negated for a short position and
+// rounded half to even to PercentPlaces. It is zero without an entry price.
+func ReturnPct(entry, exit decimal.Decimal, short bool) decimal.Decimal {
+	if !entry.IsPositive() {
+		return decimal.Zero
+	}
+	pct := exit.Sub(entry).Mul(hundred).Div(entry)
+	if short {
+		pct = pct.Neg()
+	}
+	return pct.RoundBank(PercentPlaces)
+}
+
+// PnL returns the profit of quantity shares from entry to exit; short positions gain when the
+// price falls. Prices keep their unit, so the profit is in the unit of the prices.
+func PnL(entry, exit decimal.Decimal, quantity int64, short bool) decimal.Decimal {
+	diff := exit.Sub(entry)
+	if short {
+		diff = diff.Neg()
+	}
+	return diff.Mul(decimal.NewFromInt(quantity))
+}
@@ -0,0 +1,59 @@
+package units
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Exchanges, as VNDirect names them in the stock list floor
+const (
+	ExchangeHOSE  = "HOSE"
+	ExchangeHNX   = "HNX"
+	ExchangeUPCOM = "UPCOM"
+)
+
+var (
+	tick1   = decimal.NewFromInt(1)
+	tick10  = decimal.NewFromInt(10)
+	tick50  = decimal.NewFromInt(50)
+	tick100 = decimal.NewFromInt(100)
+
+	hoseBand10 = decimal.NewFromInt(10_000)
+	hoseBand50 = decimal.NewFromInt(50_000)
+)
+
+// TickSize returns the price step in VND of an instrument at a price in VND. HOSE steps stocks
+// and fund certificates by 10, 50 or 100 đồng below 10,000, below 50,000 and above, and ETFs
+// and covered warrants by 10 đồng; HNX and UPCoM step ETFs by 1 đồng and everything else by 100.
+// instrumentType is one of the types services.ClassifyInstrument assigns; an unknown exchange
+// follows HOSE, where most instruments trade.
+func TickSize(exchange, instrumentType string, priceVND decimal.Decimal) decimal.Decimal {
+	etf := instrumentType == "etf"
+	switch strings.ToUpper(exchange) {
+	case ExchangeHNX, ExchangeUPCOM:
+		if etf {
+			return tick1
+		}
+		return tick100
+	}
+	switch {
+	case etf || instrumentType == "cw":
+		return tick10
+	case priceVND.LessThan(hoseBand10):
+		return tick10
+	case priceVND.LessThan(hoseBand50):
+		return tick50
+	}
+	return tick100
+}
+
+// RoundToTick rounds a price in VND to the nearest step, halves to the even step, so targets and
+// stops are prices an order can actually be placed at
+func RoundToTick(priceVND decimal.Decimal, exchange, instrumentType string) decimal.Decimal {
+	if !priceVND.IsPositive() {
+		return decimal.Zero
+	}
+	tick := TickSize(exchange, instrumentType, priceVND)
+	return priceVND.Div(tick).RoundBank(0).Mul(tick)
+}
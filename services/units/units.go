@@ -0,0 +1,130 @@
+// Package units names the money units amounts are kept in and converts API responses between
+// them. Prices from VNDirect, the price files and the indicators are in thousands of VND, trading
+// values in billions of VND (tỷ đồng), while SSI quotes and the database hold raw VND.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Conversion factors to raw VND
+const (
+	VNDPerThousand = 1000
+	VNDPerMillion  = 1_000_000
+	VNDPerBillion  = 1_000_000_000
+)
+
+// Price is a per-share price in thousands of VND, the unit VNDirect quotes and indicators use
+type Price float64
+
+// Value is a trading value or market cap in billions of VND (tỷ đồng)
+type Value float64
+
+// PriceFromVND converts a raw VND price, as SSI and the database hold it
+func PriceFromVND(vnd float64) Price {
+	return Price(vnd / VNDPerThousand)
+}
+
+// ValueFromVND converts a raw VND amount, such as a session's nmValue
+func ValueFromVND(vnd float64) Value {
+	return Value(vnd / VNDPerBillion)
+}
+
+// VND returns the price in VND, rounded to the đồng
+func (p Price) VND() float64 {
+	return math.Round(float64(p) * VNDPerThousand)
+}
+
+// VND returns the value in VND, rounded to the đồng
+func (v Value) VND() float64 {
+	return math.Round(float64(v) * VNDPerBillion)
+}
+
+// Mode is how a response reports amounts, chosen with ?units=
+type Mode string
+
+// Unit modes. ModeStored leaves every amount in the unit its source keeps it in, which is what
+// responses returned before ?units= existed.
+const (
+	ModeStored    Mode = ""
+	ModeRaw       Mode = "raw"       // prices and values in VND
+	ModeThousands Mode = "thousands" // prices in thousands of VND, values in billions of VND
+	ModeDisplay   Mode = "display"   // strings formatted for Vietnamese readers: "72.500", "1,25 tỷ"
+)
+
+// ParseMode parses a ?units= value; empty keeps the stored units
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case ModeStored, ModeRaw, ModeThousands, ModeDisplay:
+		return mode, nil
+	}
+	return ModeStored, fmt.Errorf("units must be raw, thousands or display")
+}
+
+// Price reports p in the mode
+func (m Mode) Price(p Price) interface{} {
+	switch m {
+	case ModeRaw:
+		return p.VND()
+	case ModeDisplay:
+		return FormatVND(p.VND())
+	}
+	return float64(p)
+}
+
+// Value reports v in the mode
+func (m Mode) Value(v Value) interface{} {
+	switch m {
+	case ModeRaw:
+		return v.VND()
+	case ModeDisplay:
+		return FormatValue(v)
+	}
+	return float64(v)
+}
+
+// FormatVND formats a whole VND amount with dots between thousands, e.g. 72500 as "72.500"
+func FormatVND(vnd float64) string {
+	s := groupThousands(fmt.Sprintf("%.0f", math.Abs(vnd)))
+	if vnd < 0 && s != "0" {
+		s = "-" + s
+	}
+	return s
+}
+
+// FormatValue formats a value in tỷ, or in triệu below one tỷ, with a decimal comma, e.g.
+// "1,25 tỷ" and "850 triệu"
+func FormatValue(v Value) string {
+	if math.Abs(float64(v)) >= 1 {
+		return decimalComma(float64(v)) + " tỷ"
+	}
+	return decimalComma(float64(v)*VNDPerBillion/VNDPerMillion) + " triệu"
+}
+
+// decimalComma formats x with at most two decimals, a comma before them and dots between
+// thousands, e.g. 1234.5 as "1.234,5"
+func decimalComma(x float64) string {
+	whole, frac, _ := strings.Cut(fmt.Sprintf("%.2f", math.Abs(x)), ".")
+	s := groupThousands(whole)
+	if frac = strings.TrimRight(frac, "0"); frac != "" {
+		s += "," + frac
+	}
+	if x < 0 && s != "0" {
+		s = "-" + s
+	}
+	return s
+}
+
+// groupThousands puts dots between the thousands of a string of digits
+func groupThousands(digits string) string {
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
@@ -0,0 +1,512 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_backend_project/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErasedUserName replaces the name of an erased user wherever a record has to be kept
+const ErasedUserName = "Deleted user"
+
+// ErrErasureNotPending is returned when acting on a request that is no longer open
+var ErrErasureNotPending = errors.New("erasure request is not pending")
+
+// UserDataBundle is everything the backend stores about one user, exported before erasure
+type UserDataBundle struct {
+	SupabaseUserID    string                         `json:"supabase_user_id"`
+	ExportedAt        time.Time                      `json:"exported_at"`
+	Profile           *UserProfile                   `json:"profile,omitempty"` // Supabase profile, when reachable
+	User              *models.User                   `json:"user,omitempty"`
+	Watchlists        []models.Watchlist             `json:"watchlists"`
+	PriceAlerts       []models.UserAlert             `json:"price_alerts"`
+	Portfolios        []models.Portfolio             `json:"portfolios"`
+	Trades            []models.Trade                 `json:"trades"`
+	Subscriptions     []models.Subscription          `json:"subscriptions"`
+	Payments          []models.PaymentHistory        `json:"payments"`
+	SignalAlerts      []models.SignalAlert           `json:"signal_alerts"`
+	TemplateRatings   []models.SignalTemplateRating  `json:"template_ratings"`
+	SignalRules       []models.SignalRule            `json:"signal_rules"`
+	ConditionGroups   []models.SignalConditionGroup  `json:"condition_groups"`
+	AuthoredTemplates []models.SignalTemplate        `json:"authored_templates"`
+	Notifications     []models.Notification          `json:"notifications"`
+	Devices           []models.DeviceToken           `json:"devices"`
+	Reports           []models.UserReport            `json:"reports"`
+	StockNotes        []models.StockNote             `json:"stock_notes"`
+	SharedWatchlists  []models.SharedWatchlist       `json:"shared_watchlists"` // owned, with stocks and members
+	WatchlistMembers  []models.SharedWatchlistMember `json:"watchlist_memberships"`
+	Ideas             []models.Idea                  `json:"ideas"`
+	IdeaLikes         []models.IdeaLike              `json:"idea_likes"`
+	IdeaFollows       []models.IdeaFollow            `json:"idea_follows"`
+	IdeaReports       []models.IdeaReport            `json:"idea_reports"`
+	StrategistFollows []models.StrategistFollow      `json:"strategist_follows"`
+	PaperTrades       []models.PaperTrade            `json:"paper_trades"`
+	Experiments       []models.ExperimentExposure    `json:"experiment_exposures"`
+	ExperimentEvents  []models.ExperimentEvent       `json:"experiment_events"`
+}
+
+// UserErasureReceipt records what an erasure removed; it holds no personal data
+type UserErasureReceipt struct {
+	RequestID            uint           `json:"request_id"`
+	SubjectHash          string         `json:"subject_hash"`
+	LocalUserID          uint           `json:"local_user_id,omitempty"`
+	Deleted              map[string]int `json:"deleted"`
+	Anonymized           map[string]int `json:"anonymized"`
+	SupabaseProfileGone  bool           `json:"supabase_profile_deleted"`
+	SupabaseAuthUserGone bool           `json:"supabase_auth_user_deleted"`
+	ApprovedBy           string         `json:"approved_by"`
+	CompletedAt          time.Time      `json:"completed_at"`
+}
+
+// ErasureSubjectHash identifies an erased user in receipts without keeping their ID
+func ErasureSubjectHash(supabaseUserID string) string {
+	sum := sha256.Sum256([]byte(supabaseUserID))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestUserErasure opens an erasure request for the user, returning the open one if the
+// user already has one
+func RequestUserErasure(db *gorm.DB, supabaseUserID, email, reason string) (*models.UserErasureRequest, bool, error) {
+	var existing models.UserErasureRequest
+	err := db.Where("supabase_user_id = ? AND status IN ?", supabaseUserID,
+		[]string{models.ErasureStatusPending, models.ErasureStatusFailed}).First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	request := &models.UserErasureRequest{
+		SupabaseUserID: supabaseUserID,
+		Email:          email,
+		SubjectHash:    ErasureSubjectHash(supabaseUserID),
+		Reason:         strings.TrimSpace(reason),
+		Status:         models.ErasureStatusPending,
+	}
+	if err := db.Create(request).Error; err != nil {
+		return nil, false, err
+	}
+	return request, true, nil
+}
+
+// ExportUserData collects the user's data from the database and, when a client is given, their
+// Supabase profile
+func ExportUserData(db *gorm.DB, supabase *SupabaseDBClient, supabaseUserID string) (*UserDataBundle, error) {
+	bundle := &UserDataBundle{SupabaseUserID: supabaseUserID, ExportedAt: time.Now()}
+	if supabase != nil {
+		if profile, err := supabase.GetProfileByID(supabaseUserID); err == nil {
+			bundle.Profile = profile
+		}
+	}
+
+	var user models.User
+	err := db.Where("supabase_user_id = ?", supabaseUserID).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil {
+		bundle.User = &user
+		for _, q := range []struct {
+			dest  interface{}
+			query *gorm.DB
+		}{
+			{&bundle.Watchlists, db.Preload("Stock")},
+			{&bundle.PriceAlerts, db.Preload("Stock")},
+			{&bundle.Portfolios, db.Preload("Stock")},
+			{&bundle.Trades, db.Preload("Stock")},
+			{&bundle.Subscriptions, db.Preload("Plan")},
+			{&bundle.Payments, db},
+		} {
+			if err := q.query.Where("user_id = ?", user.ID).Order("id").Find(q.dest).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, q := range []struct {
+		dest   interface{}
+		column string
+	}{
+		{&bundle.SignalAlerts, "user_id"},
+		{&bundle.TemplateRatings, "user_id"},
+		{&bundle.SignalRules, "owner_user_id"},
+		{&bundle.ConditionGroups, "owner_user_id"},
+		{&bundle.AuthoredTemplates, "author_id"},
+		{&bundle.Notifications, "user_id"},
+		{&bundle.Devices, "user_id"},
+		{&bundle.Reports, "user_id"},
+		{&bundle.Ideas, "author_id"},
+		{&bundle.IdeaLikes, "user_id"},
+		{&bundle.IdeaFollows, "user_id"},
+		{&bundle.IdeaReports, "user_id"},
+		{&bundle.StrategistFollows, "user_id"},
+		{&bundle.PaperTrades, "user_id"},
+		{&bundle.Experiments, "user_id"},
+		{&bundle.ExperimentEvents, "user_id"},
+	} {
+		if err := db.Where(q.column+" = ?", supabaseUserID).Order("id").Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	notes, err := ListStockNotes(db, supabaseUserID, "")
+	if err != nil {
+		return nil, err
+	}
+	bundle.StockNotes = notes
+
+	if err := db.Preload("Items").Preload("Members").Where("owner_user_id = ?", supabaseUserID).Order("id").
+		Find(&bundle.SharedWatchlists).Error; err != nil {
+		return nil, err
+	}
+	memberships := db.Where("user_id = ?", supabaseUserID)
+	if bundle.User != nil && bundle.User.Email != "" {
+		memberships = db.Where("user_id = ? OR email = ?", supabaseUserID, strings.ToLower(bundle.User.Email))
+	}
+	if err := memberships.Order("id").Find(&bundle.WatchlistMembers).Error; err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// ExecuteUserErasure erases the data of a pending (or failed) request: the local user is
+// anonymized and soft-deleted, their watchlists, alerts, portfolios, trade journal, signal
+// subscriptions, notifications, reports, stock notes, shared watchlists they own or joined,
+// trade ideas, strategist follows (theirs and of them), paper trades, experiment enrollments,
+// ratings and private rules are deleted, public templates they authored are kept under
+// ErasedUserName, API usage logs lose their user, and payments stay for accounting against the
+// anonymized user. The Supabase profile and auth user are deleted afterwards; if that fails the
+// request is marked failed and can be executed again.
+func ExecuteUserErasure(db *gorm.DB, supabase *SupabaseDBClient, request *models.UserErasureRequest, approvedBy string) (*UserErasureReceipt, error) {
+	if request.Status != models.ErasureStatusPending && request.Status != models.ErasureStatusFailed {
+		return nil, ErrErasureNotPending
+	}
+	userID := request.SupabaseUserID
+	receipt := &UserErasureReceipt{
+		RequestID:   request.ID,
+		SubjectHash: request.SubjectHash,
+		Deleted:     map[string]int{},
+		Anonymized:  map[string]int{},
+		ApprovedBy:  approvedBy,
+	}
+	// A retried request carries the counts of the earlier, partly successful run
+	if request.Receipt != "" {
+		if err := json.Unmarshal([]byte(request.Receipt), receipt); err != nil {
+			return nil, fmt.Errorf("invalid receipt of the previous run: %w", err)
+		}
+		receipt.ApprovedBy = approvedBy
+	}
+	erasedMarker := fmt.Sprintf("erased-%d", request.ID)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Unscoped().Where("supabase_user_id IN ?", []string{userID, erasedMarker}).First(&user).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == nil {
+			receipt.LocalUserID = user.ID
+			for name, model := range map[string]interface{}{
+				"watchlists":    &models.Watchlist{},
+				"price_alerts":  &models.UserAlert{},
+				"portfolios":    &models.Portfolio{},
+				"trades":        &models.Trade{},
+				"user_sessions": &models.UserSession{},
+			} {
+				result := tx.Where("user_id = ?", user.ID).Delete(model)
+				if result.Error != nil {
+					return fmt.Errorf("delete %s: %w", name, result.Error)
+				}
+				receipt.Deleted[name] += int(result.RowsAffected)
+			}
+
+			if user.Email != "" {
+				result := tx.Where("email = ?", strings.ToLower(user.Email)).Delete(&models.SharedWatchlistMember{})
+				if result.Error != nil {
+					return fmt.Errorf("delete watchlist invitations: %w", result.Error)
+				}
+				receipt.Deleted["shared_watchlist_members"] += int(result.RowsAffected)
+			}
+
+			// The row is kept for the payment ledger; nothing on it identifies the person
+			if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+				"supabase_user_id": erasedMarker,
+				"email":            fmt.Sprintf("erased-%d@erased.invalid", user.ID),
+				"full_name":        ErasedUserName,
+				"avatar_url":       "",
+				"phone":            "",
+				"preferences":      "{}",
+				"is_active":        false,
+				"deleted_at":       time.Now(),
+			}).Error; err != nil {
+				return fmt.Errorf("anonymize user: %w", err)
+			}
+			receipt.Anonymized["users"] = 1
+		}
+
+		var alertIDs []uint
+		if err := tx.Model(&models.SignalAlert{}).Where("user_id = ?", userID).Pluck("id", &alertIDs).Error; err != nil {
+			return err
+		}
+		if len(alertIDs) > 0 {
+			result := tx.Where("alert_id IN ?", alertIDs).Delete(&models.SignalAlertHistory{})
+			if result.Error != nil {
+				return fmt.Errorf("delete signal alert history: %w", result.Error)
+			}
+			receipt.Deleted["signal_alert_history"] += int(result.RowsAffected)
+			if result = tx.Where("id IN ?", alertIDs).Delete(&models.SignalAlert{}); result.Error != nil {
+				return fmt.Errorf("delete signal alerts: %w", result.Error)
+			}
+			receipt.Deleted["signal_alerts"] += int(result.RowsAffected)
+		}
+
+		var result *gorm.DB
+		for name, model := range map[string]interface{}{
+			"notifications":            &models.Notification{},
+			"device_tokens":            &models.DeviceToken{},
+			"notification_preferences": &models.NotificationPreference{},
+			"user_reports":             &models.UserReport{},
+			"stock_note_tags":          &models.StockNoteTag{},
+			"stock_notes":              &models.StockNote{},
+			"strategist_follows":       &models.StrategistFollow{},
+			"paper_trades":             &models.PaperTrade{},
+			"experiment_exposures":     &models.ExperimentExposure{},
+			"experiment_events":        &models.ExperimentEvent{},
+		} {
+			if result = tx.Where("user_id = ?", userID).Delete(model); result.Error != nil {
+				return fmt.Errorf("delete %s: %w", name, result.Error)
+			}
+			receipt.Deleted[name] += int(result.RowsAffected)
+		}
+
+		// Usage logs stay for the analytics without the user
+		if result = tx.Model(&models.APIUsageLog{}).Where("user_id = ?", userID).UpdateColumn("user_id", ""); result.Error != nil {
+			return fmt.Errorf("anonymize api usage logs: %w", result.Error)
+		}
+		receipt.Anonymized["api_usage_logs"] += int(result.RowsAffected)
+
+		// Followers of the user lose the follow; their paper trades stay without the author
+		if result = tx.Where("author_id = ?", userID).Delete(&models.StrategistFollow{}); result.Error != nil {
+			return fmt.Errorf("delete strategist followers: %w", result.Error)
+		}
+		receipt.Deleted["strategist_follows"] += int(result.RowsAffected)
+		if err := tx.Model(&models.PaperTrade{}).Where("author_id = ?", userID).UpdateColumn("author_id", "").Error; err != nil {
+			return fmt.Errorf("anonymize paper trades: %w", err)
+		}
+
+		// Lists the user owns go for every member; on other lists only their membership goes
+		var sharedListIDs []uint
+		if err := tx.Model(&models.SharedWatchlist{}).Where("owner_user_id = ?", userID).Pluck("id", &sharedListIDs).Error; err != nil {
+			return err
+		}
+		if len(sharedListIDs) > 0 {
+			for name, model := range map[string]interface{}{
+				"shared_watchlist_items":   &models.SharedWatchlistItem{},
+				"shared_watchlist_members": &models.SharedWatchlistMember{},
+			} {
+				if result = tx.Where("watchlist_id IN ?", sharedListIDs).Delete(model); result.Error != nil {
+					return fmt.Errorf("delete %s: %w", name, result.Error)
+				}
+				receipt.Deleted[name] += int(result.RowsAffected)
+			}
+			if result = tx.Where("id IN ?", sharedListIDs).Delete(&models.SharedWatchlist{}); result.Error != nil {
+				return fmt.Errorf("delete shared watchlists: %w", result.Error)
+			}
+			receipt.Deleted["shared_watchlists"] += int(result.RowsAffected)
+		}
+		if result = tx.Where("user_id = ?", userID).Delete(&models.SharedWatchlistMember{}); result.Error != nil {
+			return fmt.Errorf("delete watchlist memberships: %w", result.Error)
+		}
+		receipt.Deleted["shared_watchlist_members"] += int(result.RowsAffected)
+		if err := tx.Model(&models.SharedWatchlistItem{}).Where("added_by = ?", userID).UpdateColumn("added_by", "").Error; err != nil {
+			return fmt.Errorf("anonymize watchlist items: %w", err)
+		}
+
+		// Ideas go with their likes, follows and reports; on other ideas the counts are recomputed
+		var ideaIDs, engagedIdeaIDs []uint
+		if err := tx.Unscoped().Model(&models.Idea{}).Where("author_id = ?", userID).Pluck("id", &ideaIDs).Error; err != nil {
+			return err
+		}
+		if len(ideaIDs) > 0 {
+			for name, model := range map[string]interface{}{
+				"idea_likes":   &models.IdeaLike{},
+				"idea_follows": &models.IdeaFollow{},
+				"idea_reports": &models.IdeaReport{},
+			} {
+				if result = tx.Where("idea_id IN ?", ideaIDs).Delete(model); result.Error != nil {
+					return fmt.Errorf("delete %s: %w", name, result.Error)
+				}
+				receipt.Deleted[name] += int(result.RowsAffected)
+			}
+			if result = tx.Unscoped().Where("id IN ?", ideaIDs).Delete(&models.Idea{}); result.Error != nil {
+				return fmt.Errorf("delete ideas: %w", result.Error)
+			}
+			receipt.Deleted["ideas"] += int(result.RowsAffected)
+		}
+		if err := tx.Raw("SELECT idea_id FROM idea_likes WHERE user_id = ? UNION SELECT idea_id FROM idea_follows WHERE user_id = ? UNION SELECT idea_id FROM idea_reports WHERE user_id = ?",
+			userID, userID, userID).Scan(&engagedIdeaIDs).Error; err != nil {
+			return err
+		}
+		if len(engagedIdeaIDs) > 0 {
+			for name, model := range map[string]interface{}{
+				"idea_likes":   &models.IdeaLike{},
+				"idea_follows": &models.IdeaFollow{},
+				"idea_reports": &models.IdeaReport{},
+			} {
+				if result = tx.Where("user_id = ?", userID).Delete(model); result.Error != nil {
+					return fmt.Errorf("delete %s: %w", name, result.Error)
+				}
+				receipt.Deleted[name] += int(result.RowsAffected)
+			}
+			if err := recountIdeaEngagement(tx, engagedIdeaIDs); err != nil {
+				return err
+			}
+		}
+
+		var ratedTemplateIDs []uint
+		if err := tx.Model(&models.SignalTemplateRating{}).Where("user_id = ?", userID).Pluck("template_id", &ratedTemplateIDs).Error; err != nil {
+			return err
+		}
+		if len(ratedTemplateIDs) > 0 {
+			result := tx.Where("user_id = ?", userID).Delete(&models.SignalTemplateRating{})
+			if result.Error != nil {
+				return fmt.Errorf("delete template ratings: %w", result.Error)
+			}
+			receipt.Deleted["template_ratings"] += int(result.RowsAffected)
+			if err := recomputeTemplateRatings(tx, ratedTemplateIDs); err != nil {
+				return err
+			}
+		}
+
+		// User-owned rules and groups are private to the user, so nobody else depends on them
+		var groupIDs []uint
+		if err := tx.Unscoped().Model(&models.SignalConditionGroup{}).Where("owner_user_id = ?", userID).Pluck("id", &groupIDs).Error; err != nil {
+			return err
+		}
+		if len(groupIDs) > 0 {
+			result := tx.Unscoped().Where("group_id IN ?", groupIDs).Delete(&models.SignalCondition{})
+			if result.Error != nil {
+				return fmt.Errorf("delete signal conditions: %w", result.Error)
+			}
+			receipt.Deleted["signal_conditions"] += int(result.RowsAffected)
+			if result = tx.Unscoped().Where("id IN ?", groupIDs).Delete(&models.SignalConditionGroup{}); result.Error != nil {
+				return fmt.Errorf("delete condition groups: %w", result.Error)
+			}
+			receipt.Deleted["condition_groups"] += int(result.RowsAffected)
+		}
+		result = tx.Unscoped().Where("owner_user_id = ?", userID).Delete(&models.SignalRule{})
+		if result.Error != nil {
+			return fmt.Errorf("delete signal rules: %w", result.Error)
+		}
+		receipt.Deleted["signal_rules"] += int(result.RowsAffected)
+
+		// Public templates may be cloned or subscribed to by others: keep them, drop the author
+		result = tx.Unscoped().Where("author_id = ? AND visibility <> ?", userID, models.TemplateVisibilityPublic).Delete(&models.SignalTemplate{})
+		if result.Error != nil {
+			return fmt.Errorf("delete private templates: %w", result.Error)
+		}
+		receipt.Deleted["private_templates"] += int(result.RowsAffected)
+		result = tx.Unscoped().Model(&models.SignalTemplate{}).Where("author_id = ?", userID).UpdateColumns(map[string]interface{}{
+			"author_id":   models.ErasedAuthorID,
+			"author_name": ErasedUserName,
+		})
+		if result.Error != nil {
+			return fmt.Errorf("anonymize public templates: %w", result.Error)
+		}
+		receipt.Anonymized["public_templates"] += int(result.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cleanupErrs []string
+	if supabase == nil {
+		cleanupErrs = append(cleanupErrs, "Supabase is not configured")
+	} else {
+		if !receipt.SupabaseProfileGone {
+			if err := supabase.DeleteProfile(userID); err != nil {
+				cleanupErrs = append(cleanupErrs, "delete profile: "+err.Error())
+			} else {
+				receipt.SupabaseProfileGone = true
+			}
+		}
+		if !receipt.SupabaseAuthUserGone {
+			if err := supabase.DeleteAuthUser(userID); err != nil {
+				cleanupErrs = append(cleanupErrs, "delete auth user: "+err.Error())
+			} else {
+				receipt.SupabaseAuthUserGone = true
+			}
+		}
+	}
+
+	now := time.Now()
+	receipt.CompletedAt = now
+	updates := map[string]interface{}{
+		"reviewed_by": approvedBy,
+		"reviewed_at": now,
+	}
+	receiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, err
+	}
+	updates["receipt"] = string(receiptJSON)
+	if len(cleanupErrs) > 0 {
+		updates["status"] = models.ErasureStatusFailed
+		updates["error"] = strings.Join(cleanupErrs, "; ")
+		if err := db.Model(request).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		return receipt, fmt.Errorf("local data erased but Supabase cleanup failed: %s", updates["error"])
+	}
+
+	sum := sha256.Sum256(receiptJSON)
+	updates["status"] = models.ErasureStatusCompleted
+	updates["error"] = ""
+	updates["completed_at"] = now
+	updates["receipt_hash"] = hex.EncodeToString(sum[:])
+	updates["supabase_user_id"] = ""
+	updates["email"] = ""
+	if err := db.Model(request).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// recomputeTemplateRatings refreshes the rating aggregates of templates after ratings were removed
+func recomputeTemplateRatings(tx *gorm.DB, templateIDs []uint) error {
+	for _, id := range templateIDs {
+		var agg struct {
+			Sum   int
+			Count int
+		}
+		if err := tx.Model(&models.SignalTemplateRating{}).
+			Select("COALESCE(SUM(rating), 0) as sum, COUNT(*) as count").
+			Where("template_id = ?", id).
+			Scan(&agg).Error; err != nil {
+			return err
+		}
+		avg := decimal.Zero
+		if agg.Count > 0 {
+			avg = decimal.NewFromInt(int64(agg.Sum)).Div(decimal.NewFromInt(int64(agg.Count))).Round(2)
+		}
+		if err := tx.Unscoped().Model(&models.SignalTemplate{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"rating_sum":   agg.Sum,
+			"rating_count": agg.Count,
+			"rating_avg":   avg,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
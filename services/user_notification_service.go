@@ -0,0 +1,54 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"go_backend_project/models"
+
+	"gorm.io/gorm"
+)
+
+// NotifyUser adds a notification to a user's inbox and pushes it to their devices; userID is the
+// Supabase user ID and data is stored as JSON
+func NotifyUser(db *gorm.DB, userID, category, title, message string, data interface{}) {
+	if db == nil || userID == "" {
+		return
+	}
+
+	dataJSON := []byte("{}")
+	if data != nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			dataJSON = encoded
+		}
+	}
+
+	notification := &models.Notification{
+		UserID:   userID,
+		Category: category,
+		Title:    title,
+		Message:  message,
+		Data:     string(dataJSON),
+	}
+	if err := db.Create(notification).Error; err != nil {
+		log.Printf("Failed to record notification %q for user %s: %v", title, userID, err)
+		return
+	}
+	if GlobalPushService != nil {
+		go GlobalPushService.Deliver(db, notification)
+	}
+}
+
+// NotifyLocalUser notifies the user with the given local users.id
+func NotifyLocalUser(db *gorm.DB, localUserID uint, category, title, message string, data interface{}) {
+	if db == nil {
+		return
+	}
+
+	var user models.User
+	if err := db.Select("supabase_user_id").First(&user, localUserID).Error; err != nil {
+		log.Printf("Failed to notify user %d: %v", localUserID, err)
+		return
+	}
+	NotifyUser(db, user.SupabaseUserID, category, title, message, data)
+}